@@ -51,6 +51,9 @@ var (
 	
 	// 主題設定驗證錯誤
 	ErrInvalidTheme = errors.New("主題必須是 'light'、'dark' 或 'auto'")
+	
+	// 閒置自動鎖定時間驗證錯誤
+	ErrInvalidSessionTimeout = errors.New("閒置自動鎖定時間必須在 0 到 1440 分鐘之間")
 )
 
 // NewAppError 建立一個新的應用程式錯誤實例
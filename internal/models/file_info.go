@@ -15,6 +15,8 @@ type FileInfo struct {
 	ModTime      time.Time   `json:"mod_time"`     // 最後修改時間
 	IsEncrypted  bool        `json:"is_encrypted"` // 是否為加密檔案
 	Permissions  os.FileMode `json:"permissions"`  // 檔案權限
+	IsFavorite   bool        `json:"is_favorite"`  // 是否為最愛
+	IsPinned     bool        `json:"is_pinned"`    // 是否已釘選（排在同一個目錄的最前面）
 }
 
 // NewFileInfo 從 os.FileInfo 建立 FileInfo 實例
@@ -4,6 +4,8 @@ package models
 
 import (
 	"crypto/rand"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 )
@@ -20,6 +22,15 @@ type Note struct {
 	CreatedAt      time.Time `json:"created_at"`      // 筆記建立時間
 	UpdatedAt      time.Time `json:"updated_at"`      // 筆記最後修改時間
 	LastSaved      time.Time `json:"last_saved"`      // 筆記最後保存時間
+
+	// InEncryptedFolder 筆記位於加密資料夾中（開啟和保存時依檔案路徑設定，不寫入檔案）
+	// 檔案內容在磁碟上是密文，記憶體中的明文與加密筆記同樣需要保護
+	InEncryptedFolder bool `json:"-"`
+
+	// 以下欄位來自筆記開頭的 YAML front matter，保存時寫回 front matter
+	Tags       []string       `json:"tags,omitempty"`       // 標籤
+	Aliases    []string       `json:"aliases,omitempty"`    // 別名
+	Properties map[string]any `json:"properties,omitempty"` // 其他自訂屬性
 }
 
 // NewNote 建立一個新的筆記實例並設定預設值
@@ -217,6 +228,7 @@ func (n *Note) GetWordCount() int {
 // 1. 建立新的 Note 結構體
 // 2. 複製所有欄位的值
 // 3. 確保時間戳也被正確複製
+// 4. 複製標籤、別名和自訂屬性的切片和對應表（屬性值本身不深度複製）
 func (n *Note) Clone() *Note {
 	return &Note{
 		ID:             n.ID,
@@ -228,6 +240,11 @@ func (n *Note) Clone() *Note {
 		CreatedAt:      n.CreatedAt,
 		UpdatedAt:      n.UpdatedAt,
 		LastSaved:      n.LastSaved,
+		Tags:           slices.Clone(n.Tags),
+		Aliases:        slices.Clone(n.Aliases),
+		Properties:     maps.Clone(n.Properties),
+
+		InEncryptedFolder: n.InEncryptedFolder,
 	}
 }
 
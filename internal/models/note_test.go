@@ -339,6 +339,16 @@ func TestNote_Clone(t *testing.T) {
 	if original.Content == cloned.Content {
 		t.Error("修改複製品不應該影響原始筆記")
 	}
+
+	// 驗證 front matter 欄位也被複製
+	original.Tags = []string{"工作"}
+	original.Properties = map[string]any{"status": "draft"}
+	cloned = original.Clone()
+	cloned.Tags[0] = "個人"
+	cloned.Properties["status"] = "done"
+	if original.Tags[0] != "工作" || original.Properties["status"] != "draft" {
+		t.Error("修改複製品的標籤和屬性不應該影響原始筆記")
+	}
 }
 
 // TestGenerateID 測試 ID 生成功能
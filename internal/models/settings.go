@@ -16,8 +16,18 @@ type Settings struct {
 	DefaultSaveLocation string `json:"default_save_location"` // 預設筆記保存位置
 	BiometricEnabled    bool   `json:"biometric_enabled"`     // 是否啟用生物識別驗證
 	Theme              string `json:"theme"`                 // 主題設定："light"（淺色）、"dark"（深色）、"auto"（自動）
+	SessionTimeout      int    `json:"session_timeout"`       // 加密工作階段閒置自動鎖定時間（分鐘，0 表示不自動鎖定）
+	LockOnBlur          bool   `json:"lock_on_blur"`          // 視窗失去焦點時是否立即鎖定加密工作階段
+	ObfuscateFilenames  bool   `json:"obfuscate_filenames"`   // 加密筆記是否使用隨機檔名（標題只保存在加密標頭中）
+	KeepBackups         bool   `json:"keep_backups"`          // 保存時是否保留上一版內容為 .bak，直到下一次保存成功
 }
 
+// 加密工作階段設定範圍常數
+const (
+	MaxSessionTimeout     = 1440 // 閒置自動鎖定時間上限（分鐘，24 小時）
+	DefaultSessionTimeout = 15   // 預設閒置自動鎖定時間（分鐘）
+)
+
 // NewDefaultSettings 建立具有預設值的設定實例
 // 回傳：指向新建立設定的指標
 //
@@ -27,6 +37,9 @@ type Settings struct {
 // - 預設保存位置：使用者文件夾下的 NotebookApp/notes 目錄
 // - 生物識別：預設關閉（需要使用者手動啟用）
 // - 主題：自動（跟隨系統設定）
+// - 閒置自動鎖定：15 分鐘，視窗失去焦點時不鎖定
+// - 加密筆記檔名：預設沿用標題
+// - 上一版備份：預設不保留
 func NewDefaultSettings() *Settings {
 	return &Settings{
 		DefaultEncryption:   "aes256",                        // 使用 AES-256 作為預設加密演算法
@@ -34,6 +47,10 @@ func NewDefaultSettings() *Settings {
 		DefaultSaveLocation: "~/Documents/NotebookApp/notes", // 預設保存到文件夾
 		BiometricEnabled:    false,                           // 預設不啟用生物識別
 		Theme:              "auto",                           // 自動跟隨系統主題
+		SessionTimeout:      DefaultSessionTimeout,           // 閒置 15 分鐘後自動鎖定
+		LockOnBlur:          false,                           // 預設不因失去焦點而鎖定
+		ObfuscateFilenames:  false,                           // 預設以標題作為檔名
+		KeepBackups:         false,                           // 預設不保留上一版備份
 	}
 }
 
@@ -44,12 +61,14 @@ func NewDefaultSettings() *Settings {
 // 1. 自動保存間隔必須在 1-60 分鐘之間
 // 2. 加密演算法必須是支援的類型（aes256 或 chacha20）
 // 3. 主題設定必須是有效的選項（light、dark 或 auto）
+// 4. 閒置自動鎖定時間必須在 0-1440 分鐘之間
 //
 // 執行流程：
 // 1. 檢查自動保存間隔的有效範圍
 // 2. 驗證加密演算法是否受支援
 // 3. 確認主題設定是否有效
-// 4. 如果所有驗證都通過，回傳 nil
+// 4. 檢查閒置自動鎖定時間的有效範圍
+// 5. 如果所有驗證都通過，回傳 nil
 func (s *Settings) Validate() error {
 	// 驗證自動保存間隔（1-60 分鐘）
 	if s.AutoSaveInterval < 1 || s.AutoSaveInterval > 60 {
@@ -66,6 +85,11 @@ func (s *Settings) Validate() error {
 		return ErrInvalidTheme
 	}
 	
+	// 驗證閒置自動鎖定時間（0-1440 分鐘）
+	if s.SessionTimeout < 0 || s.SessionTimeout > MaxSessionTimeout {
+		return ErrInvalidSessionTimeout
+	}
+	
 	// 所有驗證都通過
 	return nil
 }
@@ -153,6 +177,39 @@ func (s *Settings) SetBiometric(enabled bool) {
 	s.BiometricEnabled = enabled
 }
 
+// UpdateSessionTimeout 更新加密工作階段閒置自動鎖定時間
+// 參數：
+//   - minutes: 新的閒置時間（分鐘，範圍 0-1440，0 表示不自動鎖定）
+// 回傳：如果時間無效則回傳錯誤，否則回傳 nil
+func (s *Settings) UpdateSessionTimeout(minutes int) error {
+	if minutes < 0 || minutes > MaxSessionTimeout {
+		return ErrInvalidSessionTimeout
+	}
+	s.SessionTimeout = minutes
+	return nil
+}
+
+// SetLockOnBlur 設定視窗失去焦點時是否鎖定加密工作階段
+// 參數：
+//   - enabled: 是否在失去焦點時鎖定
+func (s *Settings) SetLockOnBlur(enabled bool) {
+	s.LockOnBlur = enabled
+}
+
+// SetObfuscateFilenames 設定加密筆記是否使用隨機檔名
+// 參數：
+//   - enabled: 是否以隨機檔名保存加密筆記
+func (s *Settings) SetObfuscateFilenames(enabled bool) {
+	s.ObfuscateFilenames = enabled
+}
+
+// SetKeepBackups 設定保存時是否保留上一版內容
+// 參數：
+//   - enabled: 是否保留上一版內容為 .bak
+func (s *Settings) SetKeepBackups(enabled bool) {
+	s.KeepBackups = enabled
+}
+
 // Clone 建立設定的深度複製
 // 回傳：新的設定實例，包含相同的資料但不同的記憶體位址
 //
@@ -167,6 +224,10 @@ func (s *Settings) Clone() *Settings {
 		DefaultSaveLocation: s.DefaultSaveLocation,
 		BiometricEnabled:    s.BiometricEnabled,
 		Theme:              s.Theme,
+		SessionTimeout:      s.SessionTimeout,
+		LockOnBlur:          s.LockOnBlur,
+		ObfuscateFilenames:  s.ObfuscateFilenames,
+		KeepBackups:         s.KeepBackups,
 	}
 }
 
@@ -183,7 +244,11 @@ func (s *Settings) IsDefault() bool {
 		s.AutoSaveInterval == defaultSettings.AutoSaveInterval &&
 		s.DefaultSaveLocation == defaultSettings.DefaultSaveLocation &&
 		s.BiometricEnabled == defaultSettings.BiometricEnabled &&
-		s.Theme == defaultSettings.Theme
+		s.Theme == defaultSettings.Theme &&
+		s.SessionTimeout == defaultSettings.SessionTimeout &&
+		s.LockOnBlur == defaultSettings.LockOnBlur &&
+		s.ObfuscateFilenames == defaultSettings.ObfuscateFilenames &&
+		s.KeepBackups == defaultSettings.KeepBackups
 }
 
 // GetSupportedEncryptionAlgorithms 取得支援的加密演算法清單
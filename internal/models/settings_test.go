@@ -138,6 +138,50 @@ func TestSettings_UpdateAutoSaveInterval(t *testing.T) {
 	}
 }
 
+// TestSettings_UpdateSessionTimeout 測試閒置自動鎖定時間更新功能
+// 驗證有效和無效的閒置時間設定，以及舊設定檔缺少欄位時的相容性
+func TestSettings_UpdateSessionTimeout(t *testing.T) {
+	settings := NewDefaultSettings()
+
+	// 測試有效的閒置時間（0 表示不自動鎖定）
+	for _, minutes := range []int{0, 1, 15, MaxSessionTimeout} {
+		if err := settings.UpdateSessionTimeout(minutes); err != nil {
+			t.Errorf("更新為有效閒置時間 %d 不應該產生錯誤：%v", minutes, err)
+		}
+		if settings.SessionTimeout != minutes {
+			t.Errorf("期望閒置時間為 %d，實際得到 %d", minutes, settings.SessionTimeout)
+		}
+	}
+
+	// 測試無效的閒置時間
+	for _, minutes := range []int{-1, MaxSessionTimeout + 1} {
+		if err := settings.UpdateSessionTimeout(minutes); err == nil {
+			t.Errorf("設定無效閒置時間 %d 應該產生錯誤", minutes)
+		}
+	}
+
+	invalidSettings := NewDefaultSettings()
+	invalidSettings.SessionTimeout = -5
+	if err := invalidSettings.Validate(); err != ErrInvalidSessionTimeout {
+		t.Errorf("無效的閒置時間應該產生 ErrInvalidSessionTimeout，實際得到：%v", err)
+	}
+
+	// 舊版設定檔沒有工作階段欄位時應該仍然有效
+	tempDir := t.TempDir()
+	legacyPath := filepath.Join(tempDir, "legacy_settings.json")
+	legacyJSON := `{"default_encryption":"aes256","auto_save_interval":5,"default_save_location":"/tmp","biometric_enabled":false,"theme":"auto"}`
+	if err := os.WriteFile(legacyPath, []byte(legacyJSON), 0644); err != nil {
+		t.Fatalf("建立測試檔案失敗：%v", err)
+	}
+	loaded, err := LoadFromFile(legacyPath)
+	if err != nil {
+		t.Fatalf("載入舊版設定檔失敗：%v", err)
+	}
+	if loaded.SessionTimeout != 0 || loaded.LockOnBlur {
+		t.Errorf("舊版設定檔應該不啟用自動鎖定，實際得到：%d, %v", loaded.SessionTimeout, loaded.LockOnBlur)
+	}
+}
+
 // TestSettings_UpdateTheme 測試主題更新功能
 // 驗證有效和無效的主題設定
 func TestSettings_UpdateTheme(t *testing.T) {
@@ -283,6 +327,9 @@ func TestSettings_IsDefault(t *testing.T) {
 		{"修改預設保存位置", func(s *Settings) { s.UpdateDefaultSaveLocation("/new/path") }},
 		{"啟用生物識別", func(s *Settings) { s.SetBiometric(true) }},
 		{"修改主題", func(s *Settings) { s.UpdateTheme("dark") }},
+		{"修改閒置自動鎖定時間", func(s *Settings) { s.UpdateSessionTimeout(30) }},
+		{"啟用失去焦點鎖定", func(s *Settings) { s.SetLockOnBlur(true) }},
+		{"啟用隨機檔名", func(s *Settings) { s.SetObfuscateFilenames(true) }},
 	}
 
 	for _, tc := range testCases {
@@ -0,0 +1,430 @@
+package repositories
+
+import (
+	"encoding/json" // JSON 序列化套件
+	"fmt"           // 格式化輸出套件
+	"os"            // 作業系統介面套件
+	"path/filepath" // 檔案路徑處理套件
+	"sync"          // 同步原語套件
+
+	"mac-notebook-app/internal/models" // 引入資料模型
+)
+
+// 金鑰儲存檔案相關常數
+const (
+	EncryptionStoreFileName = "keys.json" // 金鑰儲存檔案名稱
+	encryptionStoreFileMode = 0600        // 金鑰儲存檔案權限（僅擁有者可讀寫）
+	encryptionStoreDirMode  = 0700        // 金鑰儲存目錄權限
+)
+
+// encryptionStore 代表金鑰儲存檔案的內容
+// []byte 欄位在 JSON 中以 Base64 編碼保存
+type encryptionStore struct {
+	VaultHeader    json.RawMessage   `json:"vault_header,omitempty"`    // 保險庫標頭
+	WrappedKeys    map[string][]byte `json:"wrapped_keys,omitempty"`    // 包裝後的資料金鑰（以金鑰 ID 為鍵）
+	PasswordHashes map[string]string `json:"password_hashes,omitempty"` // 筆記密碼雜湊（以筆記 ID 為鍵）
+	BiometricKeys  map[string][]byte `json:"biometric_keys,omitempty"`  // 生物識別金鑰（以筆記 ID 為鍵）
+	Identities     json.RawMessage   `json:"identities,omitempty"`      // 公鑰身分金鑰庫
+	SigningKeyring json.RawMessage   `json:"signing_keyring,omitempty"` // 簽章金鑰和受信任的簽署者
+	RetryGuard     json.RawMessage   `json:"retry_guard,omitempty"`     // 密碼重試狀態的 HMAC 金鑰和最後寫入的驗證碼
+	AuditHead      json.RawMessage   `json:"audit_head,omitempty"`      // 稽核記錄最後一筆的序號和雜湊（偵測截斷）
+}
+
+// LocalEncryptionRepository 實作 EncryptionRepository 介面
+// 將所有金鑰資料保存在單一 JSON 檔案中，檔案權限為 0600
+// 檔案只包含雜湊值和已被包裝（加密）的金鑰，不會保存任何明文金鑰
+type LocalEncryptionRepository struct {
+	storePath string       // 金鑰儲存檔案的完整路徑
+	mutex     sync.RWMutex // 讀寫鎖，保護檔案的並發存取
+}
+
+// NewLocalEncryptionRepository 建立新的本地金鑰儲存庫實例
+// 參數：
+//   - dir: 金鑰儲存目錄（通常為筆記根目錄下的 .notebook）
+//
+// 回傳：指向新建立的 LocalEncryptionRepository 的指標和可能的錯誤
+//
+// 執行流程：
+// 1. 驗證目錄路徑是否有效
+// 2. 如果目錄不存在，以 0700 權限建立目錄
+// 3. 建立並回傳 LocalEncryptionRepository 實例
+func NewLocalEncryptionRepository(dir string) (*LocalEncryptionRepository, error) {
+	if dir == "" {
+		return nil, models.NewAppError(
+			models.ErrValidationFailed,
+			"金鑰儲存目錄不能為空",
+			"請提供有效的目錄路徑",
+		)
+	}
+
+	cleanDir := filepath.Clean(dir)
+	if err := os.MkdirAll(cleanDir, encryptionStoreDirMode); err != nil {
+		return nil, models.NewAppError(
+			models.ErrPermissionDenied,
+			"無法建立金鑰儲存目錄",
+			fmt.Sprintf("目錄路徑：%s，錯誤：%v", cleanDir, err),
+		)
+	}
+
+	return &LocalEncryptionRepository{
+		storePath: filepath.Join(cleanDir, EncryptionStoreFileName),
+	}, nil
+}
+
+// StorePasswordHash 儲存指定筆記的密碼雜湊
+// 參數：noteID（筆記 ID）、hash（密碼雜湊）
+// 回傳：可能的錯誤
+func (r *LocalEncryptionRepository) StorePasswordHash(noteID, hash string) error {
+	if noteID == "" {
+		return models.NewAppError(models.ErrValidationFailed, "筆記 ID 不能為空", "")
+	}
+
+	return r.update(func(store *encryptionStore) {
+		if store.PasswordHashes == nil {
+			store.PasswordHashes = make(map[string]string)
+		}
+		store.PasswordHashes[noteID] = hash
+	})
+}
+
+// GetPasswordHash 取得指定筆記的密碼雜湊
+// 參數：noteID（筆記 ID）
+// 回傳：密碼雜湊和可能的錯誤
+func (r *LocalEncryptionRepository) GetPasswordHash(noteID string) (string, error) {
+	store, err := r.read()
+	if err != nil {
+		return "", err
+	}
+
+	hash, exists := store.PasswordHashes[noteID]
+	if !exists {
+		return "", models.NewAppError(
+			models.ErrFileNotFound,
+			"找不到筆記的密碼雜湊",
+			fmt.Sprintf("筆記 ID：%s", noteID),
+		)
+	}
+
+	return hash, nil
+}
+
+// StoreBiometricKey 儲存指定筆記的生物識別金鑰
+// 參數：noteID（筆記 ID）、keyData（金鑰資料）
+// 回傳：可能的錯誤
+func (r *LocalEncryptionRepository) StoreBiometricKey(noteID string, keyData []byte) error {
+	if noteID == "" {
+		return models.NewAppError(models.ErrValidationFailed, "筆記 ID 不能為空", "")
+	}
+
+	return r.update(func(store *encryptionStore) {
+		if store.BiometricKeys == nil {
+			store.BiometricKeys = make(map[string][]byte)
+		}
+		store.BiometricKeys[noteID] = keyData
+	})
+}
+
+// GetBiometricKey 取得指定筆記的生物識別金鑰
+// 參數：noteID（筆記 ID）
+// 回傳：金鑰資料和可能的錯誤
+func (r *LocalEncryptionRepository) GetBiometricKey(noteID string) ([]byte, error) {
+	store, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+
+	keyData, exists := store.BiometricKeys[noteID]
+	if !exists {
+		return nil, models.NewAppError(
+			models.ErrFileNotFound,
+			"找不到筆記的生物識別金鑰",
+			fmt.Sprintf("筆記 ID：%s", noteID),
+		)
+	}
+
+	return keyData, nil
+}
+
+// DeleteKeys 刪除指定筆記的所有金鑰資料
+// 參數：noteID（筆記 ID）
+// 回傳：可能的錯誤
+func (r *LocalEncryptionRepository) DeleteKeys(noteID string) error {
+	return r.update(func(store *encryptionStore) {
+		delete(store.PasswordHashes, noteID)
+		delete(store.BiometricKeys, noteID)
+	})
+}
+
+// StoreWrappedKey 儲存以主金鑰包裝後的資料金鑰
+// 參數：keyID（資料金鑰識別碼）、wrappedKey（包裝後的金鑰資料）
+// 回傳：可能的錯誤
+func (r *LocalEncryptionRepository) StoreWrappedKey(keyID string, wrappedKey []byte) error {
+	if keyID == "" {
+		return models.NewAppError(models.ErrValidationFailed, "金鑰 ID 不能為空", "")
+	}
+
+	return r.update(func(store *encryptionStore) {
+		if store.WrappedKeys == nil {
+			store.WrappedKeys = make(map[string][]byte)
+		}
+		store.WrappedKeys[keyID] = wrappedKey
+	})
+}
+
+// GetWrappedKey 取得包裝後的資料金鑰
+// 參數：keyID（資料金鑰識別碼）
+// 回傳：包裝後的金鑰資料和可能的錯誤
+func (r *LocalEncryptionRepository) GetWrappedKey(keyID string) ([]byte, error) {
+	store, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+
+	wrappedKey, exists := store.WrappedKeys[keyID]
+	if !exists {
+		return nil, models.NewAppError(
+			models.ErrFileNotFound,
+			"找不到資料金鑰",
+			fmt.Sprintf("金鑰 ID：%s", keyID),
+		)
+	}
+
+	return wrappedKey, nil
+}
+
+// DeleteWrappedKey 刪除包裝後的資料金鑰
+// 參數：keyID（資料金鑰識別碼）
+// 回傳：可能的錯誤
+func (r *LocalEncryptionRepository) DeleteWrappedKey(keyID string) error {
+	return r.update(func(store *encryptionStore) {
+		delete(store.WrappedKeys, keyID)
+	})
+}
+
+// StoreVaultHeader 儲存保險庫標頭
+// 參數：header（序列化後的保險庫標頭，必須是有效的 JSON）
+// 回傳：可能的錯誤
+func (r *LocalEncryptionRepository) StoreVaultHeader(header []byte) error {
+	if !json.Valid(header) {
+		return models.NewAppError(models.ErrValidationFailed, "保險庫標頭格式無效", "")
+	}
+
+	return r.update(func(store *encryptionStore) {
+		store.VaultHeader = append(json.RawMessage(nil), header...)
+	})
+}
+
+// GetVaultHeader 取得保險庫標頭
+// 回傳：序列化後的保險庫標頭和可能的錯誤（尚未建立時回傳 ErrFileNotFound）
+func (r *LocalEncryptionRepository) GetVaultHeader() ([]byte, error) {
+	store, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(store.VaultHeader) == 0 {
+		return nil, models.NewAppError(models.ErrFileNotFound, "保險庫尚未建立", "")
+	}
+
+	return []byte(store.VaultHeader), nil
+}
+
+// StoreIdentityKeystore 儲存公鑰身分金鑰庫
+// 參數：keystore（序列化後的金鑰庫，必須是有效的 JSON）
+// 回傳：可能的錯誤
+func (r *LocalEncryptionRepository) StoreIdentityKeystore(keystore []byte) error {
+	if !json.Valid(keystore) {
+		return models.NewAppError(models.ErrValidationFailed, "身分金鑰庫格式無效", "")
+	}
+
+	return r.update(func(store *encryptionStore) {
+		store.Identities = append(json.RawMessage(nil), keystore...)
+	})
+}
+
+// GetIdentityKeystore 取得公鑰身分金鑰庫
+// 回傳：序列化後的金鑰庫和可能的錯誤（尚未建立時回傳 ErrFileNotFound）
+func (r *LocalEncryptionRepository) GetIdentityKeystore() ([]byte, error) {
+	store, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(store.Identities) == 0 {
+		return nil, models.NewAppError(models.ErrFileNotFound, "身分金鑰庫尚未建立", "")
+	}
+
+	return []byte(store.Identities), nil
+}
+
+// StoreSigningKeyring 儲存簽章金鑰圈
+// 參數：keyring（序列化後的金鑰圈，必須是有效的 JSON）
+// 回傳：可能的錯誤
+func (r *LocalEncryptionRepository) StoreSigningKeyring(keyring []byte) error {
+	if !json.Valid(keyring) {
+		return models.NewAppError(models.ErrValidationFailed, "簽章金鑰圈格式無效", "")
+	}
+
+	return r.update(func(store *encryptionStore) {
+		store.SigningKeyring = append(json.RawMessage(nil), keyring...)
+	})
+}
+
+// GetSigningKeyring 取得簽章金鑰圈
+// 回傳：序列化後的金鑰圈和可能的錯誤（尚未建立時回傳 ErrFileNotFound）
+func (r *LocalEncryptionRepository) GetSigningKeyring() ([]byte, error) {
+	store, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(store.SigningKeyring) == 0 {
+		return nil, models.NewAppError(models.ErrFileNotFound, "簽章金鑰圈尚未建立", "")
+	}
+
+	return []byte(store.SigningKeyring), nil
+}
+
+// StoreRetryGuard 儲存密碼重試狀態的防竄改資料
+// 參數：guard（序列化後的防竄改資料，必須是有效的 JSON）
+// 回傳：可能的錯誤
+func (r *LocalEncryptionRepository) StoreRetryGuard(guard []byte) error {
+	if !json.Valid(guard) {
+		return models.NewAppError(models.ErrValidationFailed, "重試狀態防竄改資料格式無效", "")
+	}
+
+	return r.update(func(store *encryptionStore) {
+		store.RetryGuard = append(json.RawMessage(nil), guard...)
+	})
+}
+
+// GetRetryGuard 取得密碼重試狀態的防竄改資料
+// 回傳：序列化後的防竄改資料和可能的錯誤（尚未建立時回傳 ErrFileNotFound）
+func (r *LocalEncryptionRepository) GetRetryGuard() ([]byte, error) {
+	store, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(store.RetryGuard) == 0 {
+		return nil, models.NewAppError(models.ErrFileNotFound, "重試狀態防竄改資料尚未建立", "")
+	}
+
+	return []byte(store.RetryGuard), nil
+}
+
+// StoreAuditHead 儲存稽核記錄的錨點
+// 參數：head（序列化後的錨點，必須是有效的 JSON）
+// 回傳：可能的錯誤
+func (r *LocalEncryptionRepository) StoreAuditHead(head []byte) error {
+	if !json.Valid(head) {
+		return models.NewAppError(models.ErrValidationFailed, "稽核記錄錨點格式無效", "")
+	}
+
+	return r.update(func(store *encryptionStore) {
+		store.AuditHead = append(json.RawMessage(nil), head...)
+	})
+}
+
+// GetAuditHead 取得稽核記錄的錨點
+// 回傳：序列化後的錨點和可能的錯誤（尚未建立時回傳 ErrFileNotFound）
+func (r *LocalEncryptionRepository) GetAuditHead() ([]byte, error) {
+	store, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(store.AuditHead) == 0 {
+		return nil, models.NewAppError(models.ErrFileNotFound, "稽核記錄錨點尚未建立", "")
+	}
+
+	return []byte(store.AuditHead), nil
+}
+
+// GetStorePath 取得金鑰儲存檔案的完整路徑
+// 回傳：金鑰儲存檔案路徑
+func (r *LocalEncryptionRepository) GetStorePath() string {
+	return r.storePath
+}
+
+// read 以讀鎖載入金鑰儲存檔案
+// 回傳：金鑰儲存內容和可能的錯誤
+func (r *LocalEncryptionRepository) read() (*encryptionStore, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.load()
+}
+
+// update 以寫鎖載入、修改並寫回金鑰儲存檔案
+// 參數：modify（修改儲存內容的函數）
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 取得寫鎖並載入目前內容
+// 2. 執行修改函數
+// 3. 將內容寫入暫存檔案後以 rename 取代原檔案，避免寫入中斷造成檔案損毀
+func (r *LocalEncryptionRepository) update(modify func(store *encryptionStore)) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	store, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	modify(store)
+
+	data, err := json.Marshal(store)
+	if err != nil {
+		return models.NewAppError(models.ErrSaveFailed, "序列化金鑰資料失敗", err.Error())
+	}
+
+	tempPath := r.storePath + ".tmp"
+	if err := os.WriteFile(tempPath, data, encryptionStoreFileMode); err != nil {
+		return models.NewAppError(
+			models.ErrSaveFailed,
+			"無法寫入金鑰儲存檔案",
+			fmt.Sprintf("檔案路徑：%s，錯誤：%v", tempPath, err),
+		)
+	}
+
+	if err := os.Rename(tempPath, r.storePath); err != nil {
+		os.Remove(tempPath)
+		return models.NewAppError(
+			models.ErrSaveFailed,
+			"無法更新金鑰儲存檔案",
+			fmt.Sprintf("檔案路徑：%s，錯誤：%v", r.storePath, err),
+		)
+	}
+
+	return nil
+}
+
+// load 從磁碟載入金鑰儲存檔案（呼叫者必須持有鎖）
+// 回傳：金鑰儲存內容和可能的錯誤，檔案不存在時回傳空內容
+func (r *LocalEncryptionRepository) load() (*encryptionStore, error) {
+	store := &encryptionStore{}
+
+	data, err := os.ReadFile(r.storePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, models.NewAppError(
+			models.ErrPermissionDenied,
+			"無法讀取金鑰儲存檔案",
+			fmt.Sprintf("檔案路徑：%s，錯誤：%v", r.storePath, err),
+		)
+	}
+
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, models.NewAppError(
+			models.ErrValidationFailed,
+			"金鑰儲存檔案格式無效",
+			err.Error(),
+		)
+	}
+
+	return store, nil
+}
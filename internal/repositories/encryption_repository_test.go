@@ -0,0 +1,251 @@
+package repositories
+
+import (
+	"bytes"         // 位元組比較套件
+	"os"            // 作業系統介面套件
+	"path/filepath" // 檔案路徑處理套件
+	"testing"       // Go 測試套件
+
+	"mac-notebook-app/internal/models" // 引入資料模型
+)
+
+// TestNewLocalEncryptionRepository 測試金鑰儲存庫的建立
+func TestNewLocalEncryptionRepository(t *testing.T) {
+	t.Run("成功建立儲存庫並建立目錄", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), ".notebook")
+
+		repo, err := NewLocalEncryptionRepository(dir)
+		if err != nil {
+			t.Fatalf("建立金鑰儲存庫時發生錯誤：%v", err)
+		}
+
+		if repo.GetStorePath() != filepath.Join(dir, EncryptionStoreFileName) {
+			t.Errorf("儲存路徑不符合預期：%s", repo.GetStorePath())
+		}
+
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("金鑰儲存目錄應該被建立：%v", err)
+		}
+	})
+
+	t.Run("空路徑應該回傳錯誤", func(t *testing.T) {
+		if _, err := NewLocalEncryptionRepository(""); err == nil {
+			t.Error("空路徑應該回傳錯誤")
+		}
+	})
+}
+
+// TestLocalEncryptionRepository_WrappedKeys 測試包裝金鑰的儲存、讀取和刪除
+func TestLocalEncryptionRepository_WrappedKeys(t *testing.T) {
+	repo, err := NewLocalEncryptionRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("建立金鑰儲存庫時發生錯誤：%v", err)
+	}
+
+	wrapped := []byte{0x01, 0x02, 0x03, 0xff}
+	if err := repo.StoreWrappedKey("key-1", wrapped); err != nil {
+		t.Fatalf("儲存包裝金鑰失敗：%v", err)
+	}
+
+	got, err := repo.GetWrappedKey("key-1")
+	if err != nil {
+		t.Fatalf("讀取包裝金鑰失敗：%v", err)
+	}
+	if !bytes.Equal(got, wrapped) {
+		t.Errorf("包裝金鑰內容不符合預期：%v", got)
+	}
+
+	// 新實例應該能從磁碟讀取相同資料
+	reopened, _ := NewLocalEncryptionRepository(filepath.Dir(repo.GetStorePath()))
+	if got, err := reopened.GetWrappedKey("key-1"); err != nil || !bytes.Equal(got, wrapped) {
+		t.Errorf("重新開啟後讀取包裝金鑰失敗：%v", err)
+	}
+
+	if err := repo.DeleteWrappedKey("key-1"); err != nil {
+		t.Fatalf("刪除包裝金鑰失敗：%v", err)
+	}
+
+	_, err = repo.GetWrappedKey("key-1")
+	appErr, ok := err.(*models.AppError)
+	if !ok || appErr.Code != models.ErrFileNotFound {
+		t.Errorf("刪除後應該回傳 ErrFileNotFound，實際：%v", err)
+	}
+
+	if err := repo.StoreWrappedKey("", wrapped); err == nil {
+		t.Error("空的金鑰 ID 應該回傳錯誤")
+	}
+}
+
+// TestLocalEncryptionRepository_VaultHeader 測試保險庫標頭的儲存和讀取
+func TestLocalEncryptionRepository_VaultHeader(t *testing.T) {
+	repo, err := NewLocalEncryptionRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("建立金鑰儲存庫時發生錯誤：%v", err)
+	}
+
+	if _, err := repo.GetVaultHeader(); err == nil {
+		t.Error("尚未建立保險庫時應該回傳錯誤")
+	}
+
+	if err := repo.StoreVaultHeader([]byte("not json")); err == nil {
+		t.Error("無效的 JSON 標頭應該回傳錯誤")
+	}
+
+	header := []byte(`{"version":"1.0"}`)
+	if err := repo.StoreVaultHeader(header); err != nil {
+		t.Fatalf("儲存保險庫標頭失敗：%v", err)
+	}
+
+	got, err := repo.GetVaultHeader()
+	if err != nil {
+		t.Fatalf("讀取保險庫標頭失敗：%v", err)
+	}
+	if !bytes.Equal(got, header) {
+		t.Errorf("保險庫標頭內容不符合預期：%s", got)
+	}
+
+	// 金鑰儲存檔案只允許擁有者存取
+	info, err := os.Stat(repo.GetStorePath())
+	if err != nil {
+		t.Fatalf("無法取得金鑰儲存檔案資訊：%v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("金鑰儲存檔案權限應該為 0600，實際：%o", info.Mode().Perm())
+	}
+}
+
+// TestLocalEncryptionRepository_IdentityKeystore 測試身分金鑰庫的儲存和讀取
+func TestLocalEncryptionRepository_IdentityKeystore(t *testing.T) {
+	repo, err := NewLocalEncryptionRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("建立金鑰儲存庫時發生錯誤：%v", err)
+	}
+
+	if _, err := repo.GetIdentityKeystore(); err == nil {
+		t.Error("尚未建立金鑰庫時應該回傳錯誤")
+	}
+	if err := repo.StoreIdentityKeystore([]byte("{")); err == nil {
+		t.Error("無效的 JSON 金鑰庫應該回傳錯誤")
+	}
+
+	keystore := []byte(`{"identities":[]}`)
+	if err := repo.StoreIdentityKeystore(keystore); err != nil {
+		t.Fatalf("儲存身分金鑰庫失敗：%v", err)
+	}
+	if err := repo.StoreVaultHeader([]byte(`{"version":"1.0"}`)); err != nil {
+		t.Fatalf("儲存保險庫標頭失敗：%v", err)
+	}
+
+	got, err := repo.GetIdentityKeystore()
+	if err != nil || !bytes.Equal(got, keystore) {
+		t.Errorf("身分金鑰庫內容不符合預期：%s, %v", got, err)
+	}
+}
+
+// TestLocalEncryptionRepository_SigningKeyring 測試簽章金鑰圈的儲存和讀取
+func TestLocalEncryptionRepository_SigningKeyring(t *testing.T) {
+	repo, err := NewLocalEncryptionRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("建立金鑰儲存庫時發生錯誤：%v", err)
+	}
+
+	if _, err := repo.GetSigningKeyring(); err == nil {
+		t.Error("尚未建立金鑰圈時應該回傳錯誤")
+	}
+	if err := repo.StoreSigningKeyring([]byte("not json")); err == nil {
+		t.Error("無效的 JSON 金鑰圈應該回傳錯誤")
+	}
+
+	keyring := []byte(`{"trusted":[]}`)
+	if err := repo.StoreSigningKeyring(keyring); err != nil {
+		t.Fatalf("儲存簽章金鑰圈失敗：%v", err)
+	}
+	if err := repo.StoreIdentityKeystore([]byte(`{"identities":[]}`)); err != nil {
+		t.Fatalf("儲存身分金鑰庫失敗：%v", err)
+	}
+
+	got, err := repo.GetSigningKeyring()
+	if err != nil || !bytes.Equal(got, keyring) {
+		t.Errorf("簽章金鑰圈內容不符合預期：%s, %v", got, err)
+	}
+}
+
+// TestLocalEncryptionRepository_RetryGuard 測試密碼重試狀態防竄改資料的儲存和讀取
+func TestLocalEncryptionRepository_RetryGuard(t *testing.T) {
+	repo, err := NewLocalEncryptionRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("建立金鑰儲存庫時發生錯誤：%v", err)
+	}
+
+	if _, err := repo.GetRetryGuard(); err == nil {
+		t.Error("尚未建立防竄改資料時應該回傳錯誤")
+	}
+	if err := repo.StoreRetryGuard([]byte("{")); err == nil {
+		t.Error("無效的 JSON 應該回傳錯誤")
+	}
+
+	guard := []byte(`{"key":"a2V5","mac":""}`)
+	if err := repo.StoreRetryGuard(guard); err != nil {
+		t.Fatalf("儲存防竄改資料失敗：%v", err)
+	}
+	got, err := repo.GetRetryGuard()
+	if err != nil || !bytes.Equal(got, guard) {
+		t.Errorf("防竄改資料內容不符合預期：%s, %v", got, err)
+	}
+}
+
+// TestLocalEncryptionRepository_AuditHead 測試稽核記錄錨點的儲存和讀取
+func TestLocalEncryptionRepository_AuditHead(t *testing.T) {
+	repo, err := NewLocalEncryptionRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("建立金鑰儲存庫時發生錯誤：%v", err)
+	}
+
+	if _, err := repo.GetAuditHead(); err == nil {
+		t.Error("尚未建立錨點時應該回傳錯誤")
+	}
+	if err := repo.StoreAuditHead([]byte("{")); err == nil {
+		t.Error("無效的 JSON 應該回傳錯誤")
+	}
+
+	head := []byte(`{"seq":3,"hash":"abc"}`)
+	if err := repo.StoreAuditHead(head); err != nil {
+		t.Fatalf("儲存錨點失敗：%v", err)
+	}
+	got, err := repo.GetAuditHead()
+	if err != nil || !bytes.Equal(got, head) {
+		t.Errorf("錨點內容不符合預期：%s, %v", got, err)
+	}
+}
+
+// TestLocalEncryptionRepository_NoteKeys 測試筆記密碼雜湊和生物識別金鑰
+func TestLocalEncryptionRepository_NoteKeys(t *testing.T) {
+	repo, err := NewLocalEncryptionRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("建立金鑰儲存庫時發生錯誤：%v", err)
+	}
+
+	if err := repo.StorePasswordHash("note-1", "hash"); err != nil {
+		t.Fatalf("儲存密碼雜湊失敗：%v", err)
+	}
+	if err := repo.StoreBiometricKey("note-1", []byte("bio")); err != nil {
+		t.Fatalf("儲存生物識別金鑰失敗：%v", err)
+	}
+
+	if hash, err := repo.GetPasswordHash("note-1"); err != nil || hash != "hash" {
+		t.Errorf("讀取密碼雜湊失敗：%q, %v", hash, err)
+	}
+	if key, err := repo.GetBiometricKey("note-1"); err != nil || string(key) != "bio" {
+		t.Errorf("讀取生物識別金鑰失敗：%q, %v", key, err)
+	}
+
+	if err := repo.DeleteKeys("note-1"); err != nil {
+		t.Fatalf("刪除筆記金鑰失敗：%v", err)
+	}
+	if _, err := repo.GetPasswordHash("note-1"); err == nil {
+		t.Error("刪除後讀取密碼雜湊應該回傳錯誤")
+	}
+	if _, err := repo.GetBiometricKey("note-1"); err == nil {
+		t.Error("刪除後讀取生物識別金鑰應該回傳錯誤")
+	}
+}
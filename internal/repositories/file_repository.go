@@ -15,8 +15,23 @@ import (
 type LocalFileRepository struct {
 	// 基礎目錄路徑，所有檔案操作都相對於此目錄
 	baseDir string
+	
+	// 一般檔案和加密檔案寫入時使用的權限
+	fileMode          os.FileMode
+	encryptedFileMode os.FileMode
+	
+	// 是否保留上一版內容為 .bak，直到下一次保存成功
+	keepBackup bool
 }
 
+// 檔案寫入相關常數
+const (
+	DefaultFileMode          os.FileMode = 0644    // 一般檔案的預設權限
+	DefaultEncryptedFileMode os.FileMode = 0600    // 加密檔案的預設權限，只有擁有者可以讀寫
+	BackupFileSuffix                     = ".bak"  // 上一版內容的副檔名
+	tempFilePattern                      = ".tmp-" // 寫入中暫存檔名稱的標記
+)
+
 // NewLocalFileRepository 建立新的本地檔案儲存庫實例
 // 參數：
 //   - baseDir: 基礎目錄路徑，用作所有檔案操作的根目錄
@@ -52,10 +67,27 @@ func NewLocalFileRepository(baseDir string) (*LocalFileRepository, error) {
 	}
 	
 	return &LocalFileRepository{
-		baseDir: cleanPath,
+		baseDir:           cleanPath,
+		fileMode:          DefaultFileMode,
+		encryptedFileMode: DefaultEncryptedFileMode,
 	}, nil
 }
 
+// SetFileModes 設定寫入檔案時使用的權限
+// 參數：
+//   - fileMode: 一般檔案的權限
+//   - encryptedFileMode: 加密檔案（.enc 副檔名或經由 WriteEncryptedFile 寫入）的權限
+func (r *LocalFileRepository) SetFileModes(fileMode, encryptedFileMode os.FileMode) {
+	r.fileMode = fileMode.Perm()
+	r.encryptedFileMode = encryptedFileMode.Perm()
+}
+
+// SetKeepBackup 設定保存時是否保留上一版內容
+// 參數：keep（是否保留上一版內容為 .bak）
+func (r *LocalFileRepository) SetKeepBackup(keep bool) {
+	r.keepBackup = keep
+}
+
 // ReadFile 讀取指定路徑的檔案內容
 // 參數：path（檔案路徑，相對於基礎目錄）
 // 回傳：檔案內容的位元組陣列和可能的錯誤
@@ -102,12 +134,37 @@ func (r *LocalFileRepository) ReadFile(path string) ([]byte, error) {
 //   - data: 要寫入的資料
 // 回傳：可能的錯誤
 //
-// 執行流程：
-// 1. 驗證檔案路徑的安全性
-// 2. 建構完整的檔案路徑
-// 3. 確保父目錄存在
-// 4. 寫入檔案內容
+// .enc 副檔名的檔案使用加密檔案權限，其他檔案使用一般檔案權限
 func (r *LocalFileRepository) WriteFile(path string, data []byte) error {
+	mode := r.fileMode
+	if strings.HasSuffix(path, ".enc") {
+		mode = r.encryptedFileMode
+	}
+	return r.writeFile(path, data, mode)
+}
+
+// WriteEncryptedFile 以加密檔案權限寫入檔案
+// 參數：
+//   - path: 檔案路徑（相對於基礎目錄）
+//   - data: 已加密的資料
+// 回傳：可能的錯誤
+func (r *LocalFileRepository) WriteEncryptedFile(path string, data []byte) error {
+	return r.writeFile(path, data, r.encryptedFileMode)
+}
+
+// writeFile 以原子方式將資料寫入檔案
+// 參數：
+//   - path: 檔案路徑（相對於基礎目錄）
+//   - data: 要寫入的資料
+//   - mode: 檔案權限
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 驗證檔案路徑的安全性並確保父目錄存在
+// 2. 將資料寫入同一目錄中的暫存檔並同步到磁碟，失敗時原檔案不受影響
+// 3. 啟用備份時將原檔案保留為 .bak
+// 4. 以暫存檔取代原檔案，並同步目錄讓重新命名也寫入磁碟
+func (r *LocalFileRepository) writeFile(path string, data []byte, mode os.FileMode) error {
 	// 驗證路徑安全性
 	if err := r.validatePath(path); err != nil {
 		return err
@@ -126,8 +183,9 @@ func (r *LocalFileRepository) WriteFile(path string, data []byte) error {
 		)
 	}
 	
-	// 寫入檔案內容
-	if err := os.WriteFile(fullPath, data, 0644); err != nil {
+	// 寫入暫存檔
+	tempPath, err := writeTempFile(parentDir, filepath.Base(fullPath), data, mode)
+	if err != nil {
 		return models.NewAppError(
 			models.ErrSaveFailed,
 			"無法寫入檔案",
@@ -135,9 +193,118 @@ func (r *LocalFileRepository) WriteFile(path string, data []byte) error {
 		)
 	}
 	
+	// 保留上一版內容
+	if r.keepBackup {
+		if err := backupFile(fullPath); err != nil {
+			os.Remove(tempPath)
+			return models.NewAppError(
+				models.ErrSaveFailed,
+				"無法保留上一版檔案",
+				fmt.Sprintf("檔案路徑：%s，錯誤：%v", fullPath, err),
+			)
+		}
+	}
+	
+	// 以暫存檔取代原檔案
+	if err := os.Rename(tempPath, fullPath); err != nil {
+		os.Remove(tempPath)
+		return models.NewAppError(
+			models.ErrSaveFailed,
+			"無法寫入檔案",
+			fmt.Sprintf("檔案路徑：%s，錯誤：%v", fullPath, err),
+		)
+	}
+	
+	if err := syncDirectory(parentDir); err != nil {
+		return models.NewAppError(
+			models.ErrSaveFailed,
+			"無法同步目錄",
+			fmt.Sprintf("目錄路徑：%s，錯誤：%v", parentDir, err),
+		)
+	}
+	
 	return nil
 }
 
+// writeTempFile 將資料寫入目錄中的暫存檔並同步到磁碟
+// 參數：dir（目錄）、name（目標檔名）、data（要寫入的資料）、mode（檔案權限）
+// 回傳：暫存檔的完整路徑和可能的錯誤（失敗時暫存檔已刪除）
+func writeTempFile(dir, name string, data []byte, mode os.FileMode) (string, error) {
+	file, err := os.CreateTemp(dir, "."+name+tempFilePattern+"*")
+	if err != nil {
+		return "", err
+	}
+	tempPath := file.Name()
+	
+	err = file.Chmod(mode)
+	if err == nil {
+		_, err = file.Write(data)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return "", err
+	}
+	return tempPath, nil
+}
+
+// backupFile 將檔案目前的內容保留為 .bak，取代先前的備份
+// 參數：fullPath（檔案的完整路徑）
+// 回傳：可能的錯誤（檔案不存在時不需要備份）
+//
+// 以硬連結建立備份，原檔案在取代前一直存在；檔案系統不支援硬連結時改為複製
+func backupFile(fullPath string) error {
+	info, err := os.Stat(fullPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	
+	backupPath := fullPath + BackupFileSuffix
+	if err := os.Remove(backupPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(fullPath, backupPath); err == nil {
+		return nil
+	}
+	
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return err
+	}
+	tempPath, err := writeTempFile(filepath.Dir(fullPath), filepath.Base(backupPath), data, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	return os.Rename(tempPath, backupPath)
+}
+
+// syncDirectory 將目錄項目的變更同步到磁碟
+// 參數：dir（目錄的完整路徑）
+// 回傳：可能的錯誤
+func syncDirectory(dir string) error {
+	handle, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+	return handle.Sync()
+}
+
+// IsTempFile 檢查檔名是否為寫入中（或寫入中斷後遺留）的暫存檔
+// 參數：name（檔案名稱）
+// 回傳：是否為暫存檔
+func IsTempFile(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.Contains(name, tempFilePattern)
+}
+
 // FileExists 檢查指定路徑的檔案是否存在
 // 參數：path（檔案路徑，相對於基礎目錄）
 // 回傳：檔案是否存在
@@ -190,6 +357,15 @@ func (r *LocalFileRepository) DeleteFile(path string) error {
 		)
 	}
 	
+	// 一併刪除上一版內容，避免已刪除的筆記留在備份中
+	if err := os.Remove(fullPath + BackupFileSuffix); err != nil && !os.IsNotExist(err) {
+		return models.NewAppError(
+			models.ErrPermissionDenied,
+			"無法刪除備份檔案",
+			fmt.Sprintf("檔案路徑：%s，錯誤：%v", fullPath+BackupFileSuffix, err),
+		)
+	}
+	
 	return nil
 }
 
@@ -265,6 +441,11 @@ func (r *LocalFileRepository) ListDirectory(path string) ([]*models.FileInfo, er
 	
 	// 遍歷目錄項目，為每個項目建立 FileInfo
 	for _, entry := range entries {
+		// 略過寫入中斷後遺留的暫存檔
+		if IsTempFile(entry.Name()) {
+			continue
+		}
+		
 		// 取得詳細的檔案資訊
 		info, err := entry.Info()
 		if err != nil {
@@ -471,6 +652,11 @@ func (r *LocalFileRepository) WalkDirectory(startPath string, walkFunc func(*mod
 			return err
 		}
 		
+		// 略過寫入中斷後遺留的暫存檔
+		if !d.IsDir() && IsTempFile(d.Name()) {
+			return nil
+		}
+		
 		// 取得相對於基礎目錄的路徑
 		relPath, err := filepath.Rel(r.baseDir, path)
 		if err != nil {
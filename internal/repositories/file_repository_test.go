@@ -661,4 +661,84 @@ func BenchmarkFileOperations(b *testing.B) {
 			repo.FileExists(testFile)
 		}
 	})
-}
\ No newline at end of file
+}
+
+// TestAtomicWriteFile 測試寫入以暫存檔取代原檔案、檔案權限設定和上一版備份
+func TestAtomicWriteFile(t *testing.T) {
+	// 測試案例：一般檔案和加密檔案使用不同的權限
+	t.Run("檔案權限", func(t *testing.T) {
+		repo, _ := NewLocalFileRepository(t.TempDir())
+		
+		repo.WriteFile("plain.md", []byte("# 明文"))
+		repo.WriteFile("secret.md.enc", []byte("密文"))
+		repo.WriteEncryptedFile("folder/photo.png", []byte("密文"))
+		
+		expected := map[string]os.FileMode{
+			"plain.md":         DefaultFileMode,
+			"secret.md.enc":    DefaultEncryptedFileMode,
+			"folder/photo.png": DefaultEncryptedFileMode,
+		}
+		for name, mode := range expected {
+			info, err := os.Stat(filepath.Join(repo.GetBaseDirectory(), name))
+			if err != nil {
+				t.Fatalf("檔案 %s 應該存在：%v", name, err)
+			}
+			if info.Mode().Perm() != mode {
+				t.Errorf("檔案 %s 的權限應為 %o，實際為 %o", name, mode, info.Mode().Perm())
+			}
+		}
+		
+		repo.SetFileModes(0640, 0400)
+		repo.WriteFile("plain.md", []byte("# 修改"))
+		if info, _ := os.Stat(filepath.Join(repo.GetBaseDirectory(), "plain.md")); info.Mode().Perm() != 0640 {
+			t.Errorf("覆寫時應該套用新的權限，實際為 %o", info.Mode().Perm())
+		}
+	})
+	
+	// 測試案例：寫入後不留下暫存檔，遺留的暫存檔不會被列出
+	t.Run("暫存檔", func(t *testing.T) {
+		repo, _ := NewLocalFileRepository(t.TempDir())
+		repo.WriteFile("note.md", []byte("# 筆記"))
+		
+		entries, _ := os.ReadDir(repo.GetBaseDirectory())
+		if len(entries) != 1 {
+			t.Errorf("寫入後應該只有目標檔案，實際有 %d 個項目", len(entries))
+		}
+		
+		// 模擬寫入中斷後遺留的暫存檔
+		os.WriteFile(filepath.Join(repo.GetBaseDirectory(), ".note.md.tmp-123"), []byte("# 筆"), 0600)
+		infos, _ := repo.ListDirectory(".")
+		if len(infos) != 1 || infos[0].Name != "note.md" {
+			t.Errorf("列表不應包含暫存檔：%v", infos)
+		}
+		if data, _ := repo.ReadFile("note.md"); string(data) != "# 筆記" {
+			t.Errorf("原檔案內容不應受影響：%q", data)
+		}
+	})
+	
+	// 測試案例：保留上一版內容，直到下一次保存成功
+	t.Run("上一版備份", func(t *testing.T) {
+		repo, _ := NewLocalFileRepository(t.TempDir())
+		repo.WriteFile("note.md", []byte("第一版"))
+		if repo.FileExists("note.md" + BackupFileSuffix) {
+			t.Error("未啟用備份時不應產生 .bak")
+		}
+		
+		repo.SetKeepBackup(true)
+		repo.WriteFile("note.md", []byte("第二版"))
+		repo.WriteFile("note.md", []byte("第三版"))
+		if data, _ := repo.ReadFile("note.md"); string(data) != "第三版" {
+			t.Errorf("檔案應為最新內容：%q", data)
+		}
+		if data, _ := repo.ReadFile("note.md" + BackupFileSuffix); string(data) != "第二版" {
+			t.Errorf(".bak 應為上一版內容：%q", data)
+		}
+		
+		if err := repo.DeleteFile("note.md"); err != nil {
+			t.Fatalf("刪除檔案失敗：%v", err)
+		}
+		if repo.FileExists("note.md" + BackupFileSuffix) {
+			t.Error("刪除檔案時應該一併刪除 .bak")
+		}
+	})
+}
@@ -61,7 +61,7 @@ type SettingsRepository interface {
 }
 
 // EncryptionRepository 定義加密金鑰管理的介面
-// 負責處理密碼雜湊和生物識別金鑰的安全儲存和檢索
+// 負責處理密碼雜湊、生物識別金鑰、保險庫標頭、包裝後資料金鑰、身分金鑰庫和簽章金鑰圈的安全儲存和檢索
 type EncryptionRepository interface {
 	// StorePasswordHash 儲存指定筆記的密碼雜湊
 	// 參數：noteID（筆記 ID）、hash（密碼雜湊）
@@ -87,4 +87,64 @@ type EncryptionRepository interface {
 	// 參數：noteID（筆記 ID）
 	// 回傳：可能的錯誤
 	DeleteKeys(noteID string) error
-}
\ No newline at end of file
+	
+	// StoreWrappedKey 儲存以主金鑰包裝後的資料金鑰
+	// 參數：keyID（資料金鑰識別碼）、wrappedKey（包裝後的金鑰資料）
+	// 回傳：可能的錯誤
+	StoreWrappedKey(keyID string, wrappedKey []byte) error
+	
+	// GetWrappedKey 取得包裝後的資料金鑰
+	// 參數：keyID（資料金鑰識別碼）
+	// 回傳：包裝後的金鑰資料和可能的錯誤
+	GetWrappedKey(keyID string) ([]byte, error)
+	
+	// DeleteWrappedKey 刪除包裝後的資料金鑰
+	// 參數：keyID（資料金鑰識別碼）
+	// 回傳：可能的錯誤
+	DeleteWrappedKey(keyID string) error
+	
+	// StoreVaultHeader 儲存保險庫標頭（KDF 參數和包裝後的主金鑰）
+	// 參數：header（序列化後的保險庫標頭）
+	// 回傳：可能的錯誤
+	StoreVaultHeader(header []byte) error
+	
+	// GetVaultHeader 取得保險庫標頭
+	// 回傳：序列化後的保險庫標頭和可能的錯誤（尚未建立時回傳 ErrFileNotFound）
+	GetVaultHeader() ([]byte, error)
+	
+	// StoreIdentityKeystore 儲存公鑰身分金鑰庫（公鑰和包裝後的私鑰）
+	// 參數：keystore（序列化後的金鑰庫）
+	// 回傳：可能的錯誤
+	StoreIdentityKeystore(keystore []byte) error
+	
+	// GetIdentityKeystore 取得公鑰身分金鑰庫
+	// 回傳：序列化後的金鑰庫和可能的錯誤（尚未建立時回傳 ErrFileNotFound）
+	GetIdentityKeystore() ([]byte, error)
+	
+	// StoreSigningKeyring 儲存簽章金鑰圈（本機簽章私鑰和受信任的簽署者公鑰）
+	// 參數：keyring（序列化後的金鑰圈）
+	// 回傳：可能的錯誤
+	StoreSigningKeyring(keyring []byte) error
+	
+	// GetSigningKeyring 取得簽章金鑰圈
+	// 回傳：序列化後的金鑰圈和可能的錯誤（尚未建立時回傳 ErrFileNotFound）
+	GetSigningKeyring() ([]byte, error)
+	
+	// StoreRetryGuard 儲存密碼重試狀態的防竄改資料（HMAC 金鑰和最後寫入的驗證碼）
+	// 參數：guard（序列化後的防竄改資料）
+	// 回傳：可能的錯誤
+	StoreRetryGuard(guard []byte) error
+	
+	// GetRetryGuard 取得密碼重試狀態的防竄改資料
+	// 回傳：序列化後的防竄改資料和可能的錯誤（尚未建立時回傳 ErrFileNotFound）
+	GetRetryGuard() ([]byte, error)
+	
+	// StoreAuditHead 儲存稽核記錄的錨點（最後一筆的序號和雜湊），用於偵測記錄被截斷
+	// 參數：head（序列化後的錨點）
+	// 回傳：可能的錯誤
+	StoreAuditHead(head []byte) error
+	
+	// GetAuditHead 取得稽核記錄的錨點
+	// 回傳：序列化後的錨點和可能的錯誤（尚未建立時回傳 ErrFileNotFound）
+	GetAuditHead() ([]byte, error)
+}
@@ -0,0 +1,407 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含安全稽核記錄服務，記錄解鎖、啟用或停用加密、解密匯出、分享加密筆記和重新加密等事件。
+// 每筆記錄包含前一筆的雜湊形成雜湊鏈，最後一筆的序號和雜湊另外保存在金鑰庫，
+// 修改、刪除或截斷記錄都能在驗證時發現
+package services
+
+import (
+	"bytes"         // 位元組處理
+	"crypto/sha256" // SHA-256 雜湊演算法
+	"encoding/hex"  // 雜湊值的十六進位表示
+	"encoding/json" // JSON 序列化
+	"errors"        // 錯誤處理
+	"fmt"           // 格式化輸出
+	"log"           // 日誌記錄
+	"os"            // 取得主機名稱
+	"os/user"       // 取得目前使用者
+	"path/filepath" // 檔案路徑處理
+	"sync"          // 同步原語
+	"time"          // 時間處理
+
+	"mac-notebook-app/internal/models"       // 引入資料模型
+	"mac-notebook-app/internal/repositories" // 引入資料存取層
+)
+
+// AuditLogPath 稽核記錄檔的位置（相對於筆記本根目錄），每行一筆 JSON 記錄
+var AuditLogPath = filepath.Join(NotebookMetaDir, "audit.log")
+
+// AuditEventType 定義稽核事件的類型
+type AuditEventType string
+
+// 稽核事件類型
+const (
+	AuditEventUnlock            AuditEventType = "unlock"             // 以密碼解鎖保險庫、身分金鑰庫或密碼加密的筆記
+	AuditEventEnableEncryption  AuditEventType = "encryption.enable"  // 為筆記啟用加密
+	AuditEventDisableEncryption AuditEventType = "encryption.disable" // 為筆記停用加密
+	AuditEventDecryptExport     AuditEventType = "export.decrypt"     // 以明文匯出加密筆記
+	AuditEventShareEncrypted    AuditEventType = "share.encrypted"    // 分享加密筆記
+	AuditEventRekey             AuditEventType = "rekey"              // 批次重新加密筆記本
+	AuditEventRecoverySetup     AuditEventType = "recovery.setup"     // 產生保險庫復原金鑰
+	AuditEventRecover           AuditEventType = "recovery.reset"     // 以復原金鑰重設保險庫密碼
+)
+
+// String 回傳稽核事件類型的顯示名稱
+// 回傳：事件類型的中文名稱
+func (t AuditEventType) String() string {
+	switch t {
+	case AuditEventUnlock:
+		return "解鎖"
+	case AuditEventEnableEncryption:
+		return "啟用加密"
+	case AuditEventDisableEncryption:
+		return "停用加密"
+	case AuditEventDecryptExport:
+		return "解密匯出"
+	case AuditEventShareEncrypted:
+		return "分享加密筆記"
+	case AuditEventRekey:
+		return "重新加密"
+	case AuditEventRecoverySetup:
+		return "產生復原金鑰"
+	case AuditEventRecover:
+		return "以復原金鑰重設密碼"
+	default:
+		return string(t)
+	}
+}
+
+// AuditEntry 代表一筆稽核記錄
+type AuditEntry struct {
+	Seq      int64          `json:"seq"`              // 序號（從 1 開始連續遞增）
+	Time     time.Time      `json:"time"`             // 發生時間
+	Event    AuditEventType `json:"event"`            // 事件類型
+	Target   string         `json:"target,omitempty"` // 事件對象（筆記路徑、"vault" 或 "identity-keystore"）
+	Success  bool           `json:"success"`          // 是否成功
+	Detail   string         `json:"detail,omitempty"` // 補充說明（失敗原因、匯出路徑等）
+	Actor    string         `json:"actor"`            // 執行者（使用者@主機）
+	PrevHash string         `json:"prev_hash"`        // 前一筆記錄的雜湊（第一筆為空字串）
+	Hash     string         `json:"hash"`             // 本筆記錄的雜湊（不含此欄位計算）
+}
+
+// AuditVerifyResult 代表稽核記錄的驗證結果
+type AuditVerifyResult struct {
+	Valid      bool      `json:"valid"`       // 雜湊鏈是否完整
+	Entries    int       `json:"entries"`     // 記錄筆數
+	BrokenAt   int       `json:"broken_at"`   // 第一個無效記錄的行號（從 1 開始，完整時為 0）
+	Truncated  bool      `json:"truncated"`   // 記錄是否被截斷（少於錨點記錄的筆數）
+	Anchored   bool      `json:"anchored"`    // 是否有錨點可以檢查截斷
+	Problems   []string  `json:"problems"`    // 發現的問題
+	VerifiedAt time.Time `json:"verified_at"` // 驗證時間
+}
+
+// AuditService 定義安全稽核記錄服務的介面
+type AuditService interface {
+	// Record 新增一筆稽核記錄
+	// 參數：event（事件類型）、target（事件對象）、success（是否成功）、detail（補充說明）
+	// 回傳：可能的錯誤
+	Record(event AuditEventType, target string, success bool, detail string) error
+
+	// Entries 讀取所有稽核記錄（無法解析的行會略過）
+	// 回傳：稽核記錄列表（由舊到新）和可能的錯誤
+	Entries() ([]AuditEntry, error)
+
+	// Verify 驗證稽核記錄的雜湊鏈和錨點
+	// 回傳：驗證結果和可能的錯誤（無法讀取記錄檔時）
+	Verify() (*AuditVerifyResult, error)
+}
+
+// auditHead 代表保存在金鑰庫的錨點
+type auditHead struct {
+	Seq  int64  `json:"seq"`  // 最後一筆記錄的序號
+	Hash string `json:"hash"` // 最後一筆記錄的雜湊
+}
+
+// auditService 實作 AuditService 介面
+type auditService struct {
+	fileRepo repositories.FileRepository       // 筆記本檔案儲存庫（保存記錄檔）
+	keyRepo  repositories.EncryptionRepository // 金鑰儲存庫（保存錨點，可為 nil）
+	actor    string                            // 執行者名稱
+	mutex    sync.Mutex                        // 保護記錄寫入順序
+}
+
+// NewAuditService 建立新的安全稽核記錄服務實例
+// 參數：
+//   - fileRepo: 筆記本檔案儲存庫，記錄檔保存在其 .notebook 目錄
+//   - keyRepo: 金鑰儲存庫，保存最後一筆記錄的錨點（nil 表示無法偵測截斷）
+//
+// 回傳：AuditService 介面實例
+func NewAuditService(fileRepo repositories.FileRepository, keyRepo repositories.EncryptionRepository) AuditService {
+	return &auditService{
+		fileRepo: fileRepo,
+		keyRepo:  keyRepo,
+		actor:    currentAuditActor(),
+	}
+}
+
+// Record 新增一筆稽核記錄
+// 參數：event（事件類型）、target（事件對象）、success（是否成功）、detail（補充說明）
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 讀取記錄檔，以最後一筆記錄（無法解析時以錨點）作為雜湊鏈的前一筆
+// 2. 計算新記錄的雜湊並附加到記錄檔
+// 3. 更新金鑰庫中的錨點
+func (s *auditService) Record(event AuditEventType, target string, success bool, detail string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, err := s.readLog()
+	if err != nil {
+		return err
+	}
+
+	prev := s.lastHead(data)
+	entry := AuditEntry{
+		Seq:      prev.Seq + 1,
+		Time:     time.Now(),
+		Event:    event,
+		Target:   target,
+		Success:  success,
+		Detail:   detail,
+		Actor:    s.actor,
+		PrevHash: prev.Hash,
+	}
+	entry.Hash = computeAuditHash(&entry)
+
+	line, err := json.Marshal(&entry)
+	if err != nil {
+		return fmt.Errorf("序列化稽核記錄失敗: %w", err)
+	}
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	data = append(data, line...)
+	data = append(data, '\n')
+
+	if err := s.fileRepo.WriteFile(AuditLogPath, data); err != nil {
+		return fmt.Errorf("寫入稽核記錄失敗: %w", err)
+	}
+
+	// 先寫入記錄再更新錨點，中途當機時記錄會比錨點多一筆，驗證時仍視為完整
+	return s.storeHead(&auditHead{Seq: entry.Seq, Hash: entry.Hash})
+}
+
+// Entries 讀取所有稽核記錄
+// 回傳：稽核記錄列表和可能的錯誤
+func (s *auditService) Entries() ([]AuditEntry, error) {
+	s.mutex.Lock()
+	data, err := s.readLog()
+	s.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]AuditEntry, 0)
+	for _, line := range splitAuditLines(data) {
+		var entry AuditEntry
+		if err := json.Unmarshal(line, &entry); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// Verify 驗證稽核記錄的雜湊鏈和錨點
+// 回傳：驗證結果和可能的錯誤
+//
+// 執行流程：
+// 1. 逐行檢查格式、序號連續、前一筆雜湊和本筆雜湊
+// 2. 有錨點時確認錨點記錄的那一筆仍存在且雜湊相同（少於錨點筆數表示被截斷）
+func (s *auditService) Verify() (*AuditVerifyResult, error) {
+	s.mutex.Lock()
+	data, err := s.readLog()
+	head, headErr := s.loadHead()
+	s.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AuditVerifyResult{
+		Problems:   make([]string, 0),
+		VerifiedAt: time.Now(),
+	}
+	fail := func(lineNo int, format string, args ...interface{}) {
+		if result.BrokenAt == 0 {
+			result.BrokenAt = lineNo
+		}
+		result.Problems = append(result.Problems, fmt.Sprintf("第 %d 行："+format, append([]interface{}{lineNo}, args...)...))
+	}
+
+	hashes := make(map[int64]string)
+	var prev auditHead
+	for i, line := range splitAuditLines(data) {
+		lineNo := i + 1
+		result.Entries++
+
+		var entry AuditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			fail(lineNo, "格式無效")
+			continue
+		}
+		if entry.Seq != prev.Seq+1 {
+			fail(lineNo, "序號應該為 %d，實際為 %d", prev.Seq+1, entry.Seq)
+		}
+		if entry.PrevHash != prev.Hash {
+			fail(lineNo, "與前一筆記錄的雜湊不符")
+		}
+		if computeAuditHash(&entry) != entry.Hash {
+			fail(lineNo, "內容已遭修改")
+		}
+
+		hashes[entry.Seq] = entry.Hash
+		prev = auditHead{Seq: entry.Seq, Hash: entry.Hash}
+	}
+
+	switch {
+	case headErr != nil:
+		// 沒有錨點時只能檢查雜湊鏈
+	case head.Seq > prev.Seq:
+		result.Anchored = true
+		result.Truncated = true
+		result.Problems = append(result.Problems, fmt.Sprintf("記錄已被截斷：應該至少有 %d 筆，實際最後一筆為 %d", head.Seq, prev.Seq))
+	default:
+		result.Anchored = true
+		if hashes[head.Seq] != head.Hash {
+			result.Problems = append(result.Problems, fmt.Sprintf("第 %d 筆記錄與金鑰庫中的錨點不符", head.Seq))
+		}
+	}
+
+	result.Valid = len(result.Problems) == 0
+	return result, nil
+}
+
+// readLog 讀取記錄檔內容
+// 回傳：記錄檔內容（尚未建立時為 nil）和可能的錯誤
+func (s *auditService) readLog() ([]byte, error) {
+	if !s.fileRepo.FileExists(AuditLogPath) {
+		return nil, nil
+	}
+	data, err := s.fileRepo.ReadFile(AuditLogPath)
+	if err != nil {
+		return nil, fmt.Errorf("讀取稽核記錄失敗: %w", err)
+	}
+	return data, nil
+}
+
+// lastHead 取得雜湊鏈的最後一筆
+// 參數：data（記錄檔內容）
+// 回傳：最後一筆記錄的序號和雜湊
+//
+// 最後一行無法解析時改用錨點，新記錄仍接在有效的雜湊鏈之後，損壞的行在驗證時會被發現
+func (s *auditService) lastHead(data []byte) auditHead {
+	lines := splitAuditLines(data)
+	if len(lines) > 0 {
+		var entry AuditEntry
+		if err := json.Unmarshal(lines[len(lines)-1], &entry); err == nil {
+			return auditHead{Seq: entry.Seq, Hash: entry.Hash}
+		}
+	}
+	if head, err := s.loadHead(); err == nil {
+		return *head
+	}
+	return auditHead{Seq: int64(len(lines))}
+}
+
+// loadHead 從金鑰庫載入錨點
+// 回傳：錨點和可能的錯誤（沒有金鑰庫或尚未建立時回傳錯誤）
+func (s *auditService) loadHead() (*auditHead, error) {
+	if s.keyRepo == nil {
+		return nil, errors.New("未設定金鑰庫")
+	}
+	data, err := s.keyRepo.GetAuditHead()
+	if err != nil {
+		return nil, err
+	}
+	var head auditHead
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, fmt.Errorf("解析稽核記錄錨點失敗: %w", err)
+	}
+	return &head, nil
+}
+
+// storeHead 將錨點寫入金鑰庫
+// 參數：head（最後一筆記錄的序號和雜湊）
+// 回傳：可能的錯誤
+func (s *auditService) storeHead(head *auditHead) error {
+	if s.keyRepo == nil {
+		return nil
+	}
+	data, err := json.Marshal(head)
+	if err != nil {
+		return fmt.Errorf("序列化稽核記錄錨點失敗: %w", err)
+	}
+	if err := s.keyRepo.StoreAuditHead(data); err != nil {
+		return fmt.Errorf("儲存稽核記錄錨點失敗: %w", err)
+	}
+	return nil
+}
+
+// computeAuditHash 計算稽核記錄的雜湊（Hash 欄位不參與計算）
+// 參數：entry（稽核記錄）
+// 回傳：十六進位表示的 SHA-256 雜湊
+func computeAuditHash(entry *AuditEntry) string {
+	copied := *entry
+	copied.Hash = ""
+	data, _ := json.Marshal(&copied)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// splitAuditLines 將記錄檔切分為非空白的行
+// 參數：data（記錄檔內容）
+// 回傳：每一行的內容
+func splitAuditLines(data []byte) [][]byte {
+	lines := make([][]byte, 0)
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// currentAuditActor 取得目前的使用者和主機名稱
+// 回傳：「使用者@主機」格式的執行者名稱
+func currentAuditActor() string {
+	name := os.Getenv("USER")
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		name = current.Username
+	}
+	if name == "" {
+		name = "unknown"
+	}
+	if host, err := os.Hostname(); err == nil && host != "" {
+		return name + "@" + host
+	}
+	return name
+}
+
+// recordAudit 記錄稽核事件，audit 為 nil 時不做任何事
+// 參數：audit（稽核記錄服務）、event（事件類型）、target（事件對象）、err（操作結果）、detail（補充說明）
+//
+// 寫入失敗只記錄到應用程式日誌，不影響原本的操作
+func recordAudit(audit AuditService, event AuditEventType, target string, err error, detail string) {
+	if audit == nil {
+		return
+	}
+	if err != nil {
+		if detail == "" {
+			detail = err.Error()
+		} else {
+			detail += "：" + err.Error()
+		}
+	}
+	if recordErr := audit.Record(event, target, err == nil, detail); recordErr != nil {
+		log.Printf("寫入稽核記錄失敗: %v", recordErr)
+	}
+}
+
+// auditNoteTarget 取得稽核記錄中識別筆記的字串
+// 參數：note（筆記）
+// 回傳：筆記檔案路徑（尚未保存時為筆記標題）
+func auditNoteTarget(note *models.Note) string {
+	if note.FilePath != "" {
+		return note.FilePath
+	}
+	return note.Title
+}
@@ -0,0 +1,190 @@
+// Package services 提供安全稽核記錄服務的單元測試
+// 測試雜湊鏈驗證、修改和截斷偵測，以及解鎖、匯出和重新加密時寫入的稽核事件
+package services
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"mac-notebook-app/internal/models"
+	"mac-notebook-app/internal/repositories"
+)
+
+// createTestAuditService 建立使用模擬檔案存取和暫存金鑰儲存庫的稽核記錄服務
+func createTestAuditService(t *testing.T) (AuditService, *mockFileRepository) {
+	fileRepo, keyRepo := createTestRetryRepos(t)
+	return NewAuditService(fileRepo, keyRepo), fileRepo
+}
+
+// recordTestAuditEvents 寫入三筆測試用的稽核事件
+func recordTestAuditEvents(t *testing.T, audit AuditService) {
+	events := []AuditEventType{AuditEventUnlock, AuditEventEnableEncryption, AuditEventDecryptExport}
+	for i, event := range events {
+		if err := audit.Record(event, "secret.md.enc", i != 0, ""); err != nil {
+			t.Fatalf("寫入稽核記錄失敗: %v", err)
+		}
+	}
+}
+
+// TestAuditServiceHashChain 測試記錄形成雜湊鏈且驗證通過
+func TestAuditServiceHashChain(t *testing.T) {
+	audit, _ := createTestAuditService(t)
+
+	if result, err := audit.Verify(); err != nil || !result.Valid || result.Entries != 0 {
+		t.Fatalf("沒有記錄時應該驗證通過: %+v, %v", result, err)
+	}
+
+	recordTestAuditEvents(t, audit)
+
+	entries, err := audit.Entries()
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("應該有 3 筆記錄: %d, %v", len(entries), err)
+	}
+	for i, entry := range entries {
+		if entry.Seq != int64(i+1) || entry.Actor == "" || entry.Hash == "" {
+			t.Errorf("第 %d 筆記錄欄位不完整: %+v", i+1, entry)
+		}
+		if i > 0 && entry.PrevHash != entries[i-1].Hash {
+			t.Errorf("第 %d 筆記錄應該包含前一筆的雜湊", i+1)
+		}
+	}
+	if entries[0].Success || !entries[1].Success {
+		t.Error("記錄的成功狀態不符合預期")
+	}
+
+	result, err := audit.Verify()
+	if err != nil || !result.Valid || !result.Anchored || result.Entries != 3 {
+		t.Errorf("完整的記錄應該驗證通過: %+v, %v", result, err)
+	}
+}
+
+// TestAuditServiceTamperDetection 測試修改、刪除中間的記錄和截斷記錄都會被偵測
+func TestAuditServiceTamperDetection(t *testing.T) {
+	tests := []struct {
+		name      string
+		tamper    func(lines [][]byte) [][]byte
+		brokenAt  int
+		truncated bool
+	}{
+		{"修改記錄內容", func(lines [][]byte) [][]byte {
+			lines[1] = bytes.Replace(lines[1], []byte(`"success":true`), []byte(`"success":false`), 1)
+			return lines
+		}, 2, false},
+		{"刪除中間的記錄", func(lines [][]byte) [][]byte {
+			return append(lines[:1], lines[2:]...)
+		}, 2, false},
+		{"截斷最後一筆", func(lines [][]byte) [][]byte {
+			return lines[:2]
+		}, 0, true},
+		{"清空記錄", func(lines [][]byte) [][]byte {
+			return nil
+		}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit, fileRepo := createTestAuditService(t)
+			recordTestAuditEvents(t, audit)
+
+			lines := tt.tamper(splitAuditLines(fileRepo.files[AuditLogPath]))
+			fileRepo.WriteFile(AuditLogPath, append(bytes.Join(lines, []byte("\n")), '\n'))
+
+			result, err := audit.Verify()
+			if err != nil {
+				t.Fatalf("驗證失敗: %v", err)
+			}
+			if result.Valid || len(result.Problems) == 0 {
+				t.Errorf("被竄改的記錄不應驗證通過: %+v", result)
+			}
+			if result.BrokenAt != tt.brokenAt || result.Truncated != tt.truncated {
+				t.Errorf("驗證結果不符合預期: %+v", result)
+			}
+		})
+	}
+}
+
+// TestAuditServiceWithoutAnchor 測試沒有金鑰庫時仍能檢查雜湊鏈，並在損壞的記錄之後繼續寫入
+func TestAuditServiceWithoutAnchor(t *testing.T) {
+	fileRepo := newMockFileRepository()
+	audit := NewAuditService(fileRepo, nil)
+	recordTestAuditEvents(t, audit)
+
+	if result, _ := audit.Verify(); !result.Valid || result.Anchored {
+		t.Errorf("沒有錨點時應該只檢查雜湊鏈: %+v", result)
+	}
+
+	fileRepo.WriteFile(AuditLogPath, append(fileRepo.files[AuditLogPath], []byte("{損壞的行\n")...))
+	if err := audit.Record(AuditEventRekey, "", true, ""); err != nil {
+		t.Fatalf("損壞的記錄之後應該仍能寫入: %v", err)
+	}
+	if result, _ := audit.Verify(); result.Valid || result.BrokenAt != 4 {
+		t.Errorf("損壞的行應該被偵測: %+v", result)
+	}
+}
+
+// TestAuditServiceSecurityEvents 測試解鎖、啟用加密、解密匯出和重新加密時寫入稽核事件
+func TestAuditServiceSecurityEvents(t *testing.T) {
+	audit, _ := createTestAuditService(t)
+
+	// 保險庫解鎖成功和失敗
+	vault, _ := createTestVaultService(t)
+	vault.SetAuditService(audit)
+	vault.Initialize("Password123!")
+	vault.Lock()
+	vault.Unlock("WrongPassword!")
+	vault.Unlock("Password123!")
+
+	// 啟用加密並以明文匯出
+	editor, _ := createTestEditorService()
+	editor.SetOptions(EditorOptions{Audit: audit})
+	note, _ := editor.CreateNote("機密", "內容")
+	note.FilePath = "secret.md"
+	if err := editor.(*editorService).EnableEncryption(note.ID, "Password123!", AlgorithmAES256, false); err != nil {
+		t.Fatalf("啟用加密失敗: %v", err)
+	}
+
+	exportSvc := NewExportService(&mockExportEditorService{options: EditorOptions{Audit: audit}})
+	encrypted := &models.Note{Title: "機密", Content: "內容", FilePath: "secret.md.enc", IsEncrypted: true}
+	if err := exportSvc.ExportToHTML(encrypted, filepath.Join(t.TempDir(), "secret.html"), nil); err != nil {
+		t.Fatalf("匯出失敗: %v", err)
+	}
+	exportSvc.ExportToHTML(&models.Note{Title: "公開", Content: "內容"}, filepath.Join(t.TempDir(), "public.html"), nil)
+
+	// 重新加密
+	fileRepo, err := repositories.NewLocalFileRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("建立檔案儲存庫失敗: %v", err)
+	}
+	rekey := NewRekeyService(fileRepo, NewEncryptionService(), nil)
+	rekey.SetAuditService(audit)
+	rekey.RekeyNotebook("", RekeyOptions{OldPassword: "Password123!", NewPassword: "NewPassword456!"})
+
+	entries, _ := audit.Entries()
+	expected := []struct {
+		event   AuditEventType
+		target  string
+		success bool
+	}{
+		{AuditEventUnlock, VaultRetryIdentifier, false},
+		{AuditEventUnlock, VaultRetryIdentifier, true},
+		{AuditEventEnableEncryption, "secret.md.enc", true},
+		{AuditEventDecryptExport, "secret.md.enc", true},
+		{AuditEventRekey, "", true},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("應該有 %d 筆稽核記錄（未加密筆記的匯出不記錄），實際為 %d: %+v", len(expected), len(entries), entries)
+	}
+	for i, want := range expected {
+		got := entries[i]
+		if got.Event != want.event || got.Target != want.target || got.Success != want.success {
+			t.Errorf("第 %d 筆記錄應該為 %s %s %v，實際為 %s %s %v", i+1, want.event, want.target, want.success, got.Event, got.Target, got.Success)
+		}
+	}
+	if entries[0].Detail == "" {
+		t.Error("失敗的記錄應該包含失敗原因")
+	}
+	if result, _ := audit.Verify(); !result.Valid {
+		t.Errorf("稽核記錄應該驗證通過: %+v", result.Problems)
+	}
+}
@@ -3,6 +3,7 @@
 package services
 
 import (
+	"errors"                           // 錯誤處理
 	"fmt"                              // 格式化輸出
 	"mac-notebook-app/internal/models" // 引入資料模型
 	"sync"                             // 同步原語套件
@@ -21,6 +22,7 @@ type AutoSaveServiceImpl struct {
 	notes            map[string]*models.Note      // 儲存筆記實例的快取
 	defaultInterval  time.Duration                // 預設自動保存間隔
 	encryptedBackoff time.Duration                // 加密檔案的額外延遲（避免頻繁加密操作）
+	conflictHandler  func(conflict *SaveConflict) // 自動保存遇到外部變更時的處理回調
 }
 
 // NewAutoSaveService 建立新的自動保存服務實例
@@ -207,7 +209,11 @@ func (a *AutoSaveServiceImpl) GetSaveStatus(noteID string) SaveStatus {
 func (a *AutoSaveServiceImpl) performAutoSave(noteID string, interval time.Duration) {
 	a.mutex.Lock()
 	note, noteExists := a.notes[noteID]
-	_, statusExists := a.saveStatus[noteID]
+	status, statusExists := a.saveStatus[noteID]
+	var previousErr error
+	if statusExists {
+		previousErr = status.LastError
+	}
 	a.mutex.Unlock()
 
 	if !noteExists || !statusExists {
@@ -226,8 +232,16 @@ func (a *AutoSaveServiceImpl) performAutoSave(noteID string, interval time.Durat
 
 	// 更新狀態
 	a.mutex.Lock()
+	var notifyConflict func(conflict *SaveConflict)
+	conflict, isConflict := AsSaveConflict(err)
 	if status, exists := a.saveStatus[noteID]; exists {
-		if err != nil {
+		if isConflict {
+			// 檔案在外部變更時不覆寫，同一次衝突只通知一次，等待使用者選擇
+			if !errors.Is(previousErr, ErrSaveConflict) {
+				notifyConflict = a.conflictHandler
+			}
+			status.LastError = err
+		} else if err != nil {
 			status.LastError = err
 			// 如果是加密檔案保存失敗，記錄特殊錯誤類型
 			if note.IsEncrypted {
@@ -242,6 +256,10 @@ func (a *AutoSaveServiceImpl) performAutoSave(noteID string, interval time.Durat
 	}
 	a.mutex.Unlock()
 
+	if notifyConflict != nil {
+		notifyConflict(conflict)
+	}
+
 	// 重新設定下次自動保存
 	a.rescheduleTimer(noteID, interval)
 }
@@ -270,6 +288,11 @@ func (a *AutoSaveServiceImpl) saveNoteWithRetry(note *models.Note) error {
 		
 		lastErr = err
 		
+		// 外部變更需要使用者選擇，重試也不會成功
+		if errors.Is(err, ErrSaveConflict) {
+			return err
+		}
+		
 		// 如果不是加密檔案，或者已經是最後一次嘗試，不再重試
 		if !note.IsEncrypted || attempt == maxRetries {
 			break
@@ -527,4 +550,46 @@ func (a *AutoSaveServiceImpl) SetEncryptedBackoff(backoff time.Duration) {
 	defer a.mutex.Unlock()
 
 	a.encryptedBackoff = backoff
-}
\ No newline at end of file
+}
+
+// SetConflictHandler 設定自動保存遇到外部變更時的處理回調
+// 參數：
+//   - handler: 處理回調，同一次衝突只會呼叫一次，由使用者以 ResolveConflict 選擇處理方式
+func (a *AutoSaveServiceImpl) SetConflictHandler(handler func(conflict *SaveConflict)) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.conflictHandler = handler
+}
+
+// ResolveConflict 依使用者的選擇處理自動保存遇到的外部變更
+// 參數：
+//   - noteID: 筆記 ID
+//   - resolution: 處理方式（保留我的版本、採用磁碟上的版本或三方合併）
+// 回傳：合併結果是否仍有衝突標記需要手動處理，以及可能的錯誤
+//
+// 執行流程：
+// 1. 由編輯器服務依處理方式覆寫、重新載入或合併
+// 2. 處理完成後清除保存錯誤，已寫入檔案時更新保存狀態
+func (a *AutoSaveServiceImpl) ResolveConflict(noteID string, resolution ConflictResolution) (bool, error) {
+	a.mutex.RLock()
+	note, noteExists := a.notes[noteID]
+	a.mutex.RUnlock()
+
+	if !noteExists {
+		return false, models.NewAppError("NOTE_NOT_FOUND", "找不到指定的筆記", "筆記 ID: "+noteID)
+	}
+
+	conflicted, err := a.editorService.ResolveSaveConflict(note, resolution)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if status, exists := a.saveStatus[noteID]; exists {
+		status.LastError = err
+		if err == nil && !conflicted && resolution != ConflictKeepTheirs {
+			note.MarkSaved()
+			status.LastSaved = time.Now()
+			status.SaveCount++
+		}
+	}
+	return conflicted, err
+}
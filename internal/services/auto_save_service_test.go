@@ -16,6 +16,7 @@ type MockEditorService struct {
 	shouldFail   bool                     // 控制是否模擬保存失敗
 	saveDelay    time.Duration            // 模擬保存操作的延遲
 	notes        map[string]*models.Note  // 儲存的筆記
+	resolutions  []ConflictResolution     // 記錄所有保存衝突的處理方式
 }
 
 // CreateNote 模擬建立筆記功能
@@ -32,6 +33,13 @@ func (m *MockEditorService) OpenNote(filePath string) (*models.Note, error) {
 	return models.NewNote("Test Note", "Test Content", filePath), nil
 }
 
+// OpenNoteWithPassword 模擬以密碼開啟加密筆記功能
+// 參數：filePath（檔案路徑）、password（密碼）
+// 回傳：筆記實例和可能的錯誤
+func (m *MockEditorService) OpenNoteWithPassword(filePath, password string) (*models.Note, error) {
+	return m.OpenNote(filePath)
+}
+
 // SaveNote 模擬保存筆記功能，記錄保存呼叫並可模擬錯誤
 // 參數：note（要保存的筆記）
 // 回傳：可能的錯誤
@@ -60,6 +68,32 @@ func (m *MockEditorService) SaveNote(note *models.Note) error {
 	return nil
 }
 
+// ResolveSaveConflict 模擬處理保存衝突，記錄處理方式後不再模擬保存失敗
+// 參數：note（要保存的筆記）、resolution（處理方式）
+// 回傳：是否仍有衝突和可能的錯誤
+func (m *MockEditorService) ResolveSaveConflict(note *models.Note, resolution ConflictResolution) (bool, error) {
+	m.resolutions = append(m.resolutions, resolution)
+	m.shouldFail = false
+	if resolution == ConflictKeepTheirs {
+		note.MarkSaved()
+		return false, nil
+	}
+	return false, m.SaveNote(note)
+}
+
+// ReloadNote 模擬重新載入筆記
+// 參數：noteID（筆記 ID）
+// 回傳：內容是否已更新和可能的錯誤
+func (m *MockEditorService) ReloadNote(noteID string) (bool, error) {
+	return false, nil
+}
+
+// InvalidateFileCache 模擬清除檔案快取
+// 參數：filePath（檔案路徑）
+func (m *MockEditorService) InvalidateFileCache(filePath string) {
+	// 模擬清除操作
+}
+
 // UpdateContent 模擬更新筆記內容功能
 // 參數：noteID（筆記 ID）、content（新內容）
 // 回傳：可能的錯誤
@@ -169,6 +203,16 @@ func (m *MockEditorService) SetSmartEditingService(smartEditSvc SmartEditingServ
 	// 模擬設定操作
 }
 
+// Options 模擬取得編輯器的選用依賴
+func (m *MockEditorService) Options() EditorOptions {
+	return EditorOptions{}
+}
+
+// SetOptions 模擬設定編輯器的選用依賴
+func (m *MockEditorService) SetOptions(options EditorOptions) {
+	// 模擬設定操作
+}
+
 // NewMockEditorService 建立新的模擬編輯器服務
 func NewMockEditorService() *MockEditorService {
 	return &MockEditorService{
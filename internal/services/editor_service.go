@@ -4,11 +4,15 @@ package services
 
 import (
 	"bytes"                           // 位元組緩衝區處理
+	"encoding/json"                   // JSON 序列化
 	"fmt"                            // 格式化輸出
+	"io"                             // 輸入輸出介面
+	"log"                            // 日誌記錄
 	"mac-notebook-app/internal/models" // 引入資料模型
 	"mac-notebook-app/internal/repositories" // 引入資料存取層
 	"path/filepath"                  // 檔案路徑處理
 	"strings"                        // 字串處理
+	"sync"                           // 同步原語
 	"time"                          // 時間處理
 	"github.com/google/uuid"        // UUID 生成
 
@@ -32,6 +36,24 @@ type editorService struct {
 	markdown      goldmark.Markdown           // Markdown 解析器實例
 	activeNotes   map[string]*models.Note     // 當前開啟的筆記快取
 	perfService   PerformanceService          // 效能服務介面
+	vaultSvc      VaultService                // 保險庫服務介面（可選，用於信封加密）
+	noteKeyIDs    map[string]string           // 筆記 ID 對應的保險庫資料金鑰 ID
+	obfuscateFilenames bool                   // 保險庫加密筆記是否使用隨機檔名
+	noteTitles    map[string]string           // 加密筆記檔案路徑對應的標題（保險庫鎖定時清除）
+	titlesMu      sync.Mutex                  // 保護 noteTitles 的互斥鎖
+	identitySvc   IdentityService             // 公鑰身分服務介面（可選，用於收件人加密的筆記）
+	noteRecipients map[string]*RecipientNoteInfo // 筆記 ID 對應的收件人資訊
+	signingSvc    SigningService              // 筆記簽章服務介面（可選）
+	auditSvc      AuditService                // 安全稽核記錄服務介面（可選）
+	leakGuard     LeakageGuard                // 明文外洩防護（匯出、分享前的重新驗證和暫存檔清除）
+	noteSignatures map[string]*SignatureStatus // 筆記 ID 對應的簽章狀態
+	secretBlocks  map[string]*secretBlockState // 筆記 ID 對應的機密區塊加密狀態
+	recoveryJournal RecoveryJournal           // 未保存編輯的復原日誌（可選）
+	diskStates    map[string]*noteDiskState   // 筆記 ID 對應的磁碟檔案狀態，用於偵測外部變更
+	frontMatters  map[string]*FrontMatter     // 筆記 ID 對應上次與 front matter 同步時的欄位
+	noteIDs       NoteIDIndex                 // 筆記 ID 索引（可選，讓同一個檔案每次開啟都使用相同的 ID）
+	tagSvc        TagService                  // 標籤服務（可選，保存後更新標籤索引）
+	searchIdx     SearchIndex                 // 全文搜尋索引（可選，保存後更新索引）
 	
 	// 效能優化相關欄位
 	maxCacheSize     int                      // 最大快取大小
@@ -88,6 +110,14 @@ func NewEditorService(fileRepo repositories.FileRepository, encryptionSvc Encryp
 		markdown:           md,
 		activeNotes:        make(map[string]*models.Note),
 		perfService:        perfService,
+		noteKeyIDs:         make(map[string]string),
+		noteTitles:         make(map[string]string),
+		noteRecipients:     make(map[string]*RecipientNoteInfo),
+		noteSignatures:     make(map[string]*SignatureStatus),
+		secretBlocks:       make(map[string]*secretBlockState),
+		diskStates:         make(map[string]*noteDiskState),
+		frontMatters:       make(map[string]*FrontMatter),
+		leakGuard:          NewLeakageGuard(),
 		maxCacheSize:       100,              // 最多快取 100 個筆記
 		largeFileThreshold: 5 * 1024 * 1024,  // 5MB 以上視為大檔案
 		chunkSize:          1024 * 1024,      // 1MB 分塊大小
@@ -101,8 +131,9 @@ func NewEditorService(fileRepo repositories.FileRepository, encryptionSvc Encryp
 // 執行流程：
 // 1. 生成唯一的筆記 ID
 // 2. 建立筆記實例並設定基本屬性
-// 3. 將筆記加入活躍筆記快取
-// 4. 回傳建立的筆記實例
+// 3. 內容包含 front matter 時（例如範本）解析其中的標籤和屬性
+// 4. 將筆記加入活躍筆記快取
+// 5. 回傳建立的筆記實例
 func (e *editorService) CreateNote(title, content string) (*models.Note, error) {
 	// 生成唯一的筆記 ID
 	noteID := uuid.New().String()
@@ -116,6 +147,7 @@ func (e *editorService) CreateNote(title, content string) (*models.Note, error)
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
+	e.applyFrontMatter(note)
 
 	// 將新筆記加入活躍筆記快取
 	e.activeNotes[noteID] = note
@@ -133,7 +165,7 @@ func (e *editorService) CreateNote(title, content string) (*models.Note, error)
 // 3. 檢查是否為加密檔案並進行解密
 // 4. 解析檔案資訊（標題、加密狀態等）
 // 5. 建立筆記實例
-// 6. 將筆記加入活躍筆記快取
+// 6. 將筆記加入活躍筆記快取，保險庫已解鎖時解密機密區塊，並驗證簽章
 // 7. 回傳筆記實例
 func (e *editorService) OpenNote(filePath string) (*models.Note, error) {
 	// 檢查檔案是否存在
@@ -154,11 +186,23 @@ func (e *editorService) OpenNote(filePath string) (*models.Note, error) {
 	// 檢查是否為加密檔案（副檔名為 .enc）
 	isEncrypted := strings.HasSuffix(filePath, ".enc")
 	
+	// 以收件人公鑰加密的筆記使用自己的身分私鑰開啟
+	if isEncrypted && e.identitySvc != nil && e.identitySvc.IsRecipientData(rawContent) {
+		note, err := e.openRecipientNote(filePath, strings.TrimSuffix(title, ".md"), rawContent)
+		if err != nil {
+			return nil, err
+		}
+		e.rememberDiskState(note, rawContent)
+		e.verifyNoteSignature(note)
+		return note, nil
+	}
+	
 	// 處理檔案內容（解密或直接使用）
 	var content string
+	var keyInfo *VaultNoteInfo
 	if isEncrypted {
 		// 加密檔案需要解密
-		content, err = e.decryptFileContent(rawContent, title)
+		content, keyInfo, err = e.decryptFileContent(rawContent, title)
 		if err != nil {
 			return nil, fmt.Errorf("解密檔案失敗: %w", err)
 		}
@@ -172,8 +216,44 @@ func (e *editorService) OpenNote(filePath string) (*models.Note, error) {
 		content = string(rawContent)
 	}
 
-	// 生成筆記 ID
-	noteID := uuid.New().String()
+	note := e.addOpenedNote(title, content, filePath, isEncrypted, keyInfo)
+
+	// 保險庫已解鎖時直接解密機密區塊，鎖定時維持密文
+	if e.vaultSvc != nil && e.vaultSvc.IsUnlocked() {
+		if revealed, err := e.RevealSecretBlocks(note.ID, note.Content); err == nil {
+			note.Content = revealed
+		} else {
+			log.Printf("解密機密區塊失敗: %v", err)
+		}
+	}
+
+	// 以中繼資料標頭還原標題、時間戳和標籤（隨機檔名無法提供標題）
+	if keyInfo != nil && keyInfo.Metadata != nil {
+		keyInfo.Metadata.ApplyTo(note)
+		e.rememberNoteTitle(filePath, note.Title)
+	}
+
+	// 記錄開啟時的檔案指紋，保存前用來偵測外部變更
+	e.rememberDiskState(note, rawContent)
+
+	e.verifyNoteSignature(note)
+
+	return note, nil
+}
+
+// addOpenedNote 建立開啟的筆記實例並加入活躍筆記快取
+// 參數：
+//   - title: 筆記標題
+//   - content: 筆記明文內容
+//   - filePath: 檔案路徑
+//   - isEncrypted: 是否為加密筆記
+//   - keyInfo: 保險庫金鑰資訊（非信封格式時為 nil）
+// 回傳：筆記實例
+//
+// front matter 記錄的 ID、標題、時間戳、標籤和屬性會套用到筆記上
+func (e *editorService) addOpenedNote(title, content, filePath string, isEncrypted bool, keyInfo *VaultNoteInfo) *models.Note {
+	// 沿用 front matter 或筆記 ID 索引記錄的 ID，複製的檔案另外產生
+	noteID := e.openedNoteID(filePath, content)
 
 	// 建立筆記實例
 	note := &models.Note{
@@ -182,16 +262,167 @@ func (e *editorService) OpenNote(filePath string) (*models.Note, error) {
 		Content:     content,
 		FilePath:    filePath,
 		IsEncrypted: isEncrypted,
-		CreatedAt:   time.Now(), // 實際應用中可能需要從檔案屬性讀取
+		CreatedAt:   time.Now(), // front matter 沒有記錄時使用開啟時間
 		UpdatedAt:   time.Now(),
 	}
+	e.applyFrontMatter(note)
+	e.markEncryptedFolder(note)
+
+	// 記錄保險庫資料金鑰，後續保存時沿用同一把金鑰
+	if keyInfo != nil {
+		note.EncryptionType = keyInfo.Algorithm
+		e.noteKeyIDs[noteID] = keyInfo.KeyID
+	}
 
 	// 將筆記加入活躍筆記快取
 	e.activeNotes[noteID] = note
 
+	return note
+}
+
+// OpenNoteWithPassword 以密碼開啟加密筆記
+// 參數：filePath（筆記檔案路徑）、password（保險庫或筆記密碼）
+// 回傳：開啟的筆記實例和可能的錯誤
+//
+// 執行流程：
+// 1. 讀取檔案內容
+// 2. 信封格式：以密碼解鎖保險庫後依一般流程開啟；收件人格式：以密碼解鎖身分金鑰庫
+// 3. 密碼格式：以密碼直接解密，並將密碼暫存在工作階段中
+// 4. 之後保存時以目前的 KDF 參數重新加密，舊的 PBKDF2 檔案會自動升級
+func (e *editorService) OpenNoteWithPassword(filePath, password string) (*models.Note, error) {
+	if !e.fileRepo.FileExists(filePath) {
+		return nil, fmt.Errorf("檔案不存在: %s", filePath)
+	}
+
+	rawContent, err := e.fileRepo.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("讀取檔案失敗: %w", err)
+	}
+
+	if e.identitySvc != nil && e.identitySvc.IsRecipientData(rawContent) {
+		if !e.identitySvc.IsUnlocked() {
+			if err := e.identitySvc.Unlock(password); err != nil {
+				return nil, fmt.Errorf("解密檔案失敗: %w", err)
+			}
+		}
+		return e.OpenNote(filePath)
+	}
+
+	if e.vaultSvc != nil && e.vaultSvc.IsVaultData(rawContent) {
+		if !e.vaultSvc.IsUnlocked() {
+			if err := e.vaultSvc.Unlock(password); err != nil {
+				return nil, fmt.Errorf("解密檔案失敗: %w", err)
+			}
+		}
+		return e.OpenNote(filePath)
+	}
+
+	// 密碼格式的筆記以檔案路徑記錄重試次數，重新啟動應用程式也不會重設
+	var content string
+	err = guardPasswordAttempt(e.passwordSvc, filePath, func() error {
+		content, err = e.encryptionSvc.DecryptContent(rawContent, password, "")
+		return err
+	}, nil)
+	recordAudit(e.auditSvc, AuditEventUnlock, filePath, err, "")
+	if err != nil {
+		return nil, fmt.Errorf("解密檔案失敗: %w", err)
+	}
+
+	fileName := filepath.Base(filePath)
+	title := strings.TrimSuffix(strings.TrimSuffix(fileName, ".enc"), ".md")
+
+	note := e.addOpenedNote(title, content, filePath, true, nil)
+	note.EncryptionType = encryptedDataAlgorithm(rawContent)
+	e.rememberNotePassword(note.ID, password)
+	e.rememberDiskState(note, rawContent)
+
 	return note, nil
 }
 
+// openRecipientNote 以身分私鑰開啟收件人格式的筆記
+// 參數：filePath（檔案路徑）、title（筆記標題）、rawContent（加密資料）
+// 回傳：開啟的筆記實例和可能的錯誤
+//
+// 執行流程：
+// 1. 金鑰庫鎖定時要求上層處理密碼輸入
+// 2. 以身分私鑰解開檔案金鑰並解密內容
+// 3. 記錄收件人資訊，之後保存時加密給同一組收件人
+func (e *editorService) openRecipientNote(filePath, title string, rawContent []byte) (*models.Note, error) {
+	if !e.identitySvc.IsUnlocked() {
+		return nil, fmt.Errorf("解密檔案失敗: 需要密碼驗證才能開啟加密檔案")
+	}
+
+	noteID := e.indexedNoteID(filePath)
+	content, info, err := e.identitySvc.DecryptNote(rawContent, noteID)
+	if err != nil {
+		return nil, fmt.Errorf("解密檔案失敗: %w", err)
+	}
+
+	note := &models.Note{
+		ID:             noteID,
+		Title:          title,
+		Content:        content,
+		FilePath:       filePath,
+		IsEncrypted:    true,
+		EncryptionType: info.Algorithm,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	// 檔案金鑰在解密前以索引中的 ID 暫存在工作階段中，不沿用 front matter 的 ID
+	e.applyFrontMatter(note)
+	e.noteRecipients[noteID] = info
+	e.activeNotes[noteID] = note
+
+	return note, nil
+}
+
+// encryptedDataAlgorithm 取得加密資料記錄的加密演算法
+// 參數：data（加密資料）
+// 回傳：演算法名稱，無法解析時回傳 AES-256
+func encryptedDataAlgorithm(data []byte) string {
+	if header := peekStreamHeader(data); header != nil {
+		return header.Algorithm
+	}
+
+	var encData EncryptedData
+	if err := json.Unmarshal(data, &encData); err != nil || encData.Algorithm == "" {
+		return AlgorithmAES256
+	}
+	return encData.Algorithm
+}
+
+// notePasswordSessionKey 取得筆記密碼在工作階段中的名稱
+// 參數：noteID（筆記 ID）
+// 回傳：工作階段金鑰名稱
+func notePasswordSessionKey(noteID string) string {
+	return "note.password:" + noteID
+}
+
+// rememberNotePassword 將密碼格式筆記的密碼暫存在工作階段中
+// 工作階段鎖定時密碼會與其他金鑰一起被清零
+// 參數：noteID（筆記 ID）、password（筆記密碼）
+func (e *editorService) rememberNotePassword(noteID, password string) {
+	if e.vaultSvc == nil {
+		return
+	}
+	e.vaultSvc.Session().StoreKey(notePasswordSessionKey(noteID), []byte(password))
+}
+
+// notePassword 取得暫存的筆記密碼
+// 參數：noteID（筆記 ID）
+// 回傳：筆記密碼和是否存在
+func (e *editorService) notePassword(noteID string) (string, bool) {
+	if e.vaultSvc == nil {
+		return "", false
+	}
+	password, ok := e.vaultSvc.Session().GetKey(notePasswordSessionKey(noteID))
+	if !ok {
+		return "", false
+	}
+	defer zeroBytes(password)
+	return string(password), true
+}
+
 // SaveNote 保存筆記到檔案系統
 // 參數：note（要保存的筆記實例）
 // 回傳：可能的錯誤
@@ -199,10 +430,14 @@ func (e *editorService) OpenNote(filePath string) (*models.Note, error) {
 // 執行流程：
 // 1. 驗證筆記實例的有效性
 // 2. 確定保存路徑（如果未設定則生成預設路徑）
-// 3. 處理筆記內容（加密或直接使用）
-// 4. 將處理後的內容寫入檔案
-// 5. 更新筆記的最後保存時間
-// 6. 更新活躍筆記快取
+// 3. 檔案在開啟後被其他程式修改時回傳 SaveConflictError，不覆寫
+// 4. 同步 front matter 和筆記的標籤、別名、自訂屬性
+// 5. 處理筆記內容（加密整份筆記，或只加密其中的機密區塊）
+// 6. 依設定將保險庫加密筆記改為隨機檔名或改回以標題命名
+// 7. 將處理後的內容寫入檔案，檔名變更或切換加密狀態時刪除舊檔案
+// 8. 停用加密的筆記在明文保存成功後刪除保險庫資料金鑰
+// 9. 更新筆記 ID 索引、標籤索引、全文搜尋索引、最後保存時間和檔案指紋
+// 10. 更新活躍筆記快取
 func (e *editorService) SaveNote(note *models.Note) error {
 	if note == nil {
 		return fmt.Errorf("筆記實例不能為空")
@@ -225,26 +460,82 @@ func (e *editorService) SaveNote(note *models.Note) error {
 		note.FilePath = fileName + extension
 	}
 
+	// 檔案在開啟後被其他工具、同步服務或 git 修改時不覆寫，交由使用者選擇
+	if err := e.checkDiskConflict(note); err != nil {
+		return err
+	}
+
+	// 將程式修改的標籤和屬性寫入 front matter，加密筆記的 front matter 隨內容一起加密
+	editedContent := note.Content
+	e.syncFrontMatter(note, time.Now())
+
+	// 依設定將保險庫加密筆記改為隨機檔名，或改回以標題命名
+	oldPath := note.FilePath
+	previousPath := e.previousDiskPath(note)
+	if note.IsEncrypted {
+		newPath, err := e.encryptedFilePath(note)
+		if err != nil {
+			return err
+		}
+		note.FilePath = newPath
+	}
+
 	// 處理筆記內容（加密或直接使用）
 	var contentToSave []byte
 	var err error
 	
 	if note.IsEncrypted {
 		// 加密筆記內容
-		contentToSave, err = e.encryptFileContent(note.Content, note.ID)
+		contentToSave, err = e.encryptFileContent(note)
 		if err != nil {
+			note.FilePath = oldPath
 			return fmt.Errorf("加密筆記內容失敗: %w", err)
 		}
 	} else {
-		// 非加密筆記直接轉換為位元組
-		contentToSave = []byte(note.Content)
+		// 非加密筆記直接寫入，其中的機密區塊以加密格式保存
+		content, err := e.sealSecretBlocks(note)
+		if err != nil {
+			note.FilePath = oldPath
+			return fmt.Errorf("保存機密區塊失敗: %w", err)
+		}
+		contentToSave = []byte(content)
 	}
 
 	// 將處理後的內容寫入檔案
 	err = e.fileRepo.WriteFile(note.FilePath, contentToSave)
 	if err != nil {
+		note.FilePath = oldPath
 		return fmt.Errorf("保存筆記失敗: %w", err)
 	}
+	e.rememberDiskState(note, contentToSave)
+
+	// 檔名變更或切換加密狀態後刪除舊檔案，避免同一份筆記留下兩個檔案
+	if previousPath != note.FilePath {
+		if e.fileRepo.FileExists(previousPath) {
+			if err := e.fileRepo.DeleteFile(previousPath); err != nil {
+				return fmt.Errorf("刪除舊檔案失敗: %w", err)
+			}
+		}
+		e.forgetNoteTitle(previousPath)
+	}
+
+	// 停用加密後，明文已寫入且加密檔案已刪除，才刪除不再需要的資料金鑰
+	if err := e.deleteDecryptedNoteKey(note); err != nil {
+		return err
+	}
+
+	if note.IsEncrypted && IsObfuscatedFileName(filepath.Base(note.FilePath)) {
+		e.rememberNoteTitle(note.FilePath, note.Title)
+	}
+	e.recordNoteID(note.FilePath, note.ID)
+	e.markEncryptedFolder(note)
+	e.updateTagIndex(note, previousPath)
+	e.updateSearchIndex(note, previousPath)
+
+	// 簽章檔隨筆記移動，並重新簽署或驗證
+	if err := e.updateNoteSignature(note, previousPath); err != nil {
+		return err
+	}
 
 	// 更新筆記的時間戳
 	note.UpdatedAt = time.Now()
@@ -253,6 +544,13 @@ func (e *editorService) SaveNote(note *models.Note) error {
 	// 更新活躍筆記快取
 	e.activeNotes[note.ID] = note
 
+	// 已保存的內容不需要再從復原日誌還原
+	if e.recoveryJournal != nil {
+		if err := e.recoveryJournal.MarkSaved(note.ID, editedContent); err != nil {
+			log.Printf("移除復原記錄失敗: %v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -295,8 +593,8 @@ func (e *editorService) PreviewMarkdown(content string) string {
 	// 建立輸出緩衝區
 	var buf bytes.Buffer
 	
-	// 使用 goldmark 將 Markdown 轉換為 HTML
-	err := e.markdown.Convert([]byte(content), &buf)
+	// 使用 goldmark 將 Markdown 轉換為 HTML，front matter 不顯示在預覽中
+	err := e.markdown.Convert([]byte(StripFrontMatter(content)), &buf)
 	if err != nil {
 		// 如果轉換失敗，回傳錯誤訊息的 HTML
 		return fmt.Sprintf("<p>Markdown 轉換錯誤: %s</p>", err.Error())
@@ -315,6 +613,13 @@ func (e *editorService) PreviewMarkdown(content string) string {
 // 3. 移除多餘的空白字元
 // 4. 回傳清理後的檔案名稱
 func (e *editorService) sanitizeFileName(fileName string) string {
+	return sanitizeNoteFileName(fileName)
+}
+
+// sanitizeNoteFileName 清理檔案名稱中的不合法字元
+// 參數：fileName（原始檔案名稱）
+// 回傳：清理後的檔案名稱
+func sanitizeNoteFileName(fileName string) string {
 	// 定義不合法的檔案名稱字元
 	invalidChars := []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|"}
 	
@@ -352,6 +657,22 @@ func (e *editorService) GetActiveNote(noteID string) (*models.Note, bool) {
 // 1. 從活躍筆記快取中移除指定筆記
 func (e *editorService) CloseNote(noteID string) {
 	delete(e.activeNotes, noteID)
+	delete(e.noteKeyIDs, noteID)
+	delete(e.secretBlocks, noteID)
+	delete(e.diskStates, noteID)
+	delete(e.frontMatters, noteID)
+	if e.recoveryJournal != nil {
+		if err := e.recoveryJournal.Discard(noteID); err != nil {
+			log.Printf("移除復原記錄失敗: %v", err)
+		}
+	}
+	if e.vaultSvc != nil {
+		e.vaultSvc.Session().DeleteKey(notePasswordSessionKey(noteID))
+	}
+	if _, ok := e.noteRecipients[noteID]; ok {
+		delete(e.noteRecipients, noteID)
+		e.identitySvc.ForgetNote(noteID)
+	}
 }
 
 // GetActiveNotes 取得所有活躍筆記的列表
@@ -368,18 +689,19 @@ func (e *editorService) GetActiveNotes() map[string]*models.Note {
 // EnableEncryption 為指定筆記啟用加密
 // 參數：
 //   - noteID: 筆記 ID
-//   - password: 加密密碼
+//   - password: 加密密碼（有保險庫時即為保險庫密碼）
 //   - algorithm: 加密演算法
 //   - useBiometric: 是否啟用生物識別驗證
-// 回傳：可能的錯誤
+// 回傳：可能的錯誤（密碼與保險庫密碼不符時回傳保險庫驗證失敗）
 //
 // 執行流程：
 // 1. 驗證筆記是否存在於活躍快取中
 // 2. 驗證密碼強度
-// 3. 設定筆記的加密狀態
-// 4. 如果啟用生物識別，設定生物識別驗證
-// 5. 更新檔案路徑以包含 .enc 副檔名
-// 6. 更新活躍筆記快取
+// 3. 如果有保險庫服務，以密碼建立保險庫，或解鎖及重新驗證保險庫密碼
+// 4. 設定筆記的加密狀態
+// 5. 如果啟用生物識別，設定生物識別驗證
+// 6. 更新檔案路徑以包含 .enc 副檔名
+// 7. 更新活躍筆記快取
 func (e *editorService) EnableEncryption(noteID, password, algorithm string, useBiometric bool) error {
 	// 檢查筆記是否存在
 	note, exists := e.activeNotes[noteID]
@@ -392,6 +714,21 @@ func (e *editorService) EnableEncryption(noteID, password, algorithm string, use
 		return fmt.Errorf("密碼不符合安全要求")
 	}
 
+	// 保險庫尚未建立時以此密碼建立，已建立時以此密碼解鎖或重新驗證，
+	// 筆記由保險庫主金鑰保護，與保險庫密碼不同的密碼不會被默默忽略
+	if e.vaultSvc != nil {
+		var err error
+		if e.vaultSvc.IsInitialized() {
+			err = e.vaultSvc.Unlock(password)
+		} else {
+			err = e.vaultSvc.Initialize(password)
+		}
+		if err != nil {
+			recordAudit(e.auditSvc, AuditEventEnableEncryption, auditNoteTarget(note), err, "")
+			return fmt.Errorf("保險庫驗證失敗: %w", err)
+		}
+	}
+
 	// 設定筆記的加密狀態
 	note.IsEncrypted = true
 	if algorithm != "" {
@@ -420,6 +757,7 @@ func (e *editorService) EnableEncryption(noteID, password, algorithm string, use
 	// 更新活躍筆記快取
 	e.activeNotes[noteID] = note
 
+	recordAudit(e.auditSvc, AuditEventEnableEncryption, auditNoteTarget(note), nil, note.EncryptionType)
 	return nil
 }
 
@@ -431,8 +769,11 @@ func (e *editorService) EnableEncryption(noteID, password, algorithm string, use
 // 1. 驗證筆記是否存在於活躍快取中
 // 2. 移除筆記的加密狀態
 // 3. 移除生物識別驗證設定
-// 4. 更新檔案路徑移除 .enc 副檔名
+// 4. 更新檔案路徑移除 .enc 副檔名（隨機檔名改回以標題命名）
 // 5. 更新活躍筆記快取
+//
+// 磁碟上的加密檔案和保險庫資料金鑰保留到 SaveNote 寫入明文後才刪除，
+// 保存失敗或未保存就關閉筆記時，加密檔案仍然可以解密
 func (e *editorService) DisableEncryption(noteID string) error {
 	// 檢查筆記是否存在
 	note, exists := e.activeNotes[noteID]
@@ -440,6 +781,9 @@ func (e *editorService) DisableEncryption(noteID string) error {
 		return fmt.Errorf("找不到指定的筆記: %s", noteID)
 	}
 
+	// 稽核記錄以停用前的加密檔案路徑識別筆記
+	target := auditNoteTarget(note)
+
 	// 移除筆記的加密狀態
 	note.IsEncrypted = false
 	note.EncryptionType = ""
@@ -447,9 +791,17 @@ func (e *editorService) DisableEncryption(noteID string) error {
 	// 移除生物識別驗證設定
 	e.biometricSvc.RemoveForNote(noteID)
 
-	// 更新檔案路徑移除 .enc 副檔名
+	// 不再加密給收件人
+	if _, ok := e.noteRecipients[noteID]; ok {
+		delete(e.noteRecipients, noteID)
+		e.identitySvc.ForgetNote(noteID)
+	}
+	delete(e.noteSignatures, noteID)
+
+	// 更新檔案路徑移除 .enc 副檔名，隨機檔名改回以標題命名
 	if note.FilePath != "" && strings.HasSuffix(note.FilePath, ".enc") {
-		note.FilePath = strings.TrimSuffix(note.FilePath, ".enc")
+		e.forgetNoteTitle(note.FilePath)
+		note.FilePath = DecryptedFilePath(note.FilePath, note.Title)
 	}
 
 	// 更新時間戳
@@ -458,6 +810,36 @@ func (e *editorService) DisableEncryption(noteID string) error {
 	// 更新活躍筆記快取
 	e.activeNotes[noteID] = note
 
+	recordAudit(e.auditSvc, AuditEventDisableEncryption, target, nil, "")
+	return nil
+}
+
+// previousDiskPath 取得保存後需要刪除的舊檔案路徑
+// 切換加密狀態後為上次讀取或寫入的檔案（加密檔案或明文檔案），否則為筆記目前的路徑
+// 參數：note（要保存的筆記）
+// 回傳：舊檔案路徑
+func (e *editorService) previousDiskPath(note *models.Note) string {
+	if state, ok := e.diskStates[note.ID]; ok && state.encrypted != note.IsEncrypted {
+		return state.path
+	}
+	return note.FilePath
+}
+
+// deleteDecryptedNoteKey 刪除已停用加密的筆記的保險庫資料金鑰
+// 參數：note（已保存為明文的筆記）
+// 回傳：可能的錯誤
+func (e *editorService) deleteDecryptedNoteKey(note *models.Note) error {
+	keyID, ok := e.noteKeyIDs[note.ID]
+	if note.IsEncrypted || !ok {
+		return nil
+	}
+	if e.vaultSvc != nil {
+		if err := e.vaultSvc.DeleteNoteKey(keyID); err != nil {
+			recordAudit(e.auditSvc, AuditEventDisableEncryption, auditNoteTarget(note), err, "")
+			return fmt.Errorf("刪除資料金鑰失敗: %w", err)
+		}
+	}
+	delete(e.noteKeyIDs, note.ID)
 	return nil
 }
 
@@ -465,14 +847,19 @@ func (e *editorService) DisableEncryption(noteID string) error {
 // 參數：
 //   - encryptedData: 加密的檔案內容
 //   - noteID: 筆記 ID（用於生物識別驗證）
-// 回傳：解密後的內容和可能的錯誤
+// 回傳：解密後的內容、保險庫金鑰資訊（非信封格式時為 nil）和可能的錯誤
 //
 // 執行流程：
-// 1. 嘗試使用生物識別驗證（如果可用）
-// 2. 如果生物識別失敗或不可用，提示輸入密碼
-// 3. 使用密碼解密內容
+// 1. 如果是信封格式且保險庫已解鎖，直接使用資料金鑰解密
+// 2. 嘗試使用生物識別驗證（如果可用）
+// 3. 如果生物識別失敗或不可用，提示輸入密碼
 // 4. 回傳解密後的內容
-func (e *editorService) decryptFileContent(encryptedData []byte, noteID string) (string, error) {
+func (e *editorService) decryptFileContent(encryptedData []byte, noteID string) (string, *VaultNoteInfo, error) {
+	// 保險庫已解鎖時不需要再次輸入密碼
+	if e.vaultSvc != nil && e.vaultSvc.IsVaultData(encryptedData) && e.vaultSvc.IsUnlocked() {
+		return e.vaultSvc.DecryptNote(encryptedData)
+	}
+
 	// 首先嘗試生物識別驗證
 	if e.biometricSvc.IsEnabledForNote(noteID) {
 		result := e.biometricSvc.AuthenticateForNote(noteID, "開啟加密筆記")
@@ -487,7 +874,7 @@ func (e *editorService) decryptFileContent(encryptedData []byte, noteID string)
 	// 生物識別失敗或不可用，需要密碼驗證
 	// 注意：在實際的 UI 實作中，這裡應該彈出密碼輸入對話框
 	// 目前返回錯誤，要求上層處理密碼輸入
-	return "", fmt.Errorf("需要密碼驗證才能開啟加密檔案")
+	return "", nil, fmt.Errorf("需要密碼驗證才能開啟加密檔案")
 }
 
 // DecryptWithPassword 使用密碼解密筆記內容
@@ -499,7 +886,7 @@ func (e *editorService) decryptFileContent(encryptedData []byte, noteID string)
 // 執行流程：
 // 1. 檢查筆記是否存在且已加密
 // 2. 讀取加密檔案內容
-// 3. 使用密碼解密內容
+// 3. 信封格式以密碼解鎖保險庫後解密，舊格式直接以密碼解密
 // 4. 更新筆記內容
 // 5. 回傳解密後的內容
 func (e *editorService) DecryptWithPassword(noteID, password string) (string, error) {
@@ -519,53 +906,118 @@ func (e *editorService) DecryptWithPassword(noteID, password string) (string, er
 		return "", fmt.Errorf("讀取加密檔案失敗: %w", err)
 	}
 
+	// 信封格式：以密碼解鎖保險庫後使用資料金鑰解密
+	if e.vaultSvc != nil && e.vaultSvc.IsVaultData(encryptedData) {
+		if !e.vaultSvc.IsUnlocked() {
+			if err := e.vaultSvc.Unlock(password); err != nil {
+				return "", fmt.Errorf("解密失敗: %w", err)
+			}
+		}
+
+		decryptedContent, keyInfo, err := e.vaultSvc.DecryptNote(encryptedData)
+		if err != nil {
+			return "", fmt.Errorf("解密失敗: %w", err)
+		}
+
+		note.Content = decryptedContent
+		note.EncryptionType = keyInfo.Algorithm
+		note.UpdatedAt = time.Now()
+		e.noteKeyIDs[noteID] = keyInfo.KeyID
+		e.activeNotes[noteID] = note
+
+		return decryptedContent, nil
+	}
+
 	// 使用密碼解密內容
 	algorithm := note.EncryptionType
 	if algorithm == "" {
 		algorithm = "aes256" // 預設演算法
 	}
 
-	decryptedContent, err := e.encryptionSvc.DecryptContent(encryptedData, password, algorithm)
+	var decryptedContent string
+	err = guardPasswordAttempt(e.passwordSvc, note.FilePath, func() error {
+		decryptedContent, err = e.encryptionSvc.DecryptContent(encryptedData, password, algorithm)
+		return err
+	}, nil)
+	recordAudit(e.auditSvc, AuditEventUnlock, note.FilePath, err, "")
 	if err != nil {
 		return "", fmt.Errorf("解密失敗: %w", err)
 	}
 
-	// 更新筆記內容
+	// 更新筆記內容，暫存密碼供保存時以目前的 KDF 參數重新加密
 	note.Content = decryptedContent
+	note.EncryptionType = encryptedDataAlgorithm(encryptedData)
 	note.UpdatedAt = time.Now()
 	e.activeNotes[noteID] = note
+	e.rememberNotePassword(noteID, password)
 
 	return decryptedContent, nil
 }
 
 // encryptFileContent 加密檔案內容
-// 參數：
-//   - content: 要加密的內容
-//   - noteID: 筆記 ID
+// 參數：note（要加密的筆記）
 // 回傳：加密後的位元組陣列和可能的錯誤
 //
 // 執行流程：
-// 1. 取得筆記的加密設定
-// 2. 取得加密密碼（從安全儲存或提示輸入）
-// 3. 使用指定演算法加密內容
-// 4. 回傳加密後的資料
-func (e *editorService) encryptFileContent(content, noteID string) ([]byte, error) {
-	// 取得筆記實例
-	note, exists := e.activeNotes[noteID]
-	if !exists {
-		return nil, fmt.Errorf("找不到指定的筆記: %s", noteID)
+// 1. 取得筆記的加密演算法
+// 2. 收件人格式的筆記沿用檔案金鑰，以密碼開啟的密碼格式筆記使用暫存的密碼重新加密（同時升級 KDF）
+// 3. 確認保險庫已解鎖（未設定保險庫時需要上層提供密碼）
+// 4. 使用筆記的資料金鑰加密內容和中繼資料標頭，首次加密時建立新的資料金鑰
+// 5. 記錄資料金鑰 ID 並回傳加密後的資料
+//
+// 大型筆記只有檔案格式是分段的：編輯器保存時明文已是記憶體中的完整字串，
+// 加密後的完整密文同樣先放在記憶體中再一次寫入，峰值記憶體約為筆記大小的兩倍。
+// 串流格式避免的是 Base64 和 JSON 造成的額外副本；需要以固定記憶體加密的大型檔案請使用
+// PerformanceService.EncryptFileInChunks
+func (e *editorService) encryptFileContent(note *models.Note) ([]byte, error) {
+	// 取得加密演算法，EncryptionType 不是演算法名稱時使用預設演算法
+	algorithm := note.EncryptionType
+	if algorithm != AlgorithmAES256 && algorithm != AlgorithmChaCha20 {
+		algorithm = AlgorithmAES256
 	}
 
-	// 取得加密演算法
-	algorithm := note.EncryptionType
-	if algorithm == "" {
-		algorithm = "aes256" // 預設演算法
+	// 大型筆記使用串流分段格式，避免 Base64 和 JSON 造成多份完整副本（密文仍會完整放在記憶體中）
+	large := int64(len(note.Content)) > e.largeFileThreshold
+
+	// 收件人格式的筆記加密給開啟時的同一組收件人
+	if info, ok := e.noteRecipients[note.ID]; ok && e.identitySvc != nil {
+		return e.identitySvc.ResealNote(note.Content, info, note.ID)
+	}
+
+	// 以密碼開啟的密碼格式筆記沿用原密碼，並以目前的 KDF 參數重新加密
+	if password, ok := e.notePassword(note.ID); ok {
+		if large {
+			return sealStreamContent(note.Content, func(dst io.Writer) (io.WriteCloser, error) {
+				return e.encryptionSvc.NewEncryptWriter(dst, password, algorithm)
+			})
+		}
+		return e.encryptionSvc.EncryptContent(note.Content, password, algorithm)
+	}
+
+	// 沒有可用的保險庫時，需要上層處理密碼取得
+	if e.vaultSvc == nil || !e.vaultSvc.IsUnlocked() {
+		return nil, fmt.Errorf("需要密碼才能加密檔案內容")
+	}
+
+	var encrypted []byte
+	var err error
+	keyID := e.noteKeyIDs[note.ID]
+	meta := NewNoteMetadata(note)
+	if large {
+		encrypted, err = sealStreamContent(note.Content, func(dst io.Writer) (io.WriteCloser, error) {
+			writer, usedKeyID, err := e.vaultSvc.NewNoteEncryptWriter(dst, algorithm, keyID, meta)
+			keyID = usedKeyID
+			return writer, err
+		})
+	} else {
+		encrypted, keyID, err = e.vaultSvc.EncryptNoteWithMetadata(note.Content, algorithm, keyID, meta)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	// 注意：在實際實作中，這裡需要安全地取得密碼
-	// 可能從 Keychain、用戶輸入或其他安全儲存中取得
-	// 目前返回錯誤，要求上層處理密碼取得
-	return nil, fmt.Errorf("需要密碼才能加密檔案內容")
+	e.noteKeyIDs[note.ID] = keyID
+	return encrypted, nil
 }
 
 // EncryptWithPassword 使用密碼加密筆記內容
@@ -612,10 +1064,15 @@ func (e *editorService) EncryptWithPassword(noteID, password string) error {
 // 回傳：解密後的內容和可能的錯誤
 //
 // 注意：這是一個簡化的實作，實際應用中需要安全的憑證管理
-func (e *editorService) decryptWithStoredCredentials(encryptedData []byte, noteID string) (string, error) {
-	// 實際實作中應該從安全儲存（如 Keychain）中取得密碼
+func (e *editorService) decryptWithStoredCredentials(encryptedData []byte, noteID string) (string, *VaultNoteInfo, error) {
+	// 保險庫已解鎖時可直接使用資料金鑰解密
+	if e.vaultSvc != nil && e.vaultSvc.IsVaultData(encryptedData) && e.vaultSvc.IsUnlocked() {
+		return e.vaultSvc.DecryptNote(encryptedData)
+	}
+
+	// 實際實作中應該從安全儲存（如 Keychain）中取得主金鑰
 	// 目前返回錯誤，表示需要進一步實作
-	return "", fmt.Errorf("安全憑證管理功能尚未實作")
+	return "", nil, fmt.Errorf("安全憑證管理功能尚未實作")
 }
 
 // IsEncrypted 檢查指定筆記是否已加密
@@ -778,8 +1235,8 @@ func (e *editorService) PreviewMarkdownOptimized(content string) string {
 		return e.PreviewMarkdown(content)
 	}
 	
-	// 大內容分塊處理
-	result, err := e.ProcessLargeFileInChunks(content, func(chunk string) (string, error) {
+	// 大內容分塊處理，front matter 不顯示在預覽中
+	result, err := e.ProcessLargeFileInChunks(StripFrontMatter(content), func(chunk string) (string, error) {
 		var buf bytes.Buffer
 		err := e.markdown.Convert([]byte(chunk), &buf)
 		if err != nil {
@@ -1012,9 +1469,9 @@ func (e *editorService) GenerateTableTemplateMarkdown(rows, cols int) string {
 // 2. 如果有智慧編輯服務，對程式碼區塊進行語法高亮處理
 // 3. 回傳增強的 HTML 內容
 func (e *editorService) PreviewMarkdownWithHighlight(content string) string {
-	// 先進行基本的 Markdown 轉換
+	// 先進行基本的 Markdown 轉換，front matter 不顯示在預覽中
 	var buf bytes.Buffer
-	err := e.markdown.Convert([]byte(content), &buf)
+	err := e.markdown.Convert([]byte(StripFrontMatter(content)), &buf)
 	if err != nil {
 		return fmt.Sprintf("<p>Markdown 轉換錯誤: %s</p>", err.Error())
 	}
@@ -1062,4 +1519,292 @@ func (e *editorService) GetSmartEditingService() SmartEditingService {
 // 2. 允許動態替換智慧編輯服務
 func (e *editorService) SetSmartEditingService(smartEditSvc SmartEditingService) {
 	e.smartEditSvc = smartEditSvc
-}
\ No newline at end of file
+}
+
+// Options 取得編輯器服務的選用依賴和設定
+// 回傳：選用依賴和設定（未設定的服務為 nil）
+func (e *editorService) Options() EditorOptions {
+	return EditorOptions{
+		Vault:              e.vaultSvc,
+		Identity:           e.identitySvc,
+		Signing:            e.signingSvc,
+		Audit:              e.auditSvc,
+		RecoveryJournal:    e.recoveryJournal,
+		NoteIDIndex:        e.noteIDs,
+		Tags:               e.tagSvc,
+		SearchIndex:        e.searchIdx,
+		LeakageGuard:       e.leakGuard,
+		ObfuscateFilenames: e.obfuscateFilenames,
+	}
+}
+
+// SetOptions 設定編輯器服務的選用依賴和設定
+// 參數：options（選用依賴和設定，取代目前的所有選項）
+//
+// 執行流程：
+// 1. 保險庫服務變更時監聽工作階段的鎖定事件，鎖定時關閉已解密的筆記並將機密區塊換回密文
+// 2. 公鑰身分服務變更且使用獨立的工作階段時，同樣在鎖定時關閉已解密的筆記
+// 3. 更新所有選用依賴，未提供明文外洩防護時沿用目前的實例
+// 4. 隨機檔名設定只影響之後的保存，既有檔案在下次保存時才改名
+func (e *editorService) SetOptions(options EditorOptions) {
+	if options.Vault != nil && options.Vault != e.vaultSvc {
+		options.Vault.Session().AddLockListener(func(reason SessionLockReason) {
+			e.closeEncryptedNotes()
+			e.concealActiveSecretBlocks()
+		})
+	}
+	if options.Identity != nil && options.Identity != e.identitySvc &&
+		(options.Vault == nil || options.Identity.Session() != options.Vault.Session()) {
+		options.Identity.Session().AddLockListener(func(reason SessionLockReason) {
+			e.closeEncryptedNotes()
+		})
+	}
+	if options.LeakageGuard == nil {
+		options.LeakageGuard = e.leakGuard
+	}
+
+	e.vaultSvc = options.Vault
+	e.identitySvc = options.Identity
+	e.signingSvc = options.Signing
+	e.auditSvc = options.Audit
+	e.recoveryJournal = options.RecoveryJournal
+	e.noteIDs = options.NoteIDIndex
+	e.tagSvc = options.Tags
+	e.searchIdx = options.SearchIndex
+	e.leakGuard = options.LeakageGuard
+	e.obfuscateFilenames = options.ObfuscateFilenames
+}
+
+// ReauthenticateNote 重新驗證加密筆記的密碼
+// 參數：note（加密筆記）、password（保險庫、身分金鑰庫或筆記密碼）
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 未加密且不在加密資料夾中的筆記不需要重新驗證
+// 2. 依檔案格式以密碼重新解鎖保險庫或身分金鑰庫（已解鎖時同樣會驗證密碼），
+//    密碼格式的筆記以密碼解密檔案，加密資料夾中和尚未保存的筆記以保險庫密碼驗證
+// 3. 驗證失敗時計入重試次數並寫入稽核記錄
+// 4. 驗證成功後在短時間內允許匯出或分享這則筆記
+func (e *editorService) ReauthenticateNote(note *models.Note, password string) error {
+	if !IsSensitiveNote(note) {
+		return nil
+	}
+
+	var rawContent []byte
+	if note.FilePath != "" && e.fileRepo.FileExists(note.FilePath) {
+		data, err := e.fileRepo.ReadFile(note.FilePath)
+		if err != nil {
+			return fmt.Errorf("讀取加密檔案失敗: %w", err)
+		}
+		rawContent = data
+	}
+
+	var err error
+	switch {
+	case rawContent != nil && e.identitySvc != nil && e.identitySvc.IsRecipientData(rawContent):
+		err = e.identitySvc.Unlock(password)
+	case rawContent != nil && e.vaultSvc != nil && e.vaultSvc.IsVaultData(rawContent):
+		err = e.vaultSvc.Unlock(password)
+	case !note.IsEncrypted && note.InEncryptedFolder && e.vaultSvc != nil:
+		// 加密資料夾的檔案讀取時已解密，以保險庫密碼驗證
+		err = e.vaultSvc.Unlock(password)
+	case rawContent != nil:
+		err = guardPasswordAttempt(e.passwordSvc, note.FilePath, func() error {
+			_, decryptErr := e.encryptionSvc.DecryptContent(rawContent, password, "")
+			return decryptErr
+		}, nil)
+		recordAudit(e.auditSvc, AuditEventUnlock, note.FilePath, err, "")
+	case e.vaultSvc != nil:
+		err = e.vaultSvc.Unlock(password)
+	default:
+		err = fmt.Errorf("筆記尚未保存，無法驗證密碼")
+	}
+	if err != nil {
+		return fmt.Errorf("重新驗證失敗: %w", err)
+	}
+
+	e.leakGuard.GrantReauth(note)
+	return nil
+}
+
+// SignNote 以本機簽章金鑰簽署已保存的筆記
+// 參數：noteID（筆記 ID）
+// 回傳：簽署後的簽章狀態和可能的錯誤
+//
+// 執行流程：
+// 1. 確認簽章服務已設定且筆記已開啟
+// 2. 簽署筆記目前的內容（呼叫者應先保存，簽章才會與檔案內容相符）
+// 3. 記錄簽章狀態，之後以本機金鑰保存時自動重新簽署
+func (e *editorService) SignNote(noteID string) (*SignatureStatus, error) {
+	if e.signingSvc == nil {
+		return nil, fmt.Errorf("簽章服務未設定")
+	}
+
+	note, exists := e.activeNotes[noteID]
+	if !exists {
+		return nil, fmt.Errorf("筆記不存在或未開啟: %s", noteID)
+	}
+
+	status, err := e.signingSvc.SignNote(note.FilePath, note.Content)
+	if err != nil {
+		return nil, fmt.Errorf("簽署筆記失敗: %w", err)
+	}
+
+	e.noteSignatures[noteID] = status
+	return status, nil
+}
+
+// GetSignatureStatus 取得開啟的筆記的簽章狀態
+// 參數：noteID（筆記 ID）
+// 回傳：簽章狀態（未設定簽章服務或筆記未開啟時為 nil）
+func (e *editorService) GetSignatureStatus(noteID string) *SignatureStatus {
+	return e.noteSignatures[noteID]
+}
+
+// verifyNoteSignature 驗證開啟的筆記的簽章並記錄結果
+// 參數：note（開啟的筆記）
+func (e *editorService) verifyNoteSignature(note *models.Note) {
+	if e.signingSvc == nil {
+		return
+	}
+	e.noteSignatures[note.ID] = e.signingSvc.VerifyNote(note.FilePath, note.Content)
+}
+
+// updateNoteSignature 保存筆記後更新簽章
+// 參數：note（已保存的筆記）、oldPath（保存前的檔案路徑）
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 檔名變更時將簽章檔移到新路徑
+// 2. 原本以本機金鑰簽署且簽章有效的筆記重新簽署（作者本人的修改）
+// 3. 其他筆記重新驗證，他人簽署的筆記被修改後即顯示為內容已遭修改
+func (e *editorService) updateNoteSignature(note *models.Note, oldPath string) error {
+	if e.signingSvc == nil {
+		return nil
+	}
+
+	if err := e.signingSvc.MoveSignature(oldPath, note.FilePath); err != nil {
+		return fmt.Errorf("移動簽章檔失敗: %w", err)
+	}
+
+	if previous := e.noteSignatures[note.ID]; previous != nil && previous.State == SignatureValid && previous.IsOwnKey {
+		status, err := e.signingSvc.SignNote(note.FilePath, note.Content)
+		if err != nil {
+			return fmt.Errorf("重新簽署筆記失敗: %w", err)
+		}
+		e.noteSignatures[note.ID] = status
+		return nil
+	}
+
+	e.verifyNoteSignature(note)
+	return nil
+}
+
+// closeEncryptedNotes 關閉所有已解密的加密筆記
+// 工作階段鎖定時呼叫，清除記憶體中的明文內容並從活躍快取中移除，
+// 包含合併基準、front matter 和機密區塊等保留明文的狀態（筆記 ID 固定，重新開啟時不應沿用）
+func (e *editorService) closeEncryptedNotes() {
+	for noteID, note := range e.activeNotes {
+		if !note.IsEncrypted {
+			continue
+		}
+		note.Content = ""
+		delete(e.activeNotes, noteID)
+		delete(e.noteKeyIDs, noteID)
+		delete(e.noteRecipients, noteID)
+		delete(e.noteSignatures, noteID)
+		delete(e.diskStates, noteID)
+		delete(e.frontMatters, noteID)
+		delete(e.secretBlocks, noteID)
+	}
+
+	e.titlesMu.Lock()
+	e.noteTitles = make(map[string]string)
+	e.titlesMu.Unlock()
+
+	// 撤銷匯出、分享的授權，並清除分享時留下的暫存檔
+	e.leakGuard.RevokeReauth()
+	if err := e.leakGuard.WipeTempFiles(); err != nil {
+		log.Printf("清除暫存檔失敗: %v", err)
+	}
+}
+
+// encryptedFilePath 取得加密筆記保存時使用的檔案路徑
+// 參數：note（加密筆記）
+// 回傳：檔案路徑和可能的錯誤
+//
+// 執行流程：
+// 1. 只有保險庫格式的筆記有中繼資料標頭，以密碼或身分私鑰開啟的筆記維持原路徑
+// 2. 啟用隨機檔名且目前不是隨機檔名時，在同一目錄下產生隨機檔名
+// 3. 停用隨機檔名且目前是隨機檔名時，改回以標題命名（目標已存在時維持原路徑）
+func (e *editorService) encryptedFilePath(note *models.Note) (string, error) {
+	if _, ok := e.notePassword(note.ID); ok || e.vaultSvc == nil {
+		return note.FilePath, nil
+	}
+	if _, ok := e.noteRecipients[note.ID]; ok {
+		return note.FilePath, nil
+	}
+
+	obfuscated := IsObfuscatedFileName(filepath.Base(note.FilePath))
+	switch {
+	case e.obfuscateFilenames && !obfuscated:
+		return NewObfuscatedFilePath(filepath.Dir(note.FilePath))
+	case !e.obfuscateFilenames && obfuscated:
+		titledPath := DecryptedFilePath(note.FilePath, note.Title) + ".enc"
+		if e.fileRepo.FileExists(titledPath) {
+			return note.FilePath, nil
+		}
+		return titledPath, nil
+	}
+	return note.FilePath, nil
+}
+
+// NoteDisplayTitle 取得加密筆記在檔案樹中顯示的標題
+// 參數：filePath（檔案路徑）
+// 回傳：筆記標題和是否能取得
+//
+// 執行流程：
+// 1. 優先使用已快取的標題
+// 2. 保險庫已解鎖時讀取檔案並只解密中繼資料標頭
+// 3. 將取得的標題加入快取，保險庫鎖定時快取會被清除
+func (e *editorService) NoteDisplayTitle(filePath string) (string, bool) {
+	e.titlesMu.Lock()
+	title, ok := e.noteTitles[filePath]
+	e.titlesMu.Unlock()
+	if ok {
+		return title, true
+	}
+
+	if e.vaultSvc == nil || !e.vaultSvc.IsUnlocked() {
+		return "", false
+	}
+
+	data, err := e.fileRepo.ReadFile(filePath)
+	if err != nil || !e.vaultSvc.IsVaultData(data) {
+		return "", false
+	}
+	meta, err := e.vaultSvc.ReadNoteMetadata(data)
+	if err != nil || meta == nil || meta.Title == "" {
+		return "", false
+	}
+
+	e.rememberNoteTitle(filePath, meta.Title)
+	return meta.Title, true
+}
+
+// rememberNoteTitle 快取加密筆記的標題
+// 參數：filePath（檔案路徑）、title（筆記標題）
+func (e *editorService) rememberNoteTitle(filePath, title string) {
+	e.titlesMu.Lock()
+	defer e.titlesMu.Unlock()
+	e.noteTitles[filePath] = title
+}
+
+// forgetNoteTitle 移除加密筆記的標題快取
+// 參數：filePath（檔案路徑）
+func (e *editorService) forgetNoteTitle(filePath string) {
+	e.titlesMu.Lock()
+	defer e.titlesMu.Unlock()
+	delete(e.noteTitles, filePath)
+}
+
@@ -4,6 +4,7 @@ package services
 
 import (
 	"fmt"
+	"io"
 	"mac-notebook-app/internal/models"
 	"strings"
 	"testing"
@@ -43,6 +44,14 @@ func (m *mockEncryptionService) DecryptContent(encryptedData []byte, password st
 	return "", fmt.Errorf("invalid encrypted data")
 }
 
+func (m *mockEncryptionService) NewEncryptWriter(dst io.Writer, password, algorithm string) (io.WriteCloser, error) {
+	return nil, fmt.Errorf("stream encryption not available in test")
+}
+
+func (m *mockEncryptionService) NewDecryptReader(src io.Reader, password string) (io.Reader, error) {
+	return nil, fmt.Errorf("stream encryption not available in test")
+}
+
 func (m *mockEncryptionService) SetupBiometricAuth(noteID string) error {
 	return nil
 }
@@ -0,0 +1,398 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含加密資料夾：以裝飾器包裝檔案儲存庫，寫入加密資料夾中的任何檔案時以保險庫資料金鑰加密，
+// 讀取時自動解密，檔案管理、檔案樹和搜尋在保險庫解鎖時不需要任何修改
+package services
+
+import (
+	"bytes"         // 位元組處理
+	"encoding/json" // JSON 序列化
+	"errors"        // 錯誤處理
+	"fmt"           // 格式化輸出
+	"path/filepath" // 檔案路徑處理
+	"strings"       // 字串處理
+	"sync"          // 同步控制
+	"time"          // 時間處理
+
+	"mac-notebook-app/internal/models"       // 引入資料模型
+	"mac-notebook-app/internal/repositories" // 引入儲存庫介面
+)
+
+// 加密資料夾相關常數
+const (
+	EncryptedFolderMarker        = ".notebook-encrypted" // 標記加密資料夾的檔案名稱，保存資料夾使用的資料金鑰 ID
+	EncryptedFolderMarkerVersion = "1.0"                 // 標記檔案的格式版本
+)
+
+// encryptedFileMagic 加密資料夾中檔案的開頭標記，其後為保險庫的信封格式
+var encryptedFileMagic = []byte("NBENCF1\n")
+
+// ErrEncryptedFolderUnavailable 沒有保險庫時無法存取加密資料夾
+var ErrEncryptedFolderUnavailable = errors.New("保險庫無法使用，無法存取加密資料夾")
+
+// EncryptedFileRepository 定義加密資料夾檔案儲存庫的介面
+// 實作 repositories.FileRepository，可以直接取代原本的檔案儲存庫
+type EncryptedFileRepository interface {
+	repositories.FileRepository
+
+	// SetVaultService 設定加密和解密檔案使用的保險庫服務
+	// 參數：vault（保險庫服務）
+	SetVaultService(vault VaultService)
+
+	// CreateEncryptedFolder 將目錄設為加密資料夾，並加密其中既有的檔案
+	// 參數：path（目錄路徑，"." 代表整個筆記本）
+	// 回傳：可能的錯誤（保險庫鎖定時為 ErrVaultLocked）
+	CreateEncryptedFolder(path string) error
+
+	// IsEncryptedFolder 檢查目錄本身是否為加密資料夾
+	// 參數：path（目錄路徑）
+	// 回傳：是否為加密資料夾
+	IsEncryptedFolder(path string) bool
+
+	// IsEncryptedPath 檢查路徑是否位於加密資料夾中
+	// 參數：path（檔案或目錄路徑）
+	// 回傳：寫入此路徑的檔案是否會被加密
+	IsEncryptedPath(path string) bool
+
+	// SealPath 加密路徑下仍為明文、但位於加密資料夾中的檔案（例如移入加密資料夾的檔案）
+	// 參數：path（檔案或目錄路徑）
+	// 回傳：可能的錯誤（保險庫鎖定時為 ErrVaultLocked）
+	SealPath(path string) error
+}
+
+// encryptedFileWriter 支援以加密檔案權限寫入的檔案儲存庫（例如 LocalFileRepository）
+type encryptedFileWriter interface {
+	WriteEncryptedFile(path string, data []byte) error
+}
+
+// encryptedFolderMarker 加密資料夾標記檔案的內容
+type encryptedFolderMarker struct {
+	Version   string    `json:"version"`          // 格式版本
+	KeyID     string    `json:"key_id,omitempty"` // 資料夾使用的資料金鑰 ID，第一次寫入時產生
+	CreatedAt time.Time `json:"created_at"`       // 建立時間
+}
+
+// encryptedFileRepository 實作 EncryptedFileRepository 介面
+type encryptedFileRepository struct {
+	inner repositories.FileRepository // 實際存取檔案的儲存庫
+	vault VaultService                // 保險庫服務
+	mutex sync.Mutex                  // 保護標記檔案中資料金鑰 ID 的產生
+}
+
+// NewEncryptedFileRepository 建立加密資料夾檔案儲存庫
+// 參數：inner（實際存取檔案的儲存庫）
+// 回傳：加密資料夾檔案儲存庫實例，保險庫透過 SetVaultService 設定
+func NewEncryptedFileRepository(inner repositories.FileRepository) EncryptedFileRepository {
+	return &encryptedFileRepository{inner: inner}
+}
+
+// SetVaultService 設定加密和解密檔案使用的保險庫服務
+// 參數：vault（保險庫服務）
+func (r *encryptedFileRepository) SetVaultService(vault VaultService) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.vault = vault
+}
+
+// ReadFile 讀取檔案內容，加密的檔案自動解密
+// 參數：path（檔案路徑）
+// 回傳：檔案明文和可能的錯誤（保險庫鎖定時為 ErrVaultLocked）
+//
+// 執行流程：
+// 1. 讀取原始內容，沒有加密標記時直接回傳
+// 2. 依開頭標記判斷是否加密，與檔案所在位置無關，移出加密資料夾的檔案仍可讀取
+// 3. 以保險庫解密內容
+func (r *encryptedFileRepository) ReadFile(path string) ([]byte, error) {
+	data, err := r.inner.ReadFile(path)
+	if err != nil || !bytes.HasPrefix(data, encryptedFileMagic) {
+		return data, err
+	}
+
+	vault, err := r.unlockedVault()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, _, err := vault.DecryptNote(data[len(encryptedFileMagic):])
+	if err != nil {
+		return nil, fmt.Errorf("解密檔案 %s 失敗: %w", path, err)
+	}
+	return []byte(plaintext), nil
+}
+
+// WriteFile 寫入檔案內容，位於加密資料夾中的檔案自動加密
+// 參數：path（檔案路徑）、data（明文內容）
+// 回傳：可能的錯誤（保險庫鎖定時為 ErrVaultLocked）
+func (r *encryptedFileRepository) WriteFile(path string, data []byte) error {
+	folder, ok := r.encryptedFolderOf(path)
+	if !ok || bytes.HasPrefix(data, encryptedFileMagic) {
+		return r.inner.WriteFile(path, data)
+	}
+
+	sealed, err := r.sealFile(folder, data)
+	if err != nil {
+		return err
+	}
+	if writer, ok := r.inner.(encryptedFileWriter); ok {
+		return writer.WriteEncryptedFile(path, sealed)
+	}
+	return r.inner.WriteFile(path, sealed)
+}
+
+// FileExists 檢查指定路徑的檔案是否存在
+// 參數：path（檔案路徑）
+// 回傳：檔案是否存在
+func (r *encryptedFileRepository) FileExists(path string) bool {
+	return r.inner.FileExists(path)
+}
+
+// DeleteFile 刪除指定路徑的檔案
+// 參數：path（檔案路徑）
+// 回傳：可能的錯誤
+func (r *encryptedFileRepository) DeleteFile(path string) error {
+	return r.inner.DeleteFile(path)
+}
+
+// CreateDirectory 建立指定路徑的目錄
+// 參數：path（目錄路徑）
+// 回傳：可能的錯誤
+func (r *encryptedFileRepository) CreateDirectory(path string) error {
+	return r.inner.CreateDirectory(path)
+}
+
+// ListDirectory 列出目錄中的檔案和子目錄，不包含加密資料夾的標記檔案
+// 參數：path（目錄路徑）
+// 回傳：檔案資訊陣列和可能的錯誤
+func (r *encryptedFileRepository) ListDirectory(path string) ([]*models.FileInfo, error) {
+	fileInfos, err := r.inner.ListDirectory(path)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := fileInfos[:0]
+	for _, info := range fileInfos {
+		if !info.IsDirectory && info.Name == EncryptedFolderMarker {
+			continue
+		}
+		filtered = append(filtered, info)
+	}
+	return filtered, nil
+}
+
+// WalkDirectory 遞迴遍歷目錄，略過加密資料夾的標記檔案
+// 參數：path（目錄路徑）、walkFunc（對每個檔案執行的回調函數）
+// 回傳：可能的錯誤
+func (r *encryptedFileRepository) WalkDirectory(path string, walkFunc func(*models.FileInfo) error) error {
+	return r.inner.WalkDirectory(path, func(info *models.FileInfo) error {
+		if !info.IsDirectory && info.Name == EncryptedFolderMarker {
+			return nil
+		}
+		return walkFunc(info)
+	})
+}
+
+// CreateEncryptedFolder 將目錄設為加密資料夾，並加密其中既有的檔案
+// 參數：path（目錄路徑，"." 代表整個筆記本）
+// 回傳：可能的錯誤（保險庫鎖定時為 ErrVaultLocked）
+//
+// 執行流程：
+// 1. 確認保險庫已解鎖，中繼資料目錄不能設為加密資料夾
+// 2. 建立目錄和標記檔案，已是加密資料夾時不重複建立
+// 3. 加密目錄中既有的明文檔案
+func (r *encryptedFileRepository) CreateEncryptedFolder(path string) error {
+	path = filepath.Clean(path)
+	if isNotebookMetaPath(path) {
+		return fmt.Errorf("中繼資料目錄不能設為加密資料夾: %s", path)
+	}
+	if _, err := r.unlockedVault(); err != nil {
+		return err
+	}
+
+	if !r.IsEncryptedFolder(path) {
+		if path != "." {
+			if err := r.inner.CreateDirectory(path); err != nil {
+				return err
+			}
+		}
+		if err := r.writeMarker(path, &encryptedFolderMarker{
+			Version:   EncryptedFolderMarkerVersion,
+			CreatedAt: time.Now(),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return r.SealPath(path)
+}
+
+// IsEncryptedFolder 檢查目錄本身是否為加密資料夾
+// 參數：path（目錄路徑）
+// 回傳：是否為加密資料夾
+func (r *encryptedFileRepository) IsEncryptedFolder(path string) bool {
+	return r.inner.FileExists(filepath.Join(path, EncryptedFolderMarker))
+}
+
+// IsEncryptedPath 檢查路徑是否位於加密資料夾中
+// 參數：path（檔案或目錄路徑）
+// 回傳：寫入此路徑的檔案是否會被加密
+func (r *encryptedFileRepository) IsEncryptedPath(path string) bool {
+	_, ok := r.encryptedFolderOf(path)
+	return ok
+}
+
+// SealPath 加密路徑下仍為明文、但位於加密資料夾中的檔案
+// 參數：path（檔案或目錄路徑）
+// 回傳：可能的錯誤（保險庫鎖定時為 ErrVaultLocked）
+//
+// 執行流程：
+// 1. 遍歷路徑下的所有檔案，略過中繼資料目錄和標記檔案
+// 2. 已加密或不在加密資料夾中的檔案維持原樣
+// 3. 讀取明文後經由 WriteFile 加密寫回，並刪除保留明文內容的 .bak
+func (r *encryptedFileRepository) SealPath(path string) error {
+	var pending []string
+	err := r.inner.WalkDirectory(path, func(info *models.FileInfo) error {
+		if info.IsDirectory || info.Name == EncryptedFolderMarker || isNotebookMetaPath(info.Path) {
+			return nil
+		}
+		if r.IsEncryptedPath(info.Path) {
+			pending = append(pending, info.Path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("遍歷加密資料夾失敗: %w", err)
+	}
+
+	for _, filePath := range pending {
+		// 先前加密的檔案的明文備份已經刪除
+		if !r.inner.FileExists(filePath) {
+			continue
+		}
+		data, err := r.inner.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		if bytes.HasPrefix(data, encryptedFileMagic) {
+			continue
+		}
+		if err := r.WriteFile(filePath, data); err != nil {
+			return fmt.Errorf("加密檔案 %s 失敗: %w", filePath, err)
+		}
+		if backupPath := filePath + repositories.BackupFileSuffix; r.inner.FileExists(backupPath) {
+			if err := r.inner.DeleteFile(backupPath); err != nil {
+				return fmt.Errorf("刪除明文備份 %s 失敗: %w", backupPath, err)
+			}
+		}
+	}
+	return nil
+}
+
+// encryptedFolderOf 找出路徑所屬的最近一層加密資料夾
+// 參數：path（檔案或目錄路徑）
+// 回傳：加密資料夾路徑和是否位於加密資料夾中（中繼資料目錄和標記檔案本身不加密）
+func (r *encryptedFileRepository) encryptedFolderOf(path string) (string, bool) {
+	path = filepath.Clean(path)
+	if path == "." || isNotebookMetaPath(path) || filepath.Base(path) == EncryptedFolderMarker {
+		return "", false
+	}
+
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if r.IsEncryptedFolder(dir) {
+			return dir, true
+		}
+		if dir == "." || dir == string(filepath.Separator) {
+			return "", false
+		}
+	}
+}
+
+// sealFile 以加密資料夾的資料金鑰加密檔案內容
+// 參數：folder（加密資料夾路徑）、data（明文內容）
+// 回傳：加上開頭標記的加密內容和可能的錯誤
+//
+// 執行流程：
+// 1. 確認保險庫已解鎖並讀取標記檔案
+// 2. 以資料夾的資料金鑰加密內容，資料夾還沒有資料金鑰時產生並寫回標記檔案
+func (r *encryptedFileRepository) sealFile(folder string, data []byte) ([]byte, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.vault == nil {
+		return nil, ErrEncryptedFolderUnavailable
+	}
+	if !r.vault.IsUnlocked() {
+		return nil, ErrVaultLocked
+	}
+
+	marker, err := r.readMarker(folder)
+	if err != nil {
+		return nil, err
+	}
+	envelope, keyID, err := r.vault.EncryptNote(string(data), AlgorithmAES256, marker.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if marker.KeyID != keyID {
+		marker.KeyID = keyID
+		if err := r.writeMarker(folder, marker); err != nil {
+			return nil, err
+		}
+	}
+
+	return append(append([]byte{}, encryptedFileMagic...), envelope...), nil
+}
+
+// unlockedVault 取得已解鎖的保險庫
+// 回傳：保險庫服務和可能的錯誤（沒有保險庫或已鎖定時）
+func (r *encryptedFileRepository) unlockedVault() (VaultService, error) {
+	r.mutex.Lock()
+	vault := r.vault
+	r.mutex.Unlock()
+
+	if vault == nil {
+		return nil, ErrEncryptedFolderUnavailable
+	}
+	if !vault.IsUnlocked() {
+		return nil, ErrVaultLocked
+	}
+	return vault, nil
+}
+
+// readMarker 讀取加密資料夾的標記檔案
+// 參數：folder（加密資料夾路徑）
+// 回傳：標記內容和可能的錯誤
+func (r *encryptedFileRepository) readMarker(folder string) (*encryptedFolderMarker, error) {
+	data, err := r.inner.ReadFile(filepath.Join(folder, EncryptedFolderMarker))
+	if err != nil {
+		return nil, err
+	}
+	var marker encryptedFolderMarker
+	if err := json.Unmarshal(data, &marker); err != nil {
+		return nil, fmt.Errorf("解析加密資料夾標記失敗: %w", err)
+	}
+	return &marker, nil
+}
+
+// writeMarker 寫入加密資料夾的標記檔案
+// 參數：folder（加密資料夾路徑）、marker（標記內容）
+// 回傳：可能的錯誤
+func (r *encryptedFileRepository) writeMarker(folder string, marker *encryptedFolderMarker) error {
+	data, err := json.MarshalIndent(marker, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化加密資料夾標記失敗: %w", err)
+	}
+	return r.inner.WriteFile(filepath.Join(folder, EncryptedFolderMarker), data)
+}
+
+// markEncryptedFolder 依檔案路徑標記筆記是否位於加密資料夾中，讓明文外洩防護將其視為敏感內容
+// 參數：note（開啟或保存後的筆記）
+func (e *editorService) markEncryptedFolder(note *models.Note) {
+	encryptedRepo, ok := e.fileRepo.(EncryptedFileRepository)
+	note.InEncryptedFolder = ok && note.FilePath != "" && encryptedRepo.IsEncryptedPath(note.FilePath)
+}
+
+// isNotebookMetaPath 檢查路徑是否位於筆記本中繼資料目錄中
+// 參數：path（檔案或目錄路徑）
+// 回傳：是否位於中繼資料目錄中
+func isNotebookMetaPath(path string) bool {
+	first, _, _ := strings.Cut(filepath.ToSlash(filepath.Clean(path)), "/")
+	return first == NotebookMetaDir
+}
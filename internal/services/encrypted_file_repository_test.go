@@ -0,0 +1,187 @@
+// Package services 提供加密資料夾檔案儲存庫的單元測試
+// 測試加密資料夾中的檔案以密文保存、讀取時解密，以及檔案管理服務的移動和複製
+package services
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mac-notebook-app/internal/models"
+	"mac-notebook-app/internal/repositories"
+)
+
+// createTestEncryptedFileRepository 建立以暫存目錄為基礎的加密資料夾檔案儲存庫
+// 回傳：加密資料夾檔案儲存庫、基礎目錄和保險庫服務
+func createTestEncryptedFileRepository(t *testing.T) (EncryptedFileRepository, string, VaultService) {
+	baseDir := t.TempDir()
+	local, err := repositories.NewLocalFileRepository(baseDir)
+	if err != nil {
+		t.Fatalf("建立檔案儲存庫失敗: %v", err)
+	}
+	vault, _ := createTestVaultService(t)
+	repo := NewEncryptedFileRepository(local)
+	repo.SetVaultService(vault)
+	return repo, baseDir, vault
+}
+
+// TestEncryptedFolderReadWrite 測試加密資料夾中的檔案以密文保存，其他位置維持明文
+func TestEncryptedFolderReadWrite(t *testing.T) {
+	repo, baseDir, vault := createTestEncryptedFileRepository(t)
+	repo.WriteFile("journal/existing.md", []byte("# 既有日記"))
+	repo.WriteFile("public.md", []byte("# 公開筆記"))
+
+	if err := repo.CreateEncryptedFolder("journal"); !errors.Is(err, ErrVaultLocked) {
+		t.Errorf("保險庫鎖定時不應建立加密資料夾: %v", err)
+	}
+	vault.Initialize("Password123!")
+	if err := repo.CreateEncryptedFolder(NotebookMetaDir); err == nil {
+		t.Error("中繼資料目錄不應設為加密資料夾")
+	}
+	if err := repo.CreateEncryptedFolder("journal"); err != nil {
+		t.Fatalf("建立加密資料夾失敗: %v", err)
+	}
+
+	// 既有檔案和新檔案（包含子目錄和非 Markdown 檔案）都以密文保存
+	image := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+	repo.WriteFile("journal/2024/trip.md", []byte("# 旅行計畫"))
+	repo.WriteFile("journal/photo.png", image)
+	for _, name := range []string{"journal/existing.md", "journal/2024/trip.md", "journal/photo.png"} {
+		raw, _ := os.ReadFile(filepath.Join(baseDir, name))
+		if !bytes.HasPrefix(raw, encryptedFileMagic) || bytes.Contains(raw, []byte("計畫")) || bytes.Contains(raw, []byte("既有")) {
+			t.Errorf("%s 應該以密文保存: %q", name, raw)
+		}
+	}
+	if raw, _ := os.ReadFile(filepath.Join(baseDir, "public.md")); string(raw) != "# 公開筆記" {
+		t.Errorf("加密資料夾以外的檔案應該維持明文: %q", raw)
+	}
+
+	if data, err := repo.ReadFile("journal/2024/trip.md"); err != nil || string(data) != "# 旅行計畫" {
+		t.Errorf("讀取時應該解密: %q, %v", data, err)
+	}
+	if data, err := repo.ReadFile("journal/photo.png"); err != nil || !bytes.Equal(data, image) {
+		t.Errorf("二進位檔案應該完整還原: %v, %v", data, err)
+	}
+
+	// 列表和遍歷不包含標記檔案
+	infos, _ := repo.ListDirectory("journal")
+	var walked []string
+	repo.WalkDirectory("journal", func(info *models.FileInfo) error {
+		walked = append(walked, info.Name)
+		return nil
+	})
+	for _, name := range append(walked, fileInfoNames(infos)...) {
+		if name == EncryptedFolderMarker {
+			t.Error("列表和遍歷不應包含加密資料夾的標記檔案")
+		}
+	}
+	if len(infos) != 3 {
+		t.Errorf("加密資料夾應該列出 3 個項目，實際為 %v", fileInfoNames(infos))
+	}
+
+	vault.Lock()
+	if _, err := repo.ReadFile("journal/existing.md"); !errors.Is(err, ErrVaultLocked) {
+		t.Errorf("保險庫鎖定時讀取應該回傳 ErrVaultLocked: %v", err)
+	}
+	if err := repo.WriteFile("journal/new.md", []byte("鎖定時寫入")); !errors.Is(err, ErrVaultLocked) {
+		t.Errorf("保險庫鎖定時寫入應該回傳 ErrVaultLocked: %v", err)
+	}
+	if data, err := repo.ReadFile("public.md"); err != nil || string(data) != "# 公開筆記" {
+		t.Errorf("保險庫鎖定時仍應能讀取明文檔案: %v", err)
+	}
+}
+
+// TestEncryptedFolderFileManager 測試檔案管理服務在加密資料夾中移動和複製檔案
+func TestEncryptedFolderFileManager(t *testing.T) {
+	repo, baseDir, vault := createTestEncryptedFileRepository(t)
+	vault.Initialize("Password123!")
+	fileManager, err := NewLocalFileManagerService(repo, baseDir)
+	if err != nil {
+		t.Fatalf("建立檔案管理服務失敗: %v", err)
+	}
+
+	repo.CreateEncryptedFolder("vault")
+	repo.WriteFile("draft.md", []byte("# 草稿"))
+	repo.WriteFile("vault/secret.md", []byte("# 機密"))
+
+	// 移入加密資料夾的明文檔案會被加密
+	if err := fileManager.MoveFile("draft.md", "vault"); err != nil {
+		t.Fatalf("移動檔案失敗: %v", err)
+	}
+	if raw, _ := os.ReadFile(filepath.Join(baseDir, "vault", "draft.md")); !bytes.HasPrefix(raw, encryptedFileMagic) {
+		t.Errorf("移入加密資料夾的檔案應該加密: %q", raw)
+	}
+
+	// 複製加密資料夾時複本也是加密資料夾
+	if err := fileManager.CopyFile("vault", "vault-copy"); err != nil {
+		t.Fatalf("複製資料夾失敗: %v", err)
+	}
+	if !repo.IsEncryptedFolder("vault-copy") {
+		t.Error("加密資料夾的複本應該也是加密資料夾")
+	}
+	if raw, _ := os.ReadFile(filepath.Join(baseDir, "vault-copy", "secret.md")); !bytes.HasPrefix(raw, encryptedFileMagic) {
+		t.Errorf("複本中的檔案應該加密: %q", raw)
+	}
+
+	// 移出加密資料夾的檔案維持密文，仍可讀取
+	if err := fileManager.MoveFile("vault/secret.md", "secret.md"); err != nil {
+		t.Fatalf("移動檔案失敗: %v", err)
+	}
+	if data, err := repo.ReadFile("secret.md"); err != nil || string(data) != "# 機密" {
+		t.Errorf("移出加密資料夾的檔案應該能解密: %q, %v", data, err)
+	}
+
+	// 搜尋仍可找到加密資料夾中的檔案，且不會出現標記檔案
+	results, err := fileManager.SearchFiles(".", "*", true)
+	if err != nil {
+		t.Fatalf("搜尋檔案失敗: %v", err)
+	}
+	names := strings.Join(fileInfoNames(results), ",")
+	if !strings.Contains(names, "draft.md") || strings.Contains(names, EncryptedFolderMarker) {
+		t.Errorf("搜尋結果不正確: %s", names)
+	}
+}
+
+// fileInfoNames 取得檔案資訊陣列中的名稱
+// 參數：infos（檔案資訊陣列）
+// 回傳：名稱陣列
+func fileInfoNames(infos []*models.FileInfo) []string {
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	return names
+}
+
+// TestEncryptedFolderRemovesPlaintextBackup 測試加密既有檔案時不會留下明文的上一版備份
+func TestEncryptedFolderRemovesPlaintextBackup(t *testing.T) {
+	baseDir := t.TempDir()
+	local, _ := repositories.NewLocalFileRepository(baseDir)
+	local.SetKeepBackup(true)
+	vault, _ := createTestVaultService(t)
+	vault.Initialize("Password123!")
+	repo := NewEncryptedFileRepository(local)
+	repo.SetVaultService(vault)
+
+	repo.WriteFile("diary/day1.md", []byte("第一版"))
+	repo.WriteFile("diary/day1.md", []byte("第二版"))
+	if err := repo.CreateEncryptedFolder("diary"); err != nil {
+		t.Fatalf("建立加密資料夾失敗: %v", err)
+	}
+	if local.FileExists("diary/day1.md" + repositories.BackupFileSuffix) {
+		t.Error("加密既有檔案後不應保留明文的 .bak")
+	}
+
+	// 之後的備份是上一版的密文
+	repo.WriteFile("diary/day1.md", []byte("第三版"))
+	raw, _ := os.ReadFile(filepath.Join(baseDir, "diary", "day1.md"+repositories.BackupFileSuffix))
+	if !bytes.HasPrefix(raw, encryptedFileMagic) {
+		t.Errorf("加密資料夾中的 .bak 應該是密文: %q", raw)
+	}
+	if info, _ := os.Stat(filepath.Join(baseDir, "diary", "day1.md")); info.Mode().Perm() != repositories.DefaultEncryptedFileMode {
+		t.Errorf("加密資料夾中的檔案權限應為 %o，實際為 %o", repositories.DefaultEncryptedFileMode, info.Mode().Perm())
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"encoding/json"        // JSON 序列化
 	"errors"               // 錯誤處理
 	"fmt"                  // 格式化輸出
+	"golang.org/x/crypto/argon2"           // Argon2id 金鑰衍生函數
 	"golang.org/x/crypto/chacha20poly1305" // ChaCha20-Poly1305 加密演算法
 	"golang.org/x/crypto/pbkdf2"           // PBKDF2 金鑰衍生函數
 	"io"                   // 輸入輸出介面
@@ -29,7 +30,36 @@ const (
 	SaltSize     = 32  // 鹽值大小（位元組）
 	NonceSize    = 12  // Nonce 大小（位元組）
 	KeySize      = 32  // 金鑰大小（位元組）
-	PBKDF2Rounds = 100000 // PBKDF2 迭代次數
+	PBKDF2Rounds = 100000 // PBKDF2 迭代次數（舊格式使用）
+)
+
+// 金鑰衍生函數名稱常數
+const (
+	KDFPBKDF2   = "pbkdf2-sha256" // PBKDF2-SHA256（舊格式）
+	KDFArgon2id = "argon2id"      // Argon2id（目前預設）
+)
+
+// Argon2id 預設參數常數
+const (
+	Argon2idMemory      = 64 * 1024 // 記憶體用量（KiB），即 64 MiB
+	Argon2idIterations  = 3         // 迭代次數
+	Argon2idParallelism = 4         // 平行度
+)
+
+// KDF 參數上限常數，避免惡意檔案要求過高的運算成本
+const (
+	maxArgon2idMemory      = 1024 * 1024 // 記憶體用量上限（KiB），即 1 GiB
+	maxArgon2idIterations  = 64          // Argon2id 迭代次數上限
+	maxArgon2idParallelism = 64          // Argon2id 平行度上限
+	maxPBKDF2Rounds        = 10000000    // PBKDF2 迭代次數上限
+)
+
+// 加密資料格式版本常數
+const (
+	EncryptedDataVersionPassword = "1.0" // 以密碼直接衍生金鑰加密的格式（PBKDF2，參數固定）
+	EncryptedDataVersionVault    = "2.0" // 以保險庫資料金鑰加密的信封格式
+	EncryptedDataVersionKDF      = "3.0" // 以密碼衍生金鑰加密並記錄 KDF 參數的格式
+	EncryptedDataVersionRecipients = "4.0" // 以收件人 X25519 公鑰包裝檔案金鑰的格式
 )
 
 // 密碼強度要求常數
@@ -47,12 +77,103 @@ type EncryptedData struct {
 	Nonce     string `json:"nonce"`     // Base64 編碼的隨機數
 	Data      string `json:"data"`      // Base64 編碼的加密內容
 	Checksum  string `json:"checksum"`  // SHA-256 校驗和
+	KeyID     string `json:"key_id,omitempty"` // 保險庫資料金鑰識別碼（僅信封格式使用）
+	KDF       *KDFParams `json:"kdf,omitempty"` // 金鑰衍生函數參數（3.0 格式使用）
+	Metadata  string `json:"metadata,omitempty"` // Base64 編碼的加密中繼資料（僅信封格式使用）
+	Recipients []RecipientStanza `json:"recipients,omitempty"` // 每位收件人包裝後的檔案金鑰（僅收件人格式使用）
+}
+
+// KDFParams 代表金鑰衍生函數及其成本參數
+// 參數隨密文一起保存，日後提高成本時舊檔案仍可解密
+type KDFParams struct {
+	Name        string `json:"name"`                  // 金鑰衍生函數名稱
+	Memory      uint32 `json:"memory,omitempty"`      // 記憶體用量（KiB，僅 Argon2id 使用）
+	Iterations  uint32 `json:"iterations"`            // 迭代次數
+	Parallelism uint8  `json:"parallelism,omitempty"` // 平行度（僅 Argon2id 使用）
+}
+
+// DefaultKDFParams 取得目前預設的金鑰衍生參數（Argon2id）
+// 回傳：KDFParams 實例
+func DefaultKDFParams() KDFParams {
+	return KDFParams{
+		Name:        KDFArgon2id,
+		Memory:      Argon2idMemory,
+		Iterations:  Argon2idIterations,
+		Parallelism: Argon2idParallelism,
+	}
+}
+
+// LegacyKDFParams 取得舊格式使用的金鑰衍生參數（PBKDF2-SHA256）
+// 回傳：KDFParams 實例
+func LegacyKDFParams() KDFParams {
+	return KDFParams{
+		Name:       KDFPBKDF2,
+		Iterations: PBKDF2Rounds,
+	}
+}
+
+// Validate 驗證金鑰衍生參數是否受支援且在合理範圍內
+// 回傳：可能的錯誤
+func (p KDFParams) Validate() error {
+	switch p.Name {
+	case KDFArgon2id:
+		if p.Iterations == 0 || p.Iterations > maxArgon2idIterations {
+			return fmt.Errorf("Argon2id 迭代次數無效: %d", p.Iterations)
+		}
+		if p.Parallelism == 0 || p.Parallelism > maxArgon2idParallelism {
+			return fmt.Errorf("Argon2id 平行度無效: %d", p.Parallelism)
+		}
+		if p.Memory < 8*uint32(p.Parallelism) || p.Memory > maxArgon2idMemory {
+			return fmt.Errorf("Argon2id 記憶體用量無效: %d KiB", p.Memory)
+		}
+	case KDFPBKDF2:
+		if p.Iterations == 0 || p.Iterations > maxPBKDF2Rounds {
+			return fmt.Errorf("PBKDF2 迭代次數無效: %d", p.Iterations)
+		}
+	default:
+		return fmt.Errorf("不支援的金鑰衍生函數: %s", p.Name)
+	}
+	return nil
+}
+
+// DeriveKey 以密碼和鹽值衍生金鑰
+// 參數：password（密碼）、salt（鹽值）
+// 回傳：KeySize 位元組的金鑰和可能的錯誤
+func (p KDFParams) DeriveKey(password string, salt []byte) ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch p.Name {
+	case KDFArgon2id:
+		return argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, KeySize), nil
+	default:
+		return pbkdf2.Key([]byte(password), salt, int(p.Iterations), KeySize, sha256.New), nil
+	}
+}
+
+// IsWeakerThan 檢查參數的成本是否低於目標參數
+// 參數：target（目標參數）
+// 回傳：是否需要升級到目標參數
+//
+// 規則：
+// 1. PBKDF2 一律弱於 Argon2id
+// 2. 同為 Argon2id 時，記憶體用量、迭代次數或平行度任一項較低即視為較弱
+// 3. 同為 PBKDF2 時，迭代次數較低即視為較弱
+func (p KDFParams) IsWeakerThan(target KDFParams) bool {
+	if p.Name != target.Name {
+		return p.Name == KDFPBKDF2 && target.Name == KDFArgon2id
+	}
+	if p.Name == KDFArgon2id {
+		return p.Memory < target.Memory || p.Iterations < target.Iterations || p.Parallelism < target.Parallelism
+	}
+	return p.Iterations < target.Iterations
 }
 
 // encryptionService 實作 EncryptionService 介面
 // 提供完整的加密解密功能和密碼管理
 type encryptionService struct {
-	// 可以在這裡添加配置選項或依賴注入
+	kdfParams KDFParams // 加密時使用的金鑰衍生參數
 }
 
 // NewEncryptionService 建立新的加密服務實例
@@ -60,10 +181,10 @@ type encryptionService struct {
 //
 // 執行流程：
 // 1. 建立 encryptionService 結構體實例
-// 2. 初始化必要的配置（如果需要）
+// 2. 使用預設的 Argon2id 金鑰衍生參數
 // 3. 回傳服務介面
 func NewEncryptionService() EncryptionService {
-	return &encryptionService{}
+	return &encryptionService{kdfParams: DefaultKDFParams()}
 }
 
 // EncryptContent 使用指定演算法和密碼加密內容
@@ -76,9 +197,9 @@ func NewEncryptionService() EncryptionService {
 // 執行流程：
 // 1. 驗證輸入參數的有效性
 // 2. 產生隨機鹽值和隨機數
-// 3. 使用 PBKDF2 從密碼衍生金鑰
+// 3. 使用 Argon2id 從密碼衍生金鑰
 // 4. 根據指定演算法進行加密
-// 5. 建立加密資料結構並序列化為 JSON
+// 5. 建立記錄 KDF 參數的加密資料結構並序列化為 JSON
 // 6. 回傳序列化後的位元組陣列
 func (s *encryptionService) EncryptContent(content, password string, algorithm string) ([]byte, error) {
 	// 驗證輸入參數
@@ -98,12 +219,16 @@ func (s *encryptionService) EncryptContent(content, password string, algorithm s
 		return nil, fmt.Errorf("產生鹽值失敗: %w", err)
 	}
 
-	// 使用 PBKDF2 從密碼衍生金鑰
-	key := pbkdf2.Key([]byte(password), salt, PBKDF2Rounds, KeySize, sha256.New)
+	// 使用目前的 KDF 參數從密碼衍生金鑰
+	kdfParams := s.kdfParams
+	key, err := kdfParams.DeriveKey(password, salt)
+	if err != nil {
+		return nil, fmt.Errorf("衍生金鑰失敗: %w", err)
+	}
+	defer zeroBytes(key)
 
 	var encryptedContent []byte
 	var nonce []byte
-	var err error
 
 	// 根據演算法進行加密
 	switch algorithm {
@@ -124,12 +249,13 @@ func (s *encryptionService) EncryptContent(content, password string, algorithm s
 
 	// 建立加密資料結構
 	encData := EncryptedData{
-		Version:   "1.0",
+		Version:   EncryptedDataVersionKDF,
 		Algorithm: algorithm,
 		Salt:      base64.StdEncoding.EncodeToString(salt),
 		Nonce:     base64.StdEncoding.EncodeToString(nonce),
 		Data:      base64.StdEncoding.EncodeToString(encryptedContent),
 		Checksum:  base64.StdEncoding.EncodeToString(checksum[:]),
+		KDF:       &kdfParams,
 	}
 
 	// 序列化為 JSON
@@ -144,10 +270,10 @@ func (s *encryptionService) EncryptContent(content, password string, algorithm s
 // 回傳：解密後的內容字串和可能的錯誤
 //
 // 執行流程：
-// 1. 反序列化加密資料結構
-// 2. 驗證加密格式和演算法
+// 1. 串流分段格式交由串流解密處理，否則反序列化加密資料結構
+// 2. 驗證加密格式和演算法，取得 KDF 參數（1.0 格式固定為 PBKDF2）
 // 3. 解碼 Base64 編碼的資料
-// 4. 使用 PBKDF2 從密碼衍生金鑰
+// 4. 使用記錄的 KDF 參數從密碼衍生金鑰
 // 5. 根據演算法進行解密
 // 6. 驗證校驗和確保資料完整性
 // 7. 回傳解密後的明文內容
@@ -160,15 +286,27 @@ func (s *encryptionService) DecryptContent(encryptedData []byte, password string
 		return "", errors.New("密碼不能為空")
 	}
 
+	// 大型筆記使用串流分段格式
+	if IsStreamData(encryptedData) {
+		return s.decryptStreamContent(encryptedData, password, algorithm)
+	}
+
 	// 反序列化加密資料
 	var encData EncryptedData
 	if err := json.Unmarshal(encryptedData, &encData); err != nil {
 		return "", fmt.Errorf("解析加密資料失敗: %w", err)
 	}
 
-	// 驗證加密格式版本
-	if encData.Version != "1.0" {
-		return "", fmt.Errorf("不支援的加密格式版本: %s", encData.Version)
+	// 驗證加密格式版本，信封格式必須透過保險庫解密
+	if encData.Version == EncryptedDataVersionVault {
+		return "", errors.New("此檔案使用保險庫金鑰加密，請先解鎖保險庫")
+	}
+	if encData.Version == EncryptedDataVersionRecipients {
+		return "", errors.New("此檔案以收件人公鑰加密，請使用您的身分金鑰開啟")
+	}
+	kdfParams, err := passwordKDFParams(&encData)
+	if err != nil {
+		return "", err
 	}
 
 	// 驗證演算法一致性
@@ -203,8 +341,12 @@ func (s *encryptionService) DecryptContent(encryptedData []byte, password string
 		return "", errors.New("資料校驗和不匹配，可能已被篡改")
 	}
 
-	// 使用 PBKDF2 從密碼衍生金鑰
-	key := pbkdf2.Key([]byte(password), salt, PBKDF2Rounds, KeySize, sha256.New)
+	// 使用記錄的 KDF 參數從密碼衍生金鑰
+	key, err := kdfParams.DeriveKey(password, salt)
+	if err != nil {
+		return "", fmt.Errorf("衍生金鑰失敗: %w", err)
+	}
+	defer zeroBytes(key)
 
 	var plaintext []byte
 
@@ -225,6 +367,31 @@ func (s *encryptionService) DecryptContent(encryptedData []byte, password string
 	return string(plaintext), nil
 }
 
+// passwordKDFParams 取得密碼加密格式使用的 KDF 參數
+// 參數：encData（加密資料結構）
+// 回傳：KDF 參數和可能的錯誤
+//
+// 執行流程：
+// 1. 1.0 格式未記錄參數，固定使用 PBKDF2-SHA256 和 PBKDF2Rounds
+// 2. 3.0 格式使用記錄的參數，並驗證參數在合理範圍內
+// 3. 其他版本回傳不支援的錯誤
+func passwordKDFParams(encData *EncryptedData) (KDFParams, error) {
+	switch encData.Version {
+	case EncryptedDataVersionPassword:
+		return LegacyKDFParams(), nil
+	case EncryptedDataVersionKDF:
+		if encData.KDF == nil {
+			return KDFParams{}, errors.New("加密資料缺少金鑰衍生參數")
+		}
+		if err := encData.KDF.Validate(); err != nil {
+			return KDFParams{}, err
+		}
+		return *encData.KDF, nil
+	default:
+		return KDFParams{}, fmt.Errorf("不支援的加密格式版本: %s", encData.Version)
+	}
+}
+
 // encryptWithAES 使用 AES-256-GCM 模式加密資料
 // 參數：
 //   - plaintext: 要加密的明文資料
@@ -353,6 +520,73 @@ func (s *encryptionService) decryptWithChaCha20(ciphertext, key, nonce []byte) (
 	return plaintext, nil
 }
 
+// newAEAD 依演算法建立 AEAD 加密器
+// 參數：
+//   - algorithm: 加密演算法（"aes256" 或 "chacha20"）
+//   - key: 32 位元組的金鑰
+// 回傳：AEAD 加密器和可能的錯誤
+func newAEAD(algorithm string, key []byte) (cipher.AEAD, error) {
+	switch algorithm {
+	case AlgorithmAES256:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("建立 AES 加密器失敗: %w", err)
+		}
+		return cipher.NewGCM(block)
+	case AlgorithmChaCha20:
+		return chacha20poly1305.New(key)
+	default:
+		return nil, fmt.Errorf("不支援的加密演算法: %s", algorithm)
+	}
+}
+
+// sealWithAlgorithm 使用指定演算法加密資料並綁定附加驗證資料
+// 參數：
+//   - algorithm: 加密演算法
+//   - key: 32 位元組的金鑰
+//   - plaintext: 要加密的明文資料
+//   - additionalData: 附加驗證資料（AAD），解密時必須相同
+// 回傳：加密後的資料、隨機數和可能的錯誤
+func sealWithAlgorithm(algorithm string, key, plaintext, additionalData []byte) ([]byte, []byte, error) {
+	aead, err := newAEAD(algorithm, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, fmt.Errorf("產生 nonce 失敗: %w", err)
+	}
+
+	return aead.Seal(nil, nonce, plaintext, additionalData), nonce, nil
+}
+
+// openWithAlgorithm 使用指定演算法解密資料並驗證附加驗證資料
+// 參數：
+//   - algorithm: 加密演算法
+//   - key: 32 位元組的金鑰
+//   - nonce: 加密時使用的隨機數
+//   - ciphertext: 要解密的資料
+//   - additionalData: 附加驗證資料（AAD）
+// 回傳：解密後的明文資料和可能的錯誤
+func openWithAlgorithm(algorithm string, key, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(algorithm, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("nonce 長度無效: %d", len(nonce))
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("解密驗證失敗: %w", err)
+	}
+
+	return plaintext, nil
+}
+
 // ValidatePassword 驗證密碼強度是否符合要求
 // 參數：password（要驗證的密碼）
 // 回傳：密碼是否有效
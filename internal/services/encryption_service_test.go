@@ -3,9 +3,13 @@
 package services
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"strings"
 	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
 )
 
 // TestNewEncryptionService 測試加密服務的建立
@@ -87,8 +91,12 @@ func TestEncryptContent_AES256(t *testing.T) {
 			}
 			
 			// 驗證加密資料結構
-			if encData.Version != "1.0" {
-				t.Errorf("版本不正確: 期望 1.0，實際 %s", encData.Version)
+			if encData.Version != EncryptedDataVersionKDF {
+				t.Errorf("版本不正確: 期望 %s，實際 %s", EncryptedDataVersionKDF, encData.Version)
+			}
+			
+			if encData.KDF == nil || *encData.KDF != DefaultKDFParams() {
+				t.Errorf("KDF 參數不正確: %+v", encData.KDF)
 			}
 			
 			if encData.Algorithm != AlgorithmAES256 {
@@ -530,4 +538,112 @@ func BenchmarkValidatePassword(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		service.ValidatePassword(password)
 	}
-}
\ No newline at end of file
+}
+
+// encryptLegacyContent 以 1.0 格式（PBKDF2，參數固定）加密內容，模擬舊版本產生的檔案
+func encryptLegacyContent(t *testing.T, content, password, algorithm string) []byte {
+	t.Helper()
+
+	salt := make([]byte, SaltSize)
+	for i := range salt {
+		salt[i] = byte(i)
+	}
+	key := pbkdf2.Key([]byte(password), salt, PBKDF2Rounds, KeySize, sha256.New)
+
+	ciphertext, nonce, err := sealWithAlgorithm(algorithm, key, []byte(content), nil)
+	if err != nil {
+		t.Fatalf("建立舊格式加密資料失敗: %v", err)
+	}
+	checksum := sha256.Sum256(ciphertext)
+
+	data, _ := json.Marshal(EncryptedData{
+		Version:   EncryptedDataVersionPassword,
+		Algorithm: algorithm,
+		Salt:      base64.StdEncoding.EncodeToString(salt),
+		Nonce:     base64.StdEncoding.EncodeToString(nonce),
+		Data:      base64.StdEncoding.EncodeToString(ciphertext),
+		Checksum:  base64.StdEncoding.EncodeToString(checksum[:]),
+	})
+	return data
+}
+
+// TestDecryptContent_LegacyPBKDF2 測試舊的 1.0 格式仍可解密
+// 驗證新增 KDF 參數後，未記錄參數的 PBKDF2 檔案保持相容
+func TestDecryptContent_LegacyPBKDF2(t *testing.T) {
+	service := NewEncryptionService()
+	
+	for _, algorithm := range []string{AlgorithmAES256, AlgorithmChaCha20} {
+		t.Run(algorithm, func(t *testing.T) {
+			legacy := encryptLegacyContent(t, "舊格式內容", "Legacy123!", algorithm)
+			
+			decrypted, err := service.DecryptContent(legacy, "Legacy123!", algorithm)
+			if err != nil {
+				t.Fatalf("舊格式解密失敗: %v", err)
+			}
+			if decrypted != "舊格式內容" {
+				t.Errorf("解密結果不匹配: %s", decrypted)
+			}
+			
+			if _, err := service.DecryptContent(legacy, "Wrong123!", algorithm); err == nil {
+				t.Error("舊格式使用錯誤密碼應該解密失敗")
+			}
+		})
+	}
+}
+
+// TestDecryptContent_InvalidKDFParams 測試無效或過高成本的 KDF 參數會被拒絕
+// 驗證竄改參數無法造成資源耗盡
+func TestDecryptContent_InvalidKDFParams(t *testing.T) {
+	service := NewEncryptionService()
+	
+	encrypted, err := service.EncryptContent("內容", "TestPass123!", AlgorithmAES256)
+	if err != nil {
+		t.Fatalf("加密失敗: %v", err)
+	}
+	
+	testCases := []struct {
+		name   string
+		modify func(*EncryptedData)
+	}{
+		{"缺少參數", func(d *EncryptedData) { d.KDF = nil }},
+		{"未知演算法", func(d *EncryptedData) { d.KDF.Name = "scrypt" }},
+		{"記憶體過高", func(d *EncryptedData) { d.KDF.Memory = maxArgon2idMemory + 1 }},
+		{"迭代次數為零", func(d *EncryptedData) { d.KDF.Iterations = 0 }},
+		{"平行度為零", func(d *EncryptedData) { d.KDF.Parallelism = 0 }},
+	}
+	
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var encData EncryptedData
+			json.Unmarshal(encrypted, &encData)
+			tc.modify(&encData)
+			tampered, _ := json.Marshal(encData)
+			
+			if _, err := service.DecryptContent(tampered, "TestPass123!", AlgorithmAES256); err == nil {
+				t.Error("無效的 KDF 參數應該解密失敗")
+			}
+		})
+	}
+}
+
+// TestKDFParams_IsWeakerThan 測試 KDF 參數強度比較
+// 驗證升級判斷的規則
+func TestKDFParams_IsWeakerThan(t *testing.T) {
+	current := DefaultKDFParams()
+	
+	if !LegacyKDFParams().IsWeakerThan(current) {
+		t.Error("PBKDF2 應該弱於 Argon2id")
+	}
+	if current.IsWeakerThan(LegacyKDFParams()) {
+		t.Error("Argon2id 不應弱於 PBKDF2")
+	}
+	if current.IsWeakerThan(current) {
+		t.Error("相同參數不應視為較弱")
+	}
+	
+	lowMemory := current
+	lowMemory.Memory = current.Memory / 2
+	if !lowMemory.IsWeakerThan(current) {
+		t.Error("記憶體用量較低的 Argon2id 應該視為較弱")
+	}
+}
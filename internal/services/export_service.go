@@ -89,7 +89,7 @@ func NewExportService(editorService EditorService) ExportService {
 // 4. 使用 HTML 到 PDF 轉換器生成 PDF
 // 5. 應用匯出選項（頁面設定、浮水印等）
 // 6. 保存 PDF 檔案並更新進度
-func (s *exportServiceImpl) ExportToPDF(note *models.Note, outputPath string, options *ExportOptions) error {
+func (s *exportServiceImpl) ExportToPDF(note *models.Note, outputPath string, options *ExportOptions) (err error) {
 	// 驗證輸入參數
 	if note == nil {
 		return fmt.Errorf("筆記不能為空")
@@ -103,6 +103,20 @@ func (s *exportServiceImpl) ExportToPDF(note *models.Note, outputPath string, op
 		return fmt.Errorf("無效的匯出路徑: %s", errMsg)
 	}
 	
+	// 加密筆記需要先重新驗證密碼
+	if err := s.requireReauth(note); err != nil {
+		return err
+	}
+	
+	// 機密區塊預設以佔位文字取代
+	note, err = s.prepareSecretBlocks(note, options != nil && options.IncludeSecretBlocks)
+	if err != nil {
+		return err
+	}
+	
+	// 加密筆記以明文匯出時寫入稽核記錄
+	defer func() { s.auditDecryptExport(note, outputPath, err) }()
+	
 	// 建立匯出任務
 	exportID := s.generateExportID()
 	progress := &ExportProgress{
@@ -159,7 +173,7 @@ func (s *exportServiceImpl) ExportToPDF(note *models.Note, outputPath string, op
 // 4. 應用 CSS 樣式和主題
 // 5. 處理圖片和附件（如果需要）
 // 6. 保存 HTML 檔案並更新進度
-func (s *exportServiceImpl) ExportToHTML(note *models.Note, outputPath string, options *ExportOptions) error {
+func (s *exportServiceImpl) ExportToHTML(note *models.Note, outputPath string, options *ExportOptions) (err error) {
 	// 驗證輸入參數
 	if note == nil {
 		return fmt.Errorf("筆記不能為空")
@@ -173,6 +187,20 @@ func (s *exportServiceImpl) ExportToHTML(note *models.Note, outputPath string, o
 		return fmt.Errorf("無效的匯出路徑: %s", errMsg)
 	}
 	
+	// 加密筆記需要先重新驗證密碼
+	if err := s.requireReauth(note); err != nil {
+		return err
+	}
+	
+	// 機密區塊預設以佔位文字取代
+	note, err = s.prepareSecretBlocks(note, options != nil && options.IncludeSecretBlocks)
+	if err != nil {
+		return err
+	}
+	
+	// 加密筆記以明文匯出時寫入稽核記錄
+	defer func() { s.auditDecryptExport(note, outputPath, err) }()
+	
 	// 建立匯出任務
 	exportID := s.generateExportID()
 	progress := &ExportProgress{
@@ -239,7 +267,7 @@ func (s *exportServiceImpl) ExportToHTML(note *models.Note, outputPath string, o
 // 4. 建立 Word 文件並設定格式
 // 5. 轉換內容到 Word 格式
 // 6. 保存 Word 檔案並更新進度
-func (s *exportServiceImpl) ExportToWord(note *models.Note, outputPath string, options *ExportOptions) error {
+func (s *exportServiceImpl) ExportToWord(note *models.Note, outputPath string, options *ExportOptions) (err error) {
 	// 驗證輸入參數
 	if note == nil {
 		return fmt.Errorf("筆記不能為空")
@@ -253,6 +281,20 @@ func (s *exportServiceImpl) ExportToWord(note *models.Note, outputPath string, o
 		return fmt.Errorf("無效的匯出路徑: %s", errMsg)
 	}
 	
+	// 加密筆記需要先重新驗證密碼
+	if err := s.requireReauth(note); err != nil {
+		return err
+	}
+	
+	// 機密區塊預設以佔位文字取代
+	note, err = s.prepareSecretBlocks(note, options != nil && options.IncludeSecretBlocks)
+	if err != nil {
+		return err
+	}
+	
+	// 加密筆記以明文匯出時寫入稽核記錄
+	defer func() { s.auditDecryptExport(note, outputPath, err) }()
+	
 	// 建立匯出任務
 	exportID := s.generateExportID()
 	progress := &ExportProgress{
@@ -319,6 +361,13 @@ func (s *exportServiceImpl) BatchExport(notes []*models.Note, outputDir string,
 		return nil, fmt.Errorf("輸出目錄不能為空")
 	}
 	
+	// 所有加密筆記都需要先重新驗證密碼，避免只匯出部分筆記
+	for _, note := range notes {
+		if err := s.requireReauth(note); err != nil {
+			return nil, fmt.Errorf("%s: %w", note.Title, err)
+		}
+	}
+	
 	// 確保輸出目錄存在
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return nil, fmt.Errorf("建立輸出目錄失敗: %v", err)
@@ -420,7 +469,7 @@ func (s *exportServiceImpl) BatchExport(notes []*models.Note, outputDir string,
 // 2. 根據分享類型執行不同的分享邏輯
 // 3. 生成分享連結或執行分享動作
 // 4. 回傳分享結果
-func (s *exportServiceImpl) ShareNote(note *models.Note, shareOptions *ShareOptions) (*ShareResult, error) {
+func (s *exportServiceImpl) ShareNote(note *models.Note, shareOptions *ShareOptions) (result *ShareResult, err error) {
 	// 驗證輸入參數
 	if note == nil {
 		return nil, fmt.Errorf("筆記不能為空")
@@ -429,10 +478,28 @@ func (s *exportServiceImpl) ShareNote(note *models.Note, shareOptions *ShareOpti
 		return nil, fmt.Errorf("分享選項不能為空")
 	}
 	
+	// 加密筆記需要先重新驗證密碼
+	if err := s.requireReauth(note); err != nil {
+		return nil, err
+	}
+	
+	// 機密區塊預設以佔位文字取代
+	note, err = s.prepareSecretBlocks(note, shareOptions.IncludeSecretBlocks)
+	if err != nil {
+		return nil, err
+	}
+	
 	// 生成分享 ID
 	shareID := s.generateShareID()
 	
-	result := &ShareResult{
+	// 分享加密筆記時寫入稽核記錄
+	if note.IsEncrypted && s.editorService != nil {
+		defer func() {
+			recordAudit(s.editorService.Options().Audit, AuditEventShareEncrypted, auditNoteTarget(note), err, shareOptions.ShareType.String())
+		}()
+	}
+	
+	result = &ShareResult{
 		ShareID: shareID,
 		Success: false,
 	}
@@ -472,14 +539,30 @@ func (s *exportServiceImpl) ShareNote(note *models.Note, shareOptions *ShareOpti
 		result.Message = "已透過 AirDrop 分享"
 		
 	case ShareTypeClipboard:
-		// 複製到剪貼簿
-		err := s.shareToClipboard(note, shareOptions)
+		// 複製到剪貼簿，加密筆記的內容在一段時間後自動清除
+		content, err := s.shareToClipboard(note, shareOptions)
 		if err != nil {
 			result.Message = fmt.Sprintf("複製到剪貼簿失敗: %v", err)
 			return result, err
 		}
+		result.ClipboardContent = content
 		result.Success = true
 		result.Message = "內容已複製到剪貼簿"
+		if IsSensitiveNote(note) {
+			result.ClipboardClearAfter = SensitiveClipboardTimeout
+			result.Message = fmt.Sprintf("內容已複製到剪貼簿，%d 秒後自動清除", int(SensitiveClipboardTimeout.Seconds()))
+		}
+		
+	case ShareTypeRecipients:
+		// 以收件人公鑰加密為檔案
+		filePath, err := s.shareViaRecipients(note, shareOptions)
+		if err != nil {
+			result.Message = fmt.Sprintf("收件人加密失敗: %v", err)
+			return result, err
+		}
+		result.FilePath = filePath
+		result.Success = true
+		result.Message = fmt.Sprintf("已加密給 %d 位收件人", len(shareOptions.Recipients))
 		
 	default:
 		return nil, fmt.Errorf("不支援的分享類型")
@@ -1103,6 +1186,14 @@ func (s *exportServiceImpl) escapeXML(text string) string {
 }
 
 func (s *exportServiceImpl) exportToMarkdown(note *models.Note, outputPath string, options *ExportOptions) error {
+	if err := s.requireReauth(note); err != nil {
+		return err
+	}
+	note, err := s.prepareSecretBlocks(note, options.IncludeSecretBlocks)
+	if err != nil {
+		return err
+	}
+	
 	content := note.Content
 	if options.IncludeMetadata {
 		metadata := fmt.Sprintf("---\ntitle: %s\ncreated: %s\nupdated: %s\n---\n\n",
@@ -1111,7 +1202,80 @@ func (s *exportServiceImpl) exportToMarkdown(note *models.Note, outputPath strin
 			note.UpdatedAt.Format("2006-01-02 15:04:05"))
 		content = metadata + content
 	}
-	return s.writeToFile(outputPath, content)
+	err = s.writeToFile(outputPath, content)
+	s.auditDecryptExport(note, outputPath, err)
+	return err
+}
+
+// Reauthenticate 匯出或分享加密筆記前重新驗證密碼
+// 參數：note（加密筆記）、password（密碼）
+// 回傳：可能的錯誤
+func (s *exportServiceImpl) Reauthenticate(note *models.Note, password string) error {
+	if s.editorService == nil {
+		return fmt.Errorf("編輯器服務未設定")
+	}
+	reauth, ok := s.editorService.(NoteReauthenticator)
+	if !ok {
+		return fmt.Errorf("編輯器服務不支援重新驗證")
+	}
+	return reauth.ReauthenticateNote(note, password)
+}
+
+// leakageGuard 取得編輯器服務的明文外洩防護
+// 回傳：LeakageGuard 介面實例（未設定時為 nil）
+func (s *exportServiceImpl) leakageGuard() LeakageGuard {
+	if s.editorService == nil {
+		return nil
+	}
+	return s.editorService.Options().LeakageGuard
+}
+
+// requireReauth 檢查加密筆記在匯出或分享前是否已重新驗證密碼
+// 參數：note（要匯出或分享的筆記）
+// 回傳：尚未驗證時為 ErrReauthRequired
+func (s *exportServiceImpl) requireReauth(note *models.Note) error {
+	guard := s.leakageGuard()
+	if guard == nil {
+		return nil
+	}
+	return guard.RequireReauth(note)
+}
+
+// prepareSecretBlocks 取得匯出或分享使用的筆記內容
+// 參數：note（要匯出或分享的筆記）、include（是否包含機密區塊的明文）
+// 回傳：處理後的筆記副本（沒有機密區塊時為原筆記）和可能的錯誤
+//
+// 預設以佔位文字取代機密區塊；要求包含時解密仍為密文的區塊，保險庫鎖定時回傳錯誤
+func (s *exportServiceImpl) prepareSecretBlocks(note *models.Note, include bool) (*models.Note, error) {
+	if !HasSecretBlocks(note.Content) {
+		return note, nil
+	}
+
+	prepared := *note
+	if !include {
+		prepared.Content = RedactSecretBlocks(note.Content)
+		return &prepared, nil
+	}
+
+	secrets, ok := s.editorService.(SecretBlockEditor)
+	if !ok {
+		return nil, ErrVaultLocked
+	}
+	content, err := secrets.RevealSecretBlocks(note.ID, note.Content)
+	if err != nil {
+		return nil, fmt.Errorf("無法包含機密區塊: %w", err)
+	}
+	prepared.Content = content
+	return &prepared, nil
+}
+
+// auditDecryptExport 加密筆記以明文匯出時寫入稽核記錄
+// 參數：note（匯出的筆記）、outputPath（輸出檔案路徑）、err（匯出結果）
+func (s *exportServiceImpl) auditDecryptExport(note *models.Note, outputPath string, err error) {
+	if !note.IsEncrypted || s.editorService == nil {
+		return
+	}
+	recordAudit(s.editorService.Options().Audit, AuditEventDecryptExport, auditNoteTarget(note), err, outputPath)
 }
 
 func (s *exportServiceImpl) generateOutputPath(outputDir, title string, format ExportFormat) string {
@@ -1147,10 +1311,10 @@ func (s *exportServiceImpl) shareViaEmail(note *models.Note, options *ShareOptio
 	// - 第三方服務 API（SendGrid, Mailgun 等）
 	// - macOS 系統郵件應用程式整合
 	
-	// 模擬發送電子郵件
+	// 模擬發送電子郵件（加密筆記的標題和內容不寫入記錄）
 	fmt.Printf("發送電子郵件到: %v\n", options.Recipients)
-	fmt.Printf("主旨: 分享筆記 - %s\n", note.Title)
-	fmt.Printf("內容: %s\n", emailContent)
+	fmt.Printf("主旨: 分享筆記 - %s\n", redactSensitive(note, note.Title))
+	fmt.Printf("內容: %s\n", redactSensitive(note, emailContent))
 	
 	return nil
 }
@@ -1166,11 +1330,11 @@ func (s *exportServiceImpl) shareViaEmail(note *models.Note, options *ShareOptio
 // 4. 清理臨時檔案
 func (s *exportServiceImpl) shareViaAirDrop(note *models.Note, options *ShareOptions) error {
 	// 建立臨時檔案
-	tempFile, err := s.createTempFileForShare(note)
+	tempFile, cleanup, err := s.createTempFileForShare(note)
 	if err != nil {
 		return fmt.Errorf("建立臨時檔案失敗: %v", err)
 	}
-	defer os.Remove(tempFile) // 清理臨時檔案
+	defer cleanup() // 清理臨時檔案
 	
 	// 這裡應該整合 macOS AirDrop 功能，如：
 	// - 使用 NSWorkspace 的 openFile:withApplication: 方法
@@ -1179,32 +1343,80 @@ func (s *exportServiceImpl) shareViaAirDrop(note *models.Note, options *ShareOpt
 	
 	// 模擬 AirDrop 分享
 	fmt.Printf("透過 AirDrop 分享檔案: %s\n", tempFile)
-	fmt.Printf("筆記標題: %s\n", note.Title)
+	fmt.Printf("筆記標題: %s\n", redactSensitive(note, note.Title))
 	
 	return nil
 }
 
-// shareToClipboard 將筆記內容複製到剪貼簿
+// shareToClipboard 準備要複製到剪貼簿的筆記內容
 // 參數：note（要分享的筆記）、options（分享選項）
-// 回傳：可能的錯誤
+// 回傳：剪貼簿內容和可能的錯誤
 //
 // 執行流程：
 // 1. 格式化筆記內容
-// 2. 複製到系統剪貼簿
-// 3. 提供用戶回饋
-func (s *exportServiceImpl) shareToClipboard(note *models.Note, options *ShareOptions) error {
+// 2. 回傳內容，由 UI 以 Fyne 的剪貼簿功能複製（並依結果的清除時間自動清除）
+func (s *exportServiceImpl) shareToClipboard(note *models.Note, options *ShareOptions) (string, error) {
 	// 格式化筆記內容
 	clipboardContent := s.formatContentForClipboard(note)
 	
-	// 這裡應該整合剪貼簿操作，如：
-	// - github.com/atotto/clipboard 套件
-	// - 系統原生剪貼簿 API
-	// - Fyne 的剪貼簿功能
+	// 內容不寫入記錄，避免加密筆記的明文留在終端機或日誌中
+	fmt.Printf("已準備剪貼簿內容: %d 位元組\n", len(clipboardContent))
 	
-	// 模擬複製到剪貼簿
-	fmt.Printf("已複製到剪貼簿:\n%s\n", clipboardContent)
-	
-	return nil
+	return clipboardContent, nil
+}
+
+// shareViaRecipients 以收件人公鑰加密筆記並寫入檔案
+// 參數：note（筆記）、options（分享選項）
+// 回傳：加密檔案路徑和可能的錯誤
+//
+// 執行流程：
+// 1. 收集收件人公鑰，需要時加入自己的身分
+// 2. 以收件人公鑰加密筆記內容（沿用筆記的加密演算法）
+// 3. 以僅限擁有者讀寫的權限寫入輸出路徑（未指定時為家目錄下以標題命名的檔案）
+func (s *exportServiceImpl) shareViaRecipients(note *models.Note, options *ShareOptions) (string, error) {
+	if s.editorService == nil || s.editorService.Options().Identity == nil {
+		return "", fmt.Errorf("公鑰身分服務未設定")
+	}
+	identitySvc := s.editorService.Options().Identity
+
+	recipients := append([]string(nil), options.Recipients...)
+	if options.IncludeSelf {
+		for _, identity := range identitySvc.ListIdentities() {
+			recipients = append(recipients, identity.PublicKey)
+		}
+	}
+	if len(recipients) == 0 {
+		return "", fmt.Errorf("至少需要一位收件人")
+	}
+
+	algorithm := note.EncryptionType
+	if algorithm != AlgorithmAES256 && algorithm != AlgorithmChaCha20 {
+		algorithm = AlgorithmAES256
+	}
+
+	data, err := identitySvc.EncryptForRecipients(note.Content, algorithm, recipients)
+	if err != nil {
+		return "", err
+	}
+
+	outputPath := options.OutputPath
+	if outputPath == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("無法取得家目錄: %w", err)
+		}
+		name := sanitizeNoteFileName(note.Title)
+		if name == "" {
+			name = "untitled"
+		}
+		outputPath = filepath.Join(homeDir, name+".md.enc")
+	}
+
+	if err := os.WriteFile(outputPath, data, 0600); err != nil {
+		return "", fmt.Errorf("寫入加密檔案失敗: %w", err)
+	}
+
+	return outputPath, nil
 }
 
 // buildEmailContent 建立電子郵件內容
@@ -1240,29 +1452,40 @@ func (s *exportServiceImpl) buildEmailContent(note *models.Note, options *ShareO
 
 // createTempFileForShare 為分享建立臨時檔案
 // 參數：note（筆記）
-// 回傳：臨時檔案路徑和可能的錯誤
-func (s *exportServiceImpl) createTempFileForShare(note *models.Note) (string, error) {
-	// 建立臨時目錄
-	tempDir := os.TempDir()
-	
+// 回傳：臨時檔案路徑、清理函數和可能的錯誤
+//
+// 加密筆記的暫存檔寫入只有目前使用者能存取的私有目錄，清理時先以零覆寫再刪除
+func (s *exportServiceImpl) createTempFileForShare(note *models.Note) (string, func(), error) {
 	// 生成安全的檔案名稱
 	safeTitle := s.sanitizeFileName(note.Title)
 	if safeTitle == "" {
 		safeTitle = "筆記"
 	}
 	
-	tempFile := filepath.Join(tempDir, safeTitle+".md")
-	
 	// 建立檔案內容
 	content := fmt.Sprintf("# %s\n\n%s", note.Title, note.Content)
 	
+	if IsSensitiveNote(note) {
+		guard := s.leakageGuard()
+		if guard == nil {
+			guard = NewLeakageGuard()
+		}
+		tempFile, err := guard.CreateSecureTempFile(safeTitle+".md", []byte(content))
+		if err != nil {
+			return "", nil, err
+		}
+		return tempFile, func() { guard.WipeFile(tempFile) }, nil
+	}
+	
+	tempFile := filepath.Join(os.TempDir(), safeTitle+".md")
+	
 	// 寫入檔案
 	err := s.writeToFile(tempFile, content)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 	
-	return tempFile, nil
+	return tempFile, func() { os.Remove(tempFile) }, nil
 }
 
 // formatContentForClipboard 格式化內容用於剪貼簿
@@ -722,6 +722,7 @@ func createMockEditorService() EditorService {
 // mockExportEditorService 專用於匯出服務測試的模擬編輯器服務
 type mockExportEditorService struct {
 	activeNotes map[string]*models.Note
+	options     EditorOptions
 }
 
 func (m *mockExportEditorService) CreateNote(title, content string) (*models.Note, error) {
@@ -743,6 +744,10 @@ func (m *mockExportEditorService) OpenNote(filePath string) (*models.Note, error
 	}, nil
 }
 
+func (m *mockExportEditorService) OpenNoteWithPassword(filePath, password string) (*models.Note, error) {
+	return m.OpenNote(filePath)
+}
+
 func (m *mockExportEditorService) SaveNote(note *models.Note) error {
 	return nil
 }
@@ -816,4 +821,38 @@ func (m *mockExportEditorService) GetSmartEditingService() SmartEditingService {
 	return nil
 }
 
-func (m *mockExportEditorService) SetSmartEditingService(smartEditSvc SmartEditingService) {}
\ No newline at end of file
+func (m *mockExportEditorService) SetSmartEditingService(smartEditSvc SmartEditingService) {}
+
+func (m *mockExportEditorService) Options() EditorOptions {
+	return m.options
+}
+
+func (m *mockExportEditorService) SetOptions(options EditorOptions) {
+	m.options = options
+}
+
+func (m *mockExportEditorService) ResolveSaveConflict(note *models.Note, resolution ConflictResolution) (bool, error) {
+	return false, nil
+}
+
+func (m *mockExportEditorService) ReloadNote(noteID string) (bool, error) { return false, nil }
+
+func (m *mockExportEditorService) InvalidateFileCache(filePath string) {}
+
+func (m *mockExportEditorService) ReauthenticateNote(note *models.Note, password string) error {
+	if password != "Password123!" {
+		return ErrVaultWrongPassword
+	}
+	if m.options.LeakageGuard != nil {
+		m.options.LeakageGuard.GrantReauth(note)
+	}
+	return nil
+}
+
+func (m *mockExportEditorService) RevealSecretBlocks(noteID, content string) (string, error) {
+	return content, nil
+}
+
+func (m *mockExportEditorService) ConcealSecretBlocks(noteID, content string) string {
+	return content
+}
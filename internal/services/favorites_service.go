@@ -0,0 +1,415 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含最愛和釘選服務：將標記為最愛或釘選的筆記和資料夾記錄在筆記本中繼資料目錄中，
+// 重新命名和移動後記錄隨之更新，筆記另外以筆記 ID 追蹤，保存時改名的加密筆記也不會遺失標記
+package services
+
+import (
+	"encoding/json" // JSON 序列化
+	"fmt"           // 格式化輸出
+	"log"           // 日誌記錄
+	"path/filepath" // 檔案路徑處理
+	"slices"        // 切片操作
+	"sync"          // 同步控制
+
+	"mac-notebook-app/internal/models"       // 引入資料模型
+	"mac-notebook-app/internal/repositories" // 引入儲存庫介面
+)
+
+// FavoritesPath 最愛和釘選的記錄檔，位於筆記本中繼資料目錄中
+var FavoritesPath = filepath.Join(NotebookMetaDir, "favorites.json")
+
+// FavoritesService 定義最愛和釘選服務的介面
+// 最愛顯示在側邊欄頂端的最愛區段，釘選的項目在檔案列表中排在同一個目錄的最前面
+type FavoritesService interface {
+	// Favorites 取得所有最愛
+	// 回傳：最愛的路徑，依加入的順序排列，不包含已不存在的項目
+	Favorites() []string
+
+	// IsFavorite 檢查檔案或資料夾是否為最愛
+	// 參數：path（相對於筆記本目錄的路徑）
+	// 回傳：是否為最愛
+	IsFavorite(path string) bool
+
+	// SetFavorite 將檔案或資料夾加入或移出最愛
+	// 參數：path（路徑）、favorite（是否為最愛）
+	// 回傳：可能的錯誤
+	SetFavorite(path string, favorite bool) error
+
+	// Pinned 取得所有釘選的項目
+	// 回傳：釘選的路徑，不包含已不存在的項目
+	Pinned() []string
+
+	// IsPinned 檢查檔案或資料夾是否已釘選
+	// 參數：path（路徑）
+	// 回傳：是否已釘選
+	IsPinned(path string) bool
+
+	// SetPinned 釘選或取消釘選檔案或資料夾
+	// 參數：path（路徑）、pinned（是否釘選）
+	// 回傳：可能的錯誤
+	SetPinned(path string, pinned bool) error
+
+	// Rename 檔案或目錄重新命名、移動後更新記錄，目錄中的項目一併更新
+	// 參數：oldPath（舊路徑）、newPath（新路徑）
+	// 回傳：可能的錯誤
+	Rename(oldPath, newPath string) error
+
+	// Remove 檔案或目錄刪除後移除記錄
+	// 參數：path（檔案或目錄路徑）
+	// 回傳：可能的錯誤
+	Remove(path string) error
+
+	// Subscribe 訂閱最愛和釘選的變更
+	// 參數：listener（變更後呼叫的函數，可能在背景執行緒呼叫）
+	Subscribe(listener func())
+}
+
+// favoriteEntry 最愛或釘選的項目
+type favoriteEntry struct {
+	Path   string `json:"path"`              // 路徑（以 / 分隔）
+	NoteID string `json:"note_id,omitempty"` // 筆記 ID，資料夾和尚未開啟過的筆記沒有 ID
+}
+
+// favoritesFile 記錄檔的內容
+type favoritesFile struct {
+	Favorites []*favoriteEntry `json:"favorites"` // 最愛，依加入的順序排列
+	Pinned    []*favoriteEntry `json:"pinned"`    // 釘選的項目
+}
+
+// favoritesService 實作 FavoritesService 介面
+type favoritesService struct {
+	fileRepo  repositories.FileRepository // 檔案存取介面
+	noteIDs   NoteIDIndex                 // 筆記 ID 索引（可選），追蹤保存時改名的筆記
+	favorites []*favoriteEntry            // 最愛
+	pinned    []*favoriteEntry            // 釘選的項目
+	loaded    bool                        // 是否已讀取記錄檔
+	listeners []func()                    // 變更訂閱者
+	mutex     sync.Mutex                  // 保護記錄
+}
+
+// NewFavoritesService 建立最愛和釘選服務
+// 參數：fileRepo（檔案存取介面）、noteIDs（筆記 ID 索引，可為 nil）
+// 回傳：最愛和釘選服務實例，第一次使用時才讀取記錄檔
+func NewFavoritesService(fileRepo repositories.FileRepository, noteIDs NoteIDIndex) FavoritesService {
+	return &favoritesService{
+		fileRepo: fileRepo,
+		noteIDs:  noteIDs,
+	}
+}
+
+// Favorites 取得所有最愛
+// 回傳：最愛的路徑
+func (f *favoritesService) Favorites() []string {
+	return f.existingPaths(&f.favorites)
+}
+
+// IsFavorite 檢查檔案或資料夾是否為最愛
+// 參數：path（路徑）
+// 回傳：是否為最愛
+func (f *favoritesService) IsFavorite(path string) bool {
+	return f.contains(&f.favorites, path)
+}
+
+// SetFavorite 將檔案或資料夾加入或移出最愛
+// 參數：path（路徑）、favorite（是否為最愛）
+// 回傳：可能的錯誤
+func (f *favoritesService) SetFavorite(path string, favorite bool) error {
+	return f.set(&f.favorites, path, favorite)
+}
+
+// Pinned 取得所有釘選的項目
+// 回傳：釘選的路徑
+func (f *favoritesService) Pinned() []string {
+	return f.existingPaths(&f.pinned)
+}
+
+// IsPinned 檢查檔案或資料夾是否已釘選
+// 參數：path（路徑）
+// 回傳：是否已釘選
+func (f *favoritesService) IsPinned(path string) bool {
+	return f.contains(&f.pinned, path)
+}
+
+// SetPinned 釘選或取消釘選檔案或資料夾
+// 參數：path（路徑）、pinned（是否釘選）
+// 回傳：可能的錯誤
+func (f *favoritesService) SetPinned(path string, pinned bool) error {
+	return f.set(&f.pinned, path, pinned)
+}
+
+// Rename 檔案或目錄重新命名、移動後更新記錄
+// 參數：oldPath（舊路徑）、newPath（新路徑）
+// 回傳：可能的錯誤
+func (f *favoritesService) Rename(oldPath, newPath string) error {
+	f.mutex.Lock()
+	if err := f.load(); err != nil {
+		f.mutex.Unlock()
+		return err
+	}
+	oldKey, newKey := noteIndexKey(oldPath), noteIndexKey(newPath)
+	changed := false
+	for _, entries := range [][]*favoriteEntry{f.favorites, f.pinned} {
+		for _, entry := range entries {
+			if rest, ok := underNoteIndexKey(entry.Path, oldKey); ok {
+				entry.Path = newKey + rest
+				changed = true
+			}
+		}
+	}
+	err := f.saveIfChanged(changed)
+	f.mutex.Unlock()
+
+	if changed {
+		f.notify()
+	}
+	return err
+}
+
+// Remove 檔案或目錄刪除後移除記錄
+// 參數：path（檔案或目錄路徑）
+// 回傳：可能的錯誤
+func (f *favoritesService) Remove(path string) error {
+	f.mutex.Lock()
+	if err := f.load(); err != nil {
+		f.mutex.Unlock()
+		return err
+	}
+	prefix := noteIndexKey(path)
+	under := func(entry *favoriteEntry) bool {
+		_, ok := underNoteIndexKey(entry.Path, prefix)
+		return ok
+	}
+	count := len(f.favorites) + len(f.pinned)
+	f.favorites = slices.DeleteFunc(f.favorites, under)
+	f.pinned = slices.DeleteFunc(f.pinned, under)
+	changed := count != len(f.favorites)+len(f.pinned)
+	err := f.saveIfChanged(changed)
+	f.mutex.Unlock()
+
+	if changed {
+		f.notify()
+	}
+	return err
+}
+
+// Subscribe 訂閱最愛和釘選的變更
+// 參數：listener（變更後呼叫的函數）
+func (f *favoritesService) Subscribe(listener func()) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.listeners = append(f.listeners, listener)
+}
+
+// existingPaths 取得清單中仍存在的項目路徑
+// 參數：list（最愛或釘選清單）
+// 回傳：路徑
+func (f *favoritesService) existingPaths(list *[]*favoriteEntry) []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.load(); err != nil {
+		log.Printf("讀取最愛記錄失敗: %v", err)
+		return nil
+	}
+	paths := make([]string, 0, len(*list))
+	for _, entry := range *list {
+		path := filepath.FromSlash(f.resolve(entry))
+		// 暫時不存在的項目（例如同步中）保留記錄，只是不顯示
+		if f.fileRepo.FileExists(path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// contains 檢查清單中是否有指定的路徑
+// 參數：list（最愛或釘選清單）、path（路徑）
+// 回傳：是否有記錄
+func (f *favoritesService) contains(list *[]*favoriteEntry, path string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.load(); err != nil {
+		log.Printf("讀取最愛記錄失敗: %v", err)
+		return false
+	}
+	return f.find(*list, noteIndexKey(path)) >= 0
+}
+
+// set 將路徑加入或移出清單
+// 參數：list（最愛或釘選清單）、path（路徑）、enabled（是否加入）
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 狀態已相同時不寫入記錄檔
+// 2. 加入時記錄筆記 ID（已開啟過的筆記才有），移出時刪除記錄
+// 3. 寫入記錄檔並通知訂閱者
+func (f *favoritesService) set(list *[]*favoriteEntry, path string, enabled bool) error {
+	if path == "" {
+		return fmt.Errorf("路徑不能為空")
+	}
+
+	f.mutex.Lock()
+	if err := f.load(); err != nil {
+		f.mutex.Unlock()
+		return err
+	}
+	key := noteIndexKey(path)
+	index := f.find(*list, key)
+	if (index >= 0) == enabled {
+		f.mutex.Unlock()
+		return nil
+	}
+	if enabled {
+		entry := &favoriteEntry{Path: key}
+		if f.noteIDs != nil {
+			entry.NoteID, _ = f.noteIDs.Lookup(path)
+		}
+		*list = append(*list, entry)
+	} else {
+		*list = slices.Delete(*list, index, index+1)
+	}
+	err := f.save()
+	f.mutex.Unlock()
+
+	f.notify()
+	return err
+}
+
+// find 找出路徑在清單中的位置
+// 參數：list（最愛或釘選清單）、key（以 / 分隔的路徑）
+// 回傳：位置，找不到時為 -1
+func (f *favoritesService) find(list []*favoriteEntry, key string) int {
+	return slices.IndexFunc(list, func(entry *favoriteEntry) bool {
+		return f.resolve(entry) == key
+	})
+}
+
+// resolve 取得項目目前的路徑
+// 參數：entry（最愛或釘選的項目）
+// 回傳：以 / 分隔的路徑
+//
+// 筆記 ID 仍有記錄時以 ID 對應的路徑為準（加密筆記保存時可能改為隨機檔名），
+// ID 已不再使用時（例如改用 front matter 中的 ID）改記錄路徑目前的 ID
+func (f *favoritesService) resolve(entry *favoriteEntry) string {
+	if f.noteIDs == nil {
+		return entry.Path
+	}
+	if path, ok := f.noteIDs.Resolve(entry.NoteID); entry.NoteID != "" && ok {
+		entry.Path = noteIndexKey(path)
+	} else if id, ok := f.noteIDs.Lookup(entry.Path); ok {
+		entry.NoteID = id
+	}
+	return entry.Path
+}
+
+// load 第一次使用時讀取記錄檔，檔案不存在時從空的記錄開始
+// 回傳：可能的錯誤（記錄檔格式錯誤時不覆寫，避免遺失記錄）
+func (f *favoritesService) load() error {
+	if f.loaded {
+		return nil
+	}
+	if f.fileRepo.FileExists(FavoritesPath) {
+		data, err := f.fileRepo.ReadFile(FavoritesPath)
+		if err != nil {
+			return fmt.Errorf("讀取最愛記錄失敗: %w", err)
+		}
+		var file favoritesFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("最愛記錄格式錯誤: %w", err)
+		}
+		f.favorites, f.pinned = file.Favorites, file.Pinned
+	}
+	f.loaded = true
+	return nil
+}
+
+// saveIfChanged 記錄有變更時寫入記錄檔
+// 參數：changed（是否有變更）
+// 回傳：可能的錯誤
+func (f *favoritesService) saveIfChanged(changed bool) error {
+	if !changed {
+		return nil
+	}
+	return f.save()
+}
+
+// save 將記錄寫入記錄檔
+// 回傳：可能的錯誤
+func (f *favoritesService) save() error {
+	file := favoritesFile{Favorites: f.favorites, Pinned: f.pinned}
+	if file.Favorites == nil {
+		file.Favorites = []*favoriteEntry{}
+	}
+	if file.Pinned == nil {
+		file.Pinned = []*favoriteEntry{}
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化最愛記錄失敗: %w", err)
+	}
+	if err := f.fileRepo.WriteFile(FavoritesPath, data); err != nil {
+		return fmt.Errorf("寫入最愛記錄失敗: %w", err)
+	}
+	return nil
+}
+
+// notify 通知訂閱者記錄已變更（呼叫者不可持有鎖）
+func (f *favoritesService) notify() {
+	f.mutex.Lock()
+	listeners := slices.Clone(f.listeners)
+	f.mutex.Unlock()
+	for _, listener := range listeners {
+		listener()
+	}
+}
+
+// SetFavoritesService 設定最愛和釘選服務，列出檔案時標記最愛並將釘選的項目排在最前面，
+// 重新命名、移動和刪除檔案時更新記錄
+// 參數：favorites（最愛和釘選服務）
+func (s *LocalFileManagerService) SetFavoritesService(favorites FavoritesService) {
+	s.favorites = favorites
+}
+
+// markFavorites 標記檔案列表中的最愛和釘選項目
+// 參數：fileInfos（檔案資訊陣列）
+func (s *LocalFileManagerService) markFavorites(fileInfos []*models.FileInfo) {
+	if s.favorites == nil || len(fileInfos) == 0 {
+		return
+	}
+	favorites := make(map[string]bool)
+	for _, path := range s.favorites.Favorites() {
+		favorites[noteIndexKey(path)] = true
+	}
+	pinned := make(map[string]bool)
+	for _, path := range s.favorites.Pinned() {
+		pinned[noteIndexKey(path)] = true
+	}
+	for _, info := range fileInfos {
+		key := noteIndexKey(info.Path)
+		info.IsFavorite = favorites[key]
+		info.IsPinned = pinned[key]
+	}
+}
+
+// moveFavorites 檔案或目錄重新命名、移動後更新最愛和釘選的記錄
+// 參數：oldPath（舊路徑）、newPath（新路徑）
+func (s *LocalFileManagerService) moveFavorites(oldPath, newPath string) {
+	if s.favorites == nil {
+		return
+	}
+	if err := s.favorites.Rename(oldPath, newPath); err != nil {
+		log.Printf("更新最愛記錄失敗 %s: %v", oldPath, err)
+	}
+}
+
+// forgetFavorites 檔案或目錄刪除後移除最愛和釘選的記錄
+// 參數：path（刪除的路徑）
+func (s *LocalFileManagerService) forgetFavorites(path string) {
+	if s.favorites == nil {
+		return
+	}
+	if err := s.favorites.Remove(path); err != nil {
+		log.Printf("移除最愛記錄失敗 %s: %v", path, err)
+	}
+}
@@ -0,0 +1,121 @@
+// Package services 提供最愛和釘選服務的單元測試
+// 測試最愛的加入和移除、重新啟動後保留記錄、重新命名和移動後記錄不變，以及釘選的項目排在列表最前面
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mac-notebook-app/internal/repositories"
+)
+
+// createTestFavoritesEnvironment 建立使用同一個最愛服務的編輯器服務和檔案管理服務
+func createTestFavoritesEnvironment(t *testing.T) (string, EditorService, *LocalFileManagerService, FavoritesService) {
+	baseDir, editor, fileManager, index := createTestNoteIDEnvironment(t)
+	fileRepo, err := repositories.NewLocalFileRepository(baseDir)
+	if err != nil {
+		t.Fatalf("建立檔案儲存庫失敗: %v", err)
+	}
+	favorites := NewFavoritesService(fileRepo, index)
+	fileManager.SetFavoritesService(favorites)
+	return baseDir, editor, fileManager, favorites
+}
+
+// TestFavorites 測試加入和移除最愛，重新建立服務後仍保留記錄
+func TestFavorites(t *testing.T) {
+	baseDir, _, _, favorites := createTestFavoritesEnvironment(t)
+	os.MkdirAll(filepath.Join(baseDir, "projects"), 0755)
+	os.WriteFile(filepath.Join(baseDir, "plan.md"), []byte("# 計畫"), 0644)
+
+	notified := 0
+	favorites.Subscribe(func() { notified++ })
+	favorites.SetFavorite("plan.md", true)
+	favorites.SetFavorite("projects", true)
+	favorites.SetFavorite("plan.md", true)
+	if notified != 2 {
+		t.Errorf("狀態變更時才應通知訂閱者，實際通知 %d 次", notified)
+	}
+	if !reflect.DeepEqual(favorites.Favorites(), []string{"plan.md", "projects"}) {
+		t.Errorf("最愛應該依加入的順序排列: %v", favorites.Favorites())
+	}
+
+	// 模擬重新啟動應用程式
+	fileRepo, _ := repositories.NewLocalFileRepository(baseDir)
+	restarted := NewFavoritesService(fileRepo, nil)
+	if !restarted.IsFavorite("projects") || restarted.IsPinned("projects") {
+		t.Error("重新啟動後應該保留最愛記錄")
+	}
+
+	favorites.SetFavorite("plan.md", false)
+	if favorites.IsFavorite("plan.md") || len(favorites.Favorites()) != 1 {
+		t.Errorf("移除後不應再是最愛: %v", favorites.Favorites())
+	}
+	if err := favorites.SetPinned("", true); err == nil {
+		t.Error("空白路徑應該回報錯誤")
+	}
+}
+
+// TestFavoritesFollowRenameAndSave 測試重新命名、移動和刪除後更新記錄，加密筆記保存時改名也維持標記
+func TestFavoritesFollowRenameAndSave(t *testing.T) {
+	baseDir, editor, fileManager, favorites := createTestFavoritesEnvironment(t)
+	os.MkdirAll(filepath.Join(baseDir, "projects"), 0755)
+	os.WriteFile(filepath.Join(baseDir, "projects", "plan.md"), []byte("# 計畫"), 0644)
+	os.WriteFile(filepath.Join(baseDir, "draft.md"), []byte("# 草稿"), 0644)
+
+	favorites.SetFavorite(filepath.Join("projects", "plan.md"), true)
+	favorites.SetPinned("projects", true)
+	if err := fileManager.RenameFile("projects", "archive"); err != nil {
+		t.Fatalf("重新命名目錄失敗: %v", err)
+	}
+	if !favorites.IsPinned("archive") || !favorites.IsFavorite(filepath.Join("archive", "plan.md")) {
+		t.Error("重新命名目錄後應該更新目錄和其中項目的記錄")
+	}
+
+	// 編輯器保存時改變檔案路徑，以筆記 ID 追蹤
+	note, _ := editor.OpenNote("draft.md")
+	favorites.SetFavorite("draft.md", true)
+	note.FilePath = "final.md"
+	if err := editor.SaveNote(note); err != nil {
+		t.Fatalf("保存筆記失敗: %v", err)
+	}
+	os.Remove(filepath.Join(baseDir, "draft.md"))
+	if !favorites.IsFavorite("final.md") {
+		t.Errorf("保存時改名後應該維持最愛: %v", favorites.Favorites())
+	}
+
+	if err := fileManager.DeleteFile(filepath.Join("archive", "plan.md")); err != nil {
+		t.Fatalf("刪除檔案失敗: %v", err)
+	}
+	if !reflect.DeepEqual(favorites.Favorites(), []string{"final.md"}) {
+		t.Errorf("刪除後應該移除記錄: %v", favorites.Favorites())
+	}
+}
+
+// TestListFilesPinnedFirst 測試釘選的項目排在列表最前面並標記最愛
+func TestListFilesPinnedFirst(t *testing.T) {
+	baseDir, _, fileManager, favorites := createTestFavoritesEnvironment(t)
+	os.MkdirAll(filepath.Join(baseDir, "notes"), 0755)
+	for _, name := range []string{"a.md", "b.md", "z.md"} {
+		os.WriteFile(filepath.Join(baseDir, name), []byte("內容"), 0644)
+	}
+	favorites.SetPinned("z.md", true)
+	favorites.SetPinned("b.md", true)
+	favorites.SetFavorite("a.md", true)
+
+	files, err := fileManager.ListFiles(".")
+	if err != nil {
+		t.Fatalf("列出檔案失敗: %v", err)
+	}
+	var names []string
+	for _, info := range files {
+		names = append(names, info.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"b.md", "z.md", "notes", "a.md"}) {
+		t.Errorf("釘選的項目應該排在最前面: %v", names)
+	}
+	if !files[0].IsPinned || files[2].IsPinned || !files[3].IsFavorite {
+		t.Error("檔案資訊應該標記釘選和最愛")
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !unix
+
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含不支援 flock 的平台上的檔案鎖替代實作：不提供跨程序鎖定，
+// 單一實例和筆記鎖定的檢查在這些平台上一律視為成功
+package services
+
+import "os" // 檔案操作
+
+// tryLockFile 不支援跨程序鎖定，一律視為成功
+// 參數：file（已開啟的檔案）
+// 回傳：nil
+func tryLockFile(file *os.File) error {
+	return nil
+}
+
+// unlockFile 不支援跨程序鎖定，不需要釋放
+// 參數：file（已開啟的檔案）
+// 回傳：nil
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,29 @@
+//go:build unix
+
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含 Unix 平台的跨程序檔案鎖，以 flock 實作，程式結束時由系統自動釋放
+package services
+
+import (
+	"errors"  // 錯誤處理
+	"os"      // 檔案操作
+	"syscall" // 系統呼叫
+)
+
+// tryLockFile 嘗試以獨占方式鎖定檔案，不等待
+// 參數：file（已開啟的檔案）
+// 回傳：已被其他程序鎖定時為 errFileLocked，其他失敗時為原始錯誤
+func tryLockFile(file *os.File) error {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return errFileLocked
+	}
+	return err
+}
+
+// unlockFile 釋放檔案鎖
+// 參數：file（已鎖定的檔案）
+// 回傳：可能的錯誤
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"fmt"      // 格式化輸出套件
+	"log"      // 日誌記錄套件
 	"os"       // 作業系統介面套件
 	"path/filepath" // 檔案路徑處理套件
 	"strings"  // 字串處理套件
@@ -10,6 +11,10 @@ import (
 	"mac-notebook-app/internal/repositories" // 引入儲存庫介面
 )
 
+// NotebookMetaDir 筆記本中繼資料目錄名稱
+// 保存保險庫金鑰、設定等應用程式資料，不會出現在檔案列表和檔案樹中
+const NotebookMetaDir = ".notebook"
+
 // LocalFileManagerService 實作 FileManagerService 介面
 // 提供本地檔案系統的檔案和目錄管理功能，包含 CRUD 操作和檔案樹遍歷
 type LocalFileManagerService struct {
@@ -18,6 +23,15 @@ type LocalFileManagerService struct {
 	
 	// baseDir 基礎工作目錄，所有操作都相對於此目錄
 	baseDir string
+	
+	// noteIDs 筆記 ID 索引（可選），重新命名和移動後筆記 ID 維持不變
+	noteIDs NoteIDIndex
+	
+	// favorites 最愛和釘選服務（可選），重新命名和移動後標記維持不變
+	favorites FavoritesService
+	
+	// searchIdx 全文搜尋索引（可選），重新命名、移動和刪除後更新索引
+	searchIdx SearchIndex
 }
 
 // NewLocalFileManagerService 建立新的本地檔案管理服務實例
@@ -88,7 +102,11 @@ func (s *LocalFileManagerService) ListFiles(directory string) ([]*models.FileInf
 		return nil, err
 	}
 	
-	// 對檔案資訊進行排序：目錄優先，然後按名稱排序
+	// 隱藏筆記本中繼資料目錄
+	fileInfos = s.filterMetaEntries(fileInfos)
+	
+	// 對檔案資訊進行排序：釘選的項目優先，其次是目錄，然後按名稱排序
+	s.markFavorites(fileInfos)
 	s.sortFileInfos(fileInfos)
 	
 	return fileInfos, nil
@@ -180,11 +198,19 @@ func (s *LocalFileManagerService) DeleteFile(path string) error {
 			)
 		}
 		
+		s.forgetFavorites(path)
+		s.forgetSearchEntries(path)
 		return nil
 	}
 	
 	// 刪除檔案
-	return s.fileRepo.DeleteFile(path)
+	if err := s.fileRepo.DeleteFile(path); err != nil {
+		return err
+	}
+	s.forgetNoteIDs(path)
+	s.forgetFavorites(path)
+	s.forgetSearchEntries(path)
+	return nil
 }
 
 // RenameFile 重新命名檔案或目錄
@@ -249,6 +275,10 @@ func (s *LocalFileManagerService) RenameFile(oldPath, newPath string) error {
 		)
 	}
 	
+	s.moveNoteIDs(oldPath, newPath)
+	s.moveFavorites(oldPath, newPath)
+	s.moveSearchEntries(oldPath, newPath)
+	s.sealMovedPath(newPath)
 	return nil
 }
 
@@ -333,6 +363,10 @@ func (s *LocalFileManagerService) MoveFile(sourcePath, destPath string) error {
 		)
 	}
 	
+	s.moveNoteIDs(sourcePath, destPath)
+	s.moveFavorites(sourcePath, destPath)
+	s.moveSearchEntries(sourcePath, destPath)
+	s.sealMovedPath(destPath)
 	return nil
 }
 
@@ -628,16 +662,46 @@ func (s *LocalFileManagerService) isDirectoryEmpty(dirPath string) (bool, error)
 	return len(fileInfos) == 0, nil
 }
 
+// filterMetaEntries 移除筆記本中繼資料目錄、筆記的分離簽章檔和上一版備份
+// 參數：fileInfos（檔案資訊陣列）
+// 回傳：不含中繼資料目錄、簽章檔和備份的檔案資訊陣列（原檔案已不存在的備份仍會列出）
+func (s *LocalFileManagerService) filterMetaEntries(fileInfos []*models.FileInfo) []*models.FileInfo {
+	names := make(map[string]bool, len(fileInfos))
+	for _, info := range fileInfos {
+		names[info.Name] = true
+	}
+	
+	filtered := fileInfos[:0]
+	for _, info := range fileInfos {
+		if info.IsDirectory && info.Name == NotebookMetaDir {
+			continue
+		}
+		if !info.IsDirectory && IsSignatureFile(info.Name) {
+			continue
+		}
+		if !info.IsDirectory && strings.HasSuffix(info.Name, repositories.BackupFileSuffix) && names[strings.TrimSuffix(info.Name, repositories.BackupFileSuffix)] {
+			continue
+		}
+		filtered = append(filtered, info)
+	}
+	return filtered
+}
+
 // sortFileInfos 對檔案資訊陣列進行排序
-// 排序規則：目錄優先，然後按名稱字母順序排序
+// 排序規則：釘選的項目優先，其次是目錄，然後按名稱字母順序排序
 // 參數：fileInfos（要排序的檔案資訊陣列）
 func (s *LocalFileManagerService) sortFileInfos(fileInfos []*models.FileInfo) {
 	// 使用簡單的冒泡排序演算法
 	n := len(fileInfos)
 	for i := 0; i < n-1; i++ {
 		for j := 0; j < n-i-1; j++ {
-			// 目錄優先排序
-			if !fileInfos[j].IsDirectory && fileInfos[j+1].IsDirectory {
+			// 釘選的項目優先排序
+			if fileInfos[j].IsPinned != fileInfos[j+1].IsPinned {
+				if fileInfos[j+1].IsPinned {
+					fileInfos[j], fileInfos[j+1] = fileInfos[j+1], fileInfos[j]
+				}
+			} else if !fileInfos[j].IsDirectory && fileInfos[j+1].IsDirectory {
+				// 目錄優先排序
 				fileInfos[j], fileInfos[j+1] = fileInfos[j+1], fileInfos[j]
 			} else if fileInfos[j].IsDirectory == fileInfos[j+1].IsDirectory {
 				// 同類型按名稱排序
@@ -686,6 +750,7 @@ func (s *LocalFileManagerService) buildFileTreeChildren(dirPath string) ([]*File
 	if err != nil {
 		return nil, err
 	}
+	fileInfos = s.filterMetaEntries(fileInfos)
 	
 	var children []*FileTreeNode
 	
@@ -712,6 +777,22 @@ func (s *LocalFileManagerService) buildFileTreeChildren(dirPath string) ([]*File
 	return children, nil
 }
 
+// sealMovedPath 加密移入加密資料夾的明文檔案
+// 參數：path（移動後的路徑）
+//
+// 執行流程：
+// 1. 檔案儲存庫不支援加密資料夾或目標不在加密資料夾中時不處理
+// 2. 加密路徑下的明文檔案，保險庫鎖定時只記錄日誌，檔案在下次保存時加密
+func (s *LocalFileManagerService) sealMovedPath(path string) {
+	encryptedRepo, ok := s.fileRepo.(EncryptedFileRepository)
+	if !ok || !encryptedRepo.IsEncryptedPath(path) {
+		return
+	}
+	if err := encryptedRepo.SealPath(path); err != nil {
+		log.Printf("加密移入加密資料夾的檔案失敗 %s: %v", path, err)
+	}
+}
+
 // copyFile 複製單個檔案
 // 參數：sourcePath、destPath（來源和目標路徑）
 // 回傳：可能的錯誤
@@ -735,6 +816,13 @@ func (s *LocalFileManagerService) copyDirectory(sourcePath, destPath string) err
 		return err
 	}
 	
+	// 複製加密資料夾時目標也設為加密資料夾，避免複本以明文寫入
+	if encryptedRepo, ok := s.fileRepo.(EncryptedFileRepository); ok && encryptedRepo.IsEncryptedFolder(sourcePath) {
+		if err := encryptedRepo.CreateEncryptedFolder(destPath); err != nil {
+			return err
+		}
+	}
+	
 	// 列出來源目錄內容
 	fileInfos, err := s.fileRepo.ListDirectory(sourcePath)
 	if err != nil {
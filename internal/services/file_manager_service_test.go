@@ -161,6 +161,22 @@ func TestListFiles(t *testing.T) {
 		}
 	})
 	
+	// 測試案例：筆記的簽章檔不應顯示在列表中
+	t.Run("隱藏簽章檔", func(t *testing.T) {
+		fileRepo.WriteFile(SignatureFilePath("file1.md"), []byte("{}"))
+		defer fileRepo.DeleteFile(SignatureFilePath("file1.md"))
+		
+		fileInfos, err := service.ListFiles(".")
+		if err != nil {
+			t.Fatalf("列出檔案失敗：%v", err)
+		}
+		for _, info := range fileInfos {
+			if IsSignatureFile(info.Name) {
+				t.Errorf("簽章檔不應出現在列表中：%s", info.Name)
+			}
+		}
+	})
+	
 	// 測試案例：列出不存在的目錄應該回傳錯誤
 	t.Run("列出不存在的目錄應該回傳錯誤", func(t *testing.T) {
 		_, err := service.ListFiles("nonexistent")
@@ -0,0 +1,251 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含筆記本目錄的即時監看：外部程式或同步服務新增、修改、重新命名或刪除檔案時，
+// 合併短時間內的多個事件後通知檔案樹、開啟中的筆記和依檔案內容建立的快取
+package services
+
+import (
+	"fmt"           // 格式化輸出
+	"io/fs"         // 目錄遍歷
+	"log"           // 日誌記錄
+	"path/filepath" // 檔案路徑處理
+	"sort"          // 排序
+	"strings"       // 字串處理
+	"sync"          // 同步控制
+	"time"          // 時間處理
+
+	"github.com/fsnotify/fsnotify" // 檔案系統事件通知
+
+	"mac-notebook-app/internal/repositories" // 引入儲存庫（暫存檔判斷）
+)
+
+// FileWatchDebounce 合併檔案事件的等待時間，最後一個事件之後經過此時間才通知
+const FileWatchDebounce = 300 * time.Millisecond
+
+// FileChangeOp 檔案變更的類型
+type FileChangeOp int
+
+// 檔案變更類型常數
+const (
+	FileCreated  FileChangeOp = iota // 新增（包含重新命名或移入後的新路徑）
+	FileModified                     // 內容變更
+	FileRemoved                      // 刪除（包含重新命名或移出前的舊路徑）
+)
+
+// FileChangeEvent 代表一個檔案或目錄的變更
+type FileChangeEvent struct {
+	Path string       // 相對於筆記本目錄的路徑
+	Op   FileChangeOp // 變更類型
+}
+
+// FileWatcherService 定義筆記本目錄監看服務的介面
+type FileWatcherService interface {
+	// Start 開始監看筆記本目錄和所有子目錄
+	// 回傳：可能的錯誤
+	Start() error
+
+	// Stop 停止監看，尚未通知的事件會被捨棄
+	// 回傳：可能的錯誤
+	Stop() error
+
+	// Subscribe 註冊檔案變更的通知函數，合併後的事件在背景 goroutine 中通知
+	// 參數：listener（通知函數，參數為依路徑排序的變更）
+	Subscribe(listener func(events []FileChangeEvent))
+}
+
+// fileWatcherService 實作 FileWatcherService 介面
+type fileWatcherService struct {
+	baseDir   string                           // 筆記本目錄
+	debounce  time.Duration                    // 合併事件的等待時間
+	watcher   *fsnotify.Watcher                // 檔案系統監看器
+	listeners []func(events []FileChangeEvent) // 變更通知函數
+	pending   map[string]FileChangeOp          // 尚未通知的變更，以路徑為鍵
+	timer     *time.Timer                      // 下一次通知的定時器
+	done      chan struct{}                    // 停止監看的訊號
+	mutex     sync.Mutex                       // 保護監看狀態
+}
+
+// NewFileWatcherService 建立筆記本目錄監看服務
+// 參數：baseDir（筆記本目錄）
+// 回傳：監看服務實例，呼叫 Start 後才開始監看
+func NewFileWatcherService(baseDir string) FileWatcherService {
+	return &fileWatcherService{
+		baseDir:  filepath.Clean(baseDir),
+		debounce: FileWatchDebounce,
+		pending:  make(map[string]FileChangeOp),
+	}
+}
+
+// Subscribe 註冊檔案變更的通知函數
+// 參數：listener（通知函數）
+func (w *fileWatcherService) Subscribe(listener func(events []FileChangeEvent)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.listeners = append(w.listeners, listener)
+}
+
+// Start 開始監看筆記本目錄和所有子目錄
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 建立檔案系統監看器（fsnotify 不支援遞迴監看，逐一加入子目錄）
+// 2. 略過筆記本中繼資料目錄
+// 3. 在背景處理事件，新增的子目錄同樣加入監看
+func (w *fileWatcherService) Start() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.watcher != nil {
+		return nil
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("建立檔案監看器失敗: %w", err)
+	}
+	if err := w.addDirectories(watcher, w.baseDir); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	w.watcher = watcher
+	w.done = make(chan struct{})
+	go w.run(watcher, w.done)
+	return nil
+}
+
+// Stop 停止監看
+// 回傳：可能的錯誤
+func (w *fileWatcherService) Stop() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.watcher == nil {
+		return nil
+	}
+	close(w.done)
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	w.pending = make(map[string]FileChangeOp)
+	err := w.watcher.Close()
+	w.watcher = nil
+	return err
+}
+
+// run 處理檔案系統事件直到停止監看
+// 參數：watcher（檔案系統監看器）、done（停止監看的訊號）
+func (w *fileWatcherService) run(watcher *fsnotify.Watcher, done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			w.handleEvent(watcher, event)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("檔案監看錯誤: %v", err)
+		}
+	}
+}
+
+// handleEvent 將檔案系統事件轉換為檔案變更並排定通知
+// 參數：watcher（檔案系統監看器）、event（檔案系統事件）
+//
+// 執行流程：
+// 1. 略過筆記本中繼資料目錄、寫入中的暫存檔和只變更權限的事件
+// 2. 新增的目錄加入監看
+// 3. 同一路徑的多個事件合併為一個（新增後又修改仍視為新增）
+// 4. 重設通知定時器，等待事件停止後再通知
+func (w *fileWatcherService) handleEvent(watcher *fsnotify.Watcher, event fsnotify.Event) {
+	rel, err := filepath.Rel(w.baseDir, event.Name)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") || isWatchIgnored(rel) {
+		return
+	}
+
+	var op FileChangeOp
+	switch {
+	case event.Has(fsnotify.Create):
+		op = FileCreated
+		if err := w.addDirectories(watcher, event.Name); err != nil {
+			log.Printf("監看新目錄 %s 失敗: %v", rel, err)
+		}
+	case event.Has(fsnotify.Write):
+		op = FileModified
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		op = FileRemoved
+	default:
+		return
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.watcher != watcher {
+		return
+	}
+	if previous, ok := w.pending[rel]; ok && previous == FileCreated && op == FileModified {
+		op = FileCreated
+	}
+	w.pending[rel] = op
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.timer = time.AfterFunc(w.debounce, w.flush)
+}
+
+// flush 通知所有尚未通知的變更
+func (w *fileWatcherService) flush() {
+	w.mutex.Lock()
+	if len(w.pending) == 0 {
+		w.mutex.Unlock()
+		return
+	}
+	events := make([]FileChangeEvent, 0, len(w.pending))
+	for path, op := range w.pending {
+		events = append(events, FileChangeEvent{Path: path, Op: op})
+	}
+	w.pending = make(map[string]FileChangeOp)
+	w.timer = nil
+	listeners := append([]func(events []FileChangeEvent){}, w.listeners...)
+	w.mutex.Unlock()
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Path < events[j].Path
+	})
+	for _, listener := range listeners {
+		listener(events)
+	}
+}
+
+// addDirectories 將目錄和所有子目錄加入監看
+// 參數：watcher（檔案系統監看器）、root（目錄的完整路徑，不是目錄時不處理）
+// 回傳：可能的錯誤
+func (w *fileWatcherService) addDirectories(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			// 遍歷時已被刪除的項目和檔案不需要監看
+			return nil
+		}
+		if d.Name() == NotebookMetaDir {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(path); err != nil {
+			return fmt.Errorf("監看目錄 %s 失敗: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// isWatchIgnored 檢查變更是否不需要通知
+// 參數：rel（相對於筆記本目錄的路徑）
+// 回傳：筆記本中繼資料目錄中的檔案和寫入中的暫存檔不需要通知
+func isWatchIgnored(rel string) bool {
+	if rel == NotebookMetaDir || strings.HasPrefix(rel, NotebookMetaDir+string(filepath.Separator)) {
+		return true
+	}
+	return repositories.IsTempFile(filepath.Base(rel))
+}
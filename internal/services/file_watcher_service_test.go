@@ -0,0 +1,117 @@
+// Package services 提供筆記本目錄監看和外部變更重新載入的單元測試
+// 測試檔案事件的合併、新增子目錄的監看、中繼資料目錄的略過，以及未編輯的筆記自動重新載入
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// startTestFileWatcher 建立以暫存目錄為基礎的監看服務，通知的事件送到回傳的通道
+// 回傳：基礎目錄和接收通知的通道
+func startTestFileWatcher(t *testing.T) (string, chan []FileChangeEvent) {
+	baseDir := t.TempDir()
+	watcher := NewFileWatcherService(baseDir)
+	watcher.(*fileWatcherService).debounce = 50 * time.Millisecond
+
+	notifications := make(chan []FileChangeEvent, 10)
+	watcher.Subscribe(func(events []FileChangeEvent) {
+		notifications <- events
+	})
+	if err := watcher.Start(); err != nil {
+		t.Fatalf("開始監看失敗: %v", err)
+	}
+	t.Cleanup(func() { watcher.Stop() })
+	return baseDir, notifications
+}
+
+// waitFileChanges 等待下一次通知
+// 回傳：以路徑為鍵的變更類型
+func waitFileChanges(t *testing.T, notifications chan []FileChangeEvent) map[string]FileChangeOp {
+	select {
+	case events := <-notifications:
+		changes := make(map[string]FileChangeOp, len(events))
+		for _, event := range events {
+			changes[event.Path] = event.Op
+		}
+		return changes
+	case <-time.After(3 * time.Second):
+		t.Fatal("等待檔案變更通知逾時")
+		return nil
+	}
+}
+
+// TestFileWatcherNotifiesChanges 測試新增、修改和刪除檔案的通知
+func TestFileWatcherNotifiesChanges(t *testing.T) {
+	baseDir, notifications := startTestFileWatcher(t)
+
+	// 連續寫入合併為一次通知，新增後又修改仍視為新增
+	path := filepath.Join(baseDir, "note.md")
+	os.WriteFile(path, []byte("第一版"), 0644)
+	os.WriteFile(path, []byte("第二版"), 0644)
+	if changes := waitFileChanges(t, notifications); changes["note.md"] != FileCreated || len(changes) != 1 {
+		t.Errorf("新增檔案的通知不正確: %v", changes)
+	}
+
+	os.WriteFile(path, []byte("第三版"), 0644)
+	if changes := waitFileChanges(t, notifications); changes["note.md"] != FileModified {
+		t.Errorf("修改檔案的通知不正確: %v", changes)
+	}
+
+	os.Remove(path)
+	if changes := waitFileChanges(t, notifications); changes["note.md"] != FileRemoved {
+		t.Errorf("刪除檔案的通知不正確: %v", changes)
+	}
+}
+
+// TestFileWatcherWatchesNewDirectories 測試新增的子目錄同樣被監看，中繼資料目錄被略過
+func TestFileWatcherWatchesNewDirectories(t *testing.T) {
+	baseDir, notifications := startTestFileWatcher(t)
+
+	os.Mkdir(filepath.Join(baseDir, "projects"), 0755)
+	if changes := waitFileChanges(t, notifications); changes["projects"] != FileCreated {
+		t.Errorf("新增目錄的通知不正確: %v", changes)
+	}
+
+	os.Mkdir(filepath.Join(baseDir, NotebookMetaDir), 0755)
+	os.WriteFile(filepath.Join(baseDir, NotebookMetaDir, "settings.json"), []byte("{}"), 0644)
+	os.WriteFile(filepath.Join(baseDir, "projects", "plan.md"), []byte("計畫"), 0644)
+	changes := waitFileChanges(t, notifications)
+	if changes[filepath.Join("projects", "plan.md")] != FileCreated {
+		t.Errorf("新子目錄中的檔案應該被監看: %v", changes)
+	}
+	for path := range changes {
+		if isWatchIgnored(path) {
+			t.Errorf("中繼資料目錄的變更不應通知: %s", path)
+		}
+	}
+}
+
+// TestReloadNote 測試外部變更時未編輯的筆記重新載入，有未保存編輯的筆記保留編輯
+func TestReloadNote(t *testing.T) {
+	service, mockRepo := createTestEditorService()
+	mockRepo.WriteFile("a.md", []byte("原始內容"))
+	note, _ := service.OpenNote("a.md")
+
+	// 自己保存或內容未變更時不需要重新載入
+	if reloaded, err := service.ReloadNote(note.ID); err != nil || reloaded {
+		t.Errorf("檔案未變更時不應重新載入: %v, %v", reloaded, err)
+	}
+
+	mockRepo.WriteFile("a.md", []byte("同步的內容"))
+	reloaded, err := service.ReloadNote(note.ID)
+	if err != nil || !reloaded || note.Content != "同步的內容" || note.IsModified() {
+		t.Fatalf("未編輯的筆記應該重新載入: %v, %v, %q", reloaded, err, note.Content)
+	}
+
+	service.UpdateContent(note.ID, "我的編輯")
+	mockRepo.WriteFile("a.md", []byte("再次同步的內容"))
+	if reloaded, _ := service.ReloadNote(note.ID); reloaded || note.Content != "我的編輯" {
+		t.Errorf("有未保存編輯時不應重新載入: %q", note.Content)
+	}
+	if _, ok := AsSaveConflict(service.SaveNote(note)); !ok {
+		t.Error("有未保存編輯時應該在保存時提示衝突")
+	}
+}
@@ -0,0 +1,456 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含筆記開頭的 YAML front matter：開啟時解析為筆記的 ID、標題、時間戳、標籤、別名和自訂屬性，
+// 保存時只改寫 front matter 中有變更的鍵，本文維持原樣。加密筆記的 front matter 隨內容一起加密
+package services
+
+import (
+	"bytes"   // 緩衝區處理
+	"fmt"     // 格式化輸出
+	"log"     // 日誌記錄
+	"maps"    // 對應表處理
+	"reflect" // 屬性值比較
+	"slices"  // 切片處理
+	"sort"    // 排序
+	"strings" // 字串處理
+	"time"    // 時間處理
+
+	"gopkg.in/yaml.v3" // YAML 解析和輸出
+
+	"mac-notebook-app/internal/models" // 引入資料模型
+)
+
+// front matter 中有特定用途的鍵
+const (
+	FrontMatterID      = "id"      // 筆記 ID
+	FrontMatterTitle   = "title"   // 標題
+	FrontMatterCreated = "created" // 建立時間
+	FrontMatterUpdated = "updated" // 最後修改時間（已存在時保存時自動更新）
+	FrontMatterTags    = "tags"    // 標籤
+	FrontMatterAliases = "aliases" // 別名
+)
+
+// frontMatterKeyOrder 新增鍵時的排列順序，其他鍵依名稱排在後面
+var frontMatterKeyOrder = []string{FrontMatterID, FrontMatterTitle, FrontMatterCreated, FrontMatterUpdated, FrontMatterTags, FrontMatterAliases}
+
+// frontMatterTimeLayouts 可以解析的時間格式
+var frontMatterTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// FrontMatter 代表筆記開頭的 YAML front matter
+type FrontMatter struct {
+	ID         string         // 筆記 ID
+	Title      string         // 標題
+	Created    time.Time      // 建立時間
+	Updated    time.Time      // 最後修改時間
+	Tags       []string       // 標籤（不含 # 符號）
+	Aliases    []string       // 別名
+	Properties map[string]any // 其他自訂屬性
+}
+
+// ApplyTo 將 front matter 的內容套用到筆記，未設定的 ID、標題和時間戳保留筆記原本的值
+// 參數：note（筆記）
+func (fm *FrontMatter) ApplyTo(note *models.Note) {
+	if fm.Title != "" {
+		note.Title = fm.Title
+	}
+	if !fm.Created.IsZero() {
+		note.CreatedAt = fm.Created
+	}
+	if !fm.Updated.IsZero() {
+		note.UpdatedAt = fm.Updated
+	}
+	note.Tags = slices.Clone(fm.Tags)
+	note.Aliases = slices.Clone(fm.Aliases)
+	note.Properties = maps.Clone(fm.Properties)
+}
+
+// frontMatterBOM UTF-8 位元組順序標記，front matter 可以位於其後
+const frontMatterBOM = "\ufeff"
+
+// frontMatterParts 筆記內容分離後的各部分，依序串接即為原本的內容
+type frontMatterParts struct {
+	bom      string // 開頭的位元組順序標記
+	opening  string // 開始分隔行（含換行）
+	yamlText string // YAML 文字
+	closing  string // 結束分隔行（含換行）
+	body     string // 本文
+}
+
+// splitFrontMatter 分離 front matter 和本文
+// 參數：content（筆記內容）
+// 回傳：分離後的各部分和是否有 front matter（沒有時整份內容為本文）
+//
+// front matter 必須位於檔案開頭，以 --- 開始，以 --- 或 ... 結束
+func splitFrontMatter(content string) (frontMatterParts, bool) {
+	parts := frontMatterParts{body: content}
+	rest := strings.TrimPrefix(content, frontMatterBOM)
+	bom := content[:len(content)-len(rest)]
+
+	firstLine, after, found := strings.Cut(rest, "\n")
+	if !found || strings.TrimRight(firstLine, " \t\r") != "---" {
+		return parts, false
+	}
+
+	for pos := 0; pos < len(after); {
+		line, _, hasNext := strings.Cut(after[pos:], "\n")
+		end := pos + len(line)
+		if hasNext {
+			end++
+		}
+		if trimmed := strings.TrimRight(line, " \t\r"); trimmed == "---" || trimmed == "..." {
+			return frontMatterParts{
+				bom:      bom,
+				opening:  firstLine + "\n",
+				yamlText: after[:pos],
+				closing:  after[pos:end],
+				body:     after[end:],
+			}, true
+		}
+		pos = end
+	}
+	return parts, false
+}
+
+// StripFrontMatter 移除筆記開頭的 front matter，用於預覽和匯出
+// 參數：content（筆記內容）
+// 回傳：本文
+func StripFrontMatter(content string) string {
+	parts, _ := splitFrontMatter(content)
+	return parts.body
+}
+
+// ParseFrontMatter 解析筆記開頭的 front matter
+// 參數：content（筆記內容）
+// 回傳：解析結果（沒有 front matter 時為空的 FrontMatter）、是否有 front matter 和 YAML 格式錯誤
+func ParseFrontMatter(content string) (*FrontMatter, bool, error) {
+	fm := &FrontMatter{}
+	parts, ok := splitFrontMatter(content)
+	if !ok {
+		return fm, false, nil
+	}
+
+	mapping, err := parseFrontMatterMapping(parts.yamlText)
+	if err != nil {
+		return nil, true, err
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i].Value, mapping.Content[i+1]
+		switch key {
+		case FrontMatterID:
+			fm.ID = strings.TrimSpace(value.Value)
+		case FrontMatterTitle:
+			fm.Title = strings.TrimSpace(value.Value)
+		case FrontMatterCreated, FrontMatterUpdated:
+			parsed, ok := parseFrontMatterTime(value.Value)
+			if !ok {
+				// 無法解析的時間當作一般屬性保留
+				fm.setProperty(key, value)
+			} else if key == FrontMatterCreated {
+				fm.Created = parsed
+			} else {
+				fm.Updated = parsed
+			}
+		case FrontMatterTags:
+			fm.Tags = frontMatterList(value, true)
+		case FrontMatterAliases:
+			fm.Aliases = frontMatterList(value, false)
+		default:
+			fm.setProperty(key, value)
+		}
+	}
+	return fm, true, nil
+}
+
+// setProperty 將 YAML 值解碼為自訂屬性
+// 參數：key（鍵）、value（YAML 值）
+func (fm *FrontMatter) setProperty(key string, value *yaml.Node) {
+	var decoded any
+	if err := value.Decode(&decoded); err != nil {
+		decoded = value.Value
+	}
+	if fm.Properties == nil {
+		fm.Properties = make(map[string]any)
+	}
+	fm.Properties[key] = decoded
+}
+
+// SetFrontMatterValues 更新 front matter 中的鍵，其他鍵的順序和註解以及本文維持原樣
+// 參數：content（筆記內容）、values（要設定的鍵和值，值為 nil 或空切片時移除該鍵）
+// 回傳：更新後的內容和 YAML 格式錯誤
+//
+// 執行流程：
+// 1. 沒有 front matter 時在開頭新增，移除後沒有任何鍵時整段移除
+// 2. 已存在的鍵就地替換值，新增的鍵依固定順序加在最後
+// 3. 只重新輸出 front matter，本文不經過任何處理
+func SetFrontMatterValues(content string, values map[string]any) (string, error) {
+	if len(values) == 0 {
+		return content, nil
+	}
+	parts, ok := splitFrontMatter(content)
+	newline := "\n"
+	if strings.HasSuffix(parts.opening, "\r\n") {
+		newline = "\r\n"
+	}
+	if !ok {
+		rest := strings.TrimPrefix(content, frontMatterBOM)
+		parts.bom, parts.body = content[:len(content)-len(rest)], rest
+		parts.opening, parts.closing = "---"+newline, "---"+newline
+	}
+
+	mapping, err := parseFrontMatterMapping(parts.yamlText)
+	if err != nil {
+		return "", err
+	}
+	for _, key := range sortedFrontMatterKeys(values) {
+		index := -1
+		for i := 0; i+1 < len(mapping.Content); i += 2 {
+			if mapping.Content[i].Value == key {
+				index = i
+				break
+			}
+		}
+
+		node, err := frontMatterValueNode(values[key])
+		if err != nil {
+			return "", fmt.Errorf("front matter 的 %s 無法轉換為 YAML: %w", key, err)
+		}
+		switch {
+		case node == nil && index >= 0:
+			mapping.Content = slices.Delete(mapping.Content, index, index+2)
+		case node == nil:
+			// 要移除的鍵原本就不存在
+		case index >= 0:
+			node.LineComment = mapping.Content[index+1].LineComment
+			mapping.Content[index+1] = node
+		default:
+			keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+			mapping.Content = append(mapping.Content, keyNode, node)
+		}
+	}
+
+	if len(mapping.Content) == 0 && mapping.HeadComment == "" && mapping.FootComment == "" {
+		return parts.bom + parts.body, nil
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{mapping}}); err != nil {
+		return "", fmt.Errorf("輸出 front matter 失敗: %w", err)
+	}
+	encoder.Close()
+	encoded := buf.String()
+	if newline != "\n" {
+		encoded = strings.ReplaceAll(encoded, "\n", newline)
+	}
+	return parts.bom + parts.opening + encoded + parts.closing + parts.body, nil
+}
+
+// parseFrontMatterMapping 解析 front matter 的 YAML 文字
+// 參數：yamlText（YAML 文字）
+// 回傳：鍵值對應節點（空白的 front matter 回傳空的對應節點）和可能的錯誤
+func parseFrontMatterMapping(yamlText string) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(yamlText), &doc); err != nil {
+		return nil, fmt.Errorf("front matter 格式錯誤: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	mapping := doc.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("front matter 必須是鍵值對應")
+	}
+	return mapping, nil
+}
+
+// frontMatterValueNode 將值轉換為 YAML 節點
+// 參數：value（字串、時間、字串切片或其他可輸出為 YAML 的值）
+// 回傳：YAML 節點（值為 nil 或空切片時為 nil）和可能的錯誤
+func frontMatterValueNode(value any) (*yaml.Node, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}, nil
+	case time.Time:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: v.Format(time.RFC3339)}, nil
+	case []string:
+		if len(v) == 0 {
+			return nil, nil
+		}
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+		for _, item := range v {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: item})
+		}
+		return node, nil
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(value); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// sortedFrontMatterKeys 依新增順序排列要設定的鍵
+// 參數：values（要設定的鍵和值）
+// 回傳：排序後的鍵
+func sortedFrontMatterKeys(values map[string]any) []string {
+	keys := slices.Collect(maps.Keys(values))
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := slices.Index(frontMatterKeyOrder, keys[i]), slices.Index(frontMatterKeyOrder, keys[j])
+		if a < 0 {
+			a = len(frontMatterKeyOrder)
+		}
+		if b < 0 {
+			b = len(frontMatterKeyOrder)
+		}
+		if a != b {
+			return a < b
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// parseFrontMatterTime 解析 front matter 中的時間
+// 參數：value（時間字串）
+// 回傳：時間和是否解析成功（沒有時區的時間視為本地時間）
+func parseFrontMatterTime(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range frontMatterTimeLayouts {
+		if parsed, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return parsed, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// frontMatterList 取得 YAML 清單或以逗號分隔的字串中的項目
+// 參數：node（YAML 值）、isTags（標籤允許以空白分隔，並移除開頭的 # 符號）
+// 回傳：去除空白和重複後的項目
+func frontMatterList(node *yaml.Node, isTags bool) []string {
+	var raw []string
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			raw = append(raw, item.Value)
+		}
+	case yaml.ScalarNode:
+		separators := ","
+		if isTags {
+			separators = ", \t"
+		}
+		raw = strings.FieldsFunc(node.Value, func(r rune) bool {
+			return strings.ContainsRune(separators, r)
+		})
+	}
+
+	var items []string
+	for _, item := range raw {
+		item = strings.TrimSpace(item)
+		if isTags {
+			item = strings.TrimPrefix(item, "#")
+		}
+		if item != "" && !slices.Contains(items, item) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// applyFrontMatter 以筆記內容的 front matter 更新筆記欄位，並記錄同步時的欄位
+// 參數：note（筆記）
+func (e *editorService) applyFrontMatter(note *models.Note) {
+	fm, _, err := ParseFrontMatter(note.Content)
+	if err != nil {
+		log.Printf("解析 %s 的 front matter 失敗: %v", note.FilePath, err)
+	} else {
+		fm.ApplyTo(note)
+	}
+	e.frontMatters[note.ID] = noteFrontMatter(note)
+}
+
+// syncFrontMatter 保存前同步 front matter 和筆記欄位
+// 參數：note（要保存的筆記）、savedAt（保存時間）
+//
+// 執行流程：
+// 1. front matter 格式錯誤時不處理，內容照原樣保存
+// 2. 與上次同步時的欄位比對，程式修改過的標籤、別名、自訂屬性和標題寫入 front matter
+// 3. front matter 已有 updated 時更新為保存時間
+// 4. 以寫入後的 front matter 更新筆記欄位（使用者在編輯器中直接修改的部分因此保留）
+func (e *editorService) syncFrontMatter(note *models.Note, savedAt time.Time) {
+	current, hasFrontMatter, err := ParseFrontMatter(note.Content)
+	if err != nil {
+		log.Printf("%s 的 front matter 格式錯誤，保存時不更新: %v", note.FilePath, err)
+		return
+	}
+
+	synced := e.frontMatters[note.ID]
+	if synced == nil {
+		synced = &FrontMatter{}
+	}
+	values := make(map[string]any)
+	if !slices.Equal(note.Tags, synced.Tags) {
+		values[FrontMatterTags] = note.Tags
+	}
+	if !slices.Equal(note.Aliases, synced.Aliases) {
+		values[FrontMatterAliases] = note.Aliases
+	}
+	for key := range mergedKeys(note.Properties, synced.Properties) {
+		if slices.Contains(frontMatterKeyOrder, key) {
+			continue
+		}
+		if !reflect.DeepEqual(note.Properties[key], synced.Properties[key]) {
+			values[key] = note.Properties[key]
+		}
+	}
+	if current.Title != "" && note.Title != synced.Title {
+		values[FrontMatterTitle] = note.Title
+	}
+	if hasFrontMatter && !current.Updated.IsZero() {
+		values[FrontMatterUpdated] = savedAt
+	}
+
+	content, err := SetFrontMatterValues(note.Content, values)
+	if err != nil {
+		log.Printf("更新 %s 的 front matter 失敗: %v", note.FilePath, err)
+		return
+	}
+	note.Content = content
+	e.applyFrontMatter(note)
+}
+
+// noteFrontMatter 取得筆記目前對應 front matter 的欄位
+// 參數：note（筆記）
+// 回傳：欄位的複本
+func noteFrontMatter(note *models.Note) *FrontMatter {
+	return &FrontMatter{
+		ID:         note.ID,
+		Title:      note.Title,
+		Created:    note.CreatedAt,
+		Updated:    note.UpdatedAt,
+		Tags:       slices.Clone(note.Tags),
+		Aliases:    slices.Clone(note.Aliases),
+		Properties: maps.Clone(note.Properties),
+	}
+}
+
+// mergedKeys 取得兩個對應表的所有鍵
+// 參數：a、b（對應表）
+// 回傳：鍵的集合
+func mergedKeys(a, b map[string]any) map[string]bool {
+	keys := make(map[string]bool, len(a)+len(b))
+	for key := range a {
+		keys[key] = true
+	}
+	for key := range b {
+		keys[key] = true
+	}
+	return keys
+}
@@ -0,0 +1,165 @@
+// Package services 提供 YAML front matter 的單元測試
+// 測試解析、只改寫有變更的鍵、本文維持原樣，以及開啟和保存筆記時的同步
+package services
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseFrontMatter 測試解析 front matter 中的各種欄位
+func TestParseFrontMatter(t *testing.T) {
+	content := "---\r\nid: note-123\r\ntitle: 專案計畫\r\ncreated: 2024-03-01 09:30\r\ntags: \"#工作, project/alpha\"\r\naliases: [計畫, plan]\r\nstatus: draft\r\npriority: 2\r\n---\r\n# 本文\r\n"
+	fm, ok, err := ParseFrontMatter(content)
+	if err != nil || !ok {
+		t.Fatalf("解析 front matter 失敗: %v, %v", ok, err)
+	}
+	if fm.ID != "note-123" || fm.Title != "專案計畫" {
+		t.Errorf("ID 或標題不正確: %+v", fm)
+	}
+	if !fm.Created.Equal(time.Date(2024, 3, 1, 9, 30, 0, 0, time.Local)) {
+		t.Errorf("建立時間不正確: %v", fm.Created)
+	}
+	if !reflect.DeepEqual(fm.Tags, []string{"工作", "project/alpha"}) || !reflect.DeepEqual(fm.Aliases, []string{"計畫", "plan"}) {
+		t.Errorf("標籤或別名不正確: %v, %v", fm.Tags, fm.Aliases)
+	}
+	if fm.Properties["status"] != "draft" || fm.Properties["priority"] != 2 {
+		t.Errorf("自訂屬性不正確: %v", fm.Properties)
+	}
+	if StripFrontMatter(content) != "# 本文\r\n" {
+		t.Errorf("本文不正確: %q", StripFrontMatter(content))
+	}
+
+	if _, ok, _ := ParseFrontMatter("# 沒有 front matter\n---\n"); ok {
+		t.Error("不在開頭的分隔線不應視為 front matter")
+	}
+	if _, _, err := ParseFrontMatter("---\n- 清單\n---\n"); err == nil {
+		t.Error("不是鍵值對應的 front matter 應該回報錯誤")
+	}
+}
+
+// TestSetFrontMatterValues 測試只改寫有變更的鍵，其他鍵、註解和本文維持原樣
+func TestSetFrontMatterValues(t *testing.T) {
+	body := "# 標題\n\n  縮排和結尾空白都要保留  \n\n---\n"
+	content := "---\ntitle: 計畫 # 顯示名稱\nstatus: draft\ntags: [舊標籤]\n---\n" + body
+
+	updated, err := SetFrontMatterValues(content, map[string]any{
+		FrontMatterTags: []string{"工作", "project/alpha"},
+		"status":        nil,
+		"reviewed":      true,
+	})
+	if err != nil {
+		t.Fatalf("更新 front matter 失敗: %v", err)
+	}
+	expected := "---\ntitle: 計畫 # 顯示名稱\ntags: [工作, project/alpha]\nreviewed: true\n---\n" + body
+	if updated != expected {
+		t.Errorf("更新結果不正確:\n%s", updated)
+	}
+
+	// 沒有 front matter 時在開頭新增，移除所有鍵後整段移除
+	added, _ := SetFrontMatterValues("\ufeff"+body, map[string]any{FrontMatterTags: []string{"新標籤"}})
+	if added != "\ufeff---\ntags: [新標籤]\n---\n"+body {
+		t.Errorf("新增 front matter 不正確:\n%q", added)
+	}
+	removed, _ := SetFrontMatterValues(added, map[string]any{FrontMatterTags: nil})
+	if removed != "\ufeff"+body {
+		t.Errorf("移除所有鍵後應該只剩本文:\n%q", removed)
+	}
+}
+
+// TestOpenNoteFrontMatter 測試開啟筆記時套用 front matter，重新開啟後 ID 維持不變
+func TestOpenNoteFrontMatter(t *testing.T) {
+	service, mockRepo := createTestEditorService()
+	mockRepo.WriteFile("plan.md", []byte("---\nid: note-123\ntitle: 專案計畫\ncreated: 2024-03-01\ntags: [工作]\n---\n內容"))
+
+	note, err := service.OpenNote("plan.md")
+	if err != nil {
+		t.Fatalf("開啟筆記失敗: %v", err)
+	}
+	if note.ID != "note-123" || note.Title != "專案計畫" || note.CreatedAt.Year() != 2024 || !reflect.DeepEqual(note.Tags, []string{"工作"}) {
+		t.Errorf("front matter 沒有套用到筆記: %+v", note)
+	}
+	service.CloseNote(note.ID)
+	reopened, _ := service.OpenNote("plan.md")
+	if reopened.ID != "note-123" {
+		t.Errorf("重新開啟後 ID 應該維持不變: %s", reopened.ID)
+	}
+
+	// 複製的檔案有相同的 ID 時另外產生，不會取代開啟中的筆記
+	mockRepo.WriteFile("plan copy.md", mockRepo.files["plan.md"])
+	copied, _ := service.OpenNote("plan copy.md")
+	if copied.ID == reopened.ID {
+		t.Error("兩份開啟中的筆記不應使用相同的 ID")
+	}
+}
+
+// TestSaveNoteFrontMatter 測試保存時寫入程式修改的標籤，使用者在編輯器中修改的 front matter 保留
+func TestSaveNoteFrontMatter(t *testing.T) {
+	service, mockRepo := createTestEditorService()
+	body := "# 會議記錄\n\n* 項目一\n*  項目二（格式不一致也要保留）\n"
+	mockRepo.WriteFile("meeting.md", []byte("---\ntitle: 會議\nupdated: 2024-01-01T00:00:00Z\n---\n"+body))
+	note, _ := service.OpenNote("meeting.md")
+
+	note.Tags = append(note.Tags, "會議")
+	if err := service.SaveNote(note); err != nil {
+		t.Fatalf("保存筆記失敗: %v", err)
+	}
+	saved := string(mockRepo.files["meeting.md"])
+	if !strings.HasSuffix(saved, "\n---\n"+body) || !strings.Contains(saved, "tags: [會議]") {
+		t.Errorf("應該寫入標籤並保留本文:\n%s", saved)
+	}
+	if strings.Contains(saved, "2024-01-01") {
+		t.Errorf("已有 updated 時應該更新為保存時間:\n%s", saved)
+	}
+
+	// 使用者直接在編輯器中修改 front matter
+	edited := strings.Replace(note.Content, "tags: [會議]", "tags: [會議, 週報]", 1)
+	service.UpdateContent(note.ID, edited)
+	if err := service.SaveNote(note); err != nil {
+		t.Fatalf("保存筆記失敗: %v", err)
+	}
+	if !reflect.DeepEqual(note.Tags, []string{"會議", "週報"}) {
+		t.Errorf("應該以編輯器中的 front matter 更新標籤: %v", note.Tags)
+	}
+
+	// 沒有 front matter 的筆記保存後維持原樣
+	mockRepo.WriteFile("plain.md", []byte(body))
+	plain, _ := service.OpenNote("plain.md")
+	service.SaveNote(plain)
+	if string(mockRepo.files["plain.md"]) != body {
+		t.Errorf("沒有 front matter 的筆記不應被改寫:\n%s", mockRepo.files["plain.md"])
+	}
+}
+
+// TestEncryptedNoteFrontMatter 測試加密筆記的 front matter 隨內容一起加密
+func TestEncryptedNoteFrontMatter(t *testing.T) {
+	service, mockRepo := createTestEditorService()
+	vault, _ := createTestVaultService(t)
+	vault.Initialize("TestPassword123!")
+	service.SetOptions(EditorOptions{Vault: vault})
+
+	note, _ := service.CreateNote("機密", "---\nid: secret-1\ntags: [機密標籤]\n---\n機密內容")
+	note.FilePath = "secret.md"
+	mockRepo.WriteFile(note.FilePath, []byte(note.Content))
+	if err := service.(*editorService).EnableEncryption(note.ID, "TestPassword123!", AlgorithmAES256, false); err != nil {
+		t.Fatalf("啟用加密失敗: %v", err)
+	}
+	if err := service.SaveNote(note); err != nil {
+		t.Fatalf("保存加密筆記失敗: %v", err)
+	}
+	if bytes.Contains(mockRepo.files[note.FilePath], []byte("機密標籤")) {
+		t.Error("front matter 不應以明文保存")
+	}
+
+	service.CloseNote(note.ID)
+	opened, err := service.OpenNote(note.FilePath)
+	if err != nil {
+		t.Fatalf("開啟加密筆記失敗: %v", err)
+	}
+	if opened.ID != "secret-1" || !reflect.DeepEqual(opened.Tags, []string{"機密標籤"}) {
+		t.Errorf("加密筆記的 front matter 應該在解密後套用: %+v", opened)
+	}
+}
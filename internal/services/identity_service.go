@@ -0,0 +1,479 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含公鑰身分服務，管理使用者的 X25519 身分金鑰庫，
+// 並以收件人公鑰加密和開啟分享給團隊成員的筆記
+package services
+
+import (
+	"crypto/ecdh"     // X25519 金鑰交換
+	"crypto/rand"     // 安全隨機數產生
+	"encoding/base64" // Base64 編碼
+	"encoding/json"   // JSON 序列化
+	"errors"          // 錯誤處理
+	"fmt"             // 格式化輸出
+	"path/filepath"   // 檔案路徑處理
+	"strings"         // 字串處理
+	"sync"            // 同步原語
+	"time"            // 時間處理
+
+	"github.com/google/uuid"                 // UUID 生成
+	"mac-notebook-app/internal/models"       // 引入資料模型
+	"mac-notebook-app/internal/repositories" // 引入資料存取層
+)
+
+// 身分金鑰庫相關常數
+const (
+	identityPrivateKeyAAD   = "identity-private-key:" // 包裝身分私鑰時使用的附加驗證資料前綴
+	identitySessionKey      = "identity.master"       // 金鑰庫主金鑰在工作階段中的名稱
+	recipientFileSessionKey = "note.recipient:"       // 收件人格式筆記的檔案金鑰在工作階段中的名稱前綴
+	maxIdentityNameLength   = 100                     // 身分名稱長度上限
+)
+
+// IdentityRetryIdentifier 身分金鑰庫密碼在重試狀態中的識別符
+const IdentityRetryIdentifier = "identity-keystore"
+
+// 身分金鑰庫錯誤定義
+var (
+	ErrIdentityLocked     = errors.New("身分金鑰庫已鎖定，需要密碼驗證才能使用私鑰")
+	ErrIdentityNotFound   = errors.New("找不到指定的身分")
+	ErrIdentityNoIdentity = errors.New("尚未建立任何身分")
+)
+
+// Identity 代表使用者的 X25519 公鑰身分
+type Identity struct {
+	ID        string    `json:"id"`         // 身分識別碼
+	Name      string    `json:"name"`       // 顯示名稱
+	PublicKey string    `json:"public_key"` // 收件人公鑰字串，可提供給其他人
+	CreatedAt time.Time `json:"created_at"` // 建立時間
+}
+
+// storedIdentity 代表保存在金鑰庫中的身分，私鑰以金鑰庫主金鑰包裝
+type storedIdentity struct {
+	Identity
+	WrappedPrivateKey string `json:"wrapped_private_key"` // Base64 編碼的隨機數加上包裝後的私鑰
+}
+
+// identityKeystore 代表身分金鑰庫的內容
+// 金鑰庫主金鑰以密碼衍生的 KEK 包裝，格式與保險庫標頭相同
+type identityKeystore struct {
+	Header     *VaultHeader     `json:"header"`     // 金鑰庫主金鑰的包裝資訊
+	Identities []storedIdentity `json:"identities"` // 身分列表
+}
+
+// IdentityService 定義公鑰身分服務的介面
+// 負責身分金鑰庫的建立和解鎖，以及以收件人公鑰加密和開啟筆記
+type IdentityService interface {
+	// ListIdentities 取得所有身分（不含私鑰）
+	// 回傳：身分列表
+	ListIdentities() []Identity
+
+	// CreateIdentity 產生新的 X25519 身分，首次建立時以密碼建立金鑰庫
+	// 參數：name（顯示名稱）、password（金鑰庫密碼）
+	// 回傳：新的身分和可能的錯誤
+	CreateIdentity(name, password string) (*Identity, error)
+
+	// DeleteIdentity 刪除指定身分，刪除後無法再開啟加密給此身分的檔案
+	// 參數：id（身分識別碼）
+	// 回傳：可能的錯誤
+	DeleteIdentity(id string) error
+
+	// Unlock 以密碼解鎖金鑰庫，之後可使用身分私鑰開啟檔案
+	// 參數：password（金鑰庫密碼）
+	// 回傳：可能的錯誤
+	Unlock(password string) error
+
+	// IsUnlocked 檢查金鑰庫是否已解鎖
+	// 回傳：是否已解鎖
+	IsUnlocked() bool
+
+	// IsRecipientData 檢查資料是否為收件人公鑰加密格式
+	// 參數：data（檔案內容）
+	// 回傳：是否為收件人格式
+	IsRecipientData(data []byte) bool
+
+	// EncryptForRecipients 以收件人公鑰加密筆記內容，不需要解鎖金鑰庫
+	// 參數：content（明文內容）、algorithm（加密演算法）、recipients（收件人公鑰字串）
+	// 回傳：加密後的資料和可能的錯誤
+	EncryptForRecipients(content, algorithm string, recipients []string) ([]byte, error)
+
+	// DecryptNote 以自己的身分私鑰開啟收件人格式的筆記
+	// 檔案金鑰暫存在工作階段中，供重新保存時沿用同一組收件人
+	// 參數：data（加密資料）、noteID（筆記 ID）
+	// 回傳：明文內容、收件人資訊和可能的錯誤
+	DecryptNote(data []byte, noteID string) (string, *RecipientNoteInfo, error)
+
+	// ResealNote 以開啟時的檔案金鑰和收件人列表重新加密筆記
+	// 參數：content（明文內容）、info（開啟時取得的收件人資訊）、noteID（筆記 ID）
+	// 回傳：加密後的資料和可能的錯誤
+	ResealNote(content string, info *RecipientNoteInfo, noteID string) ([]byte, error)
+
+	// ForgetNote 清除筆記暫存的檔案金鑰
+	// 參數：noteID（筆記 ID）
+	ForgetNote(noteID string)
+
+	// Session 取得保存金鑰庫主金鑰的工作階段管理器
+	// 回傳：SessionManager 介面實例
+	Session() SessionManager
+
+	// SetRetryGuard 設定限制密碼重試次數的密碼服務
+	// 參數：passwordSvc（密碼服務，nil 表示不限制）
+	SetRetryGuard(passwordSvc PasswordService)
+
+	// SetAuditService 設定記錄解鎖事件的稽核記錄服務
+	// 參數：audit（稽核記錄服務，nil 表示不記錄）
+	SetAuditService(audit AuditService)
+}
+
+// identityService 實作 IdentityService 介面
+// 解鎖後的金鑰庫主金鑰保存在工作階段管理器中，工作階段鎖定時即被清零
+type identityService struct {
+	repo       repositories.EncryptionRepository // 金鑰儲存庫（保存在設定目錄中）
+	session    SessionManager                    // 工作階段管理器
+	retryGuard PasswordService                   // 限制密碼重試次數（nil 表示不限制）
+	audit      AuditService                      // 稽核記錄服務（nil 表示不記錄）
+	mutex      sync.Mutex                        // 保護金鑰庫讀寫
+}
+
+// NewIdentityService 建立新的公鑰身分服務實例
+// 參數：
+//   - repo: 金鑰儲存庫，保存身分金鑰庫
+//   - session: 工作階段管理器（可選，nil 時建立不會自動鎖定的工作階段）
+//
+// 回傳：IdentityService 介面實例，初始為鎖定狀態
+func NewIdentityService(repo repositories.EncryptionRepository, session SessionManager) IdentityService {
+	if session == nil {
+		session = NewSessionManager(0)
+	}
+
+	return &identityService{
+		repo:    repo,
+		session: session,
+	}
+}
+
+// DefaultKeystoreDir 取得身分金鑰庫的預設目錄，與設定檔案位於同一目錄
+// 回傳：目錄路徑
+func DefaultKeystoreDir() string {
+	return filepath.Dir(models.GetDefaultSettingsPath())
+}
+
+// ListIdentities 取得所有身分
+// 回傳：身分列表，金鑰庫不存在或無法讀取時回傳空列表
+func (s *identityService) ListIdentities() []Identity {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keystore, err := s.load()
+	if err != nil || keystore == nil {
+		return nil
+	}
+
+	identities := make([]Identity, 0, len(keystore.Identities))
+	for _, stored := range keystore.Identities {
+		identities = append(identities, stored.Identity)
+	}
+	return identities
+}
+
+// CreateIdentity 產生新的 X25519 身分
+// 參數：name（顯示名稱）、password（金鑰庫密碼）
+// 回傳：新的身分和可能的錯誤
+//
+// 執行流程：
+// 1. 金鑰庫不存在時產生金鑰庫主金鑰並以密碼包裝，已存在時以密碼解開主金鑰
+// 2. 產生 X25519 金鑰對，以主金鑰包裝私鑰（身分 ID 作為附加驗證資料）
+// 3. 儲存金鑰庫並將主金鑰保留在工作階段中（解鎖狀態）
+func (s *identityService) CreateIdentity(name, password string) (*Identity, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxIdentityNameLength {
+		return nil, errors.New("身分名稱無效")
+	}
+	if password == "" {
+		return nil, errors.New("密碼不能為空")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keystore, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var masterKey []byte
+	if keystore == nil {
+		masterKey = make([]byte, KeySize)
+		if _, err := rand.Read(masterKey); err != nil {
+			return nil, fmt.Errorf("產生金鑰庫主金鑰失敗: %w", err)
+		}
+		header, err := wrapMasterKey(masterKey, password, DefaultKDFParams())
+		if err != nil {
+			zeroBytes(masterKey)
+			return nil, err
+		}
+		header.CreatedAt = time.Now()
+		keystore = &identityKeystore{Header: header}
+	} else {
+		masterKey, err = unwrapMasterKey(keystore.Header, password)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer zeroBytes(masterKey)
+
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("產生身分金鑰失敗: %w", err)
+	}
+
+	identity := Identity{
+		ID:        uuid.New().String(),
+		Name:      name,
+		PublicKey: FormatRecipientKey(privateKey.PublicKey()),
+		CreatedAt: time.Now(),
+	}
+
+	privateBytes := privateKey.Bytes()
+	defer zeroBytes(privateBytes)
+	wrapped, nonce, err := sealWithAlgorithm(AlgorithmAES256, masterKey, privateBytes, []byte(identityPrivateKeyAAD+identity.ID))
+	if err != nil {
+		return nil, fmt.Errorf("包裝身分私鑰失敗: %w", err)
+	}
+
+	keystore.Identities = append(keystore.Identities, storedIdentity{
+		Identity:          identity,
+		WrappedPrivateKey: base64.StdEncoding.EncodeToString(append(nonce, wrapped...)),
+	})
+	if err := s.store(keystore); err != nil {
+		return nil, err
+	}
+
+	s.session.StoreKey(identitySessionKey, masterKey)
+	return &identity, nil
+}
+
+// DeleteIdentity 刪除指定身分
+// 參數：id（身分識別碼）
+// 回傳：可能的錯誤
+func (s *identityService) DeleteIdentity(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keystore, err := s.load()
+	if err != nil {
+		return err
+	}
+	if keystore == nil {
+		return ErrIdentityNotFound
+	}
+
+	for i, stored := range keystore.Identities {
+		if stored.ID == id {
+			keystore.Identities = append(keystore.Identities[:i], keystore.Identities[i+1:]...)
+			return s.store(keystore)
+		}
+	}
+	return ErrIdentityNotFound
+}
+
+// Unlock 以密碼解鎖金鑰庫
+// 參數：password（金鑰庫密碼）
+// 回傳：可能的錯誤（密碼錯誤時回傳 ErrVaultWrongPassword）
+func (s *identityService) Unlock(password string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keystore, err := s.load()
+	if err != nil {
+		return err
+	}
+	if keystore == nil {
+		return ErrIdentityNoIdentity
+	}
+
+	var masterKey []byte
+	err = guardPasswordAttempt(s.retryGuard, IdentityRetryIdentifier, func() error {
+		masterKey, err = unwrapMasterKey(keystore.Header, password)
+		return err
+	}, func(err error) bool {
+		return errors.Is(err, ErrVaultWrongPassword)
+	})
+	recordAudit(s.audit, AuditEventUnlock, IdentityRetryIdentifier, err, "")
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(masterKey)
+
+	s.session.StoreKey(identitySessionKey, masterKey)
+	return nil
+}
+
+// IsUnlocked 檢查金鑰庫是否已解鎖
+// 回傳：是否已解鎖
+func (s *identityService) IsUnlocked() bool {
+	return s.session.HasKey(identitySessionKey)
+}
+
+// IsRecipientData 檢查資料是否為收件人公鑰加密格式
+// 參數：data（檔案內容）
+// 回傳：是否為收件人格式
+func (s *identityService) IsRecipientData(data []byte) bool {
+	return IsRecipientData(data)
+}
+
+// EncryptForRecipients 以收件人公鑰加密筆記內容
+// 參數：content（明文內容）、algorithm（加密演算法）、recipients（收件人公鑰字串）
+// 回傳：加密後的資料和可能的錯誤
+func (s *identityService) EncryptForRecipients(content, algorithm string, recipients []string) ([]byte, error) {
+	if algorithm != AlgorithmAES256 && algorithm != AlgorithmChaCha20 {
+		return nil, fmt.Errorf("不支援的加密演算法: %s", algorithm)
+	}
+
+	seen := make(map[string]bool)
+	publicKeys := make([]*ecdh.PublicKey, 0, len(recipients))
+	for _, recipient := range recipients {
+		publicKey, err := ParseRecipientKey(recipient)
+		if err != nil {
+			return nil, err
+		}
+		if seen[string(publicKey.Bytes())] {
+			continue
+		}
+		seen[string(publicKey.Bytes())] = true
+		publicKeys = append(publicKeys, publicKey)
+	}
+
+	return sealForRecipients(content, algorithm, publicKeys)
+}
+
+// DecryptNote 以自己的身分私鑰開啟收件人格式的筆記
+// 參數：data（加密資料）、noteID（筆記 ID）
+// 回傳：明文內容、收件人資訊和可能的錯誤
+//
+// 執行流程：
+// 1. 確認金鑰庫已解鎖並解開所有身分私鑰
+// 2. 以私鑰逐一嘗試解開檔案金鑰並解密內容
+// 3. 將檔案金鑰暫存在工作階段中，鎖定時一併清零
+func (s *identityService) DecryptNote(data []byte, noteID string) (string, *RecipientNoteInfo, error) {
+	privateKeys, err := s.privateKeys()
+	if err != nil {
+		return "", nil, err
+	}
+
+	content, info, fileKey, err := openForRecipient(data, privateKeys)
+	if err != nil {
+		return "", nil, err
+	}
+	defer zeroBytes(fileKey)
+
+	s.session.StoreKey(recipientFileSessionKey+noteID, fileKey)
+	return content, info, nil
+}
+
+// ResealNote 以開啟時的檔案金鑰和收件人列表重新加密筆記
+// 參數：content（明文內容）、info（收件人資訊）、noteID（筆記 ID）
+// 回傳：加密後的資料和可能的錯誤
+func (s *identityService) ResealNote(content string, info *RecipientNoteInfo, noteID string) ([]byte, error) {
+	fileKey, ok := s.session.GetKey(recipientFileSessionKey + noteID)
+	if !ok {
+		return nil, ErrIdentityLocked
+	}
+	defer zeroBytes(fileKey)
+
+	return resealForRecipients(content, info.Algorithm, info.Recipients, fileKey)
+}
+
+// ForgetNote 清除筆記暫存的檔案金鑰
+// 參數：noteID（筆記 ID）
+func (s *identityService) ForgetNote(noteID string) {
+	s.session.DeleteKey(recipientFileSessionKey + noteID)
+}
+
+// Session 取得保存金鑰庫主金鑰的工作階段管理器
+// 回傳：SessionManager 介面實例
+func (s *identityService) Session() SessionManager {
+	return s.session
+}
+
+// SetRetryGuard 設定限制密碼重試次數的密碼服務
+// 參數：passwordSvc（密碼服務，nil 表示不限制）
+func (s *identityService) SetRetryGuard(passwordSvc PasswordService) {
+	s.retryGuard = passwordSvc
+}
+
+// SetAuditService 設定記錄解鎖事件的稽核記錄服務
+// 參數：audit（稽核記錄服務，nil 表示不記錄）
+func (s *identityService) SetAuditService(audit AuditService) {
+	s.audit = audit
+}
+
+// privateKeys 以金鑰庫主金鑰解開所有身分私鑰
+// 回傳：身分 ID 對應的私鑰和可能的錯誤（鎖定時回傳 ErrIdentityLocked）
+func (s *identityService) privateKeys() (map[string]*ecdh.PrivateKey, error) {
+	masterKey, ok := s.session.GetKey(identitySessionKey)
+	if !ok {
+		return nil, ErrIdentityLocked
+	}
+	defer zeroBytes(masterKey)
+
+	s.mutex.Lock()
+	keystore, err := s.load()
+	s.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if keystore == nil || len(keystore.Identities) == 0 {
+		return nil, ErrIdentityNoIdentity
+	}
+
+	privateKeys := make(map[string]*ecdh.PrivateKey, len(keystore.Identities))
+	for _, stored := range keystore.Identities {
+		sealed, err := base64.StdEncoding.DecodeString(stored.WrappedPrivateKey)
+		if err != nil || len(sealed) <= NonceSize {
+			return nil, fmt.Errorf("身分 %s 的私鑰格式無效", stored.Name)
+		}
+		privateBytes, err := openWithAlgorithm(AlgorithmAES256, masterKey, sealed[:NonceSize], sealed[NonceSize:], []byte(identityPrivateKeyAAD+stored.ID))
+		if err != nil {
+			return nil, fmt.Errorf("解開身分 %s 的私鑰失敗: %w", stored.Name, err)
+		}
+		privateKey, err := ecdh.X25519().NewPrivateKey(privateBytes)
+		zeroBytes(privateBytes)
+		if err != nil {
+			return nil, fmt.Errorf("身分 %s 的私鑰格式無效: %w", stored.Name, err)
+		}
+		privateKeys[stored.ID] = privateKey
+	}
+	return privateKeys, nil
+}
+
+// load 從金鑰儲存庫讀取身分金鑰庫（呼叫者必須持有鎖）
+// 回傳：金鑰庫（尚未建立時為 nil）和可能的錯誤
+func (s *identityService) load() (*identityKeystore, error) {
+	data, err := s.repo.GetIdentityKeystore()
+	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok && appErr.Code == models.ErrFileNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var keystore identityKeystore
+	if err := json.Unmarshal(data, &keystore); err != nil {
+		return nil, fmt.Errorf("解析身分金鑰庫失敗: %w", err)
+	}
+	if keystore.Header == nil {
+		return nil, errors.New("身分金鑰庫缺少標頭")
+	}
+	return &keystore, nil
+}
+
+// store 將身分金鑰庫寫入金鑰儲存庫（呼叫者必須持有鎖）
+// 參數：keystore（身分金鑰庫）
+// 回傳：可能的錯誤
+func (s *identityService) store(keystore *identityKeystore) error {
+	keystore.Header.UpdatedAt = time.Now()
+	data, err := json.Marshal(keystore)
+	if err != nil {
+		return fmt.Errorf("序列化身分金鑰庫失敗: %w", err)
+	}
+	return s.repo.StoreIdentityKeystore(data)
+}
@@ -0,0 +1,219 @@
+// Package services 提供公鑰身分服務和收件人加密的單元測試
+// 測試身分金鑰庫的建立和解鎖、多收件人加解密、竄改偵測，以及編輯器和分享流程
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mac-notebook-app/internal/repositories"
+)
+
+// createTestIdentityService 建立使用暫存目錄的公鑰身分服務，並建立一個身分
+func createTestIdentityService(t *testing.T, name string) (IdentityService, *Identity) {
+	repo, err := repositories.NewLocalEncryptionRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("建立金鑰儲存庫失敗: %v", err)
+	}
+
+	service := NewIdentityService(repo, nil)
+	identity, err := service.CreateIdentity(name, "TestPassword123!")
+	if err != nil {
+		t.Fatalf("建立身分失敗: %v", err)
+	}
+	return service, identity
+}
+
+// TestIdentityServiceCreateAndUnlock 測試身分的建立、鎖定、解鎖和刪除
+func TestIdentityServiceCreateAndUnlock(t *testing.T) {
+	service, identity := createTestIdentityService(t, "工作")
+
+	if !service.IsUnlocked() {
+		t.Error("建立身分後應該為解鎖狀態")
+	}
+	if !strings.HasPrefix(identity.PublicKey, RecipientKeyPrefix) {
+		t.Errorf("公鑰格式不正確: %s", identity.PublicKey)
+	}
+	if _, err := ParseRecipientKey(identity.PublicKey); err != nil {
+		t.Errorf("公鑰應該可以解析: %v", err)
+	}
+
+	// 第二個身分需要正確的金鑰庫密碼
+	if _, err := service.CreateIdentity("個人", "WrongPassword!"); err == nil {
+		t.Error("密碼錯誤時不應能建立身分")
+	}
+	if _, err := service.CreateIdentity("個人", "TestPassword123!"); err != nil {
+		t.Fatalf("建立第二個身分失敗: %v", err)
+	}
+	if identities := service.ListIdentities(); len(identities) != 2 {
+		t.Fatalf("應該有 2 個身分，實際為 %d", len(identities))
+	}
+
+	service.Session().Lock()
+	if service.IsUnlocked() {
+		t.Fatal("鎖定後應該為鎖定狀態")
+	}
+	if err := service.Unlock("WrongPassword!"); !errors.Is(err, ErrVaultWrongPassword) {
+		t.Errorf("密碼錯誤時應該回傳 ErrVaultWrongPassword: %v", err)
+	}
+	if err := service.Unlock("TestPassword123!"); err != nil || !service.IsUnlocked() {
+		t.Fatalf("解鎖失敗: %v", err)
+	}
+
+	if err := service.DeleteIdentity(identity.ID); err != nil {
+		t.Fatalf("刪除身分失敗: %v", err)
+	}
+	if err := service.DeleteIdentity(identity.ID); !errors.Is(err, ErrIdentityNotFound) {
+		t.Errorf("重複刪除應該回傳 ErrIdentityNotFound: %v", err)
+	}
+}
+
+// TestRecipientEncryptionRoundTrip 測試加密給多位收件人，以及非收件人和竄改的資料無法開啟
+func TestRecipientEncryptionRoundTrip(t *testing.T) {
+	alice, aliceIdentity := createTestIdentityService(t, "Alice")
+	bob, bobIdentity := createTestIdentityService(t, "Bob")
+	carol, _ := createTestIdentityService(t, "Carol")
+
+	data, err := alice.EncryptForRecipients("團隊機密", AlgorithmChaCha20, []string{aliceIdentity.PublicKey, bobIdentity.PublicKey})
+	if err != nil {
+		t.Fatalf("加密失敗: %v", err)
+	}
+	if !IsRecipientData(data) || strings.Contains(string(data), "團隊機密") {
+		t.Fatal("應該為收件人格式且不包含明文")
+	}
+
+	for name, service := range map[string]IdentityService{"Alice": alice, "Bob": bob} {
+		content, info, err := service.DecryptNote(data, "note-"+name)
+		if err != nil || content != "團隊機密" {
+			t.Fatalf("%s 解密失敗: %v", name, err)
+		}
+		if info.Algorithm != AlgorithmChaCha20 || len(info.Recipients) != 2 {
+			t.Errorf("%s 取得的收件人資訊不符: %+v", name, info)
+		}
+	}
+
+	if _, _, err := carol.DecryptNote(data, "note-carol"); !errors.Is(err, ErrNoMatchingIdentity) {
+		t.Errorf("非收件人應該回傳 ErrNoMatchingIdentity: %v", err)
+	}
+
+	// 移除其中一位收件人會改變附加驗證資料，內容無法解密
+	var encData EncryptedData
+	json.Unmarshal(data, &encData)
+	encData.Recipients = encData.Recipients[1:]
+	tampered, _ := json.Marshal(encData)
+	if _, _, err := bob.DecryptNote(tampered, "note-tampered"); err == nil {
+		t.Error("收件人列表被竄改時不應能解密")
+	}
+
+	// 鎖定時無法開啟
+	bob.Session().Lock()
+	if _, _, err := bob.DecryptNote(data, "note-locked"); !errors.Is(err, ErrIdentityLocked) {
+		t.Errorf("鎖定時應該回傳 ErrIdentityLocked: %v", err)
+	}
+
+	// 以開啟時的檔案金鑰重新加密，不需要收件人公鑰
+	_, info, _ := alice.DecryptNote(data, "note-reseal")
+	resealed, err := alice.ResealNote("修改後的內容", info, "note-reseal")
+	if err != nil {
+		t.Fatalf("重新加密失敗: %v", err)
+	}
+	bob.Unlock("TestPassword123!")
+	if content, _, err := bob.DecryptNote(resealed, "note-bob"); err != nil || content != "修改後的內容" {
+		t.Errorf("收件人應該能開啟重新加密的內容: %q, %v", content, err)
+	}
+
+	alice.ForgetNote("note-reseal")
+	if _, err := alice.ResealNote("內容", info, "note-reseal"); err == nil {
+		t.Error("清除檔案金鑰後不應能重新加密")
+	}
+}
+
+// TestEditorServiceRecipientNotes 測試編輯器以身分私鑰開啟和保存收件人格式的筆記
+func TestEditorServiceRecipientNotes(t *testing.T) {
+	alice, aliceIdentity := createTestIdentityService(t, "Alice")
+	bob, bobIdentity := createTestIdentityService(t, "Bob")
+
+	service, mockRepo := createTestEditorService()
+	service.SetOptions(EditorOptions{Identity: bob})
+
+	data, _ := alice.EncryptForRecipients("分享內容", AlgorithmAES256, []string{aliceIdentity.PublicKey, bobIdentity.PublicKey})
+	mockRepo.WriteFile("shared/計畫.md.enc", data)
+
+	bob.Session().Lock()
+	if _, err := service.OpenNote("shared/計畫.md.enc"); err == nil || !strings.Contains(err.Error(), "需要密碼驗證") {
+		t.Fatalf("鎖定時應該要求密碼驗證: %v", err)
+	}
+
+	note, err := service.OpenNoteWithPassword("shared/計畫.md.enc", "TestPassword123!")
+	if err != nil {
+		t.Fatalf("開啟收件人筆記失敗: %v", err)
+	}
+	if note.Title != "計畫" || note.Content != "分享內容" || !note.IsEncrypted || note.EncryptionType != AlgorithmAES256 {
+		t.Errorf("開啟的筆記不符: %+v", note)
+	}
+
+	// 保存時加密給同一組收件人，並維持原檔名
+	options := service.Options()
+	options.ObfuscateFilenames = true
+	service.SetOptions(options)
+	note.Content = "Bob 的修改"
+	if err := service.SaveNote(note); err != nil {
+		t.Fatalf("保存收件人筆記失敗: %v", err)
+	}
+	if note.FilePath != "shared/計畫.md.enc" {
+		t.Errorf("收件人筆記不應改名: %s", note.FilePath)
+	}
+	if content, _, err := alice.DecryptNote(mockRepo.files[note.FilePath], "alice-note"); err != nil || content != "Bob 的修改" {
+		t.Errorf("原作者應該能開啟修改後的內容: %q, %v", content, err)
+	}
+
+	// 鎖定時關閉已解密的筆記
+	bob.Session().Lock()
+	if _, exists := service.(*editorService).activeNotes[note.ID]; exists {
+		t.Error("鎖定後應該關閉已解密的收件人筆記")
+	}
+}
+
+// TestExportServiceShareRecipients 測試以收件人公鑰加密分享筆記
+func TestExportServiceShareRecipients(t *testing.T) {
+	alice, _ := createTestIdentityService(t, "Alice")
+	bob, bobIdentity := createTestIdentityService(t, "Bob")
+
+	editor, _ := createTestEditorService()
+	editor.SetOptions(EditorOptions{Identity: alice})
+	exportService := NewExportService(editor)
+
+	note, _ := editor.CreateNote("週報", "本週進度")
+	outputPath := filepath.Join(t.TempDir(), "週報.md.enc")
+
+	result, err := exportService.ShareNote(note, &ShareOptions{
+		ShareType:   ShareTypeRecipients,
+		Recipients:  []string{bobIdentity.PublicKey},
+		OutputPath:  outputPath,
+		IncludeSelf: true,
+	})
+	if err != nil || !result.Success || result.FilePath != outputPath {
+		t.Fatalf("分享失敗: %+v, %v", result, err)
+	}
+
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("讀取加密檔案失敗: %v", err)
+	}
+	if info, _ := os.Stat(outputPath); info.Mode().Perm() != 0600 {
+		t.Errorf("加密檔案權限應該為 0600: %v", info.Mode().Perm())
+	}
+	for name, service := range map[string]IdentityService{"Alice": alice, "Bob": bob} {
+		if content, _, err := service.DecryptNote(data, "shared-"+name); err != nil || content != "本週進度" {
+			t.Errorf("%s 應該能開啟分享的檔案: %v", name, err)
+		}
+	}
+
+	if _, err := exportService.ShareNote(note, &ShareOptions{ShareType: ShareTypeRecipients, Recipients: []string{"invalid"}, OutputPath: outputPath}); err == nil {
+		t.Error("無效的公鑰應該回傳錯誤")
+	}
+}
@@ -0,0 +1,218 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含單一實例的保護：同一個筆記本目錄只允許一個程式開啟，避免兩個程式互相覆寫保存的內容，
+// 再次啟動時把命令列參數（檔案路徑或 note:// 連結）轉交給已執行的程式
+package services
+
+import (
+	"bufio"         // 逐行讀取
+	"crypto/sha256" // 筆記本目錄雜湊
+	"encoding/hex"  // 十六進位編碼
+	"encoding/json" // 轉交訊息編碼
+	"errors"        // 錯誤處理
+	"fmt"           // 格式化輸出
+	"log"           // 日誌記錄
+	"net"           // 本機 socket
+	"os"            // 檔案操作
+	"path/filepath" // 檔案路徑處理
+	"strconv"       // 程序 ID 轉換
+	"sync"          // 同步控制
+	"time"          // 時間處理
+)
+
+// InstanceLockFile 單一實例鎖定檔名稱，位於筆記本中繼資料目錄
+const InstanceLockFile = "instance.lock"
+
+// instanceDialTimeout 連線到已執行程式的等待時間
+const instanceDialTimeout = 2 * time.Second
+
+// ErrInstanceRunning 筆記本已由其他程式開啟
+var ErrInstanceRunning = errors.New("筆記本已在其他程式中開啟")
+
+// errFileLocked 檔案已被其他程序鎖定
+var errFileLocked = errors.New("檔案已被其他程序鎖定")
+
+// instanceMessage 轉交給已執行程式的訊息
+type instanceMessage struct {
+	Args []string `json:"args"` // 命令列參數（檔案路徑已轉為絕對路徑）
+}
+
+// InstanceLock 定義單一實例鎖定的介面，持有鎖定的程式接收之後啟動的程式轉交的參數
+type InstanceLock interface {
+	// Listen 開始接收轉交的命令列參數，每次轉交在背景 goroutine 中呼叫 handler
+	// 參數：handler（處理函數，沒有參數的轉交代表只需要顯示視窗）
+	// 回傳：可能的錯誤
+	Listen(handler func(args []string)) error
+
+	// Release 停止接收並釋放鎖定
+	// 回傳：可能的錯誤
+	Release() error
+}
+
+// instanceLock 實作 InstanceLock 介面
+type instanceLock struct {
+	file       *os.File     // 已鎖定的鎖定檔
+	socketPath string       // 接收轉交參數的 unix socket 路徑
+	listener   net.Listener // 接收轉交參數的 socket
+	mutex      sync.Mutex   // 保護鎖定狀態
+}
+
+// AcquireInstanceLock 取得筆記本目錄的單一實例鎖定
+// 參數：baseDir（筆記本目錄）
+// 回傳：鎖定實例和可能的錯誤，其他程式已開啟時為 ErrInstanceRunning
+//
+// 執行流程：
+// 1. 在筆記本中繼資料目錄建立鎖定檔並嘗試鎖定（程式異常結束時由系統自動釋放）
+// 2. 鎖定失敗代表已有程式開啟同一個筆記本
+// 3. 鎖定成功時寫入程序 ID 供診斷使用
+func AcquireInstanceLock(baseDir string) (InstanceLock, error) {
+	metaDir := filepath.Join(baseDir, NotebookMetaDir)
+	if err := os.MkdirAll(metaDir, 0700); err != nil {
+		return nil, fmt.Errorf("建立中繼資料目錄失敗: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(metaDir, InstanceLockFile), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("開啟鎖定檔失敗: %w", err)
+	}
+	if err := tryLockFile(file); err != nil {
+		file.Close()
+		if errors.Is(err, errFileLocked) {
+			return nil, ErrInstanceRunning
+		}
+		return nil, fmt.Errorf("鎖定筆記本失敗: %w", err)
+	}
+
+	file.Truncate(0)
+	file.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	return &instanceLock{file: file, socketPath: instanceSocketPath(baseDir)}, nil
+}
+
+// Listen 開始接收轉交的命令列參數
+// 參數：handler（處理函數）
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 移除上次異常結束時留下的 socket 檔（持有鎖定代表沒有其他程式使用）
+// 2. 建立 unix socket，只允許目前的使用者連線
+// 3. 在背景接收連線，每個連線傳送一則訊息
+func (l *instanceLock) Listen(handler func(args []string)) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file == nil {
+		return fmt.Errorf("單一實例鎖定已釋放")
+	}
+	if l.listener != nil {
+		return nil
+	}
+	os.Remove(l.socketPath)
+	listener, err := net.Listen("unix", l.socketPath)
+	if err != nil {
+		return fmt.Errorf("建立轉交參數的 socket 失敗: %w", err)
+	}
+	os.Chmod(l.socketPath, 0600)
+	l.listener = listener
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				// 釋放鎖定時關閉 socket 會結束等待
+				return
+			}
+			go l.handleConn(conn, handler)
+		}
+	}()
+	return nil
+}
+
+// handleConn 讀取一則轉交的訊息
+// 參數：conn（連線）、handler（處理函數）
+func (l *instanceLock) handleConn(conn net.Conn, handler func(args []string)) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(instanceDialTimeout))
+
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		log.Printf("讀取轉交的參數失敗: %v", err)
+		return
+	}
+	var message instanceMessage
+	if err := json.Unmarshal(line, &message); err != nil {
+		log.Printf("解析轉交的參數失敗: %v", err)
+		return
+	}
+	handler(message.Args)
+	conn.Write([]byte("ok\n"))
+}
+
+// Release 停止接收並釋放鎖定
+// 回傳：可能的錯誤
+func (l *instanceLock) Release() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	if l.listener != nil {
+		l.listener.Close()
+		l.listener = nil
+		os.Remove(l.socketPath)
+	}
+	// 鎖定檔保留在原處，刪除後其他程式可能鎖定到不同的檔案
+	unlockFile(l.file)
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// ForwardToRunningInstance 將命令列參數轉交給已開啟同一個筆記本的程式
+// 參數：baseDir（筆記本目錄）、args（命令列參數）
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 檔案路徑以目前的工作目錄轉為絕對路徑，note:// 連結保持不變
+// 2. 連線到已執行程式的 socket 並傳送參數
+// 3. 等待對方確認收到
+func ForwardToRunningInstance(baseDir string, args []string) error {
+	forwarded := make([]string, 0, len(args))
+	for _, arg := range args {
+		if !IsNoteLink(arg) {
+			if abs, err := filepath.Abs(arg); err == nil {
+				arg = abs
+			}
+		}
+		forwarded = append(forwarded, arg)
+	}
+	data, err := json.Marshal(instanceMessage{Args: forwarded})
+	if err != nil {
+		return fmt.Errorf("編碼轉交的參數失敗: %w", err)
+	}
+
+	conn, err := net.DialTimeout("unix", instanceSocketPath(baseDir), instanceDialTimeout)
+	if err != nil {
+		return fmt.Errorf("連線到已開啟的程式失敗: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(instanceDialTimeout))
+
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("轉交參數失敗: %w", err)
+	}
+	if _, err := bufio.NewReader(conn).ReadString('\n'); err != nil {
+		return fmt.Errorf("已開啟的程式沒有回應: %w", err)
+	}
+	return nil
+}
+
+// instanceSocketPath 取得筆記本目錄對應的 unix socket 路徑
+// 參數：baseDir（筆記本目錄）
+// 回傳：socket 路徑（unix socket 路徑長度有限制，放在暫存目錄並以筆記本目錄的雜湊命名）
+func instanceSocketPath(baseDir string) string {
+	if abs, err := filepath.Abs(baseDir); err == nil {
+		baseDir = abs
+	}
+	sum := sha256.Sum256([]byte(baseDir))
+	return filepath.Join(os.TempDir(), "mac-notebook-"+hex.EncodeToString(sum[:8])+".sock")
+}
@@ -0,0 +1,150 @@
+// Package services 提供單一實例鎖定、筆記建議鎖和 note:// 連結的單元測試
+// 測試第二個程式無法開啟同一個筆記本、參數轉交，以及啟動參數和標題的解析
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// skipWithoutFileLock 在不支援跨程序檔案鎖的平台上略過測試
+func skipWithoutFileLock(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" || runtime.GOOS == "js" {
+		t.Skip("此平台不支援跨程序檔案鎖")
+	}
+}
+
+// TestInstanceLockForwardsArguments 測試同一個筆記本只能鎖定一次，之後的程式可以轉交參數
+func TestInstanceLockForwardsArguments(t *testing.T) {
+	skipWithoutFileLock(t)
+	baseDir := t.TempDir()
+
+	lock, err := AcquireInstanceLock(baseDir)
+	if err != nil {
+		t.Fatalf("取得單一實例鎖定失敗: %v", err)
+	}
+	defer lock.Release()
+	if _, err := AcquireInstanceLock(baseDir); !errors.Is(err, ErrInstanceRunning) {
+		t.Fatalf("筆記本已開啟時應該回傳 ErrInstanceRunning: %v", err)
+	}
+
+	received := make(chan []string, 1)
+	if err := lock.Listen(func(args []string) { received <- args }); err != nil {
+		t.Fatalf("開始接收參數失敗: %v", err)
+	}
+	if err := ForwardToRunningInstance(baseDir, []string{"note://plan.md#時程", "draft.md"}); err != nil {
+		t.Fatalf("轉交參數失敗: %v", err)
+	}
+	select {
+	case args := <-received:
+		cwd, _ := os.Getwd()
+		expected := []string{"note://plan.md#時程", filepath.Join(cwd, "draft.md")}
+		if !reflect.DeepEqual(args, expected) {
+			t.Errorf("轉交的參數不正確: %v", args)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("等待轉交的參數逾時")
+	}
+
+	// 釋放後可以再次取得鎖定
+	lock.Release()
+	again, err := AcquireInstanceLock(baseDir)
+	if err != nil {
+		t.Fatalf("釋放後應該可以再次鎖定: %v", err)
+	}
+	again.Release()
+}
+
+// TestNoteLockService 測試筆記已被其他程式鎖定時回傳持有者
+func TestNoteLockService(t *testing.T) {
+	skipWithoutFileLock(t)
+	baseDir := t.TempDir()
+	mine := NewNoteLockService(baseDir)
+	other := NewNoteLockService(baseDir)
+
+	if owner, err := mine.Acquire("notes/plan.md"); err != nil || owner != nil {
+		t.Fatalf("第一次鎖定應該成功: %v, %v", owner, err)
+	}
+	owner, err := other.Acquire("notes/plan.md")
+	if err != nil || owner == nil || owner.PID != os.Getpid() {
+		t.Fatalf("已被鎖定時應該回傳持有者: %+v, %v", owner, err)
+	}
+	if owner, _ := other.Acquire("notes/other.md"); owner != nil {
+		t.Error("不同的筆記不應互相影響")
+	}
+
+	mine.Release("notes/plan.md")
+	if owner, err := other.Acquire("notes/plan.md"); err != nil || owner != nil {
+		t.Errorf("釋放後應該可以鎖定: %+v, %v", owner, err)
+	}
+	other.ReleaseAll()
+}
+
+// TestParseNoteLink 測試 note:// 連結的解析和組成
+func TestParseNoteLink(t *testing.T) {
+	link, err := ParseNoteLink("note://projects/%E8%A8%88%E7%95%AB.md#%E5%B0%88%E6%A1%88%E6%99%82%E7%A8%8B")
+	if err != nil || link.Path != filepath.Join("projects", "計畫.md") || link.Heading != "專案時程" {
+		t.Fatalf("連結解析不正確: %+v, %v", link, err)
+	}
+	if parsed, _ := ParseNoteLink(link.String()); !reflect.DeepEqual(parsed, link) {
+		t.Errorf("組成的連結應該可以解析回原本的內容: %s", link.String())
+	}
+	if link, _ := ParseNoteLink("NOTE:///plan.md"); link == nil || link.Path != "plan.md" || link.Heading != "" {
+		t.Errorf("連結解析不正確: %+v", link)
+	}
+	if _, err := ParseNoteLink("note://../secret.md"); err == nil {
+		t.Error("不應允許連結到筆記本目錄之外")
+	}
+}
+
+// TestResolveLaunchArguments 測試啟動參數轉換為筆記路徑
+func TestResolveLaunchArguments(t *testing.T) {
+	baseDir := t.TempDir()
+	os.MkdirAll(filepath.Join(baseDir, "projects"), 0755)
+	os.WriteFile(filepath.Join(baseDir, "projects", "plan.md"), []byte("# 計畫"), 0644)
+	outside := filepath.Join(t.TempDir(), "outside.md")
+	os.WriteFile(outside, []byte("外部"), 0644)
+
+	links, err := ResolveLaunchArguments(baseDir, []string{
+		"-psn_0_12345",
+		filepath.Join(baseDir, "projects", "plan.md"),
+		"note://projects/plan.md#計畫",
+		outside,
+		filepath.Join(baseDir, "missing.md"),
+	})
+	expected := []*NoteLink{
+		{Path: filepath.Join("projects", "plan.md")},
+		{Path: filepath.Join("projects", "plan.md"), Heading: "計畫"},
+	}
+	if !reflect.DeepEqual(links, expected) {
+		t.Errorf("可以開啟的筆記不正確: %+v", links)
+	}
+	if err == nil {
+		t.Error("筆記本目錄之外和不存在的檔案應該回報錯誤")
+	}
+}
+
+// TestFindHeadingLine 測試以標題文字或 slug 找出標題所在的行
+func TestFindHeadingLine(t *testing.T) {
+	content := "# 專案計畫\n\n```\n# 程式碼中的註解\n```\n\n## Project Schedule ##\n內容\n### 程式碼中的註解"
+	tests := []struct {
+		heading  string
+		expected int
+	}{
+		{"專案計畫", 0},
+		{"project schedule", 6},
+		{"project-schedule", 6},
+		{"程式碼中的註解", 8},
+		{"不存在的標題", -1},
+	}
+	for _, test := range tests {
+		if line := FindHeadingLine(content, test.heading); line != test.expected {
+			t.Errorf("標題 %q 應該在第 %d 行，實際為 %d", test.heading, test.expected, line)
+		}
+	}
+}
@@ -3,6 +3,7 @@
 package services
 
 import (
+	"io"                               // 輸入輸出介面
 	"mac-notebook-app/internal/models" // 引入資料模型
 	"time"                             // 時間處理套件
 )
@@ -20,11 +21,31 @@ type EditorService interface {
 	// 回傳：筆記實例和可能的錯誤
 	OpenNote(filePath string) (*models.Note, error)
 	
+	// OpenNoteWithPassword 以密碼開啟加密筆記
+	// 信封格式會以密碼解鎖保險庫，密碼格式則直接以密碼解密
+	// 參數：filePath（檔案路徑）、password（保險庫或筆記密碼）
+	// 回傳：筆記實例和可能的錯誤
+	OpenNoteWithPassword(filePath, password string) (*models.Note, error)
+	
 	// SaveNote 保存筆記到檔案系統
 	// 參數：note（要保存的筆記）
 	// 回傳：可能的錯誤
 	SaveNote(note *models.Note) error
 	
+	// ResolveSaveConflict 依使用者的選擇處理保存時偵測到的外部變更
+	// 參數：note（要保存的筆記）、resolution（保留我的版本、採用磁碟上的版本或三方合併）
+	// 回傳：合併結果是否仍有衝突標記需要手動處理，以及可能的錯誤
+	ResolveSaveConflict(note *models.Note, resolution ConflictResolution) (bool, error)
+	
+	// ReloadNote 檔案在外部變更後重新載入筆記內容，有未保存的編輯時不重新載入（保存時會偵測衝突）
+	// 參數：noteID（筆記 ID）
+	// 回傳：內容是否已更新和可能的錯誤
+	ReloadNote(noteID string) (bool, error)
+	
+	// InvalidateFileCache 清除依檔案內容建立的快取，檔案在外部變更或刪除後呼叫
+	// 參數：filePath（檔案路徑）
+	InvalidateFileCache(filePath string)
+	
 	// UpdateContent 更新指定筆記的內容
 	// 參數：noteID（筆記 ID）、content（新內容）
 	// 回傳：可能的錯誤
@@ -110,6 +131,71 @@ type EditorService interface {
 	// SetSmartEditingService 設定智慧編輯服務實例
 	// 參數：smartEditSvc（智慧編輯服務實例）
 	SetSmartEditingService(smartEditSvc SmartEditingService)
+	
+	// Options 取得編輯器服務的選用依賴和設定
+	// 回傳：選用依賴和設定（未設定的服務為 nil）
+	Options() EditorOptions
+	
+	// SetOptions 設定編輯器服務的選用依賴和設定，取代目前的所有選項
+	// 參數：options（選用依賴和設定）
+	SetOptions(options EditorOptions)
+}
+
+// EditorOptions 編輯器服務的選用依賴和設定
+// 未設定的服務為 nil 時停用對應的功能；新增選用服務時只需增加欄位，不需要修改 EditorService 介面
+type EditorOptions struct {
+	Vault              VaultService    // 保險庫服務，加密筆記使用保險庫的資料金鑰，鎖定時關閉已解密的筆記
+	Identity           IdentityService // 公鑰身分服務，用於開啟以收件人公鑰加密的筆記
+	Signing            SigningService  // 筆記簽章服務，開啟筆記時驗證簽章
+	Audit              AuditService    // 安全稽核記錄服務，記錄解鎖和啟用、停用加密等事件
+	RecoveryJournal    RecoveryJournal // 未保存編輯的復原日誌，筆記保存或關閉後移除對應的復原記錄
+	NoteIDIndex        NoteIDIndex     // 筆記 ID 索引，同一個檔案每次開啟都使用相同的 ID
+	Tags               TagService      // 標籤服務，筆記保存後更新標籤索引
+	SearchIndex        SearchIndex     // 全文搜尋索引，筆記保存後更新索引
+	LeakageGuard       LeakageGuard    // 明文外洩防護（為 nil 時沿用編輯器服務建立的實例）
+	ObfuscateFilenames bool            // 保險庫加密筆記是否使用隨機檔名
+}
+
+// NoteSigner 定義筆記簽章操作的介面，由設定簽章服務的編輯器服務實作
+type NoteSigner interface {
+	// SignNote 以本機簽章金鑰簽署已保存的筆記
+	// 參數：noteID（筆記 ID）
+	// 回傳：簽署後的簽章狀態和可能的錯誤
+	SignNote(noteID string) (*SignatureStatus, error)
+	
+	// GetSignatureStatus 取得開啟的筆記的簽章狀態
+	// 參數：noteID（筆記 ID）
+	// 回傳：簽章狀態（未設定簽章服務或筆記未開啟時為 nil）
+	GetSignatureStatus(noteID string) *SignatureStatus
+}
+
+// EncryptedTitleProvider 定義取得加密筆記標題的介面
+type EncryptedTitleProvider interface {
+	// NoteDisplayTitle 取得加密筆記在檔案樹中顯示的標題
+	// 參數：filePath（檔案路徑）
+	// 回傳：筆記標題和是否能取得（保險庫鎖定或沒有中繼資料時為 false）
+	NoteDisplayTitle(filePath string) (string, bool)
+}
+
+// NoteReauthenticator 定義匯出或分享加密筆記前重新驗證密碼的介面
+type NoteReauthenticator interface {
+	// ReauthenticateNote 重新驗證加密筆記的密碼，成功後在短時間內允許匯出或分享
+	// 參數：note（加密筆記）、password（保險庫、身分金鑰庫或筆記密碼）
+	// 回傳：可能的錯誤
+	ReauthenticateNote(note *models.Note, password string) error
+}
+
+// SecretBlockEditor 定義解密和隱藏機密區塊的介面
+type SecretBlockEditor interface {
+	// RevealSecretBlocks 解密內容中以 ```secret 標記的機密區塊
+	// 參數：noteID（筆記 ID）、content（Markdown 內容）
+	// 回傳：機密區塊解密後的內容和可能的錯誤（保險庫鎖定時為 ErrVaultLocked）
+	RevealSecretBlocks(noteID, content string) (string, error)
+	
+	// ConcealSecretBlocks 將內容中已解密的機密區塊換回密文（保險庫鎖定時使用）
+	// 參數：noteID（筆記 ID）、content（Markdown 內容）
+	// 回傳：機密區塊換回密文後的內容
+	ConcealSecretBlocks(noteID, content string) string
 }
 
 // FileManagerService 定義檔案系統操作的介面
@@ -164,6 +250,16 @@ type EncryptionService interface {
 	// 回傳：解密後的內容字串和可能的錯誤
 	DecryptContent(encryptedData []byte, password string, algorithm string) (string, error)
 	
+	// NewEncryptWriter 建立以密碼加密的串流寫入器，用於大型筆記的分段加密
+	// 參數：dst（加密資料目的地）、password（密碼）、algorithm（加密演算法）
+	// 回傳：加密寫入器（必須呼叫 Close 寫入最後一段）和可能的錯誤
+	NewEncryptWriter(dst io.Writer, password, algorithm string) (io.WriteCloser, error)
+	
+	// NewDecryptReader 建立以密碼解密的串流讀取器，用於大型筆記的分段解密
+	// 參數：src（串流格式的加密資料來源）、password（密碼）
+	// 回傳：明文讀取器和可能的錯誤
+	NewDecryptReader(src io.Reader, password string) (io.Reader, error)
+	
 	// SetupBiometricAuth 為指定筆記設定生物識別驗證
 	// 參數：noteID（筆記 ID）
 	// 回傳：可能的錯誤
@@ -462,6 +558,11 @@ type ExportService interface {
 	// 參數：exportID（匯出任務 ID）
 	// 回傳：是否成功取消
 	CancelExport(exportID string) bool
+	
+	// Reauthenticate 匯出或分享加密筆記前重新驗證密碼
+	// 參數：note（加密筆記）、password（密碼）
+	// 回傳：可能的錯誤
+	Reauthenticate(note *models.Note, password string) error
 }
 
 // ExportFormat 定義匯出格式的列舉
@@ -507,6 +608,7 @@ type ExportOptions struct {
 	WatermarkText      string `json:"watermark_text"`      // 浮水印文字
 	HeaderText         string `json:"header_text"`         // 頁首文字
 	FooterText         string `json:"footer_text"`         // 頁尾文字
+	IncludeSecretBlocks bool  `json:"include_secret_blocks"` // 是否包含機密區塊的明文（預設以佔位文字取代）
 }
 
 // BatchExportResult 代表批量匯出的結果
@@ -526,7 +628,10 @@ type ShareOptions struct {
 	Password      string    `json:"password"`       // 分享密碼
 	AllowDownload bool      `json:"allow_download"` // 是否允許下載
 	AllowEdit     bool      `json:"allow_edit"`     // 是否允許編輯
-	Recipients    []string  `json:"recipients"`     // 收件人列表
+	Recipients    []string  `json:"recipients"`     // 收件人列表（收件人公鑰加密時為公鑰字串）
+	OutputPath    string    `json:"output_path"`    // 加密檔案輸出路徑（收件人公鑰加密時使用）
+	IncludeSelf   bool      `json:"include_self"`   // 是否同時加密給自己的身分
+	IncludeSecretBlocks bool `json:"include_secret_blocks"` // 是否包含機密區塊的明文（預設以佔位文字取代）
 }
 
 // ShareType 定義分享類型的列舉
@@ -541,8 +646,28 @@ const (
 	ShareTypeAirDrop
 	// ShareTypeClipboard 複製到剪貼簿
 	ShareTypeClipboard
+	// ShareTypeRecipients 以收件人公鑰加密為檔案
+	ShareTypeRecipients
 )
 
+// String 回傳分享類型的字串表示
+func (t ShareType) String() string {
+	switch t {
+	case ShareTypeLink:
+		return "連結"
+	case ShareTypeEmail:
+		return "電子郵件"
+	case ShareTypeAirDrop:
+		return "AirDrop"
+	case ShareTypeClipboard:
+		return "剪貼簿"
+	case ShareTypeRecipients:
+		return "收件人加密"
+	default:
+		return "未知"
+	}
+}
+
 // ShareResult 代表分享操作的結果
 type ShareResult struct {
 	ShareID   string    `json:"share_id"`   // 分享 ID
@@ -550,6 +675,9 @@ type ShareResult struct {
 	ExpiryTime time.Time `json:"expiry_time"` // 過期時間
 	Success   bool      `json:"success"`    // 是否成功
 	Message   string    `json:"message"`    // 結果訊息
+	FilePath  string    `json:"file_path"`  // 產生的加密檔案路徑（收件人公鑰加密時）
+	ClipboardContent    string        `json:"-"` // 要複製到剪貼簿的內容（複製到剪貼簿時）
+	ClipboardClearAfter time.Duration `json:"-"` // 剪貼簿自動清除的時間（0 表示不清除，加密筆記時設定）
 }
 
 // ExportProgress 代表匯出進度資訊
@@ -0,0 +1,251 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含明文外洩防護，避免加密筆記的明文留在快取、暫存檔、剪貼簿和記錄中。
+// 匯出或分享加密筆記前需要重新驗證密碼，分享用的暫存檔寫入私有目錄並在使用後覆寫刪除
+package services
+
+import (
+	"errors"        // 錯誤處理
+	"fmt"           // 格式化輸出
+	"os"            // 暫存檔操作
+	"path/filepath" // 檔案路徑處理
+	"sync"          // 同步原語
+	"time"          // 時間處理
+
+	"mac-notebook-app/internal/models" // 引入資料模型
+)
+
+// 明文外洩防護的預設值
+const (
+	ReauthGrantDuration       = time.Minute      // 重新驗證後允許匯出或分享的時間
+	SensitiveClipboardTimeout = 30 * time.Second // 加密筆記內容複製到剪貼簿後自動清除的時間
+	RedactedPlaceholder       = "[加密筆記內容已隱藏]"    // 記錄中取代加密筆記明文的文字
+	secureTempDirPattern      = "notebook-secure-*"
+)
+
+// ErrReauthRequired 匯出或分享加密筆記前尚未重新驗證密碼
+var ErrReauthRequired = errors.New("匯出或分享加密筆記前需要重新輸入密碼")
+
+// LeakageGuard 定義明文外洩防護的介面
+type LeakageGuard interface {
+	// GrantReauth 記錄筆記已重新驗證密碼，在 ReauthGrantDuration 內允許匯出或分享
+	// 參數：note（已驗證的筆記）
+	GrantReauth(note *models.Note)
+
+	// RequireReauth 檢查加密筆記是否在有效時間內重新驗證過密碼
+	// 參數：note（要匯出或分享的筆記）
+	// 回傳：未加密或已驗證時為 nil，否則為 ErrReauthRequired
+	RequireReauth(note *models.Note) error
+
+	// RevokeReauth 撤銷所有重新驗證的授權（工作階段鎖定時呼叫）
+	RevokeReauth()
+
+	// CreateSecureTempFile 在只有目前使用者能存取的私有目錄建立暫存檔
+	// 參數：name（檔案名稱）、data（檔案內容）
+	// 回傳：暫存檔路徑和可能的錯誤
+	CreateSecureTempFile(name string, data []byte) (string, error)
+
+	// WipeFile 以零覆寫暫存檔內容後刪除
+	// 參數：path（暫存檔路徑）
+	// 回傳：可能的錯誤
+	WipeFile(path string) error
+
+	// WipeTempFiles 覆寫刪除所有尚未清除的暫存檔和私有目錄（鎖定和結束應用程式時呼叫）
+	// 回傳：可能的錯誤
+	WipeTempFiles() error
+}
+
+// leakageGuard 實作 LeakageGuard 介面
+type leakageGuard struct {
+	grants    map[string]time.Time // 筆記識別對應的重新驗證到期時間
+	tempDir   string               // 私有暫存目錄（首次建立暫存檔時建立）
+	tempFiles map[string]bool      // 尚未清除的暫存檔
+	mutex     sync.Mutex           // 保護授權和暫存檔狀態
+}
+
+// NewLeakageGuard 建立新的明文外洩防護實例
+// 回傳：LeakageGuard 介面實例
+func NewLeakageGuard() LeakageGuard {
+	return &leakageGuard{
+		grants:    make(map[string]time.Time),
+		tempFiles: make(map[string]bool),
+	}
+}
+
+// IsSensitiveNote 檢查筆記內容是否來自加密筆記或加密資料夾中的筆記
+// 這些筆記的明文不應寫入持久快取、記錄或未保護的暫存檔
+// 參數：note（筆記）
+// 回傳：是否為敏感內容
+func IsSensitiveNote(note *models.Note) bool {
+	return note != nil && (note.IsEncrypted || note.InEncryptedFolder)
+}
+
+// redactSensitive 加密筆記的內容以佔位文字取代，用於記錄輸出
+// 參數：note（內容所屬的筆記）、text（要輸出的文字）
+// 回傳：可以安全輸出的文字
+func redactSensitive(note *models.Note, text string) string {
+	if IsSensitiveNote(note) {
+		return RedactedPlaceholder
+	}
+	return text
+}
+
+// reauthKey 取得筆記在授權表中的識別
+// 參數：note（筆記）
+// 回傳：筆記 ID，沒有 ID 時使用檔案路徑或標題
+func reauthKey(note *models.Note) string {
+	if note.ID != "" {
+		return note.ID
+	}
+	return auditNoteTarget(note)
+}
+
+// GrantReauth 記錄筆記已重新驗證密碼
+// 參數：note（已驗證的筆記）
+func (g *leakageGuard) GrantReauth(note *models.Note) {
+	if note == nil {
+		return
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.grants[reauthKey(note)] = time.Now().Add(ReauthGrantDuration)
+}
+
+// RequireReauth 檢查加密筆記是否在有效時間內重新驗證過密碼
+// 參數：note（要匯出或分享的筆記）
+// 回傳：未加密或已驗證時為 nil，否則為 ErrReauthRequired
+func (g *leakageGuard) RequireReauth(note *models.Note) error {
+	if !IsSensitiveNote(note) {
+		return nil
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	key := reauthKey(note)
+	expiry, ok := g.grants[key]
+	if !ok || time.Now().After(expiry) {
+		delete(g.grants, key)
+		return ErrReauthRequired
+	}
+	return nil
+}
+
+// RevokeReauth 撤銷所有重新驗證的授權
+func (g *leakageGuard) RevokeReauth() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.grants = make(map[string]time.Time)
+}
+
+// CreateSecureTempFile 在私有目錄建立暫存檔
+// 參數：name（檔案名稱）、data（檔案內容）
+// 回傳：暫存檔路徑和可能的錯誤
+//
+// 執行流程：
+// 1. 首次使用時在系統暫存目錄下建立權限 0700 的私有目錄
+// 2. 以權限 0600 建立暫存檔並寫入內容
+// 3. 記錄暫存檔，鎖定或結束時未清除的檔案會一併覆寫刪除
+func (g *leakageGuard) CreateSecureTempFile(name string, data []byte) (string, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.tempDir == "" {
+		dir, err := os.MkdirTemp("", secureTempDirPattern)
+		if err != nil {
+			return "", fmt.Errorf("建立私有暫存目錄失敗: %w", err)
+		}
+		if err := os.Chmod(dir, 0700); err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("設定私有暫存目錄權限失敗: %w", err)
+		}
+		g.tempDir = dir
+	}
+
+	path := filepath.Join(g.tempDir, filepath.Base(name))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", fmt.Errorf("建立暫存檔失敗: %w", err)
+	}
+	g.tempFiles[path] = true
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return "", fmt.Errorf("寫入暫存檔失敗: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("寫入暫存檔失敗: %w", err)
+	}
+	return path, nil
+}
+
+// WipeFile 以零覆寫暫存檔內容後刪除
+// 參數：path（暫存檔路徑）
+// 回傳：可能的錯誤
+func (g *leakageGuard) WipeFile(path string) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	delete(g.tempFiles, path)
+	return wipeFile(path)
+}
+
+// WipeTempFiles 覆寫刪除所有尚未清除的暫存檔和私有目錄
+// 回傳：第一個發生的錯誤
+func (g *leakageGuard) WipeTempFiles() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	var firstErr error
+	for path := range g.tempFiles {
+		if err := wipeFile(path); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	g.tempFiles = make(map[string]bool)
+
+	if g.tempDir != "" {
+		if err := os.RemoveAll(g.tempDir); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("刪除私有暫存目錄失敗: %w", err)
+		}
+		g.tempDir = ""
+	}
+	return firstErr
+}
+
+// wipeFile 以零覆寫檔案內容並同步到磁碟後刪除，檔案不存在時視為成功
+// 參數：path（檔案路徑）
+// 回傳：可能的錯誤
+func wipeFile(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("讀取暫存檔資訊失敗: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("開啟暫存檔失敗: %w", err)
+	}
+	zeros := make([]byte, 32*1024)
+	for remaining := info.Size(); remaining > 0; {
+		n := int64(len(zeros))
+		if remaining < n {
+			n = remaining
+		}
+		if _, err := file.Write(zeros[:n]); err != nil {
+			file.Close()
+			return fmt.Errorf("覆寫暫存檔失敗: %w", err)
+		}
+		remaining -= n
+	}
+	syncErr := file.Sync()
+	file.Close()
+	if syncErr != nil {
+		return fmt.Errorf("同步暫存檔失敗: %w", syncErr)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("刪除暫存檔失敗: %w", err)
+	}
+	return nil
+}
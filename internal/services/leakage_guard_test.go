@@ -0,0 +1,212 @@
+// Package services 提供明文外洩防護的單元測試
+// 測試匯出、分享前的重新驗證、私有暫存檔的覆寫刪除和剪貼簿自動清除設定
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mac-notebook-app/internal/models"
+)
+
+// TestLeakageGuardReauth 測試加密筆記需要重新驗證，授權會過期且可以撤銷
+func TestLeakageGuardReauth(t *testing.T) {
+	guard := NewLeakageGuard()
+	plain := &models.Note{ID: "plain", Title: "公開"}
+	secret := &models.Note{ID: "secret", Title: "機密", IsEncrypted: true}
+
+	if err := guard.RequireReauth(plain); err != nil {
+		t.Errorf("未加密的筆記不需要重新驗證: %v", err)
+	}
+	if err := guard.RequireReauth(secret); !errors.Is(err, ErrReauthRequired) {
+		t.Errorf("加密筆記應該要求重新驗證: %v", err)
+	}
+
+	guard.GrantReauth(secret)
+	if err := guard.RequireReauth(secret); err != nil {
+		t.Errorf("重新驗證後應該允許匯出: %v", err)
+	}
+
+	// 模擬授權過期
+	guard.(*leakageGuard).grants["secret"] = time.Now().Add(-time.Second)
+	if err := guard.RequireReauth(secret); !errors.Is(err, ErrReauthRequired) {
+		t.Errorf("授權過期後應該再次要求重新驗證: %v", err)
+	}
+
+	guard.GrantReauth(secret)
+	guard.RevokeReauth()
+	if err := guard.RequireReauth(secret); !errors.Is(err, ErrReauthRequired) {
+		t.Errorf("撤銷後應該再次要求重新驗證: %v", err)
+	}
+}
+
+// TestLeakageGuardSecureTempFile 測試暫存檔寫入私有目錄，並在清除時覆寫刪除
+func TestLeakageGuardSecureTempFile(t *testing.T) {
+	guard := NewLeakageGuard()
+	defer guard.WipeTempFiles()
+
+	first, err := guard.CreateSecureTempFile("機密.md", []byte("機密內容"))
+	if err != nil {
+		t.Fatalf("建立暫存檔失敗: %v", err)
+	}
+	second, err := guard.CreateSecureTempFile("../另一則.md", []byte("另一則機密"))
+	if err != nil {
+		t.Fatalf("建立暫存檔失敗: %v", err)
+	}
+
+	dir := filepath.Dir(first)
+	if filepath.Dir(second) != dir {
+		t.Errorf("暫存檔不應寫到私有目錄之外: %s", second)
+	}
+	if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("私有目錄權限應該為 0700: %v, %v", info.Mode().Perm(), err)
+	}
+	if info, err := os.Stat(first); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("暫存檔權限應該為 0600: %v, %v", info.Mode().Perm(), err)
+	}
+
+	if err := guard.WipeFile(first); err != nil {
+		t.Fatalf("清除暫存檔失敗: %v", err)
+	}
+	if _, err := os.Stat(first); !os.IsNotExist(err) {
+		t.Error("清除後暫存檔應該不存在")
+	}
+
+	if err := guard.WipeTempFiles(); err != nil {
+		t.Fatalf("清除所有暫存檔失敗: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("清除後私有目錄應該不存在")
+	}
+}
+
+// TestExportServiceRequiresReauth 測試加密筆記匯出、分享前需要重新驗證，複製到剪貼簿時設定自動清除
+func TestExportServiceRequiresReauth(t *testing.T) {
+	guard := NewLeakageGuard()
+	exportSvc := NewExportService(&mockExportEditorService{options: EditorOptions{LeakageGuard: guard}})
+	note := &models.Note{ID: "secret", Title: "機密", Content: "機密內容", FilePath: "secret.md.enc", IsEncrypted: true}
+	outputDir := t.TempDir()
+	outputPath := filepath.Join(outputDir, "secret.html")
+
+	if err := exportSvc.ExportToHTML(note, outputPath, nil); !errors.Is(err, ErrReauthRequired) {
+		t.Errorf("未重新驗證時匯出應該失敗: %v", err)
+	}
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Error("未重新驗證時不應寫出明文檔案")
+	}
+	if _, err := exportSvc.BatchExport([]*models.Note{note}, outputDir, ExportFormatMarkdown, nil); !errors.Is(err, ErrReauthRequired) {
+		t.Errorf("未重新驗證時批量匯出應該失敗: %v", err)
+	}
+	if _, err := exportSvc.ShareNote(note, &ShareOptions{ShareType: ShareTypeClipboard}); !errors.Is(err, ErrReauthRequired) {
+		t.Errorf("未重新驗證時分享應該失敗: %v", err)
+	}
+
+	if err := exportSvc.Reauthenticate(note, "WrongPassword!"); err == nil {
+		t.Error("密碼錯誤時重新驗證應該失敗")
+	}
+	if err := exportSvc.Reauthenticate(note, "Password123!"); err != nil {
+		t.Fatalf("重新驗證失敗: %v", err)
+	}
+
+	if err := exportSvc.ExportToHTML(note, outputPath, nil); err != nil {
+		t.Errorf("重新驗證後應該能匯出: %v", err)
+	}
+	result, err := exportSvc.ShareNote(note, &ShareOptions{ShareType: ShareTypeClipboard})
+	if err != nil || !strings.Contains(result.ClipboardContent, "機密內容") {
+		t.Fatalf("重新驗證後應該能複製到剪貼簿: %+v, %v", result, err)
+	}
+	if result.ClipboardClearAfter != SensitiveClipboardTimeout {
+		t.Errorf("加密筆記的剪貼簿內容應該在 %v 後清除，實際為 %v", SensitiveClipboardTimeout, result.ClipboardClearAfter)
+	}
+
+	// 未加密的筆記不需要重新驗證，剪貼簿也不會自動清除
+	plain := &models.Note{ID: "plain", Title: "公開", Content: "公開內容"}
+	if result, err := exportSvc.ShareNote(plain, &ShareOptions{ShareType: ShareTypeClipboard}); err != nil || result.ClipboardClearAfter != 0 {
+		t.Errorf("未加密的筆記不應設定自動清除: %+v, %v", result, err)
+	}
+}
+
+// TestEditorServiceReauthenticateNote 測試以筆記密碼重新驗證，工作階段鎖定時撤銷授權
+func TestEditorServiceReauthenticateNote(t *testing.T) {
+	service, mockRepo := createTestEditorService()
+	service.(*editorService).encryptionSvc = NewEncryptionService()
+
+	encrypted, err := NewEncryptionService().EncryptContent("機密內容", "Password123!", AlgorithmAES256)
+	if err != nil {
+		t.Fatalf("加密失敗: %v", err)
+	}
+	mockRepo.WriteFile("secret.md.enc", encrypted)
+	note := &models.Note{ID: "secret", Title: "secret", FilePath: "secret.md.enc", IsEncrypted: true}
+	guard := service.Options().LeakageGuard
+
+	if err := service.(NoteReauthenticator).ReauthenticateNote(note, "WrongPassword!"); err == nil {
+		t.Error("密碼錯誤時重新驗證應該失敗")
+	}
+	if err := guard.RequireReauth(note); !errors.Is(err, ErrReauthRequired) {
+		t.Errorf("驗證失敗時不應授權: %v", err)
+	}
+
+	if err := service.(NoteReauthenticator).ReauthenticateNote(note, "Password123!"); err != nil {
+		t.Fatalf("重新驗證失敗: %v", err)
+	}
+	if err := guard.RequireReauth(note); err != nil {
+		t.Errorf("重新驗證後應該授權匯出: %v", err)
+	}
+
+	service.(*editorService).closeEncryptedNotes()
+	if err := guard.RequireReauth(note); !errors.Is(err, ErrReauthRequired) {
+		t.Errorf("工作階段鎖定後應該撤銷授權: %v", err)
+	}
+}
+
+// TestEncryptedFolderNotesAreSensitive 測試加密資料夾中的筆記視為敏感內容，以保險庫密碼重新驗證
+func TestEncryptedFolderNotesAreSensitive(t *testing.T) {
+	repo, _, vault := createTestEncryptedFileRepository(t)
+	vault.Initialize("Password123!")
+	repo.WriteFile("public.md", []byte("# 公開"))
+	repo.WriteFile(filepath.Join("journal", "diary.md"), []byte("# 日記"))
+	if err := repo.CreateEncryptedFolder("journal"); err != nil {
+		t.Fatalf("建立加密資料夾失敗: %v", err)
+	}
+	service := NewEditorService(repo, NewEncryptionService(), &mockPasswordService{}, &mockBiometricService{}, nil, nil)
+	service.SetOptions(EditorOptions{Vault: vault})
+	guard := service.Options().LeakageGuard
+
+	public, _ := service.OpenNote("public.md")
+	if IsSensitiveNote(public) || guard.RequireReauth(public) != nil {
+		t.Error("加密資料夾外的筆記不應視為敏感內容")
+	}
+
+	diary, err := service.OpenNote(filepath.Join("journal", "diary.md"))
+	if err != nil {
+		t.Fatalf("開啟筆記失敗: %v", err)
+	}
+	if !IsSensitiveNote(diary) || !IsSensitiveNote(diary.Clone()) {
+		t.Fatal("加密資料夾中的筆記應該視為敏感內容")
+	}
+	if err := guard.RequireReauth(diary); !errors.Is(err, ErrReauthRequired) {
+		t.Errorf("加密資料夾中的筆記匯出前應該要求重新驗證: %v", err)
+	}
+	if err := service.(NoteReauthenticator).ReauthenticateNote(diary, "WrongPassword1!"); err == nil {
+		t.Error("密碼錯誤時重新驗證應該失敗")
+	}
+	if err := service.(NoteReauthenticator).ReauthenticateNote(diary, "Password123!"); err != nil {
+		t.Fatalf("以保險庫密碼重新驗證失敗: %v", err)
+	}
+	if err := guard.RequireReauth(diary); err != nil {
+		t.Errorf("重新驗證後應該授權匯出: %v", err)
+	}
+
+	// 筆記保存到加密資料夾後同樣視為敏感內容
+	public.FilePath = filepath.Join("journal", "public.md")
+	if err := service.SaveNote(public); err != nil {
+		t.Fatalf("保存筆記失敗: %v", err)
+	}
+	if !IsSensitiveNote(public) {
+		t.Error("保存到加密資料夾的筆記應該視為敏感內容")
+	}
+}
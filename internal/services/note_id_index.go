@@ -0,0 +1,334 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含筆記 ID 索引：以筆記本中繼資料目錄中的索引檔記錄每個檔案的筆記 ID，
+// 同一個檔案每次開啟都使用相同的 ID，重新命名和移動後 ID 不變，也能以 ID 找到檔案目前的路徑
+package services
+
+import (
+	"encoding/json" // JSON 序列化
+	"fmt"           // 格式化輸出
+	"log"           // 日誌記錄
+	"path/filepath" // 檔案路徑處理
+	"strings"       // 字串處理
+	"sync"          // 同步控制
+
+	"github.com/google/uuid" // 產生筆記 ID
+
+	"mac-notebook-app/internal/repositories" // 引入儲存庫介面
+)
+
+// NoteIDIndexPath 筆記 ID 索引檔，位於筆記本中繼資料目錄中
+var NoteIDIndexPath = filepath.Join(NotebookMetaDir, "note-ids.json")
+
+// NoteIDIndex 定義筆記 ID 索引的介面
+type NoteIDIndex interface {
+	// Lookup 取得檔案記錄的筆記 ID
+	// 參數：filePath（相對於筆記本目錄的檔案路徑）
+	// 回傳：筆記 ID 和是否有記錄
+	Lookup(filePath string) (string, bool)
+
+	// Resolve 取得筆記 ID 目前對應的檔案路徑
+	// 參數：noteID（筆記 ID）
+	// 回傳：檔案路徑和是否有記錄
+	Resolve(noteID string) (string, bool)
+
+	// Assign 記錄檔案的筆記 ID，同一個 ID 原本對應的其他路徑會被取代
+	// 參數：filePath（檔案路徑）、noteID（筆記 ID）
+	// 回傳：可能的錯誤
+	Assign(filePath, noteID string) error
+
+	// Rename 檔案或目錄重新命名、移動後更新路徑，目錄中所有檔案的 ID 維持不變
+	// 參數：oldPath（舊路徑）、newPath（新路徑）
+	// 回傳：可能的錯誤
+	Rename(oldPath, newPath string) error
+
+	// Remove 檔案或目錄刪除後移除記錄
+	// 參數：path（檔案或目錄路徑）
+	// 回傳：可能的錯誤
+	Remove(path string) error
+}
+
+// noteIDIndexFile 索引檔的內容
+type noteIDIndexFile struct {
+	Notes map[string]string `json:"notes"` // 檔案路徑（以 / 分隔）對應的筆記 ID
+}
+
+// noteIDIndex 實作 NoteIDIndex 介面
+type noteIDIndex struct {
+	fileRepo repositories.FileRepository // 檔案存取介面
+	ids      map[string]string           // 檔案路徑對應的筆記 ID
+	paths    map[string]string           // 筆記 ID 對應的檔案路徑
+	loaded   bool                        // 是否已讀取索引檔
+	mutex    sync.Mutex                  // 保護索引
+}
+
+// NewNoteIDIndex 建立筆記 ID 索引
+// 參數：fileRepo（檔案存取介面）
+// 回傳：筆記 ID 索引實例，第一次使用時才讀取索引檔
+func NewNoteIDIndex(fileRepo repositories.FileRepository) NoteIDIndex {
+	return &noteIDIndex{
+		fileRepo: fileRepo,
+		ids:      make(map[string]string),
+		paths:    make(map[string]string),
+	}
+}
+
+// Lookup 取得檔案記錄的筆記 ID
+// 參數：filePath（檔案路徑）
+// 回傳：筆記 ID 和是否有記錄
+func (x *noteIDIndex) Lookup(filePath string) (string, bool) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	if err := x.load(); err != nil {
+		log.Printf("讀取筆記 ID 索引失敗: %v", err)
+		return "", false
+	}
+	id, ok := x.ids[noteIndexKey(filePath)]
+	return id, ok
+}
+
+// Resolve 取得筆記 ID 目前對應的檔案路徑
+// 參數：noteID（筆記 ID）
+// 回傳：檔案路徑和是否有記錄
+func (x *noteIDIndex) Resolve(noteID string) (string, bool) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	if err := x.load(); err != nil {
+		log.Printf("讀取筆記 ID 索引失敗: %v", err)
+		return "", false
+	}
+	path, ok := x.paths[noteID]
+	if !ok {
+		return "", false
+	}
+	return filepath.FromSlash(path), true
+}
+
+// Assign 記錄檔案的筆記 ID
+// 參數：filePath（檔案路徑）、noteID（筆記 ID）
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 記錄已相同時不寫入索引檔
+// 2. 移除這個 ID 原本對應的路徑和這個路徑原本記錄的 ID，每個 ID 只對應一個檔案
+// 3. 寫入索引檔
+func (x *noteIDIndex) Assign(filePath, noteID string) error {
+	if filePath == "" || noteID == "" {
+		return fmt.Errorf("筆記路徑和 ID 不能為空")
+	}
+
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	if err := x.load(); err != nil {
+		return err
+	}
+	key := noteIndexKey(filePath)
+	if x.ids[key] == noteID {
+		return nil
+	}
+	if oldPath, ok := x.paths[noteID]; ok {
+		delete(x.ids, oldPath)
+	}
+	if oldID, ok := x.ids[key]; ok {
+		delete(x.paths, oldID)
+	}
+	x.ids[key] = noteID
+	x.paths[noteID] = key
+	return x.save()
+}
+
+// Rename 檔案或目錄重新命名、移動後更新路徑
+// 參數：oldPath（舊路徑）、newPath（新路徑）
+// 回傳：可能的錯誤
+func (x *noteIDIndex) Rename(oldPath, newPath string) error {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	if err := x.load(); err != nil {
+		return err
+	}
+	oldKey, newKey := noteIndexKey(oldPath), noteIndexKey(newPath)
+	changed := false
+	for key, id := range x.ids {
+		rest, ok := underNoteIndexKey(key, oldKey)
+		if !ok {
+			continue
+		}
+		delete(x.ids, key)
+		x.ids[newKey+rest] = id
+		x.paths[id] = newKey + rest
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return x.save()
+}
+
+// Remove 檔案或目錄刪除後移除記錄
+// 參數：path（檔案或目錄路徑）
+// 回傳：可能的錯誤
+func (x *noteIDIndex) Remove(path string) error {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	if err := x.load(); err != nil {
+		return err
+	}
+	prefix := noteIndexKey(path)
+	changed := false
+	for key, id := range x.ids {
+		if _, ok := underNoteIndexKey(key, prefix); ok {
+			delete(x.ids, key)
+			delete(x.paths, id)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return x.save()
+}
+
+// load 第一次使用時讀取索引檔，檔案不存在時從空的索引開始
+// 回傳：可能的錯誤（索引檔格式錯誤時不覆寫，避免遺失記錄）
+func (x *noteIDIndex) load() error {
+	if x.loaded {
+		return nil
+	}
+	if x.fileRepo.FileExists(NoteIDIndexPath) {
+		data, err := x.fileRepo.ReadFile(NoteIDIndexPath)
+		if err != nil {
+			return fmt.Errorf("讀取筆記 ID 索引失敗: %w", err)
+		}
+		var file noteIDIndexFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("筆記 ID 索引格式錯誤: %w", err)
+		}
+		for path, id := range file.Notes {
+			x.ids[path] = id
+			x.paths[id] = path
+		}
+	}
+	x.loaded = true
+	return nil
+}
+
+// save 將索引寫入索引檔
+// 回傳：可能的錯誤
+func (x *noteIDIndex) save() error {
+	data, err := json.MarshalIndent(noteIDIndexFile{Notes: x.ids}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化筆記 ID 索引失敗: %w", err)
+	}
+	if err := x.fileRepo.WriteFile(NoteIDIndexPath, data); err != nil {
+		return fmt.Errorf("寫入筆記 ID 索引失敗: %w", err)
+	}
+	return nil
+}
+
+// noteIndexKey 將檔案路徑轉為索引使用的鍵，不同平台的路徑分隔符號一致
+// 參數：path（檔案路徑）
+// 回傳：以 / 分隔的路徑
+func noteIndexKey(path string) string {
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+// underNoteIndexKey 檢查路徑是否為指定的路徑或位於該目錄中
+// 參數：key（索引中的路徑）、prefix（檔案或目錄路徑）
+// 回傳：key 在 prefix 之後的部分和是否符合
+func underNoteIndexKey(key, prefix string) (string, bool) {
+	if key == prefix {
+		return "", true
+	}
+	if strings.HasPrefix(key, prefix+"/") {
+		return key[len(prefix):], true
+	}
+	return "", false
+}
+
+// openedNoteID 決定開啟的筆記使用的 ID
+// 參數：filePath（檔案路徑）、content（解密後的內容）
+// 回傳：筆記 ID
+//
+// 執行流程：
+// 1. front matter 記錄的 ID 優先，但同一個 ID 已屬於另一個仍存在的檔案或開啟中的筆記時（例如複製的檔案）不沿用
+// 2. 其次使用索引中這個路徑記錄的 ID
+// 3. 都沒有時產生新的 ID
+// 4. 將結果記錄到索引，下次開啟或重新命名後沿用
+func (e *editorService) openedNoteID(filePath, content string) string {
+	if fm, _, err := ParseFrontMatter(content); err == nil && fm.ID != "" && e.noteIDAvailable(fm.ID, filePath) {
+		e.recordNoteID(filePath, fm.ID)
+		return fm.ID
+	}
+	return e.indexedNoteID(filePath)
+}
+
+// indexedNoteID 取得索引中檔案記錄的 ID，沒有記錄或已被其他開啟中的筆記使用時產生新的 ID
+// 參數：filePath（檔案路徑）
+// 回傳：筆記 ID
+func (e *editorService) indexedNoteID(filePath string) string {
+	if e.noteIDs != nil && filePath != "" {
+		if id, ok := e.noteIDs.Lookup(filePath); ok && e.noteIDAvailable(id, filePath) {
+			return id
+		}
+	}
+	id := uuid.New().String()
+	e.recordNoteID(filePath, id)
+	return id
+}
+
+// noteIDAvailable 檢查檔案是否可以使用指定的 ID
+// 參數：noteID（筆記 ID）、filePath（檔案路徑）
+// 回傳：ID 沒有被其他開啟中的筆記或其他仍存在的檔案使用時為 true
+func (e *editorService) noteIDAvailable(noteID, filePath string) bool {
+	if existing, inUse := e.activeNotes[noteID]; inUse && existing.FilePath != filePath {
+		return false
+	}
+	if e.noteIDs == nil {
+		return true
+	}
+	path, ok := e.noteIDs.Resolve(noteID)
+	return !ok || noteIndexKey(path) == noteIndexKey(filePath) || !e.fileRepo.FileExists(path)
+}
+
+// recordNoteID 將檔案的筆記 ID 記錄到索引，未設定索引或尚未保存的筆記不處理
+// 參數：filePath（檔案路徑）、noteID（筆記 ID）
+func (e *editorService) recordNoteID(filePath, noteID string) {
+	if e.noteIDs == nil || filePath == "" {
+		return
+	}
+	if err := e.noteIDs.Assign(filePath, noteID); err != nil {
+		log.Printf("記錄筆記 ID 失敗: %v", err)
+	}
+}
+
+// SetNoteIDIndex 設定筆記 ID 索引，重新命名、移動和刪除檔案時更新記錄
+// 參數：index（筆記 ID 索引）
+func (s *LocalFileManagerService) SetNoteIDIndex(index NoteIDIndex) {
+	s.noteIDs = index
+}
+
+// moveNoteIDs 檔案或目錄重新命名、移動後更新筆記 ID 索引
+// 參數：oldPath（舊路徑）、newPath（新路徑）
+func (s *LocalFileManagerService) moveNoteIDs(oldPath, newPath string) {
+	if s.noteIDs == nil {
+		return
+	}
+	if err := s.noteIDs.Rename(oldPath, newPath); err != nil {
+		log.Printf("更新筆記 ID 索引失敗 %s: %v", oldPath, err)
+	}
+}
+
+// forgetNoteIDs 檔案刪除後移除筆記 ID 索引中的記錄
+// 參數：path（刪除的路徑）
+func (s *LocalFileManagerService) forgetNoteIDs(path string) {
+	if s.noteIDs == nil {
+		return
+	}
+	if err := s.noteIDs.Remove(path); err != nil {
+		log.Printf("移除筆記 ID 索引記錄失敗 %s: %v", path, err)
+	}
+}
@@ -0,0 +1,127 @@
+// Package services 提供筆記 ID 索引的單元測試
+// 測試同一個檔案每次開啟使用相同的 ID、重新命名和移動後 ID 不變，以及以 ID 找到目前的路徑
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mac-notebook-app/internal/repositories"
+)
+
+// createTestNoteIDEnvironment 建立使用同一個筆記 ID 索引的編輯器服務和檔案管理服務
+func createTestNoteIDEnvironment(t *testing.T) (string, EditorService, *LocalFileManagerService, NoteIDIndex) {
+	baseDir := t.TempDir()
+	fileRepo, err := repositories.NewLocalFileRepository(baseDir)
+	if err != nil {
+		t.Fatalf("建立檔案儲存庫失敗: %v", err)
+	}
+	fileManager, err := NewLocalFileManagerService(fileRepo, baseDir)
+	if err != nil {
+		t.Fatalf("建立檔案管理服務失敗: %v", err)
+	}
+	editor := NewEditorService(fileRepo, &mockEncryptionService{}, &mockPasswordService{}, &mockBiometricService{}, nil, nil)
+
+	index := NewNoteIDIndex(fileRepo)
+	editor.SetOptions(EditorOptions{NoteIDIndex: index})
+	fileManager.SetNoteIDIndex(index)
+	return baseDir, editor, fileManager, index
+}
+
+// TestNoteIDStableAcrossSessions 測試重新開啟和重新啟動後（新的索引實例）筆記 ID 維持不變
+func TestNoteIDStableAcrossSessions(t *testing.T) {
+	baseDir, editor, _, _ := createTestNoteIDEnvironment(t)
+	os.WriteFile(filepath.Join(baseDir, "plan.md"), []byte("# 計畫"), 0644)
+
+	note, err := editor.OpenNote("plan.md")
+	if err != nil {
+		t.Fatalf("開啟筆記失敗: %v", err)
+	}
+	editor.CloseNote(note.ID)
+	reopened, _ := editor.OpenNote("plan.md")
+	if reopened.ID != note.ID {
+		t.Errorf("重新開啟後 ID 應該維持不變: %s != %s", reopened.ID, note.ID)
+	}
+
+	// 模擬重新啟動應用程式
+	fileRepo, _ := repositories.NewLocalFileRepository(baseDir)
+	restarted := NewEditorService(fileRepo, &mockEncryptionService{}, &mockPasswordService{}, &mockBiometricService{}, nil, nil)
+	restarted.SetOptions(EditorOptions{NoteIDIndex: NewNoteIDIndex(fileRepo)})
+	again, _ := restarted.OpenNote("plan.md")
+	if again.ID != note.ID {
+		t.Errorf("重新啟動後 ID 應該維持不變: %s != %s", again.ID, note.ID)
+	}
+
+	// 新筆記保存後記錄 ID
+	created, _ := editor.CreateNote("新筆記", "內容")
+	if err := editor.SaveNote(created); err != nil {
+		t.Fatalf("保存筆記失敗: %v", err)
+	}
+	editor.CloseNote(created.ID)
+	if opened, _ := editor.OpenNote(created.FilePath); opened.ID != created.ID {
+		t.Errorf("保存後重新開啟應該沿用建立時的 ID: %s != %s", opened.ID, created.ID)
+	}
+}
+
+// TestNoteIDFollowsRenameAndMove 測試重新命名、移動檔案和目錄後 ID 不變，並能以 ID 找到目前的路徑
+func TestNoteIDFollowsRenameAndMove(t *testing.T) {
+	baseDir, editor, fileManager, index := createTestNoteIDEnvironment(t)
+	os.MkdirAll(filepath.Join(baseDir, "projects"), 0755)
+	os.WriteFile(filepath.Join(baseDir, "projects", "plan.md"), []byte("# 計畫"), 0644)
+
+	note, _ := editor.OpenNote(filepath.Join("projects", "plan.md"))
+	editor.CloseNote(note.ID)
+
+	if err := fileManager.RenameFile(filepath.Join("projects", "plan.md"), filepath.Join("projects", "roadmap.md")); err != nil {
+		t.Fatalf("重新命名失敗: %v", err)
+	}
+	if err := fileManager.RenameFile("projects", "archive"); err != nil {
+		t.Fatalf("重新命名目錄失敗: %v", err)
+	}
+	os.MkdirAll(filepath.Join(baseDir, "done"), 0755)
+	if err := fileManager.MoveFile(filepath.Join("archive", "roadmap.md"), "done"); err != nil {
+		t.Fatalf("移動檔案失敗: %v", err)
+	}
+
+	expected := filepath.Join("done", "roadmap.md")
+	if path, ok := index.Resolve(note.ID); !ok || path != expected {
+		t.Errorf("應該以 ID 找到目前的路徑: %q, %v", path, ok)
+	}
+	moved, _ := editor.OpenNote(expected)
+	if moved.ID != note.ID {
+		t.Errorf("重新命名和移動後 ID 應該維持不變: %s != %s", moved.ID, note.ID)
+	}
+
+	editor.CloseNote(moved.ID)
+	if err := fileManager.DeleteFile(expected); err != nil {
+		t.Fatalf("刪除檔案失敗: %v", err)
+	}
+	if _, ok := index.Resolve(note.ID); ok {
+		t.Error("刪除後不應再能以 ID 找到檔案")
+	}
+}
+
+// TestNoteIDForCopiedFile 測試複製的檔案（front matter 中的 ID 相同）取得不同的 ID，原本的檔案不受影響
+func TestNoteIDForCopiedFile(t *testing.T) {
+	baseDir, editor, fileManager, index := createTestNoteIDEnvironment(t)
+	os.WriteFile(filepath.Join(baseDir, "plan.md"), []byte("---\nid: note-123\n---\n# 計畫"), 0644)
+
+	original, _ := editor.OpenNote("plan.md")
+	editor.CloseNote(original.ID)
+	if err := fileManager.CopyFile("plan.md", "plan copy.md"); err != nil {
+		t.Fatalf("複製檔案失敗: %v", err)
+	}
+
+	copied, _ := editor.OpenNote("plan copy.md")
+	editor.CloseNote(copied.ID)
+	if copied.ID == original.ID {
+		t.Fatal("複製的檔案應該取得不同的 ID")
+	}
+	if again, _ := editor.OpenNote("plan copy.md"); again.ID != copied.ID {
+		t.Errorf("複製的檔案重新開啟後 ID 應該維持不變: %s != %s", again.ID, copied.ID)
+	}
+	if path, _ := index.Resolve("note-123"); path != "plan.md" {
+		t.Errorf("front matter 的 ID 應該仍然對應原本的檔案: %q", path)
+	}
+}
@@ -0,0 +1,198 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含 note:// 連結和啟動參數的解析：以連結或檔案路徑指定要開啟的筆記，
+// 連結的片段（# 之後）指定要跳到的標題
+package services
+
+import (
+	"errors"        // 錯誤處理
+	"fmt"           // 格式化輸出
+	"net/url"       // 連結編碼
+	"os"            // 檔案狀態
+	"path/filepath" // 檔案路徑處理
+	"strings"       // 字串處理
+	"unicode"       // 字元分類
+)
+
+// NoteLinkScheme note:// 連結的前綴
+const NoteLinkScheme = "note://"
+
+// NoteLink 代表要開啟的筆記和標題
+type NoteLink struct {
+	Path    string // 相對於筆記本目錄的檔案路徑
+	Heading string // 要跳到的標題（空字串代表不跳轉）
+}
+
+// String 組成 note:// 連結
+// 回傳：連結字串，路徑和標題以 URL 編碼
+func (l *NoteLink) String() string {
+	segments := strings.Split(filepath.ToSlash(l.Path), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	link := NoteLinkScheme + strings.Join(segments, "/")
+	if l.Heading != "" {
+		link += "#" + url.PathEscape(l.Heading)
+	}
+	return link
+}
+
+// IsNoteLink 檢查參數是否為 note:// 連結
+// 參數：arg（命令列參數）
+// 回傳：是否為 note:// 連結
+func IsNoteLink(arg string) bool {
+	return len(arg) >= len(NoteLinkScheme) && strings.EqualFold(arg[:len(NoteLinkScheme)], NoteLinkScheme)
+}
+
+// ParseNoteLink 解析 note:// 連結
+// 參數：link（連結，例如 note://projects/plan.md#時程）
+// 回傳：解析結果和可能的錯誤
+func ParseNoteLink(link string) (*NoteLink, error) {
+	if !IsNoteLink(link) {
+		return nil, fmt.Errorf("不是 note:// 連結: %s", link)
+	}
+	rest := link[len(NoteLinkScheme):]
+	rawPath, rawHeading, _ := strings.Cut(rest, "#")
+
+	path, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return nil, fmt.Errorf("連結路徑格式錯誤: %w", err)
+	}
+	heading, err := url.PathUnescape(rawHeading)
+	if err != nil {
+		return nil, fmt.Errorf("連結標題格式錯誤: %w", err)
+	}
+
+	path = filepath.Clean(filepath.FromSlash(strings.TrimLeft(path, "/")))
+	if path == "." || path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("連結路徑無效: %s", link)
+	}
+	return &NoteLink{Path: path, Heading: strings.TrimSpace(heading)}, nil
+}
+
+// ResolveLaunchArguments 將命令列參數轉換為要開啟的筆記
+// 參數：baseDir（筆記本目錄）、args（命令列參數，可以是檔案路徑或 note:// 連結）
+// 回傳：可以開啟的筆記和無法開啟的參數合併後的錯誤
+//
+// 執行流程：
+// 1. note:// 連結直接解析
+// 2. 檔案路徑轉為相對於筆記本目錄的路徑，不在筆記本目錄中或不是檔案時回報錯誤
+// 3. 略過以 - 開頭的選項（例如 macOS 傳入的 -psn_ 參數）
+func ResolveLaunchArguments(baseDir string, args []string) ([]*NoteLink, error) {
+	absBase, err := filepath.Abs(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("取得筆記本目錄失敗: %w", err)
+	}
+
+	var links []*NoteLink
+	var errs []error
+	for _, arg := range args {
+		if arg == "" || strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if IsNoteLink(arg) {
+			link, err := ParseNoteLink(arg)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			links = append(links, link)
+			continue
+		}
+
+		absPath, err := filepath.Abs(arg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("檔案路徑無效 %s: %w", arg, err))
+			continue
+		}
+		rel, err := filepath.Rel(absBase, absPath)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			errs = append(errs, fmt.Errorf("檔案不在筆記本目錄中: %s", arg))
+			continue
+		}
+		info, err := os.Stat(absPath)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("找不到檔案: %s", arg))
+			continue
+		}
+		if info.IsDir() {
+			errs = append(errs, fmt.Errorf("不是筆記檔案: %s", arg))
+			continue
+		}
+		links = append(links, &NoteLink{Path: rel})
+	}
+	return links, errors.Join(errs...)
+}
+
+// FindHeadingLine 找出標題所在的行
+// 參數：content（筆記內容）、heading（標題文字或其 slug，例如「專案時程」或 project-schedule）
+// 回傳：從 0 開始的行號，找不到時為 -1
+//
+// 執行流程：
+// 1. 略過程式碼區塊中以 # 開頭的行
+// 2. 先比對標題文字（不分大小寫），再比對 slug
+func FindHeadingLine(content, heading string) int {
+	heading = strings.TrimSpace(heading)
+	if heading == "" {
+		return -1
+	}
+	slug := headingSlug(heading)
+
+	inFence := false
+	slugLine := -1
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		text, ok := headingText(trimmed)
+		if !ok {
+			continue
+		}
+		if strings.EqualFold(text, heading) {
+			return i
+		}
+		if slugLine < 0 && slug != "" && headingSlug(text) == slug {
+			slugLine = i
+		}
+	}
+	return slugLine
+}
+
+// headingText 取得 Markdown 標題行的文字
+// 參數：line（去除前後空白的行）
+// 回傳：標題文字和是否為標題行
+func headingText(line string) (string, bool) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(line) && line[level] != ' ' && line[level] != '\t') {
+		return "", false
+	}
+	text := strings.TrimSpace(line[level:])
+	// 移除結尾選用的 # 符號
+	if trimmed := strings.TrimRight(text, "#"); trimmed != text && (trimmed == "" || strings.HasSuffix(trimmed, " ")) {
+		text = strings.TrimSpace(trimmed)
+	}
+	return text, true
+}
+
+// headingSlug 將標題轉為 slug：轉小寫、空白改為連字號、移除標點符號
+// 參數：text（標題文字）
+// 回傳：slug
+func headingSlug(text string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+			builder.WriteRune(r)
+		case unicode.IsSpace(r):
+			builder.WriteByte('-')
+		}
+	}
+	return builder.String()
+}
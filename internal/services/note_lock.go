@@ -0,0 +1,143 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含筆記的跨程序建議鎖：開啟筆記時鎖定，同一個筆記已在其他程式中開啟時提醒使用者，
+// 鎖定只用於提醒，不會阻止開啟或保存
+package services
+
+import (
+	"crypto/sha256" // 鎖定檔名稱雜湊
+	"encoding/hex"  // 十六進位編碼
+	"encoding/json" // 鎖定資訊編碼
+	"errors"        // 錯誤處理
+	"fmt"           // 格式化輸出
+	"os"            // 檔案操作
+	"path/filepath" // 檔案路徑處理
+	"sync"          // 同步控制
+	"time"          // 時間處理
+)
+
+// NoteLockDir 筆記鎖定檔目錄，位於筆記本中繼資料目錄
+const NoteLockDir = "locks"
+
+// NoteLockOwner 持有筆記鎖定的程式
+type NoteLockOwner struct {
+	PID      int       `json:"pid"`      // 程序 ID
+	Hostname string    `json:"hostname"` // 主機名稱
+	Since    time.Time `json:"since"`    // 開始鎖定的時間
+}
+
+// NoteLockService 定義筆記建議鎖的介面
+type NoteLockService interface {
+	// Acquire 鎖定筆記，已被其他程式鎖定時回傳持有者
+	// 參數：filePath（相對於筆記本目錄的檔案路徑）
+	// 回傳：其他程式持有鎖定時為持有者（否則為 nil）和可能的錯誤
+	Acquire(filePath string) (*NoteLockOwner, error)
+
+	// Release 釋放筆記的鎖定
+	// 參數：filePath（檔案路徑）
+	Release(filePath string)
+
+	// ReleaseAll 釋放所有鎖定
+	ReleaseAll()
+}
+
+// noteLockService 實作 NoteLockService 介面
+type noteLockService struct {
+	lockDir string              // 鎖定檔目錄
+	held    map[string]*os.File // 目前持有的鎖定檔，以檔案路徑為鍵
+	mutex   sync.Mutex          // 保護持有的鎖定
+}
+
+// NewNoteLockService 建立筆記建議鎖服務
+// 參數：baseDir（筆記本目錄）
+// 回傳：筆記建議鎖服務實例
+func NewNoteLockService(baseDir string) NoteLockService {
+	return &noteLockService{
+		lockDir: filepath.Join(baseDir, NotebookMetaDir, NoteLockDir),
+		held:    make(map[string]*os.File),
+	}
+}
+
+// Acquire 鎖定筆記
+// 參數：filePath（檔案路徑）
+// 回傳：其他程式持有鎖定時為持有者和可能的錯誤
+//
+// 執行流程：
+// 1. 以檔案路徑的雜湊命名鎖定檔，避免在中繼資料目錄中留下筆記名稱
+// 2. 鎖定失敗時讀取持有者資訊
+// 3. 鎖定成功時寫入自己的資訊並保持開啟直到釋放
+func (s *noteLockService) Acquire(filePath string) (*NoteLockOwner, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := filepath.Clean(filePath)
+	if _, ok := s.held[key]; ok {
+		return nil, nil
+	}
+	if err := os.MkdirAll(s.lockDir, 0700); err != nil {
+		return nil, fmt.Errorf("建立鎖定檔目錄失敗: %w", err)
+	}
+
+	file, err := os.OpenFile(s.lockPath(key), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("開啟鎖定檔失敗: %w", err)
+	}
+	if err := tryLockFile(file); err != nil {
+		defer file.Close()
+		if !errors.Is(err, errFileLocked) {
+			return nil, fmt.Errorf("鎖定筆記失敗: %w", err)
+		}
+		owner := &NoteLockOwner{}
+		if data, err := os.ReadFile(s.lockPath(key)); err == nil {
+			json.Unmarshal(data, owner)
+		}
+		return owner, nil
+	}
+
+	hostname, _ := os.Hostname()
+	data, _ := json.Marshal(NoteLockOwner{PID: os.Getpid(), Hostname: hostname, Since: time.Now()})
+	file.Truncate(0)
+	file.WriteAt(data, 0)
+	s.held[key] = file
+	return nil, nil
+}
+
+// Release 釋放筆記的鎖定
+// 參數：filePath（檔案路徑）
+func (s *noteLockService) Release(filePath string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := filepath.Clean(filePath)
+	if file, ok := s.held[key]; ok {
+		s.releaseFile(file)
+		delete(s.held, key)
+	}
+}
+
+// ReleaseAll 釋放所有鎖定
+func (s *noteLockService) ReleaseAll() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for key, file := range s.held {
+		s.releaseFile(file)
+		delete(s.held, key)
+	}
+}
+
+// releaseFile 清除鎖定資訊並釋放鎖定
+// 參數：file（鎖定檔）
+func (s *noteLockService) releaseFile(file *os.File) {
+	// 鎖定檔保留在原處，刪除後其他程式可能鎖定到不同的檔案
+	file.Truncate(0)
+	unlockFile(file)
+	file.Close()
+}
+
+// lockPath 取得筆記的鎖定檔路徑
+// 參數：key（清理後的檔案路徑）
+// 回傳：鎖定檔路徑
+func (s *noteLockService) lockPath(key string) string {
+	sum := sha256.Sum256([]byte(filepath.ToSlash(key)))
+	return filepath.Join(s.lockDir, hex.EncodeToString(sum[:16])+".lock")
+}
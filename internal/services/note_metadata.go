@@ -0,0 +1,180 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含加密筆記的中繼資料標頭和檔名混淆，
+// 標題、時間戳和標籤以資料金鑰加密後保存在檔案中，並作為內容的附加驗證資料
+package services
+
+import (
+	"crypto/rand"   // 安全隨機數產生
+	"encoding/hex"  // 十六進位編碼
+	"encoding/json" // JSON 序列化
+	"errors"        // 錯誤處理
+	"fmt"           // 格式化輸出
+	"io"            // 輸入輸出介面
+	"path/filepath" // 檔案路徑處理
+	"slices"        // 切片操作
+	"strings"       // 字串處理
+	"time"          // 時間處理
+
+	"mac-notebook-app/internal/models" // 引入資料模型
+)
+
+// 中繼資料標頭相關常數
+const (
+	noteMetadataAAD       = "note-metadata:" // 加密中繼資料時使用的附加驗證資料前綴
+	maxNoteMetadataSize   = 64 * 1024        // 加密後的中繼資料大小上限
+	obfuscatedNameBytes   = 16               // 隨機檔名的位元組數（十六進位後為 32 個字元）
+	ObfuscatedFileExt     = ".enc"           // 隨機檔名的副檔名
+	obfuscatedNameHexSize = obfuscatedNameBytes * 2
+)
+
+// NoteMetadata 代表加密筆記的中繼資料標頭
+// 以資料金鑰加密後保存在檔案中，開啟筆記時用於還原標題、時間戳和標籤
+type NoteMetadata struct {
+	Title     string    `json:"title"`          // 筆記標題
+	CreatedAt time.Time `json:"created_at"`     // 建立時間
+	UpdatedAt time.Time `json:"updated_at"`     // 最後修改時間
+	Tags      []string  `json:"tags,omitempty"` // 標籤
+}
+
+// NewNoteMetadata 從筆記建立中繼資料標頭
+// 參數：note（筆記實例）
+// 回傳：中繼資料標頭
+func NewNoteMetadata(note *models.Note) *NoteMetadata {
+	return &NoteMetadata{
+		Title:     note.Title,
+		CreatedAt: note.CreatedAt,
+		UpdatedAt: note.UpdatedAt,
+		Tags:      slices.Clone(note.Tags),
+	}
+}
+
+// ApplyTo 將中繼資料標頭的內容套用到筆記
+// 參數：note（筆記實例）
+func (m *NoteMetadata) ApplyTo(note *models.Note) {
+	if m.Title != "" {
+		note.Title = m.Title
+	}
+	if !m.CreatedAt.IsZero() {
+		note.CreatedAt = m.CreatedAt
+	}
+	if !m.UpdatedAt.IsZero() {
+		note.UpdatedAt = m.UpdatedAt
+	}
+	if len(m.Tags) > 0 {
+		note.Tags = slices.Clone(m.Tags)
+	}
+}
+
+// sealNoteMetadata 加密中繼資料標頭
+// 參數：algorithm（加密演算法）、key（資料金鑰）、keyID（資料金鑰 ID，綁定為附加驗證資料）、meta（中繼資料）
+// 回傳：隨機數加上密文，meta 為 nil 時回傳 nil
+func sealNoteMetadata(algorithm string, key []byte, keyID string, meta *NoteMetadata) ([]byte, error) {
+	if meta == nil {
+		return nil, nil
+	}
+
+	plaintext, err := json.Marshal(meta)
+	if err != nil {
+		return nil, fmt.Errorf("序列化中繼資料失敗: %w", err)
+	}
+	defer zeroBytes(plaintext)
+
+	ciphertext, nonce, err := sealWithAlgorithm(algorithm, key, plaintext, []byte(noteMetadataAAD+keyID))
+	if err != nil {
+		return nil, fmt.Errorf("加密中繼資料失敗: %w", err)
+	}
+
+	sealed := append(nonce, ciphertext...)
+	if len(sealed) > maxNoteMetadataSize {
+		return nil, errors.New("中繼資料過大")
+	}
+	return sealed, nil
+}
+
+// openNoteMetadata 解密中繼資料標頭
+// 參數：algorithm（加密演算法）、key（資料金鑰）、keyID（資料金鑰 ID）、sealed（隨機數加上密文）
+// 回傳：中繼資料和可能的錯誤，sealed 為空時回傳 nil
+func openNoteMetadata(algorithm string, key []byte, keyID string, sealed []byte) (*NoteMetadata, error) {
+	if len(sealed) == 0 {
+		return nil, nil
+	}
+	if len(sealed) <= NonceSize {
+		return nil, errors.New("中繼資料格式無效")
+	}
+
+	plaintext, err := openWithAlgorithm(algorithm, key, sealed[:NonceSize], sealed[NonceSize:], []byte(noteMetadataAAD+keyID))
+	if err != nil {
+		return nil, fmt.Errorf("中繼資料驗證失敗: %w", err)
+	}
+	defer zeroBytes(plaintext)
+
+	var meta NoteMetadata
+	if err := json.Unmarshal(plaintext, &meta); err != nil {
+		return nil, fmt.Errorf("解析中繼資料失敗: %w", err)
+	}
+	return &meta, nil
+}
+
+// noteContentAAD 取得筆記內容的附加驗證資料
+// 內容綁定資料金鑰 ID 和加密後的中繼資料，中繼資料被替換或移除時內容無法解密
+// 參數：keyID（資料金鑰 ID）、sealedMeta（加密後的中繼資料，可為空）
+// 回傳：附加驗證資料
+func noteContentAAD(keyID string, sealedMeta []byte) []byte {
+	if len(sealedMeta) == 0 {
+		return []byte(keyID)
+	}
+
+	aad := make([]byte, 0, len(keyID)+1+len(sealedMeta))
+	aad = append(aad, keyID...)
+	aad = append(aad, 0)
+	return append(aad, sealedMeta...)
+}
+
+// IsObfuscatedFileName 檢查檔名是否為隨機檔名
+// 參數：name（檔案名稱，不含目錄）
+// 回傳：是否為隨機檔名
+func IsObfuscatedFileName(name string) bool {
+	base := strings.TrimSuffix(name, ObfuscatedFileExt)
+	if base == name || len(base) != obfuscatedNameHexSize {
+		return false
+	}
+	_, err := hex.DecodeString(base)
+	return err == nil && strings.ToLower(base) == base
+}
+
+// NewObfuscatedFilePath 在指定目錄下產生隨機檔名
+// 參數：dir（目錄路徑）
+// 回傳：新的檔案路徑和可能的錯誤
+func NewObfuscatedFilePath(dir string) (string, error) {
+	name := make([]byte, obfuscatedNameBytes)
+	if _, err := io.ReadFull(rand.Reader, name); err != nil {
+		return "", fmt.Errorf("產生隨機檔名失敗: %w", err)
+	}
+
+	fileName := hex.EncodeToString(name) + ObfuscatedFileExt
+	if dir == "" || dir == "." {
+		return fileName, nil
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// DecryptedFilePath 取得加密筆記取消加密後使用的檔案路徑
+// 隨機檔名改回以標題命名，一般檔名只移除 .enc 副檔名
+// 參數：filePath（加密檔案路徑）、title（筆記標題）
+// 回傳：取消加密後的檔案路徑
+func DecryptedFilePath(filePath, title string) string {
+	if !IsObfuscatedFileName(filepath.Base(filePath)) {
+		return strings.TrimSuffix(filePath, ".enc")
+	}
+
+	name := sanitizeNoteFileName(title)
+	if name == "" {
+		name = "untitled"
+	}
+
+	dir := filepath.Dir(filePath)
+	if dir == "." {
+		return name + ".md"
+	}
+	return filepath.Join(dir, name+".md")
+}
@@ -0,0 +1,232 @@
+// Package services 提供加密筆記中繼資料標頭和隨機檔名的單元測試
+// 測試中繼資料的往返加解密、與內容的綁定，以及編輯器以隨機檔名保存和顯示標題
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"mac-notebook-app/internal/models"
+)
+
+// TestNoteMetadataRoundTrip 測試中繼資料隨信封格式和串流格式加解密
+func TestNoteMetadataRoundTrip(t *testing.T) {
+	vault, _ := createTestVaultService(t)
+	if err := vault.Initialize("TestPassword123!"); err != nil {
+		t.Fatalf("初始化保險庫失敗: %v", err)
+	}
+
+	created := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	meta := &NoteMetadata{Title: "機密標題", CreatedAt: created, UpdatedAt: created, Tags: []string{"工作"}}
+
+	data, keyID, err := vault.EncryptNoteWithMetadata("內容", AlgorithmChaCha20, "", meta)
+	if err != nil {
+		t.Fatalf("加密失敗: %v", err)
+	}
+	if bytes.Contains(data, []byte("機密標題")) {
+		t.Error("加密資料不應包含明文標題")
+	}
+
+	content, info, err := vault.DecryptNote(data)
+	if err != nil || content != "內容" {
+		t.Fatalf("解密失敗: %v", err)
+	}
+	if info.Metadata == nil || info.Metadata.Title != "機密標題" || !info.Metadata.CreatedAt.Equal(created) || len(info.Metadata.Tags) != 1 {
+		t.Errorf("中繼資料不符: %+v", info.Metadata)
+	}
+
+	if read, err := vault.ReadNoteMetadata(data); err != nil || read.Title != "機密標題" {
+		t.Errorf("ReadNoteMetadata 應該只解密標頭: %+v, %v", read, err)
+	}
+
+	// 沒有中繼資料的筆記維持原格式
+	plain, _, _ := vault.EncryptNote("內容", AlgorithmAES256, keyID)
+	if read, err := vault.ReadNoteMetadata(plain); err != nil || read != nil {
+		t.Errorf("沒有中繼資料時應該回傳 nil: %+v, %v", read, err)
+	}
+
+	// 串流格式將中繼資料保存在標頭中
+	sealed, err := sealStreamContent(strings.Repeat("段落", 50000), func(dst io.Writer) (io.WriteCloser, error) {
+		writer, _, err := vault.NewNoteEncryptWriter(dst, AlgorithmAES256, keyID, meta)
+		return writer, err
+	})
+	if err != nil {
+		t.Fatalf("串流加密失敗: %v", err)
+	}
+	if _, info, err := vault.DecryptNote(sealed); err != nil || info.Metadata == nil || info.Metadata.Title != "機密標題" {
+		t.Errorf("串流格式中繼資料不符: %v", err)
+	}
+	header := peekStreamHeader(sealed)
+	if read, err := vault.ReadNoteMetadata(sealed[:len(header.raw)]); err != nil || read.Title != "機密標題" {
+		t.Errorf("串流格式應該只需標頭即可讀取中繼資料: %v", err)
+	}
+
+	vault.Lock()
+	if _, err := vault.ReadNoteMetadata(data); err == nil {
+		t.Error("鎖定後不應能讀取中繼資料")
+	}
+}
+
+// TestNoteMetadataBinding 測試中繼資料被替換或移除時內容無法解密
+func TestNoteMetadataBinding(t *testing.T) {
+	vault, _ := createTestVaultService(t)
+	if err := vault.Initialize("TestPassword123!"); err != nil {
+		t.Fatalf("初始化保險庫失敗: %v", err)
+	}
+
+	first, keyID, _ := vault.EncryptNoteWithMetadata("甲", AlgorithmAES256, "", &NoteMetadata{Title: "甲"})
+	second, _, _ := vault.EncryptNoteWithMetadata("乙", AlgorithmAES256, keyID, &NoteMetadata{Title: "乙"})
+
+	var firstData, secondData EncryptedData
+	json.Unmarshal(first, &firstData)
+	json.Unmarshal(second, &secondData)
+
+	swapped := firstData
+	swapped.Metadata = secondData.Metadata
+	stripped := firstData
+	stripped.Metadata = ""
+
+	for name, encData := range map[string]EncryptedData{"替換中繼資料": swapped, "移除中繼資料": stripped} {
+		data, _ := json.Marshal(encData)
+		if _, _, err := vault.DecryptNote(data); err == nil {
+			t.Errorf("%s: 應該無法解密", name)
+		}
+	}
+}
+
+// TestObfuscatedFileNames 測試隨機檔名的產生、判斷和取消加密後的路徑
+func TestObfuscatedFileNames(t *testing.T) {
+	path, err := NewObfuscatedFilePath("notes")
+	if err != nil {
+		t.Fatalf("產生隨機檔名失敗: %v", err)
+	}
+	if filepath.Dir(path) != "notes" || !IsObfuscatedFileName(filepath.Base(path)) {
+		t.Errorf("隨機檔名格式不正確: %s", path)
+	}
+
+	for name, want := range map[string]bool{
+		"0123456789abcdef0123456789abcdef.enc": true,
+		"0123456789ABCDEF0123456789ABCDEF.enc": false,
+		"0123456789abcdef0123456789abcdef.md":  false,
+		"日記.md.enc":                            false,
+		"0123456789abcdef.enc":                 false,
+	} {
+		if IsObfuscatedFileName(name) != want {
+			t.Errorf("IsObfuscatedFileName(%q) 應該為 %v", name, want)
+		}
+	}
+
+	if got := DecryptedFilePath("notes/日記.md.enc", "其他"); got != "notes/日記.md" {
+		t.Errorf("一般檔名只應移除 .enc: %s", got)
+	}
+	if got := DecryptedFilePath("notes/0123456789abcdef0123456789abcdef.enc", "會議/紀錄"); got != "notes/會議_紀錄.md" {
+		t.Errorf("隨機檔名應該改回以標題命名: %s", got)
+	}
+}
+
+// TestEditorServiceObfuscatedFilenames 測試編輯器以隨機檔名保存加密筆記並以中繼資料還原標題
+func TestEditorServiceObfuscatedFilenames(t *testing.T) {
+	service, mockRepo := createTestEditorService()
+	vault, _ := createTestVaultService(t)
+	service.SetOptions(EditorOptions{Vault: vault, ObfuscateFilenames: true})
+
+	note, _ := service.CreateNote("薪資談判", "內容")
+	note.FilePath = "work/薪資談判.md"
+	mockRepo.WriteFile(note.FilePath, []byte("內容"))
+	if err := service.(*editorService).EnableEncryption(note.ID, "TestPassword123!", AlgorithmAES256, false); err != nil {
+		t.Fatalf("啟用加密失敗: %v", err)
+	}
+	if err := service.SaveNote(note); err != nil {
+		t.Fatalf("保存加密筆記失敗: %v", err)
+	}
+
+	if filepath.Dir(note.FilePath) != "work" || !IsObfuscatedFileName(filepath.Base(note.FilePath)) {
+		t.Fatalf("應該改為同一目錄下的隨機檔名: %s", note.FilePath)
+	}
+	if _, exists := mockRepo.files["work/薪資談判.md.enc"]; exists {
+		t.Error("不應留下以標題命名的加密檔案")
+	}
+	if title, ok := service.(EncryptedTitleProvider).NoteDisplayTitle(note.FilePath); !ok || title != "薪資談判" {
+		t.Errorf("應該顯示真實標題: %q, %v", title, ok)
+	}
+
+	// 鎖定後無法取得標題，解鎖後從中繼資料標頭讀取
+	vault.Lock()
+	if _, ok := service.(EncryptedTitleProvider).NoteDisplayTitle(note.FilePath); ok {
+		t.Error("鎖定後不應能取得標題")
+	}
+	vault.Unlock("TestPassword123!")
+	if title, ok := service.(EncryptedTitleProvider).NoteDisplayTitle(note.FilePath); !ok || title != "薪資談判" {
+		t.Errorf("解鎖後應該能取得標題: %q, %v", title, ok)
+	}
+
+	opened, err := service.OpenNote(note.FilePath)
+	if err != nil {
+		t.Fatalf("開啟隨機檔名筆記失敗: %v", err)
+	}
+	if opened.Title != "薪資談判" || opened.Content != "內容" {
+		t.Errorf("開啟的筆記不符: %q, %q", opened.Title, opened.Content)
+	}
+
+	// 停用隨機檔名後，下次保存改回以標題命名
+	obfuscatedPath := opened.FilePath
+	service.SetOptions(EditorOptions{Vault: vault})
+	if err := service.SaveNote(opened); err != nil {
+		t.Fatalf("保存筆記失敗: %v", err)
+	}
+	if opened.FilePath != "work/薪資談判.md.enc" {
+		t.Errorf("應該改回以標題命名: %s", opened.FilePath)
+	}
+	if _, exists := mockRepo.files[obfuscatedPath]; exists {
+		t.Error("改名後不應留下隨機檔名的檔案")
+	}
+}
+
+// TestEditorServiceNoteMetadataTags 測試保存加密筆記時標籤寫入中繼資料標頭，開啟時從標頭還原
+func TestEditorServiceNoteMetadataTags(t *testing.T) {
+	service, mockRepo := createTestEditorService()
+	vault, _ := createTestVaultService(t)
+	service.SetOptions(EditorOptions{Vault: vault})
+
+	note, _ := service.CreateNote("預算", "年度預算")
+	note.FilePath = "預算.md"
+	note.Tags = []string{"工作", "財務"}
+	mockRepo.WriteFile(note.FilePath, []byte(note.Content))
+	if err := service.(*editorService).EnableEncryption(note.ID, "TestPassword123!", AlgorithmAES256, false); err != nil {
+		t.Fatalf("啟用加密失敗: %v", err)
+	}
+	if err := service.SaveNote(note); err != nil {
+		t.Fatalf("保存加密筆記失敗: %v", err)
+	}
+
+	meta, err := vault.ReadNoteMetadata(mockRepo.files[note.FilePath])
+	if err != nil || meta == nil || !reflect.DeepEqual(meta.Tags, []string{"工作", "財務"}) {
+		t.Fatalf("中繼資料標頭應該包含標籤: %+v, %v", meta, err)
+	}
+
+	service.CloseNote(note.ID)
+	opened, err := service.OpenNote(note.FilePath)
+	if err != nil {
+		t.Fatalf("開啟加密筆記失敗: %v", err)
+	}
+	if !reflect.DeepEqual(opened.Tags, []string{"工作", "財務"}) {
+		t.Errorf("開啟後應該還原標籤: %v", opened.Tags)
+	}
+
+	// 內容沒有 front matter 時由標頭提供標籤
+	restored := &models.Note{Title: "預算"}
+	meta.ApplyTo(restored)
+	if !reflect.DeepEqual(restored.Tags, []string{"工作", "財務"}) {
+		t.Errorf("ApplyTo 應該還原標籤: %v", restored.Tags)
+	}
+	restored.Tags[0] = "其他"
+	if meta.Tags[0] != "工作" {
+		t.Error("ApplyTo 應該複製標籤而非共用切片")
+	}
+}
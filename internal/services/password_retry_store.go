@@ -0,0 +1,248 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含密碼重試狀態的持久化儲存：
+// 狀態檔保存在筆記本的 .notebook 目錄並以 HMAC 驗證，HMAC 金鑰和最後寫入的驗證碼保存在金鑰庫，
+// 修改、刪除或還原舊的狀態檔，以及刪除金鑰庫中的防竄改資料，都會被視為竄改，不會重設重試次數
+package services
+
+import (
+	"crypto/hmac"     // HMAC 訊息驗證碼
+	"crypto/rand"     // 安全隨機數產生器
+	"crypto/sha256"   // SHA-256 雜湊演算法
+	"encoding/base64" // Base64 編碼
+	"encoding/hex"    // 狀態檔名稱
+	"encoding/json"   // JSON 序列化
+	"errors"          // 錯誤處理
+	"fmt"             // 格式化輸出
+	"io"              // 輸入輸出介面
+	"log"             // 日誌記錄
+	"path/filepath"   // 檔案路徑處理
+	"sync"            // 同步原語
+	"time"            // 時間處理
+
+	"mac-notebook-app/internal/repositories" // 引入資料存取層
+)
+
+// retryStateKeySize 重試狀態 HMAC 金鑰的長度（位元組）
+const retryStateKeySize = 32
+
+// ErrRetryStateTampered 表示重試狀態檔無法通過驗證
+var ErrRetryStateTampered = errors.New("密碼重試狀態檔已被修改或刪除")
+
+// retryState 代表持久化的重試狀態
+type retryState struct {
+	Entries       map[string]*RetryInfo `json:"entries"`                  // 各識別符的重試資訊
+	TamperedUntil time.Time             `json:"tampered_until,omitempty"` // 偵測到竄改後的全域鎖定到期時間
+	UpdatedAt     time.Time             `json:"updated_at"`               // 最後更新時間
+}
+
+// retryStateFile 代表狀態檔的內容
+type retryStateFile struct {
+	State json.RawMessage `json:"state"` // 序列化後的重試狀態
+	MAC   string          `json:"mac"`   // Base64 編碼的 HMAC-SHA256
+}
+
+// retryGuard 代表保存在金鑰庫中的防竄改資料
+// 同時接受前一次的驗證碼，避免寫入金鑰庫後、寫入狀態檔前當機被誤判為竄改
+type retryGuard struct {
+	Key         string `json:"key"`                    // Base64 編碼的 HMAC 金鑰
+	MAC         string `json:"mac,omitempty"`          // 最後寫入的狀態檔驗證碼
+	PreviousMAC string `json:"previous_mac,omitempty"` // 前一次寫入的狀態檔驗證碼
+}
+
+// retryStore 負責重試狀態的讀寫和驗證
+type retryStore struct {
+	fileRepo repositories.FileRepository       // 筆記本檔案儲存庫（保存狀態檔）
+	keyRepo  repositories.EncryptionRepository // 金鑰儲存庫（保存 HMAC 金鑰）
+	guard    *retryGuard                       // 目前的防竄改資料
+	key      []byte                            // HMAC 金鑰
+	mutex    sync.Mutex                        // 保護寫入順序
+}
+
+// NewPersistentPasswordService 建立會將重試狀態保存到磁碟的密碼服務
+// 參數：
+//   - fileRepo: 筆記本檔案儲存庫，重試狀態檔保存在其 .notebook 目錄
+//   - keyRepo: 金鑰儲存庫，保存驗證狀態檔的 HMAC 金鑰（應與筆記本分開存放）
+//
+// 回傳：PasswordService 介面實例
+//
+// 執行流程：
+// 1. 從金鑰庫載入（或建立）HMAC 金鑰
+// 2. 載入並驗證狀態檔，還原各識別符的重試次數和鎖定狀態
+// 3. 狀態檔被修改或刪除時，所有識別符鎖定 RetryTamperLockoutDuration 並寫入新的狀態
+func NewPersistentPasswordService(fileRepo repositories.FileRepository, keyRepo repositories.EncryptionRepository) PasswordService {
+	ps := &passwordService{
+		retryMap: make(map[string]*RetryInfo),
+		store: &retryStore{
+			fileRepo: fileRepo,
+			keyRepo:  keyRepo,
+		},
+	}
+
+	state, err := ps.store.load()
+	switch {
+	case err == nil:
+		if state.Entries != nil {
+			ps.retryMap = state.Entries
+		}
+		ps.tamperedUntil = state.TamperedUntil
+	case errors.Is(err, ErrRetryStateTampered):
+		log.Printf("%v，暫時鎖定所有密碼驗證 %v", err, RetryTamperLockoutDuration)
+		ps.tamperedUntil = time.Now().Add(RetryTamperLockoutDuration)
+		ps.persist()
+	default:
+		// 金鑰庫無法使用時退回只保存在記憶體
+		log.Printf("載入密碼重試狀態失敗，重試次數將不會保存: %v", err)
+		ps.store = nil
+	}
+
+	return ps
+}
+
+// load 載入並驗證重試狀態
+// 回傳：重試狀態和可能的錯誤（驗證失敗時回傳 ErrRetryStateTampered）
+func (s *retryStore) load() (*retryState, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.loadGuard(); err != nil {
+		return nil, err
+	}
+
+	path := s.statePath()
+	if !s.fileRepo.FileExists(path) {
+		if s.guard.MAC != "" {
+			return nil, ErrRetryStateTampered
+		}
+		return &retryState{Entries: make(map[string]*RetryInfo)}, nil
+	}
+
+	data, err := s.fileRepo.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("讀取重試狀態檔失敗: %w", err)
+	}
+
+	var file retryStateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, ErrRetryStateTampered
+	}
+	mac := s.computeMAC(file.State)
+	if !hmac.Equal([]byte(mac), []byte(file.MAC)) || (mac != s.guard.MAC && mac != s.guard.PreviousMAC) {
+		return nil, ErrRetryStateTampered
+	}
+
+	var state retryState
+	if err := json.Unmarshal(file.State, &state); err != nil {
+		return nil, ErrRetryStateTampered
+	}
+	return &state, nil
+}
+
+// save 以 HMAC 簽署並寫入重試狀態
+// 參數：state（重試狀態）
+// 回傳：可能的錯誤
+//
+// 先將新的驗證碼寫入金鑰庫（保留前一次的驗證碼）再寫入狀態檔，
+// 兩者之間當機時狀態檔仍符合前一次的驗證碼，不會被誤判為竄改
+func (s *retryStore) save(state *retryState) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stateData, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("序列化重試狀態失敗: %w", err)
+	}
+	mac := s.computeMAC(stateData)
+
+	guard := &retryGuard{Key: s.guard.Key, MAC: mac, PreviousMAC: s.guard.MAC}
+	if err := s.storeGuard(guard); err != nil {
+		return err
+	}
+
+	// 不使用縮排，避免重新排版 State 後與驗證碼不符
+	data, err := json.Marshal(&retryStateFile{State: stateData, MAC: mac})
+	if err != nil {
+		return fmt.Errorf("序列化重試狀態檔失敗: %w", err)
+	}
+	return s.fileRepo.WriteFile(s.statePath(), data)
+}
+
+// loadGuard 從金鑰庫載入防竄改資料，尚未建立時產生新的 HMAC 金鑰
+// 金鑰庫中沒有防竄改資料但 .notebook 已有狀態檔時，表示防竄改資料被刪除，視為竄改
+// 回傳：可能的錯誤（防竄改資料被刪除時回傳 ErrRetryStateTampered）
+func (s *retryStore) loadGuard() error {
+	data, err := s.keyRepo.GetRetryGuard()
+	if err != nil {
+		key := make([]byte, retryStateKeySize)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return fmt.Errorf("產生重試狀態金鑰失敗: %w", err)
+		}
+		s.key = key
+		if err := s.storeGuard(&retryGuard{Key: base64.StdEncoding.EncodeToString(key)}); err != nil {
+			return err
+		}
+		if s.hasStateFiles() {
+			return ErrRetryStateTampered
+		}
+		return nil
+	}
+
+	var guard retryGuard
+	if err := json.Unmarshal(data, &guard); err != nil {
+		return fmt.Errorf("解析重試狀態防竄改資料失敗: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(guard.Key)
+	if err != nil || len(key) != retryStateKeySize {
+		return errors.New("重試狀態金鑰格式無效")
+	}
+
+	s.guard = &guard
+	s.key = key
+	return nil
+}
+
+// hasStateFiles 檢查 .notebook 目錄中是否有任何重試狀態檔（包含以其他金鑰命名的狀態檔）
+// 回傳：是否有狀態檔
+func (s *retryStore) hasStateFiles() bool {
+	entries, err := s.fileRepo.ListDirectory(NotebookMetaDir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if matched, _ := filepath.Match("retry-state-*.json", entry.Name); matched && !entry.IsDirectory {
+			return true
+		}
+	}
+	return false
+}
+
+// storeGuard 將防竄改資料寫入金鑰庫
+// 參數：guard（防竄改資料）
+// 回傳：可能的錯誤
+func (s *retryStore) storeGuard(guard *retryGuard) error {
+	data, err := json.Marshal(guard)
+	if err != nil {
+		return fmt.Errorf("序列化重試狀態防竄改資料失敗: %w", err)
+	}
+	if err := s.keyRepo.StoreRetryGuard(data); err != nil {
+		return fmt.Errorf("儲存重試狀態防竄改資料失敗: %w", err)
+	}
+	s.guard = guard
+	return nil
+}
+
+// statePath 取得狀態檔路徑
+// 檔名包含金鑰識別碼，同步到其他電腦的筆記本不會與當地的狀態檔互相干擾
+// 回傳：相對於筆記本根目錄的路徑
+func (s *retryStore) statePath() string {
+	sum := sha256.Sum256(s.key)
+	return filepath.Join(NotebookMetaDir, "retry-state-"+hex.EncodeToString(sum[:4])+".json")
+}
+
+// computeMAC 計算狀態資料的 HMAC-SHA256
+// 參數：data（序列化後的狀態）
+// 回傳：Base64 編碼的驗證碼
+func (s *retryStore) computeMAC(data []byte) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(data)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,234 @@
+// Package services 提供密碼重試狀態持久化的單元測試
+// 測試重新啟動後保留鎖定、竄改偵測、指數退避，以及保險庫和筆記解密的重試限制
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"mac-notebook-app/internal/repositories"
+)
+
+// createTestRetryRepos 建立重試狀態測試使用的檔案儲存庫和金鑰儲存庫
+func createTestRetryRepos(t *testing.T) (*mockFileRepository, repositories.EncryptionRepository) {
+	keyRepo, err := repositories.NewLocalEncryptionRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("建立金鑰儲存庫失敗: %v", err)
+	}
+	return newMockFileRepository(), keyRepo
+}
+
+// failUntilLocked 連續記錄失敗直到鎖定，回傳鎖定錯誤
+func failUntilLocked(t *testing.T, ps PasswordService, identifier string) *LockoutError {
+	var lockErr *LockoutError
+	for i := 0; i < MaxRetryAttempts; i++ {
+		if err := ps.RecordFailedAttempt(identifier); err != nil && !errors.As(err, &lockErr) {
+			t.Fatalf("記錄失敗嘗試時發生非預期錯誤: %v", err)
+		}
+	}
+	if lockErr == nil {
+		t.Fatalf("失敗 %d 次後應該回傳 LockoutError", MaxRetryAttempts)
+	}
+	return lockErr
+}
+
+// TestPersistentPasswordServiceSurvivesRestart 測試重新建立服務後仍保留重試次數和鎖定
+func TestPersistentPasswordServiceSurvivesRestart(t *testing.T) {
+	fileRepo, keyRepo := createTestRetryRepos(t)
+
+	ps := NewPersistentPasswordService(fileRepo, keyRepo)
+	ps.RecordFailedAttempt("note.md")
+	failUntilLocked(t, ps, "vault")
+
+	restarted := NewPersistentPasswordService(fileRepo, keyRepo)
+	if locked, remaining := restarted.IsLocked("vault"); !locked || remaining <= 0 {
+		t.Error("重新啟動後應該仍然鎖定")
+	}
+	if info := restarted.GetRetryInfo("note.md"); info == nil || info.Attempts != 1 {
+		t.Errorf("重新啟動後應該保留重試次數: %+v", info)
+	}
+	if locked, _ := restarted.IsLocked("note.md"); locked {
+		t.Error("未達上限的識別符不應鎖定")
+	}
+
+	// 成功驗證後重設的狀態同樣會保存
+	restarted.ResetRetryCount("note.md")
+	if info := NewPersistentPasswordService(fileRepo, keyRepo).GetRetryInfo("note.md"); info != nil && info.Attempts != 0 {
+		t.Errorf("重設後的重試次數應該為 0，實際為 %d", info.Attempts)
+	}
+}
+
+// TestPersistentPasswordServiceTamper 測試修改、刪除或還原舊的狀態檔都會觸發全域鎖定
+func TestPersistentPasswordServiceTamper(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(fileRepo *mockFileRepository, path string, oldData []byte)
+	}{
+		{"修改狀態檔", func(fileRepo *mockFileRepository, path string, _ []byte) {
+			data := fileRepo.files[path]
+			data[len(data)/2] ^= 0x01
+			fileRepo.WriteFile(path, data)
+		}},
+		{"刪除狀態檔", func(fileRepo *mockFileRepository, path string, _ []byte) {
+			fileRepo.DeleteFile(path)
+		}},
+		{"還原舊的狀態檔", func(fileRepo *mockFileRepository, path string, oldData []byte) {
+			fileRepo.WriteFile(path, oldData)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileRepo, keyRepo := createTestRetryRepos(t)
+
+			ps := NewPersistentPasswordService(fileRepo, keyRepo)
+			ps.RecordFailedAttempt("vault")
+			path := ps.(*passwordService).store.statePath()
+			oldData := append([]byte(nil), fileRepo.files[path]...)
+			ps.RecordFailedAttempt("vault")
+			ps.RecordFailedAttempt("vault")
+			ps.ResetRetryCount("vault")
+
+			tt.tamper(fileRepo, path, oldData)
+
+			restarted := NewPersistentPasswordService(fileRepo, keyRepo)
+			locked, remaining := restarted.IsLocked("any-note.md")
+			if !locked || remaining <= RetryTamperLockoutDuration-time.Minute {
+				t.Errorf("竄改後所有識別符都應該鎖定約 %v: %v, %v", RetryTamperLockoutDuration, locked, remaining)
+			}
+
+			// 竄改鎖定也會保存，再次重新啟動不會解除
+			if locked, _ := NewPersistentPasswordService(fileRepo, keyRepo).IsLocked("vault"); !locked {
+				t.Error("再次重新啟動後竄改鎖定應該仍然有效")
+			}
+		})
+	}
+}
+
+// TestPersistentPasswordServiceGuardRemoved 測試刪除金鑰庫中的防竄改資料會觸發全域鎖定，
+// 沒有狀態檔的新筆記本則正常建立新的金鑰
+func TestPersistentPasswordServiceGuardRemoved(t *testing.T) {
+	fileRepo, err := repositories.NewLocalFileRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("建立檔案儲存庫失敗: %v", err)
+	}
+	keyRepo, err := repositories.NewLocalEncryptionRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("建立金鑰儲存庫失敗: %v", err)
+	}
+
+	ps := NewPersistentPasswordService(fileRepo, keyRepo)
+	if locked, _ := ps.IsLocked("vault"); locked {
+		t.Fatal("沒有狀態檔的新筆記本不應鎖定")
+	}
+	failUntilLocked(t, ps, "vault")
+
+	// 換成空的金鑰庫，相當於刪除防竄改資料後重新啟動
+	emptyKeyRepo, err := repositories.NewLocalEncryptionRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("建立金鑰儲存庫失敗: %v", err)
+	}
+	restarted := NewPersistentPasswordService(fileRepo, emptyKeyRepo)
+	locked, remaining := restarted.IsLocked("any-note.md")
+	if !locked || remaining <= RetryTamperLockoutDuration-time.Minute {
+		t.Errorf("刪除防竄改資料後所有識別符都應該鎖定約 %v: %v, %v", RetryTamperLockoutDuration, locked, remaining)
+	}
+	if locked, _ := NewPersistentPasswordService(fileRepo, emptyKeyRepo).IsLocked("vault"); !locked {
+		t.Error("再次重新啟動後竄改鎖定應該仍然有效")
+	}
+}
+
+// TestPasswordServiceExponentialBackoff 測試每次鎖定的時間加倍且不超過上限
+func TestPasswordServiceExponentialBackoff(t *testing.T) {
+	if retryLockoutDuration(1) != RetryLockoutDuration ||
+		retryLockoutDuration(2) != 2*RetryLockoutDuration ||
+		retryLockoutDuration(4) != 8*RetryLockoutDuration {
+		t.Error("鎖定時間應該每次加倍")
+	}
+	if retryLockoutDuration(100) != MaxRetryLockoutDuration {
+		t.Errorf("鎖定時間不應超過 %v", MaxRetryLockoutDuration)
+	}
+
+	ps := NewPasswordService()
+	first := failUntilLocked(t, ps, "note.md")
+	if first.Remaining != RetryLockoutDuration {
+		t.Errorf("第一次鎖定應該為 %v，實際為 %v", RetryLockoutDuration, first.Remaining)
+	}
+
+	// 模擬鎖定到期後再次失敗
+	ps.(*passwordService).retryMap["note.md"].LockedUntil = time.Now().Add(-time.Second)
+	var second *LockoutError
+	if err := ps.RecordFailedAttempt("note.md"); !errors.As(err, &second) || second.Remaining != 2*RetryLockoutDuration {
+		t.Errorf("鎖定到期後再次失敗應該鎖定 %v: %v", 2*RetryLockoutDuration, err)
+	}
+	if info := ps.GetRetryInfo("note.md"); info == nil || info.Lockouts != 2 {
+		t.Errorf("連續鎖定次數應該為 2: %+v", info)
+	}
+}
+
+// TestVaultUnlockRetryLockout 測試保險庫密碼錯誤次數過多時回傳 LockoutError，鎖定期間正確密碼也無法解鎖
+func TestVaultUnlockRetryLockout(t *testing.T) {
+	vault, _ := createTestVaultService(t)
+	if err := vault.Initialize("Password123!"); err != nil {
+		t.Fatalf("初始化保險庫失敗: %v", err)
+	}
+	vault.Lock()
+
+	ps := NewPasswordService()
+	vault.SetRetryGuard(ps)
+
+	// 成功解鎖會重設之前的失敗次數
+	vault.Unlock("WrongPassword!")
+	if err := vault.Unlock("Password123!"); err != nil {
+		t.Fatalf("解鎖失敗: %v", err)
+	}
+	if info := ps.GetRetryInfo(VaultRetryIdentifier); info != nil && info.Attempts != 0 {
+		t.Errorf("成功解鎖後應該重設重試次數，實際為 %d", info.Attempts)
+	}
+	vault.Lock()
+
+	var lockErr *LockoutError
+	for i := 1; i <= MaxRetryAttempts; i++ {
+		err := vault.Unlock("WrongPassword!")
+		if !errors.Is(err, ErrVaultWrongPassword) {
+			t.Errorf("第 %d 次應該回傳 ErrVaultWrongPassword: %v", i, err)
+		}
+		if errors.As(err, &lockErr) != (i == MaxRetryAttempts) {
+			t.Errorf("第 %d 次的鎖定狀態錯誤: %v", i, err)
+		}
+	}
+
+	err := vault.Unlock("Password123!")
+	if !errors.As(err, &lockErr) || lockErr.Remaining <= 0 {
+		t.Errorf("鎖定期間應該回傳 LockoutError: %v", err)
+	}
+	if vault.IsUnlocked() {
+		t.Error("鎖定期間不應解鎖")
+	}
+}
+
+// TestEditorServicePasswordNoteRetryLockout 測試密碼格式筆記以檔案路徑計算重試次數
+func TestEditorServicePasswordNoteRetryLockout(t *testing.T) {
+	service, mockRepo := createTestEditorService()
+	ps := NewPasswordService()
+	service.(*editorService).passwordSvc = ps
+	service.(*editorService).encryptionSvc = NewEncryptionService()
+
+	encrypted, err := NewEncryptionService().EncryptContent("機密內容", "Password123!", AlgorithmAES256)
+	if err != nil {
+		t.Fatalf("加密失敗: %v", err)
+	}
+	mockRepo.WriteFile("secret.md.enc", encrypted)
+
+	for i := 0; i < MaxRetryAttempts; i++ {
+		service.OpenNoteWithPassword("secret.md.enc", "WrongPassword!")
+	}
+	var lockErr *LockoutError
+	if _, err := service.OpenNoteWithPassword("secret.md.enc", "Password123!"); !errors.As(err, &lockErr) {
+		t.Errorf("鎖定期間應該回傳 LockoutError: %v", err)
+	}
+	if locked, _ := ps.IsLocked("secret.md.enc"); !locked {
+		t.Error("筆記路徑應該被鎖定")
+	}
+}
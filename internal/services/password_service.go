@@ -10,6 +10,7 @@ import (
 	"errors"          // 錯誤處理
 	"fmt"             // 格式化輸出
 	"golang.org/x/crypto/pbkdf2" // PBKDF2 金鑰衍生函數
+	"log"             // 日誌記錄
 	"strings"         // 字串處理
 	"sync"            // 同步原語
 	"time"            // 時間處理
@@ -21,7 +22,9 @@ const (
 	PasswordHashSize    = 64     // 密碼雜湊大小（位元組）
 	PasswordPBKDF2Rounds = 100000 // PBKDF2 迭代次數
 	MaxRetryAttempts    = 3      // 最大重試次數
-	RetryLockoutDuration = 5 * time.Minute // 重試鎖定時間
+	RetryLockoutDuration = 30 * time.Second // 第一次鎖定的時間，之後每次鎖定加倍
+	MaxRetryLockoutDuration = 24 * time.Hour // 指數退避的鎖定時間上限
+	RetryTamperLockoutDuration = time.Hour // 偵測到重試狀態被竄改時，所有識別符的鎖定時間
 )
 
 // PasswordHash 代表密碼雜湊資料結構
@@ -40,6 +43,19 @@ type RetryInfo struct {
 	Attempts    int       `json:"attempts"`     // 當前重試次數
 	LastAttempt time.Time `json:"last_attempt"` // 最後嘗試時間
 	LockedUntil time.Time `json:"locked_until"` // 鎖定到期時間
+	Lockouts    int       `json:"lockouts"`     // 連續鎖定次數（決定下次鎖定的時間）
+}
+
+// LockoutError 代表因密碼錯誤次數過多而暫時鎖定
+// 使用 errors.As 取得剩餘的等待時間
+type LockoutError struct {
+	Identifier string        // 被鎖定的識別符
+	Remaining  time.Duration // 剩餘鎖定時間
+}
+
+// Error 回傳包含剩餘等待時間的錯誤訊息
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("密碼錯誤次數過多，帳戶已鎖定，請在 %v 後重試", e.Remaining.Round(time.Second))
 }
 
 // PasswordService 定義密碼驗證服務的介面
@@ -109,8 +125,10 @@ func (ps PasswordStrength) String() string {
 // passwordService 實作 PasswordService 介面
 // 提供完整的密碼管理功能
 type passwordService struct {
-	retryMap map[string]*RetryInfo // 重試資訊映射表
-	mutex    sync.RWMutex          // 讀寫鎖保護並發存取
+	retryMap      map[string]*RetryInfo // 重試資訊映射表
+	mutex         sync.RWMutex          // 讀寫鎖保護並發存取
+	store         *retryStore           // 重試狀態的持久化儲存（nil 表示只保存在記憶體）
+	tamperedUntil time.Time             // 偵測到重試狀態被竄改時，所有識別符的鎖定到期時間
 }
 
 // NewPasswordService 建立新的密碼服務實例
@@ -327,8 +345,8 @@ func (ps *passwordService) CheckPasswordStrength(password string) (PasswordStren
 // 1. 取得或建立重試資訊
 // 2. 增加失敗次數
 // 3. 更新最後嘗試時間
-// 4. 檢查是否需要鎖定
-// 5. 更新鎖定狀態
+// 4. 達到最大次數時依連續鎖定次數以指數退避計算鎖定時間
+// 5. 寫入持久化儲存
 func (ps *passwordService) RecordFailedAttempt(identifier string) error {
 	if identifier == "" {
 		return errors.New("識別符不能為空")
@@ -350,21 +368,25 @@ func (ps *passwordService) RecordFailedAttempt(identifier string) error {
 	
 	// 檢查是否仍在鎖定期間
 	now := time.Now()
-	if now.Before(retryInfo.LockedUntil) {
-		return fmt.Errorf("帳戶已鎖定，請在 %v 後重試", retryInfo.LockedUntil.Sub(now).Round(time.Second))
+	if remaining := ps.lockRemaining(retryInfo, now); remaining > 0 {
+		return &LockoutError{Identifier: identifier, Remaining: remaining}
 	}
 	
 	// 增加失敗次數
 	retryInfo.Attempts++
 	retryInfo.LastAttempt = now
 	
-	// 檢查是否達到最大重試次數
+	// 檢查是否達到最大重試次數，鎖定後每次失敗都再次鎖定且時間加倍
+	var lockErr error
 	if retryInfo.Attempts >= MaxRetryAttempts {
-		retryInfo.LockedUntil = now.Add(RetryLockoutDuration)
-		return fmt.Errorf("密碼錯誤次數過多，帳戶已鎖定 %v", RetryLockoutDuration)
+		retryInfo.Lockouts++
+		duration := retryLockoutDuration(retryInfo.Lockouts)
+		retryInfo.LockedUntil = now.Add(duration)
+		lockErr = &LockoutError{Identifier: identifier, Remaining: duration}
 	}
 	
-	return nil
+	ps.persist()
+	return lockErr
 }
 
 // IsLocked 檢查是否因重試次數過多而被鎖定
@@ -380,17 +402,8 @@ func (ps *passwordService) IsLocked(identifier string) (bool, time.Duration) {
 	ps.mutex.RLock()
 	defer ps.mutex.RUnlock()
 	
-	retryInfo, exists := ps.retryMap[identifier]
-	if !exists {
-		return false, 0
-	}
-	
-	now := time.Now()
-	if now.Before(retryInfo.LockedUntil) {
-		return true, retryInfo.LockedUntil.Sub(now)
-	}
-	
-	return false, 0
+	remaining := ps.lockRemaining(ps.retryMap[identifier], time.Now())
+	return remaining > 0, remaining
 }
 
 // ResetRetryCount 重置重試計數（成功驗證後調用）
@@ -408,6 +421,8 @@ func (ps *passwordService) ResetRetryCount(identifier string) {
 	if exists {
 		retryInfo.Attempts = 0
 		retryInfo.LockedUntil = time.Time{}
+		retryInfo.Lockouts = 0
+		ps.persist()
 	}
 }
 
@@ -428,7 +443,80 @@ func (ps *passwordService) GetRetryInfo(identifier string) *RetryInfo {
 		Attempts:    retryInfo.Attempts,
 		LastAttempt: retryInfo.LastAttempt,
 		LockedUntil: retryInfo.LockedUntil,
+		Lockouts:    retryInfo.Lockouts,
+	}
+}
+
+// lockRemaining 計算識別符的剩餘鎖定時間，包含偵測到竄改時的全域鎖定
+// 參數：retryInfo（重試資訊，可為 nil）、now（目前時間）
+// 回傳：剩餘鎖定時間（未鎖定時為 0）
+func (ps *passwordService) lockRemaining(retryInfo *RetryInfo, now time.Time) time.Duration {
+	until := ps.tamperedUntil
+	if retryInfo != nil && retryInfo.LockedUntil.After(until) {
+		until = retryInfo.LockedUntil
+	}
+	if now.Before(until) {
+		return until.Sub(now)
+	}
+	return 0
+}
+
+// persist 將重試狀態寫入持久化儲存（呼叫者需持有寫入鎖）
+// 寫入失敗只記錄日誌，不影響記憶體中的鎖定狀態
+func (ps *passwordService) persist() {
+	if ps.store == nil {
+		return
+	}
+	state := &retryState{
+		Entries:       ps.retryMap,
+		TamperedUntil: ps.tamperedUntil,
+		UpdatedAt:     time.Now(),
+	}
+	if err := ps.store.save(state); err != nil {
+		log.Printf("保存密碼重試狀態失敗: %v", err)
+	}
+}
+
+// retryLockoutDuration 依連續鎖定次數計算指數退避的鎖定時間
+// 參數：lockouts（連續鎖定次數，從 1 開始）
+// 回傳：鎖定時間（不超過 MaxRetryLockoutDuration）
+func retryLockoutDuration(lockouts int) time.Duration {
+	duration := RetryLockoutDuration
+	for i := 1; i < lockouts; i++ {
+		duration *= 2
+		if duration >= MaxRetryLockoutDuration {
+			return MaxRetryLockoutDuration
+		}
+	}
+	return duration
+}
+
+// guardPasswordAttempt 在重試鎖定的保護下執行密碼驗證
+// 參數：
+//   - ps: 密碼服務（nil 時不限制重試次數）
+//   - identifier: 重試狀態的識別符（例如筆記路徑）
+//   - attempt: 實際驗證密碼的動作
+//   - isWrongPassword: 判斷錯誤是否為密碼錯誤（nil 表示所有錯誤都計為失敗）
+//
+// 回傳：驗證動作的錯誤；鎖定中或因本次失敗而鎖定時包含 *LockoutError
+func guardPasswordAttempt(ps PasswordService, identifier string, attempt func() error, isWrongPassword func(error) bool) error {
+	if ps == nil {
+		return attempt()
+	}
+	if locked, remaining := ps.IsLocked(identifier); locked {
+		return &LockoutError{Identifier: identifier, Remaining: remaining}
+	}
+
+	err := attempt()
+	switch {
+	case err == nil:
+		ps.ResetRetryCount(identifier)
+	case isWrongPassword == nil || isWrongPassword(err):
+		if lockErr := ps.RecordFailedAttempt(identifier); lockErr != nil {
+			return fmt.Errorf("%w；%w", err, lockErr)
+		}
 	}
+	return err
 }
 
 // isCommonWeakPassword 檢查是否為常見的弱密碼
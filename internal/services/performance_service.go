@@ -5,6 +5,8 @@ package services
 import (
 	"context"      // 上下文管理
 	"fmt"          // 格式化輸出
+	"io"           // 輸入輸出介面
+	"os"           // 作業系統檔案操作
 	"runtime"      // 執行時期資訊
 	"sync"         // 同步原語
 	"time"         // 時間處理
@@ -51,6 +53,8 @@ type PerformanceService interface {
 	// 大檔案處理優化
 	OptimizeForLargeFile(filePath string, size int64) error
 	ProcessLargeFileInChunks(filePath string, chunkSize int64, processor func([]byte) error) error
+	ProcessEncryptedFileInChunks(filePath string, chunkSize int64, opener StreamOpener, processor func([]byte) error) error
+	EncryptFileInChunks(srcPath, dstPath string, chunkSize int64, sealer StreamSealer) error
 	
 	// 快取管理
 	ClearCache() error
@@ -356,12 +360,25 @@ func (p *performanceService) OptimizeForLargeFile(filePath string, size int64) e
 // 1. 開啟檔案進行讀取
 // 2. 分塊讀取檔案內容
 // 3. 對每個分塊執行處理函數
-// 4. 監控記憶體使用情況
-// 5. 必要時執行垃圾回收
+// 4. 記錄處理時間
 func (p *performanceService) ProcessLargeFileInChunks(filePath string, chunkSize int64, processor func([]byte) error) error {
-	// 注意：這是一個簡化的實作
-	// 實際應用中需要整合檔案儲存庫來讀取檔案
-	
+	return p.ProcessEncryptedFileInChunks(filePath, chunkSize, nil, processor)
+}
+
+// ProcessEncryptedFileInChunks 分塊解密並處理串流加密的大檔案
+// 參數：
+//   - filePath: 檔案路徑
+//   - chunkSize: 分塊大小
+//   - opener: 將加密串流轉換為明文讀取器的函數（nil 表示檔案未加密）
+//   - processor: 處理函數，每次收到一個明文分塊（分塊緩衝區會被重複使用）
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 開啟檔案，需要時以 opener 包裝為逐段解密的讀取器
+// 2. 分塊讀取明文，整個檔案不會同時載入記憶體
+// 3. 對每個分塊執行處理函數，任何錯誤（包含驗證失敗）都會中止處理
+// 4. 記錄處理時間
+func (p *performanceService) ProcessEncryptedFileInChunks(filePath string, chunkSize int64, opener StreamOpener, processor func([]byte) error) error {
 	// 預設分塊大小為 1MB
 	if chunkSize <= 0 {
 		chunkSize = 1024 * 1024
@@ -370,12 +387,121 @@ func (p *performanceService) ProcessLargeFileInChunks(filePath string, chunkSize
 	// 記錄處理開始時間
 	startTime := time.Now()
 	
-	// 模擬分塊處理（實際實作需要讀取真實檔案）
-	// 這裡返回成功，實際實作需要完整的檔案讀取邏輯
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("開啟檔案失敗: %w", err)
+	}
+	defer file.Close()
+	
+	var reader io.Reader = file
+	if opener != nil {
+		reader, err = opener(file)
+		if err != nil {
+			return fmt.Errorf("開啟加密串流失敗: %w", err)
+		}
+	}
+	
+	buffer := make([]byte, chunkSize)
+	defer zeroBytes(buffer)
+	for {
+		n, readErr := io.ReadFull(reader, buffer)
+		if n > 0 {
+			if err := processor(buffer[:n]); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("讀取檔案失敗: %w", readErr)
+		}
+	}
 	
 	// 記錄處理時間
-	processingTime := time.Since(startTime)
-	p.recordFileReadTime(processingTime)
+	p.recordFileReadTime(time.Since(startTime))
+	
+	return nil
+}
+
+// EncryptFileInChunks 分塊讀取檔案並以串流格式加密寫入目的檔案
+// 參數：
+//   - srcPath: 明文來源檔案路徑
+//   - dstPath: 加密後的目的檔案路徑
+//   - chunkSize: 讀取分塊大小
+//   - sealer: 建立加密寫入器的函數
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 開啟來源檔案，並在目的檔案旁建立暫存檔
+// 2. 分塊讀取明文並寫入加密寫入器
+// 3. 寫入最後一段並同步到磁碟
+// 4. 以暫存檔取代目的檔案，中途失敗時不會留下不完整的加密檔案
+func (p *performanceService) EncryptFileInChunks(srcPath, dstPath string, chunkSize int64, sealer StreamSealer) error {
+	if chunkSize <= 0 {
+		chunkSize = 1024 * 1024
+	}
+	
+	startTime := time.Now()
+	
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("開啟檔案失敗: %w", err)
+	}
+	defer src.Close()
+	
+	tmpPath := dstPath + ".tmp"
+	dst, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("建立暫存檔失敗: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			dst.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+	
+	writer, err := sealer(dst)
+	if err != nil {
+		return fmt.Errorf("建立加密串流失敗: %w", err)
+	}
+	
+	// 不使用 io.CopyBuffer：*os.File 實作 io.WriterTo，會忽略指定的緩衝區和分塊大小
+	buffer := make([]byte, chunkSize)
+	defer zeroBytes(buffer)
+	for {
+		n, readErr := io.ReadFull(src, buffer)
+		if n > 0 {
+			if _, err := writer.Write(buffer[:n]); err != nil {
+				return fmt.Errorf("加密檔案失敗: %w", err)
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("讀取檔案失敗: %w", readErr)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("加密檔案失敗: %w", err)
+	}
+	if err := dst.Sync(); err != nil {
+		return fmt.Errorf("同步檔案失敗: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("關閉檔案失敗: %w", err)
+	}
+	if err := os.Rename(tmpPath, dstPath); err != nil {
+		os.Remove(tmpPath)
+		committed = true
+		return fmt.Errorf("取代檔案失敗: %w", err)
+	}
+	committed = true
+	
+	p.recordFileWriteTime(time.Since(startTime))
 	
 	return nil
 }
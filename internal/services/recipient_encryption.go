@@ -0,0 +1,304 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含以收件人公鑰加密筆記的格式（類似 age 的 X25519 收件人）：
+// 內容以隨機檔案金鑰加密，檔案金鑰再以每位收件人的公鑰分別包裝
+package services
+
+import (
+	"crypto/ecdh"     // X25519 金鑰交換
+	"crypto/rand"     // 安全隨機數產生
+	"crypto/sha256"   // SHA-256 雜湊演算法
+	"crypto/subtle"   // 常數時間比較
+	"encoding/base64" // Base64 編碼
+	"encoding/json"   // JSON 序列化
+	"errors"          // 錯誤處理
+	"fmt"             // 格式化輸出
+	"io"              // 輸入輸出介面
+	"strings"         // 字串處理
+
+	"golang.org/x/crypto/chacha20poly1305" // 包裝檔案金鑰使用的 AEAD
+	"golang.org/x/crypto/hkdf"             // HKDF 金鑰衍生
+)
+
+// 收件人格式相關常數
+const (
+	RecipientKeyPrefix   = "nbx25519:"                    // 收件人公鑰字串的前綴
+	maxRecipients        = 64                             // 單一檔案的收件人數量上限
+	recipientWrapInfo    = "notebook x25519 recipient v1" // 衍生包裝金鑰時使用的 HKDF 資訊字串
+	recipientContentAAD  = "notebook-recipients:"         // 內容加密時附加驗證資料的前綴
+	recipientFileKeySize = KeySize                        // 檔案金鑰大小
+)
+
+// ErrNoMatchingIdentity 沒有任何身分能解開檔案金鑰時回傳的錯誤
+var ErrNoMatchingIdentity = errors.New("此檔案不是加密給您的任何身分")
+
+// RecipientStanza 代表檔案金鑰為單一收件人包裝後的結果
+// 不記錄收件人的公鑰，開啟時以自己的私鑰逐一嘗試
+type RecipientStanza struct {
+	EphemeralKey string `json:"ephemeral_key"` // Base64 編碼的臨時 X25519 公鑰
+	WrappedKey   string `json:"wrapped_key"`   // Base64 編碼的包裝後檔案金鑰
+}
+
+// RecipientNoteInfo 代表收件人格式筆記的金鑰資訊
+// 重新保存時沿用同一組收件人和檔案金鑰，不需要收件人的公鑰
+type RecipientNoteInfo struct {
+	Algorithm  string            // 內容加密演算法
+	Recipients []RecipientStanza // 收件人包裝後的檔案金鑰
+	IdentityID string            // 解開檔案金鑰的身分 ID
+}
+
+// FormatRecipientKey 將 X25519 公鑰編碼為收件人公鑰字串
+// 參數：publicKey（X25519 公鑰）
+// 回傳：帶有前綴的收件人公鑰字串
+func FormatRecipientKey(publicKey *ecdh.PublicKey) string {
+	return RecipientKeyPrefix + base64.RawURLEncoding.EncodeToString(publicKey.Bytes())
+}
+
+// ParseRecipientKey 解析收件人公鑰字串
+// 參數：key（收件人公鑰字串）
+// 回傳：X25519 公鑰和可能的錯誤
+func ParseRecipientKey(key string) (*ecdh.PublicKey, error) {
+	key = strings.TrimSpace(key)
+	if !strings.HasPrefix(key, RecipientKeyPrefix) {
+		return nil, fmt.Errorf("收件人公鑰格式無效: %q", key)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(key, RecipientKeyPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("收件人公鑰格式無效: %w", err)
+	}
+	publicKey, err := ecdh.X25519().NewPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("收件人公鑰格式無效: %w", err)
+	}
+	return publicKey, nil
+}
+
+// IsRecipientData 檢查資料是否為收件人公鑰加密格式
+// 參數：data（檔案內容）
+// 回傳：是否為收件人格式
+func IsRecipientData(data []byte) bool {
+	var encData EncryptedData
+	if err := json.Unmarshal(data, &encData); err != nil {
+		return false
+	}
+	return encData.Version == EncryptedDataVersionRecipients && len(encData.Recipients) > 0
+}
+
+// sealForRecipients 以收件人公鑰加密內容
+// 參數：content（明文內容）、algorithm（加密演算法）、publicKeys（收件人公鑰）
+// 回傳：加密後的資料和可能的錯誤
+//
+// 執行流程：
+// 1. 產生隨機檔案金鑰
+// 2. 為每位收件人產生臨時金鑰對，以 X25519 和 HKDF 衍生包裝金鑰後包裝檔案金鑰
+// 3. 以檔案金鑰加密內容，收件人列表作為附加驗證資料
+func sealForRecipients(content, algorithm string, publicKeys []*ecdh.PublicKey) ([]byte, error) {
+	if len(publicKeys) == 0 {
+		return nil, errors.New("至少需要一位收件人")
+	}
+	if len(publicKeys) > maxRecipients {
+		return nil, fmt.Errorf("收件人數量超過上限: %d", maxRecipients)
+	}
+
+	fileKey := make([]byte, recipientFileKeySize)
+	if _, err := io.ReadFull(rand.Reader, fileKey); err != nil {
+		return nil, fmt.Errorf("產生檔案金鑰失敗: %w", err)
+	}
+	defer zeroBytes(fileKey)
+
+	stanzas := make([]RecipientStanza, 0, len(publicKeys))
+	for _, publicKey := range publicKeys {
+		stanza, err := wrapFileKey(fileKey, publicKey)
+		if err != nil {
+			return nil, err
+		}
+		stanzas = append(stanzas, *stanza)
+	}
+
+	return resealForRecipients(content, algorithm, stanzas, fileKey)
+}
+
+// resealForRecipients 以既有的檔案金鑰和收件人列表重新加密內容
+// 參數：content（明文內容）、algorithm（加密演算法）、stanzas（收件人列表）、fileKey（檔案金鑰）
+// 回傳：加密後的資料和可能的錯誤
+func resealForRecipients(content, algorithm string, stanzas []RecipientStanza, fileKey []byte) ([]byte, error) {
+	aad, err := recipientAAD(stanzas)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, nonce, err := sealWithAlgorithm(algorithm, fileKey, []byte(content), aad)
+	if err != nil {
+		return nil, fmt.Errorf("加密失敗: %w", err)
+	}
+
+	checksum := sha256.Sum256(ciphertext)
+	data, err := json.Marshal(EncryptedData{
+		Version:    EncryptedDataVersionRecipients,
+		Algorithm:  algorithm,
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Data:       base64.StdEncoding.EncodeToString(ciphertext),
+		Checksum:   base64.StdEncoding.EncodeToString(checksum[:]),
+		Recipients: stanzas,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("序列化加密資料失敗: %w", err)
+	}
+	return data, nil
+}
+
+// openForRecipient 以身分私鑰解密收件人格式的資料
+// 參數：data（加密資料）、privateKeys（身分 ID 對應的 X25519 私鑰）
+// 回傳：明文內容、收件人資訊、檔案金鑰（呼叫者負責清零）和可能的錯誤
+//
+// 執行流程：
+// 1. 解析收件人格式並驗證校驗和
+// 2. 以每個身分私鑰嘗試解開每位收件人的檔案金鑰
+// 3. 以檔案金鑰解密內容，收件人列表被修改時驗證失敗
+func openForRecipient(data []byte, privateKeys map[string]*ecdh.PrivateKey) (string, *RecipientNoteInfo, []byte, error) {
+	var encData EncryptedData
+	if err := json.Unmarshal(data, &encData); err != nil {
+		return "", nil, nil, fmt.Errorf("解析加密資料失敗: %w", err)
+	}
+	if encData.Version != EncryptedDataVersionRecipients || len(encData.Recipients) == 0 {
+		return "", nil, nil, fmt.Errorf("不支援的加密格式版本: %s", encData.Version)
+	}
+	if len(encData.Recipients) > maxRecipients {
+		return "", nil, nil, fmt.Errorf("收件人數量超過上限: %d", maxRecipients)
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(encData.Nonce)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("解碼隨機數失敗: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encData.Data)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("解碼加密內容失敗: %w", err)
+	}
+	expectedChecksum, err := base64.StdEncoding.DecodeString(encData.Checksum)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("解碼校驗和失敗: %w", err)
+	}
+	actualChecksum := sha256.Sum256(ciphertext)
+	if subtle.ConstantTimeCompare(expectedChecksum, actualChecksum[:]) != 1 {
+		return "", nil, nil, errors.New("資料校驗和不匹配，可能已被篡改")
+	}
+
+	identityID, fileKey := unwrapFileKey(encData.Recipients, privateKeys)
+	if fileKey == nil {
+		return "", nil, nil, ErrNoMatchingIdentity
+	}
+
+	aad, err := recipientAAD(encData.Recipients)
+	if err != nil {
+		zeroBytes(fileKey)
+		return "", nil, nil, err
+	}
+	plaintext, err := openWithAlgorithm(encData.Algorithm, fileKey, nonce, ciphertext, aad)
+	if err != nil {
+		zeroBytes(fileKey)
+		return "", nil, nil, fmt.Errorf("解密失敗: %w", err)
+	}
+
+	return string(plaintext), &RecipientNoteInfo{
+		Algorithm:  encData.Algorithm,
+		Recipients: encData.Recipients,
+		IdentityID: identityID,
+	}, fileKey, nil
+}
+
+// wrapFileKey 以收件人公鑰包裝檔案金鑰
+// 參數：fileKey（檔案金鑰）、publicKey（收件人公鑰）
+// 回傳：收件人包裝結果和可能的錯誤
+func wrapFileKey(fileKey []byte, publicKey *ecdh.PublicKey) (*RecipientStanza, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("產生臨時金鑰失敗: %w", err)
+	}
+
+	wrapKey, err := recipientWrapKey(ephemeral, publicKey, ephemeral.PublicKey(), publicKey)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(wrapKey)
+
+	aead, err := chacha20poly1305.New(wrapKey)
+	if err != nil {
+		return nil, fmt.Errorf("建立加密器失敗: %w", err)
+	}
+
+	// 每個包裝金鑰只使用一次，因此可使用固定的零隨機數
+	wrapped := aead.Seal(nil, make([]byte, aead.NonceSize()), fileKey, nil)
+	return &RecipientStanza{
+		EphemeralKey: base64.StdEncoding.EncodeToString(ephemeral.PublicKey().Bytes()),
+		WrappedKey:   base64.StdEncoding.EncodeToString(wrapped),
+	}, nil
+}
+
+// unwrapFileKey 以身分私鑰逐一嘗試解開檔案金鑰
+// 參數：stanzas（收件人列表）、privateKeys（身分 ID 對應的私鑰）
+// 回傳：成功的身分 ID 和檔案金鑰，沒有相符的身分時回傳 nil
+func unwrapFileKey(stanzas []RecipientStanza, privateKeys map[string]*ecdh.PrivateKey) (string, []byte) {
+	for _, stanza := range stanzas {
+		ephemeralBytes, err := base64.StdEncoding.DecodeString(stanza.EphemeralKey)
+		if err != nil {
+			continue
+		}
+		ephemeral, err := ecdh.X25519().NewPublicKey(ephemeralBytes)
+		if err != nil {
+			continue
+		}
+		wrapped, err := base64.StdEncoding.DecodeString(stanza.WrappedKey)
+		if err != nil {
+			continue
+		}
+
+		for identityID, privateKey := range privateKeys {
+			wrapKey, err := recipientWrapKey(privateKey, ephemeral, ephemeral, privateKey.PublicKey())
+			if err != nil {
+				continue
+			}
+			aead, err := chacha20poly1305.New(wrapKey)
+			zeroBytes(wrapKey)
+			if err != nil {
+				continue
+			}
+			fileKey, err := aead.Open(nil, make([]byte, aead.NonceSize()), wrapped, nil)
+			if err == nil && len(fileKey) == recipientFileKeySize {
+				return identityID, fileKey
+			}
+		}
+	}
+	return "", nil
+}
+
+// recipientWrapKey 以 X25519 共享秘密衍生包裝金鑰
+// 共享秘密和雙方公鑰一起輸入 HKDF，避免金鑰被搬移到其他收件人
+// 參數：privateKey（自己的私鑰）、peer（對方公鑰）、ephemeral（臨時公鑰）、recipient（收件人公鑰）
+// 回傳：包裝金鑰（呼叫者負責清零）和可能的錯誤
+func recipientWrapKey(privateKey *ecdh.PrivateKey, peer, ephemeral, recipient *ecdh.PublicKey) ([]byte, error) {
+	shared, err := privateKey.ECDH(peer)
+	if err != nil {
+		return nil, fmt.Errorf("金鑰交換失敗: %w", err)
+	}
+	defer zeroBytes(shared)
+
+	salt := append(append([]byte(nil), ephemeral.Bytes()...), recipient.Bytes()...)
+	wrapKey := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte(recipientWrapInfo)), wrapKey); err != nil {
+		return nil, fmt.Errorf("衍生包裝金鑰失敗: %w", err)
+	}
+	return wrapKey, nil
+}
+
+// recipientAAD 取得內容加密使用的附加驗證資料
+// 收件人列表綁定到內容，新增、移除或替換收件人都會讓解密失敗
+// 參數：stanzas（收件人列表）
+// 回傳：附加驗證資料和可能的錯誤
+func recipientAAD(stanzas []RecipientStanza) ([]byte, error) {
+	encoded, err := json.Marshal(stanzas)
+	if err != nil {
+		return nil, fmt.Errorf("序列化收件人列表失敗: %w", err)
+	}
+	return append([]byte(recipientContentAAD), encoded...), nil
+}
@@ -0,0 +1,368 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含未保存編輯的復原日誌：編輯器內容變更時每隔幾秒寫入筆記本的復原區，
+// 程式或系統當機後下次啟動時可以還原、比較或捨棄，加密筆記的內容以保險庫加密保存
+package services
+
+import (
+	"crypto/sha256" // 日誌檔名雜湊
+	"encoding/hex"  // 十六進位編碼
+	"encoding/json" // JSON 序列化
+	"fmt"           // 格式化輸出
+	"log"           // 日誌記錄
+	"path/filepath" // 檔案路徑處理
+	"sort"          // 排序
+	"strings"       // 字串處理
+	"sync"          // 同步控制
+	"time"          // 時間處理
+
+	"mac-notebook-app/internal/models"       // 引入資料模型
+	"mac-notebook-app/internal/repositories" // 引入儲存庫介面
+)
+
+// 復原日誌相關常數
+const (
+	RecoveryFlushInterval = 3 * time.Second // 編輯內容寫入復原區的間隔
+	recoveryEntryExt      = ".json"         // 復原日誌檔案的副檔名
+)
+
+// RecoveryJournalDir 復原區目錄，位於筆記本中繼資料目錄中
+var RecoveryJournalDir = filepath.Join(NotebookMetaDir, "recovery")
+
+// recoveryKeyIDPath 保存加密復原內容使用的資料金鑰 ID，避免每次啟動都產生新的資料金鑰
+var recoveryKeyIDPath = filepath.Join(RecoveryJournalDir, "key-id")
+
+// RecoveryEntry 代表一筆未保存編輯的復原記錄
+type RecoveryEntry struct {
+	NoteID    string    `json:"note_id"`           // 記錄時的筆記 ID（只在同一次執行中有效）
+	FilePath  string    `json:"file_path"`         // 筆記的檔案路徑，尚未保存過的新筆記為空字串
+	Title     string    `json:"title,omitempty"`   // 筆記標題，加密內容不記錄標題
+	Encrypted bool      `json:"encrypted"`         // 內容是否以保險庫加密
+	Content   string    `json:"content,omitempty"` // 未加密的編輯內容
+	Sealed    []byte    `json:"sealed,omitempty"`  // 以保險庫加密的編輯內容
+	UpdatedAt time.Time `json:"updated_at"`        // 最後記錄時間
+}
+
+// DisplayName 取得顯示用的筆記名稱
+// 回傳：標題，沒有標題時為檔案名稱
+func (e *RecoveryEntry) DisplayName() string {
+	if e.Title != "" {
+		return e.Title
+	}
+	if e.FilePath != "" {
+		return filepath.Base(e.FilePath)
+	}
+	return "未命名筆記"
+}
+
+// RecoveryJournal 定義未保存編輯復原日誌的介面
+type RecoveryJournal interface {
+	// SetVaultService 設定加密筆記內容使用的保險庫服務
+	// 參數：vault（保險庫服務）
+	SetVaultService(vault VaultService)
+
+	// Record 記錄筆記目前的編輯內容，稍後批次寫入復原區
+	// 參數：note（筆記）、content（編輯器中的內容）
+	Record(note *models.Note, content string)
+
+	// Flush 立即將尚未寫入的編輯內容寫入復原區
+	// 回傳：可能的錯誤
+	Flush() error
+
+	// MarkSaved 筆記保存後移除復原記錄，保存後又有新的編輯時保留
+	// 參數：noteID（筆記 ID）、content（已保存的內容）
+	// 回傳：可能的錯誤
+	MarkSaved(noteID, content string) error
+
+	// Discard 移除筆記的復原記錄（筆記關閉或使用者選擇捨棄時呼叫）
+	// 參數：noteID（筆記 ID）
+	// 回傳：可能的錯誤
+	Discard(noteID string) error
+
+	// Pending 取得復原區中的所有記錄
+	// 回傳：依時間由新到舊排序的復原記錄和可能的錯誤
+	Pending() ([]*RecoveryEntry, error)
+
+	// Content 取得復原記錄的編輯內容
+	// 參數：entry（復原記錄）
+	// 回傳：編輯內容和可能的錯誤（加密內容在保險庫鎖定時為 ErrVaultLocked）
+	Content(entry *RecoveryEntry) (string, error)
+}
+
+// recoveryJournal 實作 RecoveryJournal 介面
+type recoveryJournal struct {
+	fileRepo repositories.FileRepository // 檔案存取介面
+	vault    VaultService                // 保險庫服務
+	pending  map[string]*RecoveryEntry   // 尚未寫入的記錄，以筆記 ID 為鍵
+	written  map[string][32]byte         // 已寫入復原區的內容雜湊，以筆記 ID 為鍵
+	timer    *time.Timer                 // 下一次寫入的定時器
+	keyID    string                      // 加密復原內容使用的資料金鑰 ID
+	mutex    sync.Mutex                  // 保護記錄和寫入
+}
+
+// NewRecoveryJournal 建立未保存編輯的復原日誌
+// 參數：fileRepo（檔案存取介面）
+// 回傳：復原日誌實例，保險庫透過 SetVaultService 設定
+func NewRecoveryJournal(fileRepo repositories.FileRepository) RecoveryJournal {
+	return &recoveryJournal{
+		fileRepo: fileRepo,
+		pending:  make(map[string]*RecoveryEntry),
+		written:  make(map[string][32]byte),
+	}
+}
+
+// SetVaultService 設定加密筆記內容使用的保險庫服務
+// 參數：vault（保險庫服務）
+func (j *recoveryJournal) SetVaultService(vault VaultService) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.vault = vault
+}
+
+// Record 記錄筆記目前的編輯內容，稍後批次寫入復原區
+// 參數：note（筆記）、content（編輯器中的內容）
+//
+// 執行流程：
+// 1. 更新筆記待寫入的內容，同一筆記在寫入前只保留最新內容
+// 2. 還沒有排定寫入時，在 RecoveryFlushInterval 後寫入
+func (j *recoveryJournal) Record(note *models.Note, content string) {
+	if note == nil {
+		return
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.pending[note.ID] = &RecoveryEntry{
+		NoteID:    note.ID,
+		FilePath:  note.FilePath,
+		Title:     note.Title,
+		Encrypted: j.isSensitive(note, content),
+		Content:   content,
+		UpdatedAt: time.Now(),
+	}
+	if j.timer == nil {
+		j.timer = time.AfterFunc(RecoveryFlushInterval, func() {
+			if err := j.Flush(); err != nil {
+				log.Printf("寫入復原日誌失敗: %v", err)
+			}
+		})
+	}
+}
+
+// Flush 立即將尚未寫入的編輯內容寫入復原區
+// 回傳：第一個遇到的錯誤（其他記錄仍會寫入）
+//
+// 執行流程：
+// 1. 取消排定的寫入
+// 2. 需要加密的內容以保險庫加密，保險庫鎖定時略過，不以明文保存
+// 3. 逐筆寫入復原區
+func (j *recoveryJournal) Flush() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.timer != nil {
+		j.timer.Stop()
+		j.timer = nil
+	}
+
+	var firstErr error
+	for noteID, entry := range j.pending {
+		delete(j.pending, noteID)
+		if err := j.writeEntry(entry); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// MarkSaved 筆記保存後移除復原記錄，保存後又有新的編輯時保留
+// 參數：noteID（筆記 ID）、content（已保存的內容）
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 待寫入或已寫入的內容與保存的內容不同時，表示保存後又有編輯，保留記錄
+// 2. 否則移除記錄
+func (j *recoveryJournal) MarkSaved(noteID, content string) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	sum := sha256.Sum256([]byte(content))
+	if entry, ok := j.pending[noteID]; ok && entry.Content != content {
+		return nil
+	}
+	if written, ok := j.written[noteID]; ok && written != sum {
+		if _, pending := j.pending[noteID]; !pending {
+			return nil
+		}
+	}
+	return j.discard(noteID)
+}
+
+// Discard 移除筆記的復原記錄
+// 參數：noteID（筆記 ID）
+// 回傳：可能的錯誤
+func (j *recoveryJournal) Discard(noteID string) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.discard(noteID)
+}
+
+// discard 移除筆記待寫入和已寫入的復原記錄（呼叫端需持有鎖）
+// 參數：noteID（筆記 ID）
+// 回傳：可能的錯誤
+func (j *recoveryJournal) discard(noteID string) error {
+	delete(j.pending, noteID)
+	delete(j.written, noteID)
+	path := recoveryEntryPath(noteID)
+	if !j.fileRepo.FileExists(path) {
+		return nil
+	}
+	return j.fileRepo.DeleteFile(path)
+}
+
+// Pending 取得復原區中的所有記錄
+// 回傳：依時間由新到舊排序的復原記錄和可能的錯誤（無法解析的記錄會略過）
+func (j *recoveryJournal) Pending() ([]*RecoveryEntry, error) {
+	if !j.fileRepo.FileExists(RecoveryJournalDir) {
+		return nil, nil
+	}
+	infos, err := j.fileRepo.ListDirectory(RecoveryJournalDir)
+	if err != nil {
+		return nil, fmt.Errorf("讀取復原區失敗: %w", err)
+	}
+
+	var entries []*RecoveryEntry
+	for _, info := range infos {
+		if info.IsDirectory || !strings.HasSuffix(info.Name, recoveryEntryExt) {
+			continue
+		}
+		data, err := j.fileRepo.ReadFile(info.Path)
+		if err != nil {
+			log.Printf("讀取復原記錄 %s 失敗: %v", info.Name, err)
+			continue
+		}
+		var entry RecoveryEntry
+		if err := json.Unmarshal(data, &entry); err != nil || entry.NoteID == "" {
+			log.Printf("略過無法解析的復原記錄 %s: %v", info.Name, err)
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].UpdatedAt.After(entries[b].UpdatedAt)
+	})
+	return entries, nil
+}
+
+// Content 取得復原記錄的編輯內容
+// 參數：entry（復原記錄）
+// 回傳：編輯內容和可能的錯誤（加密內容在保險庫鎖定時為 ErrVaultLocked）
+func (j *recoveryJournal) Content(entry *RecoveryEntry) (string, error) {
+	if !entry.Encrypted {
+		return entry.Content, nil
+	}
+
+	j.mutex.Lock()
+	vault := j.vault
+	j.mutex.Unlock()
+	if vault == nil || !vault.IsUnlocked() {
+		return "", ErrVaultLocked
+	}
+
+	content, _, err := vault.DecryptNote(entry.Sealed)
+	if err != nil {
+		return "", fmt.Errorf("解密復原內容失敗: %w", err)
+	}
+	return content, nil
+}
+
+// writeEntry 將一筆記錄寫入復原區
+// 參數：entry（復原記錄，Content 為明文）
+// 回傳：可能的錯誤
+func (j *recoveryJournal) writeEntry(entry *RecoveryEntry) error {
+	sum := sha256.Sum256([]byte(entry.Content))
+	if entry.Encrypted {
+		sealed, err := j.sealEntryContent(entry.Content)
+		if err != nil || sealed == nil {
+			return err
+		}
+		entry.Sealed = sealed
+		entry.Content = ""
+		entry.Title = ""
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("序列化復原記錄失敗: %w", err)
+	}
+	if err := j.fileRepo.WriteFile(recoveryEntryPath(entry.NoteID), data); err != nil {
+		return err
+	}
+	j.written[entry.NoteID] = sum
+	return nil
+}
+
+// sealEntryContent 以保險庫加密復原內容
+// 參數：content（編輯內容）
+// 回傳：加密後的內容（保險庫鎖定時為 nil，不以明文保存）和可能的錯誤
+func (j *recoveryJournal) sealEntryContent(content string) ([]byte, error) {
+	if j.vault == nil || !j.vault.IsUnlocked() {
+		log.Printf("保險庫已鎖定，略過加密筆記的復原記錄")
+		return nil, nil
+	}
+	sealed, keyID, err := j.vault.EncryptNote(content, AlgorithmAES256, j.recoveryKeyID())
+	if err != nil && j.keyID != "" {
+		// 保存的資料金鑰已不存在（例如保險庫重新建立）時改用新的資料金鑰
+		sealed, keyID, err = j.vault.EncryptNote(content, AlgorithmAES256, "")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("加密復原內容失敗: %w", err)
+	}
+	j.saveRecoveryKeyID(keyID)
+	return sealed, nil
+}
+
+// isSensitive 檢查編輯內容是否需要加密保存
+// 參數：note（筆記）、content（編輯內容）
+// 回傳：加密筆記、含有機密區塊的內容和加密資料夾中的筆記都需要加密
+func (j *recoveryJournal) isSensitive(note *models.Note, content string) bool {
+	if note.IsEncrypted || HasSecretBlocks(content) {
+		return true
+	}
+	if encryptedRepo, ok := j.fileRepo.(EncryptedFileRepository); ok && note.FilePath != "" {
+		return encryptedRepo.IsEncryptedPath(note.FilePath)
+	}
+	return false
+}
+
+// recoveryKeyID 取得加密復原內容使用的資料金鑰 ID
+// 回傳：資料金鑰 ID，尚未產生時為空字串
+func (j *recoveryJournal) recoveryKeyID() string {
+	if j.keyID == "" && j.fileRepo.FileExists(recoveryKeyIDPath) {
+		if data, err := j.fileRepo.ReadFile(recoveryKeyIDPath); err == nil {
+			j.keyID = strings.TrimSpace(string(data))
+		}
+	}
+	return j.keyID
+}
+
+// saveRecoveryKeyID 保存加密復原內容使用的資料金鑰 ID
+// 參數：keyID（資料金鑰 ID）
+func (j *recoveryJournal) saveRecoveryKeyID(keyID string) {
+	if keyID == j.keyID {
+		return
+	}
+	j.keyID = keyID
+	if err := j.fileRepo.WriteFile(recoveryKeyIDPath, []byte(keyID)); err != nil {
+		log.Printf("保存復原日誌金鑰 ID 失敗: %v", err)
+	}
+}
+
+// recoveryEntryPath 取得筆記復原記錄的檔案路徑
+// 參數：noteID（筆記 ID）
+// 回傳：復原記錄的路徑（以雜湊命名，不洩漏筆記 ID 或標題）
+func recoveryEntryPath(noteID string) string {
+	sum := sha256.Sum256([]byte(noteID))
+	return filepath.Join(RecoveryJournalDir, hex.EncodeToString(sum[:8])+recoveryEntryExt)
+}
@@ -0,0 +1,156 @@
+// Package services 提供未保存編輯復原日誌的單元測試
+// 測試編輯內容寫入復原區、加密筆記的內容以密文保存，以及保存和捨棄後移除記錄
+package services
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mac-notebook-app/internal/models"
+	"mac-notebook-app/internal/repositories"
+)
+
+// createTestRecoveryJournal 建立以暫存目錄為基礎的復原日誌
+// 回傳：復原日誌、基礎目錄和保險庫服務
+func createTestRecoveryJournal(t *testing.T) (RecoveryJournal, string, VaultService) {
+	baseDir := t.TempDir()
+	fileRepo, err := repositories.NewLocalFileRepository(baseDir)
+	if err != nil {
+		t.Fatalf("建立檔案儲存庫失敗: %v", err)
+	}
+	vault, _ := createTestVaultService(t)
+	journal := NewRecoveryJournal(fileRepo)
+	journal.SetVaultService(vault)
+	return journal, baseDir, vault
+}
+
+// TestRecoveryJournalRecordAndRestore 測試未保存的編輯寫入復原區後，下次啟動可以取回
+func TestRecoveryJournalRecordAndRestore(t *testing.T) {
+	journal, baseDir, _ := createTestRecoveryJournal(t)
+	note := &models.Note{ID: "note-1", Title: "會議記錄", FilePath: "meeting.md"}
+
+	journal.Record(note, "# 第一版")
+	journal.Record(note, "# 第二版")
+	if entries, _ := journal.Pending(); len(entries) != 0 {
+		t.Errorf("寫入前復原區應該是空的，實際有 %d 筆", len(entries))
+	}
+	if err := journal.Flush(); err != nil {
+		t.Fatalf("寫入復原日誌失敗: %v", err)
+	}
+
+	// 模擬下次啟動：以新的復原日誌讀取復原區
+	fileRepo, _ := repositories.NewLocalFileRepository(baseDir)
+	restarted := NewRecoveryJournal(fileRepo)
+	entries, err := restarted.Pending()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("應該有 1 筆復原記錄: %v, %v", entries, err)
+	}
+	entry := entries[0]
+	if entry.FilePath != "meeting.md" || entry.DisplayName() != "會議記錄" || entry.Encrypted {
+		t.Errorf("復原記錄不正確: %+v", entry)
+	}
+	if content, err := restarted.Content(entry); err != nil || content != "# 第二版" {
+		t.Errorf("應該取回最新的編輯內容: %q, %v", content, err)
+	}
+
+	if err := restarted.Discard(entry.NoteID); err != nil {
+		t.Fatalf("捨棄復原記錄失敗: %v", err)
+	}
+	if entries, _ := restarted.Pending(); len(entries) != 0 {
+		t.Errorf("捨棄後不應有復原記錄，實際有 %d 筆", len(entries))
+	}
+}
+
+// TestRecoveryJournalMarkSaved 測試保存後移除復原記錄，保存後又有新的編輯時保留
+func TestRecoveryJournalMarkSaved(t *testing.T) {
+	journal, _, _ := createTestRecoveryJournal(t)
+	note := &models.Note{ID: "note-1", FilePath: "draft.md"}
+
+	journal.Record(note, "已保存的內容")
+	journal.Flush()
+	if err := journal.MarkSaved(note.ID, "已保存的內容"); err != nil {
+		t.Fatalf("移除復原記錄失敗: %v", err)
+	}
+	if entries, _ := journal.Pending(); len(entries) != 0 {
+		t.Errorf("保存後不應有復原記錄，實際有 %d 筆", len(entries))
+	}
+
+	// 保存的是較舊的內容（保存期間又有編輯）時保留記錄
+	journal.Record(note, "保存後的新編輯")
+	journal.Flush()
+	journal.MarkSaved(note.ID, "較舊的內容")
+	entries, _ := journal.Pending()
+	if len(entries) != 1 || entries[0].Content != "保存後的新編輯" {
+		t.Errorf("保存後的新編輯應該保留: %+v", entries)
+	}
+}
+
+// TestRecoveryJournalEncryptedNote 測試加密筆記的編輯以密文保存，保險庫鎖定時不寫入
+func TestRecoveryJournalEncryptedNote(t *testing.T) {
+	journal, baseDir, vault := createTestRecoveryJournal(t)
+	note := &models.Note{ID: "secret-1", Title: "機密計畫", FilePath: "plan.md.enc", IsEncrypted: true}
+
+	// 保險庫鎖定時略過，不以明文保存
+	journal.Record(note, "機密內容")
+	journal.Flush()
+	if entries, _ := journal.Pending(); len(entries) != 0 {
+		t.Errorf("保險庫鎖定時不應寫入加密筆記的復原記錄，實際有 %d 筆", len(entries))
+	}
+
+	vault.Initialize("Password123!")
+	journal.Record(note, "機密內容")
+	// 含有機密區塊的一般筆記同樣加密
+	journal.Record(&models.Note{ID: "note-2", FilePath: "public.md"}, "公開\n```"+SecretBlockLanguage+"\n密碼\n```")
+	if err := journal.Flush(); err != nil {
+		t.Fatalf("寫入復原日誌失敗: %v", err)
+	}
+
+	files, _ := os.ReadDir(filepath.Join(baseDir, RecoveryJournalDir))
+	for _, file := range files {
+		raw, _ := os.ReadFile(filepath.Join(baseDir, RecoveryJournalDir, file.Name()))
+		if bytes.Contains(raw, []byte("機密")) || bytes.Contains(raw, []byte("密碼")) {
+			t.Errorf("復原區不應包含明文: %s", raw)
+		}
+	}
+
+	entries, _ := journal.Pending()
+	if len(entries) != 2 {
+		t.Fatalf("應該有 2 筆復原記錄，實際有 %d 筆", len(entries))
+	}
+	for _, entry := range entries {
+		if !entry.Encrypted {
+			t.Errorf("%s 的復原記錄應該加密", entry.FilePath)
+		}
+	}
+
+	vault.Lock()
+	if _, err := journal.Content(entries[0]); !errors.Is(err, ErrVaultLocked) {
+		t.Errorf("保險庫鎖定時取得加密內容應該回傳 ErrVaultLocked: %v", err)
+	}
+	vault.Unlock("Password123!")
+	for _, entry := range entries {
+		content, err := journal.Content(entry)
+		if err != nil || !strings.Contains(content, "機密內容") && !strings.Contains(content, "密碼") {
+			t.Errorf("解鎖後應該取回編輯內容: %q, %v", content, err)
+		}
+	}
+}
+
+// TestDiffLines 測試以行為單位比較文字差異
+func TestDiffLines(t *testing.T) {
+	diff := FormatDiff(DiffLines("標題\n第一段\n第二段\n結尾", "標題\n第一段（修改）\n第二段\n新增段落\n結尾"))
+	expected := "  標題\n- 第一段\n+ 第一段（修改）\n  第二段\n+ 新增段落\n  結尾"
+	if diff != expected {
+		t.Errorf("差異結果不正確:\n%s", diff)
+	}
+
+	for _, line := range DiffLines("相同\n內容", "相同\n內容") {
+		if line.Op != DiffEqual {
+			t.Errorf("相同內容不應有差異: %+v", line)
+		}
+	}
+}
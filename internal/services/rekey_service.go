@@ -0,0 +1,585 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含批次重新加密（re-key）服務，以新密碼或新演算法重新加密筆記本中的所有加密筆記，
+// 並以日誌保證作業中斷時不會留下部分可讀、部分不可讀的檔案
+package services
+
+import (
+	"encoding/json" // JSON 序列化
+	"errors"        // 錯誤處理
+	"fmt"           // 格式化輸出
+	"path/filepath" // 檔案路徑處理
+	"strconv"       // 字串轉換
+	"strings"       // 字串處理
+	"sync"          // 同步原語
+	"time"          // 時間處理
+
+	"mac-notebook-app/internal/models"       // 引入資料模型
+	"mac-notebook-app/internal/repositories" // 引入資料存取層
+)
+
+// 重新加密日誌相關常數
+const (
+	RekeyPhasePrepare = "prepare" // 準備階段：新密文寫入暫存區，原檔案未變更
+	RekeyPhaseCommit  = "commit"  // 提交階段：開始以暫存區的新密文取代原檔案
+)
+
+// 重新加密日誌和暫存區的位置（相對於筆記本根目錄）
+var (
+	RekeyJournalPath = filepath.Join(NotebookMetaDir, "rekey-journal.json") // 重新加密日誌檔案
+	rekeyStagingDir  = filepath.Join(NotebookMetaDir, "rekey")              // 新密文暫存目錄
+)
+
+// ErrRekeyNothingToChange 新密碼和演算法都未指定時回傳的錯誤
+var ErrRekeyNothingToChange = errors.New("請指定新密碼或新的加密演算法")
+
+// ErrRekeyFilesFailed 有檔案無法重新加密且未指定略過失敗時回傳的錯誤，所有檔案和保險庫密碼維持原狀
+var ErrRekeyFilesFailed = errors.New("部分檔案無法重新加密，已還原所有變更")
+
+// RekeyOptions 定義批次重新加密的選項
+type RekeyOptions struct {
+	OldPassword string `json:"-"`         // 目前的密碼（保險庫密碼或筆記密碼）
+	NewPassword string `json:"-"`         // 新密碼，空字串表示沿用舊密碼
+	Algorithm   string `json:"algorithm"` // 新的加密演算法，空字串表示沿用原演算法
+	// SkipFailures 有檔案無法重新加密時仍提交其他檔案（失敗的檔案維持原密碼）；
+	// 預設為 false，任何檔案失敗都會還原整個作業，避免筆記本同時存在新舊密碼的檔案
+	SkipFailures bool `json:"skip_failures"`
+}
+
+// RekeyFileResult 代表單一檔案的重新加密結果
+type RekeyFileResult struct {
+	Path    string `json:"path"`            // 檔案路徑
+	Success bool   `json:"success"`         // 是否成功
+	Error   string `json:"error,omitempty"` // 失敗原因
+}
+
+// RekeyResult 代表批次重新加密的結果
+// 統計欄位與 BatchExportResult 相同，另外提供每個檔案的詳細結果
+type RekeyResult struct {
+	TotalFiles   int               `json:"total_files"`   // 總檔案數
+	SuccessCount int               `json:"success_count"` // 成功重新加密數量
+	FailureCount int               `json:"failure_count"` // 失敗數量
+	FailedFiles  []string          `json:"failed_files"`  // 失敗的檔案列表
+	Files        []RekeyFileResult `json:"files"`         // 每個檔案的結果
+	Recovered    bool              `json:"recovered"`     // 是否為中斷作業的復原結果
+	RolledBack   bool              `json:"rolled_back"`   // 中斷的作業是否已還原（所有檔案維持原密碼）
+	ElapsedTime  time.Duration     `json:"elapsed_time"`  // 耗費時間
+}
+
+// RekeyService 定義批次重新加密服務的介面
+type RekeyService interface {
+	// RekeyNotebook 以新密碼或新演算法重新加密指定目錄下的所有加密筆記
+	// 參數：rootPath（起始目錄，相對於筆記本根目錄，空字串表示根目錄）、options（重新加密選項）
+	// 回傳：重新加密結果和可能的錯誤（整體失敗時所有檔案維持原狀；
+	//       有檔案失敗且未指定 SkipFailures 時回傳每個檔案的結果和 ErrRekeyFilesFailed）
+	RekeyNotebook(rootPath string, options RekeyOptions) (*RekeyResult, error)
+
+	// HasPendingJournal 檢查是否有未完成的重新加密日誌
+	// 回傳：是否有未完成的作業
+	HasPendingJournal() bool
+
+	// Recover 依日誌完成或還原上次中斷的重新加密作業
+	// 回傳：復原結果（沒有未完成作業時為 nil）和可能的錯誤
+	Recover() (*RekeyResult, error)
+
+	// SetAuditService 設定記錄重新加密作業的稽核記錄服務
+	// 參數：audit（稽核記錄服務，nil 表示不記錄）
+	SetAuditService(audit AuditService)
+}
+
+// rekeyJournal 代表重新加密日誌
+// 準備階段中斷時依日誌刪除暫存檔和新資料金鑰（還原），
+// 提交階段中斷時依日誌完成剩餘的檔案取代（完成）
+type rekeyJournal struct {
+	StartedAt   time.Time           `json:"started_at"`             // 開始時間
+	Phase       string              `json:"phase"`                  // 目前階段
+	VaultHeader json.RawMessage     `json:"vault_header,omitempty"` // 以新密碼包裝的保險庫標頭（變更密碼時）
+	Entries     []rekeyJournalEntry `json:"entries"`                // 已寫入暫存區的檔案
+}
+
+// rekeyJournalEntry 代表日誌中的單一檔案
+type rekeyJournalEntry struct {
+	Path       string `json:"path"`                 // 原檔案路徑
+	StagedPath string `json:"staged_path"`          // 新密文的暫存路徑
+	OldKeyID   string `json:"old_key_id,omitempty"` // 原資料金鑰 ID（信封格式，提交後刪除）
+	NewKeyID   string `json:"new_key_id,omitempty"` // 新資料金鑰 ID（信封格式，還原時刪除）
+}
+
+// rekeyService 實作 RekeyService 介面
+type rekeyService struct {
+	fileRepo      repositories.FileRepository // 檔案存取介面
+	encryptionSvc EncryptionService           // 加密服務（密碼格式筆記）
+	vaultSvc      VaultService                // 保險庫服務（信封格式筆記，可選）
+	audit         AuditService                // 稽核記錄服務（可選）
+	mutex         sync.Mutex                  // 互斥鎖，避免同時執行多個作業
+}
+
+// NewRekeyService 建立新的批次重新加密服務實例
+// 參數：
+//   - fileRepo: 檔案存取介面（根目錄必須是筆記本根目錄）
+//   - encryptionSvc: 加密服務
+//   - vaultSvc: 保險庫服務（可選，nil 表示只處理密碼格式筆記）
+//
+// 回傳：RekeyService 介面實例
+func NewRekeyService(fileRepo repositories.FileRepository, encryptionSvc EncryptionService, vaultSvc VaultService) RekeyService {
+	return &rekeyService{
+		fileRepo:      fileRepo,
+		encryptionSvc: encryptionSvc,
+		vaultSvc:      vaultSvc,
+	}
+}
+
+// RekeyNotebook 以新密碼或新演算法重新加密所有加密筆記
+// 參數：rootPath（起始目錄）、options（重新加密選項）
+// 回傳：重新加密結果和可能的錯誤
+//
+// 執行流程：
+// 1. 驗證選項，並完成或還原上次中斷的作業
+// 2. 保險庫已設定時以舊密碼解鎖，變更密碼時預先產生新標頭
+// 3. 遍歷目錄收集所有 .enc 檔案並寫入準備階段日誌
+// 4. 逐一解密並以新憑證加密到暫存區，每完成一個檔案即更新日誌
+// 5. 有檔案失敗且未指定略過失敗時還原作業，回傳每個檔案的結果
+// 6. 將日誌切換為提交階段（提交點），之後中斷會在復原時完成
+// 7. 儲存新標頭、以暫存檔取代原檔案、刪除舊資料金鑰並移除日誌
+// 8. 將作業結果寫入稽核記錄
+func (s *rekeyService) RekeyNotebook(rootPath string, options RekeyOptions) (*RekeyResult, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	result, err := s.rekeyNotebook(rootPath, options)
+
+	detail := describeRekeyOptions(options)
+	if result != nil {
+		detail += fmt.Sprintf("，%d 個成功、%d 個失敗", result.SuccessCount, result.FailureCount)
+	}
+	recordAudit(s.audit, AuditEventRekey, rootPath, err, detail)
+
+	return result, err
+}
+
+// rekeyNotebook 執行批次重新加密（呼叫者必須持有鎖）
+// 參數：rootPath（起始目錄）、options（重新加密選項）
+// 回傳：重新加密結果和可能的錯誤
+func (s *rekeyService) rekeyNotebook(rootPath string, options RekeyOptions) (*RekeyResult, error) {
+	startTime := time.Now()
+
+	if err := s.validateOptions(options); err != nil {
+		return nil, err
+	}
+
+	// 先處理上次中斷的作業，避免兩份日誌互相干擾
+	if s.fileRepo.FileExists(RekeyJournalPath) {
+		if _, err := s.recover(); err != nil {
+			return nil, fmt.Errorf("無法復原上次中斷的重新加密作業: %w", err)
+		}
+	}
+
+	journal := &rekeyJournal{
+		StartedAt: startTime,
+		Phase:     RekeyPhasePrepare,
+		Entries:   make([]rekeyJournalEntry, 0),
+	}
+
+	// 保險庫筆記需要主金鑰，並以舊密碼驗證
+	if s.vaultSvc != nil && s.vaultSvc.IsInitialized() {
+		if err := s.vaultSvc.Unlock(options.OldPassword); err != nil {
+			return nil, fmt.Errorf("舊密碼無法解鎖保險庫: %w", err)
+		}
+		if options.NewPassword != "" {
+			header, err := s.vaultSvc.PrepareChangePassword(options.OldPassword, options.NewPassword)
+			if err != nil {
+				return nil, fmt.Errorf("準備保險庫新密碼失敗: %w", err)
+			}
+			journal.VaultHeader = header
+		}
+	}
+
+	paths, err := s.collectEncryptedFiles(rootPath)
+	if err != nil {
+		return nil, fmt.Errorf("遍歷筆記目錄失敗: %w", err)
+	}
+
+	result := &RekeyResult{
+		TotalFiles:  len(paths),
+		FailedFiles: make([]string, 0),
+		Files:       make([]RekeyFileResult, 0, len(paths)),
+	}
+
+	if err := s.saveJournal(journal); err != nil {
+		return nil, err
+	}
+
+	// 準備階段：原檔案不變，新密文寫入暫存區
+	for i, path := range paths {
+		entry, err := s.stageFile(path, i, options)
+		if err != nil {
+			result.FailureCount++
+			result.FailedFiles = append(result.FailedFiles, path)
+			result.Files = append(result.Files, RekeyFileResult{Path: path, Error: err.Error()})
+			continue
+		}
+
+		journal.Entries = append(journal.Entries, *entry)
+		if err := s.saveJournal(journal); err != nil {
+			s.rollback(journal)
+			return nil, err
+		}
+		result.Files = append(result.Files, RekeyFileResult{Path: path, Success: true})
+	}
+
+	// 部分檔案失敗時提交會讓筆記本混用新舊密碼，除非使用者明確選擇略過失敗的檔案
+	if result.FailureCount > 0 && !options.SkipFailures {
+		s.rollback(journal)
+		result.RolledBack = true
+		for i := range result.Files {
+			if result.Files[i].Success {
+				result.Files[i].Success = false
+				result.Files[i].Error = "其他檔案重新加密失敗，已還原為原密碼"
+			}
+		}
+		result.ElapsedTime = time.Since(startTime)
+		return result, ErrRekeyFilesFailed
+	}
+
+	// 提交點：日誌切換為提交階段後，中斷時會以新密文完成作業
+	journal.Phase = RekeyPhaseCommit
+	if err := s.saveJournal(journal); err != nil {
+		s.rollback(journal)
+		return nil, err
+	}
+
+	if err := s.commit(journal); err != nil {
+		return nil, fmt.Errorf("完成重新加密失敗，下次啟動時將自動完成: %w", err)
+	}
+
+	result.SuccessCount = len(journal.Entries)
+	result.ElapsedTime = time.Since(startTime)
+
+	return result, nil
+}
+
+// HasPendingJournal 檢查是否有未完成的重新加密日誌
+// 回傳：是否有未完成的作業
+func (s *rekeyService) HasPendingJournal() bool {
+	return s.fileRepo.FileExists(RekeyJournalPath)
+}
+
+// Recover 依日誌完成或還原上次中斷的重新加密作業
+// 回傳：復原結果（沒有未完成作業時為 nil）和可能的錯誤
+func (s *rekeyService) Recover() (*RekeyResult, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.fileRepo.FileExists(RekeyJournalPath) {
+		return nil, nil
+	}
+
+	result, err := s.recover()
+	detail := "復原中斷的作業"
+	if result != nil && result.RolledBack {
+		detail = "還原中斷的作業"
+	} else if result != nil {
+		detail = fmt.Sprintf("完成中斷的作業，%d 個檔案改用新密碼", result.SuccessCount)
+	}
+	recordAudit(s.audit, AuditEventRekey, "", err, detail)
+
+	return result, err
+}
+
+// SetAuditService 設定記錄重新加密作業的稽核記錄服務
+// 參數：audit（稽核記錄服務，nil 表示不記錄）
+func (s *rekeyService) SetAuditService(audit AuditService) {
+	s.audit = audit
+}
+
+// describeRekeyOptions 產生稽核記錄中的重新加密選項說明（不包含密碼）
+// 參數：options（重新加密選項）
+// 回傳：選項說明
+func describeRekeyOptions(options RekeyOptions) string {
+	var changes []string
+	if options.NewPassword != "" {
+		changes = append(changes, "變更密碼")
+	}
+	if options.Algorithm != "" {
+		changes = append(changes, "演算法改為 "+options.Algorithm)
+	}
+	if len(changes) == 0 {
+		return "未變更"
+	}
+	return strings.Join(changes, "、")
+}
+
+// recover 依日誌階段完成或還原作業（呼叫者必須持有鎖）
+// 回傳：復原結果和可能的錯誤
+//
+// 執行流程：
+// 1. 載入日誌，日誌無法解析時表示寫入準備階段日誌時中斷，清空暫存區即可
+// 2. 準備階段：刪除暫存檔和新資料金鑰，所有檔案維持原密碼
+// 3. 提交階段：完成剩餘的檔案取代，所有檔案改用新密碼
+func (s *rekeyService) recover() (*RekeyResult, error) {
+	startTime := time.Now()
+
+	journal, err := s.loadJournal()
+	if err != nil {
+		if err := s.clearStaging(); err != nil {
+			return nil, err
+		}
+		return &RekeyResult{
+			FailedFiles: make([]string, 0),
+			Files:       make([]RekeyFileResult, 0),
+			Recovered:   true,
+			RolledBack:  true,
+			ElapsedTime: time.Since(startTime),
+		}, nil
+	}
+
+	result := &RekeyResult{
+		TotalFiles:  len(journal.Entries),
+		FailedFiles: make([]string, 0),
+		Files:       make([]RekeyFileResult, 0, len(journal.Entries)),
+		Recovered:   true,
+	}
+
+	switch journal.Phase {
+	case RekeyPhaseCommit:
+		if err := s.commit(journal); err != nil {
+			return nil, err
+		}
+		result.SuccessCount = len(journal.Entries)
+		for _, entry := range journal.Entries {
+			result.Files = append(result.Files, RekeyFileResult{Path: entry.Path, Success: true})
+		}
+	default:
+		s.rollback(journal)
+		result.RolledBack = true
+		for _, entry := range journal.Entries {
+			result.Files = append(result.Files, RekeyFileResult{Path: entry.Path, Error: "作業中斷，已還原為原密碼"})
+			result.FailedFiles = append(result.FailedFiles, entry.Path)
+		}
+		result.FailureCount = len(journal.Entries)
+	}
+
+	result.ElapsedTime = time.Since(startTime)
+	return result, nil
+}
+
+// validateOptions 驗證重新加密選項
+// 參數：options（重新加密選項）
+// 回傳：可能的錯誤
+func (s *rekeyService) validateOptions(options RekeyOptions) error {
+	if options.OldPassword == "" {
+		return errors.New("目前的密碼不能為空")
+	}
+	if options.NewPassword == "" && options.Algorithm == "" {
+		return ErrRekeyNothingToChange
+	}
+	if options.Algorithm != "" && options.Algorithm != AlgorithmAES256 && options.Algorithm != AlgorithmChaCha20 {
+		return fmt.Errorf("不支援的加密演算法: %s", options.Algorithm)
+	}
+	if options.NewPassword != "" && !s.encryptionSvc.ValidatePassword(options.NewPassword) {
+		return errors.New("新密碼強度不足：需要 8-128 個字元，並包含大小寫字母、數字和特殊字元")
+	}
+	return nil
+}
+
+// collectEncryptedFiles 遍歷目錄收集所有加密檔案
+// 參數：rootPath（起始目錄）
+// 回傳：加密檔案路徑列表和可能的錯誤（略過 .notebook 中繼資料目錄）
+func (s *rekeyService) collectEncryptedFiles(rootPath string) ([]string, error) {
+	paths := make([]string, 0)
+
+	// 空字串代表筆記本根目錄
+	if rootPath == "" {
+		rootPath = "."
+	}
+
+	err := s.fileRepo.WalkDirectory(rootPath, func(info *models.FileInfo) error {
+		if info.IsDirectory {
+			if info.Name == NotebookMetaDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(info.Name, ".enc") {
+			paths = append(paths, info.Path)
+		}
+		return nil
+	})
+
+	return paths, err
+}
+
+// stageFile 解密單一檔案並以新憑證加密到暫存區
+// 參數：path（檔案路徑）、index（檔案序號，用於暫存檔名稱）、options（重新加密選項）
+// 回傳：日誌項目和可能的錯誤
+func (s *rekeyService) stageFile(path string, index int, options RekeyOptions) (*rekeyJournalEntry, error) {
+	data, err := s.fileRepo.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("讀取檔案失敗: %w", err)
+	}
+
+	entry := &rekeyJournalEntry{
+		Path:       path,
+		StagedPath: filepath.Join(rekeyStagingDir, strconv.Itoa(index)+".enc"),
+	}
+
+	var staged []byte
+	if s.vaultSvc != nil && s.vaultSvc.IsVaultData(data) {
+		content, info, err := s.vaultSvc.DecryptNote(data)
+		if err != nil {
+			return nil, fmt.Errorf("解密失敗: %w", err)
+		}
+
+		algorithm := options.Algorithm
+		if algorithm == "" {
+			algorithm = info.Algorithm
+		}
+
+		// 使用新的資料金鑰並保留中繼資料標頭，提交後刪除舊金鑰
+		staged, entry.NewKeyID, err = s.vaultSvc.EncryptNoteWithMetadata(content, algorithm, "", info.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("重新加密失敗: %w", err)
+		}
+		entry.OldKeyID = info.KeyID
+	} else {
+		content, err := s.encryptionSvc.DecryptContent(data, options.OldPassword, "")
+		if err != nil {
+			return nil, fmt.Errorf("解密失敗: %w", err)
+		}
+
+		algorithm := options.Algorithm
+		if algorithm == "" {
+			algorithm = encryptedDataAlgorithm(data)
+		}
+		password := options.NewPassword
+		if password == "" {
+			password = options.OldPassword
+		}
+
+		staged, err = s.encryptionSvc.EncryptContent(content, password, algorithm)
+		if err != nil {
+			return nil, fmt.Errorf("重新加密失敗: %w", err)
+		}
+	}
+
+	if err := s.fileRepo.WriteFile(entry.StagedPath, staged); err != nil {
+		if entry.NewKeyID != "" {
+			s.vaultSvc.DeleteNoteKey(entry.NewKeyID)
+		}
+		return nil, fmt.Errorf("寫入暫存檔失敗: %w", err)
+	}
+
+	return entry, nil
+}
+
+// commit 完成提交階段
+// 參數：journal（提交階段的日誌）
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 儲存以新密碼包裝的保險庫標頭
+// 2. 以暫存檔內容取代原檔案，再刪除暫存檔
+// 3. 刪除舊資料金鑰並移除日誌
+//
+// 提交階段不再寫入日誌，暫存檔是否存在即代表該檔案是否已完成，
+// 因此中斷後重複執行的結果相同，也不會因日誌寫入中斷而誤判為準備階段
+func (s *rekeyService) commit(journal *rekeyJournal) error {
+	if len(journal.VaultHeader) > 0 {
+		if s.vaultSvc == nil {
+			return errors.New("日誌包含保險庫標頭，但保險庫無法使用")
+		}
+		if err := s.vaultSvc.CommitHeader(journal.VaultHeader); err != nil {
+			return fmt.Errorf("儲存保險庫新標頭失敗: %w", err)
+		}
+	}
+
+	for _, entry := range journal.Entries {
+		// 暫存檔不存在表示先前已完成取代並刪除暫存檔
+		if !s.fileRepo.FileExists(entry.StagedPath) {
+			continue
+		}
+
+		staged, err := s.fileRepo.ReadFile(entry.StagedPath)
+		if err != nil {
+			return fmt.Errorf("讀取暫存檔 %s 失敗: %w", entry.StagedPath, err)
+		}
+		if err := s.fileRepo.WriteFile(entry.Path, staged); err != nil {
+			return fmt.Errorf("取代檔案 %s 失敗: %w", entry.Path, err)
+		}
+		if err := s.fileRepo.DeleteFile(entry.StagedPath); err != nil {
+			return fmt.Errorf("刪除暫存檔 %s 失敗: %w", entry.StagedPath, err)
+		}
+	}
+
+	if s.vaultSvc != nil {
+		for _, entry := range journal.Entries {
+			if entry.OldKeyID != "" {
+				s.vaultSvc.DeleteNoteKey(entry.OldKeyID)
+			}
+		}
+	}
+
+	return s.fileRepo.DeleteFile(RekeyJournalPath)
+}
+
+// rollback 還原準備階段的作業
+// 參數：journal（準備階段的日誌）
+// 刪除所有暫存檔和新資料金鑰，原檔案和保險庫標頭從未變更
+func (s *rekeyService) rollback(journal *rekeyJournal) {
+	for _, entry := range journal.Entries {
+		if s.fileRepo.FileExists(entry.StagedPath) {
+			s.fileRepo.DeleteFile(entry.StagedPath)
+		}
+		if entry.NewKeyID != "" && s.vaultSvc != nil {
+			s.vaultSvc.DeleteNoteKey(entry.NewKeyID)
+		}
+	}
+	if s.fileRepo.FileExists(RekeyJournalPath) {
+		s.fileRepo.DeleteFile(RekeyJournalPath)
+	}
+}
+
+// clearStaging 刪除暫存區的所有檔案和日誌
+// 回傳：可能的錯誤
+func (s *rekeyService) clearStaging() error {
+	if s.fileRepo.FileExists(rekeyStagingDir) {
+		entries, err := s.fileRepo.ListDirectory(rekeyStagingDir)
+		if err != nil {
+			return fmt.Errorf("讀取暫存區失敗: %w", err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDirectory {
+				s.fileRepo.DeleteFile(entry.Path)
+			}
+		}
+	}
+	return s.fileRepo.DeleteFile(RekeyJournalPath)
+}
+
+// saveJournal 將日誌寫入筆記本中繼資料目錄
+// 參數：journal（重新加密日誌）
+// 回傳：可能的錯誤
+func (s *rekeyService) saveJournal(journal *rekeyJournal) error {
+	data, err := json.Marshal(journal)
+	if err != nil {
+		return fmt.Errorf("序列化重新加密日誌失敗: %w", err)
+	}
+	if err := s.fileRepo.WriteFile(RekeyJournalPath, data); err != nil {
+		return fmt.Errorf("寫入重新加密日誌失敗: %w", err)
+	}
+	return nil
+}
+
+// loadJournal 從筆記本中繼資料目錄載入日誌
+// 回傳：重新加密日誌和可能的錯誤
+func (s *rekeyService) loadJournal() (*rekeyJournal, error) {
+	data, err := s.fileRepo.ReadFile(RekeyJournalPath)
+	if err != nil {
+		return nil, fmt.Errorf("讀取重新加密日誌失敗: %w", err)
+	}
+
+	var journal rekeyJournal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return nil, fmt.Errorf("解析重新加密日誌失敗: %w", err)
+	}
+	return &journal, nil
+}
@@ -0,0 +1,283 @@
+// Package services 提供批次重新加密服務的單元測試
+// 測試重新加密結果、單一檔案失敗，以及中斷後依日誌還原或完成作業
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mac-notebook-app/internal/repositories"
+)
+
+const (
+	rekeyOldPassword = "OldPassword123!"
+	rekeyNewPassword = "NewPassword456!"
+)
+
+// createTestRekeyService 建立使用真實檔案存取和保險庫的重新加密服務
+func createTestRekeyService(t *testing.T) (RekeyService, repositories.FileRepository, VaultService, EncryptionService) {
+	fileRepo, err := repositories.NewLocalFileRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("建立檔案存取失敗: %v", err)
+	}
+	vault, _ := createTestVaultService(t)
+	if err := vault.Initialize(rekeyOldPassword); err != nil {
+		t.Fatalf("初始化保險庫失敗: %v", err)
+	}
+	encryption := NewEncryptionService()
+	return NewRekeyService(fileRepo, encryption, vault), fileRepo, vault, encryption
+}
+
+// writeVaultNote 以保險庫加密內容並寫入檔案
+func writeVaultNote(t *testing.T, fileRepo repositories.FileRepository, vault VaultService, path, content string) {
+	data, _, err := vault.EncryptNote(content, AlgorithmAES256, "")
+	if err != nil {
+		t.Fatalf("保險庫加密失敗: %v", err)
+	}
+	if err := fileRepo.WriteFile(path, data); err != nil {
+		t.Fatalf("寫入檔案失敗: %v", err)
+	}
+}
+
+// writePasswordNote 以密碼加密內容並寫入檔案
+func writePasswordNote(t *testing.T, fileRepo repositories.FileRepository, encryption EncryptionService, path, content, password string) {
+	data, err := encryption.EncryptContent(content, password, AlgorithmAES256)
+	if err != nil {
+		t.Fatalf("密碼加密失敗: %v", err)
+	}
+	if err := fileRepo.WriteFile(path, data); err != nil {
+		t.Fatalf("寫入檔案失敗: %v", err)
+	}
+}
+
+// TestRekeyNotebook 測試以新密碼和新演算法重新加密所有加密筆記
+func TestRekeyNotebook(t *testing.T) {
+	service, fileRepo, vault, encryption := createTestRekeyService(t)
+
+	writeVaultNote(t, fileRepo, vault, "vault.enc", "保險庫內容")
+	writePasswordNote(t, fileRepo, encryption, "sub/password.enc", "密碼內容", rekeyOldPassword)
+	writePasswordNote(t, fileRepo, encryption, "other.enc", "其他密碼", "Different123!")
+	fileRepo.WriteFile("plain.md", []byte("一般筆記"))
+
+	result, err := service.RekeyNotebook("", RekeyOptions{
+		OldPassword:  rekeyOldPassword,
+		NewPassword:  rekeyNewPassword,
+		Algorithm:    AlgorithmChaCha20,
+		SkipFailures: true,
+	})
+	if err != nil {
+		t.Fatalf("重新加密失敗: %v", err)
+	}
+
+	if result.TotalFiles != 3 || result.SuccessCount != 2 || result.FailureCount != 1 {
+		t.Errorf("統計不正確: %+v", result)
+	}
+	if len(result.FailedFiles) != 1 || result.FailedFiles[0] != "other.enc" {
+		t.Errorf("失敗檔案列表不正確: %v", result.FailedFiles)
+	}
+	if service.HasPendingJournal() {
+		t.Error("完成後不應留下重新加密日誌")
+	}
+
+	// 舊密碼不再能解鎖保險庫，新密碼可以
+	vault.Lock()
+	if err := vault.Unlock(rekeyOldPassword); err == nil {
+		t.Error("舊密碼不應再能解鎖保險庫")
+	}
+	if err := vault.Unlock(rekeyNewPassword); err != nil {
+		t.Fatalf("新密碼應該能解鎖保險庫: %v", err)
+	}
+
+	data, _ := fileRepo.ReadFile("vault.enc")
+	content, info, err := vault.DecryptNote(data)
+	if err != nil || string(content) != "保險庫內容" {
+		t.Fatalf("保險庫筆記解密失敗: %v", err)
+	}
+	if info.Algorithm != AlgorithmChaCha20 {
+		t.Errorf("保險庫筆記演算法應該改為 %s，實際: %s", AlgorithmChaCha20, info.Algorithm)
+	}
+
+	data, _ = fileRepo.ReadFile("sub/password.enc")
+	if _, err := encryption.DecryptContent(data, rekeyOldPassword, ""); err == nil {
+		t.Error("舊密碼不應再能解密密碼筆記")
+	}
+	content, err = encryption.DecryptContent(data, rekeyNewPassword, "")
+	if err != nil || string(content) != "密碼內容" {
+		t.Fatalf("新密碼應該能解密密碼筆記: %v", err)
+	}
+	if encryptedDataAlgorithm(data) != AlgorithmChaCha20 {
+		t.Errorf("密碼筆記演算法應該改為 %s", AlgorithmChaCha20)
+	}
+
+	// 失敗的檔案維持原狀
+	data, _ = fileRepo.ReadFile("other.enc")
+	if _, err := encryption.DecryptContent(data, "Different123!", ""); err != nil {
+		t.Errorf("失敗的檔案應該維持原密碼: %v", err)
+	}
+}
+
+// TestRekeyNotebookRollsBackOnFailure 測試有檔案無法重新加密時還原整個作業，保險庫密碼和所有檔案維持原狀
+func TestRekeyNotebookRollsBackOnFailure(t *testing.T) {
+	service, fileRepo, vault, encryption := createTestRekeyService(t)
+	writeVaultNote(t, fileRepo, vault, "vault.enc", "保險庫內容")
+	writePasswordNote(t, fileRepo, encryption, "password.enc", "密碼內容", rekeyOldPassword)
+	writePasswordNote(t, fileRepo, encryption, "other.enc", "其他密碼", "Different123!")
+	originalVault, _ := fileRepo.ReadFile("vault.enc")
+	originalPassword, _ := fileRepo.ReadFile("password.enc")
+
+	result, err := service.RekeyNotebook("", RekeyOptions{OldPassword: rekeyOldPassword, NewPassword: rekeyNewPassword})
+	if !errors.Is(err, ErrRekeyFilesFailed) {
+		t.Fatalf("有檔案失敗時應該回傳 ErrRekeyFilesFailed，實際: %v", err)
+	}
+	if result == nil || !result.RolledBack || result.SuccessCount != 0 || result.FailureCount != 1 || len(result.Files) != 3 {
+		t.Fatalf("應該回傳每個檔案的結果並標示已還原: %+v", result)
+	}
+	for _, file := range result.Files {
+		if file.Success || file.Error == "" {
+			t.Errorf("還原後不應有成功的檔案: %+v", file)
+		}
+	}
+	if service.HasPendingJournal() {
+		t.Error("還原後不應留下重新加密日誌")
+	}
+
+	// 保險庫密碼未變更
+	vault.Lock()
+	if err := vault.Unlock(rekeyNewPassword); err == nil {
+		t.Error("還原後新密碼不應能解鎖保險庫")
+	}
+	if err := vault.Unlock(rekeyOldPassword); err != nil {
+		t.Fatalf("還原後舊密碼應該仍能解鎖保險庫: %v", err)
+	}
+
+	if data, _ := fileRepo.ReadFile("vault.enc"); string(data) != string(originalVault) {
+		t.Error("還原後保險庫筆記不應變更")
+	}
+	if data, _ := fileRepo.ReadFile("password.enc"); string(data) != string(originalPassword) {
+		t.Error("還原後密碼筆記不應變更")
+	}
+}
+
+// TestRekeyNotebookWrongPassword 測試舊密碼錯誤時不變更任何檔案
+func TestRekeyNotebookWrongPassword(t *testing.T) {
+	service, fileRepo, vault, _ := createTestRekeyService(t)
+	writeVaultNote(t, fileRepo, vault, "vault.enc", "保險庫內容")
+	original, _ := fileRepo.ReadFile("vault.enc")
+
+	if _, err := service.RekeyNotebook("", RekeyOptions{OldPassword: "Wrong123!", NewPassword: rekeyNewPassword}); err == nil {
+		t.Fatal("舊密碼錯誤時應該回傳錯誤")
+	}
+
+	data, _ := fileRepo.ReadFile("vault.enc")
+	if string(data) != string(original) {
+		t.Error("舊密碼錯誤時不應變更任何檔案")
+	}
+
+	if _, err := service.RekeyNotebook("", RekeyOptions{OldPassword: rekeyOldPassword}); !errors.Is(err, ErrRekeyNothingToChange) {
+		t.Errorf("未指定新密碼和演算法應該回傳 ErrRekeyNothingToChange，實際: %v", err)
+	}
+	if _, err := service.RekeyNotebook("", RekeyOptions{OldPassword: rekeyOldPassword, NewPassword: "weak"}); err == nil {
+		t.Error("新密碼強度不足時應該回傳錯誤")
+	}
+}
+
+// TestRekeyRecoverPrepare 測試準備階段中斷時還原為原密碼
+func TestRekeyRecoverPrepare(t *testing.T) {
+	service, fileRepo, _, encryption := createTestRekeyService(t)
+	writePasswordNote(t, fileRepo, encryption, "note.enc", "原內容", rekeyOldPassword)
+
+	// 模擬準備階段寫入暫存檔後中斷
+	staged, _ := encryption.EncryptContent("原內容", rekeyNewPassword, AlgorithmAES256)
+	stagedPath := filepath.Join(rekeyStagingDir, "0.enc")
+	fileRepo.WriteFile(stagedPath, staged)
+	journal, _ := json.Marshal(rekeyJournal{
+		StartedAt: time.Now(),
+		Phase:     RekeyPhasePrepare,
+		Entries:   []rekeyJournalEntry{{Path: "note.enc", StagedPath: stagedPath}},
+	})
+	fileRepo.WriteFile(RekeyJournalPath, journal)
+
+	if !service.HasPendingJournal() {
+		t.Fatal("應該偵測到未完成的重新加密日誌")
+	}
+
+	result, err := service.Recover()
+	if err != nil {
+		t.Fatalf("復原失敗: %v", err)
+	}
+	if !result.Recovered || !result.RolledBack || result.FailureCount != 1 {
+		t.Errorf("準備階段應該還原: %+v", result)
+	}
+	if service.HasPendingJournal() || fileRepo.FileExists(stagedPath) {
+		t.Error("還原後不應留下日誌或暫存檔")
+	}
+
+	data, _ := fileRepo.ReadFile("note.enc")
+	if _, err := encryption.DecryptContent(data, rekeyOldPassword, ""); err != nil {
+		t.Errorf("還原後應該維持原密碼: %v", err)
+	}
+}
+
+// TestRekeyRecoverCommit 測試提交階段中斷時完成剩餘的檔案取代
+func TestRekeyRecoverCommit(t *testing.T) {
+	service, fileRepo, _, encryption := createTestRekeyService(t)
+	writePasswordNote(t, fileRepo, encryption, "a.enc", "甲", rekeyOldPassword)
+	writePasswordNote(t, fileRepo, encryption, "b.enc", "乙", rekeyOldPassword)
+
+	// 模擬提交階段已取代 a.enc 後中斷
+	newA, _ := encryption.EncryptContent("甲", rekeyNewPassword, AlgorithmAES256)
+	newB, _ := encryption.EncryptContent("乙", rekeyNewPassword, AlgorithmAES256)
+	fileRepo.WriteFile("a.enc", newA)
+	stagedB := filepath.Join(rekeyStagingDir, "1.enc")
+	fileRepo.WriteFile(stagedB, newB)
+	journal, _ := json.Marshal(rekeyJournal{
+		StartedAt: time.Now(),
+		Phase:     RekeyPhaseCommit,
+		Entries: []rekeyJournalEntry{
+			{Path: "a.enc", StagedPath: filepath.Join(rekeyStagingDir, "0.enc")},
+			{Path: "b.enc", StagedPath: stagedB},
+		},
+	})
+	fileRepo.WriteFile(RekeyJournalPath, journal)
+
+	result, err := service.Recover()
+	if err != nil {
+		t.Fatalf("復原失敗: %v", err)
+	}
+	if result.RolledBack || result.SuccessCount != 2 {
+		t.Errorf("提交階段應該完成作業: %+v", result)
+	}
+
+	for path, want := range map[string]string{"a.enc": "甲", "b.enc": "乙"} {
+		data, _ := fileRepo.ReadFile(path)
+		content, err := encryption.DecryptContent(data, rekeyNewPassword, "")
+		if err != nil || string(content) != want {
+			t.Errorf("%s 應該改用新密碼: %v", path, err)
+		}
+	}
+	if service.HasPendingJournal() {
+		t.Error("完成後不應留下重新加密日誌")
+	}
+}
+
+// TestRekeyRecoverCorruptJournal 測試日誌寫入中斷（無法解析）時清空暫存區
+func TestRekeyRecoverCorruptJournal(t *testing.T) {
+	service, fileRepo, _, _ := createTestRekeyService(t)
+	stagedPath := filepath.Join(rekeyStagingDir, "0.enc")
+	fileRepo.WriteFile(stagedPath, []byte("staged"))
+	fileRepo.WriteFile(RekeyJournalPath, []byte(`{"phase":"prep`))
+
+	result, err := service.Recover()
+	if err != nil {
+		t.Fatalf("復原失敗: %v", err)
+	}
+	if !result.RolledBack {
+		t.Error("無法解析的日誌應該視為還原")
+	}
+	if service.HasPendingJournal() || fileRepo.FileExists(stagedPath) {
+		t.Error("還原後不應留下日誌或暫存檔")
+	}
+}
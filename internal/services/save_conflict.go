@@ -0,0 +1,309 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含保存前的外部變更偵測：開啟筆記時記錄檔案指紋，保存前檔案已被其他工具、
+// 同步服務或 git 修改時不覆寫，改由使用者選擇保留我的版本、採用磁碟上的版本或三方合併
+package services
+
+import (
+	"crypto/sha256" // 檔案內容雜湊
+	"encoding/hex"  // 十六進位編碼
+	"errors"        // 錯誤處理
+	"fmt"           // 格式化輸出
+	"log"           // 日誌記錄
+	"path/filepath" // 檔案路徑處理
+	"time"          // 時間處理
+
+	"mac-notebook-app/internal/models" // 引入資料模型
+)
+
+// ErrSaveConflict 保存時檔案已在外部變更
+var ErrSaveConflict = errors.New("檔案已在外部變更")
+
+// ConflictResolution 保存衝突的處理方式
+type ConflictResolution int
+
+// 保存衝突處理方式常數
+const (
+	ConflictKeepMine   ConflictResolution = iota // 以編輯器中的內容覆寫磁碟上的版本
+	ConflictKeepTheirs                           // 捨棄編輯，改用磁碟上的版本
+	ConflictMerge                                // 以開啟時的內容為共同基準進行三方合併
+)
+
+// FileFingerprint 檔案在磁碟上的指紋
+type FileFingerprint struct {
+	Hash    string    // 檔案內容的 SHA-256 雜湊
+	Size    int64     // 檔案大小（位元組）
+	ModTime time.Time // 最後修改時間（無法取得時為零值）
+}
+
+// SaveConflict 描述一次保存衝突
+type SaveConflict struct {
+	NoteID      string          // 筆記 ID
+	FilePath    string          // 檔案路徑
+	Base        string          // 開啟或上次保存時的內容（三方合併的共同基準）
+	Mine        string          // 編輯器中的內容
+	Theirs      string          // 磁碟上目前的內容
+	TheirsError error           // 無法讀取磁碟上的版本時的錯誤（例如保險庫已鎖定）
+	Disk        FileFingerprint // 磁碟上目前的檔案指紋
+}
+
+// SaveConflictError 保存時偵測到外部變更的錯誤，可用 errors.Is(err, ErrSaveConflict) 判斷
+type SaveConflictError struct {
+	Conflict *SaveConflict // 衝突內容
+}
+
+// Error 實作 error 介面
+func (e *SaveConflictError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrSaveConflict.Error(), e.Conflict.FilePath)
+}
+
+// Unwrap 讓 errors.Is 可以比對 ErrSaveConflict
+func (e *SaveConflictError) Unwrap() error {
+	return ErrSaveConflict
+}
+
+// AsSaveConflict 從錯誤中取得保存衝突
+// 參數：err（保存回傳的錯誤）
+// 回傳：保存衝突和是否為保存衝突
+func AsSaveConflict(err error) (*SaveConflict, bool) {
+	var conflictErr *SaveConflictError
+	if errors.As(err, &conflictErr) {
+		return conflictErr.Conflict, true
+	}
+	return nil, false
+}
+
+// noteDiskState 開啟中筆記最後一次讀取或寫入時的磁碟狀態
+type noteDiskState struct {
+	path        string          // 記錄指紋時的檔案路徑
+	fingerprint FileFingerprint // 檔案指紋
+	base        string          // 當時的筆記內容（三方合併的共同基準）
+	encrypted   bool            // 當時的檔案是否為加密筆記
+}
+
+// rememberDiskState 記錄筆記讀取或寫入後的檔案指紋
+// 參數：note（筆記）、data（讀取或寫入的檔案內容）
+func (e *editorService) rememberDiskState(note *models.Note, data []byte) {
+	e.diskStates[note.ID] = &noteDiskState{
+		path:        note.FilePath,
+		fingerprint: e.fileFingerprint(note.FilePath, data),
+		base:        note.Content,
+		encrypted:   note.IsEncrypted,
+	}
+}
+
+// checkDiskConflict 檢查筆記的檔案是否在開啟後被修改
+// 參數：note（要保存的筆記）
+// 回傳：檔案已被修改時為 SaveConflictError，否則為 nil
+//
+// 執行流程：
+// 1. 新筆記、另存到其他路徑或檔案已被刪除時不檢查
+// 2. 以內容雜湊比對（同步服務可能保留修改時間，不能只比對修改時間）
+// 3. 內容不同時讀取磁碟上的版本，組成保存衝突
+func (e *editorService) checkDiskConflict(note *models.Note) error {
+	conflict, err := e.diskConflict(note)
+	if err != nil {
+		return err
+	}
+	if conflict != nil {
+		return &SaveConflictError{Conflict: conflict}
+	}
+	return nil
+}
+
+// diskConflict 取得筆記目前的保存衝突
+// 參數：note（筆記）
+// 回傳：保存衝突（沒有衝突時為 nil）和可能的錯誤
+func (e *editorService) diskConflict(note *models.Note) (*SaveConflict, error) {
+	state, ok := e.diskStates[note.ID]
+	if !ok || state.path != note.FilePath || !e.fileRepo.FileExists(note.FilePath) {
+		return nil, nil
+	}
+
+	data, err := e.fileRepo.ReadFile(note.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("檢查檔案變更失敗: %w", err)
+	}
+	disk := e.fileFingerprint(note.FilePath, data)
+	if disk.Hash == state.fingerprint.Hash {
+		return nil, nil
+	}
+
+	conflict := &SaveConflict{
+		NoteID:   note.ID,
+		FilePath: note.FilePath,
+		Base:     state.base,
+		Mine:     note.Content,
+		Disk:     disk,
+	}
+	conflict.Theirs, conflict.TheirsError = e.readDiskContent(note, data)
+	return conflict, nil
+}
+
+// readDiskContent 取得磁碟上版本的明文內容
+// 參數：note（筆記）、data（檔案內容）
+// 回傳：明文內容和可能的錯誤
+//
+// 執行流程：
+// 1. 一般筆記直接使用，保險庫已解鎖時解密其中的機密區塊
+// 2. 收件人格式以身分私鑰解密，信封格式以保險庫解密
+// 3. 密碼格式使用開啟時暫存的密碼解密
+func (e *editorService) readDiskContent(note *models.Note, data []byte) (string, error) {
+	if !note.IsEncrypted {
+		content := string(data)
+		if e.vaultSvc != nil && e.vaultSvc.IsUnlocked() {
+			if revealed, err := e.RevealSecretBlocks(note.ID, content); err == nil {
+				content = revealed
+			}
+		}
+		return content, nil
+	}
+
+	if e.identitySvc != nil && e.identitySvc.IsRecipientData(data) {
+		if !e.identitySvc.IsUnlocked() {
+			return "", fmt.Errorf("身分金鑰庫已鎖定，無法讀取磁碟上的版本")
+		}
+		content, _, err := e.identitySvc.DecryptNote(data, note.ID)
+		return content, err
+	}
+	if e.vaultSvc != nil && e.vaultSvc.IsVaultData(data) {
+		if !e.vaultSvc.IsUnlocked() {
+			return "", ErrVaultLocked
+		}
+		content, _, err := e.vaultSvc.DecryptNote(data)
+		return content, err
+	}
+	if password, ok := e.notePassword(note.ID); ok {
+		return e.encryptionSvc.DecryptContent(data, password, "")
+	}
+	return "", fmt.Errorf("需要密碼驗證才能讀取磁碟上的版本")
+}
+
+// ResolveSaveConflict 依使用者的選擇處理保存時偵測到的外部變更
+// 參數：note（要保存的筆記）、resolution（處理方式）
+// 回傳：合併結果是否仍有衝突標記需要手動處理，以及可能的錯誤
+//
+// 執行流程：
+// 1. 重新讀取磁碟上的版本，衝突已不存在時直接保存
+// 2. 保留我的版本：以目前的磁碟狀態為準後覆寫
+// 3. 採用磁碟上的版本：以磁碟上的內容取代筆記內容，不寫入檔案
+// 4. 三方合併：沒有衝突時保存合併結果，有衝突時只更新筆記內容，由使用者處理衝突標記後再保存
+func (e *editorService) ResolveSaveConflict(note *models.Note, resolution ConflictResolution) (bool, error) {
+	if note == nil {
+		return false, fmt.Errorf("筆記實例不能為空")
+	}
+
+	conflict, err := e.diskConflict(note)
+	if err != nil {
+		return false, err
+	}
+	if conflict == nil {
+		if resolution == ConflictKeepTheirs {
+			return false, nil
+		}
+		return false, e.SaveNote(note)
+	}
+	if resolution != ConflictKeepMine && conflict.TheirsError != nil {
+		return false, fmt.Errorf("讀取磁碟上的版本失敗: %w", conflict.TheirsError)
+	}
+
+	state := e.diskStates[note.ID]
+	state.fingerprint = conflict.Disk
+
+	switch resolution {
+	case ConflictKeepMine:
+		return false, e.SaveNote(note)
+
+	case ConflictKeepTheirs:
+		e.acceptDiskVersion(note, conflict)
+		return false, nil
+
+	case ConflictMerge:
+		merged, conflicted := MergeText(conflict.Base, conflict.Mine, conflict.Theirs)
+		note.Content = merged
+		note.UpdatedAt = time.Now()
+		state.base = conflict.Theirs
+		if conflicted {
+			return true, nil
+		}
+		return false, e.SaveNote(note)
+	}
+	return false, fmt.Errorf("不支援的衝突處理方式: %d", resolution)
+}
+
+// ReloadNote 檔案在外部變更後重新載入筆記內容
+// 參數：noteID（筆記 ID）
+// 回傳：內容是否已更新和可能的錯誤
+//
+// 執行流程：
+// 1. 比對檔案指紋，與開啟或上次保存時相同（例如自己保存觸發的變更）時不重新載入
+// 2. 筆記內容與開啟或上次保存時不同（有未保存的編輯）時不重新載入，保存時再由使用者選擇
+// 3. 讀取磁碟上的版本並取代筆記內容
+func (e *editorService) ReloadNote(noteID string) (bool, error) {
+	note, exists := e.activeNotes[noteID]
+	if !exists {
+		return false, fmt.Errorf("找不到指定的筆記: %s", noteID)
+	}
+
+	conflict, err := e.diskConflict(note)
+	if err != nil || conflict == nil || conflict.Mine != conflict.Base {
+		return false, err
+	}
+	if conflict.TheirsError != nil {
+		return false, fmt.Errorf("讀取磁碟上的版本失敗: %w", conflict.TheirsError)
+	}
+	e.acceptDiskVersion(note, conflict)
+	return true, nil
+}
+
+// InvalidateFileCache 清除依檔案內容建立的快取
+// 參數：filePath（檔案路徑）
+func (e *editorService) InvalidateFileCache(filePath string) {
+	e.forgetNoteTitle(filePath)
+}
+
+// acceptDiskVersion 以磁碟上的版本取代筆記內容，之後的保存以此版本為基準
+// 參數：note（筆記）、conflict（包含磁碟上版本的保存衝突）
+func (e *editorService) acceptDiskVersion(note *models.Note, conflict *SaveConflict) {
+	state := e.diskStates[note.ID]
+	state.fingerprint = conflict.Disk
+	state.base = conflict.Theirs
+
+	note.Content = conflict.Theirs
+	e.applyFrontMatter(note)
+	note.UpdatedAt = time.Now()
+	note.LastSaved = note.UpdatedAt
+	if e.recoveryJournal != nil {
+		if err := e.recoveryJournal.Discard(note.ID); err != nil {
+			log.Printf("移除復原記錄失敗: %v", err)
+		}
+	}
+}
+
+// fileFingerprint 計算檔案指紋
+// 參數：path（檔案路徑）、data（檔案內容）
+// 回傳：檔案指紋
+func (e *editorService) fileFingerprint(path string, data []byte) FileFingerprint {
+	sum := sha256.Sum256(data)
+	return FileFingerprint{
+		Hash:    hex.EncodeToString(sum[:]),
+		Size:    int64(len(data)),
+		ModTime: e.fileModTime(path),
+	}
+}
+
+// fileModTime 取得檔案的最後修改時間
+// 參數：path（檔案路徑）
+// 回傳：最後修改時間，無法取得時為零值
+func (e *editorService) fileModTime(path string) time.Time {
+	infos, err := e.fileRepo.ListDirectory(filepath.Dir(path))
+	if err != nil {
+		return time.Time{}
+	}
+	name := filepath.Base(path)
+	for _, info := range infos {
+		if info.Name == name {
+			return info.ModTime
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,170 @@
+// Package services 提供保存衝突偵測和三方合併的單元測試
+// 測試檔案在開啟後被外部修改時不會被覆寫，以及保留我的版本、採用磁碟上的版本和合併
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"mac-notebook-app/internal/models"
+)
+
+// openConflictingNote 開啟筆記、修改內容，並模擬其他程式修改了同一個檔案
+// 參數：base（原始內容）、mine（編輯器中的內容）、theirs（外部修改後的內容）
+// 回傳：編輯器服務、模擬檔案儲存庫和開啟的筆記
+func openConflictingNote(t *testing.T, base, mine, theirs string) (EditorService, *mockFileRepository, *models.Note) {
+	service, mockRepo := createTestEditorService()
+	mockRepo.WriteFile("notes/plan.md", []byte(base))
+	note, err := service.OpenNote("notes/plan.md")
+	if err != nil {
+		t.Fatalf("開啟筆記失敗: %v", err)
+	}
+	service.UpdateContent(note.ID, mine)
+	mockRepo.WriteFile("notes/plan.md", []byte(theirs))
+	return service, mockRepo, note
+}
+
+// TestSaveNoteDetectsExternalChange 測試檔案在開啟後被修改時保存不會覆寫
+func TestSaveNoteDetectsExternalChange(t *testing.T) {
+	service, mockRepo, note := openConflictingNote(t, "第一行\n第二行", "我的修改\n第二行", "第一行\n同步的修改")
+
+	err := service.SaveNote(note)
+	if !errors.Is(err, ErrSaveConflict) {
+		t.Fatalf("應該回傳保存衝突: %v", err)
+	}
+	conflict, ok := AsSaveConflict(err)
+	if !ok || conflict.Base != "第一行\n第二行" || conflict.Mine != "我的修改\n第二行" || conflict.Theirs != "第一行\n同步的修改" {
+		t.Errorf("保存衝突內容不正確: %+v", conflict)
+	}
+	if string(mockRepo.files["notes/plan.md"]) != "第一行\n同步的修改" {
+		t.Error("發生衝突時不應覆寫磁碟上的版本")
+	}
+
+	// 沒有外部變更時照常保存，之後的保存以新的內容為準
+	service2, mockRepo2 := createTestEditorService()
+	mockRepo2.WriteFile("a.md", []byte("原始內容"))
+	note2, _ := service2.OpenNote("a.md")
+	service2.UpdateContent(note2.ID, "第一次保存")
+	if err := service2.SaveNote(note2); err != nil {
+		t.Fatalf("沒有外部變更時應該可以保存: %v", err)
+	}
+	service2.UpdateContent(note2.ID, "第二次保存")
+	if err := service2.SaveNote(note2); err != nil {
+		t.Errorf("保存後再次保存不應視為衝突: %v", err)
+	}
+}
+
+// TestResolveSaveConflict 測試保存衝突的三種處理方式
+func TestResolveSaveConflict(t *testing.T) {
+	t.Run("保留我的版本", func(t *testing.T) {
+		service, mockRepo, note := openConflictingNote(t, "原始", "我的版本", "磁碟上的版本")
+		if _, err := service.ResolveSaveConflict(note, ConflictKeepMine); err != nil {
+			t.Fatalf("處理衝突失敗: %v", err)
+		}
+		if string(mockRepo.files["notes/plan.md"]) != "我的版本" {
+			t.Errorf("應該以我的版本覆寫: %q", mockRepo.files["notes/plan.md"])
+		}
+	})
+
+	t.Run("採用磁碟上的版本", func(t *testing.T) {
+		service, mockRepo, note := openConflictingNote(t, "原始", "我的版本", "磁碟上的版本")
+		if _, err := service.ResolveSaveConflict(note, ConflictKeepTheirs); err != nil {
+			t.Fatalf("處理衝突失敗: %v", err)
+		}
+		if note.Content != "磁碟上的版本" || note.IsModified() {
+			t.Errorf("應該載入磁碟上的版本: %q", note.Content)
+		}
+		// 之後的編輯以磁碟上的版本為基準，可以直接保存
+		service.UpdateContent(note.ID, "磁碟上的版本（已修改）")
+		if err := service.SaveNote(note); err != nil || string(mockRepo.files["notes/plan.md"]) != "磁碟上的版本（已修改）" {
+			t.Errorf("採用磁碟上的版本後應該可以保存: %v", err)
+		}
+	})
+
+	t.Run("合併不同段落的修改", func(t *testing.T) {
+		service, mockRepo, note := openConflictingNote(t, "標題\n第一段\n第二段", "標題\n第一段（我的）\n第二段", "標題\n第一段\n第二段（同步的）")
+		conflicted, err := service.ResolveSaveConflict(note, ConflictMerge)
+		if err != nil || conflicted {
+			t.Fatalf("不同段落的修改應該可以自動合併: %v, %v", conflicted, err)
+		}
+		if string(mockRepo.files["notes/plan.md"]) != "標題\n第一段（我的）\n第二段（同步的）" {
+			t.Errorf("合併結果不正確: %q", mockRepo.files["notes/plan.md"])
+		}
+	})
+
+	t.Run("合併相同段落的修改", func(t *testing.T) {
+		service, mockRepo, note := openConflictingNote(t, "標題\n內容", "標題\n我的內容", "標題\n同步的內容")
+		conflicted, err := service.ResolveSaveConflict(note, ConflictMerge)
+		if err != nil || !conflicted {
+			t.Fatalf("相同段落的修改應該標示衝突: %v, %v", conflicted, err)
+		}
+		if !strings.Contains(note.Content, MergeConflictStart) || !note.IsModified() {
+			t.Errorf("筆記內容應該包含衝突標記並維持修改狀態: %q", note.Content)
+		}
+		if string(mockRepo.files["notes/plan.md"]) != "標題\n同步的內容" {
+			t.Error("有衝突時不應寫入檔案")
+		}
+		// 處理衝突標記後可以直接保存
+		service.UpdateContent(note.ID, "標題\n合併後的內容")
+		if err := service.SaveNote(note); err != nil {
+			t.Errorf("處理衝突後應該可以保存: %v", err)
+		}
+	})
+}
+
+// TestMergeText 測試三方合併
+func TestMergeText(t *testing.T) {
+	merged, conflicted := MergeText("a\nb\nc\nd", "a\nB\nc\nd", "a\nb\nc\nD\ne")
+	if conflicted || merged != "a\nB\nc\nD\ne" {
+		t.Errorf("不重疊的修改應該合併: %q, %v", merged, conflicted)
+	}
+
+	merged, conflicted = MergeText("a\nb\nc", "a\nx\nc", "a\nx\nc")
+	if conflicted || merged != "a\nx\nc" {
+		t.Errorf("兩邊相同的修改不應視為衝突: %q, %v", merged, conflicted)
+	}
+
+	merged, conflicted = MergeText("a\nb\nc", "a\nmine\nc", "a\ntheirs\nc")
+	expected := strings.Join([]string{"a", MergeConflictStart, "mine", MergeConflictMiddle, "theirs", MergeConflictEnd, "c"}, "\n")
+	if !conflicted || merged != expected {
+		t.Errorf("衝突的修改應該以標記保留兩邊:\n%s", merged)
+	}
+}
+
+// TestAutoSaveConflict 測試自動保存遇到外部變更時不重試，只通知一次並等待使用者選擇
+func TestAutoSaveConflict(t *testing.T) {
+	note := models.NewNote("衝突筆記", "內容", "/test/conflict.md")
+	conflictErr := &SaveConflictError{Conflict: &SaveConflict{NoteID: note.ID, FilePath: note.FilePath}}
+	mockEditor := &MockEditorService{shouldFail: true, saveError: conflictErr}
+	autoSave := NewAutoSaveServiceWithDefaults(mockEditor)
+
+	var notified []*SaveConflict
+	autoSave.SetConflictHandler(func(conflict *SaveConflict) {
+		notified = append(notified, conflict)
+	})
+	autoSave.StartAutoSave(note, time.Hour)
+	defer autoSave.Shutdown()
+
+	note.UpdatedAt = time.Now().Add(time.Second)
+	autoSave.performAutoSave(note.ID, time.Hour)
+	autoSave.performAutoSave(note.ID, time.Hour)
+	if len(mockEditor.saveCallLog) != 2 {
+		t.Errorf("保存衝突不應重試，實際保存 %d 次", len(mockEditor.saveCallLog))
+	}
+	if len(notified) != 1 || notified[0] != conflictErr.Conflict {
+		t.Errorf("同一次衝突應該只通知一次，實際 %d 次", len(notified))
+	}
+	if !errors.Is(autoSave.GetSaveStatus(note.ID).LastError, ErrSaveConflict) {
+		t.Error("保存狀態應該記錄保存衝突")
+	}
+
+	if _, err := autoSave.ResolveConflict(note.ID, ConflictKeepMine); err != nil {
+		t.Fatalf("處理衝突失敗: %v", err)
+	}
+	status := autoSave.GetSaveStatus(note.ID)
+	if status.LastError != nil || len(mockEditor.resolutions) != 1 || mockEditor.resolutions[0] != ConflictKeepMine {
+		t.Errorf("處理衝突後應該清除保存錯誤: %+v", status)
+	}
+}
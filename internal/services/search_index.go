@@ -0,0 +1,859 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含全文搜尋索引：以倒排索引記錄每個筆記的詞彙，中文和日文以相鄰兩字（bigram）切詞，
+// 英文等拉丁文字以單字切詞。索引保存在筆記本中繼資料目錄中，啟動時只重新讀取修改過的筆記，
+// 保存、重新命名和刪除時逐筆更新，搜尋結果依 BM25 排序並附上標示符合文字的摘要
+package services
+
+import (
+	"encoding/json" // JSON 序列化
+	"fmt"           // 格式化輸出
+	"log"           // 日誌記錄
+	"math"          // 計算排序分數
+	"path/filepath" // 檔案路徑處理
+	"sort"          // 排序搜尋結果
+	"strings"       // 字串處理
+	"sync"          // 同步控制
+	"time"          // 檔案修改時間
+	"unicode"       // 判斷文字類型
+
+	"mac-notebook-app/internal/models"       // 引入資料模型
+	"mac-notebook-app/internal/repositories" // 引入儲存庫介面
+)
+
+// SearchIndexPath 全文搜尋索引檔，位於筆記本中繼資料目錄中
+var SearchIndexPath = filepath.Join(NotebookMetaDir, "search-index.json")
+
+// searchIndexVersion 索引檔格式版本，切詞方式改變時遞增，舊的索引會被捨棄並重新建立
+const searchIndexVersion = 1
+
+// 排序和摘要的參數
+const (
+	searchBM25K1          = 1.2  // BM25 詞頻飽和參數
+	searchBM25B           = 0.75 // BM25 文件長度正規化參數
+	searchPrefixWeight    = 0.6  // 前綴符合的權重（輸入到一半的英文單字）
+	searchPhraseBonus     = 1.5  // 內容包含完整查詢字串時的加權
+	searchTitleBonus      = 1.3  // 標題包含查詢字串時的加權
+	searchSnippetBefore   = 20   // 摘要中符合位置之前的字數
+	searchSnippetAfter    = 60   // 摘要中符合位置之後的字數
+	searchMinCandidates   = 30   // 計算摘要和加權的最少候選筆記數
+	searchSnippetEllipsis = "…"  // 摘要截斷處的省略符號
+)
+
+// SearchHighlight 摘要中符合查詢的範圍
+type SearchHighlight struct {
+	Start int // 起始位置（以字元計算，包含）
+	End   int // 結束位置（以字元計算，不包含）
+}
+
+// SearchResult 代表一筆搜尋結果
+type SearchResult struct {
+	FilePath   string            // 筆記路徑
+	Title      string            // 筆記標題（front matter 的標題、第一個標題或檔名）
+	Score      float64           // 排序分數，越高越相關
+	Line       int               // 符合位置所在的行（從 0 開始）
+	Column     int               // 符合位置在該行的字元位置（從 0 開始）
+	Snippet    string            // 符合位置附近的文字
+	Highlights []SearchHighlight // 摘要中符合查詢的範圍
+}
+
+// SearchIndex 定義全文搜尋索引的介面
+// 加密筆記、加密資料夾中的筆記和機密區塊的內容不會寫入索引，避免明文出現在索引檔中
+type SearchIndex interface {
+	// Rebuild 載入索引檔並與筆記本同步，只重新讀取新增或修改過的筆記
+	// 回傳：可能的錯誤
+	Rebuild() error
+
+	// UpdateNote 筆記保存後重新讀取並更新索引
+	// 參數：filePath（相對於筆記本目錄的筆記路徑）
+	// 回傳：可能的錯誤
+	UpdateNote(filePath string) error
+
+	// RenameNote 檔案或目錄重新命名、移動後更新索引中的路徑
+	// 參數：oldPath（舊路徑）、newPath（新路徑）
+	// 回傳：可能的錯誤
+	RenameNote(oldPath, newPath string) error
+
+	// RemoveNote 從索引移除檔案或目錄中的所有筆記
+	// 參數：path（檔案或目錄路徑）
+	// 回傳：可能的錯誤
+	RemoveNote(path string) error
+
+	// ApplyFileChanges 依檔案監看的變更更新索引
+	// 參數：events（檔案變更）
+	ApplyFileChanges(events []FileChangeEvent)
+
+	// Search 搜尋筆記內容
+	// 參數：query（查詢字串）、limit（最多回傳的結果數）
+	// 回傳：依相關程度排序的搜尋結果，每個筆記一筆
+	Search(query string, limit int) []*SearchResult
+}
+
+// searchDocument 索引中的一個筆記
+type searchDocument struct {
+	ModTime time.Time      `json:"mod_time"` // 建立索引時的檔案修改時間
+	Size    int64          `json:"size"`     // 建立索引時的檔案大小
+	Length  int            `json:"length"`   // 詞彙總數
+	Terms   map[string]int `json:"terms"`    // 詞彙出現的次數
+}
+
+// searchIndexFile 索引檔的內容
+type searchIndexFile struct {
+	Version int                        `json:"version"` // 索引檔格式版本
+	Notes   map[string]*searchDocument `json:"notes"`   // 筆記路徑（以 / 分隔）對應的索引
+}
+
+// searchIndex 實作 SearchIndex 介面
+type searchIndex struct {
+	fileRepo    repositories.FileRepository // 檔案存取介面
+	docs        map[string]*searchDocument  // 筆記路徑對應的索引
+	postings    map[string]map[string]int   // 倒排索引：詞彙對應的筆記和出現次數
+	totalLength int                         // 所有筆記的詞彙總數，用於計算平均長度
+	loaded      bool                        // 是否已讀取索引檔
+	mutex       sync.RWMutex                // 保護索引
+}
+
+// NewSearchIndex 建立全文搜尋索引
+// 參數：fileRepo（檔案存取介面）
+// 回傳：全文搜尋索引實例，第一次使用時才讀取索引檔
+func NewSearchIndex(fileRepo repositories.FileRepository) SearchIndex {
+	return &searchIndex{
+		fileRepo: fileRepo,
+		docs:     make(map[string]*searchDocument),
+		postings: make(map[string]map[string]int),
+	}
+}
+
+// Rebuild 載入索引檔並與筆記本同步
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 讀取索引檔（格式不符時從空的索引開始）
+// 2. 掃描筆記本，修改時間或大小改變的筆記重新讀取，讀取檔案時不持有鎖
+// 3. 移除已不存在或移入加密資料夾的筆記並寫入索引檔
+func (x *searchIndex) Rebuild() error {
+	x.ensureLoaded()
+
+	var files []*models.FileInfo
+	err := x.fileRepo.WalkDirectory(".", func(info *models.FileInfo) error {
+		if info.IsDirectory {
+			if info.Name == NotebookMetaDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if x.indexable(info.Path) {
+			files = append(files, info)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("掃描筆記失敗: %w", err)
+	}
+
+	seen := make(map[string]bool, len(files))
+	changed := false
+	for _, info := range files {
+		seen[noteIndexKey(info.Path)] = true
+		changed = x.indexFile(info) || changed
+	}
+
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	for key := range x.docs {
+		if !seen[key] {
+			x.removeDocument(key)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return x.save()
+}
+
+// UpdateNote 筆記保存後重新讀取並更新索引
+// 參數：filePath（筆記路徑）
+// 回傳：可能的錯誤
+func (x *searchIndex) UpdateNote(filePath string) error {
+	if !x.indexable(filePath) {
+		// 加密筆記（例如改為加密後保存）和加密資料夾中的筆記不建立索引
+		return x.RemoveNote(filePath)
+	}
+	x.ensureLoaded()
+
+	var info *models.FileInfo
+	err := x.fileRepo.WalkDirectory(filePath, func(found *models.FileInfo) error {
+		info = found
+		return nil
+	})
+	if err != nil || info == nil {
+		return fmt.Errorf("讀取筆記資訊失敗: %s", filePath)
+	}
+	if !x.indexFile(info) {
+		return nil
+	}
+
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	return x.save()
+}
+
+// RenameNote 檔案或目錄重新命名、移動後更新索引中的路徑
+// 參數：oldPath（舊路徑）、newPath（新路徑）
+// 回傳：可能的錯誤
+func (x *searchIndex) RenameNote(oldPath, newPath string) error {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	x.load()
+	oldKey, newKey := noteIndexKey(oldPath), noteIndexKey(newPath)
+	changed := false
+	for key, doc := range x.docs {
+		rest, ok := underNoteIndexKey(key, oldKey)
+		if !ok {
+			continue
+		}
+		x.removeDocument(key)
+		// 改為非 Markdown 的副檔名或移入加密資料夾後不再建立索引
+		if x.indexable(filepath.FromSlash(newKey + rest)) {
+			x.putDocument(newKey+rest, doc)
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return x.save()
+}
+
+// RemoveNote 從索引移除檔案或目錄中的所有筆記
+// 參數：path（檔案或目錄路徑）
+// 回傳：可能的錯誤
+func (x *searchIndex) RemoveNote(path string) error {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	x.load()
+	if !x.removeUnder(path) {
+		return nil
+	}
+	return x.save()
+}
+
+// ApplyFileChanges 依檔案監看的變更更新索引
+// 參數：events（檔案變更）
+//
+// 執行流程：
+// 1. 刪除的路徑從索引移除（目錄中的筆記一併移除）
+// 2. 新增或修改的路徑重新讀取，移入的目錄會掃描其中所有筆記；修改時間和大小未變的筆記（例如剛由本程式保存）略過，加密資料夾中的筆記從索引移除
+// 3. 有變更時寫入索引檔
+func (x *searchIndex) ApplyFileChanges(events []FileChangeEvent) {
+	x.ensureLoaded()
+
+	changed := false
+	for _, event := range events {
+		if isNotebookMetaPath(event.Path) {
+			continue
+		}
+		if event.Op == FileRemoved {
+			x.mutex.Lock()
+			changed = x.removeUnder(event.Path) || changed
+			x.mutex.Unlock()
+			continue
+		}
+		x.fileRepo.WalkDirectory(event.Path, func(info *models.FileInfo) error {
+			switch {
+			case info.IsDirectory:
+			case x.indexable(info.Path):
+				changed = x.indexFile(info) || changed
+			default:
+				// 移入加密資料夾（或資料夾改為加密）的筆記從索引移除
+				x.mutex.Lock()
+				changed = x.removeUnder(info.Path) || changed
+				x.mutex.Unlock()
+			}
+			return nil
+		})
+	}
+	if !changed {
+		return
+	}
+
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	if err := x.save(); err != nil {
+		log.Printf("更新全文搜尋索引失敗: %v", err)
+	}
+}
+
+// Search 搜尋筆記內容
+// 參數：query（查詢字串）、limit（最多回傳的結果數）
+// 回傳：搜尋結果
+//
+// 執行流程：
+// 1. 以建立索引相同的方式切詞，筆記必須符合所有查詢詞彙
+// 2. 以 BM25 計算分數，單一漢字和輸入到一半的英文單字以包含或前綴符合
+// 3. 讀取分數最高的筆記，內容或標題包含完整查詢字串時加權，並擷取摘要
+// 4. 依分數排序後回傳
+func (x *searchIndex) Search(query string, limit int) []*SearchResult {
+	terms := uniqueSearchTerms(TokenizeSearchText(query))
+	if len(terms) == 0 || limit <= 0 {
+		return nil
+	}
+	x.ensureLoaded()
+
+	scores := x.score(terms)
+	candidates := make([]string, 0, len(scores))
+	for key := range scores {
+		candidates = append(candidates, key)
+	}
+	sortByScore(candidates, scores)
+	if count := max(limit*3, searchMinCandidates); len(candidates) > count {
+		candidates = candidates[:count]
+	}
+
+	results := make([]*SearchResult, 0, len(candidates))
+	for _, key := range candidates {
+		path := filepath.FromSlash(key)
+		data, err := x.fileRepo.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		content := blankSecretBlocks(string(data))
+		result := buildSearchResult(path, content, query, terms)
+		result.Score = scores[key]
+		if result.phrase {
+			result.Score *= searchPhraseBonus
+		}
+		if strings.Contains(foldSearchText(result.Title), foldSearchText(strings.TrimSpace(query))) {
+			result.Score *= searchTitleBonus
+		}
+		results = append(results, &result.SearchResult)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].FilePath < results[j].FilePath
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// score 計算符合所有查詢詞彙的筆記分數
+// 參數：terms（查詢詞彙）
+// 回傳：筆記路徑對應的分數
+func (x *searchIndex) score(terms []string) map[string]float64 {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+
+	if len(x.docs) == 0 {
+		return nil
+	}
+	avgLength := float64(x.totalLength) / float64(len(x.docs))
+	var scores map[string]float64
+	for _, term := range terms {
+		// 同一個查詢詞彙符合多個索引詞彙時取最高分
+		termScores := make(map[string]float64)
+		for indexed, weight := range x.matchingTerms(term) {
+			postings := x.postings[indexed]
+			df := float64(len(postings))
+			idf := math.Log(1 + (float64(len(x.docs))-df+0.5)/(df+0.5))
+			for key, tf := range postings {
+				length := float64(x.docs[key].Length)
+				tfWeight := float64(tf) * (searchBM25K1 + 1) / (float64(tf) + searchBM25K1*(1-searchBM25B+searchBM25B*length/avgLength))
+				if s := weight * idf * tfWeight; s > termScores[key] {
+					termScores[key] = s
+				}
+			}
+		}
+
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for key := range scores {
+			if s, ok := termScores[key]; ok {
+				scores[key] += s
+			} else {
+				delete(scores, key)
+			}
+		}
+		if len(scores) == 0 {
+			break
+		}
+	}
+	return scores
+}
+
+// matchingTerms 找出查詢詞彙符合的索引詞彙
+// 參數：term（查詢詞彙）
+// 回傳：索引詞彙對應的權重
+//
+// 單一漢字或假名符合所有包含該字的詞彙（索引中是兩字一組），
+// 兩個字母以上的英文單字另外以較低的權重符合以其開頭的單字
+func (x *searchIndex) matchingTerms(term string) map[string]float64 {
+	matches := make(map[string]float64)
+	if _, ok := x.postings[term]; ok {
+		matches[term] = 1
+	}
+
+	runes := []rune(term)
+	singleCJK := len(runes) == 1 && isCJKRune(runes[0])
+	prefix := !isCJKRune(runes[0]) && len(runes) >= 2
+	if !singleCJK && !prefix {
+		return matches
+	}
+	for indexed := range x.postings {
+		if indexed == term {
+			continue
+		}
+		switch {
+		case singleCJK && strings.Contains(indexed, term):
+			matches[indexed] = 1
+		case prefix && strings.HasPrefix(indexed, term):
+			matches[indexed] = searchPrefixWeight
+		}
+	}
+	return matches
+}
+
+// indexable 檢查路徑是否建立索引
+// 參數：path（檔案路徑）
+// 回傳：Markdown 筆記且不在加密資料夾中時為 true（加密資料夾中的筆記在保險庫解鎖時會讀到明文）
+func (x *searchIndex) indexable(path string) bool {
+	if !isTaggableNoteFile(path) {
+		return false
+	}
+	if encryptedRepo, ok := x.fileRepo.(EncryptedFileRepository); ok {
+		return !encryptedRepo.IsEncryptedPath(path)
+	}
+	return true
+}
+
+// indexFile 讀取筆記並更新索引，修改時間和大小與索引相同時略過
+// 參數：info（筆記的檔案資訊）
+// 回傳：索引是否有變更
+func (x *searchIndex) indexFile(info *models.FileInfo) bool {
+	key := noteIndexKey(info.Path)
+	x.mutex.RLock()
+	doc, exists := x.docs[key]
+	current := exists && doc.Size == info.Size && doc.ModTime.Equal(info.ModTime)
+	x.mutex.RUnlock()
+	if current {
+		return false
+	}
+
+	data, err := x.fileRepo.ReadFile(info.Path)
+	if err != nil {
+		return false
+	}
+	terms := TokenizeSearchText(blankSecretBlocks(string(data)))
+	doc = &searchDocument{
+		ModTime: info.ModTime,
+		Size:    info.Size,
+		Length:  len(terms),
+		Terms:   make(map[string]int),
+	}
+	for _, term := range terms {
+		doc.Terms[term]++
+	}
+
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.removeDocument(key)
+	x.putDocument(key, doc)
+	return true
+}
+
+// putDocument 將筆記加入索引（呼叫者需持有鎖）
+// 參數：key（以 / 分隔的筆記路徑）、doc（筆記的索引）
+func (x *searchIndex) putDocument(key string, doc *searchDocument) {
+	x.docs[key] = doc
+	x.totalLength += doc.Length
+	for term, count := range doc.Terms {
+		postings, ok := x.postings[term]
+		if !ok {
+			postings = make(map[string]int)
+			x.postings[term] = postings
+		}
+		postings[key] = count
+	}
+}
+
+// removeDocument 從索引移除筆記（呼叫者需持有鎖）
+// 參數：key（以 / 分隔的筆記路徑）
+func (x *searchIndex) removeDocument(key string) {
+	doc, ok := x.docs[key]
+	if !ok {
+		return
+	}
+	delete(x.docs, key)
+	x.totalLength -= doc.Length
+	for term := range doc.Terms {
+		delete(x.postings[term], key)
+		if len(x.postings[term]) == 0 {
+			delete(x.postings, term)
+		}
+	}
+}
+
+// removeUnder 移除檔案或目錄中的所有筆記（呼叫者需持有鎖）
+// 參數：path（檔案或目錄路徑）
+// 回傳：索引是否有變更
+func (x *searchIndex) removeUnder(path string) bool {
+	prefix := noteIndexKey(path)
+	changed := false
+	for key := range x.docs {
+		if _, ok := underNoteIndexKey(key, prefix); ok {
+			x.removeDocument(key)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// ensureLoaded 第一次使用時讀取索引檔
+func (x *searchIndex) ensureLoaded() {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.load()
+}
+
+// load 讀取索引檔（呼叫者需持有鎖）
+// 索引可以由筆記重新建立，檔案損毀或格式版本不同時記錄後從空的索引開始
+func (x *searchIndex) load() {
+	if x.loaded {
+		return
+	}
+	x.loaded = true
+	if !x.fileRepo.FileExists(SearchIndexPath) {
+		return
+	}
+	data, err := x.fileRepo.ReadFile(SearchIndexPath)
+	if err != nil {
+		log.Printf("讀取全文搜尋索引失敗，將重新建立: %v", err)
+		return
+	}
+	var file searchIndexFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Version != searchIndexVersion {
+		log.Printf("全文搜尋索引格式不符，將重新建立")
+		return
+	}
+	for key, doc := range file.Notes {
+		if doc != nil && doc.Terms != nil {
+			x.putDocument(key, doc)
+		}
+	}
+}
+
+// save 將索引寫入索引檔（呼叫者需持有鎖）
+// 回傳：可能的錯誤
+func (x *searchIndex) save() error {
+	data, err := json.Marshal(searchIndexFile{Version: searchIndexVersion, Notes: x.docs})
+	if err != nil {
+		return fmt.Errorf("序列化全文搜尋索引失敗: %w", err)
+	}
+	if err := x.fileRepo.WriteFile(SearchIndexPath, data); err != nil {
+		return fmt.Errorf("寫入全文搜尋索引失敗: %w", err)
+	}
+	return nil
+}
+
+// TokenizeSearchText 將文字切成索引詞彙
+// 參數：text（文字）
+// 回傳：依出現順序排列的詞彙（可能重複）
+//
+// 切詞規則：
+// 1. 連續的漢字、假名和韓文以相鄰兩字為一個詞彙，只有一個字時以該字為詞彙
+// 2. 連續的字母和數字為一個單字，轉為小寫
+// 3. 全形英數字先轉為半形，其他符號和空白作為分隔
+func TokenizeSearchText(text string) []string {
+	var terms []string
+	var run []rune
+	cjk := false
+	flush := func() {
+		switch {
+		case len(run) == 0:
+		case !cjk:
+			terms = append(terms, string(run))
+		case len(run) == 1:
+			terms = append(terms, string(run))
+		default:
+			for i := 0; i+1 < len(run); i++ {
+				terms = append(terms, string(run[i:i+2]))
+			}
+		}
+		run = run[:0]
+	}
+
+	for _, r := range text {
+		r = foldSearchRune(r)
+		switch {
+		case isCJKRune(r):
+			if !cjk {
+				flush()
+				cjk = true
+			}
+			run = append(run, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			if cjk {
+				flush()
+				cjk = false
+			}
+			run = append(run, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
+
+// isCJKRune 檢查字元是否為漢字、假名或韓文
+// 參數：r（字元）
+// 回傳：是否以兩字一組切詞
+func isCJKRune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) || r == 'ー'
+}
+
+// foldSearchRune 將字元轉為比對用的形式：全形英數字轉為半形並轉為小寫
+// 參數：r（字元）
+// 回傳：轉換後的字元
+func foldSearchRune(r rune) rune {
+	if r >= '！' && r <= '～' {
+		r -= '！' - '!'
+	}
+	return unicode.ToLower(r)
+}
+
+// foldSearchText 將文字轉為比對用的形式
+// 參數：text（文字）
+// 回傳：轉換後的文字，字元數與原文相同
+func foldSearchText(text string) string {
+	return strings.Map(foldSearchRune, text)
+}
+
+// uniqueSearchTerms 移除重複的詞彙並維持順序
+// 參數：terms（詞彙）
+// 回傳：不重複的詞彙
+func uniqueSearchTerms(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	unique := terms[:0]
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+	return unique
+}
+
+// sortByScore 依分數由高到低排序筆記路徑，分數相同時依路徑排序
+// 參數：keys（筆記路徑）、scores（分數）
+func sortByScore(keys []string, scores map[string]float64) {
+	sort.Slice(keys, func(i, j int) bool {
+		if scores[keys[i]] != scores[keys[j]] {
+			return scores[keys[i]] > scores[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+}
+
+// blankSecretBlocks 將機密區塊的每一行改為空白行，行號維持不變
+// 參數：content（筆記內容）
+// 回傳：不含機密區塊的內容
+func blankSecretBlocks(content string) string {
+	blocks := FindSecretBlocks(content)
+	if len(blocks) == 0 {
+		return content
+	}
+	lines := strings.Split(content, "\n")
+	for _, block := range blocks {
+		for i := block.StartLine; i <= block.EndLine && i < len(lines); i++ {
+			lines[i] = ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// searchMatch 搜尋結果和內容是否包含完整的查詢字串
+type searchMatch struct {
+	SearchResult
+	phrase bool // 內容包含完整的查詢字串
+}
+
+// buildSearchResult 找出筆記中符合查詢的位置並擷取摘要
+// 參數：path（筆記路徑）、content（筆記內容）、query（查詢字串）、terms（查詢詞彙）
+// 回傳：搜尋結果（不含分數）
+//
+// 執行流程：
+// 1. 優先找完整的查詢字串，其次是以空白分隔的各段查詢，最後是個別的詞彙
+// 2. 計算符合位置的行和字元位置
+// 3. 擷取該行中符合位置附近的文字，標示所有符合的查詢字串
+func buildSearchResult(path, content, query string, terms []string) searchMatch {
+	runes := []rune(content)
+	folded := []rune(foldSearchText(content))
+	phrase := []rune(foldSearchText(strings.TrimSpace(query)))
+
+	needles := [][]rune{phrase}
+	for _, segment := range strings.Fields(query) {
+		needles = append(needles, []rune(foldSearchText(segment)))
+	}
+	for _, term := range terms {
+		needles = append(needles, []rune(term))
+	}
+
+	pos, length, isPhrase := 0, 0, false
+	for i, needle := range needles {
+		if p := indexRunes(folded, needle, 0); p >= 0 {
+			pos, length, isPhrase = p, len(needle), i == 0
+			break
+		}
+	}
+
+	// 符合位置所在的行
+	lineStart := pos
+	for lineStart > 0 && runes[lineStart-1] != '\n' {
+		lineStart--
+	}
+	lineEnd := pos
+	for lineEnd < len(runes) && runes[lineEnd] != '\n' {
+		lineEnd++
+	}
+	line := 0
+	for _, r := range runes[:lineStart] {
+		if r == '\n' {
+			line++
+		}
+	}
+
+	// 擷取摘要並標示符合的文字
+	start := max(lineStart, pos-searchSnippetBefore)
+	end := min(lineEnd, pos+length+searchSnippetAfter)
+	offset := 0
+	snippet := string(runes[start:end])
+	if start > lineStart {
+		snippet = searchSnippetEllipsis + snippet
+		offset = len([]rune(searchSnippetEllipsis))
+	}
+	if end < lineEnd {
+		snippet += searchSnippetEllipsis
+	}
+	var highlights []SearchHighlight
+	for _, needle := range needles {
+		for p := indexRunes(folded[:end], needle, start); p >= 0; p = indexRunes(folded[:end], needle, p+len(needle)) {
+			highlights = append(highlights, SearchHighlight{Start: p - start + offset, End: p - start + offset + len(needle)})
+		}
+	}
+
+	return searchMatch{
+		SearchResult: SearchResult{
+			FilePath:   path,
+			Title:      searchNoteTitle(path, content),
+			Line:       line,
+			Column:     pos - lineStart,
+			Snippet:    snippet,
+			Highlights: mergeSearchHighlights(highlights),
+		},
+		phrase: isPhrase && len(phrase) > 0,
+	}
+}
+
+// indexRunes 在字元陣列中尋找子字串
+// 參數：text（字元陣列）、needle（要尋找的字元）、from（開始尋找的位置）
+// 回傳：第一次出現的位置，找不到或 needle 為空時為 -1
+func indexRunes(text, needle []rune, from int) int {
+	if len(needle) == 0 {
+		return -1
+	}
+	for i := from; i+len(needle) <= len(text); i++ {
+		if text[i] == needle[0] && string(text[i:i+len(needle)]) == string(needle) {
+			return i
+		}
+	}
+	return -1
+}
+
+// mergeSearchHighlights 排序並合併重疊的標示範圍
+// 參數：highlights（標示範圍）
+// 回傳：不重疊的標示範圍
+func mergeSearchHighlights(highlights []SearchHighlight) []SearchHighlight {
+	if len(highlights) == 0 {
+		return nil
+	}
+	sort.Slice(highlights, func(i, j int) bool { return highlights[i].Start < highlights[j].Start })
+	merged := []SearchHighlight{highlights[0]}
+	for _, h := range highlights[1:] {
+		last := &merged[len(merged)-1]
+		if h.Start <= last.End {
+			last.End = max(last.End, h.End)
+			continue
+		}
+		merged = append(merged, h)
+	}
+	return merged
+}
+
+// searchNoteTitle 取得搜尋結果顯示的標題
+// 參數：path（筆記路徑）、content（筆記內容）
+// 回傳：front matter 的標題、第一個一級標題，或不含副檔名的檔名
+func searchNoteTitle(path, content string) string {
+	if fm, _, err := ParseFrontMatter(content); err == nil && fm.Title != "" {
+		return fm.Title
+	}
+	for _, line := range strings.Split(StripFrontMatter(content), "\n") {
+		if title, ok := strings.CutPrefix(strings.TrimSpace(line), "# "); ok && strings.TrimSpace(title) != "" {
+			return strings.TrimSpace(title)
+		}
+	}
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// updateSearchIndex 筆記保存後更新全文搜尋索引，保存到新路徑時移除舊路徑的索引
+// 參數：note（已保存的筆記）、oldPath（保存前的檔案路徑）
+func (e *editorService) updateSearchIndex(note *models.Note, oldPath string) {
+	if e.searchIdx == nil {
+		return
+	}
+	if oldPath != "" && oldPath != note.FilePath {
+		if err := e.searchIdx.RemoveNote(oldPath); err != nil {
+			log.Printf("更新全文搜尋索引失敗: %v", err)
+		}
+	}
+	if err := e.searchIdx.UpdateNote(note.FilePath); err != nil {
+		log.Printf("更新全文搜尋索引失敗: %v", err)
+	}
+}
+
+// SetSearchIndex 設定全文搜尋索引，重新命名、移動和刪除檔案時更新索引
+// 參數：index（全文搜尋索引）
+func (s *LocalFileManagerService) SetSearchIndex(index SearchIndex) {
+	s.searchIdx = index
+}
+
+// moveSearchEntries 檔案或目錄重新命名、移動後更新全文搜尋索引
+// 參數：oldPath（舊路徑）、newPath（新路徑）
+func (s *LocalFileManagerService) moveSearchEntries(oldPath, newPath string) {
+	if s.searchIdx == nil {
+		return
+	}
+	if err := s.searchIdx.RenameNote(oldPath, newPath); err != nil {
+		log.Printf("更新全文搜尋索引失敗 %s: %v", oldPath, err)
+	}
+}
+
+// forgetSearchEntries 檔案刪除後從全文搜尋索引移除
+// 參數：path（刪除的路徑）
+func (s *LocalFileManagerService) forgetSearchEntries(path string) {
+	if s.searchIdx == nil {
+		return
+	}
+	if err := s.searchIdx.RemoveNote(path); err != nil {
+		log.Printf("更新全文搜尋索引失敗 %s: %v", path, err)
+	}
+}
@@ -0,0 +1,237 @@
+// Package services 提供全文搜尋索引的單元測試
+// 測試中文兩字切詞和英文單字切詞、搜尋結果的排序和摘要、索引檔的保存和增量更新，
+// 以及保存、重新命名和刪除筆記後索引隨之更新
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"mac-notebook-app/internal/repositories"
+)
+
+// createTestSearchEnvironment 建立使用同一個全文搜尋索引的編輯器服務和檔案管理服務
+func createTestSearchEnvironment(t *testing.T) (string, EditorService, *LocalFileManagerService, SearchIndex) {
+	baseDir, editor, fileManager, _ := createTestNoteIDEnvironment(t)
+	fileRepo, err := repositories.NewLocalFileRepository(baseDir)
+	if err != nil {
+		t.Fatalf("建立檔案儲存庫失敗: %v", err)
+	}
+	index := NewSearchIndex(fileRepo)
+	options := editor.Options()
+	options.SearchIndex = index
+	editor.SetOptions(options)
+	fileManager.SetSearchIndex(index)
+	return baseDir, editor, fileManager, index
+}
+
+// searchPaths 回傳搜尋結果的筆記路徑
+func searchPaths(results []*SearchResult) []string {
+	var paths []string
+	for _, result := range results {
+		paths = append(paths, result.FilePath)
+	}
+	return paths
+}
+
+// TestTokenizeSearchText 測試中日文以兩字一組切詞，英文以單字切詞並轉為小寫
+func TestTokenizeSearchText(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"機器學習", []string{"機器", "器學", "學習"}},
+		{"Go 語言 Notes", []string{"go", "語言", "notes"}},
+		{"學", []string{"學"}},
+		{"ＡＰＩ文件v2", []string{"api", "文件", "v2"}},
+		{"カタカナ・テスト", []string{"カタ", "タカ", "カナ", "テス", "スト"}},
+		{"，。！", nil},
+	}
+	for _, tt := range tests {
+		if got := TokenizeSearchText(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("TokenizeSearchText(%q) = %v，期望 %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+// TestSearchRankingAndSnippet 測試搜尋結果的排序、摘要、標示範圍和符合位置
+func TestSearchRankingAndSnippet(t *testing.T) {
+	baseDir, _, _, index := createTestSearchEnvironment(t)
+	os.WriteFile(filepath.Join(baseDir, "ml.md"), []byte("# 機器學習筆記\n\n今天研究機器學習的基礎，機器學習需要大量資料。"), 0644)
+	os.WriteFile(filepath.Join(baseDir, "diary.md"), []byte("# 日記\n\n今天去學校學習，順便修理機器。"), 0644)
+	os.WriteFile(filepath.Join(baseDir, "go.md"), []byte("# Go Notes\n\nGoroutines and channels."), 0644)
+	if err := index.Rebuild(); err != nil {
+		t.Fatalf("建立索引失敗: %v", err)
+	}
+
+	results := index.Search("機器學習", 10)
+	if !reflect.DeepEqual(searchPaths(results), []string{"ml.md"}) {
+		t.Fatalf("詞彙不連續的筆記不應符合: %v", searchPaths(results))
+	}
+	result := results[0]
+	if result.Title != "機器學習筆記" || result.Line != 0 || result.Column != 2 {
+		t.Errorf("標題或符合位置不正確: %+v", result)
+	}
+	snippet := []rune(result.Snippet)
+	if len(result.Highlights) == 0 || string(snippet[result.Highlights[0].Start:result.Highlights[0].End]) != "機器學習" {
+		t.Errorf("摘要應該標示符合的文字: %q %v", result.Snippet, result.Highlights)
+	}
+
+	// 單一漢字和輸入到一半的英文單字
+	if got := searchPaths(index.Search("學", 10)); !reflect.DeepEqual(got, []string{"ml.md", "diary.md"}) && !reflect.DeepEqual(got, []string{"diary.md", "ml.md"}) {
+		t.Errorf("單一漢字應該符合包含該字的筆記: %v", got)
+	}
+	if got := searchPaths(index.Search("gorou", 10)); !reflect.DeepEqual(got, []string{"go.md"}) {
+		t.Errorf("應該以前綴符合英文單字: %v", got)
+	}
+	if got := index.Search("CHANNELS", 10); len(got) != 1 || got[0].Line != 2 || !strings.Contains(got[0].Snippet, "channels") {
+		t.Errorf("英文搜尋不應區分大小寫: %+v", got)
+	}
+	if got := index.Search("機器 不存在", 10); len(got) != 0 {
+		t.Errorf("筆記必須符合所有查詢詞彙: %v", searchPaths(got))
+	}
+}
+
+// TestSearchIndexPersistence 測試索引保存後由新的實例讀取，只重新讀取修改過的筆記並移除已刪除的筆記
+func TestSearchIndexPersistence(t *testing.T) {
+	baseDir, _, _, index := createTestSearchEnvironment(t)
+	os.WriteFile(filepath.Join(baseDir, "a.md"), []byte("資料結構"), 0644)
+	os.WriteFile(filepath.Join(baseDir, "b.md"), []byte("演算法"), 0644)
+	os.WriteFile(filepath.Join(baseDir, "c.txt"), []byte("資料結構"), 0644)
+	if err := index.Rebuild(); err != nil {
+		t.Fatalf("建立索引失敗: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, SearchIndexPath)); err != nil {
+		t.Fatalf("應該寫入索引檔: %v", err)
+	}
+
+	// 模擬重新啟動：新的實例不需重新讀取筆記就能搜尋
+	fileRepo, _ := repositories.NewLocalFileRepository(baseDir)
+	restarted := NewSearchIndex(fileRepo)
+	if got := searchPaths(restarted.Search("資料", 10)); !reflect.DeepEqual(got, []string{"a.md"}) {
+		t.Errorf("重新啟動後應該讀取索引檔: %v", got)
+	}
+
+	// 關閉應用程式期間修改和刪除筆記
+	os.WriteFile(filepath.Join(baseDir, "a.md"), []byte("作業系統"), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(baseDir, "a.md"), later, later)
+	os.Remove(filepath.Join(baseDir, "b.md"))
+	if err := restarted.Rebuild(); err != nil {
+		t.Fatalf("同步索引失敗: %v", err)
+	}
+	if len(restarted.Search("資料", 10)) != 0 || len(restarted.Search("演算", 10)) != 0 {
+		t.Error("修改和刪除的筆記應該更新索引")
+	}
+	if got := searchPaths(restarted.Search("作業系統", 10)); !reflect.DeepEqual(got, []string{"a.md"}) {
+		t.Errorf("修改後的內容應該可以搜尋: %v", got)
+	}
+
+	// 損毀的索引檔從空的索引開始
+	os.WriteFile(filepath.Join(baseDir, SearchIndexPath), []byte("{"), 0644)
+	corrupted := NewSearchIndex(fileRepo)
+	if len(corrupted.Search("作業", 10)) != 0 {
+		t.Error("損毀的索引檔不應有搜尋結果")
+	}
+	corrupted.Rebuild()
+	if len(corrupted.Search("作業", 10)) != 1 {
+		t.Error("重新建立後應該可以搜尋")
+	}
+}
+
+// TestSearchIndexFollowsNoteChanges 測試保存、重新命名、移動和刪除筆記後更新索引
+func TestSearchIndexFollowsNoteChanges(t *testing.T) {
+	baseDir, editor, fileManager, index := createTestSearchEnvironment(t)
+	os.MkdirAll(filepath.Join(baseDir, "projects"), 0755)
+	os.WriteFile(filepath.Join(baseDir, "projects", "plan.md"), []byte("# 計畫\n\n季度目標"), 0644)
+	index.Rebuild()
+
+	note, err := editor.OpenNote(filepath.Join("projects", "plan.md"))
+	if err != nil {
+		t.Fatalf("開啟筆記失敗: %v", err)
+	}
+	note.Content = "# 計畫\n\n年度預算"
+	if err := editor.SaveNote(note); err != nil {
+		t.Fatalf("保存筆記失敗: %v", err)
+	}
+	if len(index.Search("季度", 10)) != 0 || len(index.Search("預算", 10)) != 1 {
+		t.Error("保存後應該以新的內容更新索引")
+	}
+
+	if err := fileManager.RenameFile("projects", "archive"); err != nil {
+		t.Fatalf("重新命名目錄失敗: %v", err)
+	}
+	want := []string{filepath.Join("archive", "plan.md")}
+	if got := searchPaths(index.Search("預算", 10)); !reflect.DeepEqual(got, want) {
+		t.Errorf("重新命名目錄後應該更新路徑: %v", got)
+	}
+
+	if err := fileManager.DeleteFile(want[0]); err != nil {
+		t.Fatalf("刪除檔案失敗: %v", err)
+	}
+	if len(index.Search("預算", 10)) != 0 {
+		t.Error("刪除後不應再有搜尋結果")
+	}
+}
+
+// TestSearchIndexSkipsSecrets 測試機密區塊、加密筆記和加密資料夾中的筆記不會寫入索引
+func TestSearchIndexSkipsSecrets(t *testing.T) {
+	baseDir, _, _, index := createTestSearchEnvironment(t)
+	content := strings.Join([]string{"# 帳號", "```secret", "銀行密碼", "```", "公開備註"}, "\n")
+	os.WriteFile(filepath.Join(baseDir, "accounts.md"), []byte(content), 0644)
+	os.WriteFile(filepath.Join(baseDir, "vault.enc"), []byte("銀行密碼"), 0644)
+	index.Rebuild()
+
+	if got := index.Search("密碼", 10); len(got) != 0 {
+		t.Errorf("機密區塊和加密筆記不應被搜尋到: %v", searchPaths(got))
+	}
+	if got := index.Search("公開", 10); len(got) != 1 || got[0].Line != 4 {
+		t.Errorf("機密區塊之後的內容應該保留行號: %+v", got)
+	}
+	data, _ := os.ReadFile(filepath.Join(baseDir, SearchIndexPath))
+	if strings.Contains(string(data), "密碼") {
+		t.Error("索引檔不應包含機密內容")
+	}
+
+	// 保險庫解鎖時加密資料夾中的筆記會讀到明文，也不應寫入索引
+	repo, baseDir, vault := createTestEncryptedFileRepository(t)
+	vault.Initialize("Password123!")
+	repo.WriteFile(filepath.Join("journal", "diary.md"), []byte("# 日記\n\n看診紀錄"))
+	repo.WriteFile("plan.md", []byte("# 計畫\n\n就醫安排"))
+	if err := repo.CreateEncryptedFolder("journal"); err != nil {
+		t.Fatalf("建立加密資料夾失敗: %v", err)
+	}
+	index = NewSearchIndex(repo)
+	if err := index.Rebuild(); err != nil {
+		t.Fatalf("建立索引失敗: %v", err)
+	}
+	if got := index.Search("就醫", 10); len(got) != 1 {
+		t.Fatalf("加密資料夾外的筆記應該可以搜尋: %v", searchPaths(got))
+	}
+	if got := index.Search("看診", 10); len(got) != 0 {
+		t.Errorf("加密資料夾中的筆記不應被搜尋到: %v", searchPaths(got))
+	}
+
+	// 筆記移入加密資料夾後從索引移除
+	os.Rename(filepath.Join(baseDir, "plan.md"), filepath.Join(baseDir, "journal", "plan.md"))
+	if err := index.RenameNote("plan.md", filepath.Join("journal", "plan.md")); err != nil {
+		t.Fatalf("更新索引失敗: %v", err)
+	}
+	if got := index.Search("就醫", 10); len(got) != 0 {
+		t.Errorf("移入加密資料夾的筆記不應被搜尋到: %v", searchPaths(got))
+	}
+	index.UpdateNote(filepath.Join("journal", "plan.md"))
+	index.ApplyFileChanges([]FileChangeEvent{{Path: filepath.Join("journal", "plan.md"), Op: FileModified}})
+	if got := index.Search("就醫", 10); len(got) != 0 {
+		t.Errorf("保存加密資料夾中的筆記後不應建立索引: %v", searchPaths(got))
+	}
+
+	data, _ = os.ReadFile(filepath.Join(baseDir, SearchIndexPath))
+	if strings.Contains(string(data), "看診") || strings.Contains(string(data), "就醫") {
+		t.Error("索引檔不應包含加密資料夾的內容")
+	}
+}
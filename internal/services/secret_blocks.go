@@ -0,0 +1,329 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含筆記中的機密區塊：以 ```secret 圍欄標記的區塊在檔案中以保險庫資料金鑰加密保存，
+// 其餘內容維持明文，仍可搜尋和比對差異
+package services
+
+import (
+	"crypto/sha256"   // 明文雜湊
+	"encoding/base64" // Base64 編碼
+	"errors"          // 錯誤處理
+	"fmt"             // 格式化輸出
+	"log"             // 日誌記錄
+	"regexp"          // 圍欄解析
+	"strings"         // 字串處理
+
+	"mac-notebook-app/internal/models" // 引入資料模型
+)
+
+// 機密區塊相關常數
+const (
+	SecretBlockLanguage     = "secret"        // 機密區塊圍欄的語言標記
+	SecretBlockSealedMarker = "nbsecret:v1"   // 加密後區塊內容的第一行
+	SecretBlockPlaceholder  = "> 🔒 機密區塊（已隱藏）" // 預覽和匯出時取代機密區塊的文字
+	secretBlockLineWidth    = 64              // 加密後每行的 Base64 字元數，方便比對差異
+)
+
+// ErrSecretBlockLocked 保險庫鎖定時無法加密新的機密區塊
+var ErrSecretBlockLocked = errors.New("保險庫已鎖定，請先解鎖再保存機密區塊")
+
+// fencePattern 比對程式碼圍欄的開頭行（最多三個空白縮排、三個以上的 ` 或 ~ 和語言標記）
+var fencePattern = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})[ \t]*([^`]*?)[ \t]*$")
+
+// SecretBlock 代表筆記中的一個機密區塊
+type SecretBlock struct {
+	StartLine int    // 開頭圍欄所在的行（從 0 起算）
+	EndLine   int    // 結尾圍欄所在的行（沒有結尾圍欄時為總行數）
+	Body      string // 圍欄之間的內容
+}
+
+// IsSealed 檢查區塊內容是否已加密
+// 回傳：是否已加密
+func (b SecretBlock) IsSealed() bool {
+	return isSealedSecretBody(b.Body)
+}
+
+// secretBlockState 記錄單一筆記機密區塊的加密狀態
+// 以明文的雜湊對應密文，保險庫鎖定後仍能將編輯器中的明文換回密文，而不需要在記憶體保留明文
+type secretBlockState struct {
+	keyID  string              // 機密區塊使用的保險庫資料金鑰 ID
+	sealed map[[32]byte]string // 區塊明文的 SHA-256 雜湊對應的加密內容，內容未變更時沿用同一份密文
+}
+
+// FindSecretBlocks 找出內容中的所有機密區塊
+// 參數：content（Markdown 內容）
+// 回傳：機密區塊列表
+//
+// 執行流程：
+// 1. 逐行掃描圍欄，其他語言的程式碼區塊內容不會被誤認為機密區塊
+// 2. 結尾圍欄需使用相同字元且長度不少於開頭圍欄
+// 3. 沒有結尾圍欄的機密區塊延伸到內容結尾
+func FindSecretBlocks(content string) []SecretBlock {
+	if !strings.Contains(content, SecretBlockLanguage) {
+		return nil
+	}
+	lines := strings.Split(content, "\n")
+
+	var blocks []SecretBlock
+	for i := 0; i < len(lines); i++ {
+		match := fencePattern.FindStringSubmatch(strings.TrimSuffix(lines[i], "\r"))
+		if match == nil {
+			continue
+		}
+
+		end := len(lines)
+		for j := i + 1; j < len(lines); j++ {
+			if isClosingFence(lines[j], match[1]) {
+				end = j
+				break
+			}
+		}
+
+		if match[2] == SecretBlockLanguage {
+			blocks = append(blocks, SecretBlock{
+				StartLine: i,
+				EndLine:   end,
+				Body:      strings.Join(lines[i+1:end], "\n"),
+			})
+		}
+		i = end
+	}
+	return blocks
+}
+
+// HasSecretBlocks 檢查內容是否包含機密區塊
+// 參數：content（Markdown 內容）
+// 回傳：是否包含機密區塊
+func HasSecretBlocks(content string) bool {
+	return len(FindSecretBlocks(content)) > 0
+}
+
+// RedactSecretBlocks 以佔位文字取代所有機密區塊（包含圍欄）
+// 參數：content（Markdown 內容）
+// 回傳：取代後的內容
+func RedactSecretBlocks(content string) string {
+	blocks := FindSecretBlocks(content)
+	if len(blocks) == 0 {
+		return content
+	}
+
+	lines := strings.Split(content, "\n")
+	result := make([]string, 0, len(lines))
+	next := 0
+	for _, block := range blocks {
+		result = append(result, lines[next:block.StartLine]...)
+		result = append(result, SecretBlockPlaceholder)
+		next = block.EndLine + 1
+	}
+	if next < len(lines) {
+		result = append(result, lines[next:]...)
+	}
+	return strings.Join(result, "\n")
+}
+
+// mapSecretBlocks 逐一轉換機密區塊的內容，圍欄和其他內容維持不變
+// 參數：content（Markdown 內容）、transform（轉換函數，輸入和輸出皆為區塊內容）
+// 回傳：轉換後的內容和可能的錯誤
+func mapSecretBlocks(content string, transform func(body string) (string, error)) (string, error) {
+	blocks := FindSecretBlocks(content)
+	if len(blocks) == 0 {
+		return content, nil
+	}
+
+	lines := strings.Split(content, "\n")
+	result := make([]string, 0, len(lines))
+	next := 0
+	for _, block := range blocks {
+		result = append(result, lines[next:block.StartLine+1]...)
+		if strings.TrimSpace(block.Body) != "" {
+			body, err := transform(block.Body)
+			if err != nil {
+				return "", err
+			}
+			if body != "" {
+				result = append(result, strings.Split(body, "\n")...)
+			}
+		} else {
+			result = append(result, lines[block.StartLine+1:block.EndLine]...)
+		}
+		next = block.EndLine
+	}
+	result = append(result, lines[next:]...)
+	return strings.Join(result, "\n"), nil
+}
+
+// isClosingFence 檢查是否為對應的結尾圍欄
+// 參數：line（行內容）、fence（開頭圍欄的字元）
+// 回傳：是否為結尾圍欄
+func isClosingFence(line, fence string) bool {
+	trimmed := strings.TrimRight(line, " \t\r")
+	indent := len(trimmed) - len(strings.TrimLeft(trimmed, " "))
+	if indent > 3 {
+		return false
+	}
+	trimmed = trimmed[indent:]
+	return len(trimmed) >= len(fence) && strings.Trim(trimmed, fence[:1]) == ""
+}
+
+// isSealedSecretBody 檢查區塊內容是否為加密格式
+// 參數：body（區塊內容）
+// 回傳：是否為加密格式
+func isSealedSecretBody(body string) bool {
+	firstLine, _, _ := strings.Cut(body, "\n")
+	return strings.TrimSpace(firstLine) == SecretBlockSealedMarker
+}
+
+// sealSecretBody 以保險庫資料金鑰加密區塊內容
+// 參數：vault（保險庫服務）、body（區塊明文）、keyID（資料金鑰 ID，空字串表示產生新的金鑰）
+// 回傳：加密後的區塊內容、使用的資料金鑰 ID 和可能的錯誤
+func sealSecretBody(vault VaultService, body, keyID string) (string, string, error) {
+	data, keyID, err := vault.EncryptNote(body, AlgorithmAES256, keyID)
+	if err != nil {
+		return "", "", err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	lines := []string{SecretBlockSealedMarker}
+	for len(encoded) > secretBlockLineWidth {
+		lines = append(lines, encoded[:secretBlockLineWidth])
+		encoded = encoded[secretBlockLineWidth:]
+	}
+	lines = append(lines, encoded)
+	return strings.Join(lines, "\n"), keyID, nil
+}
+
+// openSecretBody 解密區塊內容
+// 參數：vault（保險庫服務）、body（加密後的區塊內容）
+// 回傳：區塊明文、使用的資料金鑰 ID 和可能的錯誤
+func openSecretBody(vault VaultService, body string) (string, string, error) {
+	_, encoded, _ := strings.Cut(body, "\n")
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+	if err != nil {
+		return "", "", fmt.Errorf("機密區塊格式無效: %w", err)
+	}
+
+	plaintext, info, err := vault.DecryptNote(data)
+	if err != nil {
+		return "", "", err
+	}
+	return plaintext, info.KeyID, nil
+}
+
+// RevealSecretBlocks 解密內容中已加密的機密區塊
+// 參數：noteID（筆記 ID）、content（Markdown 內容）
+// 回傳：機密區塊解密後的內容和可能的錯誤（保險庫鎖定時為 ErrVaultLocked）
+//
+// 執行流程：
+// 1. 沒有加密的機密區塊時直接回傳原內容
+// 2. 確認保險庫已解鎖
+// 3. 逐一解密區塊，記錄明文對應的密文和資料金鑰，之後內容未變更時沿用同一份密文
+func (e *editorService) RevealSecretBlocks(noteID, content string) (string, error) {
+	if !HasSecretBlocks(content) {
+		return content, nil
+	}
+	if e.vaultSvc == nil || !e.vaultSvc.IsUnlocked() {
+		return "", ErrVaultLocked
+	}
+
+	state := e.secretBlockState(noteID)
+	return mapSecretBlocks(content, func(body string) (string, error) {
+		if !isSealedSecretBody(body) {
+			return body, nil
+		}
+		plaintext, keyID, err := openSecretBody(e.vaultSvc, body)
+		if err != nil {
+			return "", fmt.Errorf("解密機密區塊失敗: %w", err)
+		}
+		if state.keyID == "" {
+			state.keyID = keyID
+		}
+		state.sealed[sha256.Sum256([]byte(plaintext))] = body
+		return plaintext, nil
+	})
+}
+
+// sealSecretBlocks 取得保存到檔案的內容，機密區塊以加密格式寫入
+// 參數：note（要保存的筆記）
+// 回傳：要寫入檔案的內容和可能的錯誤
+//
+// 執行流程：
+// 1. 已加密的區塊維持原樣
+// 2. 內容未變更的區塊沿用上次的密文，避免每次保存都產生差異
+// 3. 新增或修改的區塊需要保險庫已解鎖，以筆記的資料金鑰加密
+func (e *editorService) sealSecretBlocks(note *models.Note) (string, error) {
+	if !HasSecretBlocks(note.Content) {
+		return note.Content, nil
+	}
+
+	state := e.secretBlockState(note.ID)
+	return mapSecretBlocks(note.Content, func(body string) (string, error) {
+		if isSealedSecretBody(body) {
+			return body, nil
+		}
+		if sealed, ok := state.sealed[sha256.Sum256([]byte(body))]; ok {
+			return sealed, nil
+		}
+		if e.vaultSvc == nil || !e.vaultSvc.IsUnlocked() {
+			return "", ErrSecretBlockLocked
+		}
+
+		sealed, keyID, err := sealSecretBody(e.vaultSvc, body, state.keyID)
+		if err != nil {
+			return "", fmt.Errorf("加密機密區塊失敗: %w", err)
+		}
+		state.keyID = keyID
+		state.sealed[sha256.Sum256([]byte(body))] = sealed
+		return sealed, nil
+	})
+}
+
+// ConcealSecretBlocks 將內容中已解密的機密區塊換回密文
+// 參數：noteID（筆記 ID）、content（Markdown 內容）
+// 回傳：機密區塊換回密文後的內容
+//
+// 執行流程：
+// 1. 已解密的區塊換回上次保存或開啟時的密文
+// 2. 保存後又修改過的區塊在保險庫鎖定時無法加密，移除其內容（與加密筆記鎖定時捨棄未保存內容相同）
+func (e *editorService) ConcealSecretBlocks(noteID, content string) string {
+	state := e.secretBlocks[noteID]
+	concealed, _ := mapSecretBlocks(content, func(body string) (string, error) {
+		if isSealedSecretBody(body) {
+			return body, nil
+		}
+		if state != nil {
+			if sealed, ok := state.sealed[sha256.Sum256([]byte(body))]; ok {
+				return sealed, nil
+			}
+		}
+		if e.vaultSvc != nil && e.vaultSvc.IsUnlocked() {
+			if sealed, keyID, err := sealSecretBody(e.vaultSvc, body, e.secretBlockState(noteID).keyID); err == nil {
+				e.secretBlockState(noteID).keyID = keyID
+				return sealed, nil
+			}
+		}
+		log.Printf("無法加密筆記 %s 中未保存的機密區塊，已捨棄其內容", noteID)
+		return "", nil
+	})
+	return concealed
+}
+
+// concealActiveSecretBlocks 保險庫鎖定時將活躍筆記中已解密的機密區塊換回密文
+func (e *editorService) concealActiveSecretBlocks() {
+	for noteID, note := range e.activeNotes {
+		if note.IsEncrypted {
+			continue
+		}
+		note.Content = e.ConcealSecretBlocks(noteID, note.Content)
+	}
+}
+
+// secretBlockState 取得筆記機密區塊的加密狀態，不存在時建立
+// 參數：noteID（筆記 ID）
+// 回傳：加密狀態
+func (e *editorService) secretBlockState(noteID string) *secretBlockState {
+	state, ok := e.secretBlocks[noteID]
+	if !ok {
+		state = &secretBlockState{sealed: make(map[[32]byte]string)}
+		e.secretBlocks[noteID] = state
+	}
+	return state
+}
@@ -0,0 +1,159 @@
+// Package services 提供機密區塊的單元測試
+// 測試圍欄解析、佔位文字取代、保存時加密、鎖定時換回密文，以及匯出時預設隱藏機密區塊
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mac-notebook-app/internal/models"
+)
+
+// TestFindSecretBlocks 測試只有 secret 圍欄會被視為機密區塊
+func TestFindSecretBlocks(t *testing.T) {
+	content := strings.Join([]string{
+		"# 部署手冊",
+		"```go",
+		"```secret",
+		"這是程式碼範例，不是機密區塊",
+		"```",
+		"```secret",
+		"API_KEY=abc123",
+		"```",
+		"~~~~ secret",
+		"| 帳號 | 密碼 |",
+		"~~~~",
+		"```secret",
+		"沒有結尾圍欄",
+	}, "\n")
+
+	blocks := FindSecretBlocks(content)
+	if len(blocks) != 3 {
+		t.Fatalf("應該找到 3 個機密區塊，實際為 %d: %+v", len(blocks), blocks)
+	}
+	if blocks[0].Body != "API_KEY=abc123" || blocks[0].StartLine != 5 || blocks[0].EndLine != 7 {
+		t.Errorf("第一個機密區塊不正確: %+v", blocks[0])
+	}
+	if blocks[1].Body != "| 帳號 | 密碼 |" {
+		t.Errorf("應該支援 ~~~ 圍欄: %+v", blocks[1])
+	}
+	if blocks[2].Body != "沒有結尾圍欄" || blocks[2].EndLine != 13 {
+		t.Errorf("沒有結尾圍欄時應該延伸到內容結尾: %+v", blocks[2])
+	}
+
+	redacted := RedactSecretBlocks(content)
+	if strings.Contains(redacted, "abc123") || strings.Contains(redacted, "密碼") || strings.Contains(redacted, "沒有結尾圍欄") {
+		t.Errorf("取代後不應包含機密內容: %s", redacted)
+	}
+	if !strings.Contains(redacted, "這是程式碼範例") || strings.Count(redacted, SecretBlockPlaceholder) != 3 {
+		t.Errorf("其他內容應該保留，機密區塊以佔位文字取代: %s", redacted)
+	}
+	if HasSecretBlocks("# 沒有 secret 區塊") {
+		t.Error("沒有圍欄時不應視為包含機密區塊")
+	}
+}
+
+// TestEditorServiceSecretBlocks 測試機密區塊以密文保存、開啟時解密，鎖定後換回密文
+func TestEditorServiceSecretBlocks(t *testing.T) {
+	service, mockRepo := createTestEditorService()
+	vault, _ := createTestVaultService(t)
+	service.SetOptions(EditorOptions{Vault: vault})
+
+	content := "# 部署手冊\n\n執行部署前設定金鑰：\n\n```secret\nAPI_KEY=abc123\n```\n\n完成後重新啟動服務。"
+	note, _ := service.CreateNote("部署手冊", content)
+
+	// 保險庫尚未解鎖時無法加密新的機密區塊
+	if err := service.SaveNote(note); !errors.Is(err, ErrSecretBlockLocked) {
+		t.Errorf("保險庫鎖定時保存機密區塊應該失敗: %v", err)
+	}
+
+	if err := vault.Initialize("Password123!"); err != nil {
+		t.Fatalf("初始化保險庫失敗: %v", err)
+	}
+	if err := service.SaveNote(note); err != nil {
+		t.Fatalf("保存筆記失敗: %v", err)
+	}
+	saved := string(mockRepo.files[note.FilePath])
+	if strings.Contains(saved, "abc123") {
+		t.Error("保存的檔案不應包含機密區塊的明文")
+	}
+	if !strings.Contains(saved, "執行部署前設定金鑰") || !strings.Contains(saved, "```secret\n"+SecretBlockSealedMarker) {
+		t.Errorf("其他內容應該維持明文，機密區塊以密文保存: %s", saved)
+	}
+	if note.Content != content {
+		t.Error("保存後編輯器中的內容應該維持明文")
+	}
+
+	// 內容未變更時再次保存不應產生差異
+	if err := service.SaveNote(note); err != nil || string(mockRepo.files[note.FilePath]) != saved {
+		t.Errorf("內容未變更時應該沿用同一份密文: %v", err)
+	}
+
+	opened, err := service.OpenNote(note.FilePath)
+	if err != nil || opened.Content != content {
+		t.Fatalf("保險庫解鎖時開啟應該解密機密區塊: %q, %v", opened.Content, err)
+	}
+
+	// 修改其他內容後未保存的機密區塊在鎖定時捨棄，已保存的換回密文
+	edited := strings.Replace(opened.Content, "```\n\n完成", "```\n\n```secret\n未保存的密碼\n```\n\n完成", 1)
+	service.UpdateContent(opened.ID, edited)
+	vault.Lock()
+	if strings.Contains(opened.Content, "abc123") || strings.Contains(opened.Content, "未保存的密碼") {
+		t.Errorf("鎖定後記憶體中的筆記不應包含機密區塊的明文: %s", opened.Content)
+	}
+	if expected := strings.Replace(saved, "```\n\n完成", "```\n\n```secret\n```\n\n完成", 1); opened.Content != expected {
+		t.Errorf("鎖定後應該換回保存時的密文: %s", opened.Content)
+	}
+	if concealed := service.(SecretBlockEditor).ConcealSecretBlocks(opened.ID, content); concealed != saved {
+		t.Errorf("鎖定後編輯器內容應該能換回密文: %s", concealed)
+	}
+
+	// 鎖定時開啟的筆記維持密文，解鎖後可以解密
+	locked, err := service.OpenNote(note.FilePath)
+	if err != nil || locked.Content != saved {
+		t.Fatalf("鎖定時開啟應該維持密文: %v", err)
+	}
+	if _, err := service.(SecretBlockEditor).RevealSecretBlocks(locked.ID, locked.Content); !errors.Is(err, ErrVaultLocked) {
+		t.Errorf("鎖定時解密應該回傳 ErrVaultLocked: %v", err)
+	}
+	if err := service.SaveNote(locked); err != nil {
+		t.Errorf("鎖定時保存未解密的機密區塊應該成功: %v", err)
+	}
+	vault.Unlock("Password123!")
+	if revealed, err := service.(SecretBlockEditor).RevealSecretBlocks(locked.ID, locked.Content); err != nil || revealed != content {
+		t.Errorf("解鎖後應該能解密機密區塊: %q, %v", revealed, err)
+	}
+}
+
+// TestExportRedactsSecretBlocks 測試匯出和分享預設以佔位文字取代機密區塊
+func TestExportRedactsSecretBlocks(t *testing.T) {
+	exportSvc := NewExportService(&mockExportEditorService{})
+	note := &models.Note{ID: "runbook", Title: "部署手冊", Content: "# 部署手冊\n\n```secret\nAPI_KEY=abc123\n```\n\n完成"}
+	outputPath := filepath.Join(t.TempDir(), "runbook.md")
+
+	if err := exportSvc.(*exportServiceImpl).exportToMarkdown(note, outputPath, &ExportOptions{}); err != nil {
+		t.Fatalf("匯出失敗: %v", err)
+	}
+	data, _ := os.ReadFile(outputPath)
+	if strings.Contains(string(data), "abc123") || !strings.Contains(string(data), SecretBlockPlaceholder) {
+		t.Errorf("匯出的檔案應該隱藏機密區塊: %s", data)
+	}
+	if !strings.Contains(note.Content, "abc123") {
+		t.Error("匯出不應修改原筆記內容")
+	}
+
+	if err := exportSvc.(*exportServiceImpl).exportToMarkdown(note, outputPath, &ExportOptions{IncludeSecretBlocks: true}); err != nil {
+		t.Fatalf("匯出失敗: %v", err)
+	}
+	if data, _ := os.ReadFile(outputPath); !strings.Contains(string(data), "abc123") {
+		t.Errorf("選擇包含機密區塊時應該匯出明文: %s", data)
+	}
+
+	result, err := exportSvc.ShareNote(note, &ShareOptions{ShareType: ShareTypeClipboard})
+	if err != nil || strings.Contains(result.ClipboardContent, "abc123") {
+		t.Errorf("分享時應該隱藏機密區塊: %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,362 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含加密工作階段管理器，負責在記憶體中暫存已衍生的金鑰，
+// 並在閒置逾時、視窗失去焦點或手動鎖定時清除金鑰
+package services
+
+import (
+	"sync" // 同步原語
+	"time" // 時間處理
+
+	"mac-notebook-app/internal/models" // 引入資料模型
+)
+
+// SessionLockReason 代表工作階段被鎖定的原因
+type SessionLockReason string
+
+// 工作階段鎖定原因常數
+const (
+	SessionLockManual SessionLockReason = "manual" // 使用者手動鎖定
+	SessionLockIdle   SessionLockReason = "idle"   // 閒置逾時自動鎖定
+	SessionLockBlur   SessionLockReason = "blur"   // 視窗失去焦點自動鎖定
+)
+
+// SessionLockListener 工作階段鎖定時的回調函數類型
+type SessionLockListener func(reason SessionLockReason)
+
+// SessionManager 定義加密工作階段管理的介面
+// 解鎖後暫存金鑰，閒置逾時或失去焦點時自動鎖定並清零金鑰
+type SessionManager interface {
+	// StoreKey 暫存金鑰副本並開始（或延續）工作階段
+	// 參數：name（金鑰名稱）、key（金鑰資料，會複製保存）
+	StoreKey(name string, key []byte)
+
+	// GetKey 取得金鑰副本（不記錄活動，避免自動保存延長工作階段）
+	// 參數：name（金鑰名稱）
+	// 回傳：金鑰副本和是否存在
+	GetKey(name string) ([]byte, bool)
+
+	// HasKey 檢查指定金鑰是否存在
+	// 參數：name（金鑰名稱）
+	// 回傳：是否存在
+	HasKey(name string) bool
+
+	// DeleteKey 清零並移除指定金鑰，不影響其他金鑰也不通知監聽者
+	// 參數：name（金鑰名稱）
+	DeleteKey(name string)
+
+	// IsUnlocked 檢查工作階段是否持有任何金鑰
+	// 回傳：是否已解鎖
+	IsUnlocked() bool
+
+	// Touch 記錄使用者活動，延後閒置自動鎖定
+	Touch()
+
+	// Lock 手動鎖定工作階段，清零所有金鑰並通知監聽者
+	Lock()
+
+	// SetTimeout 設定閒置自動鎖定時間
+	// 參數：timeout（閒置時間，0 表示不自動鎖定）
+	SetTimeout(timeout time.Duration)
+
+	// GetTimeout 取得閒置自動鎖定時間
+	// 回傳：閒置時間
+	GetTimeout() time.Duration
+
+	// SetLockOnBlur 設定視窗失去焦點時是否鎖定
+	// 參數：enabled（是否啟用）
+	SetLockOnBlur(enabled bool)
+
+	// IsLockOnBlur 檢查視窗失去焦點時是否鎖定
+	// 回傳：是否啟用
+	IsLockOnBlur() bool
+
+	// ApplySettings 套用應用程式設定中的工作階段選項
+	// 參數：settings（應用程式設定）
+	ApplySettings(settings *models.Settings)
+
+	// HandleWindowBlur 處理視窗失去焦點事件，啟用失去焦點鎖定時鎖定工作階段
+	HandleWindowBlur()
+
+	// RemainingTime 取得距離閒置自動鎖定的剩餘時間
+	// 回傳：剩餘時間（未解鎖或未啟用自動鎖定時為 0）
+	RemainingTime() time.Duration
+
+	// AddLockListener 新增工作階段鎖定監聽者
+	// 參數：listener（鎖定時的回調函數）
+	AddLockListener(listener SessionLockListener)
+
+	// SetDispatcher 設定執行鎖定回調的方式（例如切換到 UI 執行緒）
+	// 參數：dispatch（執行回調的函數，nil 表示直接執行）
+	SetDispatcher(dispatch func(func()))
+}
+
+// sessionManager 實作 SessionManager 介面
+type sessionManager struct {
+	keys         map[string][]byte     // 暫存的金鑰
+	timeout      time.Duration         // 閒置自動鎖定時間
+	lockOnBlur   bool                  // 失去焦點時是否鎖定
+	lastActivity time.Time             // 最後活動時間
+	timer        *time.Timer           // 閒置檢查計時器
+	listeners    []SessionLockListener // 鎖定監聽者
+	dispatch     func(func())          // 回調執行方式
+	mutex        sync.Mutex            // 互斥鎖，保護所有欄位
+}
+
+// NewSessionManager 建立新的工作階段管理器
+// 參數：timeout（閒置自動鎖定時間，0 表示不自動鎖定）
+// 回傳：SessionManager 介面實例，初始為鎖定狀態
+func NewSessionManager(timeout time.Duration) SessionManager {
+	return &sessionManager{
+		keys:    make(map[string][]byte),
+		timeout: timeout,
+	}
+}
+
+// StoreKey 暫存金鑰副本並開始工作階段
+// 參數：name（金鑰名稱）、key（金鑰資料）
+//
+// 執行流程：
+// 1. 清零同名的舊金鑰
+// 2. 保存新金鑰的副本
+// 3. 記錄活動時間並啟動閒置計時器
+func (s *sessionManager) StoreKey(name string, key []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	zeroBytes(s.keys[name])
+	s.keys[name] = append([]byte(nil), key...)
+	s.lastActivity = time.Now()
+	s.scheduleLocked()
+}
+
+// GetKey 取得金鑰副本
+// 參數：name（金鑰名稱）
+// 回傳：金鑰副本和是否存在
+func (s *sessionManager) GetKey(name string) ([]byte, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key, exists := s.keys[name]
+	if !exists {
+		return nil, false
+	}
+
+	return append([]byte(nil), key...), true
+}
+
+// HasKey 檢查指定金鑰是否存在
+// 參數：name（金鑰名稱）
+// 回傳：是否存在
+func (s *sessionManager) HasKey(name string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, exists := s.keys[name]
+	return exists
+}
+
+// DeleteKey 清零並移除指定金鑰
+// 參數：name（金鑰名稱）
+func (s *sessionManager) DeleteKey(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	zeroBytes(s.keys[name])
+	delete(s.keys, name)
+	if len(s.keys) == 0 {
+		s.scheduleLocked()
+	}
+}
+
+// IsUnlocked 檢查工作階段是否持有任何金鑰
+// 回傳：是否已解鎖
+func (s *sessionManager) IsUnlocked() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.keys) > 0
+}
+
+// Touch 記錄使用者活動
+func (s *sessionManager) Touch() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.keys) > 0 {
+		s.lastActivity = time.Now()
+	}
+}
+
+// Lock 手動鎖定工作階段
+func (s *sessionManager) Lock() {
+	s.lock(SessionLockManual)
+}
+
+// SetTimeout 設定閒置自動鎖定時間
+// 參數：timeout（閒置時間，0 表示不自動鎖定）
+func (s *sessionManager) SetTimeout(timeout time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if timeout < 0 {
+		timeout = 0
+	}
+	s.timeout = timeout
+	s.scheduleLocked()
+}
+
+// GetTimeout 取得閒置自動鎖定時間
+// 回傳：閒置時間
+func (s *sessionManager) GetTimeout() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.timeout
+}
+
+// SetLockOnBlur 設定視窗失去焦點時是否鎖定
+// 參數：enabled（是否啟用）
+func (s *sessionManager) SetLockOnBlur(enabled bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.lockOnBlur = enabled
+}
+
+// IsLockOnBlur 檢查視窗失去焦點時是否鎖定
+// 回傳：是否啟用
+func (s *sessionManager) IsLockOnBlur() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.lockOnBlur
+}
+
+// ApplySettings 套用應用程式設定中的工作階段選項
+// 參數：settings（應用程式設定）
+func (s *sessionManager) ApplySettings(settings *models.Settings) {
+	if settings == nil {
+		return
+	}
+	s.SetTimeout(time.Duration(settings.SessionTimeout) * time.Minute)
+	s.SetLockOnBlur(settings.LockOnBlur)
+}
+
+// HandleWindowBlur 處理視窗失去焦點事件
+func (s *sessionManager) HandleWindowBlur() {
+	if s.IsLockOnBlur() && s.IsUnlocked() {
+		s.lock(SessionLockBlur)
+	}
+}
+
+// RemainingTime 取得距離閒置自動鎖定的剩餘時間
+// 回傳：剩餘時間
+func (s *sessionManager) RemainingTime() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.keys) == 0 || s.timeout <= 0 {
+		return 0
+	}
+
+	remaining := s.timeout - time.Since(s.lastActivity)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// AddLockListener 新增工作階段鎖定監聽者
+// 參數：listener（鎖定時的回調函數）
+func (s *sessionManager) AddLockListener(listener SessionLockListener) {
+	if listener == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.listeners = append(s.listeners, listener)
+}
+
+// SetDispatcher 設定執行鎖定回調的方式
+// 參數：dispatch（執行回調的函數）
+func (s *sessionManager) SetDispatcher(dispatch func(func())) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.dispatch = dispatch
+}
+
+// lock 鎖定工作階段
+// 參數：reason（鎖定原因）
+//
+// 執行流程：
+// 1. 停止閒置計時器
+// 2. 清零並移除所有金鑰
+// 3. 在鎖外依序通知所有監聽者
+func (s *sessionManager) lock(reason SessionLockReason) {
+	s.mutex.Lock()
+	wasUnlocked := len(s.keys) > 0
+	for name, key := range s.keys {
+		zeroBytes(key)
+		delete(s.keys, name)
+	}
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	listeners := append([]SessionLockListener(nil), s.listeners...)
+	dispatch := s.dispatch
+	s.mutex.Unlock()
+
+	if !wasUnlocked {
+		return
+	}
+
+	notify := func() {
+		for _, listener := range listeners {
+			listener(reason)
+		}
+	}
+	if dispatch != nil {
+		dispatch(notify)
+	} else {
+		notify()
+	}
+}
+
+// scheduleLocked 依剩餘閒置時間重新安排計時器（呼叫者必須持有鎖）
+func (s *sessionManager) scheduleLocked() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if len(s.keys) == 0 || s.timeout <= 0 {
+		return
+	}
+
+	delay := s.timeout - time.Since(s.lastActivity)
+	if delay < 0 {
+		delay = 0
+	}
+	s.timer = time.AfterFunc(delay, s.checkIdle)
+}
+
+// checkIdle 計時器到期時檢查是否已閒置逾時
+// 期間有活動時重新安排計時器，否則鎖定工作階段
+func (s *sessionManager) checkIdle() {
+	s.mutex.Lock()
+	if len(s.keys) == 0 || s.timeout <= 0 {
+		s.mutex.Unlock()
+		return
+	}
+	if time.Since(s.lastActivity) < s.timeout {
+		s.scheduleLocked()
+		s.mutex.Unlock()
+		return
+	}
+	s.mutex.Unlock()
+
+	s.lock(SessionLockIdle)
+}
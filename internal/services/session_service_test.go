@@ -0,0 +1,170 @@
+// Package services 提供工作階段管理器的單元測試
+// 測試金鑰暫存、清零、閒置自動鎖定和失去焦點鎖定的正確性
+package services
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"mac-notebook-app/internal/models"
+)
+
+// TestSessionManagerStoreAndLock 測試金鑰暫存和手動鎖定
+func TestSessionManagerStoreAndLock(t *testing.T) {
+	session := NewSessionManager(0)
+	if session.IsUnlocked() {
+		t.Fatal("新的工作階段應該為鎖定狀態")
+	}
+
+	original := []byte{1, 2, 3, 4}
+	session.StoreKey("test", original)
+	original[0] = 9 // 修改原始資料不應影響暫存的副本
+
+	key, ok := session.GetKey("test")
+	if !ok || !bytes.Equal(key, []byte{1, 2, 3, 4}) {
+		t.Fatalf("取得的金鑰不符: %v, %v", key, ok)
+	}
+
+	var reasons []SessionLockReason
+	session.AddLockListener(func(reason SessionLockReason) {
+		reasons = append(reasons, reason)
+	})
+
+	session.Lock()
+	if session.IsUnlocked() || session.HasKey("test") {
+		t.Error("鎖定後不應持有任何金鑰")
+	}
+	if len(reasons) != 1 || reasons[0] != SessionLockManual {
+		t.Errorf("應該以手動鎖定原因通知一次，實際: %v", reasons)
+	}
+
+	// 已鎖定時再次鎖定不應重複通知
+	session.Lock()
+	if len(reasons) != 1 {
+		t.Errorf("重複鎖定不應再次通知，實際: %v", reasons)
+	}
+}
+
+// TestSessionManagerIdleTimeout 測試閒置逾時自動鎖定和活動延後鎖定
+func TestSessionManagerIdleTimeout(t *testing.T) {
+	session := NewSessionManager(150 * time.Millisecond)
+
+	locked := make(chan SessionLockReason, 1)
+	session.AddLockListener(func(reason SessionLockReason) {
+		locked <- reason
+	})
+
+	session.StoreKey("test", []byte{1})
+	if remaining := session.RemainingTime(); remaining <= 0 || remaining > 150*time.Millisecond {
+		t.Errorf("剩餘時間不正確: %v", remaining)
+	}
+
+	// 持續活動時不應鎖定
+	for i := 0; i < 4; i++ {
+		time.Sleep(60 * time.Millisecond)
+		session.Touch()
+	}
+	if !session.IsUnlocked() {
+		t.Fatal("持續活動時工作階段不應被鎖定")
+	}
+
+	select {
+	case reason := <-locked:
+		if reason != SessionLockIdle {
+			t.Errorf("鎖定原因應該是閒置逾時，實際: %s", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("閒置逾時後應該自動鎖定")
+	}
+
+	if session.IsUnlocked() {
+		t.Error("閒置逾時後不應持有任何金鑰")
+	}
+}
+
+// TestSessionManagerWindowBlur 測試視窗失去焦點時的鎖定行為
+func TestSessionManagerWindowBlur(t *testing.T) {
+	session := NewSessionManager(0)
+	session.StoreKey("test", []byte{1})
+
+	// 未啟用失去焦點鎖定時不應鎖定
+	session.HandleWindowBlur()
+	if !session.IsUnlocked() {
+		t.Fatal("未啟用失去焦點鎖定時不應鎖定")
+	}
+
+	settings := models.NewDefaultSettings()
+	settings.SetLockOnBlur(true)
+	settings.UpdateSessionTimeout(30)
+	session.ApplySettings(settings)
+
+	if session.GetTimeout() != 30*time.Minute || !session.IsLockOnBlur() {
+		t.Errorf("套用設定失敗: %v, %v", session.GetTimeout(), session.IsLockOnBlur())
+	}
+
+	var reason SessionLockReason
+	session.AddLockListener(func(r SessionLockReason) { reason = r })
+	session.HandleWindowBlur()
+	if session.IsUnlocked() || reason != SessionLockBlur {
+		t.Errorf("啟用失去焦點鎖定時應該鎖定，原因: %s", reason)
+	}
+}
+
+// TestSessionManagerDispatcher 測試鎖定回調透過指定的方式執行
+func TestSessionManagerDispatcher(t *testing.T) {
+	session := NewSessionManager(0)
+
+	var mu sync.Mutex
+	dispatched := 0
+	session.SetDispatcher(func(fn func()) {
+		mu.Lock()
+		dispatched++
+		mu.Unlock()
+		fn()
+	})
+
+	called := false
+	session.AddLockListener(func(SessionLockReason) { called = true })
+	session.StoreKey("test", []byte{1})
+	session.Lock()
+
+	if dispatched != 1 || !called {
+		t.Errorf("鎖定回調應該透過指定方式執行一次: %d, %v", dispatched, called)
+	}
+}
+
+// TestEditorServiceClosesNotesOnLock 測試工作階段鎖定時編輯器關閉已解密的筆記
+func TestEditorServiceClosesNotesOnLock(t *testing.T) {
+	service, _ := createTestEditorService()
+	vault, _ := createTestVaultService(t)
+	service.SetOptions(EditorOptions{Vault: vault})
+
+	plain, _ := service.CreateNote("一般筆記", "公開內容")
+	secret, _ := service.CreateNote("加密筆記", "機密內容")
+	if err := service.(*editorService).EnableEncryption(secret.ID, "TestPassword123!", AlgorithmAES256, false); err != nil {
+		t.Fatalf("啟用加密失敗: %v", err)
+	}
+
+	// 模擬開啟後保留的合併基準、front matter 和機密區塊狀態
+	impl := service.(*editorService)
+	impl.diskStates[secret.ID] = &noteDiskState{base: "機密內容"}
+	impl.frontMatters[secret.ID] = &FrontMatter{Title: "加密筆記"}
+	impl.secretBlocks[secret.ID] = &secretBlockState{}
+
+	vault.Session().Lock()
+
+	if _, exists := service.GetActiveNote(secret.ID); exists {
+		t.Error("鎖定後加密筆記應該被關閉")
+	}
+	if impl.diskStates[secret.ID] != nil || impl.frontMatters[secret.ID] != nil || impl.secretBlocks[secret.ID] != nil {
+		t.Error("鎖定後應該清除加密筆記的合併基準、front matter 和機密區塊狀態")
+	}
+	if secret.Content != "" {
+		t.Error("鎖定後加密筆記的明文內容應該被清除")
+	}
+	if _, exists := service.GetActiveNote(plain.ID); !exists {
+		t.Error("鎖定不應關閉一般筆記")
+	}
+}
@@ -0,0 +1,134 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含 GF(2^8) 上的 Shamir 秘密分享：將秘密拆成 N 份片段，任意 K 份即可還原，
+// 少於 K 份則無法得到秘密的任何資訊
+package services
+
+import (
+	"crypto/rand" // 安全隨機數產生器
+	"errors"      // 錯誤處理
+	"fmt"         // 格式化輸出
+	"io"          // 輸入輸出介面
+)
+
+// MaxShamirShares 可以拆分的最大片段數（片段編號使用 1 到 255）
+const MaxShamirShares = 255
+
+// splitSecret 將秘密拆成多份片段
+// 參數：secret（秘密）、shares（片段總數 N）、threshold（還原需要的片段數 K）
+// 回傳：片段列表（每份長度為秘密長度加 1，最後一個位元組是片段編號）和可能的錯誤
+//
+// 執行流程：
+// 1. 確認 2 <= K <= N <= 255
+// 2. 秘密的每個位元組各自產生一個 K-1 次的隨機多項式，常數項為該位元組
+// 3. 第 i 份片段保存每個多項式在 x = i 的值
+func splitSecret(secret []byte, shares, threshold int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("秘密不能為空")
+	}
+	if threshold < 2 || threshold > shares || shares > MaxShamirShares {
+		return nil, fmt.Errorf("片段設定無效：需要 2 <= 門檻 (%d) <= 片段數 (%d) <= %d", threshold, shares, MaxShamirShares)
+	}
+
+	result := make([][]byte, shares)
+	for i := range result {
+		result[i] = make([]byte, len(secret)+1)
+		result[i][len(secret)] = byte(i + 1)
+	}
+
+	coefficients := make([]byte, threshold)
+	defer zeroBytes(coefficients)
+	for index, value := range secret {
+		coefficients[0] = value
+		if _, err := io.ReadFull(rand.Reader, coefficients[1:]); err != nil {
+			return nil, fmt.Errorf("產生隨機係數失敗: %w", err)
+		}
+		for _, share := range result {
+			share[index] = gfEvaluate(coefficients, share[len(secret)])
+		}
+	}
+
+	return result, nil
+}
+
+// combineShares 以拉格朗日插值從片段還原秘密
+// 參數：shares（片段列表，數量需達到拆分時的門檻，否則會得到錯誤的秘密）
+// 回傳：秘密和可能的錯誤
+func combineShares(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("至少需要 2 份片段")
+	}
+
+	size := len(shares[0])
+	if size < 2 {
+		return nil, errors.New("片段格式無效")
+	}
+	xs := make([]byte, len(shares))
+	seen := make(map[byte]bool, len(shares))
+	for i, share := range shares {
+		if len(share) != size {
+			return nil, errors.New("片段長度不一致")
+		}
+		x := share[size-1]
+		if x == 0 || seen[x] {
+			return nil, errors.New("片段編號無效或重複")
+		}
+		seen[x] = true
+		xs[i] = x
+	}
+
+	secret := make([]byte, size-1)
+	for index := range secret {
+		var value byte
+		for i, share := range shares {
+			// 拉格朗日基底多項式在 x = 0 的值：Π x_j / (x_j - x_i)，GF(2^8) 的減法即 XOR
+			basis := byte(1)
+			for j, xj := range xs {
+				if i == j {
+					continue
+				}
+				basis = gfMul(basis, gfMul(xj, gfInverse(xj^xs[i])))
+			}
+			value ^= gfMul(share[index], basis)
+		}
+		secret[index] = value
+	}
+
+	return secret, nil
+}
+
+// gfEvaluate 以霍納法計算多項式在 x 的值
+// 參數：coefficients（係數，索引 0 為常數項）、x（自變數）
+// 回傳：多項式的值
+func gfEvaluate(coefficients []byte, x byte) byte {
+	var result byte
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result = gfMul(result, x) ^ coefficients[i]
+	}
+	return result
+}
+
+// gfMul 計算 GF(2^8) 上的乘法（不可約多項式 x^8 + x^4 + x^3 + x + 1，與 AES 相同）
+// 參數：a、b（乘數）
+// 回傳：乘積
+func gfMul(a, b byte) byte {
+	var product byte
+	for i := 0; i < 8; i++ {
+		// 以遮罩取代分支，執行時間不依賴輸入值
+		product ^= a & -(b & 1)
+		carry := -(a >> 7)
+		a = (a << 1) ^ (0x1b & carry)
+		b >>= 1
+	}
+	return product
+}
+
+// gfInverse 計算 GF(2^8) 上的乘法反元素（a^254）
+// 參數：a（非零元素）
+// 回傳：反元素
+func gfInverse(a byte) byte {
+	result := byte(1)
+	for i := 0; i < 254; i++ {
+		result = gfMul(result, a)
+	}
+	return result
+}
@@ -0,0 +1,667 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含筆記簽章服務，以本機 Ed25519 金鑰簽署筆記的正規化內容，
+// 簽章以分離的簽章檔保存在筆記旁，用於確認筆記是否被作者以外的人修改
+package services
+
+import (
+	"crypto/ed25519"  // Ed25519 數位簽章
+	"crypto/rand"     // 安全隨機數產生
+	"crypto/sha256"   // SHA-256 雜湊演算法
+	"encoding/base64" // Base64 編碼
+	"encoding/json"   // JSON 序列化
+	"errors"          // 錯誤處理
+	"fmt"             // 格式化輸出
+	"path/filepath"   // 檔案路徑處理
+	"strings"         // 字串處理
+	"sync"            // 同步原語
+	"time"            // 時間處理
+
+	"mac-notebook-app/internal/models"       // 引入資料模型
+	"mac-notebook-app/internal/repositories" // 引入資料存取層
+)
+
+// 筆記簽章相關常數
+const (
+	SigningKeyPrefix     = "nbed25519:"            // 簽章公鑰字串的前綴
+	SignatureFileExt     = ".sig"                  // 分離簽章檔的副檔名
+	signatureVersion     = "1.0"                   // 簽章檔格式版本
+	signatureAlgorithm   = "ed25519"               // 簽章演算法
+	signatureDomain      = "notebook-signature-v1" // 簽署訊息的用途前綴，避免簽章被挪作他用
+	maxSignatureFileSize = 4 * 1024                // 簽章檔大小上限
+	maxSignerNameLength  = 100                     // 受信任簽署者名稱長度上限
+	ownSigningKeyName    = "本機簽章金鑰"                // 本機金鑰的顯示名稱
+)
+
+// 筆記簽章錯誤定義
+var (
+	ErrNoSigningKey       = errors.New("尚未建立簽章金鑰")
+	ErrSigningKeyExists   = errors.New("簽章金鑰已存在")
+	ErrInvalidSigningKey  = errors.New("簽章公鑰格式無效")
+	ErrSignatureMalformed = errors.New("簽章檔格式無效")
+)
+
+// SignatureState 定義筆記簽章狀態的列舉
+type SignatureState int
+
+const (
+	// SignatureUnsigned 筆記沒有簽章
+	SignatureUnsigned SignatureState = iota
+	// SignatureValid 簽章有效且簽署者受信任
+	SignatureValid
+	// SignatureUntrusted 簽章有效但簽署者不是本機金鑰或受信任的簽署者
+	SignatureUntrusted
+	// SignatureTampered 簽章無效，筆記在簽署後遭到修改
+	SignatureTampered
+	// SignatureUnverified 筆記已簽署但無法取得內容（例如加密筆記尚未解鎖）
+	SignatureUnverified
+)
+
+// String 回傳簽章狀態的顯示名稱
+// 回傳：簽章狀態的中文名稱
+func (s SignatureState) String() string {
+	switch s {
+	case SignatureUnsigned:
+		return "未簽署"
+	case SignatureValid:
+		return "簽章有效"
+	case SignatureUntrusted:
+		return "簽署者未受信任"
+	case SignatureTampered:
+		return "內容已遭修改"
+	case SignatureUnverified:
+		return "無法驗證"
+	default:
+		return "未知"
+	}
+}
+
+// SignatureStatus 代表單一筆記的簽章驗證結果
+type SignatureStatus struct {
+	State      SignatureState `json:"state"`       // 簽章狀態
+	SignerKey  string         `json:"signer_key"`  // 簽署者公鑰字串
+	SignerName string         `json:"signer_name"` // 簽署者名稱（受信任時）
+	IsOwnKey   bool           `json:"is_own_key"`  // 是否以本機金鑰簽署
+	SignedAt   time.Time      `json:"signed_at"`   // 簽署時間
+	Message    string         `json:"message"`     // 補充說明（驗證失敗原因等）
+}
+
+// TrustedSigner 代表受信任的簽署者
+type TrustedSigner struct {
+	Name      string    `json:"name"`       // 顯示名稱
+	PublicKey string    `json:"public_key"` // 簽章公鑰字串
+	AddedAt   time.Time `json:"added_at"`   // 加入時間
+}
+
+// SignatureReportEntry 代表簽章驗證報告中的單一筆記
+type SignatureReportEntry struct {
+	Path   string           `json:"path"`   // 筆記檔案路徑
+	Status *SignatureStatus `json:"status"` // 驗證結果
+}
+
+// SignatureReport 代表整個筆記本的簽章驗證報告
+type SignatureReport struct {
+	Entries    []SignatureReportEntry `json:"entries"`     // 每個筆記的驗證結果
+	Counts     map[SignatureState]int `json:"counts"`      // 各狀態的筆記數量
+	VerifiedAt time.Time              `json:"verified_at"` // 驗證時間
+}
+
+// NoteContentLoader 取得筆記明文內容的函數
+// 用於驗證加密筆記，無法取得內容時回傳錯誤，該筆記會標示為無法驗證
+// 參數：filePath（筆記檔案路徑）、data（檔案原始內容）
+// 回傳：筆記明文內容和可能的錯誤
+type NoteContentLoader func(filePath string, data []byte) (string, error)
+
+// noteSignature 代表分離簽章檔的內容
+type noteSignature struct {
+	Version   string    `json:"version"`    // 簽章檔格式版本
+	Algorithm string    `json:"algorithm"`  // 簽章演算法
+	PublicKey string    `json:"public_key"` // 簽署者公鑰字串
+	Signature string    `json:"signature"`  // Base64 編碼的簽章
+	SignedAt  time.Time `json:"signed_at"`  // 簽署時間（包含在簽署訊息中）
+}
+
+// signingKeyring 代表保存在金鑰儲存庫中的簽章金鑰圈
+type signingKeyring struct {
+	PrivateKey string          `json:"private_key,omitempty"` // Base64 編碼的 Ed25519 私鑰種子
+	PublicKey  string          `json:"public_key,omitempty"`  // 本機簽章公鑰字串
+	CreatedAt  time.Time       `json:"created_at,omitempty"`  // 本機金鑰建立時間
+	Trusted    []TrustedSigner `json:"trusted,omitempty"`     // 受信任的簽署者
+}
+
+// SigningService 定義筆記簽章服務的介面
+// 負責本機簽章金鑰的管理、筆記的簽署和驗證，以及整個筆記本的驗證報告
+type SigningService interface {
+	// HasSigningKey 檢查是否已建立本機簽章金鑰
+	// 回傳：是否已建立
+	HasSigningKey() bool
+
+	// GenerateSigningKey 產生本機 Ed25519 簽章金鑰
+	// 回傳：簽章公鑰字串和可能的錯誤（已存在時回傳 ErrSigningKeyExists）
+	GenerateSigningKey() (string, error)
+
+	// PublicKey 取得本機簽章公鑰，可提供給其他人加入受信任的簽署者
+	// 回傳：簽章公鑰字串和可能的錯誤（尚未建立時回傳 ErrNoSigningKey）
+	PublicKey() (string, error)
+
+	// TrustSigner 將其他人的簽章公鑰加入受信任的簽署者
+	// 參數：name（顯示名稱）、publicKey（簽章公鑰字串）
+	// 回傳：可能的錯誤
+	TrustSigner(name, publicKey string) error
+
+	// UntrustSigner 從受信任的簽署者中移除指定公鑰
+	// 參數：publicKey（簽章公鑰字串）
+	// 回傳：可能的錯誤
+	UntrustSigner(publicKey string) error
+
+	// ListTrustedSigners 取得受信任的簽署者列表
+	// 回傳：受信任的簽署者
+	ListTrustedSigners() []TrustedSigner
+
+	// SignNote 以本機金鑰簽署筆記內容，並將簽章檔寫在筆記檔案旁
+	// 參數：filePath（筆記檔案路徑）、content（筆記明文內容）
+	// 回傳：簽署後的簽章狀態和可能的錯誤
+	SignNote(filePath, content string) (*SignatureStatus, error)
+
+	// VerifyNote 驗證筆記內容與簽章檔是否相符
+	// 參數：filePath（筆記檔案路徑）、content（筆記明文內容）
+	// 回傳：簽章狀態
+	VerifyNote(filePath, content string) *SignatureStatus
+
+	// MoveSignature 將簽章檔隨筆記移動到新的路徑
+	// 參數：oldPath（原筆記路徑）、newPath（新筆記路徑）
+	// 回傳：可能的錯誤（沒有簽章檔時不做任何事）
+	MoveSignature(oldPath, newPath string) error
+
+	// RemoveSignature 刪除筆記的簽章檔
+	// 參數：filePath（筆記檔案路徑）
+	// 回傳：可能的錯誤（沒有簽章檔時不做任何事）
+	RemoveSignature(filePath string) error
+
+	// VerifyAll 驗證目錄下所有筆記的簽章並產生報告
+	// 參數：rootPath（起始目錄，空字串代表筆記本根目錄）、loader（取得加密筆記內容的函數，可為 nil）
+	// 回傳：驗證報告和可能的錯誤
+	VerifyAll(rootPath string, loader NoteContentLoader) (*SignatureReport, error)
+}
+
+// signingService 實作 SigningService 介面
+// 本機私鑰和受信任的簽署者保存在金鑰儲存庫中（檔案權限為 0600），簽章檔透過檔案儲存庫讀寫
+type signingService struct {
+	fileRepo repositories.FileRepository       // 檔案存取介面
+	keyRepo  repositories.EncryptionRepository // 金鑰儲存庫
+	mutex    sync.Mutex                        // 保護金鑰圈讀寫
+}
+
+// NewSigningService 建立新的筆記簽章服務實例
+// 參數：
+//   - fileRepo: 檔案存取介面，用於讀寫簽章檔
+//   - keyRepo: 金鑰儲存庫，保存本機簽章金鑰和受信任的簽署者
+//
+// 回傳：SigningService 介面實例
+func NewSigningService(fileRepo repositories.FileRepository, keyRepo repositories.EncryptionRepository) SigningService {
+	return &signingService{
+		fileRepo: fileRepo,
+		keyRepo:  keyRepo,
+	}
+}
+
+// SignatureFilePath 取得筆記的分離簽章檔路徑
+// 參數：notePath（筆記檔案路徑）
+// 回傳：簽章檔路徑
+func SignatureFilePath(notePath string) string {
+	return notePath + SignatureFileExt
+}
+
+// IsSignatureFile 檢查檔名是否為分離簽章檔
+// 參數：name（檔案名稱或路徑）
+// 回傳：是否為簽章檔
+func IsSignatureFile(name string) bool {
+	return strings.HasSuffix(name, SignatureFileExt)
+}
+
+// FormatSigningKey 將 Ed25519 公鑰編碼為簽章公鑰字串
+// 參數：publicKey（Ed25519 公鑰）
+// 回傳：帶有前綴的簽章公鑰字串
+func FormatSigningKey(publicKey ed25519.PublicKey) string {
+	return SigningKeyPrefix + base64.RawURLEncoding.EncodeToString(publicKey)
+}
+
+// ParseSigningKey 解析簽章公鑰字串
+// 參數：key（簽章公鑰字串）
+// 回傳：Ed25519 公鑰和可能的錯誤
+func ParseSigningKey(key string) (ed25519.PublicKey, error) {
+	key = strings.TrimSpace(key)
+	if !strings.HasPrefix(key, SigningKeyPrefix) {
+		return nil, ErrInvalidSigningKey
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(key, SigningKeyPrefix))
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, ErrInvalidSigningKey
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// CanonicalNoteContent 取得筆記用於簽署的正規化內容
+// 統一換行字元並移除行尾空白和結尾空行，避免不同編輯器的格式差異造成驗證失敗
+// 參數：content（筆記內容）
+// 回傳：正規化後的內容
+func CanonicalNoteContent(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\r", "\n")
+
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// signatureMessage 建立簽署訊息
+// 訊息包含用途前綴、簽署時間和正規化內容的雜湊，簽署時間無法在簽署後竄改
+// 參數：signedAt（簽署時間）、content（筆記內容）
+// 回傳：簽署訊息
+func signatureMessage(signedAt time.Time, content string) []byte {
+	digest := sha256.Sum256([]byte(CanonicalNoteContent(content)))
+
+	message := make([]byte, 0, len(signatureDomain)+64+len(digest))
+	message = append(message, signatureDomain...)
+	message = append(message, 0)
+	message = append(message, signedAt.UTC().Format(time.RFC3339Nano)...)
+	message = append(message, 0)
+	return append(message, digest[:]...)
+}
+
+// HasSigningKey 檢查是否已建立本機簽章金鑰
+// 回傳：是否已建立
+func (s *signingService) HasSigningKey() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keyring, err := s.load()
+	return err == nil && keyring.PrivateKey != ""
+}
+
+// GenerateSigningKey 產生本機 Ed25519 簽章金鑰
+// 回傳：簽章公鑰字串和可能的錯誤
+func (s *signingService) GenerateSigningKey() (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keyring, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	if keyring.PrivateKey != "" {
+		return "", ErrSigningKeyExists
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("產生簽章金鑰失敗: %w", err)
+	}
+	seed := privateKey.Seed()
+	defer zeroBytes(seed)
+	defer zeroBytes(privateKey)
+
+	keyring.PrivateKey = base64.StdEncoding.EncodeToString(seed)
+	keyring.PublicKey = FormatSigningKey(publicKey)
+	keyring.CreatedAt = time.Now()
+	if err := s.store(keyring); err != nil {
+		return "", err
+	}
+	return keyring.PublicKey, nil
+}
+
+// PublicKey 取得本機簽章公鑰
+// 回傳：簽章公鑰字串和可能的錯誤
+func (s *signingService) PublicKey() (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keyring, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	if keyring.PublicKey == "" {
+		return "", ErrNoSigningKey
+	}
+	return keyring.PublicKey, nil
+}
+
+// TrustSigner 將其他人的簽章公鑰加入受信任的簽署者
+// 參數：name（顯示名稱）、publicKey（簽章公鑰字串）
+// 回傳：可能的錯誤（已存在時更新名稱）
+func (s *signingService) TrustSigner(name, publicKey string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxSignerNameLength {
+		return errors.New("簽署者名稱無效")
+	}
+	key, err := ParseSigningKey(publicKey)
+	if err != nil {
+		return err
+	}
+	publicKey = FormatSigningKey(key)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keyring, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	for i := range keyring.Trusted {
+		if keyring.Trusted[i].PublicKey == publicKey {
+			keyring.Trusted[i].Name = name
+			return s.store(keyring)
+		}
+	}
+	keyring.Trusted = append(keyring.Trusted, TrustedSigner{Name: name, PublicKey: publicKey, AddedAt: time.Now()})
+	return s.store(keyring)
+}
+
+// UntrustSigner 從受信任的簽署者中移除指定公鑰
+// 參數：publicKey（簽章公鑰字串）
+// 回傳：可能的錯誤
+func (s *signingService) UntrustSigner(publicKey string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keyring, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	for i, signer := range keyring.Trusted {
+		if signer.PublicKey == strings.TrimSpace(publicKey) {
+			keyring.Trusted = append(keyring.Trusted[:i], keyring.Trusted[i+1:]...)
+			return s.store(keyring)
+		}
+	}
+	return errors.New("找不到指定的簽署者")
+}
+
+// ListTrustedSigners 取得受信任的簽署者列表
+// 回傳：受信任的簽署者，金鑰圈無法讀取時回傳空列表
+func (s *signingService) ListTrustedSigners() []TrustedSigner {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keyring, err := s.load()
+	if err != nil {
+		return []TrustedSigner{}
+	}
+	return append([]TrustedSigner{}, keyring.Trusted...)
+}
+
+// SignNote 以本機金鑰簽署筆記內容
+// 參數：filePath（筆記檔案路徑）、content（筆記明文內容）
+// 回傳：簽署後的簽章狀態和可能的錯誤
+//
+// 執行流程：
+// 1. 從金鑰圈取得本機私鑰
+// 2. 以簽署時間和正規化內容的雜湊建立簽署訊息並簽署
+// 3. 將簽章檔寫在筆記檔案旁（覆寫舊的簽章）
+func (s *signingService) SignNote(filePath, content string) (*SignatureStatus, error) {
+	if filePath == "" {
+		return nil, errors.New("筆記尚未保存，無法簽署")
+	}
+
+	s.mutex.Lock()
+	keyring, err := s.load()
+	s.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if keyring.PrivateKey == "" {
+		return nil, ErrNoSigningKey
+	}
+
+	seed, err := base64.StdEncoding.DecodeString(keyring.PrivateKey)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, errors.New("簽章金鑰格式無效")
+	}
+	privateKey := ed25519.NewKeyFromSeed(seed)
+	defer zeroBytes(seed)
+	defer zeroBytes(privateKey)
+
+	signedAt := time.Now().UTC()
+	sig := noteSignature{
+		Version:   signatureVersion,
+		Algorithm: signatureAlgorithm,
+		PublicKey: keyring.PublicKey,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, signatureMessage(signedAt, content))),
+		SignedAt:  signedAt,
+	}
+
+	data, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("序列化簽章失敗: %w", err)
+	}
+	if err := s.fileRepo.WriteFile(SignatureFilePath(filePath), data); err != nil {
+		return nil, fmt.Errorf("寫入簽章檔失敗: %w", err)
+	}
+
+	return &SignatureStatus{
+		State:      SignatureValid,
+		SignerKey:  keyring.PublicKey,
+		SignerName: ownSigningKeyName,
+		IsOwnKey:   true,
+		SignedAt:   signedAt,
+	}, nil
+}
+
+// VerifyNote 驗證筆記內容與簽章檔是否相符
+// 參數：filePath（筆記檔案路徑）、content（筆記明文內容）
+// 回傳：簽章狀態
+func (s *signingService) VerifyNote(filePath, content string) *SignatureStatus {
+	sig, status := s.readSignature(filePath)
+	if sig == nil {
+		return status
+	}
+	return s.verify(sig, content)
+}
+
+// readSignature 讀取筆記的簽章檔
+// 參數：filePath（筆記檔案路徑）
+// 回傳：簽章（沒有或無法解析時為 nil）和對應的簽章狀態
+func (s *signingService) readSignature(filePath string) (*noteSignature, *SignatureStatus) {
+	sigPath := SignatureFilePath(filePath)
+	if filePath == "" || !s.fileRepo.FileExists(sigPath) {
+		return nil, &SignatureStatus{State: SignatureUnsigned}
+	}
+
+	data, err := s.fileRepo.ReadFile(sigPath)
+	if err != nil {
+		return nil, &SignatureStatus{State: SignatureUnverified, Message: fmt.Sprintf("讀取簽章檔失敗: %v", err)}
+	}
+
+	var sig noteSignature
+	if len(data) > maxSignatureFileSize || json.Unmarshal(data, &sig) != nil ||
+		sig.Version != signatureVersion || sig.Algorithm != signatureAlgorithm {
+		return nil, &SignatureStatus{State: SignatureTampered, Message: ErrSignatureMalformed.Error()}
+	}
+	return &sig, nil
+}
+
+// verify 以簽章檔中的公鑰驗證內容，並依金鑰圈判斷簽署者是否受信任
+// 參數：sig（簽章）、content（筆記明文內容）
+// 回傳：簽章狀態
+func (s *signingService) verify(sig *noteSignature, content string) *SignatureStatus {
+	status := &SignatureStatus{SignerKey: sig.PublicKey, SignedAt: sig.SignedAt}
+
+	publicKey, err := ParseSigningKey(sig.PublicKey)
+	if err != nil {
+		status.State = SignatureTampered
+		status.Message = ErrSignatureMalformed.Error()
+		return status
+	}
+	signature, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil || !ed25519.Verify(publicKey, signatureMessage(sig.SignedAt, content), signature) {
+		status.State = SignatureTampered
+		status.Message = "簽章與筆記內容不符"
+		return status
+	}
+
+	s.mutex.Lock()
+	keyring, err := s.load()
+	s.mutex.Unlock()
+	if err != nil {
+		status.State = SignatureUntrusted
+		status.Message = fmt.Sprintf("無法讀取受信任的簽署者: %v", err)
+		return status
+	}
+
+	status.State = SignatureUntrusted
+	if keyring.PublicKey == sig.PublicKey {
+		status.State = SignatureValid
+		status.SignerName = ownSigningKeyName
+		status.IsOwnKey = true
+		return status
+	}
+	for _, signer := range keyring.Trusted {
+		if signer.PublicKey == sig.PublicKey {
+			status.State = SignatureValid
+			status.SignerName = signer.Name
+			break
+		}
+	}
+	return status
+}
+
+// MoveSignature 將簽章檔隨筆記移動到新的路徑
+// 參數：oldPath（原筆記路徑）、newPath（新筆記路徑）
+// 回傳：可能的錯誤
+func (s *signingService) MoveSignature(oldPath, newPath string) error {
+	oldSig := SignatureFilePath(oldPath)
+	if oldPath == newPath || !s.fileRepo.FileExists(oldSig) {
+		return nil
+	}
+
+	data, err := s.fileRepo.ReadFile(oldSig)
+	if err != nil {
+		return fmt.Errorf("讀取簽章檔失敗: %w", err)
+	}
+	if err := s.fileRepo.WriteFile(SignatureFilePath(newPath), data); err != nil {
+		return fmt.Errorf("寫入簽章檔失敗: %w", err)
+	}
+	return s.fileRepo.DeleteFile(oldSig)
+}
+
+// RemoveSignature 刪除筆記的簽章檔
+// 參數：filePath（筆記檔案路徑）
+// 回傳：可能的錯誤
+func (s *signingService) RemoveSignature(filePath string) error {
+	sigPath := SignatureFilePath(filePath)
+	if !s.fileRepo.FileExists(sigPath) {
+		return nil
+	}
+	return s.fileRepo.DeleteFile(sigPath)
+}
+
+// VerifyAll 驗證目錄下所有筆記的簽章並產生報告
+// 參數：rootPath（起始目錄）、loader（取得加密筆記內容的函數，可為 nil）
+// 回傳：驗證報告和可能的錯誤
+//
+// 執行流程：
+// 1. 遍歷目錄收集 Markdown 和加密筆記（略過 .notebook 中繼資料目錄和簽章檔）
+// 2. 沒有簽章檔的筆記直接標示為未簽署，不需要讀取內容
+// 3. 一般筆記以檔案內容驗證，加密筆記透過 loader 取得明文，無法取得時標示為無法驗證
+func (s *signingService) VerifyAll(rootPath string, loader NoteContentLoader) (*SignatureReport, error) {
+	if rootPath == "" {
+		rootPath = "."
+	}
+
+	report := &SignatureReport{
+		Entries:    make([]SignatureReportEntry, 0),
+		Counts:     make(map[SignatureState]int),
+		VerifiedAt: time.Now(),
+	}
+
+	err := s.fileRepo.WalkDirectory(rootPath, func(info *models.FileInfo) error {
+		if info.IsDirectory {
+			if info.Name == NotebookMetaDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if IsSignatureFile(info.Name) || !isNoteFileName(info.Name) {
+			return nil
+		}
+
+		status := s.verifyFile(info.Path, loader)
+		report.Entries = append(report.Entries, SignatureReportEntry{Path: info.Path, Status: status})
+		report.Counts[status.State]++
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("遍歷筆記本失敗: %w", err)
+	}
+
+	return report, nil
+}
+
+// verifyFile 驗證單一筆記檔案的簽章
+// 參數：filePath（筆記檔案路徑）、loader（取得加密筆記內容的函數）
+// 回傳：簽章狀態
+func (s *signingService) verifyFile(filePath string, loader NoteContentLoader) *SignatureStatus {
+	sig, status := s.readSignature(filePath)
+	if sig == nil {
+		return status
+	}
+
+	data, err := s.fileRepo.ReadFile(filePath)
+	if err != nil {
+		return &SignatureStatus{State: SignatureUnverified, SignerKey: sig.PublicKey, SignedAt: sig.SignedAt, Message: fmt.Sprintf("讀取筆記失敗: %v", err)}
+	}
+
+	content := string(data)
+	if strings.HasSuffix(filePath, ".enc") {
+		if loader == nil {
+			return &SignatureStatus{State: SignatureUnverified, SignerKey: sig.PublicKey, SignedAt: sig.SignedAt, Message: "加密筆記需要解鎖才能驗證"}
+		}
+		content, err = loader(filePath, data)
+		if err != nil {
+			return &SignatureStatus{State: SignatureUnverified, SignerKey: sig.PublicKey, SignedAt: sig.SignedAt, Message: err.Error()}
+		}
+	}
+
+	return s.verify(sig, content)
+}
+
+// isNoteFileName 檢查檔名是否為筆記檔案（Markdown 或加密筆記）
+// 參數：name（檔案名稱）
+// 回傳：是否為筆記檔案
+func isNoteFileName(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".md" || ext == ".markdown" || ext == ObfuscatedFileExt
+}
+
+// load 從金鑰儲存庫讀取簽章金鑰圈（呼叫者必須持有鎖）
+// 回傳：金鑰圈（尚未建立時為空的金鑰圈）和可能的錯誤
+func (s *signingService) load() (*signingKeyring, error) {
+	data, err := s.keyRepo.GetSigningKeyring()
+	if err != nil {
+		if appErr, ok := err.(*models.AppError); ok && appErr.Code == models.ErrFileNotFound {
+			return &signingKeyring{}, nil
+		}
+		return nil, err
+	}
+
+	var keyring signingKeyring
+	if err := json.Unmarshal(data, &keyring); err != nil {
+		return nil, fmt.Errorf("解析簽章金鑰圈失敗: %w", err)
+	}
+	return &keyring, nil
+}
+
+// store 將簽章金鑰圈寫入金鑰儲存庫（呼叫者必須持有鎖）
+// 參數：keyring（簽章金鑰圈）
+// 回傳：可能的錯誤
+func (s *signingService) store(keyring *signingKeyring) error {
+	data, err := json.Marshal(keyring)
+	if err != nil {
+		return fmt.Errorf("序列化簽章金鑰圈失敗: %w", err)
+	}
+	return s.keyRepo.StoreSigningKeyring(data)
+}
@@ -0,0 +1,188 @@
+// Package services 提供筆記簽章服務的單元測試
+// 測試簽署和驗證、受信任的簽署者、驗證報告，以及編輯器開啟和保存時的簽章處理
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mac-notebook-app/internal/repositories"
+)
+
+// createTestSigningService 建立使用暫存金鑰儲存庫的簽章服務，並產生本機簽章金鑰
+func createTestSigningService(t *testing.T, fileRepo repositories.FileRepository) (SigningService, string) {
+	keyRepo, err := repositories.NewLocalEncryptionRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("建立金鑰儲存庫失敗: %v", err)
+	}
+
+	service := NewSigningService(fileRepo, keyRepo)
+	publicKey, err := service.GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("產生簽章金鑰失敗: %v", err)
+	}
+	return service, publicKey
+}
+
+// TestSigningServiceSignAndVerify 測試簽署、正規化內容、竄改偵測和受信任的簽署者
+func TestSigningServiceSignAndVerify(t *testing.T) {
+	fileRepo := newMockFileRepository()
+	alice, alicePublicKey := createTestSigningService(t, fileRepo)
+	bob, _ := createTestSigningService(t, fileRepo)
+
+	if _, err := alice.GenerateSigningKey(); err != ErrSigningKeyExists {
+		t.Errorf("重複產生金鑰應該回傳 ErrSigningKeyExists: %v", err)
+	}
+	if status := alice.VerifyNote("runbook.md", "步驟"); status.State != SignatureUnsigned {
+		t.Errorf("沒有簽章檔時應該為未簽署: %v", status.State)
+	}
+
+	content := "# 部署步驟\n\n1. 備份資料庫\n"
+	if _, err := alice.SignNote("runbook.md", content); err != nil {
+		t.Fatalf("簽署失敗: %v", err)
+	}
+	if !fileRepo.FileExists(SignatureFilePath("runbook.md")) {
+		t.Fatal("應該在筆記旁寫入簽章檔")
+	}
+
+	if status := alice.VerifyNote("runbook.md", content); status.State != SignatureValid || !status.IsOwnKey {
+		t.Errorf("作者驗證應該為有效: %+v", status)
+	}
+	if status := alice.VerifyNote("runbook.md", "# 部署步驟\r\n\r\n1. 備份資料庫   \r\n\r\n"); status.State != SignatureValid {
+		t.Errorf("換行和行尾空白不同時應該視為相同內容: %v", status.State)
+	}
+	if status := alice.VerifyNote("runbook.md", "# 部署步驟\n\n1. 刪除資料庫\n"); status.State != SignatureTampered {
+		t.Errorf("內容被修改時應該為已遭修改: %v", status.State)
+	}
+
+	// 其他使用者在信任作者前顯示未受信任，信任後顯示作者名稱
+	if status := bob.VerifyNote("runbook.md", content); status.State != SignatureUntrusted || status.SignerKey != alicePublicKey {
+		t.Errorf("未信任的簽署者應該為未受信任: %+v", status)
+	}
+	if err := bob.TrustSigner("Alice", alicePublicKey); err != nil {
+		t.Fatalf("加入受信任的簽署者失敗: %v", err)
+	}
+	if status := bob.VerifyNote("runbook.md", content); status.State != SignatureValid || status.SignerName != "Alice" || status.IsOwnKey {
+		t.Errorf("受信任的簽署者應該為有效: %+v", status)
+	}
+	if err := bob.TrustSigner("Mallory", "nbed25519:invalid"); err == nil {
+		t.Error("無效的公鑰不應能加入")
+	}
+
+	// 竄改簽署時間或簽章格式都會驗證失敗
+	var sig noteSignature
+	json.Unmarshal(fileRepo.files[SignatureFilePath("runbook.md")], &sig)
+	sig.SignedAt = sig.SignedAt.Add(-time.Hour)
+	data, _ := json.Marshal(sig)
+	fileRepo.WriteFile(SignatureFilePath("runbook.md"), data)
+	if status := alice.VerifyNote("runbook.md", content); status.State != SignatureTampered {
+		t.Errorf("簽署時間被竄改時應該為已遭修改: %v", status.State)
+	}
+	fileRepo.WriteFile(SignatureFilePath("runbook.md"), []byte("{"))
+	if status := alice.VerifyNote("runbook.md", content); status.State != SignatureTampered {
+		t.Errorf("簽章檔格式無效時應該為已遭修改: %v", status.State)
+	}
+}
+
+// TestSigningServiceVerifyAll 測試整個筆記本的簽章驗證報告
+func TestSigningServiceVerifyAll(t *testing.T) {
+	baseDir := t.TempDir()
+	fileRepo, err := repositories.NewLocalFileRepository(baseDir)
+	if err != nil {
+		t.Fatalf("建立檔案儲存庫失敗: %v", err)
+	}
+	service, _ := createTestSigningService(t, fileRepo)
+
+	notes := map[string]string{
+		"valid.md":       "有效",
+		"docs/edited.md": "原始內容",
+		"unsigned.md":    "未簽署",
+		"secret.md.enc":  "加密內容",
+		".notebook/x.md": "中繼資料",
+	}
+	for path, content := range notes {
+		os.MkdirAll(filepath.Join(baseDir, filepath.Dir(path)), 0755)
+		fileRepo.WriteFile(path, []byte(content))
+	}
+	for _, path := range []string{"valid.md", "docs/edited.md", "secret.md.enc"} {
+		if _, err := service.SignNote(path, notes[path]); err != nil {
+			t.Fatalf("簽署 %s 失敗: %v", path, err)
+		}
+	}
+	fileRepo.WriteFile("docs/edited.md", []byte("被修改的內容"))
+
+	report, err := service.VerifyAll("", nil)
+	if err != nil {
+		t.Fatalf("驗證失敗: %v", err)
+	}
+	if len(report.Entries) != 4 {
+		t.Fatalf("應該驗證 4 個筆記（不含簽章檔和中繼資料目錄），實際為 %d", len(report.Entries))
+	}
+	expected := map[SignatureState]int{SignatureValid: 1, SignatureTampered: 1, SignatureUnsigned: 1, SignatureUnverified: 1}
+	for state, count := range expected {
+		if report.Counts[state] != count {
+			t.Errorf("%s 的數量應該為 %d，實際為 %d", state, count, report.Counts[state])
+		}
+	}
+
+	// 提供內容載入函數後可以驗證加密筆記
+	report, _ = service.VerifyAll("", func(filePath string, data []byte) (string, error) {
+		return string(data), nil
+	})
+	if report.Counts[SignatureValid] != 2 || report.Counts[SignatureUnverified] != 0 {
+		t.Errorf("提供載入函數後加密筆記應該為有效: %v", report.Counts)
+	}
+}
+
+// TestEditorServiceNoteSignatures 測試編輯器開啟時驗證簽章，以及保存時重新簽署或標示為已遭修改
+func TestEditorServiceNoteSignatures(t *testing.T) {
+	service, mockRepo := createTestEditorService()
+	alice, _ := createTestSigningService(t, mockRepo)
+	service.SetOptions(EditorOptions{Signing: alice})
+
+	mockRepo.WriteFile("runbook.md", []byte("步驟一"))
+	note, err := service.OpenNote("runbook.md")
+	if err != nil {
+		t.Fatalf("開啟筆記失敗: %v", err)
+	}
+	if status := service.(NoteSigner).GetSignatureStatus(note.ID); status == nil || status.State != SignatureUnsigned {
+		t.Fatalf("未簽署的筆記應該為未簽署: %+v", status)
+	}
+
+	if status, err := service.(NoteSigner).SignNote(note.ID); err != nil || status.State != SignatureValid {
+		t.Fatalf("簽署筆記失敗: %+v, %v", status, err)
+	}
+
+	// 作者以本機金鑰保存時自動重新簽署
+	note.Content = "步驟一\n步驟二"
+	if err := service.SaveNote(note); err != nil {
+		t.Fatalf("保存筆記失敗: %v", err)
+	}
+	if status := service.(NoteSigner).GetSignatureStatus(note.ID); status.State != SignatureValid {
+		t.Errorf("作者修改後應該重新簽署: %v", status.State)
+	}
+	service.CloseNote(note.ID)
+
+	// 其他人開啟時顯示未受信任，修改保存後顯示已遭修改
+	other, otherRepo := createTestEditorService()
+	for path, data := range mockRepo.files {
+		otherRepo.WriteFile(path, data)
+	}
+	bob, _ := createTestSigningService(t, otherRepo)
+	other.SetOptions(EditorOptions{Signing: bob})
+
+	opened, _ := other.OpenNote("runbook.md")
+	if status := other.(NoteSigner).GetSignatureStatus(opened.ID); status.State != SignatureUntrusted {
+		t.Errorf("其他人開啟時應該為未受信任: %v", status.State)
+	}
+	opened.Content = "步驟一\n跳過步驟二"
+	if err := other.SaveNote(opened); err != nil {
+		t.Fatalf("保存筆記失敗: %v", err)
+	}
+	if status := other.(NoteSigner).GetSignatureStatus(opened.ID); status.State != SignatureTampered {
+		t.Errorf("他人修改後應該為已遭修改: %v", status.State)
+	}
+}
@@ -0,0 +1,580 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含大型加密筆記使用的串流分段 AEAD 格式（STREAM 結構），
+// 以固定大小的分段逐段加密和解密，不需要將整個檔案載入記憶體
+package services
+
+import (
+	"bufio"           // 緩衝讀取，用於判斷是否為最後一段
+	"bytes"           // 位元組處理
+	"crypto/cipher"   // AEAD 介面
+	"crypto/rand"     // 安全隨機數產生
+	"crypto/sha256"   // HKDF 雜湊函數
+	"encoding/binary" // 二進位編碼
+	"errors"          // 錯誤處理
+	"fmt"             // 格式化輸出
+	"io"              // 輸入輸出介面
+	"strings"         // 字串處理
+
+	"golang.org/x/crypto/hkdf" // HKDF 金鑰衍生
+)
+
+// 串流格式常數
+//
+// 檔案結構（整數皆為 big-endian）：
+//
+//	magic(6) | version(1) | algorithm(1) | chunkSize(4) | keyMode(1) | streamNonce(16) | 金鑰來源 | metaLen(4) | meta | 分段...
+//
+// 金鑰來源依 keyMode 而定：
+//   - 密碼：kdf(1) | memory(4) | iterations(4) | parallelism(1) | saltLen(1) | salt
+//   - 保險庫：keyIDLen(2) | keyID
+//
+// meta 為加密後的筆記中繼資料（見 NoteMetadata），長度可為 0；版本 1 的檔案沒有這個欄位
+//
+// 每個分段為 chunkSize 位元組明文加密後的密文（最後一段可較短），
+// 分段金鑰以 HKDF 從檔案金鑰和 streamNonce 衍生，nonce 為 11 位元組的分段序號加上 1 位元組的最後一段旗標，
+// 整個標頭作為每個分段的附加驗證資料，因此分段無法被重排、截斷、延長或搬移到其他檔案
+const (
+	StreamMagic            = "NBSTRM"         // 串流格式識別碼
+	StreamVersion          = byte(2)          // 串流格式版本
+	streamVersionNoMeta    = byte(1)          // 不含中繼資料欄位的舊版本，仍可讀取
+	StreamDefaultChunkSize = 64 * 1024        // 預設分段大小（位元組）
+	minStreamChunkSize     = 1024             // 分段大小下限
+	maxStreamChunkSize     = 16 * 1024 * 1024 // 分段大小上限，避免惡意檔案要求過大的緩衝區
+	streamNonceSize        = 16               // 每個檔案的隨機值大小，用於衍生分段金鑰
+	maxStreamKeyIDLength   = 256              // 保險庫資料金鑰 ID 長度上限
+	streamPayloadKeyInfo   = "notebook stream payload v1"
+)
+
+// 串流金鑰來源常數
+const (
+	streamKeyModePassword = byte(1) // 以密碼衍生金鑰
+	streamKeyModeVault    = byte(2) // 以保險庫資料金鑰加密
+)
+
+// 串流演算法和 KDF 的二進位代碼
+var (
+	streamAlgorithmCodes = map[string]byte{AlgorithmAES256: 1, AlgorithmChaCha20: 2}
+	streamKDFCodes       = map[string]byte{KDFPBKDF2: 1, KDFArgon2id: 2}
+)
+
+// ErrStreamTruncated 串流在最後一段之前結束時回傳的錯誤
+var ErrStreamTruncated = errors.New("加密串流不完整，檔案可能被截斷")
+
+// StreamOpener 將加密串流轉換為明文讀取器的函數
+// 參數：src（加密資料來源）
+// 回傳：明文讀取器和可能的錯誤
+type StreamOpener func(src io.Reader) (io.Reader, error)
+
+// StreamSealer 建立加密寫入器的函數，寫入的明文會以串流格式加密後寫入 dst
+// 參數：dst（加密資料目的地）
+// 回傳：加密寫入器（必須呼叫 Close 寫入最後一段）和可能的錯誤
+type StreamSealer func(dst io.Writer) (io.WriteCloser, error)
+
+// sealStreamContent 以串流格式加密整份內容
+// 只有輸出格式是分段的，加密後的完整資料會放在記憶體中回傳
+// 參數：content（明文內容）、sealer（建立加密寫入器的函數）
+// 回傳：加密後的資料和可能的錯誤
+func sealStreamContent(content string, sealer StreamSealer) ([]byte, error) {
+	var sealed bytes.Buffer
+	sealed.Grow(len(content) + len(content)/StreamDefaultChunkSize*32 + 128)
+
+	writer, err := sealer(&sealed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(writer, strings.NewReader(content)); err != nil {
+		return nil, fmt.Errorf("加密失敗: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("加密失敗: %w", err)
+	}
+	return sealed.Bytes(), nil
+}
+
+// streamHeader 代表串流格式的標頭
+type streamHeader struct {
+	Algorithm   string    // 加密演算法
+	ChunkSize   int       // 分段明文大小
+	KeyMode     byte      // 金鑰來源
+	StreamNonce []byte    // 每個檔案的隨機值
+	KDF         KDFParams // 密碼模式的 KDF 參數
+	Salt        []byte    // 密碼模式的鹽值
+	KeyID       string    // 保險庫模式的資料金鑰 ID
+	Metadata    []byte    // 加密後的筆記中繼資料（可為空）
+
+	raw []byte // 序列化後的標頭，作為附加驗證資料
+}
+
+// IsStreamData 檢查資料是否為串流加密格式
+// 參數：data（檔案內容或開頭部分）
+// 回傳：是否為串流格式
+func IsStreamData(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(StreamMagic))
+}
+
+// newStreamHeader 建立新的串流標頭並產生隨機值
+// 參數：algorithm（加密演算法）、keyMode（金鑰來源）
+// 回傳：串流標頭和可能的錯誤
+func newStreamHeader(algorithm string, keyMode byte) (*streamHeader, error) {
+	if _, ok := streamAlgorithmCodes[algorithm]; !ok {
+		return nil, fmt.Errorf("不支援的加密演算法: %s", algorithm)
+	}
+
+	header := &streamHeader{
+		Algorithm:   algorithm,
+		ChunkSize:   StreamDefaultChunkSize,
+		KeyMode:     keyMode,
+		StreamNonce: make([]byte, streamNonceSize),
+	}
+	if _, err := io.ReadFull(rand.Reader, header.StreamNonce); err != nil {
+		return nil, fmt.Errorf("產生串流隨機值失敗: %w", err)
+	}
+	return header, nil
+}
+
+// marshal 序列化串流標頭
+// 回傳：序列化後的標頭和可能的錯誤
+func (h *streamHeader) marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(StreamMagic)
+	buf.WriteByte(StreamVersion)
+	buf.WriteByte(streamAlgorithmCodes[h.Algorithm])
+	binary.Write(&buf, binary.BigEndian, uint32(h.ChunkSize))
+	buf.WriteByte(h.KeyMode)
+	buf.Write(h.StreamNonce)
+
+	switch h.KeyMode {
+	case streamKeyModePassword:
+		code, ok := streamKDFCodes[h.KDF.Name]
+		if !ok {
+			return nil, fmt.Errorf("不支援的金鑰衍生函數: %s", h.KDF.Name)
+		}
+		buf.WriteByte(code)
+		binary.Write(&buf, binary.BigEndian, h.KDF.Memory)
+		binary.Write(&buf, binary.BigEndian, h.KDF.Iterations)
+		buf.WriteByte(h.KDF.Parallelism)
+		buf.WriteByte(byte(len(h.Salt)))
+		buf.Write(h.Salt)
+	case streamKeyModeVault:
+		if h.KeyID == "" || len(h.KeyID) > maxStreamKeyIDLength {
+			return nil, fmt.Errorf("資料金鑰 ID 無效: %q", h.KeyID)
+		}
+		binary.Write(&buf, binary.BigEndian, uint16(len(h.KeyID)))
+		buf.WriteString(h.KeyID)
+	default:
+		return nil, fmt.Errorf("不支援的串流金鑰來源: %d", h.KeyMode)
+	}
+
+	if len(h.Metadata) > maxNoteMetadataSize {
+		return nil, errors.New("中繼資料過大")
+	}
+	binary.Write(&buf, binary.BigEndian, uint32(len(h.Metadata)))
+	buf.Write(h.Metadata)
+
+	h.raw = buf.Bytes()
+	return h.raw, nil
+}
+
+// readStreamHeader 從資料來源讀取並驗證串流標頭
+// 參數：src（加密資料來源）
+// 回傳：串流標頭和可能的錯誤
+//
+// 執行流程：
+// 1. 讀取固定長度部分，驗證識別碼、版本、演算法和分段大小
+// 2. 依金鑰來源讀取 KDF 參數和鹽值，或資料金鑰 ID
+// 3. 版本 2 以上讀取加密後的中繼資料
+// 4. 保留原始位元組作為附加驗證資料
+func readStreamHeader(src io.Reader) (*streamHeader, error) {
+	var raw bytes.Buffer
+	r := io.TeeReader(src, &raw)
+
+	fixed := make([]byte, len(StreamMagic)+1+1+4+1+streamNonceSize)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, fmt.Errorf("讀取串流標頭失敗: %w", err)
+	}
+	if !IsStreamData(fixed) {
+		return nil, errors.New("不是串流加密格式")
+	}
+
+	offset := len(StreamMagic)
+	version := fixed[offset]
+	if version != StreamVersion && version != streamVersionNoMeta {
+		return nil, fmt.Errorf("不支援的串流格式版本: %d", fixed[offset])
+	}
+
+	header := &streamHeader{}
+	for name, code := range streamAlgorithmCodes {
+		if code == fixed[offset+1] {
+			header.Algorithm = name
+		}
+	}
+	if header.Algorithm == "" {
+		return nil, fmt.Errorf("不支援的加密演算法代碼: %d", fixed[offset+1])
+	}
+
+	chunkSize := binary.BigEndian.Uint32(fixed[offset+2:])
+	if chunkSize < minStreamChunkSize || chunkSize > maxStreamChunkSize {
+		return nil, fmt.Errorf("分段大小無效: %d", chunkSize)
+	}
+	header.ChunkSize = int(chunkSize)
+	header.KeyMode = fixed[offset+6]
+	header.StreamNonce = append([]byte(nil), fixed[offset+7:]...)
+
+	switch header.KeyMode {
+	case streamKeyModePassword:
+		params := make([]byte, 1+4+4+1+1)
+		if _, err := io.ReadFull(r, params); err != nil {
+			return nil, fmt.Errorf("讀取金鑰衍生參數失敗: %w", err)
+		}
+		for name, code := range streamKDFCodes {
+			if code == params[0] {
+				header.KDF.Name = name
+			}
+		}
+		header.KDF.Memory = binary.BigEndian.Uint32(params[1:])
+		header.KDF.Iterations = binary.BigEndian.Uint32(params[5:])
+		header.KDF.Parallelism = params[9]
+		if err := header.KDF.Validate(); err != nil {
+			return nil, err
+		}
+		if int(params[10]) != SaltSize {
+			return nil, fmt.Errorf("鹽值長度無效: %d", params[10])
+		}
+		header.Salt = make([]byte, SaltSize)
+		if _, err := io.ReadFull(r, header.Salt); err != nil {
+			return nil, fmt.Errorf("讀取鹽值失敗: %w", err)
+		}
+	case streamKeyModeVault:
+		var length uint16
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return nil, fmt.Errorf("讀取資料金鑰 ID 失敗: %w", err)
+		}
+		if length == 0 || length > maxStreamKeyIDLength {
+			return nil, fmt.Errorf("資料金鑰 ID 長度無效: %d", length)
+		}
+		keyID := make([]byte, length)
+		if _, err := io.ReadFull(r, keyID); err != nil {
+			return nil, fmt.Errorf("讀取資料金鑰 ID 失敗: %w", err)
+		}
+		header.KeyID = string(keyID)
+	default:
+		return nil, fmt.Errorf("不支援的串流金鑰來源: %d", header.KeyMode)
+	}
+
+	if version >= StreamVersion {
+		var length uint32
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return nil, fmt.Errorf("讀取中繼資料失敗: %w", err)
+		}
+		if length > maxNoteMetadataSize {
+			return nil, fmt.Errorf("中繼資料長度無效: %d", length)
+		}
+		if length > 0 {
+			header.Metadata = make([]byte, length)
+			if _, err := io.ReadFull(r, header.Metadata); err != nil {
+				return nil, fmt.Errorf("讀取中繼資料失敗: %w", err)
+			}
+		}
+	}
+
+	header.raw = raw.Bytes()
+	return header, nil
+}
+
+// peekStreamHeader 解析資料開頭的串流標頭
+// 參數：data（檔案內容）
+// 回傳：串流標頭，資料不是有效的串流格式時回傳 nil
+func peekStreamHeader(data []byte) *streamHeader {
+	if !IsStreamData(data) {
+		return nil
+	}
+	header, err := readStreamHeader(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	return header
+}
+
+// streamAEAD 以檔案金鑰和標頭的隨機值衍生分段金鑰並建立 AEAD 加密器
+// 參數：header（串流標頭）、key（檔案金鑰）
+// 回傳：AEAD 加密器和可能的錯誤
+func streamAEAD(header *streamHeader, key []byte) (cipher.AEAD, error) {
+	payloadKey := make([]byte, KeySize)
+	defer zeroBytes(payloadKey)
+
+	kdf := hkdf.New(sha256.New, key, header.StreamNonce, []byte(streamPayloadKeyInfo))
+	if _, err := io.ReadFull(kdf, payloadKey); err != nil {
+		return nil, fmt.Errorf("衍生分段金鑰失敗: %w", err)
+	}
+	return newAEAD(header.Algorithm, payloadKey)
+}
+
+// streamChunkNonce 產生分段的 nonce
+// 參數：nonce（輸出緩衝區）、counter（分段序號）、last（是否為最後一段）
+func streamChunkNonce(nonce []byte, counter uint64, last bool) {
+	for i := range nonce {
+		nonce[i] = 0
+	}
+	binary.BigEndian.PutUint64(nonce[len(nonce)-9:], counter)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+}
+
+// streamWriter 以串流格式逐段加密寫入的明文
+type streamWriter struct {
+	dst     io.Writer   // 加密資料目的地
+	aead    cipher.AEAD // 分段加密器
+	aad     []byte      // 附加驗證資料（標頭）
+	chunk   int         // 分段大小
+	buf     []byte      // 尚未加密的明文
+	nonce   []byte      // nonce 緩衝區
+	counter uint64      // 下一段的序號
+	closed  bool        // 是否已寫入最後一段
+}
+
+// newStreamWriter 寫入標頭並建立串流加密寫入器
+// 參數：dst（加密資料目的地）、header（串流標頭）、key（檔案金鑰）
+// 回傳：加密寫入器和可能的錯誤
+func newStreamWriter(dst io.Writer, header *streamHeader, key []byte) (io.WriteCloser, error) {
+	raw, err := header.marshal()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := streamAEAD(header, key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dst.Write(raw); err != nil {
+		return nil, fmt.Errorf("寫入串流標頭失敗: %w", err)
+	}
+
+	return &streamWriter{
+		dst:   dst,
+		aead:  aead,
+		aad:   raw,
+		chunk: header.ChunkSize,
+		buf:   make([]byte, 0, header.ChunkSize),
+		nonce: make([]byte, aead.NonceSize()),
+	}, nil
+}
+
+// Write 寫入明文，累積滿一段且確定還有後續資料時才加密輸出
+// 參數：p（明文）
+// 回傳：寫入的位元組數和可能的錯誤
+func (w *streamWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("加密串流已關閉")
+	}
+
+	written := 0
+	for len(p) > 0 {
+		// 緩衝區已滿且還有資料，表示緩衝區內容不是最後一段
+		if len(w.buf) == w.chunk {
+			if err := w.flush(false); err != nil {
+				return written, err
+			}
+		}
+
+		n := copy(w.buf[len(w.buf):w.chunk], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close 加密並寫入最後一段
+// 回傳：可能的錯誤
+func (w *streamWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	err := w.flush(true)
+	w.closed = true
+	zeroBytes(w.buf[:cap(w.buf)])
+	return err
+}
+
+// flush 加密緩衝區中的明文並寫入目的地
+// 參數：last（是否為最後一段）
+// 回傳：可能的錯誤
+func (w *streamWriter) flush(last bool) error {
+	streamChunkNonce(w.nonce, w.counter, last)
+	sealed := w.aead.Seal(nil, w.nonce, w.buf, w.aad)
+	if _, err := w.dst.Write(sealed); err != nil {
+		return fmt.Errorf("寫入加密分段失敗: %w", err)
+	}
+
+	w.counter++
+	zeroBytes(w.buf)
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// streamReader 逐段解密串流格式的資料
+type streamReader struct {
+	src     *bufio.Reader // 加密資料來源
+	aead    cipher.AEAD   // 分段加密器
+	aad     []byte        // 附加驗證資料（標頭）
+	sealed  []byte        // 分段密文緩衝區
+	plain   []byte        // 尚未讀取的明文
+	nonce   []byte        // nonce 緩衝區
+	counter uint64        // 下一段的序號
+	done    bool          // 是否已讀取最後一段
+}
+
+// newStreamReader 建立串流解密讀取器
+// 參數：src（已讀取標頭後的資料來源）、header（串流標頭）、key（檔案金鑰）
+// 回傳：明文讀取器和可能的錯誤
+func newStreamReader(src io.Reader, header *streamHeader, key []byte) (io.Reader, error) {
+	aead, err := streamAEAD(header, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &streamReader{
+		src:    bufio.NewReader(src),
+		aead:   aead,
+		aad:    header.raw,
+		sealed: make([]byte, header.ChunkSize+aead.Overhead()),
+		nonce:  make([]byte, aead.NonceSize()),
+	}, nil
+}
+
+// Read 讀取解密後的明文
+// 參數：p（輸出緩衝區）
+// 回傳：讀取的位元組數和可能的錯誤（所有分段驗證通過後回傳 io.EOF）
+func (r *streamReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+// readChunk 讀取並解密下一段
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 讀取一整段密文，不足一段或之後沒有資料即為最後一段
+// 2. 以分段序號和最後一段旗標組成 nonce 並解密驗證
+// 3. 最後一段之後還有資料、或在最後一段之前結束都視為錯誤
+func (r *streamReader) readChunk() error {
+	n, err := io.ReadFull(r.src, r.sealed)
+	last := false
+	switch {
+	case err == io.EOF:
+		return ErrStreamTruncated
+	case err == io.ErrUnexpectedEOF:
+		last = true
+	case err != nil:
+		return fmt.Errorf("讀取加密分段失敗: %w", err)
+	default:
+		if _, peekErr := r.src.Peek(1); peekErr == io.EOF {
+			last = true
+		}
+	}
+
+	streamChunkNonce(r.nonce, r.counter, last)
+	plain, err := r.aead.Open(r.sealed[:0], r.nonce, r.sealed[:n], r.aad)
+	if err != nil {
+		return errors.New("加密分段驗證失敗，資料可能已被篡改或截斷")
+	}
+
+	// 只有空內容的檔案才會有空的最後一段
+	if last && len(plain) == 0 && r.counter > 0 {
+		return ErrStreamTruncated
+	}
+
+	r.counter++
+	r.done = last
+	r.plain = plain
+	return nil
+}
+
+// NewEncryptWriter 建立以密碼加密的串流寫入器
+// 參數：dst（加密資料目的地）、password（密碼）、algorithm（加密演算法）
+// 回傳：加密寫入器（必須呼叫 Close 寫入最後一段）和可能的錯誤
+//
+// 執行流程：
+// 1. 驗證參數並建立串流標頭
+// 2. 產生隨機鹽值並以目前的 KDF 參數從密碼衍生檔案金鑰
+// 3. 寫入標頭並回傳逐段加密的寫入器
+func (s *encryptionService) NewEncryptWriter(dst io.Writer, password, algorithm string) (io.WriteCloser, error) {
+	if password == "" {
+		return nil, errors.New("密碼不能為空")
+	}
+
+	header, err := newStreamHeader(algorithm, streamKeyModePassword)
+	if err != nil {
+		return nil, err
+	}
+
+	header.KDF = s.kdfParams
+	header.Salt = make([]byte, SaltSize)
+	if _, err := io.ReadFull(rand.Reader, header.Salt); err != nil {
+		return nil, fmt.Errorf("產生鹽值失敗: %w", err)
+	}
+
+	key, err := header.KDF.DeriveKey(password, header.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("衍生金鑰失敗: %w", err)
+	}
+	defer zeroBytes(key)
+
+	return newStreamWriter(dst, header, key)
+}
+
+// NewDecryptReader 建立以密碼解密的串流讀取器
+// 參數：src（加密資料來源）、password（密碼）
+// 回傳：明文讀取器和可能的錯誤（密碼錯誤時在第一次讀取時回傳驗證失敗）
+func (s *encryptionService) NewDecryptReader(src io.Reader, password string) (io.Reader, error) {
+	if password == "" {
+		return nil, errors.New("密碼不能為空")
+	}
+
+	header, err := readStreamHeader(src)
+	if err != nil {
+		return nil, err
+	}
+	if header.KeyMode != streamKeyModePassword {
+		return nil, errors.New("此檔案使用保險庫金鑰加密，請先解鎖保險庫")
+	}
+
+	key, err := header.KDF.DeriveKey(password, header.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("衍生金鑰失敗: %w", err)
+	}
+	defer zeroBytes(key)
+
+	return newStreamReader(src, header, key)
+}
+
+// decryptStreamContent 解密整個串流格式的資料
+// 參數：data（加密資料）、password（密碼）、algorithm（期望的演算法，空字串表示不檢查）
+// 回傳：解密後的內容字串和可能的錯誤
+func (s *encryptionService) decryptStreamContent(data []byte, password, algorithm string) (string, error) {
+	if header := peekStreamHeader(data); header != nil && algorithm != "" && header.Algorithm != algorithm {
+		return "", fmt.Errorf("演算法不匹配: 期望 %s，實際 %s", algorithm, header.Algorithm)
+	}
+
+	reader, err := s.NewDecryptReader(bytes.NewReader(data), password)
+	if err != nil {
+		return "", err
+	}
+
+	var plaintext bytes.Buffer
+	plaintext.Grow(len(data))
+	if _, err := io.Copy(&plaintext, reader); err != nil {
+		return "", fmt.Errorf("解密失敗: %w", err)
+	}
+	return plaintext.String(), nil
+}
@@ -0,0 +1,260 @@
+// Package services 提供串流分段加密格式的單元測試
+// 測試各種長度的往返加解密、截斷和篡改偵測，以及大型筆記的保存和分塊處理
+package services
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newFastEncryptionService 建立使用低成本 KDF 參數的加密服務，縮短測試時間
+func newFastEncryptionService() *encryptionService {
+	return &encryptionService{kdfParams: KDFParams{Name: KDFArgon2id, Memory: 64, Iterations: 1, Parallelism: 1}}
+}
+
+// sealTestStream 以密碼將明文加密為串流格式
+func sealTestStream(t *testing.T, service *encryptionService, plaintext []byte, algorithm string) []byte {
+	t.Helper()
+	var sealed bytes.Buffer
+	writer, err := service.NewEncryptWriter(&sealed, "StreamPassword123!", algorithm)
+	if err != nil {
+		t.Fatalf("建立加密寫入器失敗: %v", err)
+	}
+	if _, err := writer.Write(plaintext); err != nil {
+		t.Fatalf("寫入明文失敗: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("關閉加密寫入器失敗: %v", err)
+	}
+	return sealed.Bytes()
+}
+
+// openTestStream 以密碼解密串流格式的資料
+func openTestStream(service *encryptionService, sealed []byte) ([]byte, error) {
+	reader, err := service.NewDecryptReader(bytes.NewReader(sealed), "StreamPassword123!")
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+// TestStreamRoundTrip 測試分段邊界附近各種長度的往返加解密
+func TestStreamRoundTrip(t *testing.T) {
+	service := newFastEncryptionService()
+	sizes := []int{0, 1, StreamDefaultChunkSize - 1, StreamDefaultChunkSize, StreamDefaultChunkSize + 1, 3*StreamDefaultChunkSize + 5}
+
+	for _, algorithm := range []string{AlgorithmAES256, AlgorithmChaCha20} {
+		for _, size := range sizes {
+			plaintext := bytes.Repeat([]byte("筆"), size/3+1)[:size]
+			sealed := sealTestStream(t, service, plaintext, algorithm)
+
+			if !IsStreamData(sealed) {
+				t.Fatalf("%s/%d: 應該產生串流格式", algorithm, size)
+			}
+			if encryptedDataAlgorithm(sealed) != algorithm {
+				t.Errorf("%s/%d: 無法從標頭取得演算法", algorithm, size)
+			}
+
+			opened, err := openTestStream(service, sealed)
+			if err != nil {
+				t.Fatalf("%s/%d: 解密失敗: %v", algorithm, size, err)
+			}
+			if !bytes.Equal(opened, plaintext) {
+				t.Fatalf("%s/%d: 解密結果不符", algorithm, size)
+			}
+		}
+	}
+
+	// DecryptContent 能直接讀取串流格式，既有的 JSON 格式不受影響
+	sealed := sealTestStream(t, service, []byte("串流內容"), AlgorithmAES256)
+	if content, err := service.DecryptContent(sealed, "StreamPassword123!", ""); err != nil || content != "串流內容" {
+		t.Errorf("DecryptContent 應該能讀取串流格式: %q, %v", content, err)
+	}
+	if _, err := service.DecryptContent(sealed, "StreamPassword123!", AlgorithmChaCha20); err == nil {
+		t.Error("演算法不符時應該回傳錯誤")
+	}
+	legacy, _ := service.EncryptContent("JSON 內容", "StreamPassword123!", AlgorithmAES256)
+	if content, err := service.DecryptContent(legacy, "StreamPassword123!", ""); err != nil || content != "JSON 內容" {
+		t.Errorf("JSON 格式應該仍可讀取: %q, %v", content, err)
+	}
+}
+
+// TestStreamTamperDetection 測試截斷、延長、重排和篡改都會被偵測
+func TestStreamTamperDetection(t *testing.T) {
+	service := newFastEncryptionService()
+	plaintext := bytes.Repeat([]byte{'a'}, 2*StreamDefaultChunkSize+100)
+	sealed := sealTestStream(t, service, plaintext, AlgorithmChaCha20)
+
+	header := peekStreamHeader(sealed)
+	if header == nil {
+		t.Fatal("無法解析串流標頭")
+	}
+	headerLen := len(header.raw)
+	sealedChunk := StreamDefaultChunkSize + 16
+	firstChunk := sealed[headerLen : headerLen+sealedChunk]
+	secondChunk := sealed[headerLen+sealedChunk : headerLen+2*sealedChunk]
+
+	cases := map[string][]byte{
+		"錯誤密碼":     nil,
+		"截斷在分段邊界":  sealed[:headerLen+2*sealedChunk],
+		"截斷在分段中間":  sealed[:len(sealed)-10],
+		"只剩標頭":     sealed[:headerLen],
+		"附加資料":     append(append([]byte(nil), sealed...), 0),
+		"分段重排":     append(append(append(append([]byte(nil), sealed[:headerLen]...), secondChunk...), firstChunk...), sealed[headerLen+2*sealedChunk:]...),
+		"修改密文":     flipByte(sealed, headerLen+10),
+		"修改標頭隨機值":  flipByte(sealed, len(StreamMagic)+8),
+		"修改最後一段標籤": flipByte(sealed, len(sealed)-1),
+	}
+
+	for name, data := range cases {
+		var err error
+		if data == nil {
+			var reader io.Reader
+			reader, err = service.NewDecryptReader(bytes.NewReader(sealed), "WrongPassword123!")
+			if err == nil {
+				_, err = io.ReadAll(reader)
+			}
+		} else {
+			_, err = openTestStream(service, data)
+		}
+		if err == nil {
+			t.Errorf("%s: 應該回傳錯誤", name)
+		}
+	}
+}
+
+// flipByte 回傳修改指定位置一個位元後的副本
+func flipByte(data []byte, index int) []byte {
+	modified := append([]byte(nil), data...)
+	modified[index] ^= 0x01
+	return modified
+}
+
+// TestVaultStreamNote 測試保險庫資料金鑰的串流格式
+func TestVaultStreamNote(t *testing.T) {
+	vault, _ := createTestVaultService(t)
+	if err := vault.Initialize("TestPassword123!"); err != nil {
+		t.Fatalf("初始化保險庫失敗: %v", err)
+	}
+
+	content := strings.Repeat("保險庫大型筆記\n", 20000)
+	sealed, err := sealStreamContent(content, func(dst io.Writer) (io.WriteCloser, error) {
+		writer, _, err := vault.NewNoteEncryptWriter(dst, AlgorithmAES256, "", nil)
+		return writer, err
+	})
+	if err != nil {
+		t.Fatalf("串流加密失敗: %v", err)
+	}
+
+	if !vault.IsVaultData(sealed) {
+		t.Error("保險庫串流格式應該被識別為保險庫資料")
+	}
+	if _, err := newFastEncryptionService().DecryptContent(sealed, "TestPassword123!", ""); err == nil {
+		t.Error("保險庫串流格式不應以密碼解密")
+	}
+
+	opened, info, err := vault.DecryptNote(sealed)
+	if err != nil || opened != content {
+		t.Fatalf("保險庫串流解密失敗: %v", err)
+	}
+	if info.Algorithm != AlgorithmAES256 || info.KeyID == "" {
+		t.Errorf("金鑰資訊不正確: %+v", info)
+	}
+
+	vault.Lock()
+	if _, _, err := vault.DecryptNote(sealed); err == nil {
+		t.Error("鎖定後不應能解密")
+	}
+}
+
+// TestEditorServiceSavesLargeNoteAsStream 測試大型加密筆記以串流格式保存並可重新開啟
+func TestEditorServiceSavesLargeNoteAsStream(t *testing.T) {
+	service, mockRepo := createTestEditorService()
+	vault, _ := createTestVaultService(t)
+	service.SetOptions(EditorOptions{Vault: vault})
+	service.(*editorService).largeFileThreshold = 1024
+
+	note, _ := service.CreateNote("大型筆記", strings.Repeat("機密段落\n", 1000))
+	if err := service.(*editorService).EnableEncryption(note.ID, "TestPassword123!", AlgorithmChaCha20, false); err != nil {
+		t.Fatalf("啟用加密失敗: %v", err)
+	}
+	if err := service.SaveNote(note); err != nil {
+		t.Fatalf("保存加密筆記失敗: %v", err)
+	}
+
+	saved := mockRepo.files[note.FilePath]
+	if !IsStreamData(saved) {
+		t.Fatal("超過大檔案閾值的加密筆記應該以串流格式保存")
+	}
+
+	opened, err := service.OpenNote(note.FilePath)
+	if err != nil {
+		t.Fatalf("開啟串流格式筆記失敗: %v", err)
+	}
+	if opened.Content != note.Content || opened.EncryptionType != AlgorithmChaCha20 {
+		t.Errorf("開啟的筆記不符: %d 位元組, %s", len(opened.Content), opened.EncryptionType)
+	}
+}
+
+// writeSizeRecorder 記錄每次寫入加密寫入器的資料大小
+type writeSizeRecorder struct {
+	io.WriteCloser
+	sizes []int
+}
+
+// Write 記錄寫入大小後交給加密寫入器
+func (r *writeSizeRecorder) Write(p []byte) (int, error) {
+	r.sizes = append(r.sizes, len(p))
+	return r.WriteCloser.Write(p)
+}
+
+// TestPerformanceServiceEncryptedChunks 測試分塊加密和分塊解密處理大檔案
+func TestPerformanceServiceEncryptedChunks(t *testing.T) {
+	service := newFastEncryptionService()
+	perfService := NewPerformanceService(nil)
+	dir := t.TempDir()
+
+	plaintext := bytes.Repeat([]byte("0123456789"), 50000)
+	srcPath := filepath.Join(dir, "large.md")
+	dstPath := filepath.Join(dir, "large.md.enc")
+	if err := os.WriteFile(srcPath, plaintext, 0600); err != nil {
+		t.Fatalf("寫入測試檔案失敗: %v", err)
+	}
+
+	recorder := &writeSizeRecorder{}
+	err := perfService.EncryptFileInChunks(srcPath, dstPath, 64*1024, func(dst io.Writer) (io.WriteCloser, error) {
+		writer, err := service.NewEncryptWriter(dst, "StreamPassword123!", AlgorithmAES256)
+		recorder.WriteCloser = writer
+		return recorder, err
+	})
+	if err != nil {
+		t.Fatalf("分塊加密失敗: %v", err)
+	}
+	if want := []int{65536, 65536, 65536, 65536, 65536, 65536, 65536, 41248}; !reflect.DeepEqual(recorder.sizes, want) {
+		t.Errorf("應該以指定的分塊大小寫入加密串流: %v", recorder.sizes)
+	}
+	if _, err := os.Stat(dstPath + ".tmp"); !os.IsNotExist(err) {
+		t.Error("完成後不應留下暫存檔")
+	}
+
+	var processed bytes.Buffer
+	chunks := 0
+	err = perfService.ProcessEncryptedFileInChunks(dstPath, 100*1024, func(src io.Reader) (io.Reader, error) {
+		return service.NewDecryptReader(src, "StreamPassword123!")
+	}, func(chunk []byte) error {
+		chunks++
+		processed.Write(chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("分塊解密處理失敗: %v", err)
+	}
+	if chunks != 5 || !bytes.Equal(processed.Bytes(), plaintext) {
+		t.Errorf("分塊處理結果不符: %d 個分塊, %d 位元組", chunks, processed.Len())
+	}
+}
@@ -0,0 +1,697 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含標籤服務：解析筆記內文中的 #標籤 和 front matter 的 tags，維護標籤索引，
+// 並提供跨筆記重新命名、合併標籤，以及批次新增、移除標籤的功能
+package services
+
+import (
+	"errors"        // 錯誤處理
+	"fmt"           // 格式化輸出
+	"path/filepath" // 檔案路徑處理
+	"slices"        // 切片操作
+	"sort"          // 排序
+	"strings"       // 字串處理
+	"sync"          // 同步控制
+	"unicode"       // 字元分類
+	"unicode/utf8"  // UTF-8 解碼
+
+	"mac-notebook-app/internal/models"       // 引入資料模型
+	"mac-notebook-app/internal/repositories" // 引入儲存庫介面
+)
+
+// TagSeparator 巢狀標籤的階層分隔符號，例如 project/alpha
+const TagSeparator = "/"
+
+// TagInfo 代表標籤瀏覽器中的一個標籤
+type TagInfo struct {
+	Name     string     // 完整的標籤名稱，例如 project/alpha
+	Label    string     // 顯示名稱（最後一層），例如 alpha
+	Count    int        // 含有此標籤或其子標籤的筆記數量
+	Children []*TagInfo // 子標籤，依名稱排序
+}
+
+// TagService 定義標籤服務的介面
+type TagService interface {
+	// Rebuild 掃描筆記本中所有可讀取的筆記，重新建立標籤索引
+	// 回傳：可能的錯誤
+	Rebuild() error
+
+	// UpdateNote 筆記保存後更新索引
+	// 參數：filePath（筆記路徑）、content（筆記的明文內容）
+	UpdateNote(filePath, content string)
+
+	// RemoveNote 檔案或目錄刪除、移出後從索引移除
+	// 參數：path（檔案或目錄路徑）
+	RemoveNote(path string)
+
+	// ApplyFileChanges 依檔案監看的變更更新索引，加密筆記在保存時由 UpdateNote 更新
+	// 參數：events（檔案變更）
+	ApplyFileChanges(events []FileChangeEvent)
+
+	// Tags 取得所有標籤，巢狀標籤依階層排列
+	// 回傳：最上層的標籤
+	Tags() []*TagInfo
+
+	// NotesWithTag 取得含有標籤或其子標籤的筆記
+	// 參數：tag（標籤名稱）
+	// 回傳：依路徑排序的筆記路徑
+	NotesWithTag(tag string) []string
+
+	// NoteTags 取得筆記的標籤
+	// 參數：filePath（筆記路徑）
+	// 回傳：依名稱排序的標籤
+	NoteTags(filePath string) []string
+
+	// RenameTag 在所有筆記中重新命名標籤，子標籤一併改名，新名稱已存在時合併
+	// 參數：oldTag（原本的標籤）、newTag（新的標籤）
+	// 回傳：修改的筆記數量和無法修改的筆記合併後的錯誤
+	RenameTag(oldTag, newTag string) (int, error)
+
+	// AddTags 為多個筆記新增標籤，新增的標籤寫入 front matter
+	// 參數：filePaths（筆記路徑）、tags（要新增的標籤）
+	// 回傳：修改的筆記數量和無法修改的筆記合併後的錯誤
+	AddTags(filePaths, tags []string) (int, error)
+
+	// RemoveTags 從多個筆記移除標籤，front matter 和內文中的標籤都會移除
+	// 參數：filePaths（筆記路徑）、tags（要移除的標籤）
+	// 回傳：修改的筆記數量和無法修改的筆記合併後的錯誤
+	RemoveTags(filePaths, tags []string) (int, error)
+
+	// Subscribe 註冊索引變更的通知函數，可能在背景 goroutine 中通知
+	// 參數：listener（通知函數）
+	Subscribe(listener func())
+}
+
+// tagService 實作 TagService 介面
+type tagService struct {
+	fileRepo  repositories.FileRepository // 檔案存取介面
+	editor    EditorService               // 編輯器服務，修改標籤時開啟、保存筆記
+	notes     map[string][]string         // 筆記路徑對應的標籤
+	listeners []func()                    // 索引變更通知函數
+	mutex     sync.RWMutex                // 保護索引
+}
+
+// NewTagService 建立標籤服務
+// 參數：fileRepo（檔案存取介面）、editor（編輯器服務）
+// 回傳：標籤服務實例，呼叫 Rebuild 後才有完整的索引
+func NewTagService(fileRepo repositories.FileRepository, editor EditorService) TagService {
+	return &tagService{
+		fileRepo: fileRepo,
+		editor:   editor,
+		notes:    make(map[string][]string),
+	}
+}
+
+// Rebuild 重新建立標籤索引
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 遍歷筆記本，略過中繼資料目錄
+// 2. 讀取 Markdown 筆記並解析標籤，無法讀取的檔案（例如已鎖定的加密資料夾）略過
+// 3. 隨機檔名的加密筆記保留先前由 UpdateNote 記錄的標籤
+func (s *tagService) Rebuild() error {
+	notes, err := s.scan(".")
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	for path, tags := range s.notes {
+		if isEncryptedNoteFile(path) {
+			notes[path] = tags
+		}
+	}
+	s.notes = notes
+	s.mutex.Unlock()
+
+	s.notify()
+	return nil
+}
+
+// UpdateNote 筆記保存後更新索引
+// 參數：filePath（筆記路徑）、content（筆記的明文內容）
+func (s *tagService) UpdateNote(filePath, content string) {
+	if filePath == "" {
+		return
+	}
+	tags := ExtractTags(content)
+	key := filepath.Clean(filePath)
+
+	s.mutex.Lock()
+	old := s.notes[key]
+	if len(tags) == 0 {
+		delete(s.notes, key)
+	} else {
+		s.notes[key] = tags
+	}
+	s.mutex.Unlock()
+
+	if !slices.Equal(old, tags) {
+		s.notify()
+	}
+}
+
+// RemoveNote 從索引移除檔案或目錄中的所有筆記
+// 參數：path（檔案或目錄路徑）
+func (s *tagService) RemoveNote(path string) {
+	if s.removePath(path) {
+		s.notify()
+	}
+}
+
+// ApplyFileChanges 依檔案監看的變更更新索引
+// 參數：events（檔案變更）
+//
+// 執行流程：
+// 1. 刪除的路徑從索引移除（目錄中的筆記一併移除）
+// 2. 新增或修改的路徑重新讀取，移入的目錄會掃描其中所有筆記
+// 3. 隨機檔名的加密筆記無法直接讀取，刪除時移除、保存時由 UpdateNote 更新
+func (s *tagService) ApplyFileChanges(events []FileChangeEvent) {
+	changed := false
+	for _, event := range events {
+		if isNotebookMetaPath(event.Path) {
+			continue
+		}
+		if event.Op == FileRemoved {
+			changed = s.removePath(event.Path) || changed
+			continue
+		}
+		if isEncryptedNoteFile(event.Path) {
+			continue
+		}
+		notes, err := s.scan(event.Path)
+		if err != nil {
+			continue
+		}
+
+		s.mutex.Lock()
+		for path, tags := range notes {
+			if !slices.Equal(s.notes[path], tags) {
+				s.notes[path] = tags
+				changed = true
+			}
+		}
+		// 修改後不再有標籤的筆記
+		key := filepath.Clean(event.Path)
+		if _, existed := s.notes[key]; existed && notes[key] == nil {
+			delete(s.notes, key)
+			changed = true
+		}
+		s.mutex.Unlock()
+	}
+	if changed {
+		s.notify()
+	}
+}
+
+// Tags 取得所有標籤
+// 回傳：最上層的標籤，每個標籤的數量包含子標籤的筆記（同一個筆記只計算一次）
+func (s *tagService) Tags() []*TagInfo {
+	s.mutex.RLock()
+	counts := make(map[string]int)
+	for _, tags := range s.notes {
+		seen := make(map[string]bool)
+		for _, tag := range tags {
+			for _, name := range tagAncestors(tag) {
+				if !seen[name] {
+					seen[name] = true
+					counts[name]++
+				}
+			}
+		}
+	}
+	s.mutex.RUnlock()
+
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var roots []*TagInfo
+	nodes := make(map[string]*TagInfo)
+	// 上層標籤排序在子標籤之前，建立子標籤時上層標籤已經存在
+	for _, name := range names {
+		info := &TagInfo{Name: name, Label: name, Count: counts[name]}
+		nodes[name] = info
+		if i := strings.LastIndex(name, TagSeparator); i >= 0 {
+			info.Label = name[i+1:]
+			nodes[name[:i]].Children = append(nodes[name[:i]].Children, info)
+		} else {
+			roots = append(roots, info)
+		}
+	}
+	return roots
+}
+
+// NotesWithTag 取得含有標籤或其子標籤的筆記
+// 參數：tag（標籤名稱）
+// 回傳：依路徑排序的筆記路徑
+func (s *tagService) NotesWithTag(tag string) []string {
+	tag = NormalizeTag(tag)
+	if tag == "" {
+		return nil
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var paths []string
+	for path, tags := range s.notes {
+		if slices.ContainsFunc(tags, func(t string) bool { return tagUnder(t, tag) }) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// NoteTags 取得筆記的標籤
+// 參數：filePath（筆記路徑）
+// 回傳：依名稱排序的標籤
+func (s *tagService) NoteTags(filePath string) []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return slices.Clone(s.notes[filepath.Clean(filePath)])
+}
+
+// RenameTag 在所有筆記中重新命名標籤
+// 參數：oldTag（原本的標籤）、newTag（新的標籤）
+// 回傳：修改的筆記數量和可能的錯誤
+//
+// 執行流程：
+// 1. 驗證標籤名稱，不允許把標籤改名為自己的子標籤
+// 2. 找出含有此標籤或其子標籤的筆記
+// 3. 改寫 front matter 和內文中的標籤，子標籤保留原本的下層名稱（project/alpha 改名為 work/alpha）
+// 4. 筆記已有新名稱的標籤時合併為一個
+func (s *tagService) RenameTag(oldTag, newTag string) (int, error) {
+	oldTag, newTag = NormalizeTag(oldTag), NormalizeTag(newTag)
+	if oldTag == "" || newTag == "" {
+		return 0, fmt.Errorf("標籤名稱無效")
+	}
+	if oldTag == newTag {
+		return 0, nil
+	}
+	if tagUnder(newTag, oldTag) {
+		return 0, fmt.Errorf("無法將標籤 %s 改名為自己的子標籤 %s", oldTag, newTag)
+	}
+
+	rename := func(tag string) string {
+		if tagUnder(tag, oldTag) {
+			return newTag + tag[len(oldTag):]
+		}
+		return tag
+	}
+	return s.editNotes(s.NotesWithTag(oldTag), func(note *models.Note) bool {
+		return s.rewriteTags(note, rename, nil)
+	})
+}
+
+// AddTags 為多個筆記新增標籤
+// 參數：filePaths（筆記路徑）、tags（要新增的標籤）
+// 回傳：修改的筆記數量和可能的錯誤
+func (s *tagService) AddTags(filePaths, tags []string) (int, error) {
+	added, err := normalizeTags(tags)
+	if err != nil {
+		return 0, err
+	}
+	return s.editNotes(filePaths, func(note *models.Note) bool {
+		return s.rewriteTags(note, nil, added)
+	})
+}
+
+// RemoveTags 從多個筆記移除標籤
+// 參數：filePaths（筆記路徑）、tags（要移除的標籤，子標籤不受影響）
+// 回傳：修改的筆記數量和可能的錯誤
+func (s *tagService) RemoveTags(filePaths, tags []string) (int, error) {
+	removed, err := normalizeTags(tags)
+	if err != nil {
+		return 0, err
+	}
+	return s.editNotes(filePaths, func(note *models.Note) bool {
+		return s.rewriteTags(note, func(tag string) string {
+			if slices.Contains(removed, tag) {
+				return ""
+			}
+			return tag
+		}, nil)
+	})
+}
+
+// Subscribe 註冊索引變更的通知函數
+// 參數：listener（通知函數）
+func (s *tagService) Subscribe(listener func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// notify 通知索引已變更（呼叫者不可持有鎖）
+func (s *tagService) notify() {
+	s.mutex.RLock()
+	listeners := slices.Clone(s.listeners)
+	s.mutex.RUnlock()
+	for _, listener := range listeners {
+		listener()
+	}
+}
+
+// scan 讀取路徑（檔案或目錄）中的筆記並解析標籤
+// 參數：root（檔案或目錄路徑）
+// 回傳：有標籤的筆記和可能的錯誤
+func (s *tagService) scan(root string) (map[string][]string, error) {
+	notes := make(map[string][]string)
+	err := s.fileRepo.WalkDirectory(root, func(info *models.FileInfo) error {
+		if info.IsDirectory {
+			if info.Name == NotebookMetaDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isTaggableNoteFile(info.Path) {
+			return nil
+		}
+		data, err := s.fileRepo.ReadFile(info.Path)
+		if err != nil {
+			return nil
+		}
+		if tags := ExtractTags(string(data)); len(tags) > 0 {
+			notes[filepath.Clean(info.Path)] = tags
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("掃描筆記標籤失敗: %w", err)
+	}
+	return notes, nil
+}
+
+// removePath 從索引移除檔案或目錄中的所有筆記
+// 參數：path（檔案或目錄路徑）
+// 回傳：索引是否有變更
+func (s *tagService) removePath(path string) bool {
+	prefix := filepath.Clean(path)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	changed := false
+	for notePath := range s.notes {
+		if notePath == prefix || strings.HasPrefix(notePath, prefix+string(filepath.Separator)) {
+			delete(s.notes, notePath)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// editNotes 逐一開啟、修改並保存筆記
+// 參數：filePaths（筆記路徑）、edit（修改筆記的函數，回傳是否有變更）
+// 回傳：修改的筆記數量和無法修改的筆記合併後的錯誤
+//
+// 執行流程：
+// 1. 已開啟的筆記直接修改同一個實例，編輯器和服務的內容才會一致
+// 2. 未開啟的筆記暫時開啟，保存後關閉（已鎖定的加密筆記無法開啟，回報錯誤）
+// 3. 保存後由編輯器服務呼叫 UpdateNote 更新索引
+func (s *tagService) editNotes(filePaths []string, edit func(note *models.Note) bool) (int, error) {
+	active := make(map[string]*models.Note)
+	for _, note := range s.editor.GetActiveNotes() {
+		if note.FilePath != "" {
+			active[filepath.Clean(note.FilePath)] = note
+		}
+	}
+
+	count := 0
+	var errs []error
+	for _, filePath := range filePaths {
+		note, opened := active[filepath.Clean(filePath)]
+		if !opened {
+			var err error
+			note, err = s.editor.OpenNote(filePath)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", filePath, err))
+				continue
+			}
+		}
+
+		if edit(note) {
+			if err := s.editor.SaveNote(note); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", filePath, err))
+			} else {
+				count++
+			}
+		}
+		if !opened {
+			s.editor.CloseNote(note.ID)
+		}
+	}
+	return count, errors.Join(errs...)
+}
+
+// rewriteTags 改寫筆記的標籤
+// 參數：note（筆記）、rename（對應每個既有標籤的新名稱，空字串代表移除，nil 代表不改寫）、
+// add（要新增的標籤，已存在於內文或 front matter 時不重複新增）
+// 回傳：是否有變更
+//
+// 執行流程：
+// 1. 改寫內文中的 #標籤，移除時一併移除多餘的空白
+// 2. 改寫 front matter 的標籤（保存時由編輯器服務寫回 front matter），合併重複的標籤
+// 3. 新增的標籤加到 front matter
+func (s *tagService) rewriteTags(note *models.Note, rename func(tag string) string, add []string) bool {
+	content := note.Content
+	tags := slices.Clone(note.Tags)
+	if rename != nil {
+		content = rewriteInlineTags(content, rename)
+		tags = nil
+		for _, tag := range note.Tags {
+			renamed := tag
+			if normalized := NormalizeTag(tag); normalized != "" {
+				renamed = rename(normalized)
+			}
+			if renamed != "" && !slices.Contains(tags, renamed) {
+				tags = append(tags, renamed)
+			}
+		}
+	}
+	existing := ExtractTags(content)
+	for _, tag := range add {
+		if !slices.Contains(existing, tag) && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	if content == note.Content && slices.Equal(tags, note.Tags) {
+		return false
+	}
+	if content != note.Content {
+		s.editor.UpdateContent(note.ID, content)
+	}
+	note.Tags = tags
+	return true
+}
+
+// ExtractTags 取得筆記的所有標籤：front matter 的 tags 和內文中的 #標籤
+// 參數：content（筆記內容）
+// 回傳：去除重複後依名稱排序的標籤
+func ExtractTags(content string) []string {
+	var tags []string
+	if fm, _, err := ParseFrontMatter(content); err == nil {
+		for _, tag := range fm.Tags {
+			if tag = NormalizeTag(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	forEachInlineTag(StripFrontMatter(content), func(start, end int, tag string) {
+		tags = append(tags, tag)
+	})
+	sort.Strings(tags)
+	return slices.Compact(tags)
+}
+
+// NormalizeTag 將使用者輸入的標籤轉為索引使用的名稱
+// 參數：tag（標籤，可以有開頭的 # 符號）
+// 回傳：標籤名稱，不是有效的標籤時為空字串
+func NormalizeTag(tag string) string {
+	tag = strings.Trim(strings.TrimPrefix(strings.TrimSpace(tag), "#"), TagSeparator)
+	if tag == "" || strings.Contains(tag, TagSeparator+TagSeparator) {
+		return ""
+	}
+	hasLetter := false
+	for _, r := range tag {
+		if !isTagRune(r) {
+			return ""
+		}
+		if !unicode.IsDigit(r) && r != '/' {
+			hasLetter = true
+		}
+	}
+	if !hasLetter {
+		return ""
+	}
+	return tag
+}
+
+// normalizeTags 驗證並整理使用者輸入的多個標籤
+// 參數：tags（標籤）
+// 回傳：去除重複的標籤和可能的錯誤
+func normalizeTags(tags []string) ([]string, error) {
+	var result []string
+	for _, tag := range tags {
+		normalized := NormalizeTag(tag)
+		if normalized == "" {
+			return nil, fmt.Errorf("標籤名稱無效: %s", tag)
+		}
+		if !slices.Contains(result, normalized) {
+			result = append(result, normalized)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("請輸入至少一個標籤")
+	}
+	return result, nil
+}
+
+// forEachInlineTag 找出內文中的 #標籤
+// 參數：body（不含 front matter 的內文）、visit（每個標籤的位置和名稱，位置包含 # 符號）
+//
+// 標籤的 # 必須位於行首或空白之後，後面接字母、數字、_、- 或 /，且不能全為數字；
+// 程式碼區塊和行內程式碼中的 # 不視為標籤，Markdown 標題的 # 後面有空白因此不會被誤判
+func forEachInlineTag(body string, visit func(start, end int, tag string)) {
+	offset := 0
+	inFence := false
+	for _, line := range strings.SplitAfter(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		} else if !inFence {
+			forEachLineTag(line, offset, visit)
+		}
+		offset += len(line)
+	}
+}
+
+// forEachLineTag 找出單一行中的 #標籤
+// 參數：line（一行內容）、offset（這一行在內文中的位置）、visit（每個標籤的位置和名稱）
+func forEachLineTag(line string, offset int, visit func(start, end int, tag string)) {
+	inCode := false
+	prev := ' '
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		switch {
+		case r == '`':
+			inCode = !inCode
+		case r == '#' && !inCode && unicode.IsSpace(prev):
+			end := i + size
+			for end < len(line) {
+				next, nextSize := utf8.DecodeRuneInString(line[end:])
+				if !isTagRune(next) {
+					break
+				}
+				end += nextSize
+			}
+			raw := strings.TrimRight(line[i+size:end], TagSeparator)
+			if tag := NormalizeTag(raw); tag != "" && tag == raw {
+				visit(offset+i, offset+i+size+len(raw), tag)
+				prev = 'x'
+				i += size + len(raw)
+				continue
+			}
+		}
+		prev = r
+		i += size
+	}
+}
+
+// rewriteInlineTags 改寫內文中的 #標籤，front matter 維持原樣
+// 參數：content（筆記內容）、rename（新的標籤名稱，空字串代表移除）
+// 回傳：改寫後的內容
+func rewriteInlineTags(content string, rename func(tag string) string) string {
+	parts, _ := splitFrontMatter(content)
+	body := parts.body
+
+	var builder strings.Builder
+	last := 0
+	forEachInlineTag(body, func(start, end int, tag string) {
+		renamed := rename(tag)
+		if renamed == tag {
+			return
+		}
+		if renamed != "" {
+			builder.WriteString(body[last:start])
+			builder.WriteString("#" + renamed)
+			last = end
+			return
+		}
+		// 移除標籤和前面的空白，標籤位於行首時改為移除後面的空白
+		cut := start
+		for cut > last && (body[cut-1] == ' ' || body[cut-1] == '\t') {
+			cut--
+		}
+		builder.WriteString(body[last:cut])
+		last = end
+		if cut == start || cut == 0 || body[cut-1] == '\n' {
+			for last < len(body) && (body[last] == ' ' || body[last] == '\t') {
+				last++
+			}
+		}
+	})
+	if last == 0 {
+		return content
+	}
+	builder.WriteString(body[last:])
+	return content[:len(content)-len(body)] + builder.String()
+}
+
+// isTagRune 檢查字元是否可以出現在標籤名稱中
+// 參數：r（字元）
+// 回傳：是否為字母、數字、_、- 或 /
+func isTagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '_' || r == '-' || r == '/'
+}
+
+// tagUnder 檢查標籤是否為指定的標籤或其子標籤
+// 參數：tag（標籤）、parent（上層標籤）
+// 回傳：是否相同或位於其下
+func tagUnder(tag, parent string) bool {
+	return tag == parent || strings.HasPrefix(tag, parent+TagSeparator)
+}
+
+// tagAncestors 取得標籤和其所有上層標籤
+// 參數：tag（標籤，例如 a/b/c）
+// 回傳：由上到下的標籤（a、a/b、a/b/c）
+func tagAncestors(tag string) []string {
+	var names []string
+	for i, r := range tag {
+		if r == '/' {
+			names = append(names, tag[:i])
+		}
+	}
+	return append(names, tag)
+}
+
+// isTaggableNoteFile 檢查檔案是否為可以直接讀取標籤的 Markdown 筆記
+// 參數：path（檔案路徑）
+// 回傳：是否為 Markdown 筆記
+func isTaggableNoteFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// isEncryptedNoteFile 檢查檔案是否為需要解密才能讀取的加密筆記
+// 參數：path（檔案路徑）
+// 回傳：是否為 .enc 加密筆記
+func isEncryptedNoteFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ObfuscatedFileExt)
+}
+
+// updateTagIndex 筆記保存後更新標籤索引，檔名變更時移除舊路徑
+// 參數：note（已保存的筆記）、oldPath（保存前的路徑）
+func (e *editorService) updateTagIndex(note *models.Note, oldPath string) {
+	if e.tagSvc == nil {
+		return
+	}
+	if oldPath != "" && oldPath != note.FilePath {
+		e.tagSvc.RemoveNote(oldPath)
+	}
+	e.tagSvc.UpdateNote(note.FilePath, note.Content)
+}
@@ -0,0 +1,165 @@
+// Package services 提供標籤服務的單元測試
+// 測試標籤解析、巢狀標籤的數量統計、跨筆記重新命名和合併，以及批次新增、移除標籤
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"mac-notebook-app/internal/repositories"
+)
+
+// createTestTagService 建立使用暫存筆記本的標籤服務和編輯器服務
+func createTestTagService(t *testing.T, files map[string]string) (string, TagService, EditorService) {
+	baseDir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(baseDir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	fileRepo, err := repositories.NewLocalFileRepository(baseDir)
+	if err != nil {
+		t.Fatalf("建立檔案儲存庫失敗: %v", err)
+	}
+	editor := NewEditorService(fileRepo, &mockEncryptionService{}, &mockPasswordService{}, &mockBiometricService{}, nil, nil)
+	tags := NewTagService(fileRepo, editor)
+	editor.SetOptions(EditorOptions{Tags: tags})
+	if err := tags.Rebuild(); err != nil {
+		t.Fatalf("建立標籤索引失敗: %v", err)
+	}
+	return baseDir, tags, editor
+}
+
+// readTestNote 讀取暫存筆記本中的筆記內容
+func readTestNote(t *testing.T, baseDir, name string) string {
+	data, err := os.ReadFile(filepath.Join(baseDir, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatalf("讀取筆記失敗: %v", err)
+	}
+	return string(data)
+}
+
+// TestExtractTags 測試解析 front matter 和內文中的標籤，程式碼和標題不視為標籤
+func TestExtractTags(t *testing.T) {
+	content := "---\ntags: [會議, \"#project/alpha\"]\n---\n# 標題\n內容 #工作 和 #project/beta/ 以及 #123\n" +
+		"網址 https://example.com/#anchor 與 a#b\n`#inline` 程式碼\n```\n#fenced\n```\n#todo"
+	expected := []string{"project/alpha", "project/beta", "todo", "工作", "會議"}
+	if tags := ExtractTags(content); !reflect.DeepEqual(tags, expected) {
+		t.Errorf("解析的標籤不正確: %v", tags)
+	}
+
+	for input, want := range map[string]string{"#工作": "工作", " a/b ": "a/b", "a//b": "", "2024": "", "有 空白": ""} {
+		if got := NormalizeTag(input); got != want {
+			t.Errorf("NormalizeTag(%q) = %q，應為 %q", input, got, want)
+		}
+	}
+}
+
+// TestTagIndex 測試巢狀標籤的數量統計和依標籤找出筆記
+func TestTagIndex(t *testing.T) {
+	baseDir, tags, editor := createTestTagService(t, map[string]string{
+		"a.md":             "#project/alpha #project/beta",
+		"notes/b.md":       "---\ntags: [project]\n---\n內容",
+		"notes/c.markdown": "#project/alpha/ui",
+		".notebook/d.md":   "#hidden",
+	})
+
+	roots := tags.Tags()
+	if len(roots) != 1 || roots[0].Name != "project" || roots[0].Count != 3 {
+		t.Fatalf("最上層標籤不正確: %+v", roots)
+	}
+	alpha := roots[0].Children[0]
+	if alpha.Label != "alpha" || alpha.Count != 2 || len(alpha.Children) != 1 || alpha.Children[0].Name != "project/alpha/ui" {
+		t.Errorf("巢狀標籤不正確: %+v", alpha)
+	}
+	expected := []string{"a.md", filepath.Join("notes", "c.markdown")}
+	if notes := tags.NotesWithTag("#project/alpha"); !reflect.DeepEqual(notes, expected) {
+		t.Errorf("含有標籤的筆記不正確: %v", notes)
+	}
+
+	// 保存後更新索引
+	note, _ := editor.OpenNote("a.md")
+	editor.UpdateContent(note.ID, "#released")
+	if err := editor.SaveNote(note); err != nil {
+		t.Fatalf("保存筆記失敗: %v", err)
+	}
+	if notes := tags.NotesWithTag("released"); !reflect.DeepEqual(notes, []string{"a.md"}) {
+		t.Errorf("保存後應該更新索引: %v", notes)
+	}
+
+	// 檔案監看的刪除事件移除目錄中的筆記
+	os.RemoveAll(filepath.Join(baseDir, "notes"))
+	tags.ApplyFileChanges([]FileChangeEvent{{Path: "notes", Op: FileRemoved}})
+	if notes := tags.NotesWithTag("project"); len(notes) != 0 {
+		t.Errorf("刪除後不應再有筆記: %v", notes)
+	}
+}
+
+// TestRenameTag 測試在所有筆記中重新命名標籤，子標籤一併改名，已有新標籤時合併
+func TestRenameTag(t *testing.T) {
+	baseDir, tags, _ := createTestTagService(t, map[string]string{
+		"a.md": "---\ntags: [proj, work]\n---\n內容 #proj/alpha 結尾\n",
+		"b.md": "#projects 不受影響\n#proj\n",
+	})
+
+	count, err := tags.RenameTag("proj", "work")
+	if err != nil || count != 2 {
+		t.Fatalf("重新命名標籤失敗: %d, %v", count, err)
+	}
+	if a := readTestNote(t, baseDir, "a.md"); a != "---\ntags: [work]\n---\n內容 #work/alpha 結尾\n" {
+		t.Errorf("a.md 的標籤沒有正確改名和合併:\n%s", a)
+	}
+	if b := readTestNote(t, baseDir, "b.md"); b != "#projects 不受影響\n#work\n" {
+		t.Errorf("b.md 的標籤沒有正確改名:\n%s", b)
+	}
+	if notes := tags.NotesWithTag("proj"); len(notes) != 0 {
+		t.Errorf("改名後不應再有舊標籤: %v", notes)
+	}
+	if _, err := tags.RenameTag("work", "work/sub"); err == nil {
+		t.Error("不應允許改名為自己的子標籤")
+	}
+}
+
+// TestBulkTags 測試為多個筆記新增和移除標籤，開啟中的筆記直接修改同一個實例
+func TestBulkTags(t *testing.T) {
+	baseDir, tags, editor := createTestTagService(t, map[string]string{
+		"a.md": "# 筆記 A\n",
+		"b.md": "#草稿 內容 #待辦\n",
+	})
+	opened, _ := editor.OpenNote("b.md")
+
+	count, err := tags.AddTags([]string{"a.md", "b.md"}, []string{"#待辦", "專案"})
+	if err != nil || count != 2 {
+		t.Fatalf("新增標籤失敗: %d, %v", count, err)
+	}
+	if a := readTestNote(t, baseDir, "a.md"); a != "---\ntags: [待辦, 專案]\n---\n# 筆記 A\n" {
+		t.Errorf("a.md 應該新增 front matter 標籤:\n%s", a)
+	}
+	if !reflect.DeepEqual(opened.Tags, []string{"專案"}) {
+		t.Errorf("開啟中的筆記應該更新標籤，內文已有的標籤不重複新增: %v", opened.Tags)
+	}
+
+	count, err = tags.RemoveTags([]string{"a.md", "b.md"}, []string{"待辦", "草稿"})
+	if err != nil || count != 2 {
+		t.Fatalf("移除標籤失敗: %d, %v", count, err)
+	}
+	if b := readTestNote(t, baseDir, "b.md"); !strings.HasSuffix(b, "---\n內容\n") {
+		t.Errorf("b.md 內文中的標籤應該移除:\n%s", b)
+	}
+	if opened.Content != readTestNote(t, baseDir, "b.md") {
+		t.Error("開啟中的筆記內容應該與檔案一致")
+	}
+	if notes := tags.NotesWithTag("待辦"); len(notes) != 0 {
+		t.Errorf("移除後不應再有筆記: %v", notes)
+	}
+
+	if _, err := tags.AddTags([]string{"missing.md"}, []string{"專案"}); err == nil {
+		t.Error("無法開啟的筆記應該回報錯誤")
+	}
+	if _, err := tags.AddTags([]string{"a.md"}, []string{"2024"}); err == nil {
+		t.Error("無效的標籤應該回報錯誤")
+	}
+}
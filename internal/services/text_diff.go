@@ -0,0 +1,271 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含以行為單位的文字差異比較和三方合併，用於比較復原內容和已保存的筆記，
+// 以及合併保存衝突時編輯器和磁碟上的版本
+package services
+
+import (
+	"slices"  // 切片比較
+	"strings" // 字串處理
+)
+
+// DiffOp 差異行的類型
+type DiffOp int
+
+// 差異行類型常數
+const (
+	DiffEqual  DiffOp = iota // 兩邊相同的行
+	DiffDelete               // 只存在於舊內容的行
+	DiffInsert               // 只存在於新內容的行
+)
+
+// maxDiffCells 最長共同子序列表格的大小上限，超過時中間不同的部分整段視為刪除後新增
+const maxDiffCells = 4_000_000
+
+// 三方合併衝突標記
+const (
+	MergeConflictStart  = "<<<<<<< 我的版本"   // 衝突開始，之後為編輯器中的內容
+	MergeConflictMiddle = "======="        // 分隔編輯器和磁碟上的內容
+	MergeConflictEnd    = ">>>>>>> 磁碟上的版本" // 衝突結束
+)
+
+// DiffLine 代表差異結果中的一行
+type DiffLine struct {
+	Op   DiffOp // 差異類型
+	Text string // 行內容（不含換行字元）
+}
+
+// DiffLines 以行為單位比較兩段文字
+// 參數：oldText（舊內容）、newText（新內容）
+// 回傳：依序排列的差異行
+//
+// 執行流程：
+// 1. 略過開頭和結尾相同的行
+// 2. 以最長共同子序列找出中間部分相同的行
+// 3. 中間部分過大時整段視為刪除後新增，避免佔用過多記憶體
+func DiffLines(oldText, newText string) []DiffLine {
+	return diffLineSlices(strings.Split(oldText, "\n"), strings.Split(newText, "\n"))
+}
+
+// diffLineSlices 比較兩組行
+// 參數：oldLines（舊內容的行）、newLines（新內容的行）
+// 回傳：依序排列的差異行
+func diffLineSlices(oldLines, newLines []string) []DiffLine {
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	var result []DiffLine
+	for _, line := range oldLines[:prefix] {
+		result = append(result, DiffLine{Op: DiffEqual, Text: line})
+	}
+	result = append(result, diffMiddle(oldLines[prefix:len(oldLines)-suffix], newLines[prefix:len(newLines)-suffix])...)
+	for _, line := range oldLines[len(oldLines)-suffix:] {
+		result = append(result, DiffLine{Op: DiffEqual, Text: line})
+	}
+	return result
+}
+
+// FormatDiff 將差異結果格式化為文字，刪除的行以 "- " 開頭、新增的行以 "+ " 開頭
+// 參數：lines（差異行）
+// 回傳：格式化後的文字
+func FormatDiff(lines []DiffLine) string {
+	var builder strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			builder.WriteByte('\n')
+		}
+		switch line.Op {
+		case DiffDelete:
+			builder.WriteString("- ")
+		case DiffInsert:
+			builder.WriteString("+ ")
+		default:
+			builder.WriteString("  ")
+		}
+		builder.WriteString(line.Text)
+	}
+	return builder.String()
+}
+
+// diffMiddle 以最長共同子序列比較兩組行
+// 參數：oldLines、newLines（要比較的行）
+// 回傳：差異行
+func diffMiddle(oldLines, newLines []string) []DiffLine {
+	var result []DiffLine
+	if len(oldLines)*len(newLines) > maxDiffCells {
+		for _, line := range oldLines {
+			result = append(result, DiffLine{Op: DiffDelete, Text: line})
+		}
+		for _, line := range newLines {
+			result = append(result, DiffLine{Op: DiffInsert, Text: line})
+		}
+		return result
+	}
+
+	// lcs[i][j] 為 oldLines[i:] 和 newLines[j:] 的最長共同子序列長度
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(oldLines) && j < len(newLines) {
+		switch {
+		case oldLines[i] == newLines[j]:
+			result = append(result, DiffLine{Op: DiffEqual, Text: oldLines[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			result = append(result, DiffLine{Op: DiffDelete, Text: oldLines[i]})
+			i++
+		default:
+			result = append(result, DiffLine{Op: DiffInsert, Text: newLines[j]})
+			j++
+		}
+	}
+	for ; i < len(oldLines); i++ {
+		result = append(result, DiffLine{Op: DiffDelete, Text: oldLines[i]})
+	}
+	for ; j < len(newLines); j++ {
+		result = append(result, DiffLine{Op: DiffInsert, Text: newLines[j]})
+	}
+	return result
+}
+
+// mergeHunk 代表一段變更：以 lines 取代基準內容的 [start, end) 行
+type mergeHunk struct {
+	start int      // 基準內容中被取代的起始行
+	end   int      // 基準內容中被取代的結束行（不含）
+	lines []string // 取代後的行
+}
+
+// overlaps 檢查變更區段是否與基準內容的 [start, end) 行重疊
+// 參數：start、end（範圍）
+// 回傳：是否重疊（在範圍起點或終點插入的行無法決定先後順序，也視為重疊）
+func (h mergeHunk) overlaps(start, end int) bool {
+	return h.start < end || h.start == start || (h.start == end && h.start == h.end)
+}
+
+// MergeText 以共同基準進行三方合併
+// 參數：base（共同基準）、mine（編輯器中的內容）、theirs（磁碟上的內容）
+// 回傳：合併結果和是否有衝突（衝突部分以 MergeConflictStart 等標記包住兩邊的內容）
+//
+// 執行流程：
+// 1. 分別找出兩邊相對於基準的變更區段
+// 2. 只有一邊變更的區段直接採用該邊的內容
+// 3. 兩邊變更的區段重疊時，內容相同則採用，不同則以衝突標記保留兩邊
+func MergeText(base, mine, theirs string) (string, bool) {
+	if mine == theirs {
+		return mine, false
+	}
+	baseLines := strings.Split(base, "\n")
+	mineHunks := diffHunks(baseLines, strings.Split(mine, "\n"))
+	theirHunks := diffHunks(baseLines, strings.Split(theirs, "\n"))
+
+	var result []string
+	conflicted := false
+	pos, m, t := 0, 0, 0
+	for m < len(mineHunks) || t < len(theirHunks) {
+		// 從起始位置最前面的區段開始，納入所有與之重疊的區段
+		start := 0
+		if t >= len(theirHunks) || (m < len(mineHunks) && mineHunks[m].start <= theirHunks[t].start) {
+			start = mineHunks[m].start
+		} else {
+			start = theirHunks[t].start
+		}
+		end := start
+		mineFrom, theirFrom := m, t
+		for {
+			if m < len(mineHunks) && mineHunks[m].overlaps(start, end) {
+				end = max(end, mineHunks[m].end)
+				m++
+			} else if t < len(theirHunks) && theirHunks[t].overlaps(start, end) {
+				end = max(end, theirHunks[t].end)
+				t++
+			} else {
+				break
+			}
+		}
+
+		result = append(result, baseLines[pos:start]...)
+		mineVersion := applyHunks(baseLines, mineHunks[mineFrom:m], start, end)
+		theirVersion := applyHunks(baseLines, theirHunks[theirFrom:t], start, end)
+		switch {
+		case mineFrom == m:
+			result = append(result, theirVersion...)
+		case theirFrom == t, slices.Equal(mineVersion, theirVersion):
+			result = append(result, mineVersion...)
+		default:
+			conflicted = true
+			result = append(result, MergeConflictStart)
+			result = append(result, mineVersion...)
+			result = append(result, MergeConflictMiddle)
+			result = append(result, theirVersion...)
+			result = append(result, MergeConflictEnd)
+		}
+		pos = end
+	}
+	result = append(result, baseLines[pos:]...)
+	return strings.Join(result, "\n"), conflicted
+}
+
+// diffHunks 取得相對於基準內容的變更區段
+// 參數：baseLines（基準內容的行）、otherLines（另一版本的行）
+// 回傳：依起始位置排列的變更區段
+func diffHunks(baseLines, otherLines []string) []mergeHunk {
+	var hunks []mergeHunk
+	var current *mergeHunk
+	basePos := 0
+	for _, line := range diffLineSlices(baseLines, otherLines) {
+		if line.Op == DiffEqual {
+			if current != nil {
+				hunks = append(hunks, *current)
+				current = nil
+			}
+			basePos++
+			continue
+		}
+		if current == nil {
+			current = &mergeHunk{start: basePos, end: basePos}
+		}
+		if line.Op == DiffDelete {
+			basePos++
+			current.end = basePos
+		} else {
+			current.lines = append(current.lines, line.Text)
+		}
+	}
+	if current != nil {
+		hunks = append(hunks, *current)
+	}
+	return hunks
+}
+
+// applyHunks 將變更區段套用到基準內容的 [start, end) 行
+// 參數：baseLines（基準內容的行）、hunks（位於範圍內的變更區段）、start、end（範圍）
+// 回傳：套用後的行
+func applyHunks(baseLines []string, hunks []mergeHunk, start, end int) []string {
+	var result []string
+	pos := start
+	for _, hunk := range hunks {
+		result = append(result, baseLines[pos:hunk.start]...)
+		result = append(result, hunk.lines...)
+		pos = hunk.end
+	}
+	return append(result, baseLines[pos:end]...)
+}
@@ -0,0 +1,147 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案實作 RFC 6238 的 TOTP 一次性驗證碼，供保險庫作為不需要硬體的第二驗證因素
+package services
+
+import (
+	"crypto/hmac"     // HMAC 訊息驗證碼
+	"crypto/rand"     // 安全隨機數產生器
+	"crypto/sha1"     // RFC 6238 預設使用的 HMAC-SHA1
+	"crypto/subtle"   // 常數時間比較
+	"encoding/base32" // Base32 編碼（驗證器應用程式使用的秘密格式）
+	"encoding/binary" // 時間步的位元組轉換
+	"errors"          // 錯誤處理
+	"fmt"             // 格式化輸出
+	"image"           // QR Code 圖片
+	"io"              // 輸入輸出介面
+	"net/url"         // otpauth URI 編碼
+	"strings"         // 字串處理
+	"time"            // 時間處理
+
+	"github.com/skip2/go-qrcode" // QR Code 編碼
+)
+
+// TOTP 相關常數
+const (
+	TOTPPeriod     = 30 // 驗證碼的有效時間（秒）
+	TOTPDigits     = 6  // 驗證碼位數
+	totpSkew       = 1  // 允許前後各一個時間步的時鐘誤差
+	totpSecretSize = 20 // 秘密長度（位元組，與 HMAC-SHA1 輸出長度相同）
+)
+
+// totpEncoding 驗證器應用程式使用的 Base32 編碼（不含補位字元）
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret 產生新的 TOTP 秘密
+// 回傳：Base32 編碼的秘密和可能的錯誤
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, totpSecretSize)
+	if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+		return "", fmt.Errorf("產生 TOTP 秘密失敗: %w", err)
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TOTPCode 計算指定時間的驗證碼
+// 參數：secret（Base32 編碼的秘密）、t（時間）
+// 回傳：驗證碼和可能的錯誤
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return totpCodeAt(key, totpStep(t)), nil
+}
+
+// TOTPProvisioningURI 產生驗證器應用程式可以匯入的 otpauth URI（以 TOTPQRCode 轉成 QR Code 掃描）
+// 參數：secret（Base32 編碼的秘密）、account（帳號名稱）、issuer（發行者名稱）
+// 回傳：otpauth URI
+func TOTPProvisioningURI(secret, account, issuer string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(TOTPDigits))
+	query.Set("period", fmt.Sprint(TOTPPeriod))
+
+	uri := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}
+	return uri.String()
+}
+
+// TOTPQRCode 將 otpauth URI 轉成驗證器應用程式可以掃描的 QR Code
+// 參數：uri（otpauth URI）、size（圖片邊長，像素）
+// 回傳：QR Code 圖片和可能的錯誤
+func TOTPQRCode(uri string, size int) (image.Image, error) {
+	code, err := qrcode.New(uri, qrcode.Medium)
+	if err != nil {
+		return nil, fmt.Errorf("產生 QR Code 失敗: %w", err)
+	}
+	return code.Image(size), nil
+}
+
+// decodeTOTPSecret 解碼 Base32 秘密，忽略空白和大小寫
+// 參數：secret（Base32 編碼的秘密）
+// 回傳：秘密和可能的錯誤
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	normalized := strings.ToUpper(strings.Join(strings.Fields(secret), ""))
+	normalized = strings.TrimRight(normalized, "=")
+
+	key, err := totpEncoding.DecodeString(normalized)
+	if err != nil || len(key) == 0 {
+		return nil, errors.New("TOTP 秘密格式無效")
+	}
+	return key, nil
+}
+
+// totpStep 計算時間所在的時間步
+// 參數：t（時間）
+// 回傳：時間步
+func totpStep(t time.Time) int64 {
+	return t.Unix() / TOTPPeriod
+}
+
+// totpCodeAt 依 RFC 4226 動態截斷計算指定時間步的驗證碼
+// 參數：key（秘密）、step（時間步）
+// 回傳：補零至固定位數的驗證碼
+func totpCodeAt(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulus := uint32(1)
+	for i := 0; i < TOTPDigits; i++ {
+		modulus *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, value%modulus)
+}
+
+// verifyTOTP 驗證驗證碼，允許前後一個時間步的誤差並拒絕已使用過的時間步
+// 參數：key（秘密）、code（驗證碼）、t（目前時間）、lastStep（最後一次使用的時間步）
+// 回傳：符合的時間步和是否驗證成功
+func verifyTOTP(key []byte, code string, t time.Time, lastStep int64) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+
+	current := totpStep(t)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCodeAt(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
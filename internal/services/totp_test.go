@@ -0,0 +1,88 @@
+// Package services 提供 TOTP 驗證碼的單元測試
+// 使用 RFC 6238 附錄 B 的 SHA-1 測試向量驗證計算結果，並測試驗證器匯入用的 URI 和 QR Code
+package services
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTOTPCodeRFC6238Vectors 測試 RFC 6238 的測試向量（取 8 位數結果的後 6 位）
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, expected := range vectors {
+		code, err := TOTPCode(secret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatalf("計算驗證碼失敗: %v", err)
+		}
+		if code != expected {
+			t.Errorf("時間 %d 的驗證碼應該為 %s，實際為 %s", unix, expected, code)
+		}
+	}
+
+	if _, err := TOTPCode("不是 base32", time.Now()); err == nil {
+		t.Error("無效的秘密應該回傳錯誤")
+	}
+}
+
+// TestVerifyTOTP 測試時鐘誤差容許範圍和拒絕重複使用的驗證碼
+func TestVerifyTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("產生秘密失敗: %v", err)
+	}
+	key, _ := decodeTOTPSecret(strings.ToLower(secret))
+	now := time.Unix(1700000000, 0)
+
+	previous, _ := TOTPCode(secret, now.Add(-TOTPPeriod*time.Second))
+	step, ok := verifyTOTP(key, previous, now, 0)
+	if !ok || step != totpStep(now)-1 {
+		t.Fatalf("前一個時間步的驗證碼應該有效: %v", ok)
+	}
+	if _, ok := verifyTOTP(key, previous, now, step); ok {
+		t.Error("已使用過的驗證碼不應再次通過")
+	}
+
+	expired, _ := TOTPCode(secret, now.Add(-3*TOTPPeriod*time.Second))
+	if _, ok := verifyTOTP(key, expired, now, 0); ok {
+		t.Error("超過容許範圍的驗證碼不應通過")
+	}
+
+	uri := TOTPProvisioningURI(secret, "vault", "Notebook")
+	if !strings.HasPrefix(uri, "otpauth://totp/Notebook:vault?") || !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("otpauth URI 格式不正確: %s", uri)
+	}
+
+	qr, err := TOTPQRCode(uri, 200)
+	if err != nil {
+		t.Fatalf("產生 QR Code 失敗: %v", err)
+	}
+	if bounds := qr.Bounds(); bounds.Dx() != 200 || bounds.Dy() != 200 {
+		t.Errorf("QR Code 大小不正確: %v", bounds)
+	}
+	if r, _, _, _ := qr.At(0, 0).RGBA(); r == 0 {
+		t.Error("QR Code 應該保留白色邊界")
+	}
+	dark := 0
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 200; x++ {
+			if r, _, _, _ := qr.At(x, y).RGBA(); r == 0 {
+				dark++
+			}
+		}
+	}
+	if dark == 0 {
+		t.Error("QR Code 應該包含深色模組")
+	}
+}
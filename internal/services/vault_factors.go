@@ -0,0 +1,401 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含保險庫的第二驗證因素：
+// 金鑰檔（與密碼一起衍生 KEK，缺少金鑰檔時無法解開主金鑰）和 TOTP 驗證碼（解開主金鑰後離線驗證）
+package services
+
+import (
+	"crypto/rand"     // 安全隨機數產生器
+	"crypto/sha256"   // 金鑰檔雜湊
+	"encoding/base64" // Base64 編碼
+	"encoding/hex"    // 產生的金鑰檔以十六進位文字保存
+	"errors"          // 錯誤處理
+	"fmt"             // 格式化輸出
+	"io"              // 輸入輸出介面
+	"log"             // 日誌記錄
+	"time"            // 時間處理
+)
+
+// 金鑰檔相關常數
+const (
+	MinKeyfileSize       = 32                   // 金鑰檔的最小長度（位元組）
+	generatedKeyfileSize = 64                   // 產生的金鑰檔包含的隨機位元組數
+	keyfileKEKSeparator  = "\x00nb-keyfile-v1:" // 組合密碼和金鑰檔雜湊時使用的分隔字串
+)
+
+// 第二驗證因素錯誤定義
+var (
+	ErrVaultKeyfileRequired = errors.New("保險庫需要金鑰檔才能解鎖")
+	ErrVaultTOTPRequired    = errors.New("保險庫需要驗證碼才能解鎖")
+	ErrVaultWrongTOTP       = errors.New("驗證碼錯誤或已使用過")
+)
+
+// VaultFactors 代表保險庫啟用的第二驗證因素
+type VaultFactors struct {
+	Keyfile bool // 是否需要金鑰檔
+	TOTP    bool // 是否需要 TOTP 驗證碼
+}
+
+// Any 檢查是否啟用任何第二驗證因素
+// 回傳：是否啟用
+func (f VaultFactors) Any() bool {
+	return f.Keyfile || f.TOTP
+}
+
+// UnlockFactors 代表解鎖時提供的第二驗證因素
+type UnlockFactors struct {
+	Keyfile  []byte // 金鑰檔內容（不需要時可為 nil）
+	TOTPCode string // 驗證器顯示的驗證碼（不需要時可為空字串）
+}
+
+// IsSecondFactorRequired 檢查錯誤是否表示缺少第二驗證因素
+// 參數：err（解鎖時回傳的錯誤）
+// 回傳：是否需要金鑰檔或驗證碼
+func IsSecondFactorRequired(err error) bool {
+	return errors.Is(err, ErrVaultKeyfileRequired) || errors.Is(err, ErrVaultTOTPRequired)
+}
+
+// GenerateKeyfile 產生新的隨機金鑰檔內容
+// 回傳：十六進位文字格式的金鑰檔內容和可能的錯誤
+func GenerateKeyfile() ([]byte, error) {
+	random := make([]byte, generatedKeyfileSize)
+	if _, err := io.ReadFull(rand.Reader, random); err != nil {
+		return nil, fmt.Errorf("產生金鑰檔失敗: %w", err)
+	}
+	defer zeroBytes(random)
+
+	return []byte(hex.EncodeToString(random) + "\n"), nil
+}
+
+// UnlockWithFactors 以密碼和第二驗證因素解鎖保險庫
+// 參數：password（保險庫密碼）、factors（金鑰檔內容和驗證碼，可為 nil）
+// 回傳：可能的錯誤
+//
+// 密碼、金鑰檔或驗證碼錯誤都計入重試次數，次數過多時回傳 *LockoutError；
+// 除了要求輸入第二驗證因素以外，每次嘗試都寫入稽核記錄
+func (v *vaultService) UnlockWithFactors(password string, factors *UnlockFactors) error {
+	err := guardPasswordAttempt(v.retryGuard, VaultRetryIdentifier, func() error {
+		return v.unlockWithFactors(password, factors)
+	}, func(err error) bool {
+		return errors.Is(err, ErrVaultWrongPassword) || errors.Is(err, ErrVaultWrongTOTP)
+	})
+	if !IsSecondFactorRequired(err) {
+		recordAudit(v.audit, AuditEventUnlock, VaultRetryIdentifier, err, "")
+	}
+	return err
+}
+
+// unlockWithFactors 以密碼和第二驗證因素解鎖保險庫（不檢查重試次數）
+// 參數：password（保險庫密碼）、factors（金鑰檔內容和驗證碼，可為 nil）
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 載入標頭，確認已提供標頭要求的第二驗證因素（金鑰檔可沿用工作階段中的雜湊）
+// 2. 以密碼和金鑰檔雜湊衍生 KEK 並解開主金鑰
+// 3. 保險庫原本鎖定且啟用 TOTP 時，以主金鑰解開 TOTP 秘密並驗證驗證碼
+// 4. 標頭的 KDF 參數弱於目前預設值時，重新包裝主金鑰並儲存新標頭
+// 5. 將主金鑰和金鑰檔雜湊保留在工作階段中
+func (v *vaultService) unlockWithFactors(password string, factors *UnlockFactors) error {
+	header, err := v.loadHeader()
+	if err != nil {
+		return err
+	}
+	if factors == nil {
+		factors = &UnlockFactors{}
+	}
+
+	// 已解鎖時只是重新驗證密碼，主金鑰已經在記憶體中，不需要再次輸入驗證碼
+	requireTOTP := header.TOTPSecret != "" && !v.IsUnlocked()
+	if requireTOTP && factors.TOTPCode == "" {
+		return ErrVaultTOTPRequired
+	}
+
+	keyfileDigest, err := v.resolveKeyfileDigest(header, factors.Keyfile)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(keyfileDigest)
+
+	masterKey, err := unwrapVaultMasterKey(header, password, keyfileDigest)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(masterKey)
+
+	if requireTOTP {
+		if err := v.verifyVaultTOTP(header, masterKey, factors.TOTPCode); err != nil {
+			return err
+		}
+	}
+
+	// 升級失敗不影響解鎖，下次解鎖時會再次嘗試
+	if header.kdfParams().IsWeakerThan(DefaultKDFParams()) {
+		if err := v.rewrapMasterKey(header, masterKey, password, keyfileDigest); err != nil {
+			log.Printf("升級保險庫金鑰衍生參數失敗: %v", err)
+		}
+	}
+
+	v.session.StoreKey(vaultSessionKey, masterKey)
+	if keyfileDigest != nil {
+		v.session.StoreKey(vaultKeyfileKey, keyfileDigest)
+	}
+
+	return nil
+}
+
+// SecondFactors 取得保險庫啟用的第二驗證因素
+// 回傳：第二驗證因素設定
+func (v *vaultService) SecondFactors() VaultFactors {
+	header, err := v.loadHeader()
+	if err != nil {
+		return VaultFactors{}
+	}
+	return VaultFactors{
+		Keyfile: header.Keyfile,
+		TOTP:    header.TOTPSecret != "",
+	}
+}
+
+// EnableKeyfile 設定（或更換）解鎖時需要的金鑰檔
+// 參數：password（保險庫密碼）、keyfile（金鑰檔內容）
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 確認保險庫已解鎖且金鑰檔長度足夠
+// 2. 以密碼和目前的金鑰檔雜湊解開主金鑰，驗證密碼正確
+// 3. 以密碼和新金鑰檔的雜湊重新包裝主金鑰並儲存標頭
+// 4. 更新工作階段中的金鑰檔雜湊
+func (v *vaultService) EnableKeyfile(password string, keyfile []byte) error {
+	if !v.IsUnlocked() {
+		return ErrVaultLocked
+	}
+	if len(keyfile) < MinKeyfileSize {
+		return fmt.Errorf("金鑰檔至少需要 %d 位元組", MinKeyfileSize)
+	}
+
+	header, masterKey, currentDigest, err := v.verifyVaultPassword(password)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(masterKey)
+	defer zeroBytes(currentDigest)
+
+	newDigest := keyfileDigest(keyfile)
+	defer zeroBytes(newDigest)
+
+	if err := v.rewrapMasterKey(header, masterKey, password, newDigest); err != nil {
+		return err
+	}
+	v.session.StoreKey(vaultKeyfileKey, newDigest)
+
+	return nil
+}
+
+// DisableKeyfile 移除金鑰檔，之後只需要密碼即可解鎖
+// 參數：password（保險庫密碼）
+// 回傳：可能的錯誤
+func (v *vaultService) DisableKeyfile(password string) error {
+	if !v.IsUnlocked() {
+		return ErrVaultLocked
+	}
+
+	header, masterKey, currentDigest, err := v.verifyVaultPassword(password)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(masterKey)
+	defer zeroBytes(currentDigest)
+
+	if !header.Keyfile {
+		return nil
+	}
+	if err := v.rewrapMasterKey(header, masterKey, password, nil); err != nil {
+		return err
+	}
+	v.session.DeleteKey(vaultKeyfileKey)
+
+	return nil
+}
+
+// EnableTOTP 啟用 TOTP 驗證碼
+// 參數：secret（Base32 編碼的 TOTP 秘密）、code（驗證器顯示的驗證碼）
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 確認保險庫已解鎖，並以驗證碼確認驗證器已正確設定
+// 2. 以主金鑰加密 TOTP 秘密，沒有密碼時無法從標頭取得秘密
+// 3. 記錄已使用的時間步並儲存標頭，確認用的驗證碼不能再用來解鎖
+func (v *vaultService) EnableTOTP(secret, code string) error {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(key)
+
+	masterKey, err := v.copyMasterKey()
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(masterKey)
+
+	step, ok := verifyTOTP(key, code, time.Now(), 0)
+	if !ok {
+		return ErrVaultWrongTOTP
+	}
+
+	header, err := v.loadHeader()
+	if err != nil {
+		return err
+	}
+
+	sealed, nonce, err := sealWithAlgorithm(AlgorithmAES256, masterKey, key, []byte(vaultTOTPAAD))
+	if err != nil {
+		return fmt.Errorf("加密 TOTP 秘密失敗: %w", err)
+	}
+
+	header.TOTPSecret = base64.StdEncoding.EncodeToString(append(nonce, sealed...))
+	header.TOTPLastStep = step
+	header.UpdatedAt = time.Now()
+
+	return v.storeHeader(header)
+}
+
+// DisableTOTP 停用 TOTP 驗證碼
+// 參數：password（保險庫密碼）
+// 回傳：可能的錯誤
+func (v *vaultService) DisableTOTP(password string) error {
+	if !v.IsUnlocked() {
+		return ErrVaultLocked
+	}
+
+	header, masterKey, currentDigest, err := v.verifyVaultPassword(password)
+	if err != nil {
+		return err
+	}
+	zeroBytes(masterKey)
+	zeroBytes(currentDigest)
+
+	if header.TOTPSecret == "" {
+		return nil
+	}
+	header.TOTPSecret = ""
+	header.TOTPLastStep = 0
+	header.UpdatedAt = time.Now()
+
+	return v.storeHeader(header)
+}
+
+// verifyVaultPassword 以密碼和工作階段中的金鑰檔雜湊解開主金鑰，驗證密碼正確
+// 參數：password（保險庫密碼）
+// 回傳：目前的標頭、主金鑰和金鑰檔雜湊（呼叫者負責清零）和可能的錯誤
+func (v *vaultService) verifyVaultPassword(password string) (*VaultHeader, []byte, []byte, error) {
+	header, err := v.loadHeader()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	digest, err := v.resolveKeyfileDigest(header, nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	masterKey, err := unwrapVaultMasterKey(header, password, digest)
+	if err != nil {
+		zeroBytes(digest)
+		return nil, nil, nil, err
+	}
+	return header, masterKey, digest, nil
+}
+
+// resolveKeyfileDigest 取得解開主金鑰需要的金鑰檔雜湊
+// 參數：header（保險庫標頭）、keyfile（提供的金鑰檔內容，可為 nil）
+// 回傳：金鑰檔雜湊（不需要金鑰檔時為 nil，呼叫者負責清零）和可能的錯誤
+//
+// 沒有提供金鑰檔時沿用工作階段中保存的雜湊，讓已解鎖的保險庫可以變更密碼或重新驗證
+func (v *vaultService) resolveKeyfileDigest(header *VaultHeader, keyfile []byte) ([]byte, error) {
+	if !header.Keyfile {
+		return nil, nil
+	}
+	if len(keyfile) > 0 {
+		return keyfileDigest(keyfile), nil
+	}
+	if digest, ok := v.session.GetKey(vaultKeyfileKey); ok {
+		return digest, nil
+	}
+	return nil, ErrVaultKeyfileRequired
+}
+
+// verifyVaultTOTP 以主金鑰解開 TOTP 秘密並驗證驗證碼，成功後記錄已使用的時間步
+// 參數：header（保險庫標頭，會更新最後使用的時間步）、masterKey（主金鑰）、code（驗證碼）
+// 回傳：可能的錯誤（驗證碼錯誤時回傳 ErrVaultWrongTOTP）
+func (v *vaultService) verifyVaultTOTP(header *VaultHeader, masterKey []byte, code string) error {
+	stored, err := base64.StdEncoding.DecodeString(header.TOTPSecret)
+	if err != nil || len(stored) <= NonceSize {
+		return errors.New("TOTP 秘密格式無效")
+	}
+
+	key, err := openWithAlgorithm(AlgorithmAES256, masterKey, stored[:NonceSize], stored[NonceSize:], []byte(vaultTOTPAAD))
+	if err != nil {
+		return fmt.Errorf("解開 TOTP 秘密失敗: %w", err)
+	}
+	defer zeroBytes(key)
+
+	step, ok := verifyTOTP(key, code, time.Now(), header.TOTPLastStep)
+	if !ok {
+		return ErrVaultWrongTOTP
+	}
+
+	// 記錄失敗時仍允許解鎖，只是同一個驗證碼在有效期間內可能被重複使用
+	header.TOTPLastStep = step
+	if err := v.storeHeader(header); err != nil {
+		log.Printf("記錄 TOTP 時間步失敗: %v", err)
+	}
+	return nil
+}
+
+// keyfileDigest 計算金鑰檔內容的 SHA-256 雜湊
+// 參數：keyfile（金鑰檔內容）
+// 回傳：雜湊值
+func keyfileDigest(keyfile []byte) []byte {
+	sum := sha256.Sum256(keyfile)
+	return sum[:]
+}
+
+// unwrapVaultMasterKey 以密碼和金鑰檔雜湊解開主金鑰
+// 參數：header（保險庫標頭）、password（保險庫密碼）、keyfileDigest（金鑰檔雜湊，nil 表示不使用金鑰檔）
+// 回傳：主金鑰和可能的錯誤（密碼或金鑰檔錯誤時回傳 ErrVaultWrongPassword）
+func unwrapVaultMasterKey(header *VaultHeader, password string, keyfileDigest []byte) ([]byte, error) {
+	if password == "" {
+		return nil, errors.New("密碼不能為空")
+	}
+	return unwrapMasterKey(header, compositeVaultPassword(password, keyfileDigest))
+}
+
+// rewrapVaultHeader 以密碼和金鑰檔雜湊重新包裝主金鑰，並保留原標頭的建立時間、TOTP 和復原金鑰設定
+// 參數：header（目前的標頭）、masterKey（主金鑰）、password（保險庫密碼）、keyfileDigest（金鑰檔雜湊，nil 表示不使用金鑰檔）
+// 回傳：新的標頭和可能的錯誤
+func rewrapVaultHeader(header *VaultHeader, masterKey []byte, password string, keyfileDigest []byte) (*VaultHeader, error) {
+	newHeader, err := wrapMasterKey(masterKey, compositeVaultPassword(password, keyfileDigest), DefaultKDFParams())
+	if err != nil {
+		return nil, err
+	}
+	newHeader.Keyfile = keyfileDigest != nil
+	newHeader.TOTPSecret = header.TOTPSecret
+	newHeader.TOTPLastStep = header.TOTPLastStep
+	newHeader.RecoveryWrappedKey = header.RecoveryWrappedKey
+	newHeader.RecoveryShares = header.RecoveryShares
+	newHeader.RecoveryThreshold = header.RecoveryThreshold
+	newHeader.CreatedAt = header.CreatedAt
+	newHeader.UpdatedAt = time.Now()
+
+	return newHeader, nil
+}
+
+// compositeVaultPassword 組合密碼和金鑰檔雜湊作為 KDF 的輸入
+// 參數：password（保險庫密碼）、keyfileDigest（金鑰檔雜湊，nil 表示不使用金鑰檔）
+// 回傳：KDF 使用的組合密碼
+func compositeVaultPassword(password string, keyfileDigest []byte) string {
+	if keyfileDigest == nil {
+		return password
+	}
+	return password + keyfileKEKSeparator + base64.StdEncoding.EncodeToString(keyfileDigest)
+}
@@ -0,0 +1,126 @@
+// Package services 提供保險庫第二驗證因素的單元測試
+// 測試金鑰檔參與金鑰衍生、TOTP 驗證碼檢查，以及啟用第二驗證因素後變更密碼
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestVaultKeyfileFactor 測試設定金鑰檔後必須同時提供密碼和正確的金鑰檔才能解鎖
+func TestVaultKeyfileFactor(t *testing.T) {
+	vault, _ := createTestVaultService(t)
+	if err := vault.Initialize("Password123!"); err != nil {
+		t.Fatalf("初始化保險庫失敗: %v", err)
+	}
+	data, keyID, _ := vault.EncryptNote("金鑰檔保護的內容", AlgorithmAES256, "")
+
+	keyfile, err := GenerateKeyfile()
+	if err != nil {
+		t.Fatalf("產生金鑰檔失敗: %v", err)
+	}
+	if err := vault.EnableKeyfile("Password123!", []byte("太短")); err == nil {
+		t.Error("過短的金鑰檔不應能設定")
+	}
+	if err := vault.EnableKeyfile("WrongPassword!", keyfile); !errors.Is(err, ErrVaultWrongPassword) {
+		t.Errorf("密碼錯誤時應該回傳 ErrVaultWrongPassword: %v", err)
+	}
+	if err := vault.EnableKeyfile("Password123!", keyfile); err != nil {
+		t.Fatalf("設定金鑰檔失敗: %v", err)
+	}
+	if factors := vault.SecondFactors(); !factors.Keyfile || factors.TOTP {
+		t.Errorf("應該只啟用金鑰檔: %+v", factors)
+	}
+
+	// 解鎖狀態下變更密碼沿用工作階段中的金鑰檔雜湊
+	if err := vault.ChangePassword("Password123!", "NewPassword456!"); err != nil {
+		t.Fatalf("變更密碼失敗: %v", err)
+	}
+
+	vault.Lock()
+	if err := vault.Unlock("NewPassword456!"); !errors.Is(err, ErrVaultKeyfileRequired) || !IsSecondFactorRequired(err) {
+		t.Errorf("沒有金鑰檔時應該回傳 ErrVaultKeyfileRequired: %v", err)
+	}
+	otherKeyfile, _ := GenerateKeyfile()
+	if err := vault.UnlockWithFactors("NewPassword456!", &UnlockFactors{Keyfile: otherKeyfile}); !errors.Is(err, ErrVaultWrongPassword) {
+		t.Errorf("金鑰檔錯誤時應該回傳 ErrVaultWrongPassword: %v", err)
+	}
+	if err := vault.UnlockWithFactors("NewPassword456!", &UnlockFactors{Keyfile: keyfile}); err != nil {
+		t.Fatalf("以密碼和金鑰檔解鎖失敗: %v", err)
+	}
+	if content, info, err := vault.DecryptNote(data); err != nil || content != "金鑰檔保護的內容" || info.KeyID != keyID {
+		t.Errorf("解鎖後應該能解密原有筆記: %q, %v", content, err)
+	}
+
+	// 移除金鑰檔後只需要密碼
+	if err := vault.DisableKeyfile("NewPassword456!"); err != nil {
+		t.Fatalf("移除金鑰檔失敗: %v", err)
+	}
+	vault.Lock()
+	if err := vault.Unlock("NewPassword456!"); err != nil {
+		t.Errorf("移除金鑰檔後應該只需要密碼: %v", err)
+	}
+}
+
+// TestVaultTOTPFactor 測試啟用 TOTP 後解鎖需要有效且未使用過的驗證碼
+func TestVaultTOTPFactor(t *testing.T) {
+	vault, _ := createTestVaultService(t)
+	vault.Initialize("Password123!")
+
+	secret, _ := GenerateTOTPSecret()
+	now := time.Now()
+	code, _ := TOTPCode(secret, now)
+	if err := vault.EnableTOTP(secret, "000000"+code); !errors.Is(err, ErrVaultWrongTOTP) {
+		t.Errorf("驗證碼錯誤時不應啟用 TOTP: %v", err)
+	}
+	if err := vault.EnableTOTP(secret, code); err != nil {
+		t.Fatalf("啟用 TOTP 失敗: %v", err)
+	}
+	if factors := vault.SecondFactors(); !factors.TOTP || factors.Keyfile {
+		t.Errorf("應該只啟用 TOTP: %+v", factors)
+	}
+
+	// 已解鎖時重新驗證密碼不需要驗證碼（例如批次重新加密）
+	if err := vault.Unlock("Password123!"); err != nil {
+		t.Errorf("已解鎖時重新驗證密碼不應要求驗證碼: %v", err)
+	}
+
+	vault.Lock()
+	if err := vault.Unlock("Password123!"); !errors.Is(err, ErrVaultTOTPRequired) {
+		t.Errorf("沒有驗證碼時應該回傳 ErrVaultTOTPRequired: %v", err)
+	}
+	if err := vault.UnlockWithFactors("Password123!", &UnlockFactors{TOTPCode: code}); !errors.Is(err, ErrVaultWrongTOTP) {
+		t.Errorf("啟用時使用過的驗證碼不應能解鎖: %v", err)
+	}
+	if vault.IsUnlocked() {
+		t.Fatal("驗證碼錯誤時不應解鎖")
+	}
+
+	next, _ := TOTPCode(secret, now.Add(TOTPPeriod*time.Second))
+	if err := vault.UnlockWithFactors("WrongPassword!", &UnlockFactors{TOTPCode: next}); !errors.Is(err, ErrVaultWrongPassword) {
+		t.Errorf("密碼錯誤時應該回傳 ErrVaultWrongPassword: %v", err)
+	}
+	if err := vault.UnlockWithFactors("Password123!", &UnlockFactors{TOTPCode: next}); err != nil {
+		t.Fatalf("以密碼和驗證碼解鎖失敗: %v", err)
+	}
+
+	// 變更密碼後 TOTP 設定保留
+	if err := vault.ChangePassword("Password123!", "NewPassword456!"); err != nil {
+		t.Fatalf("變更密碼失敗: %v", err)
+	}
+	if !vault.SecondFactors().TOTP {
+		t.Error("變更密碼後應該保留 TOTP 設定")
+	}
+
+	if err := vault.DisableTOTP("WrongPassword!"); err == nil {
+		t.Error("密碼錯誤時不應能停用 TOTP")
+	}
+	if err := vault.DisableTOTP("NewPassword456!"); err != nil {
+		t.Fatalf("停用 TOTP 失敗: %v", err)
+	}
+	vault.Lock()
+	if err := vault.Unlock("NewPassword456!"); err != nil {
+		t.Errorf("停用 TOTP 後應該只需要密碼: %v", err)
+	}
+}
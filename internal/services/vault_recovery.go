@@ -0,0 +1,390 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含保險庫的復原金鑰：以隨機復原金鑰另外包裝一份主金鑰，忘記密碼或唯一知道密碼的人離開時，
+// 可以用復原金鑰（或 N 取 K 的 Shamir 片段還原出的復原金鑰）設定新密碼，不需要逐一解密筆記
+package services
+
+import (
+	"crypto/rand"     // 安全隨機數產生器
+	"crypto/sha256"   // 校驗碼和金鑰識別
+	"crypto/subtle"   // 常數時間比較
+	"encoding/base32" // 復原金鑰以 Base32 文字顯示
+	"encoding/base64" // Base64 編碼
+	"errors"          // 錯誤處理
+	"fmt"             // 格式化輸出
+	"io"              // 輸入輸出介面
+	"strings"         // 字串處理
+	"time"            // 時間處理
+)
+
+// 復原金鑰相關常數
+const (
+	RecoveryKeyPrefix   = "NBRK"               // 復原金鑰文字的前綴
+	RecoverySharePrefix = "NBRS"               // 復原金鑰片段文字的前綴
+	recoveryKeySize     = 32                   // 復原金鑰的位元組數
+	recoveryChecksumLen = 2                    // 文字格式附加的校驗碼長度，用於發現輸入錯誤
+	recoveryKeyIDLen    = 2                    // 片段記錄的復原金鑰識別長度，避免混用不同組的片段
+	recoveryGroupSize   = 5                    // 顯示時每組的字元數
+	vaultRecoveryAAD    = "vault-recovery-key" // 以復原金鑰包裝主金鑰時使用的附加驗證資料
+)
+
+// VaultRecoveryRetryIdentifier 復原金鑰在重試狀態中的識別符
+const VaultRecoveryRetryIdentifier = "vault-recovery"
+
+// 復原金鑰錯誤定義
+var (
+	ErrRecoveryNotConfigured = errors.New("保險庫尚未設定復原金鑰")
+	ErrRecoveryKeyInvalid    = errors.New("復原金鑰錯誤或格式無效")
+)
+
+// recoveryEncoding 復原金鑰使用的 Base32 編碼（不含補位字元）
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// RecoveryKit 代表產生的復原金鑰和片段，只在產生時顯示一次
+type RecoveryKit struct {
+	RecoveryKey string    // 完整的復原金鑰
+	Shares      []string  // 拆分後的片段（未拆分時為空）
+	Threshold   int       // 還原需要的片段數
+	CreatedAt   time.Time // 產生時間
+}
+
+// VaultRecoveryStatus 代表復原金鑰的設定狀態
+type VaultRecoveryStatus struct {
+	Enabled   bool // 是否已設定復原金鑰
+	Shares    int  // 拆分的片段數（0 表示未拆分）
+	Threshold int  // 還原需要的片段數
+}
+
+// SetupRecovery 產生新的復原金鑰
+// 參數：password（保險庫密碼）、shares（片段數，0 表示不拆分）、threshold（還原需要的片段數）
+// 回傳：復原金鑰和片段和可能的錯誤
+//
+// 執行流程：
+// 1. 確認保險庫已解鎖，並以密碼解開主金鑰驗證密碼正確
+// 2. 產生隨機復原金鑰並以它包裝主金鑰，取代既有的復原金鑰
+// 3. 需要拆分時將復原金鑰拆成 N 取 K 的 Shamir 片段
+// 4. 儲存標頭並寫入稽核記錄
+func (v *vaultService) SetupRecovery(password string, shares, threshold int) (*RecoveryKit, error) {
+	if !v.IsUnlocked() {
+		return nil, ErrVaultLocked
+	}
+	if shares != 0 && (threshold < 2 || threshold > shares || shares > MaxShamirShares) {
+		return nil, fmt.Errorf("片段設定無效：需要 2 <= 門檻 (%d) <= 片段數 (%d) <= %d", threshold, shares, MaxShamirShares)
+	}
+
+	header, masterKey, digest, err := v.verifyVaultPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(masterKey)
+	zeroBytes(digest)
+
+	recoveryKey := make([]byte, recoveryKeySize)
+	if _, err := io.ReadFull(rand.Reader, recoveryKey); err != nil {
+		return nil, fmt.Errorf("產生復原金鑰失敗: %w", err)
+	}
+	defer zeroBytes(recoveryKey)
+
+	kit := &RecoveryKit{
+		RecoveryKey: formatRecoveryKey(recoveryKey),
+		CreatedAt:   time.Now(),
+	}
+	if shares != 0 {
+		parts, err := splitSecret(recoveryKey, shares, threshold)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range parts {
+			kit.Shares = append(kit.Shares, formatRecoveryShare(recoveryKey, part, threshold))
+			zeroBytes(part)
+		}
+		kit.Threshold = threshold
+	}
+
+	sealed, nonce, err := sealWithAlgorithm(AlgorithmAES256, recoveryKey, masterKey, []byte(vaultRecoveryAAD))
+	if err != nil {
+		return nil, fmt.Errorf("包裝主金鑰失敗: %w", err)
+	}
+	header.RecoveryWrappedKey = base64.StdEncoding.EncodeToString(append(nonce, sealed...))
+	header.RecoveryShares = shares
+	header.RecoveryThreshold = kit.Threshold
+	header.UpdatedAt = kit.CreatedAt
+
+	err = v.storeHeader(header)
+	recordAudit(v.audit, AuditEventRecoverySetup, VaultRetryIdentifier, err, describeRecoveryShares(shares, threshold))
+	if err != nil {
+		return nil, err
+	}
+	return kit, nil
+}
+
+// RecoveryStatus 取得復原金鑰的設定狀態
+// 回傳：復原金鑰狀態
+func (v *vaultService) RecoveryStatus() VaultRecoveryStatus {
+	header, err := v.loadHeader()
+	if err != nil || header.RecoveryWrappedKey == "" {
+		return VaultRecoveryStatus{}
+	}
+	return VaultRecoveryStatus{
+		Enabled:   true,
+		Shares:    header.RecoveryShares,
+		Threshold: header.RecoveryThreshold,
+	}
+}
+
+// RecoverWithKey 以復原金鑰設定新密碼
+// 參數：recoveryKey（復原金鑰）、newPassword（新密碼）
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 解析復原金鑰文字，校驗碼不符時視為輸入錯誤（不計入重試次數）
+// 2. 以復原金鑰解開主金鑰，錯誤時計入重試次數，次數過多時回傳 *LockoutError
+// 3. 以新密碼重新包裝主金鑰；持有金鑰檔或驗證器的人可能已經離開，因此一併移除金鑰檔和 TOTP
+// 4. 保留復原金鑰，儲存標頭後以主金鑰解鎖保險庫，並重設密碼的重試次數
+// 5. 寫入稽核記錄
+func (v *vaultService) RecoverWithKey(recoveryKey, newPassword string) error {
+	if newPassword == "" {
+		return errors.New("新密碼不能為空")
+	}
+	key, err := parseRecoveryKey(recoveryKey)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(key)
+
+	header, err := v.loadHeader()
+	if err != nil {
+		return err
+	}
+	if header.RecoveryWrappedKey == "" {
+		return ErrRecoveryNotConfigured
+	}
+
+	var masterKey []byte
+	err = guardPasswordAttempt(v.retryGuard, VaultRecoveryRetryIdentifier, func() error {
+		masterKey, err = unwrapRecoveryMasterKey(header, key)
+		return err
+	}, func(err error) bool {
+		return errors.Is(err, ErrRecoveryKeyInvalid)
+	})
+	if err == nil {
+		defer zeroBytes(masterKey)
+		err = v.resetPasswordWithMasterKey(header, masterKey, newPassword)
+	}
+	recordAudit(v.audit, AuditEventRecover, VaultRetryIdentifier, err, "")
+	return err
+}
+
+// resetPasswordWithMasterKey 以新密碼重新包裝主金鑰，移除第二驗證因素後解鎖保險庫
+// 參數：header（目前的標頭）、masterKey（主金鑰）、newPassword（新密碼）
+// 回傳：可能的錯誤
+func (v *vaultService) resetPasswordWithMasterKey(header *VaultHeader, masterKey []byte, newPassword string) error {
+	newHeader, err := rewrapVaultHeader(header, masterKey, newPassword, nil)
+	if err != nil {
+		return err
+	}
+	newHeader.TOTPSecret = ""
+	newHeader.TOTPLastStep = 0
+	if err := v.storeHeader(newHeader); err != nil {
+		return err
+	}
+
+	v.session.DeleteKey(vaultKeyfileKey)
+	v.session.StoreKey(vaultSessionKey, masterKey)
+	if v.retryGuard != nil {
+		v.retryGuard.ResetRetryCount(VaultRetryIdentifier)
+	}
+	return nil
+}
+
+// CombineRecoveryShares 從片段還原復原金鑰
+// 參數：shares（片段文字，數量需達到產生時的門檻）
+// 回傳：復原金鑰文字和可能的錯誤
+//
+// 執行流程：
+// 1. 解析每份片段並檢查校驗碼，略過重複的片段
+// 2. 確認片段屬於同一組復原金鑰且數量達到門檻
+// 3. 以拉格朗日插值還原復原金鑰，並以片段記錄的金鑰識別確認結果正確
+func CombineRecoveryShares(shares []string) (string, error) {
+	var (
+		parts     [][]byte
+		threshold int
+		keyID     []byte
+		seen      = make(map[string]bool)
+	)
+	defer func() {
+		for _, part := range parts {
+			zeroBytes(part)
+		}
+	}()
+
+	for i, text := range shares {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		partThreshold, partKeyID, part, err := parseRecoveryShare(text)
+		if err != nil {
+			return "", fmt.Errorf("第 %d 份片段: %w", i+1, err)
+		}
+		if keyID == nil {
+			threshold, keyID = partThreshold, partKeyID
+		} else if partThreshold != threshold || subtle.ConstantTimeCompare(partKeyID, keyID) != 1 {
+			return "", fmt.Errorf("第 %d 份片段: %w（片段不屬於同一組復原金鑰）", i+1, ErrRecoveryKeyInvalid)
+		}
+		if seen[string(part)] {
+			zeroBytes(part)
+			continue
+		}
+		seen[string(part)] = true
+		parts = append(parts, part)
+	}
+
+	if keyID == nil {
+		return "", errors.New("沒有輸入任何片段")
+	}
+	if len(parts) < threshold {
+		return "", fmt.Errorf("需要 %d 份不同的片段，目前只有 %d 份", threshold, len(parts))
+	}
+
+	key, err := combineShares(parts[:threshold])
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrRecoveryKeyInvalid, err)
+	}
+	defer zeroBytes(key)
+
+	if subtle.ConstantTimeCompare(recoveryKeyID(key), keyID) != 1 {
+		return "", ErrRecoveryKeyInvalid
+	}
+	return formatRecoveryKey(key), nil
+}
+
+// unwrapRecoveryMasterKey 以復原金鑰解開主金鑰
+// 參數：header（保險庫標頭）、recoveryKey（復原金鑰）
+// 回傳：主金鑰和可能的錯誤（復原金鑰錯誤時回傳 ErrRecoveryKeyInvalid）
+func unwrapRecoveryMasterKey(header *VaultHeader, recoveryKey []byte) ([]byte, error) {
+	stored, err := base64.StdEncoding.DecodeString(header.RecoveryWrappedKey)
+	if err != nil || len(stored) <= NonceSize {
+		return nil, errors.New("復原金鑰包裝格式無效")
+	}
+
+	masterKey, err := openWithAlgorithm(AlgorithmAES256, recoveryKey, stored[:NonceSize], stored[NonceSize:], []byte(vaultRecoveryAAD))
+	if err != nil {
+		return nil, ErrRecoveryKeyInvalid
+	}
+	return masterKey, nil
+}
+
+// describeRecoveryShares 產生稽核記錄使用的片段設定說明
+// 參數：shares（片段數）、threshold（門檻）
+// 回傳：說明文字
+func describeRecoveryShares(shares, threshold int) string {
+	if shares == 0 {
+		return "未拆分"
+	}
+	return fmt.Sprintf("%d 取 %d 片段", shares, threshold)
+}
+
+// recoveryChecksum 計算復原金鑰文字的校驗碼
+// 參數：data（要校驗的資料）
+// 回傳：校驗碼
+func recoveryChecksum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:recoveryChecksumLen]
+}
+
+// recoveryKeyID 計算片段記錄的復原金鑰識別
+// 參數：key（復原金鑰）
+// 回傳：金鑰識別
+func recoveryKeyID(key []byte) []byte {
+	sum := sha256.Sum256(append([]byte(vaultRecoveryAAD), key...))
+	return sum[:recoveryKeyIDLen]
+}
+
+// formatRecoveryKey 將復原金鑰格式化為分組的文字
+// 參數：key（復原金鑰）
+// 回傳：復原金鑰文字，例如 NBRK-XXXXX-XXXXX-...
+func formatRecoveryKey(key []byte) string {
+	return formatRecoveryText(RecoveryKeyPrefix, key)
+}
+
+// parseRecoveryKey 解析復原金鑰文字
+// 參數：text（復原金鑰文字，不分大小寫，可省略前綴和分隔符號）
+// 回傳：復原金鑰和可能的錯誤
+func parseRecoveryKey(text string) ([]byte, error) {
+	data, err := parseRecoveryText(RecoveryKeyPrefix, text)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != recoveryKeySize {
+		zeroBytes(data)
+		return nil, fmt.Errorf("%w: 長度不正確", ErrRecoveryKeyInvalid)
+	}
+	return data, nil
+}
+
+// formatRecoveryShare 將片段格式化為分組的文字
+// 參數：key（完整復原金鑰，用於計算金鑰識別）、part（片段）、threshold（門檻）
+// 回傳：片段文字，例如 NBRS-XXXXX-XXXXX-...
+//
+// 片段內容依序為門檻、復原金鑰識別和 Shamir 片段（最後一個位元組是片段編號）
+func formatRecoveryShare(key, part []byte, threshold int) string {
+	data := make([]byte, 0, 1+recoveryKeyIDLen+len(part))
+	data = append(data, byte(threshold))
+	data = append(data, recoveryKeyID(key)...)
+	data = append(data, part...)
+	defer zeroBytes(data)
+	return formatRecoveryText(RecoverySharePrefix, data)
+}
+
+// parseRecoveryShare 解析片段文字
+// 參數：text（片段文字）
+// 回傳：門檻、復原金鑰識別、Shamir 片段和可能的錯誤
+func parseRecoveryShare(text string) (int, []byte, []byte, error) {
+	data, err := parseRecoveryText(RecoverySharePrefix, text)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer zeroBytes(data)
+	if len(data) != 1+recoveryKeyIDLen+recoveryKeySize+1 || data[0] < 2 {
+		return 0, nil, nil, fmt.Errorf("%w: 片段格式不正確", ErrRecoveryKeyInvalid)
+	}
+
+	keyID := append([]byte(nil), data[1:1+recoveryKeyIDLen]...)
+	part := append([]byte(nil), data[1+recoveryKeyIDLen:]...)
+	return int(data[0]), keyID, part, nil
+}
+
+// formatRecoveryText 將資料加上校驗碼後以 Base32 分組顯示
+// 參數：prefix（前綴）、data（資料）
+// 回傳：分組的文字
+func formatRecoveryText(prefix string, data []byte) string {
+	encoded := recoveryEncoding.EncodeToString(append(append([]byte(nil), data...), recoveryChecksum(data)...))
+
+	groups := []string{prefix}
+	for len(encoded) > recoveryGroupSize {
+		groups = append(groups, encoded[:recoveryGroupSize])
+		encoded = encoded[recoveryGroupSize:]
+	}
+	groups = append(groups, encoded)
+	return strings.Join(groups, "-")
+}
+
+// parseRecoveryText 解析 formatRecoveryText 產生的文字並檢查校驗碼
+// 參數：prefix（預期的前綴）、text（輸入的文字）
+// 回傳：資料和可能的錯誤（格式或校驗碼錯誤時包裝 ErrRecoveryKeyInvalid）
+func parseRecoveryText(prefix, text string) ([]byte, error) {
+	normalized := strings.ToUpper(strings.Join(strings.Fields(text), ""))
+	normalized = strings.ReplaceAll(normalized, "-", "")
+	normalized = strings.TrimPrefix(normalized, prefix)
+
+	decoded, err := recoveryEncoding.DecodeString(normalized)
+	if err != nil || len(decoded) <= recoveryChecksumLen {
+		return nil, fmt.Errorf("%w: 無法解析，請確認是否完整輸入", ErrRecoveryKeyInvalid)
+	}
+
+	data := decoded[:len(decoded)-recoveryChecksumLen]
+	if subtle.ConstantTimeCompare(recoveryChecksum(data), decoded[len(data):]) != 1 {
+		zeroBytes(decoded)
+		return nil, fmt.Errorf("%w: 校驗碼不符，請確認是否輸入錯誤", ErrRecoveryKeyInvalid)
+	}
+	return data, nil
+}
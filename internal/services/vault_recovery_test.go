@@ -0,0 +1,171 @@
+// Package services 提供保險庫復原金鑰的單元測試
+// 測試 Shamir 片段的拆分與還原、復原金鑰的文字格式，以及以復原金鑰重設密碼
+package services
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestShamirSplitCombine 測試任意門檻數量的片段都能還原秘密，少於門檻則不能
+func TestShamirSplitCombine(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	shares, err := splitSecret(secret, 5, 3)
+	if err != nil {
+		t.Fatalf("拆分秘密失敗: %v", err)
+	}
+	if len(shares) != 5 {
+		t.Fatalf("應該產生 5 份片段，實際為 %d", len(shares))
+	}
+
+	for _, combination := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+		var subset [][]byte
+		for _, index := range combination {
+			subset = append(subset, shares[index])
+		}
+		combined, err := combineShares(subset)
+		if err != nil || !bytes.Equal(combined, secret) {
+			t.Errorf("片段 %v 應該還原出秘密: %x, %v", combination, combined, err)
+		}
+	}
+
+	if combined, _ := combineShares(shares[:2]); bytes.Equal(combined, secret) {
+		t.Error("少於門檻的片段不應還原出秘密")
+	}
+	if _, err := combineShares([][]byte{shares[0], shares[0]}); err == nil {
+		t.Error("重複的片段應該回傳錯誤")
+	}
+	if _, err := splitSecret(secret, 2, 3); err == nil {
+		t.Error("門檻大於片段數時應該回傳錯誤")
+	}
+}
+
+// TestRecoveryKeyFormat 測試復原金鑰文字可以容忍大小寫和空白，並以校驗碼發現輸入錯誤
+func TestRecoveryKeyFormat(t *testing.T) {
+	key := bytes.Repeat([]byte{0x5a}, recoveryKeySize)
+	text := formatRecoveryKey(key)
+	if !strings.HasPrefix(text, RecoveryKeyPrefix+"-") {
+		t.Errorf("復原金鑰應該以 %s 開頭: %s", RecoveryKeyPrefix, text)
+	}
+
+	parsed, err := parseRecoveryKey(" " + strings.ToLower(strings.ReplaceAll(text, "-", " ")) + "\n")
+	if err != nil || !bytes.Equal(parsed, key) {
+		t.Errorf("應該能解析小寫和空白分隔的復原金鑰: %x, %v", parsed, err)
+	}
+
+	// 修改一個字元後校驗碼不符
+	typo := []byte(text)
+	last := len(typo) - 3
+	if typo[last] == 'A' {
+		typo[last] = 'B'
+	} else {
+		typo[last] = 'A'
+	}
+	if _, err := parseRecoveryKey(string(typo)); !errors.Is(err, ErrRecoveryKeyInvalid) {
+		t.Errorf("輸入錯誤時應該回傳 ErrRecoveryKeyInvalid: %v", err)
+	}
+}
+
+// TestVaultRecoverWithKey 測試以復原金鑰重設密碼，並移除第二驗證因素
+func TestVaultRecoverWithKey(t *testing.T) {
+	vault, _ := createTestVaultService(t)
+	if err := vault.Initialize("Password123!"); err != nil {
+		t.Fatalf("初始化保險庫失敗: %v", err)
+	}
+	data, _, _ := vault.EncryptNote("需要復原的內容", AlgorithmAES256, "")
+
+	if err := vault.RecoverWithKey(formatRecoveryKey(make([]byte, recoveryKeySize)), "NewPassword456!"); !errors.Is(err, ErrRecoveryNotConfigured) {
+		t.Errorf("未設定復原金鑰時應該回傳 ErrRecoveryNotConfigured: %v", err)
+	}
+	if _, err := vault.SetupRecovery("WrongPassword!", 0, 0); !errors.Is(err, ErrVaultWrongPassword) {
+		t.Errorf("密碼錯誤時不應產生復原金鑰: %v", err)
+	}
+	kit, err := vault.SetupRecovery("Password123!", 0, 0)
+	if err != nil {
+		t.Fatalf("產生復原金鑰失敗: %v", err)
+	}
+	if status := vault.RecoveryStatus(); !status.Enabled || status.Shares != 0 {
+		t.Errorf("復原金鑰狀態不正確: %+v", status)
+	}
+
+	keyfile, _ := GenerateKeyfile()
+	if err := vault.EnableKeyfile("Password123!", keyfile); err != nil {
+		t.Fatalf("設定金鑰檔失敗: %v", err)
+	}
+	// 變更密碼後復原金鑰仍然有效
+	if err := vault.ChangePassword("Password123!", "Forgotten789!"); err != nil {
+		t.Fatalf("變更密碼失敗: %v", err)
+	}
+	vault.Lock()
+
+	if err := vault.RecoverWithKey(formatRecoveryKey(make([]byte, recoveryKeySize)), "NewPassword456!"); !errors.Is(err, ErrRecoveryKeyInvalid) {
+		t.Errorf("復原金鑰錯誤時應該回傳 ErrRecoveryKeyInvalid: %v", err)
+	}
+	if err := vault.RecoverWithKey(kit.RecoveryKey, "NewPassword456!"); err != nil {
+		t.Fatalf("以復原金鑰重設密碼失敗: %v", err)
+	}
+	if !vault.IsUnlocked() {
+		t.Error("重設密碼後保險庫應該已解鎖")
+	}
+	if factors := vault.SecondFactors(); factors.Keyfile || factors.TOTP {
+		t.Errorf("重設密碼後應該移除第二驗證因素: %+v", factors)
+	}
+
+	vault.Lock()
+	if err := vault.Unlock("NewPassword456!"); err != nil {
+		t.Fatalf("以新密碼解鎖失敗: %v", err)
+	}
+	if content, _, err := vault.DecryptNote(data); err != nil || content != "需要復原的內容" {
+		t.Errorf("重設密碼後應該能解密原有筆記: %q, %v", content, err)
+	}
+	if status := vault.RecoveryStatus(); !status.Enabled {
+		t.Error("重設密碼後應該保留復原金鑰")
+	}
+}
+
+// TestVaultRecoveryShares 測試以達到門檻的片段還原復原金鑰
+func TestVaultRecoveryShares(t *testing.T) {
+	vault, _ := createTestVaultService(t)
+	vault.Initialize("Password123!")
+
+	if _, err := vault.SetupRecovery("Password123!", 3, 4); err == nil {
+		t.Error("門檻大於片段數時應該回傳錯誤")
+	}
+	kit, err := vault.SetupRecovery("Password123!", 5, 3)
+	if err != nil {
+		t.Fatalf("產生復原金鑰片段失敗: %v", err)
+	}
+	if len(kit.Shares) != 5 || kit.Threshold != 3 {
+		t.Fatalf("片段設定不正確: %d 份，門檻 %d", len(kit.Shares), kit.Threshold)
+	}
+	if status := vault.RecoveryStatus(); status.Shares != 5 || status.Threshold != 3 {
+		t.Errorf("復原金鑰狀態不正確: %+v", status)
+	}
+
+	if _, err := CombineRecoveryShares([]string{kit.Shares[0], kit.Shares[3]}); err == nil {
+		t.Error("少於門檻的片段應該回傳錯誤")
+	}
+	if _, err := CombineRecoveryShares([]string{kit.Shares[0], kit.Shares[0], kit.Shares[3]}); err == nil {
+		t.Error("重複的片段不應計入門檻")
+	}
+
+	other, _ := vault.SetupRecovery("Password123!", 5, 3)
+	if _, err := CombineRecoveryShares([]string{kit.Shares[0], kit.Shares[1], other.Shares[2]}); !errors.Is(err, ErrRecoveryKeyInvalid) {
+		t.Errorf("混用不同組的片段應該回傳 ErrRecoveryKeyInvalid: %v", err)
+	}
+
+	recoveryKey, err := CombineRecoveryShares([]string{other.Shares[4], "", other.Shares[1], other.Shares[2]})
+	if err != nil || recoveryKey != other.RecoveryKey {
+		t.Fatalf("應該從片段還原復原金鑰: %v", err)
+	}
+
+	vault.Lock()
+	if err := vault.RecoverWithKey(kit.RecoveryKey, "NewPassword456!"); !errors.Is(err, ErrRecoveryKeyInvalid) {
+		t.Errorf("重新產生後舊的復原金鑰應該失效: %v", err)
+	}
+	if err := vault.RecoverWithKey(recoveryKey, "NewPassword456!"); err != nil {
+		t.Fatalf("以還原的復原金鑰重設密碼失敗: %v", err)
+	}
+}
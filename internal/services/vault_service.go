@@ -0,0 +1,913 @@
+// Package services 實作應用程式的業務邏輯服務
+// 本檔案包含保險庫（Vault）服務，提供以主金鑰為核心的信封加密金鑰階層：
+// 使用者密碼 → 金鑰加密金鑰（KEK）→ 主金鑰 → 每則筆記的資料金鑰
+package services
+
+import (
+	"bytes"           // 位元組處理
+	"crypto/rand"     // 安全隨機數產生器
+	"crypto/sha256"   // SHA-256 雜湊演算法
+	"crypto/subtle"   // 常數時間比較
+	"encoding/base64" // Base64 編碼
+	"encoding/json"   // JSON 序列化
+	"errors"          // 錯誤處理
+	"fmt"             // 格式化輸出
+	"io"              // 輸入輸出介面
+	"time"            // 時間處理
+
+	"github.com/google/uuid"                 // UUID 生成
+	"mac-notebook-app/internal/repositories" // 引入資料存取層
+)
+
+// 保險庫相關常數
+const (
+	VaultHeaderVersion = "1.0"              // 保險庫標頭格式版本
+	vaultMasterKeyAAD  = "vault-master-key" // 包裝主金鑰時使用的附加驗證資料
+	vaultDataKeyAAD    = "vault-data-key:"  // 包裝資料金鑰時使用的附加驗證資料前綴
+	vaultSessionKey    = "vault.master"     // 主金鑰在工作階段中的名稱
+	vaultKeyfileKey    = "vault.keyfile"    // 金鑰檔雜湊在工作階段中的名稱
+	vaultTOTPAAD       = "vault-totp"       // 加密 TOTP 秘密時使用的附加驗證資料
+)
+
+// VaultRetryIdentifier 保險庫密碼在重試狀態中的識別符
+const VaultRetryIdentifier = "vault"
+
+// 保險庫錯誤定義
+var (
+	ErrVaultLocked             = errors.New("保險庫已鎖定，需要密碼驗證才能存取加密筆記")
+	ErrVaultNotInitialized     = errors.New("保險庫尚未設定密碼")
+	ErrVaultAlreadyInitialized = errors.New("保險庫已經設定過密碼")
+	ErrVaultWrongPassword      = errors.New("保險庫密碼錯誤")
+)
+
+// VaultHeader 代表保險庫標頭
+// 保存 KDF 參數和以 KEK 包裝後的主金鑰，本身不含任何明文金鑰
+// 早期的標頭只有 PBKDF2 的 kdf 和 rounds 欄位，Argon2id 另外記錄記憶體用量和平行度
+// 第二驗證因素記錄在標頭中，整個保險庫共用同一組設定
+// 復原金鑰另外包裝一份主金鑰，與密碼無關，變更密碼後仍然有效
+type VaultHeader struct {
+	Version            string    `json:"version"`                        // 標頭格式版本
+	KDF                string    `json:"kdf"`                            // 金鑰衍生函數名稱
+	Rounds             int       `json:"rounds"`                         // KDF 迭代次數
+	Memory             uint32    `json:"memory,omitempty"`               // 記憶體用量（KiB，僅 Argon2id 使用）
+	Parallelism        uint8     `json:"parallelism,omitempty"`          // 平行度（僅 Argon2id 使用）
+	Salt               string    `json:"salt"`                           // Base64 編碼的鹽值
+	Algorithm          string    `json:"algorithm"`                      // 包裝主金鑰使用的演算法
+	MasterKeyNonce     string    `json:"master_key_nonce"`               // Base64 編碼的主金鑰包裝隨機數
+	WrappedMasterKey   string    `json:"wrapped_master_key"`             // Base64 編碼的包裝後主金鑰
+	Keyfile            bool      `json:"keyfile,omitempty"`              // 是否需要金鑰檔（金鑰檔雜湊和密碼一起衍生 KEK）
+	TOTPSecret         string    `json:"totp_secret,omitempty"`          // Base64 編碼、以主金鑰加密的 TOTP 秘密
+	TOTPLastStep       int64     `json:"totp_last_step,omitempty"`       // 最後一次使用的 TOTP 時間步，避免驗證碼被重複使用
+	RecoveryWrappedKey string    `json:"recovery_wrapped_key,omitempty"` // Base64 編碼、以復原金鑰包裝的主金鑰（含隨機數）
+	RecoveryShares     int       `json:"recovery_shares,omitempty"`      // 復原金鑰拆分的片段數（0 表示未拆分）
+	RecoveryThreshold  int       `json:"recovery_threshold,omitempty"`   // 還原復原金鑰需要的片段數
+	CreatedAt          time.Time `json:"created_at"`                     // 建立時間
+	UpdatedAt          time.Time `json:"updated_at"`                     // 最後更新時間（變更密碼時更新）
+}
+
+// kdfParams 取得標頭記錄的金鑰衍生參數
+// 回傳：KDFParams 實例
+func (h *VaultHeader) kdfParams() KDFParams {
+	rounds := h.Rounds
+	if rounds < 0 {
+		rounds = 0
+	}
+	return KDFParams{
+		Name:        h.KDF,
+		Memory:      h.Memory,
+		Iterations:  uint32(rounds),
+		Parallelism: h.Parallelism,
+	}
+}
+
+// VaultNoteInfo 代表保險庫加密筆記的金鑰資訊
+type VaultNoteInfo struct {
+	KeyID     string        // 資料金鑰識別碼
+	Algorithm string        // 內容加密演算法
+	Metadata  *NoteMetadata // 中繼資料標頭（沒有時為 nil）
+}
+
+// VaultService 定義保險庫服務的介面
+// 負責主金鑰的建立、解鎖、鎖定，以及使用資料金鑰加解密筆記內容
+type VaultService interface {
+	// IsInitialized 檢查保險庫是否已設定密碼
+	// 回傳：是否已初始化
+	IsInitialized() bool
+
+	// Initialize 以指定密碼建立保險庫並產生主金鑰，完成後保險庫為解鎖狀態
+	// 參數：password（保險庫密碼）
+	// 回傳：可能的錯誤
+	Initialize(password string) error
+
+	// Unlock 以密碼解鎖保險庫，將主金鑰保留在記憶體中
+	// 標頭使用較弱的 KDF 參數時，會以目前的預設參數重新包裝主金鑰
+	// 參數：password（保險庫密碼）
+	// 回傳：可能的錯誤（密碼錯誤時回傳 ErrVaultWrongPassword，需要第二驗證因素時回傳 ErrVaultKeyfileRequired 或 ErrVaultTOTPRequired）
+	Unlock(password string) error
+
+	// UnlockWithFactors 以密碼和第二驗證因素解鎖保險庫
+	// 參數：password（保險庫密碼）、factors（金鑰檔內容和驗證碼，可為 nil）
+	// 回傳：可能的錯誤（驗證碼錯誤時回傳 ErrVaultWrongTOTP）
+	UnlockWithFactors(password string, factors *UnlockFactors) error
+
+	// SecondFactors 取得保險庫啟用的第二驗證因素
+	// 回傳：第二驗證因素設定（尚未初始化時為零值）
+	SecondFactors() VaultFactors
+
+	// EnableKeyfile 設定（或更換）解鎖時需要的金鑰檔，需要保險庫已解鎖
+	// 參數：password（保險庫密碼）、keyfile（金鑰檔內容）
+	// 回傳：可能的錯誤
+	EnableKeyfile(password string, keyfile []byte) error
+
+	// DisableKeyfile 移除金鑰檔，之後只需要密碼即可解鎖，需要保險庫已解鎖
+	// 參數：password（保險庫密碼）
+	// 回傳：可能的錯誤
+	DisableKeyfile(password string) error
+
+	// EnableTOTP 啟用 TOTP 驗證碼，以目前的驗證碼確認驗證器已正確設定，需要保險庫已解鎖
+	// 參數：secret（Base32 編碼的 TOTP 秘密）、code（驗證器顯示的驗證碼）
+	// 回傳：可能的錯誤
+	EnableTOTP(secret, code string) error
+
+	// DisableTOTP 停用 TOTP 驗證碼，需要保險庫已解鎖
+	// 參數：password（保險庫密碼）
+	// 回傳：可能的錯誤
+	DisableTOTP(password string) error
+
+	// Lock 鎖定保險庫並清除記憶體中的主金鑰
+	Lock()
+
+	// IsUnlocked 檢查保險庫是否已解鎖
+	// 回傳：是否已解鎖
+	IsUnlocked() bool
+
+	// ChangePassword 變更保險庫密碼，只重新包裝主金鑰，不需重新加密任何筆記
+	// 參數：oldPassword（舊密碼）、newPassword（新密碼）
+	// 回傳：可能的錯誤
+	ChangePassword(oldPassword, newPassword string) error
+
+	// PrepareChangePassword 以新密碼重新包裝主金鑰並回傳新標頭，但不儲存
+	// 供需要先完成其他步驟再切換密碼的作業（例如批次重新加密）使用
+	// 參數：oldPassword（舊密碼）、newPassword（新密碼）
+	// 回傳：序列化後的新標頭和可能的錯誤
+	PrepareChangePassword(oldPassword, newPassword string) ([]byte, error)
+
+	// CommitHeader 儲存由 PrepareChangePassword 產生的標頭
+	// 參數：header（序列化後的標頭）
+	// 回傳：可能的錯誤
+	CommitHeader(header []byte) error
+
+	// IsVaultData 檢查資料是否為保險庫信封格式
+	// 參數：data（檔案內容）
+	// 回傳：是否為信封格式
+	IsVaultData(data []byte) bool
+
+	// EncryptNote 使用資料金鑰加密筆記內容
+	// 參數：content（明文內容）、algorithm（加密演算法）、keyID（既有的資料金鑰 ID，空字串表示建立新金鑰）
+	// 回傳：加密後的資料、使用的資料金鑰 ID 和可能的錯誤
+	EncryptNote(content, algorithm, keyID string) ([]byte, string, error)
+
+	// EncryptNoteWithMetadata 使用資料金鑰加密筆記內容和中繼資料標頭
+	// 中繼資料以同一把資料金鑰加密，並作為內容的附加驗證資料
+	// 參數：content（明文內容）、algorithm（加密演算法）、keyID（既有的資料金鑰 ID）、meta（中繼資料，可為 nil）
+	// 回傳：加密後的資料、使用的資料金鑰 ID 和可能的錯誤
+	EncryptNoteWithMetadata(content, algorithm, keyID string, meta *NoteMetadata) ([]byte, string, error)
+
+	// DecryptNote 解密信封格式的筆記內容（包含串流分段格式）
+	// 參數：data（加密資料）
+	// 回傳：明文內容、金鑰資訊和可能的錯誤
+	DecryptNote(data []byte) (string, *VaultNoteInfo, error)
+
+	// ReadNoteMetadata 只解密筆記的中繼資料標頭，不解密內容
+	// 參數：data（加密資料，串流格式只需包含標頭）
+	// 回傳：中繼資料（沒有時為 nil）和可能的錯誤
+	ReadNoteMetadata(data []byte) (*NoteMetadata, error)
+
+	// NewNoteEncryptWriter 建立使用資料金鑰的串流加密寫入器，用於大型筆記的分段加密
+	// 參數：dst（加密資料目的地）、algorithm（加密演算法）、keyID（既有的資料金鑰 ID，空字串表示建立新金鑰）、meta（中繼資料，可為 nil）
+	// 回傳：加密寫入器（必須呼叫 Close 寫入最後一段）、使用的資料金鑰 ID 和可能的錯誤
+	NewNoteEncryptWriter(dst io.Writer, algorithm, keyID string, meta *NoteMetadata) (io.WriteCloser, string, error)
+
+	// NewNoteDecryptReader 建立使用資料金鑰的串流解密讀取器
+	// 參數：src（串流格式的加密資料來源）
+	// 回傳：明文讀取器、金鑰資訊和可能的錯誤
+	NewNoteDecryptReader(src io.Reader) (io.Reader, *VaultNoteInfo, error)
+
+	// DeleteNoteKey 刪除指定的資料金鑰（筆記取消加密時使用）
+	// 參數：keyID（資料金鑰 ID）
+	// 回傳：可能的錯誤
+	DeleteNoteKey(keyID string) error
+
+	// Session 取得保存主金鑰的工作階段管理器
+	// 回傳：SessionManager 介面實例
+	Session() SessionManager
+
+	// SetRetryGuard 設定限制密碼重試次數的密碼服務
+	// 參數：passwordSvc（密碼服務，nil 表示不限制）
+	SetRetryGuard(passwordSvc PasswordService)
+
+	// SetAuditService 設定記錄解鎖事件的稽核記錄服務
+	// 參數：audit（稽核記錄服務，nil 表示不記錄）
+	SetAuditService(audit AuditService)
+
+	// SetupRecovery 產生新的復原金鑰（取代既有的復原金鑰），可選擇拆成 N 取 K 的片段，需要保險庫已解鎖
+	// 參數：password（保險庫密碼）、shares（片段數，0 表示不拆分）、threshold（還原需要的片段數）
+	// 回傳：復原金鑰和片段（只會顯示這一次）和可能的錯誤
+	SetupRecovery(password string, shares, threshold int) (*RecoveryKit, error)
+
+	// RecoveryStatus 取得復原金鑰的設定狀態
+	// 回傳：復原金鑰狀態（尚未初始化時為零值）
+	RecoveryStatus() VaultRecoveryStatus
+
+	// RecoverWithKey 以復原金鑰解開主金鑰並設定新密碼，同時移除金鑰檔和 TOTP 驗證碼
+	// 參數：recoveryKey（復原金鑰，可由 CombineRecoveryShares 從片段還原）、newPassword（新密碼）
+	// 回傳：可能的錯誤
+	RecoverWithKey(recoveryKey, newPassword string) error
+}
+
+// vaultService 實作 VaultService 介面
+// 解鎖後的主金鑰保存在工作階段管理器中，工作階段鎖定時即被清零
+type vaultService struct {
+	repo       repositories.EncryptionRepository // 金鑰儲存庫
+	session    SessionManager                    // 工作階段管理器
+	retryGuard PasswordService                   // 限制密碼重試次數（nil 表示不限制）
+	audit      AuditService                      // 稽核記錄服務（nil 表示不記錄）
+}
+
+// NewVaultService 建立新的保險庫服務實例
+// 參數：
+//   - repo: 金鑰儲存庫，保存保險庫標頭和包裝後的資料金鑰
+//   - session: 工作階段管理器（可選，nil 時建立不會自動鎖定的工作階段）
+//
+// 回傳：VaultService 介面實例，初始為鎖定狀態
+func NewVaultService(repo repositories.EncryptionRepository, session SessionManager) VaultService {
+	if session == nil {
+		session = NewSessionManager(0)
+	}
+
+	return &vaultService{
+		repo:    repo,
+		session: session,
+	}
+}
+
+// IsInitialized 檢查保險庫是否已設定密碼
+// 回傳：是否已初始化
+func (v *vaultService) IsInitialized() bool {
+	_, err := v.loadHeader()
+	return err == nil
+}
+
+// Initialize 以指定密碼建立保險庫並產生主金鑰
+// 參數：password（保險庫密碼）
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 確認保險庫尚未初始化
+// 2. 產生隨機主金鑰
+// 3. 以密碼衍生的 KEK 包裝主金鑰並儲存標頭
+// 4. 將主金鑰保留在記憶體中（解鎖狀態）
+func (v *vaultService) Initialize(password string) error {
+	if password == "" {
+		return errors.New("密碼不能為空")
+	}
+	if v.IsInitialized() {
+		return ErrVaultAlreadyInitialized
+	}
+
+	masterKey := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, masterKey); err != nil {
+		return fmt.Errorf("產生主金鑰失敗: %w", err)
+	}
+
+	now := time.Now()
+	header, err := wrapMasterKey(masterKey, password, DefaultKDFParams())
+	if err != nil {
+		return err
+	}
+	header.CreatedAt = now
+	header.UpdatedAt = now
+
+	if err := v.storeHeader(header); err != nil {
+		return err
+	}
+
+	v.session.StoreKey(vaultSessionKey, masterKey)
+	zeroBytes(masterKey)
+
+	return nil
+}
+
+// Unlock 以密碼解鎖保險庫
+// 參數：password（保險庫密碼）
+// 回傳：可能的錯誤
+//
+// 保險庫啟用第二驗證因素時，只有在工作階段已經持有金鑰檔雜湊和主金鑰（例如重新驗證密碼）時才能成功
+func (v *vaultService) Unlock(password string) error {
+	return v.UnlockWithFactors(password, nil)
+}
+
+// Lock 鎖定保險庫，透過工作階段清零記憶體中的所有金鑰
+func (v *vaultService) Lock() {
+	v.session.Lock()
+}
+
+// IsUnlocked 檢查保險庫是否已解鎖
+// 回傳：是否已解鎖
+func (v *vaultService) IsUnlocked() bool {
+	return v.session.HasKey(vaultSessionKey)
+}
+
+// ChangePassword 變更保險庫密碼
+// 參數：oldPassword（舊密碼）、newPassword（新密碼）
+// 回傳：可能的錯誤
+//
+// 執行流程：
+// 1. 以舊密碼和解鎖時保存的金鑰檔雜湊解開主金鑰，驗證舊密碼正確
+// 2. 使用新鹽值和新密碼重新包裝主金鑰
+// 3. 儲存新的標頭（資料金鑰和筆記檔案都不需要變更）
+func (v *vaultService) ChangePassword(oldPassword, newPassword string) error {
+	if newPassword == "" {
+		return errors.New("新密碼不能為空")
+	}
+
+	header, err := v.loadHeader()
+	if err != nil {
+		return err
+	}
+
+	keyfileDigest, err := v.resolveKeyfileDigest(header, nil)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(keyfileDigest)
+
+	masterKey, err := unwrapVaultMasterKey(header, oldPassword, keyfileDigest)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(masterKey)
+
+	return v.rewrapMasterKey(header, masterKey, newPassword, keyfileDigest)
+}
+
+// PrepareChangePassword 以新密碼重新包裝主金鑰並回傳新標頭
+// 參數：oldPassword（舊密碼）、newPassword（新密碼）
+// 回傳：序列化後的新標頭和可能的錯誤
+//
+// 執行流程：
+// 1. 以舊密碼和解鎖時保存的金鑰檔雜湊解開主金鑰，驗證舊密碼正確
+// 2. 使用新鹽值、新密碼和目前的預設 KDF 參數重新包裝主金鑰
+// 3. 回傳序列化後的標頭，由呼叫者決定何時以 CommitHeader 儲存
+func (v *vaultService) PrepareChangePassword(oldPassword, newPassword string) ([]byte, error) {
+	if newPassword == "" {
+		return nil, errors.New("新密碼不能為空")
+	}
+
+	header, err := v.loadHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	keyfileDigest, err := v.resolveKeyfileDigest(header, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(keyfileDigest)
+
+	masterKey, err := unwrapVaultMasterKey(header, oldPassword, keyfileDigest)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(masterKey)
+
+	newHeader, err := rewrapVaultHeader(header, masterKey, newPassword, keyfileDigest)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(newHeader)
+	if err != nil {
+		return nil, fmt.Errorf("序列化保險庫標頭失敗: %w", err)
+	}
+	return data, nil
+}
+
+// CommitHeader 儲存先前準備好的標頭
+// 參數：header（序列化後的標頭）
+// 回傳：可能的錯誤
+func (v *vaultService) CommitHeader(header []byte) error {
+	var parsed VaultHeader
+	if err := json.Unmarshal(header, &parsed); err != nil {
+		return fmt.Errorf("解析保險庫標頭失敗: %w", err)
+	}
+	if parsed.Version != VaultHeaderVersion {
+		return fmt.Errorf("不支援的保險庫標頭版本: %s", parsed.Version)
+	}
+	if err := parsed.kdfParams().Validate(); err != nil {
+		return err
+	}
+
+	return v.repo.StoreVaultHeader(header)
+}
+
+// rewrapMasterKey 以目前的預設 KDF 參數重新包裝主金鑰並儲存標頭
+// 參數：header（目前的標頭）、masterKey（主金鑰）、password（保險庫密碼）、keyfileDigest（金鑰檔雜湊，nil 表示不使用金鑰檔）
+// 回傳：可能的錯誤
+func (v *vaultService) rewrapMasterKey(header *VaultHeader, masterKey []byte, password string, keyfileDigest []byte) error {
+	newHeader, err := rewrapVaultHeader(header, masterKey, password, keyfileDigest)
+	if err != nil {
+		return err
+	}
+
+	return v.storeHeader(newHeader)
+}
+
+// IsVaultData 檢查資料是否為保險庫信封格式
+// 參數：data（檔案內容）
+// 回傳：是否為信封格式
+func (v *vaultService) IsVaultData(data []byte) bool {
+	if header := peekStreamHeader(data); header != nil {
+		return header.KeyMode == streamKeyModeVault
+	}
+
+	var encData EncryptedData
+	if err := json.Unmarshal(data, &encData); err != nil {
+		return false
+	}
+	return encData.Version == EncryptedDataVersionVault && encData.KeyID != ""
+}
+
+// EncryptNote 使用資料金鑰加密筆記內容
+// 參數：content（明文內容）、algorithm（加密演算法）、keyID（既有的資料金鑰 ID）
+// 回傳：加密後的資料、使用的資料金鑰 ID 和可能的錯誤
+func (v *vaultService) EncryptNote(content, algorithm, keyID string) ([]byte, string, error) {
+	return v.EncryptNoteWithMetadata(content, algorithm, keyID, nil)
+}
+
+// EncryptNoteWithMetadata 使用資料金鑰加密筆記內容和中繼資料標頭
+// 參數：content（明文內容）、algorithm（加密演算法）、keyID（既有的資料金鑰 ID）、meta（中繼資料，可為 nil）
+// 回傳：加密後的資料、使用的資料金鑰 ID 和可能的錯誤
+//
+// 執行流程：
+// 1. 確認保險庫已解鎖且演算法有效
+// 2. 取得既有的資料金鑰，或產生並包裝新的資料金鑰
+// 3. 以資料金鑰加密中繼資料標頭
+// 4. 以資料金鑰加密內容，並以金鑰 ID 和加密後的中繼資料作為附加驗證資料
+// 5. 建立信封格式的加密資料結構並序列化
+func (v *vaultService) EncryptNoteWithMetadata(content, algorithm, keyID string, meta *NoteMetadata) ([]byte, string, error) {
+	if algorithm != AlgorithmAES256 && algorithm != AlgorithmChaCha20 {
+		return nil, "", fmt.Errorf("不支援的加密演算法: %s", algorithm)
+	}
+
+	keyID, dataKey, err := v.noteDataKey(keyID)
+	if err != nil {
+		return nil, "", err
+	}
+	defer zeroBytes(dataKey)
+
+	sealedMeta, err := sealNoteMetadata(algorithm, dataKey, keyID, meta)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ciphertext, nonce, err := sealWithAlgorithm(algorithm, dataKey, []byte(content), noteContentAAD(keyID, sealedMeta))
+	if err != nil {
+		return nil, "", fmt.Errorf("加密失敗: %w", err)
+	}
+
+	checksum := sha256.Sum256(ciphertext)
+	encData := EncryptedData{
+		Version:   EncryptedDataVersionVault,
+		Algorithm: algorithm,
+		Nonce:     base64.StdEncoding.EncodeToString(nonce),
+		Data:      base64.StdEncoding.EncodeToString(ciphertext),
+		Checksum:  base64.StdEncoding.EncodeToString(checksum[:]),
+		KeyID:     keyID,
+	}
+	if len(sealedMeta) > 0 {
+		encData.Metadata = base64.StdEncoding.EncodeToString(sealedMeta)
+	}
+
+	data, err := json.Marshal(encData)
+	if err != nil {
+		return nil, "", fmt.Errorf("序列化加密資料失敗: %w", err)
+	}
+
+	return data, keyID, nil
+}
+
+// DecryptNote 解密信封格式的筆記內容
+// 參數：data（加密資料）
+// 回傳：明文內容、金鑰資訊和可能的錯誤
+//
+// 執行流程：
+// 1. 解析信封格式並驗證版本
+// 2. 確認保險庫已解鎖
+// 3. 以主金鑰解開資料金鑰
+// 4. 驗證校驗和後以資料金鑰解密內容（中繼資料一併作為附加驗證資料）
+// 5. 解密中繼資料標頭
+func (v *vaultService) DecryptNote(data []byte) (string, *VaultNoteInfo, error) {
+	// 大型筆記使用串流分段格式
+	if IsStreamData(data) {
+		reader, info, err := v.NewNoteDecryptReader(bytes.NewReader(data))
+		if err != nil {
+			return "", nil, err
+		}
+		var plaintext bytes.Buffer
+		plaintext.Grow(len(data))
+		if _, err := io.Copy(&plaintext, reader); err != nil {
+			return "", nil, fmt.Errorf("解密失敗: %w", err)
+		}
+		return plaintext.String(), info, nil
+	}
+
+	var encData EncryptedData
+	if err := json.Unmarshal(data, &encData); err != nil {
+		return "", nil, fmt.Errorf("解析加密資料失敗: %w", err)
+	}
+	if encData.Version != EncryptedDataVersionVault || encData.KeyID == "" {
+		return "", nil, fmt.Errorf("不支援的加密格式版本: %s", encData.Version)
+	}
+
+	masterKey, err := v.copyMasterKey()
+	if err != nil {
+		return "", nil, err
+	}
+	defer zeroBytes(masterKey)
+
+	nonce, err := base64.StdEncoding.DecodeString(encData.Nonce)
+	if err != nil {
+		return "", nil, fmt.Errorf("解碼隨機數失敗: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encData.Data)
+	if err != nil {
+		return "", nil, fmt.Errorf("解碼加密內容失敗: %w", err)
+	}
+	expectedChecksum, err := base64.StdEncoding.DecodeString(encData.Checksum)
+	if err != nil {
+		return "", nil, fmt.Errorf("解碼校驗和失敗: %w", err)
+	}
+	sealedMeta, err := base64.StdEncoding.DecodeString(encData.Metadata)
+	if err != nil {
+		return "", nil, fmt.Errorf("解碼中繼資料失敗: %w", err)
+	}
+
+	actualChecksum := sha256.Sum256(ciphertext)
+	if subtle.ConstantTimeCompare(expectedChecksum, actualChecksum[:]) != 1 {
+		return "", nil, errors.New("資料校驗和不匹配，可能已被篡改")
+	}
+
+	dataKey, err := v.unwrapDataKey(masterKey, encData.KeyID)
+	if err != nil {
+		return "", nil, err
+	}
+	defer zeroBytes(dataKey)
+
+	plaintext, err := openWithAlgorithm(encData.Algorithm, dataKey, nonce, ciphertext, noteContentAAD(encData.KeyID, sealedMeta))
+	if err != nil {
+		return "", nil, fmt.Errorf("解密失敗: %w", err)
+	}
+
+	meta, err := openNoteMetadata(encData.Algorithm, dataKey, encData.KeyID, sealedMeta)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return string(plaintext), &VaultNoteInfo{
+		KeyID:     encData.KeyID,
+		Algorithm: encData.Algorithm,
+		Metadata:  meta,
+	}, nil
+}
+
+// ReadNoteMetadata 只解密筆記的中繼資料標頭
+// 參數：data（加密資料，串流格式只需包含標頭）
+// 回傳：中繼資料（沒有時為 nil）和可能的錯誤
+//
+// 執行流程：
+// 1. 從串流標頭或信封格式取得演算法、資料金鑰 ID 和加密後的中繼資料
+// 2. 沒有中繼資料時直接回傳 nil
+// 3. 以主金鑰解開資料金鑰後解密中繼資料
+func (v *vaultService) ReadNoteMetadata(data []byte) (*NoteMetadata, error) {
+	var algorithm, keyID string
+	var sealedMeta []byte
+
+	if IsStreamData(data) {
+		header, err := readStreamHeader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if header.KeyMode != streamKeyModeVault {
+			return nil, errors.New("此檔案使用密碼加密，不是保險庫格式")
+		}
+		algorithm, keyID, sealedMeta = header.Algorithm, header.KeyID, header.Metadata
+	} else {
+		var encData EncryptedData
+		if err := json.Unmarshal(data, &encData); err != nil {
+			return nil, fmt.Errorf("解析加密資料失敗: %w", err)
+		}
+		if encData.Version != EncryptedDataVersionVault || encData.KeyID == "" {
+			return nil, fmt.Errorf("不支援的加密格式版本: %s", encData.Version)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(encData.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("解碼中繼資料失敗: %w", err)
+		}
+		algorithm, keyID, sealedMeta = encData.Algorithm, encData.KeyID, decoded
+	}
+
+	if len(sealedMeta) == 0 {
+		return nil, nil
+	}
+
+	masterKey, err := v.copyMasterKey()
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(masterKey)
+
+	dataKey, err := v.unwrapDataKey(masterKey, keyID)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(dataKey)
+
+	return openNoteMetadata(algorithm, dataKey, keyID, sealedMeta)
+}
+
+// NewNoteEncryptWriter 建立使用資料金鑰的串流加密寫入器
+// 中繼資料保存在串流標頭中，標頭是每個分段的附加驗證資料
+// 參數：dst（加密資料目的地）、algorithm（加密演算法）、keyID（既有的資料金鑰 ID，空字串表示建立新金鑰）、meta（中繼資料，可為 nil）
+// 回傳：加密寫入器、使用的資料金鑰 ID 和可能的錯誤
+func (v *vaultService) NewNoteEncryptWriter(dst io.Writer, algorithm, keyID string, meta *NoteMetadata) (io.WriteCloser, string, error) {
+	header, err := newStreamHeader(algorithm, streamKeyModeVault)
+	if err != nil {
+		return nil, "", err
+	}
+
+	keyID, dataKey, err := v.noteDataKey(keyID)
+	if err != nil {
+		return nil, "", err
+	}
+	defer zeroBytes(dataKey)
+
+	header.KeyID = keyID
+	header.Metadata, err = sealNoteMetadata(algorithm, dataKey, keyID, meta)
+	if err != nil {
+		return nil, "", err
+	}
+	writer, err := newStreamWriter(dst, header, dataKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return writer, keyID, nil
+}
+
+// NewNoteDecryptReader 建立使用資料金鑰的串流解密讀取器
+// 參數：src（串流格式的加密資料來源）
+// 回傳：明文讀取器、金鑰資訊和可能的錯誤
+func (v *vaultService) NewNoteDecryptReader(src io.Reader) (io.Reader, *VaultNoteInfo, error) {
+	header, err := readStreamHeader(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	if header.KeyMode != streamKeyModeVault {
+		return nil, nil, errors.New("此檔案使用密碼加密，不是保險庫格式")
+	}
+
+	masterKey, err := v.copyMasterKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer zeroBytes(masterKey)
+
+	dataKey, err := v.unwrapDataKey(masterKey, header.KeyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer zeroBytes(dataKey)
+
+	meta, err := openNoteMetadata(header.Algorithm, dataKey, header.KeyID, header.Metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader, err := newStreamReader(src, header, dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return reader, &VaultNoteInfo{KeyID: header.KeyID, Algorithm: header.Algorithm, Metadata: meta}, nil
+}
+
+// DeleteNoteKey 刪除指定的資料金鑰
+// 參數：keyID（資料金鑰 ID）
+// 回傳：可能的錯誤
+func (v *vaultService) DeleteNoteKey(keyID string) error {
+	if keyID == "" {
+		return nil
+	}
+	return v.repo.DeleteWrappedKey(keyID)
+}
+
+// Session 取得保存主金鑰的工作階段管理器
+// 回傳：SessionManager 介面實例
+func (v *vaultService) Session() SessionManager {
+	return v.session
+}
+
+// SetRetryGuard 設定限制密碼重試次數的密碼服務
+// 參數：passwordSvc（密碼服務，nil 表示不限制）
+func (v *vaultService) SetRetryGuard(passwordSvc PasswordService) {
+	v.retryGuard = passwordSvc
+}
+
+// SetAuditService 設定記錄解鎖事件的稽核記錄服務
+// 參數：audit（稽核記錄服務，nil 表示不記錄）
+func (v *vaultService) SetAuditService(audit AuditService) {
+	v.audit = audit
+}
+
+// copyMasterKey 取得主金鑰的副本，避免呼叫期間被 Lock 清除
+// 回傳：主金鑰副本和可能的錯誤（鎖定時回傳 ErrVaultLocked）
+func (v *vaultService) copyMasterKey() ([]byte, error) {
+	masterKey, ok := v.session.GetKey(vaultSessionKey)
+	if !ok {
+		return nil, ErrVaultLocked
+	}
+	return masterKey, nil
+}
+
+// noteDataKey 取得筆記使用的資料金鑰
+// 參數：keyID（既有的資料金鑰 ID，空字串表示建立新金鑰）
+// 回傳：資料金鑰 ID、資料金鑰（呼叫者負責清零）和可能的錯誤
+func (v *vaultService) noteDataKey(keyID string) (string, []byte, error) {
+	masterKey, err := v.copyMasterKey()
+	if err != nil {
+		return "", nil, err
+	}
+	defer zeroBytes(masterKey)
+
+	if keyID == "" {
+		return v.createDataKey(masterKey)
+	}
+
+	dataKey, err := v.unwrapDataKey(masterKey, keyID)
+	if err != nil {
+		return "", nil, err
+	}
+	return keyID, dataKey, nil
+}
+
+// createDataKey 產生新的資料金鑰，以主金鑰包裝後儲存
+// 參數：masterKey（主金鑰）
+// 回傳：新的金鑰 ID、資料金鑰和可能的錯誤
+func (v *vaultService) createDataKey(masterKey []byte) (string, []byte, error) {
+	keyID := uuid.New().String()
+
+	dataKey := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", nil, fmt.Errorf("產生資料金鑰失敗: %w", err)
+	}
+
+	wrapped, nonce, err := sealWithAlgorithm(AlgorithmAES256, masterKey, dataKey, []byte(vaultDataKeyAAD+keyID))
+	if err != nil {
+		return "", nil, fmt.Errorf("包裝資料金鑰失敗: %w", err)
+	}
+
+	if err := v.repo.StoreWrappedKey(keyID, append(nonce, wrapped...)); err != nil {
+		return "", nil, fmt.Errorf("儲存資料金鑰失敗: %w", err)
+	}
+
+	return keyID, dataKey, nil
+}
+
+// unwrapDataKey 以主金鑰解開指定的資料金鑰
+// 參數：masterKey（主金鑰）、keyID（資料金鑰 ID）
+// 回傳：資料金鑰和可能的錯誤
+func (v *vaultService) unwrapDataKey(masterKey []byte, keyID string) ([]byte, error) {
+	stored, err := v.repo.GetWrappedKey(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("取得資料金鑰失敗: %w", err)
+	}
+	if len(stored) <= NonceSize {
+		return nil, errors.New("資料金鑰格式無效")
+	}
+
+	dataKey, err := openWithAlgorithm(AlgorithmAES256, masterKey, stored[:NonceSize], stored[NonceSize:], []byte(vaultDataKeyAAD+keyID))
+	if err != nil {
+		return nil, fmt.Errorf("解開資料金鑰失敗: %w", err)
+	}
+
+	return dataKey, nil
+}
+
+// loadHeader 從金鑰儲存庫載入保險庫標頭
+// 回傳：保險庫標頭和可能的錯誤（尚未建立時回傳 ErrVaultNotInitialized）
+func (v *vaultService) loadHeader() (*VaultHeader, error) {
+	data, err := v.repo.GetVaultHeader()
+	if err != nil {
+		return nil, ErrVaultNotInitialized
+	}
+
+	var header VaultHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("解析保險庫標頭失敗: %w", err)
+	}
+	if header.Version != VaultHeaderVersion {
+		return nil, fmt.Errorf("不支援的保險庫標頭版本: %s", header.Version)
+	}
+
+	return &header, nil
+}
+
+// storeHeader 將保險庫標頭寫入金鑰儲存庫
+// 參數：header（保險庫標頭）
+// 回傳：可能的錯誤
+func (v *vaultService) storeHeader(header *VaultHeader) error {
+	data, err := json.Marshal(header)
+	if err != nil {
+		return fmt.Errorf("序列化保險庫標頭失敗: %w", err)
+	}
+	return v.repo.StoreVaultHeader(data)
+}
+
+// wrapMasterKey 以密碼衍生的 KEK 包裝主金鑰
+// 參數：masterKey（主金鑰）、password（保險庫密碼）、params（金鑰衍生參數）
+// 回傳：包含新鹽值和包裝後主金鑰的標頭（不含時間戳記）和可能的錯誤
+func wrapMasterKey(masterKey []byte, password string, params KDFParams) (*VaultHeader, error) {
+	salt := make([]byte, SaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("產生鹽值失敗: %w", err)
+	}
+
+	kek, err := params.DeriveKey(password, salt)
+	if err != nil {
+		return nil, fmt.Errorf("衍生金鑰失敗: %w", err)
+	}
+	defer zeroBytes(kek)
+
+	wrapped, nonce, err := sealWithAlgorithm(AlgorithmAES256, kek, masterKey, []byte(vaultMasterKeyAAD))
+	if err != nil {
+		return nil, fmt.Errorf("包裝主金鑰失敗: %w", err)
+	}
+
+	return &VaultHeader{
+		Version:          VaultHeaderVersion,
+		KDF:              params.Name,
+		Rounds:           int(params.Iterations),
+		Memory:           params.Memory,
+		Parallelism:      params.Parallelism,
+		Salt:             base64.StdEncoding.EncodeToString(salt),
+		Algorithm:        AlgorithmAES256,
+		MasterKeyNonce:   base64.StdEncoding.EncodeToString(nonce),
+		WrappedMasterKey: base64.StdEncoding.EncodeToString(wrapped),
+	}, nil
+}
+
+// unwrapMasterKey 以密碼衍生的 KEK 解開主金鑰
+// 參數：header（保險庫標頭）、password（保險庫密碼）
+// 回傳：主金鑰和可能的錯誤（密碼錯誤時回傳 ErrVaultWrongPassword）
+func unwrapMasterKey(header *VaultHeader, password string) ([]byte, error) {
+	if password == "" {
+		return nil, errors.New("密碼不能為空")
+	}
+	params := header.kdfParams()
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(header.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("解碼鹽值失敗: %w", err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(header.MasterKeyNonce)
+	if err != nil {
+		return nil, fmt.Errorf("解碼隨機數失敗: %w", err)
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(header.WrappedMasterKey)
+	if err != nil {
+		return nil, fmt.Errorf("解碼主金鑰失敗: %w", err)
+	}
+
+	kek, err := params.DeriveKey(password, salt)
+	if err != nil {
+		return nil, fmt.Errorf("衍生金鑰失敗: %w", err)
+	}
+	defer zeroBytes(kek)
+
+	masterKey, err := openWithAlgorithm(header.Algorithm, kek, nonce, wrapped, []byte(vaultMasterKeyAAD))
+	if err != nil {
+		return nil, ErrVaultWrongPassword
+	}
+
+	return masterKey, nil
+}
+
+// zeroBytes 將位元組陣列內容清為零，避免金鑰殘留在記憶體中
+// 參數：b（要清除的位元組陣列）
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,406 @@
+// Package services 提供保險庫服務的單元測試
+// 測試主金鑰建立、解鎖、鎖定、變更密碼和信封加密的正確性
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"mac-notebook-app/internal/models"
+	"mac-notebook-app/internal/repositories"
+)
+
+// createTestVaultService 建立使用臨時目錄金鑰儲存庫的保險庫服務
+func createTestVaultService(t *testing.T) (VaultService, *repositories.LocalEncryptionRepository) {
+	repo, err := repositories.NewLocalEncryptionRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("建立金鑰儲存庫失敗: %v", err)
+	}
+	return NewVaultService(repo, nil), repo
+}
+
+// TestVaultInitializeAndUnlock 測試保險庫的建立、鎖定和解鎖流程
+func TestVaultInitializeAndUnlock(t *testing.T) {
+	vault, repo := createTestVaultService(t)
+
+	if vault.IsInitialized() || vault.IsUnlocked() {
+		t.Fatal("新的保險庫應該尚未初始化且為鎖定狀態")
+	}
+
+	if err := vault.Unlock("Password123!"); !errors.Is(err, ErrVaultNotInitialized) {
+		t.Errorf("未初始化時解鎖應該回傳 ErrVaultNotInitialized，實際: %v", err)
+	}
+
+	if err := vault.Initialize("Password123!"); err != nil {
+		t.Fatalf("初始化保險庫失敗: %v", err)
+	}
+	if !vault.IsInitialized() || !vault.IsUnlocked() {
+		t.Error("初始化後保險庫應該為已初始化且解鎖狀態")
+	}
+	if err := vault.Initialize("Another123!"); !errors.Is(err, ErrVaultAlreadyInitialized) {
+		t.Errorf("重複初始化應該回傳 ErrVaultAlreadyInitialized，實際: %v", err)
+	}
+
+	// 標頭不應包含明文密碼
+	header, _ := repo.GetVaultHeader()
+	if strings.Contains(string(header), "Password123!") {
+		t.Error("保險庫標頭不應包含明文密碼")
+	}
+
+	vault.Lock()
+	if vault.IsUnlocked() {
+		t.Error("鎖定後保險庫應該為鎖定狀態")
+	}
+
+	if err := vault.Unlock("WrongPass1!"); !errors.Is(err, ErrVaultWrongPassword) {
+		t.Errorf("錯誤密碼應該回傳 ErrVaultWrongPassword，實際: %v", err)
+	}
+	if err := vault.Unlock("Password123!"); err != nil {
+		t.Errorf("正確密碼解鎖失敗: %v", err)
+	}
+}
+
+// TestVaultEncryptDecryptNote 測試以資料金鑰加解密筆記
+func TestVaultEncryptDecryptNote(t *testing.T) {
+	vault, _ := createTestVaultService(t)
+	if err := vault.Initialize("Password123!"); err != nil {
+		t.Fatalf("初始化保險庫失敗: %v", err)
+	}
+
+	for _, algorithm := range []string{AlgorithmAES256, AlgorithmChaCha20} {
+		t.Run(algorithm, func(t *testing.T) {
+			content := "# 機密筆記\n\n保險庫加密內容"
+
+			data, keyID, err := vault.EncryptNote(content, algorithm, "")
+			if err != nil {
+				t.Fatalf("加密失敗: %v", err)
+			}
+			if keyID == "" {
+				t.Fatal("應該產生新的資料金鑰 ID")
+			}
+			if !vault.IsVaultData(data) {
+				t.Error("加密結果應該是信封格式")
+			}
+
+			decrypted, info, err := vault.DecryptNote(data)
+			if err != nil {
+				t.Fatalf("解密失敗: %v", err)
+			}
+			if decrypted != content {
+				t.Errorf("解密內容不符: %q", decrypted)
+			}
+			if info.KeyID != keyID || info.Algorithm != algorithm {
+				t.Errorf("金鑰資訊不符: %+v", info)
+			}
+
+			// 沿用既有資料金鑰再次加密
+			_, sameKeyID, err := vault.EncryptNote("更新內容", algorithm, keyID)
+			if err != nil || sameKeyID != keyID {
+				t.Errorf("沿用資料金鑰失敗: %s, %v", sameKeyID, err)
+			}
+		})
+	}
+
+	t.Run("鎖定後無法加解密", func(t *testing.T) {
+		data, _, err := vault.EncryptNote("內容", AlgorithmAES256, "")
+		if err != nil {
+			t.Fatalf("加密失敗: %v", err)
+		}
+
+		vault.Lock()
+		defer vault.Unlock("Password123!")
+
+		if _, _, err := vault.EncryptNote("內容", AlgorithmAES256, ""); !errors.Is(err, ErrVaultLocked) {
+			t.Errorf("鎖定時加密應該回傳 ErrVaultLocked，實際: %v", err)
+		}
+		if _, _, err := vault.DecryptNote(data); !errors.Is(err, ErrVaultLocked) {
+			t.Errorf("鎖定時解密應該回傳 ErrVaultLocked，實際: %v", err)
+		}
+	})
+
+	t.Run("竄改金鑰 ID 無法解密", func(t *testing.T) {
+		data, _, _ := vault.EncryptNote("內容", AlgorithmAES256, "")
+		_, otherKeyID, _ := vault.EncryptNote("其他", AlgorithmAES256, "")
+
+		var encData EncryptedData
+		json.Unmarshal(data, &encData)
+		encData.KeyID = otherKeyID
+		tampered, _ := json.Marshal(encData)
+
+		if _, _, err := vault.DecryptNote(tampered); err == nil {
+			t.Error("竄改金鑰 ID 後應該解密失敗")
+		}
+	})
+
+	t.Run("刪除資料金鑰後無法解密", func(t *testing.T) {
+		data, keyID, _ := vault.EncryptNote("內容", AlgorithmAES256, "")
+		if err := vault.DeleteNoteKey(keyID); err != nil {
+			t.Fatalf("刪除資料金鑰失敗: %v", err)
+		}
+		if _, _, err := vault.DecryptNote(data); err == nil {
+			t.Error("刪除資料金鑰後應該解密失敗")
+		}
+	})
+}
+
+// TestVaultChangePassword 測試變更密碼後既有筆記仍可解密
+func TestVaultChangePassword(t *testing.T) {
+	vault, _ := createTestVaultService(t)
+	if err := vault.Initialize("OldPassword1!"); err != nil {
+		t.Fatalf("初始化保險庫失敗: %v", err)
+	}
+
+	data, _, err := vault.EncryptNote("變更密碼前的內容", AlgorithmAES256, "")
+	if err != nil {
+		t.Fatalf("加密失敗: %v", err)
+	}
+
+	if err := vault.ChangePassword("WrongPass1!", "NewPassword1!"); !errors.Is(err, ErrVaultWrongPassword) {
+		t.Errorf("舊密碼錯誤時應該回傳 ErrVaultWrongPassword，實際: %v", err)
+	}
+	if err := vault.ChangePassword("OldPassword1!", "NewPassword1!"); err != nil {
+		t.Fatalf("變更密碼失敗: %v", err)
+	}
+
+	vault.Lock()
+	if err := vault.Unlock("OldPassword1!"); err == nil {
+		t.Error("變更後舊密碼不應能解鎖")
+	}
+	if err := vault.Unlock("NewPassword1!"); err != nil {
+		t.Fatalf("新密碼解鎖失敗: %v", err)
+	}
+
+	if content, _, err := vault.DecryptNote(data); err != nil || content != "變更密碼前的內容" {
+		t.Errorf("變更密碼後解密失敗: %q, %v", content, err)
+	}
+}
+
+// TestEditorServiceWithVault 測試編輯器服務透過保險庫保存和開啟加密筆記
+func TestEditorServiceWithVault(t *testing.T) {
+	service, mockRepo := createTestEditorService()
+	vault, _ := createTestVaultService(t)
+	service.SetOptions(EditorOptions{Vault: vault})
+
+	note, _ := service.CreateNote("保險庫筆記", "機密內容")
+	if err := service.(*editorService).EnableEncryption(note.ID, "TestPassword123!", AlgorithmChaCha20, false); err != nil {
+		t.Fatalf("啟用加密失敗: %v", err)
+	}
+	if !vault.IsUnlocked() {
+		t.Fatal("啟用加密後保險庫應該已解鎖")
+	}
+
+	// 保險庫解鎖時保存不需要密碼
+	if err := service.SaveNote(note); err != nil {
+		t.Fatalf("保存加密筆記失敗: %v", err)
+	}
+	if strings.Contains(string(mockRepo.files[note.FilePath]), "機密內容") {
+		t.Error("保存的檔案不應包含明文內容")
+	}
+
+	// 保險庫解鎖時開啟不需要密碼
+	opened, err := service.OpenNote(note.FilePath)
+	if err != nil {
+		t.Fatalf("開啟加密筆記失敗: %v", err)
+	}
+	if opened.Content != "機密內容" || opened.EncryptionType != AlgorithmChaCha20 {
+		t.Errorf("開啟的筆記不符: %q, %s", opened.Content, opened.EncryptionType)
+	}
+
+	// 鎖定後開啟需要密碼驗證
+	vault.Lock()
+	if _, err := service.OpenNote(note.FilePath); err == nil || !strings.Contains(err.Error(), "需要密碼驗證") {
+		t.Errorf("鎖定時開啟應該要求密碼驗證，實際: %v", err)
+	}
+	if err := service.SaveNote(opened); err == nil {
+		t.Error("鎖定時保存加密筆記應該失敗")
+	}
+
+	// 鎖定時已解密的筆記會被關閉
+	if _, exists := service.GetActiveNote(opened.ID); exists {
+		t.Error("鎖定後已解密的筆記應該被關閉")
+	}
+
+	// 重新解鎖後可以再次開啟
+	if err := vault.Unlock("TestPassword123!"); err != nil {
+		t.Fatalf("重新解鎖失敗: %v", err)
+	}
+	reopened, err := service.OpenNote(note.FilePath)
+	if err != nil || reopened.Content != "機密內容" {
+		t.Errorf("重新解鎖後開啟失敗: %v", err)
+	}
+}
+
+// TestEditorServiceDisableEncryptionDeletesKeyAfterSave 測試停用加密後保存明文前加密檔案仍可解密，
+// 保存後刪除加密檔案和資料金鑰；直接修改筆記欄位再保存（切換加密的按鈕）行為相同
+func TestEditorServiceDisableEncryptionDeletesKeyAfterSave(t *testing.T) {
+	disablers := map[string]func(service *editorService, note *models.Note) error{
+		"DisableEncryption": func(service *editorService, note *models.Note) error {
+			return service.DisableEncryption(note.ID)
+		},
+		"直接修改筆記欄位": func(_ *editorService, note *models.Note) error {
+			note.IsEncrypted = false
+			note.EncryptionType = ""
+			note.FilePath = DecryptedFilePath(note.FilePath, note.Title)
+			return nil
+		},
+	}
+
+	for name, disable := range disablers {
+		t.Run(name, func(t *testing.T) {
+			service, mockRepo := createTestEditorService()
+			vault, _ := createTestVaultService(t)
+			service.SetOptions(EditorOptions{Vault: vault})
+			editor := service.(*editorService)
+
+			note, _ := service.CreateNote("日記", "機密內容")
+			if err := editor.EnableEncryption(note.ID, "TestPassword123!", AlgorithmAES256, false); err != nil {
+				t.Fatalf("啟用加密失敗: %v", err)
+			}
+			if err := service.SaveNote(note); err != nil {
+				t.Fatalf("保存加密筆記失敗: %v", err)
+			}
+			encPath := note.FilePath
+			encData := append([]byte(nil), mockRepo.files[encPath]...)
+
+			if err := disable(editor, note); err != nil {
+				t.Fatalf("停用加密失敗: %v", err)
+			}
+			if content, _, err := vault.DecryptNote(mockRepo.files[encPath]); err != nil || content != "機密內容" {
+				t.Fatalf("保存明文前加密檔案應該仍可解密: %v", err)
+			}
+
+			if err := service.SaveNote(note); err != nil {
+				t.Fatalf("保存明文筆記失敗: %v", err)
+			}
+			if string(mockRepo.files[note.FilePath]) != "機密內容" {
+				t.Errorf("應該保存明文檔案: %s", note.FilePath)
+			}
+			if _, exists := mockRepo.files[encPath]; exists {
+				t.Error("保存明文後應該刪除加密檔案")
+			}
+			if _, _, err := vault.DecryptNote(encData); err == nil {
+				t.Error("保存明文後應該刪除資料金鑰")
+			}
+			if _, exists := editor.noteKeyIDs[note.ID]; exists {
+				t.Error("不應保留已刪除的資料金鑰 ID")
+			}
+		})
+	}
+}
+
+// TestEditorServiceEnableEncryptionChecksVaultPassword 測試保險庫已解鎖時，啟用加密的密碼必須是保險庫密碼
+func TestEditorServiceEnableEncryptionChecksVaultPassword(t *testing.T) {
+	service, _ := createTestEditorService()
+	vault, _ := createTestVaultService(t)
+	service.SetOptions(EditorOptions{Vault: vault})
+	editor := service.(*editorService)
+
+	first, _ := service.CreateNote("第一篇", "內容")
+	if err := editor.EnableEncryption(first.ID, "TestPassword123!", AlgorithmAES256, false); err != nil {
+		t.Fatalf("啟用加密失敗: %v", err)
+	}
+
+	second, _ := service.CreateNote("第二篇", "內容")
+	if err := editor.EnableEncryption(second.ID, "OtherPassword456!", AlgorithmAES256, false); !errors.Is(err, ErrVaultWrongPassword) {
+		t.Errorf("密碼與保險庫密碼不同時應該拒絕: %v", err)
+	}
+	if second.IsEncrypted {
+		t.Error("密碼錯誤時不應啟用加密")
+	}
+	if err := editor.EnableEncryption(second.ID, "TestPassword123!", AlgorithmAES256, false); err != nil || !second.IsEncrypted {
+		t.Errorf("使用保險庫密碼應該可以啟用加密: %v", err)
+	}
+}
+
+// TestVaultUpgradesLegacyKDF 測試以舊的 PBKDF2 標頭解鎖後自動升級為 Argon2id
+func TestVaultUpgradesLegacyKDF(t *testing.T) {
+	vault, repo := createTestVaultService(t)
+	if err := vault.Initialize("Password123!"); err != nil {
+		t.Fatalf("初始化保險庫失敗: %v", err)
+	}
+	data, _, err := vault.EncryptNote("升級前的內容", AlgorithmAES256, "")
+	if err != nil {
+		t.Fatalf("加密失敗: %v", err)
+	}
+
+	// 以 PBKDF2 重新包裝同一把主金鑰，模擬舊版本建立的保險庫
+	masterKey, err := vault.(*vaultService).copyMasterKey()
+	if err != nil {
+		t.Fatalf("取得主金鑰失敗: %v", err)
+	}
+	legacyHeader, err := wrapMasterKey(masterKey, "Password123!", LegacyKDFParams())
+	if err != nil {
+		t.Fatalf("建立舊格式標頭失敗: %v", err)
+	}
+	legacyData, _ := json.Marshal(legacyHeader)
+	repo.StoreVaultHeader(legacyData)
+	vault.Lock()
+
+	if err := vault.Unlock("Password123!"); err != nil {
+		t.Fatalf("以舊格式標頭解鎖失敗: %v", err)
+	}
+
+	var header VaultHeader
+	raw, _ := repo.GetVaultHeader()
+	json.Unmarshal(raw, &header)
+	if header.kdfParams() != DefaultKDFParams() {
+		t.Errorf("解鎖後標頭應該升級為預設 KDF 參數，實際: %+v", header.kdfParams())
+	}
+
+	// 升級後舊筆記仍可解密，且新密碼標頭可再次解鎖
+	if content, _, err := vault.DecryptNote(data); err != nil || content != "升級前的內容" {
+		t.Errorf("升級後解密失敗: %q, %v", content, err)
+	}
+	vault.Lock()
+	if err := vault.Unlock("Password123!"); err != nil {
+		t.Errorf("升級後解鎖失敗: %v", err)
+	}
+}
+
+// TestEditorServiceUpgradesLegacyPasswordNote 測試以密碼開啟舊格式筆記後，保存時升級為 Argon2id
+func TestEditorServiceUpgradesLegacyPasswordNote(t *testing.T) {
+	mockRepo := newMockFileRepository()
+	service := NewEditorService(mockRepo, NewEncryptionService(), &mockPasswordService{}, &mockBiometricService{}, nil, nil)
+	vault, _ := createTestVaultService(t)
+	service.SetOptions(EditorOptions{Vault: vault})
+
+	filePath := "legacy.md.enc"
+	mockRepo.WriteFile(filePath, encryptLegacyContent(t, "舊筆記內容", "Legacy123!", AlgorithmChaCha20))
+
+	// 沒有密碼時無法開啟
+	if _, err := service.OpenNote(filePath); err == nil {
+		t.Fatal("未提供密碼時開啟舊格式筆記應該失敗")
+	}
+	if _, err := service.OpenNoteWithPassword(filePath, "Wrong123!"); err == nil {
+		t.Fatal("錯誤密碼應該開啟失敗")
+	}
+
+	note, err := service.OpenNoteWithPassword(filePath, "Legacy123!")
+	if err != nil {
+		t.Fatalf("以密碼開啟舊格式筆記失敗: %v", err)
+	}
+	if note.Content != "舊筆記內容" || note.Title != "legacy" || note.EncryptionType != AlgorithmChaCha20 {
+		t.Errorf("開啟的筆記不符: %q, %q, %s", note.Content, note.Title, note.EncryptionType)
+	}
+
+	// 保險庫未解鎖時仍可沿用筆記密碼保存，並升級為記錄 KDF 參數的格式
+	service.UpdateContent(note.ID, "更新後的內容")
+	if err := service.SaveNote(note); err != nil {
+		t.Fatalf("保存舊格式筆記失敗: %v", err)
+	}
+
+	var encData EncryptedData
+	json.Unmarshal(mockRepo.files[filePath], &encData)
+	if encData.Version != EncryptedDataVersionKDF || encData.KDF == nil || encData.KDF.Name != KDFArgon2id {
+		t.Errorf("保存後應該升級為 Argon2id 格式，實際版本 %s，KDF %+v", encData.Version, encData.KDF)
+	}
+
+	// 關閉後以原密碼重新開啟
+	service.CloseNote(note.ID)
+	reopened, err := service.OpenNoteWithPassword(filePath, "Legacy123!")
+	if err != nil || reopened.Content != "更新後的內容" {
+		t.Errorf("升級後以原密碼開啟失敗: %v", err)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"fyne.io/fyne/v2/app"      // 提供應用程式生命週期管理和視窗創建功能
 	"fyne.io/fyne/v2/theme"    // 提供主題相關功能，用於自訂 UI 外觀樣式
 	_ "embed"                  // Go 1.16+ 嵌入式檔案支援，用於嵌入字型資源
+	"errors"                   // Go 標準庫，用於錯誤類型判斷
+	"fmt"                      // Go 標準庫，用於命令列輸出
 	"image/color"              // Go 標準庫，提供顏色定義和處理功能
 	"log"                      // Go 標準庫，用於錯誤記錄
 	"os"                       // Go 標準庫，用於作業系統介面
@@ -61,10 +63,13 @@ func main() {
 		}
 	}
 	
-	fileRepo, err := repositories.NewLocalFileRepository(baseDir)
+	localFileRepo, err := repositories.NewLocalFileRepository(baseDir)
 	if err != nil {
 		log.Fatalf("建立檔案儲存庫失敗: %v", err)
 	}
+	localFileRepo.SetKeepBackup(settings.KeepBackups)
+	// 加密資料夾中的檔案經由保險庫透明加解密，保險庫建立後再設定
+	fileRepo := services.NewEncryptedFileRepository(localFileRepo)
 
 	// 2. 建立檔案管理服務
 	fileManagerService, err := services.NewLocalFileManagerService(fileRepo, baseDir)
@@ -72,9 +77,21 @@ func main() {
 		log.Fatalf("建立檔案管理服務失敗: %v", err)
 	}
 
+	// 身分金鑰庫保存在設定目錄，與筆記本分開存放
+	keystoreRepo, err := repositories.NewLocalEncryptionRepository(services.DefaultKeystoreDir())
+	if err != nil {
+		log.Printf("建立身分金鑰庫失敗，將無法開啟收件人加密的筆記: %v", err)
+	}
+
 	// 3. 建立各種服務
+	// 密碼重試狀態保存在筆記本的 .notebook 目錄，驗證用的 HMAC 金鑰保存在身分金鑰庫
 	encryptionService := services.NewEncryptionService()
-	passwordService := services.NewPasswordService()
+	var passwordService services.PasswordService
+	if keystoreRepo != nil {
+		passwordService = services.NewPersistentPasswordService(fileRepo, keystoreRepo)
+	} else {
+		passwordService = services.NewPasswordService()
+	}
 	biometricService := services.NewBiometricService()
 	performanceService := services.NewPerformanceService(nil)
 	smartEditingService := services.NewSmartEditingService()
@@ -82,17 +99,192 @@ func main() {
 	// 4. 建立編輯器服務
 	editorService := services.NewEditorService(fileRepo, encryptionService, passwordService, biometricService, performanceService, smartEditingService)
 
+	// 安全稽核記錄保存在筆記本的 .notebook 目錄，偵測截斷用的錨點保存在身分金鑰庫
+	var auditService services.AuditService
+	if keystoreRepo != nil {
+		auditService = services.NewAuditService(fileRepo, keystoreRepo)
+	} else {
+		auditService = services.NewAuditService(fileRepo, nil)
+	}
+	// 編輯器的選用依賴在下方逐一建立，全部建立後一次設定
+	editorOptions := services.EditorOptions{Audit: auditService}
+
+	// 以 verify-audit 參數啟動時只驗證稽核記錄，不開啟視窗
+	if len(os.Args) > 1 && os.Args[1] == "verify-audit" {
+		os.Exit(runAuditVerify(auditService))
+	}
+
+	// 同一個筆記本只允許一個程式開啟，已開啟時把檔案路徑和 note:// 連結轉交給該程式後結束
+	instanceLock, err := services.AcquireInstanceLock(baseDir)
+	if errors.Is(err, services.ErrInstanceRunning) {
+		if err := services.ForwardToRunningInstance(baseDir, os.Args[1:]); err != nil {
+			log.Fatalf("筆記本已在其他程式中開啟，轉交參數失敗: %v", err)
+		}
+		return
+	} else if err != nil {
+		log.Printf("無法確認筆記本是否已在其他程式中開啟: %v", err)
+	}
+
+	// 5. 建立保險庫服務，金鑰資料保存在筆記本的 .notebook 目錄
+	// 主金鑰由工作階段管理器暫存，依設定在閒置逾時或失去焦點時自動鎖定
+	var vault services.VaultService
+	encryptionRepo, err := repositories.NewLocalEncryptionRepository(filepath.Join(baseDir, services.NotebookMetaDir))
+	if err != nil {
+		log.Printf("建立金鑰儲存庫失敗，加密筆記將無法使用保險庫: %v", err)
+	} else {
+		session := services.NewSessionManager(0)
+		session.ApplySettings(settings)
+		vault = services.NewVaultService(encryptionRepo, session)
+		vault.SetRetryGuard(passwordService)
+		vault.SetAuditService(auditService)
+		editorOptions.Vault = vault
+		fileRepo.SetVaultService(vault)
+		editorOptions.ObfuscateFilenames = settings.ObfuscateFilenames
+	}
+
+	// 建立公鑰身分服務，身分金鑰庫保存在設定目錄，與保險庫共用工作階段一起鎖定
+	if keystoreRepo != nil {
+		var identitySession services.SessionManager
+		if vault != nil {
+			identitySession = vault.Session()
+		}
+		identityService := services.NewIdentityService(keystoreRepo, identitySession)
+		identityService.SetRetryGuard(passwordService)
+		identityService.SetAuditService(auditService)
+		editorOptions.Identity = identityService
+
+		// 簽章金鑰和受信任的簽署者與身分金鑰庫保存在同一處，簽章檔保存在筆記旁
+		editorOptions.Signing = services.NewSigningService(fileRepo, keystoreRepo)
+	}
+
+	// 6. 建立批次重新加密服務，上次作業中斷時依日誌完成或還原
+	rekeyService := services.NewRekeyService(fileRepo, encryptionService, vault)
+	rekeyService.SetAuditService(auditService)
+	if rekeyService.HasPendingJournal() {
+		if result, err := rekeyService.Recover(); err != nil {
+			log.Printf("復原中斷的重新加密作業失敗: %v", err)
+		} else if result.RolledBack {
+			log.Printf("已還原中斷的重新加密作業，%d 個檔案維持原密碼", result.TotalFiles)
+		} else {
+			log.Printf("已完成中斷的重新加密作業，%d 個檔案改用新密碼", result.SuccessCount)
+		}
+	}
+
+	// 7. 建立未保存編輯的復原日誌，加密筆記的編輯以保險庫金鑰加密後才寫入
+	recoveryJournal := services.NewRecoveryJournal(fileRepo)
+	recoveryJournal.SetVaultService(vault)
+	editorOptions.RecoveryJournal = recoveryJournal
+
+	// 筆記 ID 索引讓同一個檔案每次開啟都使用相同的 ID，重新命名和移動後 ID 不變
+	noteIDIndex := services.NewNoteIDIndex(fileRepo)
+	editorOptions.NoteIDIndex = noteIDIndex
+	fileManagerService.SetNoteIDIndex(noteIDIndex)
+
+	// 標籤索引在保存時更新，檔案監看的變更也會重新讀取受影響的筆記
+	tagService := services.NewTagService(fileRepo, editorService)
+	editorOptions.Tags = tagService
+
+	// 最愛和釘選記錄在筆記本中繼資料目錄中，筆記以 ID 追蹤，重新命名和移動後仍然有效
+	favoritesService := services.NewFavoritesService(fileRepo, noteIDIndex)
+	fileManagerService.SetFavoritesService(favoritesService)
+
+	// 全文搜尋索引保存在筆記本中繼資料目錄中，保存、重新命名和刪除時逐筆更新
+	searchIndex := services.NewSearchIndex(fileRepo)
+	editorOptions.SearchIndex = searchIndex
+	fileManagerService.SetSearchIndex(searchIndex)
+	editorService.SetOptions(editorOptions)
+
+	// 8. 監看筆記本目錄，外部程式或同步服務的變更即時反映到檔案樹和開啟中的筆記
+	fileWatcher := services.NewFileWatcherService(baseDir)
+	fileWatcher.Subscribe(func(events []services.FileChangeEvent) {
+		for _, event := range events {
+			editorService.InvalidateFileCache(event.Path)
+		}
+		tagService.ApplyFileChanges(events)
+		searchIndex.ApplyFileChanges(events)
+	})
+	if err := fileWatcher.Start(); err != nil {
+		log.Printf("監看筆記本目錄失敗，外部變更需要手動重新整理: %v", err)
+	}
+
 	// 建立主視窗實例
 	// 使用新的 MainWindow 結構，包含完整的 UI 佈局和服務整合
 	mainWindow := ui.NewMainWindow(myApp, settings, editorService, fileManagerService)
+	mainWindow.SetRekeyService(rekeyService)
+	mainWindow.SetEncryptedFileRepository(fileRepo)
+	mainWindow.SetRecoveryJournal(recoveryJournal)
+	mainWindow.SetFileWatcher(fileWatcher)
+	noteLocks := services.NewNoteLockService(baseDir)
+	mainWindow.SetNotebookDir(baseDir)
+	mainWindow.SetNoteLockService(noteLocks)
+	mainWindow.SetNoteIDIndex(noteIDIndex)
+	mainWindow.SetTagService(tagService)
+	mainWindow.SetFavoritesService(favoritesService)
+	mainWindow.SetSearchIndex(searchIndex)
+	go func() {
+		// 在背景掃描筆記本建立標籤索引和全文搜尋索引，完成後標籤瀏覽器自動重新載入
+		if err := tagService.Rebuild(); err != nil {
+			log.Printf("建立標籤索引失敗: %v", err)
+		}
+		// 只重新讀取上次關閉後新增或修改的筆記
+		if err := searchIndex.Rebuild(); err != nil {
+			log.Printf("更新全文搜尋索引失敗: %v", err)
+		}
+	}()
+	if instanceLock != nil {
+		mainWindow.SetInstanceLock(instanceLock)
+	}
+	mainWindow.OpenLaunchArguments(os.Args[1:])
 
 	// 顯示主視窗並啟動應用程式的主事件迴圈
 	// 這個函數會阻塞直到使用者關閉應用程式
 	mainWindow.ShowAndRun()
+	fileWatcher.Stop()
+	noteLocks.ReleaseAll()
+	if instanceLock != nil {
+		instanceLock.Release()
+	}
+
+	// 結束前寫入尚未寫入的復原記錄，已保存或已關閉的筆記不會留下記錄
+	if err := recoveryJournal.Flush(); err != nil {
+		log.Printf("寫入復原日誌失敗: %v", err)
+	}
+
+	// 結束前覆寫刪除分享加密筆記時留下的暫存檔
+	if err := editorService.Options().LeakageGuard.WipeTempFiles(); err != nil {
+		log.Printf("清除暫存檔失敗: %v", err)
+	}
 }
 
 
 
+// runAuditVerify 驗證安全稽核記錄並輸出結果
+// 參數：audit（稽核記錄服務）
+// 回傳：結束代碼（記錄完整時為 0，偵測到修改或截斷時為 1，無法讀取時為 2）
+func runAuditVerify(audit services.AuditService) int {
+	result, err := audit.Verify()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "驗證稽核記錄失敗: %v\n", err)
+		return 2
+	}
+
+	fmt.Printf("稽核記錄：%s\n", services.AuditLogPath)
+	fmt.Printf("記錄筆數：%d\n", result.Entries)
+	if !result.Anchored {
+		fmt.Println("注意：金鑰庫中沒有錨點，無法確認記錄是否被截斷")
+	}
+	if result.Valid {
+		fmt.Println("結果：雜湊鏈完整")
+		return 0
+	}
+
+	fmt.Println("結果：偵測到修改或截斷")
+	for _, problem := range result.Problems {
+		fmt.Printf("  - %s\n", problem)
+	}
+	return 1
+}
+
 //go:embed assets/font/GoogleSansCode-Regular.ttf
 var fontRegular []byte
 
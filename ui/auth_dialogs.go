@@ -2,9 +2,14 @@ package ui
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"mac-notebook-app/internal/services"
 )
 
 // AuthMethod 驗證方法類型
@@ -18,8 +23,42 @@ const (
 	AuthMethodBiometric
 	// AuthMethodBoth 兩種驗證方法都支援
 	AuthMethodBoth
+	// AuthMethodKeyfile 密碼加金鑰檔
+	AuthMethodKeyfile
+	// AuthMethodTOTP 密碼加 TOTP 驗證碼
+	AuthMethodTOTP
+	// AuthMethodKeyfileTOTP 密碼加金鑰檔和 TOTP 驗證碼
+	AuthMethodKeyfileTOTP
 )
 
+// AuthMethodForFactors 依保險庫啟用的第二驗證因素取得對應的驗證方法
+// 參數：factors（保險庫啟用的第二驗證因素）
+// 回傳：驗證方法（沒有第二驗證因素時為密碼驗證）
+func AuthMethodForFactors(factors services.VaultFactors) AuthMethod {
+	switch {
+	case factors.Keyfile && factors.TOTP:
+		return AuthMethodKeyfileTOTP
+	case factors.Keyfile:
+		return AuthMethodKeyfile
+	case factors.TOTP:
+		return AuthMethodTOTP
+	default:
+		return AuthMethodPassword
+	}
+}
+
+// requiresKeyfile 檢查驗證方法是否需要金鑰檔
+// 回傳：是否需要金鑰檔
+func (m AuthMethod) requiresKeyfile() bool {
+	return m == AuthMethodKeyfile || m == AuthMethodKeyfileTOTP
+}
+
+// requiresTOTP 檢查驗證方法是否需要 TOTP 驗證碼
+// 回傳：是否需要驗證碼
+func (m AuthMethod) requiresTOTP() bool {
+	return m == AuthMethodTOTP || m == AuthMethodKeyfileTOTP
+}
+
 // AuthResult 驗證結果
 // 包含驗證的完整結果資訊
 type AuthResult struct {
@@ -28,6 +67,17 @@ type AuthResult struct {
 	Method       AuthMethod // 使用的驗證方法
 	ErrorMessage string     // 錯誤訊息（如果有）
 	Cancelled    bool       // 用戶是否取消了驗證
+	Keyfile      []byte     // 金鑰檔內容（如果使用金鑰檔）
+	TOTPCode     string     // TOTP 驗證碼（如果使用驗證碼）
+}
+
+// UnlockFactors 取得驗證結果中的第二驗證因素
+// 回傳：保險庫解鎖時使用的第二驗證因素
+func (r AuthResult) UnlockFactors() *services.UnlockFactors {
+	return &services.UnlockFactors{
+		Keyfile:  r.Keyfile,
+		TOTPCode: r.TOTPCode,
+	}
 }
 
 // AuthCallback 驗證回調函數類型
@@ -104,6 +154,9 @@ func (m *AuthDialogManager) ShowAuthDialog(title, message string, callback AuthC
 			m.showPasswordDialog(title, message, callback)
 		}
 
+	case AuthMethodKeyfile, AuthMethodTOTP, AuthMethodKeyfileTOTP:
+		m.showSecondFactorDialog(title, message, m.preferredMethod, callback)
+
 	default:
 		m.handleAuthError("未知的驗證方法", callback)
 	}
@@ -222,6 +275,73 @@ func (m *AuthDialogManager) showPasswordDialog(title, message string, callback A
 	})
 }
 
+// showSecondFactorDialog 顯示密碼加第二驗證因素的對話框
+// 參數：
+//   - title: 對話框標題
+//   - message: 驗證提示訊息
+//   - method: 驗證方法（決定顯示金鑰檔和驗證碼欄位）
+//   - callback: 完成時的回調函數
+//
+// 執行流程：
+// 1. 建立密碼欄位，依驗證方法加入金鑰檔路徑和驗證碼欄位
+// 2. 確認時讀取金鑰檔內容
+// 3. 調用回調函數回傳密碼、金鑰檔內容和驗證碼
+func (m *AuthDialogManager) showSecondFactorDialog(title, message string, method AuthMethod, callback AuthCallback) {
+	passwordEntry := widget.NewPasswordEntry()
+	passwordEntry.SetPlaceHolder("請輸入密碼...")
+	formItems := []*widget.FormItem{widget.NewFormItem("密碼", passwordEntry)}
+
+	keyfileEntry := widget.NewEntry()
+	if method.requiresKeyfile() {
+		keyfileEntry.SetPlaceHolder("金鑰檔路徑")
+		browseButton := widget.NewButton("瀏覽...", func() {
+			dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+				if err == nil && reader != nil {
+					keyfileEntry.SetText(reader.URI().Path())
+					reader.Close()
+				}
+			}, m.parent)
+		})
+		formItems = append(formItems, widget.NewFormItem("金鑰檔", container.NewBorder(nil, nil, nil, browseButton, keyfileEntry)))
+	}
+
+	codeEntry := widget.NewEntry()
+	if method.requiresTOTP() {
+		codeEntry.SetPlaceHolder("驗證器顯示的 6 位數驗證碼")
+		formItems = append(formItems, widget.NewFormItem("驗證碼", codeEntry))
+	}
+
+	content := container.NewVBox(widget.NewLabel(message), widget.NewForm(formItems...))
+	dialog.ShowCustomConfirm(title, "解鎖", "取消", content, func(confirmed bool) {
+		if !confirmed {
+			callback(AuthResult{
+				Success:   false,
+				Method:    method,
+				Cancelled: true,
+			})
+			return
+		}
+
+		var keyfile []byte
+		if path := strings.TrimSpace(keyfileEntry.Text); method.requiresKeyfile() && path != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				m.handleAuthError(fmt.Sprintf("讀取金鑰檔失敗: %v", err), callback)
+				return
+			}
+			keyfile = data
+		}
+
+		callback(AuthResult{
+			Success:  true,
+			Password: passwordEntry.Text,
+			Method:   method,
+			Keyfile:  keyfile,
+			TOTPCode: strings.TrimSpace(codeEntry.Text),
+		})
+	}, m.parent)
+}
+
 // handleAuthError 處理驗證錯誤
 // 參數：
 //   - errorMessage: 錯誤訊息
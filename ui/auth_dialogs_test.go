@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"fyne.io/fyne/v2/test"
+	"mac-notebook-app/internal/services"
 )
 
 // TestAuthMethod 測試驗證方法枚舉
@@ -22,6 +23,34 @@ func TestAuthMethod(t *testing.T) {
 	}
 }
 
+// TestAuthMethodForFactors 測試依保險庫的第二驗證因素選擇驗證方法
+func TestAuthMethodForFactors(t *testing.T) {
+	tests := []struct {
+		factors  services.VaultFactors
+		expected AuthMethod
+	}{
+		{services.VaultFactors{}, AuthMethodPassword},
+		{services.VaultFactors{Keyfile: true}, AuthMethodKeyfile},
+		{services.VaultFactors{TOTP: true}, AuthMethodTOTP},
+		{services.VaultFactors{Keyfile: true, TOTP: true}, AuthMethodKeyfileTOTP},
+	}
+
+	for _, tt := range tests {
+		method := AuthMethodForFactors(tt.factors)
+		if method != tt.expected {
+			t.Errorf("%+v 應該使用驗證方法 %d，實際為 %d", tt.factors, tt.expected, method)
+		}
+		if method.requiresKeyfile() != tt.factors.Keyfile || method.requiresTOTP() != tt.factors.TOTP {
+			t.Errorf("驗證方法 %d 需要的欄位與 %+v 不符", method, tt.factors)
+		}
+	}
+
+	result := AuthResult{Password: "pw", Keyfile: []byte("key"), TOTPCode: "123456"}
+	if factors := result.UnlockFactors(); string(factors.Keyfile) != "key" || factors.TOTPCode != "123456" {
+		t.Errorf("轉換後的第二驗證因素不符: %+v", factors)
+	}
+}
+
 // TestAuthResult 測試驗證結果結構
 func TestAuthResult(t *testing.T) {
 	// 測試成功結果
@@ -31,10 +31,12 @@ type MarkdownEditor struct {
 	// 服務依賴
 	editorService        services.EditorService        // 編輯器服務
 	chineseInputService  services.ChineseInputService  // 中文輸入服務
+	recoveryJournal      services.RecoveryJournal      // 未保存編輯的復原日誌（可選）
 	
 	// 當前狀態
 	currentNote   *models.Note         // 當前編輯的筆記
 	isModified    bool                 // 內容是否已修改
+	loadingContent bool                // 是否正在以程式載入內容（不記錄到復原日誌）
 	
 	// 回調函數
 	onContentChanged func(content string) // 內容變更回調
@@ -478,7 +480,7 @@ func (me *MarkdownEditor) LoadNote(note *models.Note) {
 	me.currentNote = note
 	
 	// 載入筆記內容到編輯器
-	me.editor.SetText(note.Content)
+	me.setTextWithoutRecording(note.Content)
 	
 	// 重置修改狀態
 	me.isModified = false
@@ -525,9 +527,10 @@ func (me *MarkdownEditor) CreateNewNote(title string) error {
 // 1. 檢查是否有當前筆記
 // 2. 更新筆記內容
 // 3. 使用編輯器服務保存筆記
-// 4. 重置修改狀態
-// 5. 更新狀態顯示
-// 6. 觸發保存回調
+// 4. 保存時更新了 front matter 時重新載入內容
+// 5. 重置修改狀態
+// 6. 更新狀態顯示
+// 7. 觸發保存回調
 func (me *MarkdownEditor) SaveNote() error {
 	if me.currentNote == nil {
 		return fmt.Errorf("沒有可保存的筆記")
@@ -548,6 +551,9 @@ func (me *MarkdownEditor) SaveNote() error {
 		return err
 	}
 	
+	// 保存時更新了 front matter（例如 updated 時間）時，以保存的內容更新編輯器並維持游標位置
+	me.ReloadContent()
+	
 	// 重置修改狀態
 	me.isModified = false
 	
@@ -562,6 +568,63 @@ func (me *MarkdownEditor) SaveNote() error {
 	return nil
 }
 
+// ResolveSaveConflict 依使用者的選擇處理保存時偵測到的外部變更
+// 參數：resolution（保留我的版本、採用磁碟上的版本或三方合併）
+// 回傳：合併結果是否仍有衝突標記需要手動處理，以及可能的錯誤
+//
+// 執行流程：
+// 1. 使用編輯器服務覆寫、重新載入或合併
+// 2. 採用磁碟上的版本或合併後，以新的內容更新編輯器
+// 3. 合併仍有衝突時維持修改狀態，由使用者處理衝突標記後再保存
+func (me *MarkdownEditor) ResolveSaveConflict(resolution services.ConflictResolution) (bool, error) {
+	if me.currentNote == nil {
+		return false, fmt.Errorf("沒有可保存的筆記")
+	}
+	
+	conflicted, err := me.editorService.ResolveSaveConflict(me.currentNote, resolution)
+	if err != nil {
+		me.updateStatus(fmt.Sprintf("保存失敗: %s", err.Error()))
+		return false, err
+	}
+	
+	if conflicted {
+		// 含衝突標記的合併結果尚未保存，與一般編輯一樣記錄到復原日誌
+		me.editor.SetText(me.currentNote.Content)
+		me.isModified = true
+		me.updateStatus("合併有衝突，請處理衝突標記後再保存")
+		return true, nil
+	}
+	if me.editor.Text != me.currentNote.Content {
+		me.setTextWithoutRecording(me.currentNote.Content)
+	}
+	
+	// 重置修改狀態
+	me.isModified = false
+	if resolution == services.ConflictKeepTheirs {
+		me.updateStatus(fmt.Sprintf("已載入磁碟上的版本: %s", me.currentNote.Title))
+	} else {
+		me.updateStatus(fmt.Sprintf("已保存筆記: %s", me.currentNote.Title))
+	}
+	
+	return false, nil
+}
+
+// ReloadContent 以筆記目前的內容更新編輯器並維持游標位置
+// 用於保存或批次修改標籤等操作在編輯器之外變更了筆記內容之後
+func (me *MarkdownEditor) ReloadContent() {
+	if me.currentNote == nil || me.editor.Text == me.currentNote.Content {
+		return
+	}
+	
+	row, column := me.editor.CursorRow, me.editor.CursorColumn
+	me.setTextWithoutRecording(me.currentNote.Content)
+	lines := strings.Split(me.currentNote.Content, "\n")
+	row = min(row, len(lines)-1)
+	me.editor.CursorRow, me.editor.CursorColumn = row, min(column, len([]rune(lines[row])))
+	me.editor.Refresh()
+	me.updateWordCount()
+}
+
 // GetContent 取得編輯器當前內容
 // 回傳：編輯器中的文字內容
 func (me *MarkdownEditor) GetContent() string {
@@ -576,11 +639,35 @@ func (me *MarkdownEditor) GetContent() string {
 // 2. 重置修改狀態
 // 3. 更新字數統計
 func (me *MarkdownEditor) SetContent(content string) {
-	me.editor.SetText(content)
+	me.setTextWithoutRecording(content)
 	me.isModified = false
 	me.updateWordCount()
 }
 
+// RestoreContent 以復原日誌中的內容取代編輯器內容
+// 參數：content（復原的內容）
+//
+// 與 SetContent 不同，還原的內容視為尚未保存的修改，並重新記錄到復原日誌
+func (me *MarkdownEditor) RestoreContent(content string) {
+	me.editor.SetText(content)
+	me.isModified = true
+	me.updateStatus("已還原未保存的編輯")
+}
+
+// SetRecoveryJournal 設定未保存編輯的復原日誌
+// 參數：journal（復原日誌）
+func (me *MarkdownEditor) SetRecoveryJournal(journal services.RecoveryJournal) {
+	me.recoveryJournal = journal
+}
+
+// setTextWithoutRecording 以程式設定編輯器內容，不記錄到復原日誌
+// 參數：content（要設定的內容）
+func (me *MarkdownEditor) setTextWithoutRecording(content string) {
+	me.loadingContent = true
+	defer func() { me.loadingContent = false }()
+	me.editor.SetText(content)
+}
+
 // IsModified 檢查內容是否已修改
 // 回傳：內容是否已修改的布林值
 func (me *MarkdownEditor) IsModified() bool {
@@ -667,13 +754,19 @@ func (me *MarkdownEditor) wrapSelection(startMark, endMark, placeholder string)
 //
 // 執行流程：
 // 1. 標記內容為已修改
-// 2. 更新字數統計
-// 3. 觸發內容變更回調
-// 4. 更新狀態顯示
+// 2. 記錄到復原日誌（以程式載入的內容除外）
+// 3. 更新字數統計
+// 4. 觸發內容變更回調
+// 5. 更新狀態顯示
 func (me *MarkdownEditor) onTextChanged(content string) {
 	// 標記為已修改
 	me.isModified = true
 	
+	// 記錄到復原日誌，當機後可以還原
+	if me.recoveryJournal != nil && me.currentNote != nil && !me.loadingContent {
+		me.recoveryJournal.Record(me.currentNote, content)
+	}
+	
 	// 更新字數統計
 	me.updateWordCount()
 	
@@ -773,6 +866,35 @@ func (me *MarkdownEditor) Focus() {
 	}
 }
 
+// GoToHeading 將游標移到指定標題所在的行
+// 參數：heading（標題文字或 slug）
+// 回傳：是否找到標題
+func (me *MarkdownEditor) GoToHeading(heading string) bool {
+	line := services.FindHeadingLine(me.editor.Text, heading)
+	if line < 0 {
+		return false
+	}
+	
+	me.editor.CursorRow = line
+	me.editor.CursorColumn = 0
+	me.editor.Refresh()
+	me.Focus()
+	return true
+}
+
+// GoToPosition 將游標移到指定的行和字元位置，超出範圍時移到最接近的位置
+// 參數：row（行，從 0 開始）、column（字元位置，從 0 開始）
+func (me *MarkdownEditor) GoToPosition(row, column int) {
+	lines := strings.Split(me.editor.Text, "\n")
+	row = max(0, min(row, len(lines)-1))
+	column = max(0, min(column, len([]rune(lines[row]))))
+	
+	me.editor.CursorRow = row
+	me.editor.CursorColumn = column
+	me.editor.Refresh()
+	me.Focus()
+}
+
 // Clear 清空編輯器內容
 // 清除所有文字並重置狀態
 //
@@ -782,7 +904,7 @@ func (me *MarkdownEditor) Focus() {
 // 3. 重置修改狀態
 // 4. 更新狀態顯示
 func (me *MarkdownEditor) Clear() {
-	me.editor.SetText("")
+	me.setTextWithoutRecording("")
 	me.currentNote = nil
 	me.isModified = false
 	me.updateStatus("編輯器已清空")
@@ -49,6 +49,13 @@ func (m *mockEditorService) OpenNote(filePath string) (*models.Note, error) {
 	return note, nil
 }
 
+// OpenNoteWithPassword 模擬以密碼開啟加密筆記功能
+// 參數：filePath（檔案路徑）、password（密碼）
+// 回傳：開啟的筆記實例和可能的錯誤
+func (m *mockEditorService) OpenNoteWithPassword(filePath, password string) (*models.Note, error) {
+	return m.OpenNote(filePath)
+}
+
 // SaveNote 模擬保存筆記功能
 // 參數：note（要保存的筆記）
 // 回傳：可能的錯誤
@@ -192,6 +199,33 @@ func (m *mockEditorService) SetSmartEditingService(smartEditSvc services.SmartEd
 	// 模擬實作，不執行任何操作
 }
 
+// Options 模擬取得編輯器的選用依賴
+// 回傳：空的選用依賴（模擬環境不使用保險庫等服務）
+func (m *mockEditorService) Options() services.EditorOptions {
+	return services.EditorOptions{}
+}
+
+// SetOptions 模擬設定編輯器的選用依賴
+// 參數：options（選用依賴）
+func (m *mockEditorService) SetOptions(options services.EditorOptions) {
+	// 模擬實作，不執行任何操作
+}
+
+// ResolveSaveConflict 模擬處理保存衝突
+func (m *mockEditorService) ResolveSaveConflict(note *models.Note, resolution services.ConflictResolution) (bool, error) {
+	return false, m.SaveNote(note)
+}
+
+// ReloadNote 模擬重新載入筆記
+func (m *mockEditorService) ReloadNote(noteID string) (bool, error) {
+	return false, nil
+}
+
+// InvalidateFileCache 模擬清除檔案快取
+func (m *mockEditorService) InvalidateFileCache(filePath string) {
+	// 模擬實作，不執行任何操作
+}
+
 // TestNewMarkdownEditor 測試 Markdown 編輯器的建立和初始化
 // 驗證編輯器是否正確建立並包含所有必要的 UI 元件
 //
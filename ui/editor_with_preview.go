@@ -95,7 +95,7 @@ func (ewp *EditorWithPreview) setupConnections() {
 	ewp.editor.SetOnContentChanged(func(content string) {
 		// 如果預覽面板可見且啟用自動刷新，更新預覽
 		if ewp.previewVisible && ewp.preview.IsAutoRefreshEnabled() {
-			ewp.preview.UpdatePreview(content)
+			ewp.updatePreview(content)
 		}
 		
 		// 觸發外部內容變更回調
@@ -132,7 +132,7 @@ func (ewp *EditorWithPreview) setupConnections() {
 	ewp.preview.SetOnRefreshRequested(func() {
 		// 從編輯器取得當前內容並更新預覽
 		content := ewp.editor.GetContent()
-		ewp.preview.UpdatePreview(content)
+		ewp.updatePreview(content)
 	})
 }
 
@@ -201,7 +201,7 @@ func (ewp *EditorWithPreview) LoadNote(note *models.Note) {
 	
 	// 如果預覽可見，更新預覽
 	if ewp.previewVisible {
-		ewp.preview.UpdatePreview(note.Content)
+		ewp.updatePreview(note.Content)
 	}
 }
 
@@ -254,7 +254,7 @@ func (ewp *EditorWithPreview) SetContent(content string) {
 	ewp.editor.SetContent(content)
 	
 	if ewp.previewVisible {
-		ewp.preview.UpdatePreview(content)
+		ewp.updatePreview(content)
 	}
 }
 
@@ -302,7 +302,7 @@ func (ewp *EditorWithPreview) TogglePreview() {
 	// 如果顯示預覽，更新預覽內容
 	if ewp.previewVisible {
 		content := ewp.editor.GetContent()
-		ewp.preview.UpdatePreview(content)
+		ewp.updatePreview(content)
 	}
 }
 
@@ -325,7 +325,7 @@ func (ewp *EditorWithPreview) SetPreviewVisible(visible bool) {
 	
 	if visible {
 		content := ewp.editor.GetContent()
-		ewp.preview.UpdatePreview(content)
+		ewp.updatePreview(content)
 	}
 }
 
@@ -354,6 +354,13 @@ func (ewp *EditorWithPreview) GetSplitRatio() float64 {
 	return ewp.splitRatio
 }
 
+// updatePreview 依當前筆記是否加密設定預覽的快取行為後更新預覽
+// 參數：content（要預覽的 Markdown 內容）
+func (ewp *EditorWithPreview) updatePreview(content string) {
+	ewp.preview.SetSensitive(services.IsSensitiveNote(ewp.editor.GetCurrentNote()))
+	ewp.preview.UpdatePreview(content)
+}
+
 // RefreshPreview 手動刷新預覽
 // 強制更新預覽面板內容
 //
@@ -362,7 +369,7 @@ func (ewp *EditorWithPreview) GetSplitRatio() float64 {
 // 2. 更新預覽面板
 func (ewp *EditorWithPreview) RefreshPreview() {
 	content := ewp.editor.GetContent()
-	ewp.preview.UpdatePreview(content)
+	ewp.updatePreview(content)
 }
 
 // SetAutoRefresh 設定預覽自動刷新
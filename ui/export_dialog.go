@@ -3,6 +3,7 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"mac-notebook-app/internal/models"
 	"mac-notebook-app/internal/services"
@@ -33,6 +34,7 @@ type ExportDialog struct {
 	// 進階選項
 	includeMetadata *widget.Check          // 包含元資料選項
 	includeTOC      *widget.Check          // 包含目錄選項
+	includeSecrets  *widget.Check          // 包含機密區塊明文選項
 	themeSelect     *widget.Select         // 主題選擇
 	fontSizeEntry   *widget.Entry          // 字體大小輸入
 	pageSizeSelect  *widget.Select         // 頁面大小選擇
@@ -132,6 +134,7 @@ func (d *ExportDialog) createUIComponents() {
 	d.includeMetadata = widget.NewCheck("包含元資料", nil)
 	d.includeTOC = widget.NewCheck("包含目錄", nil)
 	d.includeTOC.SetChecked(true)
+	d.includeSecrets = widget.NewCheck("包含機密區塊明文", nil)
 	
 	d.themeSelect = widget.NewSelect([]string{"預設", "淺色", "深色", "專業"}, nil)
 	d.themeSelect.SetSelected("預設")
@@ -216,6 +219,7 @@ func (d *ExportDialog) createLayout() {
 		container.NewGridWithColumns(2,
 			d.includeMetadata,
 			d.includeTOC,
+			d.includeSecrets,
 		),
 		container.NewGridWithColumns(2,
 			widget.NewLabel("主題:"),
@@ -412,6 +416,7 @@ func (d *ExportDialog) createExportOptions() *services.ExportOptions {
 	return &services.ExportOptions{
 		IncludeMetadata:        d.includeMetadata.Checked,
 		IncludeTableOfContents: d.includeTOC.Checked,
+		IncludeSecretBlocks:    d.includeSecrets.Checked,
 		Theme:                  d.themeSelect.Selected,
 		FontSize:               fontSize,
 		PageSize:               d.pageSizeSelect.Selected,
@@ -480,6 +485,15 @@ func (d *ExportDialog) onExportComplete(success bool, outputPath string, err err
 	d.exportButton.Enable()
 	d.progressBar.Hide()
 	
+	// 加密筆記需要先重新輸入密碼，驗證成功後重新匯出
+	if errors.Is(err, services.ErrReauthRequired) {
+		d.statusLabel.SetText("需要重新輸入密碼")
+		fyne.Do(func() {
+			ShowReauthDialog(d.window, d.exportService, d.note, d.onExportClicked)
+		})
+		return
+	}
+	
 	if success {
 		d.statusLabel.SetText("匯出完成！")
 		d.showSuccess(fmt.Sprintf("檔案已成功匯出到: %s", outputPath))
@@ -492,4 +492,8 @@ func (m *mockExportService) GetExportProgress(exportID string) *services.ExportP
 
 func (m *mockExportService) CancelExport(exportID string) bool {
 	return true
+}
+
+func (m *mockExportService) Reauthenticate(note *models.Note, password string) error {
+	return nil
 }
\ No newline at end of file
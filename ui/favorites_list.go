@@ -0,0 +1,160 @@
+// Package ui 包含最愛列表元件
+// 在側邊欄頂端列出標記為最愛的筆記和資料夾，點選後開啟筆記或在檔案樹中展開資料夾
+package ui
+
+import (
+	"os"            // Go 標準庫，用於判斷最愛是否為資料夾
+	"path/filepath" // Go 標準庫，用於檔案路徑處理
+
+	"fyne.io/fyne/v2"                    // Fyne GUI 框架核心套件
+	"fyne.io/fyne/v2/container"          // Fyne 容器佈局套件
+	"fyne.io/fyne/v2/layout"             // Fyne 佈局套件
+	"fyne.io/fyne/v2/theme"              // Fyne 主題套件（圖示）
+	"fyne.io/fyne/v2/widget"             // Fyne UI 元件套件
+	"mac-notebook-app/internal/services" // 本專案的服務層套件
+)
+
+// favoritesListMinHeight 最愛列表的最小高度
+const favoritesListMinHeight = 100
+
+// favoriteItem 最愛列表中的項目
+type favoriteItem struct {
+	Path        string // 相對於筆記本目錄的路徑
+	IsDirectory bool   // 是否為資料夾
+}
+
+// FavoritesWidget 代表側邊欄的最愛列表
+type FavoritesWidget struct {
+	widget.BaseWidget // 繼承 Fyne 基礎元件
+
+	// 服務依賴
+	favorites services.FavoritesService // 最愛和釘選服務
+	rootDir   string                    // 筆記本目錄，用於判斷項目是否為資料夾
+
+	// UI 元件
+	list       *widget.List    // 最愛列表
+	emptyLabel *widget.Label   // 沒有最愛時的提示
+	container  *fyne.Container // 容器元件
+
+	// 資料和狀態
+	items         []favoriteItem                       // 目前顯示的最愛
+	titleResolver func(filePath string) (string, bool) // 加密筆記標題解析函數（可選）
+
+	// 回調函數
+	onOpen func(path string, isDirectory bool) // 開啟最愛回調
+}
+
+// NewFavoritesWidget 建立最愛列表
+// 參數：favorites（最愛和釘選服務）、rootDir（筆記本目錄）
+// 回傳：最愛列表實例
+func NewFavoritesWidget(favorites services.FavoritesService, rootDir string) *FavoritesWidget {
+	fw := &FavoritesWidget{
+		favorites: favorites,
+		rootDir:   rootDir,
+	}
+	fw.ExtendBaseWidget(fw)
+	fw.createUI()
+	fw.ReloadFavorites()
+	return fw
+}
+
+// createUI 建立最愛列表的 UI 佈局
+func (fw *FavoritesWidget) createUI() {
+	fw.list = widget.NewList(
+		func() int {
+			return len(fw.items)
+		},
+		func() fyne.CanvasObject {
+			removeButton := widget.NewButtonWithIcon("", theme.CancelIcon(), nil)
+			removeButton.Importance = widget.LowImportance
+			return container.NewHBox(widget.NewIcon(theme.DocumentIcon()), widget.NewLabel(""), layout.NewSpacer(), removeButton)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			fw.updateItem(id, obj.(*fyne.Container))
+		},
+	)
+	fw.list.OnSelected = func(id widget.ListItemID) {
+		fw.list.UnselectAll()
+		if id < len(fw.items) && fw.onOpen != nil {
+			fw.onOpen(fw.items[id].Path, fw.items[id].IsDirectory)
+		}
+	}
+
+	fw.emptyLabel = widget.NewLabel("尚無最愛，使用工具列的 ⭐ 將筆記或資料夾加入最愛")
+	fw.emptyLabel.Wrapping = fyne.TextWrapWord
+
+	fw.container = container.NewVBox(fw.emptyLabel, withMinHeight(fw.list, favoritesListMinHeight))
+}
+
+// updateItem 更新列表項目的圖示、名稱和移除按鈕
+// 參數：id（項目索引）、row（項目的容器）
+func (fw *FavoritesWidget) updateItem(id widget.ListItemID, row *fyne.Container) {
+	if id >= len(fw.items) {
+		return
+	}
+	item := fw.items[id]
+
+	icon := row.Objects[0].(*widget.Icon)
+	if item.IsDirectory {
+		icon.SetResource(theme.FolderIcon())
+	} else {
+		icon.SetResource(theme.DocumentIcon())
+	}
+	row.Objects[1].(*widget.Label).SetText(fw.displayName(item))
+	row.Objects[3].(*widget.Button).OnTapped = func() {
+		fw.favorites.SetFavorite(item.Path, false)
+	}
+}
+
+// displayName 取得最愛顯示的名稱，加密筆記顯示標題
+// 參數：item（最愛項目）
+// 回傳：顯示名稱
+func (fw *FavoritesWidget) displayName(item favoriteItem) string {
+	if !item.IsDirectory && fw.titleResolver != nil {
+		if title, ok := fw.titleResolver(item.Path); ok {
+			return title
+		}
+	}
+	return filepath.Base(item.Path)
+}
+
+// CreateRenderer 實作 fyne.Widget 介面
+// 回傳：元件的渲染器
+func (fw *FavoritesWidget) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(fw.container)
+}
+
+// ReloadFavorites 從最愛服務重新載入最愛
+func (fw *FavoritesWidget) ReloadFavorites() {
+	paths := fw.favorites.Favorites()
+	fw.items = make([]favoriteItem, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(filepath.Join(fw.rootDir, path))
+		fw.items = append(fw.items, favoriteItem{Path: path, IsDirectory: err == nil && info.IsDir()})
+	}
+
+	if len(fw.items) == 0 {
+		fw.emptyLabel.Show()
+	} else {
+		fw.emptyLabel.Hide()
+	}
+	fw.list.Refresh()
+}
+
+// SetTitleResolver 設定加密筆記的標題解析函數
+// 參數：resolver（以檔案路徑取得標題的函數）
+func (fw *FavoritesWidget) SetTitleResolver(resolver func(filePath string) (string, bool)) {
+	fw.titleResolver = resolver
+}
+
+// SetOnOpen 設定開啟最愛回調函數
+// 參數：callback（點選最愛時的回調函數）
+func (fw *FavoritesWidget) SetOnOpen(callback func(path string, isDirectory bool)) {
+	fw.onOpen = callback
+}
+
+// GetContainer 取得最愛列表的容器
+// 回傳：容器元件
+func (fw *FavoritesWidget) GetContainer() *fyne.Container {
+	return fw.container
+}
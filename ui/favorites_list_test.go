@@ -0,0 +1,59 @@
+// Package ui 包含最愛列表的測試
+// 測試最愛列表的載入、開啟最愛，以及移除最愛後重新載入
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+	"mac-notebook-app/internal/repositories"
+	"mac-notebook-app/internal/services"
+)
+
+// TestFavoritesWidget 測試最愛列表顯示最愛並區分資料夾和筆記
+func TestFavoritesWidget(t *testing.T) {
+	test.NewApp()
+	baseDir := t.TempDir()
+	os.MkdirAll(filepath.Join(baseDir, "projects"), 0755)
+	os.WriteFile(filepath.Join(baseDir, "plan.md"), []byte("# 計畫"), 0644)
+	fileRepo, err := repositories.NewLocalFileRepository(baseDir)
+	if err != nil {
+		t.Fatalf("建立檔案儲存庫失敗: %v", err)
+	}
+	favorites := services.NewFavoritesService(fileRepo, nil)
+
+	list := NewFavoritesWidget(favorites, baseDir)
+	if !list.emptyLabel.Visible() {
+		t.Error("沒有最愛時應該顯示提示")
+	}
+
+	favorites.SetFavorite("projects", true)
+	favorites.SetFavorite("plan.md", true)
+	list.ReloadFavorites()
+	if len(list.items) != 2 || !list.items[0].IsDirectory || list.items[1].IsDirectory {
+		t.Fatalf("最愛項目不正確: %+v", list.items)
+	}
+
+	var openedPath string
+	var openedDirectory bool
+	list.SetOnOpen(func(path string, isDirectory bool) {
+		openedPath, openedDirectory = path, isDirectory
+	})
+	list.list.OnSelected(1)
+	if openedPath != "plan.md" || openedDirectory {
+		t.Errorf("點選筆記應該開啟 plan.md，但得到 %q %v", openedPath, openedDirectory)
+	}
+
+	// 點選移除按鈕後移出最愛
+	row := list.list.CreateItem().(*fyne.Container)
+	list.updateItem(1, row)
+	row.Objects[3].(*widget.Button).OnTapped()
+	list.ReloadFavorites()
+	if favorites.IsFavorite("plan.md") || len(list.items) != 1 {
+		t.Errorf("移除後不應再是最愛: %+v", list.items)
+	}
+}
@@ -5,9 +5,11 @@ package ui
 import (
 	"fmt"                      // Go 標準庫，用於格式化字串
 	"path/filepath"            // Go 標準庫，用於檔案路徑處理
+	"sort"                     // Go 標準庫，用於排序
 	"strings"                  // Go 標準庫，用於字串處理
 	"fyne.io/fyne/v2"          // Fyne GUI 框架核心套件
 	"fyne.io/fyne/v2/container" // Fyne 容器佈局套件
+	"fyne.io/fyne/v2/driver/desktop" // Fyne 桌面驅動程式（取得修飾鍵狀態）
 	"fyne.io/fyne/v2/widget"   // Fyne UI 元件套件
 	"fyne.io/fyne/v2/theme"    // Fyne 主題套件
 	"mac-notebook-app/internal/services" // 本專案的服務層套件
@@ -28,6 +30,9 @@ type FileTreeWidget struct {
 	// 資料和狀態
 	rootPath    string                   // 根目錄路徑
 	fileNodes   map[string]*FileNode     // 檔案節點快取
+	titleResolver func(filePath string) (string, bool) // 加密筆記標題解析函數（可選）
+	selectedPath  string                   // 目前選擇的檔案或目錄路徑
+	selectedFiles map[string]bool          // 多選的檔案路徑（按住 Cmd/Ctrl 或 Shift 點選加入或移除）
 	
 	// 回調函數
 	onFileSelect     func(filePath string)                        // 檔案選擇回調
@@ -44,6 +49,7 @@ type FileNode struct {
 	Name        string      // 檔案或目錄名稱
 	IsDirectory bool        // 是否為目錄
 	IsExpanded  bool        // 是否已展開（僅對目錄有效）
+	IsPinned    bool        // 是否已釘選
 	Children    []*FileNode // 子節點列表（僅對目錄有效）
 	Parent      *FileNode   // 父節點引用
 }
@@ -67,6 +73,7 @@ func NewFileTreeWidget(fileManager services.FileManagerService, rootPath string)
 		fileManager: fileManager,
 		rootPath:    rootPath,
 		fileNodes:   make(map[string]*FileNode),
+		selectedFiles: make(map[string]bool),
 	}
 	
 	// 擴展基礎元件
@@ -191,6 +198,7 @@ func (ftw *FileTreeWidget) loadDirectoryChildren(dirNode *FileNode) {
 			Name:        fileInfo.Name,
 			IsDirectory: fileInfo.IsDirectory,
 			IsExpanded:  false,
+			IsPinned:    fileInfo.IsPinned,
 			Children:    make([]*FileNode, 0),
 			Parent:      dirNode,
 		}
@@ -323,9 +331,66 @@ func (ftw *FileTreeWidget) updateNodeWidget(uid widget.TreeNodeID, branch bool,
 		}
 	}
 	
-	// 更新標籤文字
+	// 更新標籤文字，多選的檔案以強調色顯示
 	label := hbox.Objects[1].(*widget.Label)
-	label.SetText(node.Name)
+	if ftw.selectedFiles[node.Path] && len(ftw.selectedFiles) > 1 {
+		label.Importance = widget.HighImportance
+	} else {
+		label.Importance = widget.MediumImportance
+	}
+	label.SetText(ftw.markedName(node))
+}
+
+// markedName 取得顯示名稱，釘選的項目加上釘選標記
+// 參數：node（檔案節點）
+// 回傳：顯示名稱
+func (ftw *FileTreeWidget) markedName(node *FileNode) string {
+	if node.IsPinned {
+		return "📌 " + ftw.displayName(node)
+	}
+	return ftw.displayName(node)
+}
+
+// lockedNotePlaceholder 無法取得隨機檔名筆記的標題時顯示的文字
+const lockedNotePlaceholder = "（已鎖定的加密筆記）"
+
+// displayName 取得節點在檔案樹中顯示的名稱
+// 參數：node（檔案節點）
+// 回傳：顯示名稱
+//
+// 執行流程：
+// 1. 目錄和一般檔案直接顯示檔案名稱
+// 2. 加密筆記可由標題解析函數取得真實標題時顯示標題
+// 3. 隨機檔名的加密筆記無法取得標題時顯示佔位文字，不顯示無意義的檔名
+func (ftw *FileTreeWidget) displayName(node *FileNode) string {
+	if node.IsDirectory || !strings.HasSuffix(node.Name, ".enc") {
+		return node.Name
+	}
+
+	if ftw.titleResolver != nil {
+		if title, ok := ftw.titleResolver(node.Path); ok {
+			return title
+		}
+	}
+	if services.IsObfuscatedFileName(node.Name) {
+		return lockedNotePlaceholder
+	}
+	return node.Name
+}
+
+// SetTitleResolver 設定加密筆記標題解析函數
+// 參數：resolver（依檔案路徑回傳筆記標題和是否能取得的函數）
+func (ftw *FileTreeWidget) SetTitleResolver(resolver func(filePath string) (string, bool)) {
+	ftw.titleResolver = resolver
+	ftw.RefreshTitles()
+}
+
+// RefreshTitles 重新繪製節點名稱，不重新載入檔案結構
+// 保險庫鎖定或解鎖後呼叫，讓加密筆記顯示標題或佔位文字
+func (ftw *FileTreeWidget) RefreshTitles() {
+	if ftw.tree != nil {
+		ftw.tree.Refresh()
+	}
 }
 
 // handleNodeSelection 處理節點選擇事件
@@ -333,9 +398,10 @@ func (ftw *FileTreeWidget) updateNodeWidget(uid widget.TreeNodeID, branch bool,
 //
 // 執行流程：
 // 1. 查找對應的檔案節點
-// 2. 根據節點類型調用適當的回調函數
-// 3. 如果是檔案，調用檔案選擇回調
-// 4. 如果是目錄，調用目錄開啟回調
+// 2. 按住 Cmd/Ctrl 或 Shift 點選檔案時加入或移除多選，不開啟檔案
+// 3. 根據節點類型調用適當的回調函數
+// 4. 如果是檔案，多選只保留這個檔案並調用檔案選擇回調
+// 5. 如果是目錄，調用目錄開啟回調
 func (ftw *FileTreeWidget) handleNodeSelection(uid widget.TreeNodeID) {
 	// 查找對應的檔案節點
 	node, exists := ftw.fileNodes[string(uid)]
@@ -343,6 +409,19 @@ func (ftw *FileTreeWidget) handleNodeSelection(uid widget.TreeNodeID) {
 		return
 	}
 	
+	if !node.IsDirectory && multiSelectModifierPressed() {
+		ftw.ToggleFileSelection(node.Path)
+		// 取消樹狀元件的選擇，再次點選同一個檔案時才會再觸發
+		if ftw.tree != nil {
+			ftw.tree.Unselect(uid)
+		}
+		return
+	}
+	ftw.selectedPath = node.Path
+	if !node.IsDirectory {
+		ftw.selectFiles(node.Path)
+	}
+	
 	// 根據節點類型調用適當的回調
 	if node.IsDirectory {
 		if ftw.onDirectoryOpen != nil {
@@ -440,16 +519,172 @@ func (ftw *FileTreeWidget) Refresh() {
 	ftw.loadFileStructure()
 }
 
+// ApplyFileChanges 依檔案監看的變更更新檔案樹，不重新載入整個檔案結構
+// 參數：events（相對於筆記本目錄的檔案變更）
+//
+// 執行流程：
+// 1. 找出變更所在的目錄，尚未載入的目錄在展開時才會載入，不需要處理
+// 2. 重新列出這些目錄，保留仍存在的節點（維持展開狀態和已載入的子節點）
+// 3. 移除已不存在的節點和其所有子節點
+// 4. 內容變更的檔案重新繪製（加密筆記的標題可能改變）
+func (ftw *FileTreeWidget) ApplyFileChanges(events []services.FileChangeEvent) {
+	changedDirs := make(map[string]bool)
+	for _, event := range events {
+		nodePath := filepath.Join(ftw.rootPath, event.Path)
+		if event.Op == services.FileModified {
+			if _, exists := ftw.fileNodes[nodePath]; exists && ftw.tree != nil {
+				ftw.tree.RefreshItem(widget.TreeNodeID(nodePath))
+			}
+			continue
+		}
+		changedDirs[filepath.Dir(nodePath)] = true
+	}
+	
+	for dirPath := range changedDirs {
+		dirNode, exists := ftw.fileNodes[dirPath]
+		if !exists || !dirNode.IsDirectory || len(dirNode.Children) == 0 {
+			continue
+		}
+		ftw.reloadDirectoryChildren(dirNode)
+	}
+	
+	if len(changedDirs) > 0 && ftw.tree != nil {
+		ftw.tree.Refresh()
+	}
+}
+
+// ReloadDirectory 重新列出已載入的目錄，例如釘選變更後更新排序和標記
+// 參數：dirPath（目錄節點路徑）
+func (ftw *FileTreeWidget) ReloadDirectory(dirPath string) {
+	dirNode, exists := ftw.fileNodes[dirPath]
+	if !exists || !dirNode.IsDirectory {
+		return
+	}
+	ftw.reloadDirectoryChildren(dirNode)
+	if ftw.tree != nil {
+		ftw.tree.Refresh()
+	}
+}
+
+// reloadDirectoryChildren 重新列出目錄的子項目，保留仍存在的節點
+// 參數：dirNode（目錄節點）
+func (ftw *FileTreeWidget) reloadDirectoryChildren(dirNode *FileNode) {
+	files, err := ftw.fileManager.ListFiles(dirNode.Path)
+	if err != nil {
+		fmt.Printf("載入目錄失敗 %s: %v\n", dirNode.Path, err)
+		return
+	}
+	
+	existing := make(map[string]*FileNode, len(dirNode.Children))
+	for _, child := range dirNode.Children {
+		existing[child.Path] = child
+	}
+	
+	children := make([]*FileNode, 0, len(files))
+	for _, fileInfo := range files {
+		if child, ok := existing[fileInfo.Path]; ok && child.IsDirectory == fileInfo.IsDirectory {
+			delete(existing, fileInfo.Path)
+			child.IsPinned = fileInfo.IsPinned
+			children = append(children, child)
+			continue
+		}
+		childNode := &FileNode{
+			Path:        fileInfo.Path,
+			Name:        fileInfo.Name,
+			IsDirectory: fileInfo.IsDirectory,
+			IsPinned:    fileInfo.IsPinned,
+			Children:    make([]*FileNode, 0),
+			Parent:      dirNode,
+		}
+		children = append(children, childNode)
+		ftw.fileNodes[childNode.Path] = childNode
+	}
+	dirNode.Children = children
+	
+	// 移除已不存在的節點（目錄改為同名檔案時，新的節點已在上面建立）
+	for path, removed := range existing {
+		ftw.forgetNode(removed)
+		if ftw.fileNodes[path] == removed {
+			delete(ftw.fileNodes, path)
+		}
+	}
+}
+
+// forgetNode 從節點快取中移除節點的所有子節點
+// 參數：node（要移除的節點）
+func (ftw *FileTreeWidget) forgetNode(node *FileNode) {
+	for _, child := range node.Children {
+		ftw.forgetNode(child)
+		delete(ftw.fileNodes, child.Path)
+	}
+}
+
 // GetSelectedPath 取得目前選擇的檔案或目錄路徑
 // 回傳：選擇的路徑，如果沒有選擇則回傳空字串
 func (ftw *FileTreeWidget) GetSelectedPath() string {
-	if ftw.tree == nil {
-		return ""
+	return ftw.selectedPath
+}
+
+// SelectedFiles 取得多選的檔案，沒有多選時為目前選擇的檔案
+// 回傳：依路徑排序的檔案路徑（已從檔案樹移除的檔案不包含在內）
+func (ftw *FileTreeWidget) SelectedFiles() []string {
+	paths := make([]string, 0, len(ftw.selectedFiles))
+	for path := range ftw.selectedFiles {
+		if _, exists := ftw.fileNodes[path]; exists {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ToggleFileSelection 將檔案加入或移出多選
+// 參數：filePath（檔案路徑）
+func (ftw *FileTreeWidget) ToggleFileSelection(filePath string) {
+	if node, exists := ftw.fileNodes[filePath]; !exists || node.IsDirectory {
+		return
+	}
+	if ftw.selectedFiles[filePath] {
+		delete(ftw.selectedFiles, filePath)
+	} else {
+		ftw.selectedFiles[filePath] = true
+	}
+	if ftw.tree != nil {
+		ftw.tree.Refresh()
+	}
+}
+
+// ClearSelection 清除多選
+func (ftw *FileTreeWidget) ClearSelection() {
+	ftw.selectFiles()
+}
+
+// selectFiles 以指定的檔案取代多選
+// 參數：paths（檔案路徑）
+func (ftw *FileTreeWidget) selectFiles(paths ...string) {
+	changed := len(ftw.selectedFiles) > 1 || len(paths) > 1
+	ftw.selectedFiles = make(map[string]bool, len(paths))
+	for _, path := range paths {
+		ftw.selectedFiles[path] = true
+	}
+	// 多選的強調色只在選擇多個檔案時顯示，單選切換時不需要重新繪製
+	if changed && ftw.tree != nil {
+		ftw.tree.Refresh()
 	}
-	
-	// 注意：Fyne 的 Tree 元件沒有 CurrentSelection 方法
-	// 這裡我們需要追蹤選擇狀態，暫時回傳空字串
-	return ""
+}
+
+// multiSelectModifierPressed 檢查是否按住多選的修飾鍵（Cmd/Ctrl 或 Shift）
+// 回傳：是否按住修飾鍵，非桌面環境時為 false
+func multiSelectModifierPressed() bool {
+	app := fyne.CurrentApp()
+	if app == nil {
+		return false
+	}
+	driver, ok := app.Driver().(desktop.Driver)
+	if !ok {
+		return false
+	}
+	return driver.CurrentKeyModifiers()&(fyne.KeyModifierShortcutDefault|fyne.KeyModifierShift) != 0
 }
 
 // ExpandPath 展開指定路徑的所有父目錄
@@ -481,6 +716,43 @@ func (ftw *FileTreeWidget) ExpandPath(path string) {
 	}
 }
 
+// RevealPath 在檔案樹中顯示並選擇指定的檔案或目錄，尚未載入的父目錄會先載入
+// 參數：path（檔案或目錄路徑）
+//
+// 執行流程：
+// 1. 由上而下載入尚未載入的父目錄
+// 2. 展開所有父目錄，目錄本身也一併展開
+// 3. 選擇並捲動到該節點
+func (ftw *FileTreeWidget) RevealPath(path string) {
+	// 收集父目錄，由近到遠
+	var ancestors []string
+	for current := filepath.Dir(path); ; current = filepath.Dir(current) {
+		ancestors = append(ancestors, current)
+		if current == ftw.rootPath || current == filepath.Dir(current) {
+			break
+		}
+	}
+	
+	// 由上而下載入尚未載入的目錄
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		if node, exists := ftw.fileNodes[ancestors[i]]; exists && node.IsDirectory && len(node.Children) == 0 {
+			ftw.loadDirectoryChildren(node)
+		}
+	}
+	
+	node, exists := ftw.fileNodes[path]
+	if !exists || ftw.tree == nil {
+		return
+	}
+	ftw.ExpandPath(path)
+	if node.IsDirectory {
+		node.IsExpanded = true
+		ftw.tree.OpenBranch(widget.TreeNodeID(path))
+	}
+	ftw.tree.Select(widget.TreeNodeID(path))
+	ftw.tree.ScrollTo(widget.TreeNodeID(path))
+}
+
 // CreateObject 實作 fyne.Widget 介面
 // 回傳：元件的 UI 物件
 func (ftw *FileTreeWidget) CreateRenderer() fyne.WidgetRenderer {
@@ -519,6 +791,11 @@ func (ftw *FileTreeWidget) ShowContextMenu(filePath string, isDirectory bool, po
 					ftw.onFileOperation("create_folder", filePath)
 				}
 			}),
+			fyne.NewMenuItem("新增加密資料夾", func() {
+				if ftw.onFileOperation != nil {
+					ftw.onFileOperation("create_encrypted_folder", filePath)
+				}
+			}),
 			fyne.NewMenuItemSeparator(),
 			fyne.NewMenuItem("重新命名", func() {
 				if ftw.onFileOperation != nil {
@@ -447,4 +447,119 @@ func TestFileTreeWithRealFileSystem(t *testing.T) {
 	if !foundNotes {
 		t.Error("應該找到 notes 目錄")
 	}
-}
\ No newline at end of file
+}
+// TestFileTreeDisplayName 測試加密筆記的標題顯示
+// 驗證可取得標題時顯示真實標題，無法取得時隨機檔名顯示佔位文字
+func TestFileTreeDisplayName(t *testing.T) {
+	mockService := newFileTreeMockFileManagerService()
+	fileTree := NewFileTreeWidget(mockService, "/test")
+	
+	obfuscated := &FileNode{Path: "/test/0123456789abcdef0123456789abcdef.enc", Name: "0123456789abcdef0123456789abcdef.enc"}
+	titled := &FileNode{Path: "/test/日記.md.enc", Name: "日記.md.enc"}
+	plain := &FileNode{Path: "/test/readme.md", Name: "readme.md"}
+	
+	// 沒有標題解析函數（保險庫鎖定）時隱藏隨機檔名
+	if name := fileTree.displayName(obfuscated); name != lockedNotePlaceholder {
+		t.Errorf("隨機檔名應該顯示佔位文字，但得到 '%s'", name)
+	}
+	if name := fileTree.displayName(titled); name != "日記.md.enc" {
+		t.Errorf("一般加密檔名應該維持原名，但得到 '%s'", name)
+	}
+	
+	fileTree.SetTitleResolver(func(filePath string) (string, bool) {
+		if filePath == obfuscated.Path {
+			return "薪資談判", true
+		}
+		return "", false
+	})
+	
+	if name := fileTree.displayName(obfuscated); name != "薪資談判" {
+		t.Errorf("應該顯示中繼資料中的標題，但得到 '%s'", name)
+	}
+	if name := fileTree.displayName(plain); name != "readme.md" {
+		t.Errorf("一般檔案應該顯示檔名，但得到 '%s'", name)
+	}
+}
+
+// TestFileTreeApplyFileChanges 測試外部變更只更新受影響的目錄
+// 驗證新增和刪除的檔案反映到檔案樹，未變更的節點保留原本的實例和展開狀態
+func TestFileTreeApplyFileChanges(t *testing.T) {
+	mockService := newFileTreeMockFileManagerService()
+	fileTree := NewFileTreeWidget(mockService, "/test")
+	
+	notesNode := fileTree.fileNodes["/test/notes"]
+	notesNode.IsExpanded = true
+	
+	// 模擬外部新增 new.md 並刪除 readme.md
+	mockService.files["/test"] = []*models.FileInfo{
+		{Path: "/test/notes", Name: "notes", IsDirectory: true},
+		{Path: "/test/new.md", Name: "new.md", IsDirectory: false},
+	}
+	fileTree.ApplyFileChanges([]services.FileChangeEvent{
+		{Path: "new.md", Op: services.FileCreated},
+		{Path: "readme.md", Op: services.FileRemoved},
+	})
+	
+	if _, exists := fileTree.fileNodes["/test/new.md"]; !exists {
+		t.Error("新增的檔案應該出現在檔案樹中")
+	}
+	if _, exists := fileTree.fileNodes["/test/readme.md"]; exists {
+		t.Error("刪除的檔案應該從檔案樹中移除")
+	}
+	if fileTree.fileNodes["/test/notes"] != notesNode || !notesNode.IsExpanded {
+		t.Error("未變更的目錄節點應該保留")
+	}
+	if len(fileTree.fileNodes["/test"].Children) != 2 {
+		t.Errorf("根節點應該有 2 個子節點，但得到 %d 個", len(fileTree.fileNodes["/test"].Children))
+	}
+}
+
+// TestFileTreeMultiSelect 測試多選檔案
+// 驗證切換多選、單選取代多選，以及目錄不會加入多選
+func TestFileTreeMultiSelect(t *testing.T) {
+	mockService := newFileTreeMockFileManagerService()
+	fileTree := NewFileTreeWidget(mockService, "/test")
+	fileTree.handleBranchOpened(widget.TreeNodeID("/test/notes"))
+	
+	// 一般選擇檔案時只有該檔案
+	fileTree.handleNodeSelection(widget.TreeNodeID("/test/readme.md"))
+	if files := fileTree.SelectedFiles(); len(files) != 1 || files[0] != "/test/readme.md" {
+		t.Errorf("選擇的檔案應該只有 readme.md，但得到 %v", files)
+	}
+	
+	// 加入其他檔案，目錄不會加入
+	fileTree.ToggleFileSelection("/test/notes/todo.md")
+	fileTree.ToggleFileSelection("/test/notes")
+	files := fileTree.SelectedFiles()
+	if len(files) != 2 || files[0] != "/test/notes/todo.md" || files[1] != "/test/readme.md" {
+		t.Errorf("應該選擇 2 個檔案，但得到 %v", files)
+	}
+	
+	// 再次切換時移出多選
+	fileTree.ToggleFileSelection("/test/readme.md")
+	if files := fileTree.SelectedFiles(); len(files) != 1 || files[0] != "/test/notes/todo.md" {
+		t.Errorf("移出後應該只剩 todo.md，但得到 %v", files)
+	}
+	
+	fileTree.ClearSelection()
+	if files := fileTree.SelectedFiles(); len(files) != 0 {
+		t.Errorf("清除後不應該有選擇的檔案，但得到 %v", files)
+	}
+}
+
+// TestFileTreePinnedMarker 測試釘選的項目顯示釘選標記，重新列出目錄後更新標記
+func TestFileTreePinnedMarker(t *testing.T) {
+	mockService := newFileTreeMockFileManagerService()
+	fileTree := NewFileTreeWidget(mockService, "/test")
+	
+	readme := fileTree.fileNodes["/test/readme.md"]
+	if fileTree.markedName(readme) != "readme.md" {
+		t.Errorf("未釘選的項目不應有標記，但得到 '%s'", fileTree.markedName(readme))
+	}
+	
+	mockService.files["/test"][1].IsPinned = true
+	fileTree.ReloadDirectory("/test")
+	if fileTree.fileNodes["/test/readme.md"] != readme || fileTree.markedName(readme) != "📌 readme.md" {
+		t.Errorf("重新列出後應該保留節點並顯示釘選標記，但得到 '%s'", fileTree.markedName(readme))
+	}
+}
@@ -25,6 +25,9 @@ type LayoutManager struct {
 	
 	// 面板容器
 	sidebarPanel    *fyne.Container      // 側邊欄面板
+	sidebarTopSections *widget.Accordion // 側邊欄主要內容上方的可收合區段（例如最愛）
+	sidebarContent  *fyne.Container      // 側邊欄主要內容（檔案樹）
+	sidebarSections *widget.Accordion    // 側邊欄主要內容下方的可收合區段（例如標籤瀏覽器）
 	noteListPanel   *fyne.Container      // 筆記列表面板
 	editorPanel     *fyne.Container      // 編輯器面板
 	
@@ -314,7 +317,52 @@ func (lm *LayoutManager) GetContainer() *fyne.Container {
 // 2. 添加新的內容到側邊欄面板
 // 3. 刷新側邊欄面板顯示
 func (lm *LayoutManager) SetSidebarContent(content *fyne.Container) {
-	lm.sidebarPanel.Objects = []fyne.CanvasObject{content}
+	lm.sidebarContent = content
+	lm.refreshSidebar()
+}
+
+// AddSidebarSection 在側邊欄主要內容下方新增可收合的區段
+// 參數：title（區段標題）、content（區段內容）
+func (lm *LayoutManager) AddSidebarSection(title string, content fyne.CanvasObject) {
+	lm.sidebarSections = appendSidebarSection(lm.sidebarSections, title, content)
+	lm.refreshSidebar()
+}
+
+// AddSidebarTopSection 在側邊欄主要內容上方新增可收合的區段
+// 參數：title（區段標題）、content（區段內容）
+func (lm *LayoutManager) AddSidebarTopSection(title string, content fyne.CanvasObject) {
+	lm.sidebarTopSections = appendSidebarSection(lm.sidebarTopSections, title, content)
+	lm.refreshSidebar()
+}
+
+// appendSidebarSection 在摺疊容器中新增預設展開的區段
+// 參數：sections（摺疊容器，第一次新增時為 nil）、title（區段標題）、content（區段內容）
+// 回傳：可同時展開多個區段的摺疊容器
+func appendSidebarSection(sections *widget.Accordion, title string, content fyne.CanvasObject) *widget.Accordion {
+	if sections == nil {
+		sections = widget.NewAccordion()
+		sections.MultiOpen = true
+	}
+	
+	item := widget.NewAccordionItem(title, content)
+	item.Open = true
+	sections.Append(item)
+	return sections
+}
+
+// refreshSidebar 以主要內容和區段重新組合側邊欄面板
+func (lm *LayoutManager) refreshSidebar() {
+	objects := make([]fyne.CanvasObject, 0, 3)
+	if lm.sidebarTopSections != nil {
+		objects = append(objects, lm.sidebarTopSections)
+	}
+	if lm.sidebarContent != nil {
+		objects = append(objects, lm.sidebarContent)
+	}
+	if lm.sidebarSections != nil {
+		objects = append(objects, lm.sidebarSections)
+	}
+	lm.sidebarPanel.Objects = objects
 	lm.sidebarPanel.Refresh()
 }
 
@@ -361,4 +361,45 @@ func TestLayoutManagerCallbacks(t *testing.T) {
 	if lastPanelSize != 0.4 {
 		t.Errorf("面板大小應該為 0.4，實際為 %f", lastPanelSize)
 	}
-}
\ No newline at end of file
+}
+// TestLayoutManagerSidebarSections 測試側邊欄區段
+// 驗證新增的區段放在主要內容下方，之後更換主要內容時區段仍然保留
+func TestLayoutManagerSidebarSections(t *testing.T) {
+	layoutManager := NewLayoutManager()
+	
+	layoutManager.SetSidebarContent(container.NewVBox(widget.NewLabel("檔案樹")))
+	layoutManager.AddSidebarSection("標籤", widget.NewLabel("標籤瀏覽器"))
+	layoutManager.AddSidebarSection("其他", widget.NewLabel("其他區段"))
+	
+	if len(layoutManager.sidebarPanel.Objects) != 2 {
+		t.Fatalf("側邊欄應該包含主要內容和區段，實際為 %d 個物件", len(layoutManager.sidebarPanel.Objects))
+	}
+	if len(layoutManager.sidebarSections.Items) != 2 || !layoutManager.sidebarSections.Items[0].Open {
+		t.Error("應該有 2 個預設展開的區段")
+	}
+	
+	newContent := container.NewVBox(widget.NewLabel("新的檔案樹"))
+	layoutManager.SetSidebarContent(newContent)
+	if layoutManager.sidebarPanel.Objects[0] != newContent || layoutManager.sidebarPanel.Objects[1] != layoutManager.sidebarSections {
+		t.Error("更換主要內容後區段應該保留在下方")
+	}
+}
+
+// TestLayoutManagerSidebarTopSections 測試側邊欄頂端的區段
+// 驗證頂端區段放在主要內容上方，下方的區段維持在主要內容之後
+func TestLayoutManagerSidebarTopSections(t *testing.T) {
+	layoutManager := NewLayoutManager()
+	
+	sidebarContent := container.NewVBox(widget.NewLabel("檔案樹"))
+	layoutManager.SetSidebarContent(sidebarContent)
+	layoutManager.AddSidebarSection("標籤", widget.NewLabel("標籤瀏覽器"))
+	layoutManager.AddSidebarTopSection("最愛", widget.NewLabel("最愛列表"))
+	
+	objects := layoutManager.sidebarPanel.Objects
+	if len(objects) != 3 {
+		t.Fatalf("側邊欄應該包含 3 個物件，實際為 %d 個", len(objects))
+	}
+	if objects[0] != layoutManager.sidebarTopSections || objects[1] != sidebarContent || objects[2] != layoutManager.sidebarSections {
+		t.Error("最愛區段應該在主要內容上方，標籤區段在下方")
+	}
+}
@@ -3,10 +3,17 @@
 package ui
 
 import (
+	"errors"                   // 錯誤類型判斷
 	"fmt"                      // Go 標準庫，用於格式化字串
+	"log"                      // 復原日誌錯誤記錄
+	"os"                       // 金鑰檔讀寫
 	"path/filepath"            // 檔案路徑處理
+	"sort"                     // 標籤排序
+	"strconv"                  // 復原金鑰片段數解析
 	"strings"                  // 字串處理
+	"time"                     // 時間處理
 	"fyne.io/fyne/v2"          // Fyne GUI 框架核心套件
+	"fyne.io/fyne/v2/canvas"   // Fyne 畫布套件（QR Code 圖片）
 	"fyne.io/fyne/v2/container" // Fyne 容器佈局套件
 	"fyne.io/fyne/v2/widget"   // Fyne UI 元件套件
 	"fyne.io/fyne/v2/dialog"   // Fyne 對話框套件
@@ -49,6 +56,7 @@ type MainWindow struct {
 	statusBar    *fyne.Container  // 狀態欄容器
 	saveStatus   *widget.Label    // 保存狀態指示器
 	encStatus    *widget.Label    // 加密狀態指示器
+	sigStatus    *widget.Label    // 簽章狀態指示器
 	wordCount    *widget.Label    // 字數統計顯示
 	viewModeLabel *widget.Label   // 視圖模式指示器
 	
@@ -64,6 +72,20 @@ type MainWindow struct {
 	themeService     *services.ThemeService           // 主題管理服務
 	editorService    services.EditorService           // 編輯器服務
 	fileManagerService services.FileManagerService   // 檔案管理服務
+	rekeyService     services.RekeyService            // 批次重新加密服務
+	encryptedRepo    services.EncryptedFileRepository // 加密資料夾檔案儲存庫
+	recoveryJournal  services.RecoveryJournal         // 未保存編輯的復原日誌
+	fileWatcher      services.FileWatcherService      // 筆記本目錄監看服務
+	noteLocks        services.NoteLockService         // 筆記的跨程序建議鎖
+	lockedNotePath   string                           // 目前鎖定的筆記路徑
+	notebookDir      string                           // 筆記本目錄，用於解析啟動參數
+	noteIDs          services.NoteIDIndex             // 筆記 ID 索引，用於開啟以 ID 指定的 note:// 連結
+	tagService       services.TagService              // 標籤索引和批次標籤服務
+	tagBrowser       *TagBrowserWidget                // 側邊欄的標籤瀏覽器
+	favorites        services.FavoritesService        // 最愛和釘選服務
+	favoritesList    *FavoritesWidget                 // 側邊欄頂端的最愛列表
+	searchIndex      services.SearchIndex             // 全文搜尋索引
+	searchPanel      *SearchPanel                     // 筆記列表面板中的全文搜尋面板
 }
 
 // NewMainWindow 建立新的主視窗實例
@@ -112,6 +134,9 @@ func NewMainWindow(app fyne.App, settings *models.Settings, editorService servic
 	
 	// 初始化使用者介面元件
 	mw.setupUI()
+
+	// 設定加密工作階段的自動鎖定
+	mw.setupSessionLock()
 	
 	// 設定視窗關閉時的清理工作
 	window.SetCloseIntercept(func() {
@@ -172,11 +197,14 @@ func (mw *MainWindow) Show() {
 //
 // 執行流程：
 // 1. 顯示主視窗
-// 2. 啟動 Fyne 的事件迴圈
-// 3. 處理使用者互動事件
-// 4. 當視窗關閉時結束應用程式
+// 2. 上次未正常結束時，提示還原、比較或捨棄未保存的編輯
+// 3. 啟動 Fyne 的事件迴圈
+// 4. 處理使用者互動事件
+// 5. 當視窗關閉時結束應用程式
 func (mw *MainWindow) ShowAndRun() {
-	mw.window.ShowAndRun()
+	mw.window.Show()
+	mw.offerRecoveredEdits()
+	mw.app.Run()
 }
 
 // createMenuBar 建立應用程式的選單欄
@@ -204,6 +232,40 @@ func (mw *MainWindow) createMenuBar() {
 			mw.saveAsNewFile()
 		}),
 		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("鎖定保險庫", func() {
+			mw.lockVault()
+		}),
+		fyne.NewMenuItem("重新加密所有筆記...", func() {
+			mw.showRekeyDialog()
+		}),
+		fyne.NewMenuItem("第二驗證因素...", func() {
+			mw.showSecondFactorSettings()
+		}),
+		fyne.NewMenuItem("復原金鑰...", func() {
+			mw.showRecoverySettings()
+		}),
+		fyne.NewMenuItem("以復原金鑰重設密碼...", func() {
+			mw.showRecoverVaultDialog()
+		}),
+		fyne.NewMenuItem("分享筆記...", func() {
+			mw.showShareDialog()
+		}),
+		fyne.NewMenuItem("管理身分金鑰...", func() {
+			mw.showIdentityDialog()
+		}),
+		fyne.NewMenuItem("安全稽核記錄...", func() {
+			mw.showAuditLog()
+		}),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("簽署筆記", func() {
+			mw.signCurrentNote()
+		}),
+		fyne.NewMenuItem("驗證所有簽章...", func() {
+			mw.verifyAllSignatures()
+		}),
+		fyne.NewMenuItem("簽章金鑰...", func() {
+			mw.showSigningKeyDialog()
+		}),
 		fyne.NewMenuItem("設定", func() {
 			mw.showSettingsDialog()
 		}),
@@ -232,6 +294,13 @@ func (mw *MainWindow) createMenuBar() {
 			// TODO: 實作取代功能
 			fmt.Println("取代功能將在後續任務中實作")
 		}),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("插入機密區塊", func() {
+			mw.editor.InsertText("```" + services.SecretBlockLanguage + "\n\n```\n")
+		}),
+		fyne.NewMenuItem("顯示機密區塊", func() {
+			mw.revealSecretBlocks()
+		}),
 	)
 	
 	// 建立檢視選單項目
@@ -288,7 +357,7 @@ func (mw *MainWindow) setupEnhancedToolbar() {
 //
 // 執行流程：
 // 1. 建立保存狀態指示器
-// 2. 建立加密狀態指示器
+// 2. 建立加密和簽章狀態指示器
 // 3. 建立字數統計顯示
 // 4. 建立視圖模式指示器
 // 5. 使用水平佈局組合狀態欄元件
@@ -301,6 +370,10 @@ func (mw *MainWindow) createStatusBar() {
 	mw.encStatus = widget.NewLabel("未加密")
 	mw.encStatus.TextStyle = fyne.TextStyle{Italic: true}
 	
+	// 建立簽章狀態指示器（未設定簽章服務時不顯示文字）
+	mw.sigStatus = widget.NewLabel("")
+	mw.sigStatus.TextStyle = fyne.TextStyle{Italic: true}
+	
 	// 建立字數統計顯示
 	mw.wordCount = widget.NewLabel("字數: 0")
 	mw.wordCount.TextStyle = fyne.TextStyle{Italic: true}
@@ -320,6 +393,7 @@ func (mw *MainWindow) createStatusBar() {
 		mw.saveStatus,
 		separator1,
 		mw.encStatus,
+		mw.sigStatus,
 		separator2,
 		mw.viewModeLabel,
 		widget.NewLabel(""), // 彈性空間
@@ -361,6 +435,11 @@ func (mw *MainWindow) setupEditorCallbacks() {
 	mw.editor.SetOnContentChanged(func(content string) {
 		// 更新保存狀態為未保存
 		mw.UpdateSaveStatus("未保存")
+
+		// 記錄使用者活動，延後保險庫閒置自動鎖定
+		if vault := mw.editorService.Options().Vault; vault != nil {
+			vault.Session().Touch()
+		}
 		
 		// 檢查是否為加密筆記並更新加密狀態
 		if currentNote := mw.editor.GetCurrentNote(); currentNote != nil {
@@ -372,6 +451,7 @@ func (mw *MainWindow) setupEditorCallbacks() {
 	mw.editor.SetOnSaveRequested(func() {
 		// 更新保存狀態
 		mw.UpdateSaveStatus("已保存")
+		mw.refreshSignatureStatus()
 	})
 	
 	// 設定字數變更回調
@@ -545,6 +625,50 @@ func (mw *MainWindow) UpdateEncryptionStatus(isEncrypted bool, encType string) {
 		}
 		mw.encStatus.Refresh()
 	}
+	
+	// 加密狀態改變時（開啟、建立或切換筆記）一併更新簽章狀態
+	mw.refreshSignatureStatus()
+}
+
+// UpdateSignatureStatus 更新簽章狀態顯示
+// 參數：status（簽章狀態，nil 表示未設定簽章服務或沒有開啟的筆記）
+//
+// 執行流程：
+// 1. 沒有簽章狀態時清空指示器
+// 2. 顯示簽章狀態，簽章有效時附上簽署者名稱
+// 3. 內容遭修改時以警示色顯示
+func (mw *MainWindow) UpdateSignatureStatus(status *services.SignatureStatus) {
+	if mw.sigStatus == nil {
+		return
+	}
+
+	mw.sigStatus.Importance = widget.MediumImportance
+	switch {
+	case status == nil:
+		mw.sigStatus.SetText("")
+	case status.State == services.SignatureValid && status.SignerName != "":
+		mw.sigStatus.SetText(fmt.Sprintf("%s（%s）", status.State, status.SignerName))
+	default:
+		if status.State == services.SignatureTampered {
+			mw.sigStatus.Importance = widget.DangerImportance
+		}
+		mw.sigStatus.SetText(status.State.String())
+	}
+	mw.sigStatus.Refresh()
+}
+
+// refreshSignatureStatus 依當前筆記更新簽章狀態顯示
+func (mw *MainWindow) refreshSignatureStatus() {
+	if mw.editor == nil || mw.editorService == nil {
+		return
+	}
+
+	var status *services.SignatureStatus
+	signer, ok := mw.editorService.(services.NoteSigner)
+	if note := mw.editor.GetCurrentNote(); note != nil && ok {
+		status = signer.GetSignatureStatus(note.ID)
+	}
+	mw.UpdateSignatureStatus(status)
 }
 
 // UpdateWordCount 更新字數統計顯示
@@ -593,11 +717,22 @@ func (mw *MainWindow) showSettingsDialog() {
 //
 // 執行流程：
 // 1. 更新內部設定實例
-// 2. 套用主題變更
-// 3. 更新其他相關的 UI 元件
+// 2. 套用自動鎖定和隨機檔名設定
+// 3. 套用主題變更
+// 4. 更新其他相關的 UI 元件
 func (mw *MainWindow) onSettingsChanged(newSettings *models.Settings) {
 	// 更新內部設定
 	mw.settings = newSettings
+
+	// 套用工作階段自動鎖定設定
+	if vault := mw.editorService.Options().Vault; vault != nil {
+		vault.Session().ApplySettings(newSettings)
+	}
+
+	// 套用加密筆記隨機檔名設定，之後保存時生效
+	editorOptions := mw.editorService.Options()
+	editorOptions.ObfuscateFilenames = newSettings.ObfuscateFilenames
+	mw.editorService.SetOptions(editorOptions)
 	
 	// 如果主題有變更，套用新主題
 	if mw.themeService.GetCurrentTheme() != newSettings.Theme {
@@ -719,78 +854,1594 @@ func (mw *MainWindow) openFile() {
 	fileDialog.Show()
 }
 
-// openFileFromPath 從指定路徑開啟檔案
-// 參數：filePath（檔案路徑）
+// openFileFromPath 從指定路徑開啟檔案
+// 參數：filePath（檔案路徑）
+//
+// 執行流程：
+// 1. 使用編輯器服務開啟檔案
+// 2. 處理加密檔案的密碼驗證
+// 3. 載入檔案內容到編輯器
+// 4. 更新狀態顯示
+func (mw *MainWindow) openFileFromPath(filePath string) {
+	// 使用編輯器服務開啟檔案
+	note, err := mw.editorService.OpenNote(filePath)
+	if err != nil {
+		// 檢查是否為加密檔案需要密碼
+		if strings.Contains(err.Error(), "需要密碼驗證") {
+			mw.handleEncryptedFileOpen(filePath)
+			return
+		}
+		
+		dialog.ShowError(err, mw.window)
+		return
+	}
+	
+	// 載入筆記到編輯器
+	mw.editor.LoadNote(note)
+	mw.lockOpenedNote(note.FilePath)
+	
+	// 更新狀態顯示
+	mw.UpdateSaveStatus("已載入")
+	mw.UpdateEncryptionStatus(note.IsEncrypted, note.EncryptionType)
+	
+	// 重新整理檔案樹以反映變更
+	mw.refreshFileTree()
+}
+
+// handleEncryptedFileOpen 處理加密檔案的開啟
+// 參數：filePath（加密檔案路徑）
+//
+// 執行流程：
+// 1. 顯示密碼輸入對話框（保險庫啟用第二驗證因素時一併輸入金鑰檔和驗證碼）
+// 2. 信封格式以密碼解鎖保險庫，舊的密碼格式則直接以密碼解密
+// 3. 載入解密後的內容到編輯器
+func (mw *MainWindow) handleEncryptedFileOpen(filePath string) {
+	openWithPassword := func(password string, unlockErr error) error {
+		// 保險庫解鎖後，之後的開啟和保存都不需要再次輸入密碼
+		note, err := mw.editorService.OpenNoteWithPassword(filePath, password)
+		if err != nil {
+			// 信封格式的筆記顯示第二驗證因素的實際錯誤，而不是「需要金鑰檔」
+			if unlockErr != nil && services.IsSecondFactorRequired(err) {
+				err = unlockErr
+			}
+			return err
+		}
+
+		// 載入筆記到編輯器
+		mw.editor.LoadNote(note)
+		mw.lockOpenedNote(note.FilePath)
+
+		// 更新狀態顯示
+		mw.UpdateSaveStatus("已載入")
+		mw.UpdateEncryptionStatus(true, note.EncryptionType)
+		return nil
+	}
+
+	if vault := mw.editorService.Options().Vault; vault != nil && !vault.IsUnlocked() {
+		if factors := vault.SecondFactors(); factors.Any() {
+			mw.showVaultAuthDialog("請輸入密碼以開啟加密檔案", factors, func(result AuthResult) {
+				if err := openWithPassword(result.Password, vault.UnlockWithFactors(result.Password, result.UnlockFactors())); err != nil {
+					dialog.ShowError(fmt.Errorf("密碼錯誤或解密失敗: %w", err), mw.window)
+				}
+			})
+			return
+		}
+	}
+
+	// 建立密碼驗證對話框，錯誤次數過多時在對話框中顯示剩餘的等待時間
+	var verifyDialog *PasswordVerifyDialog
+	verifyDialog = NewPasswordVerifyDialog(mw.window, "請輸入密碼以開啟加密檔案", services.MaxRetryAttempts, func(result PasswordDialogResult) {
+		if !result.Confirmed {
+			return
+		}
+
+		err := openWithPassword(result.Password, nil)
+		if err == nil {
+			verifyDialog.Hide()
+			return
+		}
+
+		var lockErr *services.LockoutError
+		if errors.As(err, &lockErr) {
+			verifyDialog.SetLockout(lockErr.Remaining)
+			return
+		}
+		dialog.ShowError(fmt.Errorf("密碼錯誤或解密失敗: %w", err), mw.window)
+	})
+
+	// 顯示密碼對話框
+	verifyDialog.Show()
+}
+
+// showVaultAuthDialog 依保險庫的第二驗證因素顯示驗證對話框
+// 參數：message（提示訊息）、factors（保險庫啟用的第二驗證因素）、onConfirm（使用者確認後的回調）
+func (mw *MainWindow) showVaultAuthDialog(message string, factors services.VaultFactors, onConfirm func(AuthResult)) {
+	manager := NewAuthDialogManager(mw.window, false, AuthMethodForFactors(factors), false, 3)
+	manager.ShowAuthDialog("解鎖保險庫", message, func(result AuthResult) {
+		if result.Success {
+			onConfirm(result)
+		}
+	})
+}
+
+// ensureVaultUnlocked 確保保險庫已解鎖後再執行指定動作
+// 參數：action（保險庫解鎖後要執行的動作）
+//
+// 執行流程：
+// 1. 保險庫已解鎖時直接執行動作
+// 2. 保險庫尚未設定密碼時顯示密碼設定對話框並建立保險庫，選擇產生復原金鑰時顯示復原金鑰
+// 3. 保險庫已鎖定時顯示密碼輸入對話框並解鎖（啟用第二驗證因素時一併輸入）
+func (mw *MainWindow) ensureVaultUnlocked(action func()) {
+	vault := mw.editorService.Options().Vault
+	if vault == nil {
+		dialog.ShowError(fmt.Errorf("保險庫無法使用，無法處理加密筆記"), mw.window)
+		return
+	}
+
+	switch {
+	case vault.IsUnlocked():
+		action()
+	case !vault.IsInitialized():
+		setupDialog := NewPasswordSetupDialog(mw.window, "設定保險庫密碼", func(result PasswordDialogResult) {
+			if !result.Confirmed {
+				return
+			}
+			if err := vault.Initialize(result.Password); err != nil {
+				dialog.ShowError(fmt.Errorf("建立保險庫失敗: %w", err), mw.window)
+				return
+			}
+			if !result.GenerateRecovery {
+				action()
+				return
+			}
+			kit, err := vault.SetupRecovery(result.Password, result.RecoveryShares, result.RecoveryThreshold)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("產生復原金鑰失敗: %w", err), mw.window)
+				action()
+				return
+			}
+			mw.showRecoveryKit(kit, action)
+		})
+		setupDialog.EnableRecoveryOptions()
+		setupDialog.Show()
+	case vault.SecondFactors().Any():
+		mw.showVaultAuthDialog("請輸入保險庫密碼和第二驗證因素", vault.SecondFactors(), func(result AuthResult) {
+			if err := vault.UnlockWithFactors(result.Password, result.UnlockFactors()); err != nil {
+				dialog.ShowError(fmt.Errorf("解鎖保險庫失敗: %w", err), mw.window)
+				return
+			}
+			action()
+		})
+	default:
+		passwordDialog := NewPasswordDialog(mw.window, "請輸入保險庫密碼", func(password string) {
+			if err := vault.Unlock(password); err != nil {
+				dialog.ShowError(fmt.Errorf("解鎖保險庫失敗: %w", err), mw.window)
+				return
+			}
+			action()
+		})
+		passwordDialog.Show()
+	}
+}
+
+// toggleEncryption 切換當前筆記的加密狀態
+//
+// 執行流程：
+// 1. 檢查是否有當前筆記
+// 2. 啟用加密時先確保保險庫已解鎖
+// 3. 更新筆記的加密狀態和副檔名後立即保存
+// 4. SaveNote 保存成功後刪除舊路徑的檔案，停用加密時一併刪除保險庫資料金鑰
+func (mw *MainWindow) toggleEncryption() {
+	note := mw.editor.GetCurrentNote()
+	if note == nil {
+		dialog.ShowInformation("提示", "請先開啟或建立筆記", mw.window)
+		return
+	}
+
+	apply := func() {
+		oldPath := note.FilePath
+		oldType := note.EncryptionType
+
+		if note.IsEncrypted {
+			note.IsEncrypted = false
+			note.EncryptionType = ""
+			note.FilePath = services.DecryptedFilePath(oldPath, note.Title)
+		} else {
+			note.IsEncrypted = true
+			note.EncryptionType = mw.settings.DefaultEncryption
+			if oldPath != "" {
+				note.FilePath = oldPath + ".enc"
+			}
+		}
+
+		if err := mw.editorService.SaveNote(note); err != nil {
+			// 保存失敗時還原加密狀態
+			note.IsEncrypted = !note.IsEncrypted
+			note.EncryptionType = oldType
+			note.FilePath = oldPath
+			dialog.ShowError(fmt.Errorf("切換加密狀態失敗: %w", err), mw.window)
+			return
+		}
+
+		// 舊檔案和停用加密後不再需要的資料金鑰已由 SaveNote 在保存成功後刪除
+		mw.UpdateSaveStatus("已保存")
+		mw.UpdateEncryptionStatus(note.IsEncrypted, note.EncryptionType)
+		mw.refreshFileTree()
+	}
+
+	if note.IsEncrypted {
+		apply()
+		return
+	}
+	mw.ensureVaultUnlocked(apply)
+}
+
+// setupSessionLock 設定加密工作階段的自動鎖定
+// 工作階段鎖定時關閉編輯器中的加密筆記，視窗移到背景時依設定鎖定
+//
+// 執行流程：
+// 1. 將鎖定回調切換到 UI 執行緒執行
+// 2. 註冊鎖定監聽者，清空編輯器中已解密的內容
+// 3. 監聽應用程式離開前景事件，先保存加密筆記再鎖定
+func (mw *MainWindow) setupSessionLock() {
+	vault := mw.editorService.Options().Vault
+	if vault == nil {
+		return
+	}
+
+	session := vault.Session()
+	session.SetDispatcher(fyne.Do)
+	session.AddLockListener(func(reason services.SessionLockReason) {
+		mw.onSessionLocked(reason)
+	})
+
+	mw.app.Lifecycle().SetOnExitedForeground(func() {
+		if session.IsLockOnBlur() {
+			mw.saveEncryptedNoteBeforeLock()
+		}
+		session.HandleWindowBlur()
+	})
+}
+
+// lockVault 手動鎖定保險庫
+// 鎖定前先保存已修改的加密筆記，避免未保存的內容遺失
+func (mw *MainWindow) lockVault() {
+	vault := mw.editorService.Options().Vault
+	if vault == nil || !vault.IsUnlocked() {
+		dialog.ShowInformation("提示", "保險庫目前未解鎖", mw.window)
+		return
+	}
+
+	mw.saveEncryptedNoteBeforeLock()
+	vault.Lock()
+}
+
+// saveEncryptedNoteBeforeLock 在鎖定前保存已修改的加密筆記
+// 鎖定後金鑰會被清零，之後就無法再加密保存
+func (mw *MainWindow) saveEncryptedNoteBeforeLock() {
+	note := mw.editor.GetCurrentNote()
+	if note == nil || !note.IsEncrypted || !mw.editor.IsModified() {
+		return
+	}
+
+	if err := mw.editor.SaveNote(); err != nil {
+		fmt.Printf("鎖定前保存加密筆記失敗: %v\n", err)
+	}
+}
+
+// onSessionLocked 處理工作階段鎖定事件
+// 參數：reason（鎖定原因）
+//
+// 執行流程：
+// 1. 編輯器顯示加密筆記時清空編輯器和預覽內容（編輯器服務已關閉已解密的筆記），
+//    顯示未加密筆記時將其中的機密區塊換回密文
+// 2. 更新狀態欄和檔案樹中的加密筆記標題
+// 3. 非手動鎖定時提示使用者鎖定原因
+func (mw *MainWindow) onSessionLocked(reason services.SessionLockReason) {
+	if note := mw.editor.GetCurrentNote(); note != nil && note.IsEncrypted {
+		mw.editor.Clear()
+		if mw.editorWithPreview != nil {
+			mw.editorWithPreview.GetPreview().Clear()
+		}
+		mw.UpdateSaveStatus("已鎖定")
+		mw.UpdateEncryptionStatus(false, "")
+	} else if secrets, ok := mw.editorService.(services.SecretBlockEditor); ok && note != nil && services.HasSecretBlocks(mw.editor.GetContent()) {
+		// 未加密筆記中的機密區塊換回密文
+		mw.editor.SetContent(secrets.ConcealSecretBlocks(note.ID, mw.editor.GetContent()))
+	}
+
+	// 標題快取已清除，加密筆記改為顯示佔位文字
+	if mw.fileTreeWidget != nil {
+		mw.fileTreeWidget.RefreshTitles()
+	}
+
+	switch reason {
+	case services.SessionLockIdle:
+		dialog.ShowInformation("保險庫已鎖定", "閒置時間過長，保險庫已自動鎖定，加密筆記已關閉", mw.window)
+	case services.SessionLockBlur:
+		dialog.ShowInformation("保險庫已鎖定", "視窗移到背景，保險庫已自動鎖定，加密筆記已關閉", mw.window)
+	}
+}
+
+// revealSecretBlocks 解密當前筆記中的機密區塊
+// 保險庫鎖定時先要求解鎖，解密後的內容只顯示在編輯器中，保存時仍以密文寫入檔案
+func (mw *MainWindow) revealSecretBlocks() {
+	secrets, ok := mw.editorService.(services.SecretBlockEditor)
+	if !ok {
+		dialog.ShowError(fmt.Errorf("編輯器服務不支援機密區塊"), mw.window)
+		return
+	}
+
+	note := mw.editor.GetCurrentNote()
+	if note == nil || !services.HasSecretBlocks(mw.editor.GetContent()) {
+		dialog.ShowInformation("機密區塊", "目前的筆記沒有機密區塊", mw.window)
+		return
+	}
+
+	mw.ensureVaultUnlocked(func() {
+		content, err := secrets.RevealSecretBlocks(note.ID, mw.editor.GetContent())
+		if err != nil {
+			dialog.ShowError(err, mw.window)
+			return
+		}
+		mw.editor.SetContent(content)
+	})
+}
+
+// SetRekeyService 設定批次重新加密服務
+// 參數：rekeyService（批次重新加密服務）
+func (mw *MainWindow) SetRekeyService(rekeyService services.RekeyService) {
+	mw.rekeyService = rekeyService
+}
+
+// SetEncryptedFileRepository 設定加密資料夾檔案儲存庫
+// 參數：encryptedRepo（加密資料夾檔案儲存庫）
+func (mw *MainWindow) SetEncryptedFileRepository(encryptedRepo services.EncryptedFileRepository) {
+	mw.encryptedRepo = encryptedRepo
+}
+
+// SetRecoveryJournal 設定未保存編輯的復原日誌，編輯器內容變更時記錄到復原日誌
+// 參數：journal（復原日誌）
+func (mw *MainWindow) SetRecoveryJournal(journal services.RecoveryJournal) {
+	mw.recoveryJournal = journal
+	mw.editor.SetRecoveryJournal(journal)
+}
+
+// SetFileWatcher 設定筆記本目錄監看服務，外部變更時更新檔案樹和開啟中的筆記
+// 參數：watcher（目錄監看服務）
+func (mw *MainWindow) SetFileWatcher(watcher services.FileWatcherService) {
+	mw.fileWatcher = watcher
+	watcher.Subscribe(func(events []services.FileChangeEvent) {
+		fyne.Do(func() {
+			mw.handleFileChanges(events)
+		})
+	})
+}
+
+// handleFileChanges 處理筆記本目錄中的外部變更
+// 參數：events（檔案變更）
+//
+// 執行流程：
+// 1. 更新檔案樹中變更的節點
+// 2. 沒有未保存編輯的開啟中筆記重新載入磁碟上的版本
+// 3. 有未保存編輯或檔案已被刪除時只提示，保存時再由使用者選擇處理方式
+func (mw *MainWindow) handleFileChanges(events []services.FileChangeEvent) {
+	if mw.fileTreeWidget != nil {
+		mw.fileTreeWidget.ApplyFileChanges(events)
+	}
+
+	changes := make(map[string]services.FileChangeOp, len(events))
+	for _, event := range events {
+		changes[event.Path] = event.Op
+	}
+
+	current := mw.editor.GetCurrentNote()
+	for noteID, note := range mw.editorService.GetActiveNotes() {
+		op, changed := changes[note.FilePath]
+		if !changed || note.FilePath == "" {
+			continue
+		}
+		isCurrent := current != nil && current.ID == noteID
+		if op == services.FileRemoved {
+			if isCurrent {
+				mw.UpdateSaveStatus("檔案已在外部刪除或移動")
+			}
+			continue
+		}
+		if isCurrent && mw.editor.IsModified() {
+			mw.UpdateSaveStatus("檔案已在外部變更，保存時可以選擇合併")
+			continue
+		}
+
+		reloaded, err := mw.editorService.ReloadNote(noteID)
+		if err != nil {
+			log.Printf("重新載入 %s 失敗: %v", note.FilePath, err)
+			continue
+		}
+		if reloaded && isCurrent {
+			mw.editor.LoadNote(note)
+			mw.UpdateSaveStatus("已重新載入外部變更")
+		}
+	}
+}
+
+// SetNotebookDir 設定筆記本目錄，用於將啟動參數中的檔案路徑轉為筆記路徑
+// 參數：dir（筆記本目錄）
+func (mw *MainWindow) SetNotebookDir(dir string) {
+	mw.notebookDir = dir
+}
+
+// SetNoteLockService 設定筆記的跨程序建議鎖，開啟已在其他程式中開啟的筆記時提醒使用者
+// 參數：locks（筆記建議鎖服務）
+func (mw *MainWindow) SetNoteLockService(locks services.NoteLockService) {
+	mw.noteLocks = locks
+}
+
+// SetNoteIDIndex 設定筆記 ID 索引，note:// 連結可以用筆記 ID 取代檔案路徑，重新命名或移動後連結仍然有效
+// 參數：index（筆記 ID 索引）
+func (mw *MainWindow) SetNoteIDIndex(index services.NoteIDIndex) {
+	mw.noteIDs = index
+}
+
+// SetTagService 設定標籤服務，在側邊欄加入標籤瀏覽器並啟用批次標籤
+// 參數：tags（標籤服務）
+//
+// 執行流程：
+// 1. 建立標籤瀏覽器並加入側邊欄
+// 2. 點選筆記時開啟筆記，重新命名時顯示重新命名對話框
+// 3. 標籤索引變更時在 UI 執行緒重新載入標籤瀏覽器
+func (mw *MainWindow) SetTagService(tags services.TagService) {
+	mw.tagService = tags
+	mw.tagBrowser = NewTagBrowserWidget(tags)
+	mw.tagBrowser.SetOnNoteOpen(mw.openFileFromPath)
+	mw.tagBrowser.SetOnRenameTag(mw.showRenameTagDialog)
+	mw.layoutManager.AddSidebarSection("標籤", mw.tagBrowser)
+	
+	tags.Subscribe(func() {
+		fyne.Do(mw.tagBrowser.ReloadTags)
+	})
+}
+
+// SetFavoritesService 設定最愛和釘選服務，在側邊欄頂端加入最愛列表
+// 參數：favorites（最愛和釘選服務）
+//
+// 執行流程：
+// 1. 建立最愛列表並加入側邊欄頂端
+// 2. 點選最愛時開啟筆記或在檔案樹中展開資料夾
+// 3. 最愛變更時（包含重新命名和刪除）在 UI 執行緒重新載入最愛列表
+func (mw *MainWindow) SetFavoritesService(favorites services.FavoritesService) {
+	mw.favorites = favorites
+	mw.favoritesList = NewFavoritesWidget(favorites, mw.notebookDir)
+	if titles, ok := mw.editorService.(services.EncryptedTitleProvider); ok {
+		mw.favoritesList.SetTitleResolver(titles.NoteDisplayTitle)
+	}
+	mw.favoritesList.SetOnOpen(mw.openFavorite)
+	mw.layoutManager.AddSidebarTopSection("最愛", mw.favoritesList)
+	
+	favorites.Subscribe(func() {
+		fyne.Do(mw.favoritesList.ReloadFavorites)
+	})
+}
+
+// SetSearchIndex 設定全文搜尋索引，以搜尋面板取代筆記列表面板的佔位內容
+// 參數：index（全文搜尋索引）
+func (mw *MainWindow) SetSearchIndex(index services.SearchIndex) {
+	mw.searchIndex = index
+	mw.searchPanel = NewSearchPanel(index)
+	mw.searchPanel.SetOnResultOpen(mw.openSearchResult)
+	mw.layoutManager.SetNoteListContent(mw.searchPanel.GetContainer())
+}
+
+// SetInstanceLock 設定單一實例鎖定，接收之後啟動的程式轉交的檔案路徑和 note:// 連結
+// 參數：lock（單一實例鎖定）
+func (mw *MainWindow) SetInstanceLock(lock services.InstanceLock) {
+	err := lock.Listen(func(args []string) {
+		fyne.Do(func() {
+			mw.OpenLaunchArguments(args)
+		})
+	})
+	if err != nil {
+		log.Printf("無法接收其他程式轉交的參數: %v", err)
+	}
+}
+
+// OpenLaunchArguments 開啟命令列參數指定的筆記
+// 參數：args（檔案路徑或 note:// 連結）
+//
+// 執行流程：
+// 1. 將視窗帶到最前面
+// 2. 解析參數，無法開啟的參數顯示錯誤
+// 3. 依序開啟筆記，連結指定標題時將游標移到該標題
+func (mw *MainWindow) OpenLaunchArguments(args []string) {
+	mw.window.RequestFocus()
+	
+	links, err := services.ResolveLaunchArguments(mw.notebookDir, args)
+	if err != nil {
+		dialog.ShowError(err, mw.window)
+	}
+	for _, link := range links {
+		mw.openNoteLink(link)
+	}
+}
+
+// openNoteLink 開啟連結指定的筆記和標題
+// 參數：link（筆記連結，路徑也可以是筆記 ID）
+func (mw *MainWindow) openNoteLink(link *services.NoteLink) {
+	// 連結的路徑不存在時視為筆記 ID，開啟該筆記目前的檔案
+	if mw.noteIDs != nil {
+		if _, err := os.Stat(filepath.Join(mw.notebookDir, link.Path)); os.IsNotExist(err) {
+			if path, ok := mw.noteIDs.Resolve(link.Path); ok {
+				link = &services.NoteLink{Path: filepath.Clean(path), Heading: link.Heading}
+			}
+		}
+	}
+	mw.openFileFromPath(link.Path)
+	if link.Heading == "" {
+		return
+	}
+	
+	// 加密筆記需要先輸入密碼，開啟後不會自動跳到標題
+	note := mw.editor.GetCurrentNote()
+	if note == nil || filepath.Clean(note.FilePath) != link.Path {
+		return
+	}
+	if !mw.editor.GoToHeading(link.Heading) {
+		mw.UpdateSaveStatus(fmt.Sprintf("找不到標題：%s", link.Heading))
+	}
+}
+
+// lockOpenedNote 鎖定編輯器中開啟的筆記並釋放前一個筆記的鎖定，已在其他程式中開啟時提醒使用者
+// 參數：filePath（筆記路徑）
+func (mw *MainWindow) lockOpenedNote(filePath string) {
+	if mw.noteLocks == nil || filePath == mw.lockedNotePath {
+		return
+	}
+	if mw.lockedNotePath != "" {
+		mw.noteLocks.Release(mw.lockedNotePath)
+	}
+	mw.lockedNotePath = filePath
+	
+	owner, err := mw.noteLocks.Acquire(filePath)
+	if err != nil {
+		log.Printf("鎖定筆記失敗: %v", err)
+		return
+	}
+	if owner == nil {
+		return
+	}
+	
+	holder := "其他程式"
+	if owner.PID > 0 {
+		holder = fmt.Sprintf("程序 %d", owner.PID)
+		if owner.Hostname != "" {
+			holder = fmt.Sprintf("%s 上的程序 %d", owner.Hostname, owner.PID)
+		}
+	}
+	message := fmt.Sprintf("「%s」已由%s開啟。\n同時編輯可能互相覆寫，保存時若偵測到外部變更會讓您選擇處理方式。", filepath.Base(filePath), holder)
+	if !owner.Since.IsZero() {
+		message += fmt.Sprintf("\n\n開啟時間：%s", owner.Since.Format("2006-01-02 15:04:05"))
+	}
+	dialog.ShowInformation("筆記已在其他程式中開啟", message, mw.window)
+}
+
+// offerRecoveredEdits 逐一提示復原日誌中未保存的編輯
+func (mw *MainWindow) offerRecoveredEdits() {
+	if mw.recoveryJournal == nil {
+		return
+	}
+	entries, err := mw.recoveryJournal.Pending()
+	if err != nil {
+		log.Printf("讀取復原日誌失敗: %v", err)
+		return
+	}
+	mw.showRecoveredEdit(entries, 0)
+}
+
+// showRecoveredEdit 顯示一筆未保存編輯的處理選項
+// 參數：entries（復原記錄）、index（目前處理的記錄索引）
+//
+// 執行流程：
+// 1. 顯示筆記名稱和記錄時間，加密內容提示需要解鎖保險庫
+// 2. 還原：開啟筆記並以復原內容取代編輯器內容
+// 3. 比較差異：顯示與已保存內容的差異後回到此對話框
+// 4. 捨棄：刪除復原記錄；稍後：保留到下次啟動
+func (mw *MainWindow) showRecoveredEdit(entries []*services.RecoveryEntry, index int) {
+	if index >= len(entries) {
+		return
+	}
+	entry := entries[index]
+	next := func() { mw.showRecoveredEdit(entries, index+1) }
+
+	info := container.NewVBox(
+		widget.NewLabel(fmt.Sprintf("「%s」有上次未保存的編輯（%d/%d）", entry.DisplayName(), index+1, len(entries))),
+		widget.NewLabel(fmt.Sprintf("記錄時間：%s", entry.UpdatedAt.Local().Format("2006-01-02 15:04:05"))),
+	)
+	if entry.Encrypted {
+		info.Add(widget.NewLabel("內容已加密，需要先解鎖保險庫"))
+	}
+
+	var recoveredDialog dialog.Dialog
+	restoreButton := widget.NewButton("還原", func() {
+		recoveredDialog.Hide()
+		mw.withRecoveredEditContent(entry, func(content string) {
+			mw.restoreRecoveredEdit(entry, content)
+			next()
+		})
+	})
+	restoreButton.Importance = widget.HighImportance
+	diffButton := widget.NewButton("比較差異", func() {
+		recoveredDialog.Hide()
+		mw.withRecoveredEditContent(entry, func(content string) {
+			mw.showRecoveredEditDiff(entry, content, func() { mw.showRecoveredEdit(entries, index) })
+		})
+	})
+	discardButton := widget.NewButton("捨棄", func() {
+		recoveredDialog.Hide()
+		if err := mw.recoveryJournal.Discard(entry.NoteID); err != nil {
+			dialog.ShowError(fmt.Errorf("捨棄復原記錄失敗: %w", err), mw.window)
+		}
+		next()
+	})
+	laterButton := widget.NewButton("稍後再說", func() {
+		recoveredDialog.Hide()
+		next()
+	})
+
+	content := container.NewVBox(info, container.NewHBox(restoreButton, diffButton, discardButton, laterButton))
+	recoveredDialog = dialog.NewCustomWithoutButtons("復原未保存的編輯", content, mw.window)
+	recoveredDialog.Show()
+}
+
+// withRecoveredEditContent 取得復原內容後執行動作，加密內容先確認保險庫已解鎖
+// 參數：entry（復原記錄）、action（取得內容後執行的動作）
+func (mw *MainWindow) withRecoveredEditContent(entry *services.RecoveryEntry, action func(content string)) {
+	load := func() {
+		content, err := mw.recoveryJournal.Content(entry)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("讀取復原內容失敗: %w", err), mw.window)
+			return
+		}
+		action(content)
+	}
+	if entry.Encrypted {
+		mw.ensureVaultUnlocked(load)
+		return
+	}
+	load()
+}
+
+// restoreRecoveredEdit 開啟筆記並以復原內容取代編輯器內容
+// 參數：entry（復原記錄）、content（復原內容）
+//
+// 執行流程：
+// 1. 開啟記錄中的筆記，尚未保存過或已不存在的筆記以新筆記還原
+// 2. 以復原內容取代編輯器內容，視為尚未保存的修改
+// 3. 刪除舊的復原記錄（還原後的內容會以新的筆記 ID 重新記錄）
+func (mw *MainWindow) restoreRecoveredEdit(entry *services.RecoveryEntry, content string) {
+	var note *models.Note
+	var err error
+	if entry.FilePath != "" {
+		note, err = mw.editorService.OpenNote(entry.FilePath)
+		if err != nil {
+			log.Printf("開啟 %s 失敗，以新筆記還原: %v", entry.FilePath, err)
+		}
+	}
+	if note == nil {
+		note, err = mw.editorService.CreateNote(entry.DisplayName(), "")
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("還原未保存的編輯失敗: %w", err), mw.window)
+			return
+		}
+	}
+
+	mw.editor.LoadNote(note)
+	mw.editor.RestoreContent(content)
+	mw.UpdateSaveStatus("已還原未保存的編輯")
+	mw.UpdateEncryptionStatus(note.IsEncrypted, note.EncryptionType)
+
+	if err := mw.recoveryJournal.Discard(entry.NoteID); err != nil {
+		log.Printf("移除復原記錄失敗: %v", err)
+	}
+}
+
+// showRecoveredEditDiff 顯示復原內容與已保存內容的差異
+// 參數：entry（復原記錄）、content（復原內容）、onClose（關閉後執行的動作）
+func (mw *MainWindow) showRecoveredEditDiff(entry *services.RecoveryEntry, content string, onClose func()) {
+	saved := ""
+	if entry.FilePath != "" {
+		if note, err := mw.editorService.OpenNote(entry.FilePath); err == nil {
+			saved = note.Content
+			mw.editorService.CloseNote(note.ID)
+		}
+	}
+
+	diffLabel := widget.NewLabelWithStyle(services.FormatDiff(services.DiffLines(saved, content)), fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+	scroll := container.NewScroll(diffLabel)
+	scroll.SetMinSize(fyne.NewSize(640, 400))
+
+	diffContent := container.NewBorder(widget.NewLabel("「- 」為已保存的內容，「+ 」為未保存的編輯"), nil, nil, nil, scroll)
+	diffDialog := dialog.NewCustom(fmt.Sprintf("比較差異：%s", entry.DisplayName()), "返回", diffContent, mw.window)
+	diffDialog.SetOnClosed(onClose)
+	diffDialog.Show()
+}
+
+// showShareDialog 顯示當前筆記的分享對話框
+// 收件人公鑰加密會產生只有收件人能以身分私鑰開啟的檔案
+func (mw *MainWindow) showShareDialog() {
+	note := mw.editor.GetCurrentNote()
+	if note == nil {
+		dialog.ShowInformation("提示", "請先開啟或建立筆記", mw.window)
+		return
+	}
+
+	NewShareDialog(mw.window, services.NewExportService(mw.editorService), note).Show()
+}
+
+// showIdentityDialog 顯示身分金鑰管理對話框
+//
+// 執行流程：
+// 1. 列出所有身分和可提供給其他人的公鑰
+// 2. 提供複製公鑰和刪除身分的按鈕
+// 3. 輸入名稱和金鑰庫密碼建立新的身分（首次建立時同時設定金鑰庫密碼）
+func (mw *MainWindow) showIdentityDialog() {
+	identitySvc := mw.editorService.Options().Identity
+	if identitySvc == nil {
+		dialog.ShowError(fmt.Errorf("身分金鑰庫無法使用"), mw.window)
+		return
+	}
+
+	var identityDialog dialog.Dialog
+	list := container.NewVBox()
+	for _, identity := range identitySvc.ListIdentities() {
+		identity := identity
+		keyEntry := widget.NewEntry()
+		keyEntry.SetText(identity.PublicKey)
+		list.Add(container.NewBorder(nil, nil, widget.NewLabel(identity.Name),
+			container.NewHBox(
+				widget.NewButton("複製", func() {
+					mw.window.Clipboard().SetContent(identity.PublicKey)
+				}),
+				widget.NewButton("刪除", func() {
+					dialog.ShowConfirm("刪除身分", fmt.Sprintf("刪除後將無法開啟加密給「%s」的檔案，確定要刪除嗎？", identity.Name), func(confirmed bool) {
+						if !confirmed {
+							return
+						}
+						if err := identitySvc.DeleteIdentity(identity.ID); err != nil {
+							dialog.ShowError(fmt.Errorf("刪除身分失敗: %w", err), mw.window)
+							return
+						}
+						identityDialog.Hide()
+						mw.showIdentityDialog()
+					}, mw.window)
+				}),
+			),
+			keyEntry,
+		))
+	}
+	if len(list.Objects) == 0 {
+		list.Add(widget.NewLabel("尚未建立任何身分"))
+	}
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("身分名稱，例如：工作")
+	passwordEntry := widget.NewPasswordEntry()
+	passwordEntry.SetPlaceHolder("金鑰庫密碼")
+	createButton := widget.NewButton("建立身分", func() {
+		if _, err := identitySvc.CreateIdentity(nameEntry.Text, passwordEntry.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("建立身分失敗: %w", err), mw.window)
+			return
+		}
+		identityDialog.Hide()
+		mw.showIdentityDialog()
+	})
+
+	content := container.NewVBox(
+		widget.NewLabel("將公鑰提供給團隊成員，他們即可加密筆記給您"),
+		list,
+		widget.NewSeparator(),
+		widget.NewForm(
+			widget.NewFormItem("名稱", nameEntry),
+			widget.NewFormItem("密碼", passwordEntry),
+		),
+		createButton,
+	)
+
+	identityDialog = dialog.NewCustom("身分金鑰", "關閉", container.NewVScroll(content), mw.window)
+	identityDialog.Resize(fyne.NewSize(560, 420))
+	identityDialog.Show()
+}
+
+// signCurrentNote 以本機簽章金鑰簽署當前筆記
+//
+// 執行流程：
+// 1. 確認簽章服務可用，尚未建立簽章金鑰時先產生
+// 2. 先保存筆記，確保簽章與檔案內容相符
+// 3. 簽署並更新狀態列
+func (mw *MainWindow) signCurrentNote() {
+	signingSvc := mw.editorService.Options().Signing
+	signer, ok := mw.editorService.(services.NoteSigner)
+	if signingSvc == nil || !ok {
+		dialog.ShowError(fmt.Errorf("簽章服務無法使用"), mw.window)
+		return
+	}
+
+	note := mw.editor.GetCurrentNote()
+	if note == nil {
+		dialog.ShowInformation("提示", "請先開啟或建立筆記", mw.window)
+		return
+	}
+
+	if !signingSvc.HasSigningKey() {
+		if _, err := signingSvc.GenerateSigningKey(); err != nil {
+			dialog.ShowError(fmt.Errorf("產生簽章金鑰失敗: %w", err), mw.window)
+			return
+		}
+	}
+
+	if err := mw.editor.SaveNote(); err != nil {
+		dialog.ShowError(fmt.Errorf("簽署前保存筆記失敗: %w", err), mw.window)
+		return
+	}
+
+	status, err := signer.SignNote(note.ID)
+	if err != nil {
+		dialog.ShowError(err, mw.window)
+		return
+	}
+
+	mw.UpdateSaveStatus("已簽署")
+	mw.UpdateSignatureStatus(status)
+}
+
+// verifyAllSignatures 驗證筆記本中所有筆記的簽章並顯示報告
+// 保險庫已解鎖時一併驗證保險庫加密的筆記，其他加密筆記標示為無法驗證
+func (mw *MainWindow) verifyAllSignatures() {
+	signingSvc := mw.editorService.Options().Signing
+	if signingSvc == nil {
+		dialog.ShowError(fmt.Errorf("簽章服務無法使用"), mw.window)
+		return
+	}
+
+	var loader services.NoteContentLoader
+	if vault := mw.editorService.Options().Vault; vault != nil && vault.IsUnlocked() {
+		loader = func(filePath string, data []byte) (string, error) {
+			if !vault.IsVaultData(data) {
+				return "", fmt.Errorf("需要筆記密碼才能驗證")
+			}
+			content, _, err := vault.DecryptNote(data)
+			return content, err
+		}
+	}
+
+	progress := dialog.NewCustomWithoutButtons("驗證簽章中", widget.NewProgressBarInfinite(), mw.window)
+	progress.Show()
+
+	go func() {
+		report, err := signingSvc.VerifyAll("", loader)
+
+		fyne.Do(func() {
+			progress.Hide()
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("驗證簽章失敗: %w", err), mw.window)
+				return
+			}
+			mw.showSignatureReport(report)
+		})
+	}()
+}
+
+// showSignatureReport 顯示簽章驗證報告
+// 參數：report（驗證報告）
+// 只列出已簽署的筆記，未簽署的筆記只顯示數量
+func (mw *MainWindow) showSignatureReport(report *services.SignatureReport) {
+	summary := fmt.Sprintf("有效 %d、未受信任 %d、已遭修改 %d、無法驗證 %d、未簽署 %d",
+		report.Counts[services.SignatureValid],
+		report.Counts[services.SignatureUntrusted],
+		report.Counts[services.SignatureTampered],
+		report.Counts[services.SignatureUnverified],
+		report.Counts[services.SignatureUnsigned])
+
+	var details strings.Builder
+	for _, entry := range report.Entries {
+		if entry.Status.State == services.SignatureUnsigned {
+			continue
+		}
+		details.WriteString(fmt.Sprintf("%s：%s", mw.reportDisplayName(entry.Path), entry.Status.State))
+		if entry.Status.SignerName != "" {
+			details.WriteString(fmt.Sprintf("（%s）", entry.Status.SignerName))
+		}
+		if entry.Status.Message != "" {
+			details.WriteString(fmt.Sprintf(" - %s", entry.Status.Message))
+		}
+		details.WriteString("\n")
+	}
+	if details.Len() == 0 {
+		details.WriteString("沒有已簽署的筆記")
+	}
+
+	detailLabel := widget.NewLabel(details.String())
+	detailLabel.Wrapping = fyne.TextWrapWord
+	content := container.NewBorder(widget.NewLabel(summary), nil, nil, nil, container.NewVScroll(detailLabel))
+
+	reportDialog := dialog.NewCustom("簽章驗證報告", "關閉", content, mw.window)
+	reportDialog.Resize(fyne.NewSize(560, 400))
+	reportDialog.Show()
+}
+
+// reportDisplayName 取得報告中顯示的筆記名稱，隨機檔名的加密筆記顯示真實標題
+// 參數：filePath（筆記檔案路徑）
+// 回傳：顯示名稱
+func (mw *MainWindow) reportDisplayName(filePath string) string {
+	titles, ok := mw.editorService.(services.EncryptedTitleProvider)
+	if !ok {
+		return filePath
+	}
+	if title, ok := titles.NoteDisplayTitle(filePath); ok {
+		return fmt.Sprintf("%s（%s）", title, filePath)
+	}
+	return filePath
+}
+
+// showAuditLog 顯示安全稽核記錄和雜湊鏈的驗證結果
+// 最新的記錄顯示在最上方，記錄被修改或截斷時在摘要中列出問題
+func (mw *MainWindow) showAuditLog() {
+	audit := mw.editorService.Options().Audit
+	if audit == nil {
+		dialog.ShowError(fmt.Errorf("稽核記錄服務無法使用"), mw.window)
+		return
+	}
+
+	result, err := audit.Verify()
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("驗證稽核記錄失敗: %w", err), mw.window)
+		return
+	}
+	entries, err := audit.Entries()
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("讀取稽核記錄失敗: %w", err), mw.window)
+		return
+	}
+
+	summary := fmt.Sprintf("共 %d 筆記錄，雜湊鏈完整", result.Entries)
+	if !result.Valid {
+		summary = fmt.Sprintf("共 %d 筆記錄，偵測到修改或截斷：\n%s", result.Entries, strings.Join(result.Problems, "\n"))
+	} else if !result.Anchored && result.Entries > 0 {
+		summary += "（金鑰庫中沒有錨點，無法確認是否被截斷）"
+	}
+
+	var details strings.Builder
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		outcome := "成功"
+		if !entry.Success {
+			outcome = "失敗"
+		}
+		details.WriteString(fmt.Sprintf("#%d %s %s %s：%s",
+			entry.Seq, entry.Time.Format("2006-01-02 15:04:05"), entry.Actor, entry.Event, outcome))
+		if entry.Target != "" {
+			details.WriteString(fmt.Sprintf(" - %s", mw.reportDisplayName(entry.Target)))
+		}
+		if entry.Detail != "" {
+			details.WriteString(fmt.Sprintf("（%s）", entry.Detail))
+		}
+		details.WriteString("\n")
+	}
+	if details.Len() == 0 {
+		details.WriteString("尚無稽核記錄")
+	}
+
+	summaryLabel := widget.NewLabel(summary)
+	summaryLabel.Wrapping = fyne.TextWrapWord
+	detailLabel := widget.NewLabel(details.String())
+	detailLabel.Wrapping = fyne.TextWrapWord
+	content := container.NewBorder(summaryLabel, nil, nil, nil, container.NewVScroll(detailLabel))
+
+	auditDialog := dialog.NewCustom("安全稽核記錄", "關閉", content, mw.window)
+	auditDialog.Resize(fyne.NewSize(640, 460))
+	auditDialog.Show()
+}
+
+// showSigningKeyDialog 顯示簽章金鑰對話框
+//
+// 執行流程：
+// 1. 顯示本機簽章公鑰（尚未建立時提供產生按鈕）
+// 2. 列出受信任的簽署者並提供移除按鈕
+// 3. 輸入名稱和公鑰將團隊成員加入受信任的簽署者
+func (mw *MainWindow) showSigningKeyDialog() {
+	signingSvc := mw.editorService.Options().Signing
+	if signingSvc == nil {
+		dialog.ShowError(fmt.Errorf("簽章服務無法使用"), mw.window)
+		return
+	}
+
+	var keyDialog dialog.Dialog
+	reopen := func() {
+		keyDialog.Hide()
+		mw.showSigningKeyDialog()
+	}
+
+	var ownSection fyne.CanvasObject
+	if publicKey, err := signingSvc.PublicKey(); err == nil {
+		keyEntry := widget.NewEntry()
+		keyEntry.SetText(publicKey)
+		ownSection = container.NewBorder(nil, nil, widget.NewLabel("我的簽章公鑰"),
+			widget.NewButton("複製", func() {
+				mw.window.Clipboard().SetContent(publicKey)
+			}), keyEntry)
+	} else {
+		ownSection = widget.NewButton("產生簽章金鑰", func() {
+			if _, err := signingSvc.GenerateSigningKey(); err != nil {
+				dialog.ShowError(fmt.Errorf("產生簽章金鑰失敗: %w", err), mw.window)
+				return
+			}
+			reopen()
+		})
+	}
+
+	trusted := container.NewVBox()
+	for _, signer := range signingSvc.ListTrustedSigners() {
+		signer := signer
+		trusted.Add(container.NewBorder(nil, nil, widget.NewLabel(signer.Name),
+			widget.NewButton("移除", func() {
+				if err := signingSvc.UntrustSigner(signer.PublicKey); err != nil {
+					dialog.ShowError(err, mw.window)
+					return
+				}
+				reopen()
+			}),
+			widget.NewLabel(signer.PublicKey)))
+	}
+	if len(trusted.Objects) == 0 {
+		trusted.Add(widget.NewLabel("尚未加入受信任的簽署者"))
+	}
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("簽署者名稱")
+	keyEntry := widget.NewEntry()
+	keyEntry.SetPlaceHolder(services.SigningKeyPrefix + "...")
+	addButton := widget.NewButton("加入受信任的簽署者", func() {
+		if err := signingSvc.TrustSigner(nameEntry.Text, keyEntry.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("加入簽署者失敗: %w", err), mw.window)
+			return
+		}
+		reopen()
+	})
+
+	content := container.NewVBox(
+		ownSection,
+		widget.NewSeparator(),
+		widget.NewLabel("受信任的簽署者"),
+		trusted,
+		widget.NewForm(
+			widget.NewFormItem("名稱", nameEntry),
+			widget.NewFormItem("公鑰", keyEntry),
+		),
+		addButton,
+	)
+
+	keyDialog = dialog.NewCustom("簽章金鑰", "關閉", container.NewVScroll(content), mw.window)
+	keyDialog.Resize(fyne.NewSize(560, 420))
+	keyDialog.Show()
+}
+
+// showSecondFactorSettings 顯示保險庫第二驗證因素的設定對話框
+//
+// 執行流程：
+// 1. 確保保險庫已解鎖，顯示金鑰檔和 TOTP 目前的狀態
+// 2. 產生新的金鑰檔或選擇現有檔案，輸入保險庫密碼後設定
+// 3. 產生 TOTP 秘密供驗證器應用程式掃描，輸入驗證碼確認後啟用
+// 4. 輸入保險庫密碼後移除金鑰檔或停用 TOTP
+func (mw *MainWindow) showSecondFactorSettings() {
+	vault := mw.editorService.Options().Vault
+	if vault == nil {
+		dialog.ShowError(fmt.Errorf("保險庫無法使用"), mw.window)
+		return
+	}
+
+	mw.ensureVaultUnlocked(func() {
+		var factorDialog dialog.Dialog
+		done := func(err error, action string) {
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("%s失敗: %w", action, err), mw.window)
+				return
+			}
+			factorDialog.Hide()
+			mw.showSecondFactorSettings()
+		}
+
+		factors := vault.SecondFactors()
+		status := func(enabled bool) string {
+			if enabled {
+				return "已啟用"
+			}
+			return "未啟用"
+		}
+
+		passwordEntry := widget.NewPasswordEntry()
+		passwordEntry.SetPlaceHolder("保險庫密碼")
+
+		generateButton := widget.NewButton("產生金鑰檔...", func() {
+			saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+				if err != nil || writer == nil {
+					return
+				}
+				path := writer.URI().Path()
+				writer.Close()
+
+				keyfile, err := services.GenerateKeyfile()
+				if err == nil {
+					err = os.WriteFile(path, keyfile, 0600)
+				}
+				if err == nil {
+					err = vault.EnableKeyfile(passwordEntry.Text, keyfile)
+				}
+				done(err, "設定金鑰檔")
+			}, mw.window)
+			saveDialog.SetFileName("notebook.keyfile")
+			saveDialog.Show()
+		})
+		chooseButton := widget.NewButton("使用現有檔案...", func() {
+			dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+				if err != nil || reader == nil {
+					return
+				}
+				path := reader.URI().Path()
+				reader.Close()
+
+				keyfile, err := os.ReadFile(path)
+				if err == nil {
+					err = vault.EnableKeyfile(passwordEntry.Text, keyfile)
+				}
+				done(err, "設定金鑰檔")
+			}, mw.window)
+		})
+		keyfileActions := container.NewHBox(generateButton, chooseButton)
+		if factors.Keyfile {
+			keyfileActions.Add(widget.NewButton("移除金鑰檔", func() {
+				done(vault.DisableKeyfile(passwordEntry.Text), "移除金鑰檔")
+			}))
+		}
+
+		var totpAction fyne.CanvasObject
+		if factors.TOTP {
+			totpAction = widget.NewButton("停用驗證碼", func() {
+				done(vault.DisableTOTP(passwordEntry.Text), "停用驗證碼")
+			})
+		} else {
+			totpAction = widget.NewButton("啟用驗證碼...", func() {
+				factorDialog.Hide()
+				mw.showTOTPEnrollment(vault)
+			})
+		}
+
+		content := container.NewVBox(
+			widget.NewLabel("金鑰檔會和密碼一起衍生金鑰，遺失金鑰檔將無法解鎖保險庫，請妥善備份"),
+			widget.NewForm(widget.NewFormItem("密碼", passwordEntry)),
+			widget.NewSeparator(),
+			widget.NewLabel("金鑰檔："+status(factors.Keyfile)),
+			keyfileActions,
+			widget.NewSeparator(),
+			widget.NewLabel("TOTP 驗證碼："+status(factors.TOTP)),
+			totpAction,
+		)
+
+		factorDialog = dialog.NewCustom("第二驗證因素", "關閉", content, mw.window)
+		factorDialog.Resize(fyne.NewSize(520, 360))
+		factorDialog.Show()
+	})
+}
+
+// showRecoverySettings 顯示保險庫復原金鑰的設定對話框
+//
+// 執行流程：
+// 1. 確保保險庫已解鎖，顯示復原金鑰目前的狀態
+// 2. 輸入保險庫密碼和片段設定後產生新的復原金鑰（取代舊的復原金鑰）
+// 3. 顯示新的復原金鑰供列印或匯出
+func (mw *MainWindow) showRecoverySettings() {
+	vault := mw.editorService.Options().Vault
+	if vault == nil {
+		dialog.ShowError(fmt.Errorf("保險庫無法使用"), mw.window)
+		return
+	}
+
+	mw.ensureVaultUnlocked(func() {
+		status := vault.RecoveryStatus()
+		statusText := "尚未設定復原金鑰"
+		switch {
+		case status.Enabled && status.Shares > 0:
+			statusText = fmt.Sprintf("已設定復原金鑰，拆分為 %d 份片段，任 %d 份可還原", status.Shares, status.Threshold)
+		case status.Enabled:
+			statusText = "已設定復原金鑰（未拆分）"
+		}
+
+		passwordEntry := widget.NewPasswordEntry()
+		passwordEntry.SetPlaceHolder("保險庫密碼")
+		sharesEntry := widget.NewEntry()
+		sharesEntry.SetPlaceHolder("0 表示不拆分")
+		thresholdEntry := widget.NewEntry()
+		thresholdEntry.SetPlaceHolder("例如 3 份中任 2 份")
+
+		content := container.NewVBox(
+			widget.NewLabel(statusText),
+			widget.NewLabel("產生新的復原金鑰後，舊的復原金鑰和片段將無法再使用"),
+			widget.NewForm(
+				widget.NewFormItem("密碼", passwordEntry),
+				widget.NewFormItem("拆分片段數", sharesEntry),
+				widget.NewFormItem("還原需要", thresholdEntry),
+			),
+		)
+
+		recoveryDialog := dialog.NewCustomConfirm("復原金鑰", "產生", "取消", content, func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			shares, threshold := 0, 0
+			if text := strings.TrimSpace(sharesEntry.Text); text != "" {
+				shares, _ = strconv.Atoi(text)
+				threshold, _ = strconv.Atoi(strings.TrimSpace(thresholdEntry.Text))
+			}
+			kit, err := vault.SetupRecovery(passwordEntry.Text, shares, threshold)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("產生復原金鑰失敗: %w", err), mw.window)
+				return
+			}
+			mw.showRecoveryKit(kit, nil)
+		}, mw.window)
+		recoveryDialog.Resize(fyne.NewSize(520, 320))
+		recoveryDialog.Show()
+	})
+}
+
+// showRecoveryKit 顯示剛產生的復原金鑰和片段
+// 參數：kit（復原金鑰和片段）、onClose（關閉對話框後要執行的動作，可為 nil）
+//
+// 復原金鑰只顯示這一次，使用者可以複製（一段時間後自動清除剪貼簿）或匯出成文字檔列印保存
+func (mw *MainWindow) showRecoveryKit(kit *services.RecoveryKit, onClose func()) {
+	text := formatRecoveryKit(kit)
+
+	kitEntry := widget.NewMultiLineEntry()
+	kitEntry.SetText(text)
+	kitEntry.Wrapping = fyne.TextWrapBreak
+
+	copyButton := widget.NewButton("複製", func() {
+		copyToClipboard(mw.window.Clipboard(), text, services.SensitiveClipboardTimeout)
+	})
+	exportButton := widget.NewButton("匯出...", func() {
+		saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil || writer == nil {
+				return
+			}
+			path := writer.URI().Path()
+			writer.Close()
+
+			if err := os.WriteFile(path, []byte(text), 0600); err != nil {
+				dialog.ShowError(fmt.Errorf("匯出復原金鑰失敗: %w", err), mw.window)
+			}
+		}, mw.window)
+		saveDialog.SetFileName("notebook-recovery.txt")
+		saveDialog.Show()
+	})
+
+	message := "請列印或匯出後離線保存，這是唯一一次顯示復原金鑰"
+	if len(kit.Shares) > 0 {
+		message = fmt.Sprintf("請將 %d 份片段分別交給不同的人保管，任 %d 份即可還原復原金鑰；這是唯一一次顯示", len(kit.Shares), kit.Threshold)
+	}
+
+	content := container.NewBorder(
+		widget.NewLabel(message),
+		container.NewHBox(copyButton, exportButton),
+		nil, nil,
+		kitEntry,
+	)
+
+	kitDialog := dialog.NewCustom("復原金鑰", "我已保存", content, mw.window)
+	kitDialog.SetOnClosed(func() {
+		if onClose != nil {
+			onClose()
+		}
+	})
+	kitDialog.Resize(fyne.NewSize(620, 420))
+	kitDialog.Show()
+}
+
+// formatRecoveryKit 將復原金鑰和片段整理成可列印的文字
+// 參數：kit（復原金鑰和片段）
+// 回傳：可列印的文字
+func formatRecoveryKit(kit *services.RecoveryKit) string {
+	var builder strings.Builder
+	builder.WriteString("筆記本保險庫復原金鑰\n")
+	builder.WriteString("產生時間：" + kit.CreatedAt.Format("2006-01-02 15:04:05") + "\n\n")
+
+	if len(kit.Shares) == 0 {
+		builder.WriteString(kit.RecoveryKey + "\n")
+		return builder.String()
+	}
+
+	builder.WriteString(fmt.Sprintf("以下 %d 份片段中任 %d 份即可還原復原金鑰\n\n", len(kit.Shares), kit.Threshold))
+	for i, share := range kit.Shares {
+		builder.WriteString(fmt.Sprintf("片段 %d：%s\n", i+1, share))
+	}
+	return builder.String()
+}
+
+// showRecoverVaultDialog 顯示以復原金鑰重設保險庫密碼的對話框
+//
+// 執行流程：
+// 1. 使用者輸入復原金鑰，或每行一份輸入達到門檻的片段
+// 2. 輸入片段時先還原出復原金鑰
+// 3. 顯示密碼設定對話框，以復原金鑰重設密碼（會移除金鑰檔和 TOTP）
+func (mw *MainWindow) showRecoverVaultDialog() {
+	vault := mw.editorService.Options().Vault
+	if vault == nil {
+		dialog.ShowError(fmt.Errorf("保險庫無法使用"), mw.window)
+		return
+	}
+	if !vault.RecoveryStatus().Enabled {
+		dialog.ShowInformation("復原金鑰", "保險庫尚未設定復原金鑰", mw.window)
+		return
+	}
+
+	keyEntry := widget.NewMultiLineEntry()
+	keyEntry.SetPlaceHolder("輸入復原金鑰（NBRK-...），或每行輸入一份片段（NBRS-...）")
+	keyEntry.SetMinRowsVisible(5)
+
+	content := container.NewVBox(
+		widget.NewLabel("重設密碼後會移除金鑰檔和 TOTP 驗證碼，需要時請重新設定"),
+		keyEntry,
+	)
+
+	recoverDialog := dialog.NewCustomConfirm("以復原金鑰重設密碼", "下一步", "取消", content, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+
+		recoveryKey := strings.TrimSpace(keyEntry.Text)
+		if strings.Contains(strings.ToUpper(recoveryKey), services.RecoverySharePrefix) {
+			combined, err := services.CombineRecoveryShares(strings.Split(recoveryKey, "\n"))
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("還原復原金鑰失敗: %w", err), mw.window)
+				return
+			}
+			recoveryKey = combined
+		}
+
+		ShowPasswordSetupDialog(mw.window, "設定新的保險庫密碼", func(result PasswordDialogResult) {
+			if !result.Confirmed {
+				return
+			}
+			if err := vault.RecoverWithKey(recoveryKey, result.Password); err != nil {
+				dialog.ShowError(fmt.Errorf("重設密碼失敗: %w", err), mw.window)
+				return
+			}
+			dialog.ShowInformation("復原金鑰", "已設定新的保險庫密碼", mw.window)
+		})
+	}, mw.window)
+	recoverDialog.Resize(fyne.NewSize(560, 320))
+	recoverDialog.Show()
+}
+
+// totpQRCodeSize TOTP 設定對話框中 QR Code 的邊長（像素）
+const totpQRCodeSize = 200
+
+// showTOTPEnrollment 顯示 TOTP 驗證碼的設定對話框
+// 參數：vault（保險庫服務）
+//
+// 執行流程：
+// 1. 產生新的 TOTP 秘密和 otpauth URI，並將 URI 顯示為 QR Code
+// 2. 使用者以驗證器應用程式掃描 QR Code（無法掃描時手動輸入秘密）後輸入目前的驗證碼
+// 3. 驗證碼正確時啟用 TOTP
+func (mw *MainWindow) showTOTPEnrollment(vault services.VaultService) {
+	secret, err := services.GenerateTOTPSecret()
+	if err != nil {
+		dialog.ShowError(err, mw.window)
+		return
+	}
+	uri := services.TOTPProvisioningURI(secret, "vault", "Notebook")
+
+	secretEntry := widget.NewEntry()
+	secretEntry.SetText(secret)
+	uriEntry := widget.NewEntry()
+	uriEntry.SetText(uri)
+	codeEntry := widget.NewEntry()
+	codeEntry.SetPlaceHolder("驗證器顯示的 6 位數驗證碼")
+
+	qrImage, err := services.TOTPQRCode(uri, totpQRCodeSize)
+	if err != nil {
+		dialog.ShowError(err, mw.window)
+		return
+	}
+	qrCode := canvas.NewImageFromImage(qrImage)
+	qrCode.FillMode = canvas.ImageFillContain
+	qrCode.ScaleMode = canvas.ImageScalePixels
+	qrCode.SetMinSize(fyne.NewSize(totpQRCodeSize, totpQRCodeSize))
+
+	content := container.NewVBox(
+		widget.NewLabel("以驗證器應用程式掃描 QR Code，無法掃描時手動輸入下方的秘密"),
+		container.NewCenter(qrCode),
+		widget.NewForm(
+			widget.NewFormItem("秘密", container.NewBorder(nil, nil, nil, widget.NewButton("複製", func() {
+				mw.window.Clipboard().SetContent(secret)
+			}), secretEntry)),
+			widget.NewFormItem("連結", container.NewBorder(nil, nil, nil, widget.NewButton("複製", func() {
+				mw.window.Clipboard().SetContent(uri)
+			}), uriEntry)),
+			widget.NewFormItem("驗證碼", codeEntry),
+		),
+	)
+
+	enrollDialog := dialog.NewCustomConfirm("啟用驗證碼", "啟用", "取消", content, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		if err := vault.EnableTOTP(secret, codeEntry.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("啟用驗證碼失敗: %w", err), mw.window)
+			return
+		}
+		dialog.ShowInformation("驗證碼", "已啟用驗證碼，之後解鎖保險庫時需要輸入驗證器顯示的驗證碼", mw.window)
+	}, mw.window)
+	enrollDialog.Resize(fyne.NewSize(560, 520))
+	enrollDialog.Show()
+}
+
+// showRekeyDialog 顯示批次重新加密對話框
+// 讓使用者輸入目前的密碼、新密碼和新的加密演算法
+func (mw *MainWindow) showRekeyDialog() {
+	if mw.rekeyService == nil {
+		dialog.ShowError(fmt.Errorf("批次重新加密服務無法使用"), mw.window)
+		return
+	}
+
+	oldEntry := widget.NewPasswordEntry()
+	oldEntry.SetPlaceHolder("保險庫密碼或筆記密碼")
+	newEntry := widget.NewPasswordEntry()
+	newEntry.SetPlaceHolder("留空表示沿用目前的密碼")
+	confirmEntry := widget.NewPasswordEntry()
+
+	algorithms := map[string]string{
+		"沿用原演算法":            "",
+		"AES-256-GCM":       services.AlgorithmAES256,
+		"ChaCha20-Poly1305": services.AlgorithmChaCha20,
+	}
+	algorithmSelect := widget.NewSelect([]string{"沿用原演算法", "AES-256-GCM", "ChaCha20-Poly1305"}, nil)
+	algorithmSelect.SetSelected("沿用原演算法")
+
+	form := widget.NewForm(
+		widget.NewFormItem("目前的密碼", oldEntry),
+		widget.NewFormItem("新密碼", newEntry),
+		widget.NewFormItem("確認新密碼", confirmEntry),
+		widget.NewFormItem("加密演算法", algorithmSelect),
+	)
+
+	dialog.ShowCustomConfirm("重新加密所有筆記", "開始", "取消", form, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		if newEntry.Text != confirmEntry.Text {
+			dialog.ShowError(fmt.Errorf("兩次輸入的新密碼不一致"), mw.window)
+			return
+		}
+		mw.runRekey(services.RekeyOptions{
+			OldPassword: oldEntry.Text,
+			NewPassword: newEntry.Text,
+			Algorithm:   algorithms[algorithmSelect.Selected],
+		})
+	}, mw.window)
+}
+
+// runRekey 執行批次重新加密並顯示結果
+// 參數：options（重新加密選項）
 //
 // 執行流程：
-// 1. 使用編輯器服務開啟檔案
-// 2. 處理加密檔案的密碼驗證
-// 3. 載入檔案內容到編輯器
-// 4. 更新狀態顯示
-func (mw *MainWindow) openFileFromPath(filePath string) {
-	// 使用編輯器服務開啟檔案
-	note, err := mw.editorService.OpenNote(filePath)
-	if err != nil {
-		// 檢查是否為加密檔案需要密碼
-		if strings.Contains(err.Error(), "需要密碼驗證") {
-			mw.handleEncryptedFileOpen(filePath)
+// 1. 保存已修改的當前筆記，確保重新加密的是最新內容
+// 2. 在背景執行重新加密，避免阻塞 UI
+// 3. 有檔案無法重新加密時作業已還原，列出失敗的檔案並詢問是否略過這些檔案重試
+// 4. 關閉已解密的加密筆記（金鑰或密碼已變更），並以新憑證重新開啟當前筆記
+// 5. 顯示每個檔案的處理結果
+func (mw *MainWindow) runRekey(options services.RekeyOptions) {
+	if mw.editor.IsModified() {
+		if err := mw.editor.SaveNote(); err != nil {
+			dialog.ShowError(fmt.Errorf("重新加密前保存筆記失敗: %w", err), mw.window)
 			return
 		}
-		
-		dialog.ShowError(err, mw.window)
+	}
+
+	progress := dialog.NewCustomWithoutButtons("重新加密中", widget.NewProgressBarInfinite(), mw.window)
+	progress.Show()
+
+	go func() {
+		result, err := mw.rekeyService.RekeyNotebook("", options)
+
+		fyne.Do(func() {
+			progress.Hide()
+			if errors.Is(err, services.ErrRekeyFilesFailed) && result != nil {
+				mw.confirmRekeySkipFailures(options, result)
+				return
+			}
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("重新加密失敗: %w", err), mw.window)
+				return
+			}
+
+			mw.reopenAfterRekey(options)
+			mw.showRekeyResult(result)
+		})
+	}()
+}
+
+// confirmRekeySkipFailures 列出無法重新加密的檔案，詢問是否略過這些檔案重新執行
+// 參數：options（原本的重新加密選項）、result（已還原的重新加密結果）
+func (mw *MainWindow) confirmRekeySkipFailures(options services.RekeyOptions, result *services.RekeyResult) {
+	failed := make(map[string]bool, len(result.FailedFiles))
+	for _, path := range result.FailedFiles {
+		failed[path] = true
+	}
+	var details strings.Builder
+	for _, file := range result.Files {
+		if failed[file.Path] {
+			details.WriteString(fmt.Sprintf("\n%s：%s", file.Path, file.Error))
+		}
+	}
+	message := fmt.Sprintf("%d 個檔案無法重新加密，所有檔案和保險庫密碼維持原狀：%s\n\n要略過這些檔案並重新加密其他檔案嗎？略過的檔案會維持原密碼。",
+		result.FailureCount, details.String())
+	dialog.ShowConfirm("重新加密未完成", message, func(confirmed bool) {
+		if confirmed {
+			options.SkipFailures = true
+			mw.runRekey(options)
+		}
+	}, mw.window)
+}
+
+// reopenAfterRekey 重新加密後重新開啟當前的加密筆記
+// 參數：options（重新加密選項）
+func (mw *MainWindow) reopenAfterRekey(options services.RekeyOptions) {
+	current := mw.editor.GetCurrentNote()
+	reopenPath := ""
+	if current != nil && current.IsEncrypted {
+		reopenPath = current.FilePath
+	}
+
+	for id, note := range mw.editorService.GetActiveNotes() {
+		if note.IsEncrypted {
+			mw.editorService.CloseNote(id)
+		}
+	}
+
+	if reopenPath == "" {
+		return
+	}
+
+	password := options.NewPassword
+	if password == "" {
+		password = options.OldPassword
+	}
+
+	note, err := mw.editorService.OpenNoteWithPassword(reopenPath, password)
+	if err != nil {
+		mw.editor.Clear()
+		mw.UpdateEncryptionStatus(false, "")
 		return
 	}
-	
-	// 載入筆記到編輯器
 	mw.editor.LoadNote(note)
-	
-	// 更新狀態顯示
-	mw.UpdateSaveStatus("已載入")
-	mw.UpdateEncryptionStatus(note.IsEncrypted, note.EncryptionType)
-	
-	// 重新整理檔案樹以反映變更
-	mw.refreshFileTree()
+	mw.UpdateEncryptionStatus(true, note.EncryptionType)
 }
 
-// handleEncryptedFileOpen 處理加密檔案的開啟
-// 參數：filePath（加密檔案路徑）
-//
-// 執行流程：
-// 1. 顯示密碼輸入對話框
-// 2. 使用密碼解密檔案
-// 3. 載入解密後的內容到編輯器
-func (mw *MainWindow) handleEncryptedFileOpen(filePath string) {
-	// 建立密碼輸入對話框
-	passwordDialog := NewPasswordDialog(mw.window, "開啟加密檔案", func(password string) {
-		// 先開啟檔案取得筆記 ID
-		note, err := mw.editorService.OpenNote(filePath)
-		if err != nil {
-			// 如果還是失敗，嘗試使用密碼解密
-			// 這裡需要特殊處理，因為我們需要筆記 ID 來解密
-			dialog.ShowError(fmt.Errorf("無法開啟加密檔案: %w", err), mw.window)
-			return
-		}
-		
-		// 使用密碼解密內容
-		decryptedContent, err := mw.editorService.DecryptWithPassword(note.ID, password)
-		if err != nil {
-			dialog.ShowError(fmt.Errorf("密碼錯誤或解密失敗: %w", err), mw.window)
-			return
+// showRekeyResult 顯示批次重新加密的結果摘要
+// 參數：result（重新加密結果）
+func (mw *MainWindow) showRekeyResult(result *services.RekeyResult) {
+	message := fmt.Sprintf("共 %d 個加密筆記，成功 %d 個，失敗 %d 個（耗時 %s）",
+		result.TotalFiles, result.SuccessCount, result.FailureCount, result.ElapsedTime.Round(time.Millisecond))
+
+	if result.FailureCount > 0 {
+		var details strings.Builder
+		for _, file := range result.Files {
+			if !file.Success {
+				details.WriteString(fmt.Sprintf("\n%s：%s", file.Path, file.Error))
+			}
 		}
-		
-		// 更新筆記內容
-		note.Content = decryptedContent
-		
-		// 載入筆記到編輯器
-		mw.editor.LoadNote(note)
-		
-		// 更新狀態顯示
-		mw.UpdateSaveStatus("已載入")
-		mw.UpdateEncryptionStatus(true, note.EncryptionType)
-	})
-	
-	// 顯示密碼對話框
-	passwordDialog.Show()
+		message += "\n\n以下檔案維持原狀：" + details.String()
+	}
+
+	dialog.ShowInformation("重新加密完成", message, mw.window)
 }
 
 // saveCurrentNote 保存當前筆記
@@ -799,7 +2450,7 @@ func (mw *MainWindow) handleEncryptedFileOpen(filePath string) {
 // 執行流程：
 // 1. 檢查是否有當前筆記
 // 2. 使用編輯器保存筆記
-// 3. 處理保存結果和錯誤
+// 3. 處理保存結果和錯誤，檔案已在外部變更時讓使用者選擇處理方式
 // 4. 更新狀態顯示
 func (mw *MainWindow) saveCurrentNote() {
 	// 檢查編輯器是否可以保存
@@ -810,6 +2461,11 @@ func (mw *MainWindow) saveCurrentNote() {
 	
 	// 使用編輯器保存筆記
 	err := mw.editor.SaveNote()
+	if conflict, ok := services.AsSaveConflict(err); ok {
+		mw.UpdateSaveStatus("檔案已在外部變更")
+		mw.showSaveConflictDialog(conflict)
+		return
+	}
 	if err != nil {
 		dialog.ShowError(err, mw.window)
 		mw.UpdateSaveStatus("保存失敗")
@@ -818,11 +2474,78 @@ func (mw *MainWindow) saveCurrentNote() {
 	
 	// 更新狀態顯示
 	mw.UpdateSaveStatus("已保存")
+	mw.refreshSignatureStatus()
 	
 	// 重新整理檔案樹以反映變更
 	mw.refreshFileTree()
 }
 
+// showSaveConflictDialog 顯示保存衝突的處理選項
+// 參數：conflict（保存衝突）
+//
+// 執行流程：
+// 1. 顯示檔案在外部變更的時間，無法讀取磁碟上的版本時只能保留我的版本
+// 2. 保留我的版本：覆寫磁碟上的版本
+// 3. 採用磁碟上的版本：捨棄編輯並載入磁碟上的內容
+// 4. 合併：三方合併兩邊的變更，有衝突時在編輯器中標示
+// 5. 比較差異：顯示磁碟上的版本和我的版本的差異後回到此對話框
+func (mw *MainWindow) showSaveConflictDialog(conflict *services.SaveConflict) {
+	info := container.NewVBox(
+		widget.NewLabel(fmt.Sprintf("「%s」在開啟後已被其他程式修改", filepath.Base(conflict.FilePath))),
+	)
+	if !conflict.Disk.ModTime.IsZero() {
+		info.Add(widget.NewLabel(fmt.Sprintf("磁碟上的版本修改於 %s", conflict.Disk.ModTime.Local().Format("2006-01-02 15:04:05"))))
+	}
+	if conflict.TheirsError != nil {
+		info.Add(widget.NewLabel(fmt.Sprintf("無法讀取磁碟上的版本：%v", conflict.TheirsError)))
+	}
+
+	var conflictDialog dialog.Dialog
+	resolve := func(resolution services.ConflictResolution) {
+		conflictDialog.Hide()
+		conflicted, err := mw.editor.ResolveSaveConflict(resolution)
+		switch {
+		case err != nil:
+			dialog.ShowError(err, mw.window)
+			mw.UpdateSaveStatus("保存失敗")
+		case conflicted:
+			mw.UpdateSaveStatus("合併有衝突")
+			dialog.ShowInformation("合併有衝突", "兩邊修改了相同的段落，請在編輯器中處理衝突標記後再保存", mw.window)
+		case resolution == services.ConflictKeepTheirs:
+			mw.UpdateSaveStatus("已載入磁碟上的版本")
+		default:
+			mw.UpdateSaveStatus("已保存")
+			mw.refreshSignatureStatus()
+			mw.refreshFileTree()
+		}
+	}
+
+	keepMineButton := widget.NewButton("保留我的版本", func() { resolve(services.ConflictKeepMine) })
+	keepTheirsButton := widget.NewButton("採用磁碟上的版本", func() { resolve(services.ConflictKeepTheirs) })
+	mergeButton := widget.NewButton("合併", func() { resolve(services.ConflictMerge) })
+	mergeButton.Importance = widget.HighImportance
+	diffButton := widget.NewButton("比較差異", func() {
+		conflictDialog.Hide()
+		diffLabel := widget.NewLabelWithStyle(services.FormatDiff(services.DiffLines(conflict.Theirs, conflict.Mine)), fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+		scroll := container.NewScroll(diffLabel)
+		scroll.SetMinSize(fyne.NewSize(640, 400))
+		diffContent := container.NewBorder(widget.NewLabel("「- 」為磁碟上的版本，「+ 」為我的版本"), nil, nil, nil, scroll)
+		diffDialog := dialog.NewCustom(fmt.Sprintf("比較差異：%s", filepath.Base(conflict.FilePath)), "返回", diffContent, mw.window)
+		diffDialog.SetOnClosed(func() { mw.showSaveConflictDialog(conflict) })
+		diffDialog.Show()
+	})
+	cancelButton := widget.NewButton("取消", func() { conflictDialog.Hide() })
+	if conflict.TheirsError != nil {
+		keepTheirsButton.Disable()
+		mergeButton.Disable()
+		diffButton.Disable()
+	}
+
+	content := container.NewVBox(info, container.NewHBox(keepMineButton, keepTheirsButton, mergeButton, diffButton, cancelButton))
+	conflictDialog = dialog.NewCustomWithoutButtons("檔案已在外部變更", content, mw.window)
+	conflictDialog.Show()
+}
+
 // saveAsNewFile 另存新檔
 // 顯示檔案保存對話框並將當前筆記保存為新檔案
 //
@@ -1150,6 +2873,8 @@ func (mw *MainWindow) handleFileTreeOperation(operation, filePath string) {
 		mw.createNewFileInDirectory(filePath)
 	case "create_folder":
 		mw.createNewFolderInDirectory(filePath)
+	case "create_encrypted_folder":
+		mw.createEncryptedFolderInDirectory(filePath)
 	case "rename":
 		mw.renameFileWithDialog(filePath)
 	case "delete":
@@ -1158,6 +2883,14 @@ func (mw *MainWindow) handleFileTreeOperation(operation, filePath string) {
 		mw.copyFileWithDialog(filePath)
 	case "cut":
 		mw.cutFileWithDialog(filePath)
+	case "toggle_favorite":
+		if mw.favorites != nil {
+			mw.toggleFavorite(filePath)
+		}
+	case "toggle_pin":
+		if mw.favorites != nil {
+			mw.togglePinned(filePath)
+		}
 	default:
 		fmt.Printf("未知的檔案操作: %s\n", operation)
 	}
@@ -1187,6 +2920,9 @@ func (mw *MainWindow) showFileContextMenu(filePath string, isDirectory bool) {
 			fyne.NewMenuItem("新增資料夾", func() {
 				mw.createNewFolderInDirectory(filePath)
 			}),
+			fyne.NewMenuItem("新增加密資料夾", func() {
+				mw.createEncryptedFolderInDirectory(filePath)
+			}),
 			fyne.NewMenuItemSeparator(),
 			fyne.NewMenuItem("重新命名", func() {
 				mw.renameFileWithDialog(filePath)
@@ -1215,6 +2951,19 @@ func (mw *MainWindow) showFileContextMenu(filePath string, isDirectory bool) {
 		}
 	}
 	
+	// 最愛和釘選
+	if mw.favorites != nil {
+		menuItems = append(menuItems,
+			fyne.NewMenuItemSeparator(),
+			fyne.NewMenuItem("切換最愛", func() {
+				mw.toggleFavorite(filePath)
+			}),
+			fyne.NewMenuItem("切換釘選", func() {
+				mw.togglePinned(filePath)
+			}),
+		)
+	}
+	
 	// 建立並顯示右鍵選單
 	_ = fyne.NewMenu("", menuItems...)
 	
@@ -1331,6 +3080,49 @@ func (mw *MainWindow) createNewFolderInDirectory(dirPath string) {
 	folderNameEntry.FocusGained()
 }
 
+// createEncryptedFolderInDirectory 在指定目錄中建立加密資料夾
+// 參數：dirPath（目錄路徑）
+//
+// 執行流程：
+// 1. 顯示資料夾名稱輸入對話框，未輸入名稱時將目錄本身設為加密資料夾
+// 2. 確認保險庫已解鎖
+// 3. 建立加密資料夾並加密其中既有的檔案
+// 4. 重新整理檔案樹並顯示操作結果
+func (mw *MainWindow) createEncryptedFolderInDirectory(dirPath string) {
+	if mw.encryptedRepo == nil {
+		dialog.ShowError(fmt.Errorf("加密資料夾無法使用"), mw.window)
+		return
+	}
+
+	folderNameEntry := widget.NewEntry()
+	folderNameEntry.SetPlaceHolder("請輸入資料夾名稱（留空則加密此目錄）...")
+
+	content := container.NewVBox(
+		widget.NewLabel("加密資料夾中的所有檔案以保險庫金鑰加密保存，資料夾和檔案名稱維持可見"),
+		widget.NewLabel(fmt.Sprintf("目錄: %s", dirPath)),
+		folderNameEntry,
+	)
+
+	dialog.ShowCustomConfirm("新增加密資料夾", "建立", "取消", content, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		folderPath := dirPath
+		if name := strings.TrimSpace(folderNameEntry.Text); name != "" {
+			folderPath = filepath.Join(dirPath, name)
+		}
+
+		mw.ensureVaultUnlocked(func() {
+			if err := mw.encryptedRepo.CreateEncryptedFolder(folderPath); err != nil {
+				dialog.ShowError(fmt.Errorf("建立加密資料夾失敗: %w", err), mw.window)
+				return
+			}
+			mw.refreshFileTree()
+			dialog.ShowInformation("成功", fmt.Sprintf("資料夾 '%s' 已加密", folderPath), mw.window)
+		})
+	}, mw.window)
+}
+
 // createNewFileInCurrentDir 在當前目錄中建立新檔案
 // 這是工具欄按鈕的回調函數
 //
@@ -1643,6 +3435,11 @@ func (mw *MainWindow) createFileTreeComponent() {
 	// 建立檔案樹元件並整合檔案管理服務
 	mw.fileTreeWidget = NewFileTreeWidget(mw.fileManagerService, rootPath)
 	
+	// 加密筆記以中繼資料標頭中的標題顯示
+	if titles, ok := mw.editorService.(services.EncryptedTitleProvider); ok {
+		mw.fileTreeWidget.SetTitleResolver(titles.NoteDisplayTitle)
+	}
+	
 	// 設定檔案樹的回調函數
 	mw.setupFileTreeCallbacks()
 }
@@ -2032,11 +3829,11 @@ func (mw *MainWindow) handleViewAction(action string) {
 func (mw *MainWindow) handleToolAction(action string) {
 	switch action {
 	case "toggle_encryption":
-		fmt.Println("加密切換功能將在後續任務中實作")
+		mw.toggleEncryption()
 	case "toggle_favorite":
-		fmt.Println("最愛切換功能將在後續任務中實作")
+		mw.showFavoriteDialog()
 	case "manage_tags":
-		fmt.Println("標籤管理功能將在後續任務中實作")
+		mw.showManageTagsDialog()
 	case "show_stats":
 		fmt.Println("統計資訊功能將在後續任務中實作")
 	case "show_help":
@@ -2044,12 +3841,279 @@ func (mw *MainWindow) handleToolAction(action string) {
 	}
 }
 
+// showFavoriteDialog 顯示將檔案樹中選擇的項目（沒有選擇時為目前編輯的筆記）加入最愛或釘選的對話框
+func (mw *MainWindow) showFavoriteDialog() {
+	if mw.favorites == nil {
+		return
+	}
+	
+	target := ""
+	if mw.fileTreeWidget != nil {
+		target = mw.fileTreeWidget.GetSelectedPath()
+	}
+	if target == "" {
+		if note := mw.editor.GetCurrentNote(); note != nil {
+			target = note.FilePath
+		}
+	}
+	if target == "" {
+		dialog.ShowInformation("最愛與釘選", "請先在檔案樹中選擇筆記或資料夾，或開啟一個筆記", mw.window)
+		return
+	}
+	
+	var favoriteDialog dialog.Dialog
+	favoriteText, pinText := "加入最愛", "釘選到最前面"
+	if mw.favorites.IsFavorite(target) {
+		favoriteText = "移出最愛"
+	}
+	if mw.favorites.IsPinned(target) {
+		pinText = "取消釘選"
+	}
+	favoriteButton := widget.NewButton(favoriteText, func() {
+		favoriteDialog.Hide()
+		mw.toggleFavorite(target)
+	})
+	favoriteButton.Importance = widget.HighImportance
+	pinButton := widget.NewButton(pinText, func() {
+		favoriteDialog.Hide()
+		mw.togglePinned(target)
+	})
+	closeButton := widget.NewButton("關閉", func() { favoriteDialog.Hide() })
+	
+	content := container.NewVBox(
+		widget.NewLabel(fmt.Sprintf("「%s」", filepath.Base(target))),
+		container.NewHBox(favoriteButton, pinButton, closeButton),
+	)
+	favoriteDialog = dialog.NewCustomWithoutButtons("最愛與釘選", content, mw.window)
+	favoriteDialog.Show()
+}
+
+// toggleFavorite 將檔案或資料夾加入或移出最愛
+// 參數：path（檔案或資料夾路徑）
+func (mw *MainWindow) toggleFavorite(path string) {
+	favorite := !mw.favorites.IsFavorite(path)
+	if err := mw.favorites.SetFavorite(path, favorite); err != nil {
+		dialog.ShowError(err, mw.window)
+		return
+	}
+	if favorite {
+		mw.UpdateSaveStatus(fmt.Sprintf("已將「%s」加入最愛", filepath.Base(path)))
+	} else {
+		mw.UpdateSaveStatus(fmt.Sprintf("已將「%s」移出最愛", filepath.Base(path)))
+	}
+}
+
+// togglePinned 釘選或取消釘選檔案或資料夾，並重新排列所在目錄
+// 參數：path（檔案或資料夾路徑）
+func (mw *MainWindow) togglePinned(path string) {
+	pinned := !mw.favorites.IsPinned(path)
+	if err := mw.favorites.SetPinned(path, pinned); err != nil {
+		dialog.ShowError(err, mw.window)
+		return
+	}
+	if mw.fileTreeWidget != nil {
+		mw.fileTreeWidget.ReloadDirectory(filepath.Dir(path))
+	}
+	if pinned {
+		mw.UpdateSaveStatus(fmt.Sprintf("已釘選「%s」", filepath.Base(path)))
+	} else {
+		mw.UpdateSaveStatus(fmt.Sprintf("已取消釘選「%s」", filepath.Base(path)))
+	}
+}
+
+// openFavorite 開啟最愛，筆記在編輯器中開啟，資料夾在檔案樹中展開
+// 參數：path（最愛的路徑）、isDirectory（是否為資料夾）
+func (mw *MainWindow) openFavorite(path string, isDirectory bool) {
+	if !isDirectory {
+		mw.openFileFromPath(path)
+		return
+	}
+	if mw.fileTreeWidget != nil {
+		mw.fileTreeWidget.RevealPath(path)
+	}
+}
+
+// showSearchPanel 顯示筆記列表面板中的搜尋面板並讓查詢輸入框獲得焦點
+func (mw *MainWindow) showSearchPanel() {
+	if mw.searchPanel == nil {
+		dialog.ShowInformation("搜尋", "全文搜尋索引尚未啟用", mw.window)
+		return
+	}
+	if !mw.layoutManager.IsNoteListVisible() {
+		mw.layoutManager.ToggleNoteList()
+	}
+	mw.searchPanel.FocusSearch(mw.window.Canvas())
+}
+
+// openSearchResult 開啟搜尋結果的筆記，並將游標移到符合的位置
+// 參數：result（搜尋結果）
+func (mw *MainWindow) openSearchResult(result *services.SearchResult) {
+	mw.openFileFromPath(result.FilePath)
+	if note := mw.editor.GetCurrentNote(); note != nil && note.FilePath == result.FilePath {
+		mw.editor.GoToPosition(result.Line, result.Column)
+	}
+}
+
+// showManageTagsDialog 顯示批次新增或移除標籤的對話框
+// 對象為檔案樹中選擇的筆記（按住 Cmd、Ctrl 或 Shift 點選可選擇多個），沒有選擇時為目前編輯的筆記
+//
+// 執行流程：
+// 1. 決定要修改的筆記，沒有筆記時提示使用者
+// 2. 顯示這些筆記目前的標籤和要新增或移除的標籤輸入框
+// 3. 新增或移除標籤後回報修改的筆記數量
+func (mw *MainWindow) showManageTagsDialog() {
+	if mw.tagService == nil {
+		return
+	}
+	
+	var targets []string
+	if mw.fileTreeWidget != nil {
+		targets = mw.fileTreeWidget.SelectedFiles()
+	}
+	if len(targets) == 0 {
+		if note := mw.editor.GetCurrentNote(); note != nil && note.FilePath != "" {
+			targets = []string{note.FilePath}
+		}
+	}
+	if len(targets) == 0 {
+		dialog.ShowInformation("管理標籤", "請先在檔案樹中選擇筆記或開啟一個筆記", mw.window)
+		return
+	}
+	
+	// 列出所選筆記目前的標籤
+	existing := make(map[string]bool)
+	for _, path := range targets {
+		for _, tag := range mw.tagService.NoteTags(path) {
+			existing[tag] = true
+		}
+	}
+	currentTags := make([]string, 0, len(existing))
+	for tag := range existing {
+		currentTags = append(currentTags, "#"+tag)
+	}
+	sort.Strings(currentTags)
+	currentLabel := widget.NewLabel("目前的標籤：無")
+	if len(currentTags) > 0 {
+		currentLabel.SetText("目前的標籤：" + strings.Join(currentTags, " "))
+	}
+	currentLabel.Wrapping = fyne.TextWrapWord
+	
+	tagEntry := widget.NewEntry()
+	tagEntry.SetPlaceHolder("標籤，以空白或逗號分隔，例如 專案/alpha 待辦")
+	
+	var tagsDialog dialog.Dialog
+	apply := func(remove bool) {
+		tags := splitTagInput(tagEntry.Text)
+		if len(tags) == 0 {
+			return
+		}
+		tagsDialog.Hide()
+		mw.applyTagEdit(targets, func() (int, error) {
+			if remove {
+				return mw.tagService.RemoveTags(targets, tags)
+			}
+			return mw.tagService.AddTags(targets, tags)
+		})
+	}
+	addButton := widget.NewButton("新增標籤", func() { apply(false) })
+	addButton.Importance = widget.HighImportance
+	removeButton := widget.NewButton("移除標籤", func() { apply(true) })
+	cancelButton := widget.NewButton("取消", func() { tagsDialog.Hide() })
+	
+	content := container.NewVBox(
+		widget.NewLabel(fmt.Sprintf("為 %d 個筆記新增或移除標籤", len(targets))),
+		currentLabel,
+		tagEntry,
+		container.NewHBox(addButton, removeButton, cancelButton),
+	)
+	tagsDialog = dialog.NewCustomWithoutButtons("管理標籤", content, mw.window)
+	tagsDialog.Resize(fyne.NewSize(420, 0))
+	tagsDialog.Show()
+	mw.window.Canvas().Focus(tagEntry)
+}
+
+// showRenameTagDialog 顯示重新命名標籤的對話框，新名稱已是現有標籤時確認後合併
+// 參數：tag（要重新命名的標籤）
+func (mw *MainWindow) showRenameTagDialog(tag string) {
+	if mw.tagService == nil {
+		return
+	}
+	
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(tag)
+	
+	dialog.ShowForm(fmt.Sprintf("重新命名標籤 #%s", tag), "重新命名", "取消", []*widget.FormItem{
+		widget.NewFormItem("新名稱", nameEntry),
+	}, func(confirmed bool) {
+		newTag := services.NormalizeTag(nameEntry.Text)
+		if !confirmed || newTag == tag {
+			return
+		}
+		
+		affected := mw.tagService.NotesWithTag(tag)
+		rename := func() {
+			mw.applyTagEdit(affected, func() (int, error) {
+				return mw.tagService.RenameTag(tag, newTag)
+			})
+		}
+		if newTag != "" && len(mw.tagService.NotesWithTag(newTag)) > 0 {
+			dialog.ShowConfirm("合併標籤",
+				fmt.Sprintf("標籤 #%s 已經存在，要將 #%s 合併到 #%s 嗎？", newTag, tag, newTag),
+				func(merge bool) {
+					if merge {
+						rename()
+					}
+				}, mw.window)
+			return
+		}
+		rename()
+	}, mw.window)
+}
+
+// applyTagEdit 執行批次修改標籤並更新編輯器和狀態
+// 參數：paths（可能被修改的筆記路徑）、edit（修改標籤的操作，回傳修改的筆記數量）
+//
+// 執行流程：
+// 1. 目前編輯的筆記會被修改且有未保存的編輯時先保存，避免修改標籤時遺失編輯
+// 2. 執行修改，以修改後的內容更新編輯器
+// 3. 顯示修改的筆記數量，部分筆記失敗時顯示錯誤
+func (mw *MainWindow) applyTagEdit(paths []string, edit func() (int, error)) {
+	if note := mw.editor.GetCurrentNote(); note != nil && mw.editor.IsModified() {
+		for _, path := range paths {
+			if filepath.Clean(path) != filepath.Clean(note.FilePath) {
+				continue
+			}
+			if err := mw.editor.SaveNote(); err != nil {
+				dialog.ShowError(fmt.Errorf("修改標籤前無法保存目前的筆記: %w", err), mw.window)
+				return
+			}
+			break
+		}
+	}
+	
+	count, err := edit()
+	mw.editor.ReloadContent()
+	if err != nil {
+		dialog.ShowError(err, mw.window)
+	}
+	mw.UpdateSaveStatus(fmt.Sprintf("已更新 %d 個筆記的標籤", count))
+}
+
+// splitTagInput 將使用者輸入的標籤以空白或逗號分隔
+// 參數：input（使用者輸入）
+// 回傳：標籤清單
+func splitTagInput(input string) []string {
+	return strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || r == '，' || r == '、' || r == ' ' || r == '\t' || r == '\n'
+	})
+}
+
 // handleLayoutAction 處理佈局動作
 // 參數：action（佈局動作名稱）
 func (mw *MainWindow) handleLayoutAction(action string) {
 	switch action {
 	case "open_search":
-		fmt.Println("搜尋功能將在後續任務中實作")
+		mw.showSearchPanel()
 	}
 }
 
@@ -1,9 +1,16 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 	"unicode"
 
+	"mac-notebook-app/internal/models"
+	"mac-notebook-app/internal/services"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
@@ -26,8 +33,11 @@ const (
 // PasswordDialogResult 密碼對話框結果
 // 包含用戶輸入的密碼和操作結果
 type PasswordDialogResult struct {
-	Password  string // 用戶輸入的密碼
-	Confirmed bool   // 用戶是否確認操作
+	Password          string // 用戶輸入的密碼
+	Confirmed         bool   // 用戶是否確認操作
+	GenerateRecovery  bool   // 是否產生復原金鑰（僅在啟用復原金鑰選項時有效）
+	RecoveryShares    int    // 復原金鑰拆分的片段數（0 表示不拆分）
+	RecoveryThreshold int    // 還原復原金鑰需要的片段數
 }
 
 // PasswordDialogCallback 密碼對話框回調函數類型
@@ -42,6 +52,11 @@ type PasswordSetupDialog struct {
 	confirmEntry    *widget.Entry        // 確認密碼輸入框
 	strengthBar     *widget.ProgressBar  // 密碼強度指示器
 	strengthLabel   *widget.Label        // 密碼強度文字說明
+	recoveryBox     *fyne.Container      // 復原金鑰選項區塊（預設隱藏）
+	recoveryCheck   *widget.Check        // 是否產生復原金鑰
+	sharesEntry     *widget.Entry        // 復原金鑰片段數
+	thresholdEntry  *widget.Entry        // 還原需要的片段數
+	parent          fyne.Window          // 父視窗，用於顯示錯誤訊息
 	callback        PasswordDialogCallback // 完成時的回調函數
 }
 
@@ -60,6 +75,7 @@ type PasswordSetupDialog struct {
 // 5. 組裝對話框佈局
 func NewPasswordSetupDialog(parent fyne.Window, title string, callback PasswordDialogCallback) *PasswordSetupDialog {
 	d := &PasswordSetupDialog{
+		parent:   parent,
 		callback: callback,
 	}
 
@@ -83,6 +99,22 @@ func NewPasswordSetupDialog(parent fyne.Window, title string, callback PasswordD
 		d.updatePasswordStrength(text)
 	}
 
+	// 創建復原金鑰選項（預設隱藏，由 EnableRecoveryOptions 顯示）
+	d.recoveryCheck = widget.NewCheck("產生復原金鑰（忘記密碼時可用來設定新密碼）", nil)
+	d.sharesEntry = widget.NewEntry()
+	d.sharesEntry.SetPlaceHolder("0 表示不拆分")
+	d.thresholdEntry = widget.NewEntry()
+	d.thresholdEntry.SetPlaceHolder("例如 3 份中任 2 份")
+	d.recoveryBox = container.NewVBox(
+		widget.NewSeparator(),
+		d.recoveryCheck,
+		widget.NewForm(
+			widget.NewFormItem("拆分片段數", d.sharesEntry),
+			widget.NewFormItem("還原需要", d.thresholdEntry),
+		),
+	)
+	d.recoveryBox.Hide()
+
 	// 創建確認按鈕
 	confirmButton := widget.NewButton("確認", func() {
 		d.handleConfirm()
@@ -103,6 +135,7 @@ func NewPasswordSetupDialog(parent fyne.Window, title string, callback PasswordD
 		widget.NewLabel("密碼強度："),
 		d.strengthBar,
 		d.strengthLabel,
+		d.recoveryBox,
 		widget.NewSeparator(),
 		container.NewHBox(
 			confirmButton,
@@ -123,6 +156,37 @@ func (d *PasswordSetupDialog) Show() {
 	d.dialog.Show()
 }
 
+// EnableRecoveryOptions 顯示復原金鑰選項
+// 用於第一次設定保險庫密碼時，讓用戶選擇產生復原金鑰並拆分成片段交給多人保管
+func (d *PasswordSetupDialog) EnableRecoveryOptions() {
+	d.recoveryCheck.SetChecked(true)
+	d.recoveryBox.Show()
+}
+
+// recoveryOptions 解析復原金鑰選項
+// 回傳：是否產生復原金鑰、片段數、門檻和可能的錯誤
+func (d *PasswordSetupDialog) recoveryOptions() (bool, int, int, error) {
+	if !d.recoveryBox.Visible() || !d.recoveryCheck.Checked {
+		return false, 0, 0, nil
+	}
+	if strings.TrimSpace(d.sharesEntry.Text) == "" {
+		return true, 0, 0, nil
+	}
+
+	shares, err := strconv.Atoi(strings.TrimSpace(d.sharesEntry.Text))
+	if err != nil || shares < 0 {
+		return false, 0, 0, fmt.Errorf("片段數必須是非負整數")
+	}
+	if shares == 0 {
+		return true, 0, 0, nil
+	}
+	threshold, err := strconv.Atoi(strings.TrimSpace(d.thresholdEntry.Text))
+	if err != nil || threshold < 2 || threshold > shares || shares > services.MaxShamirShares {
+		return false, 0, 0, fmt.Errorf("還原需要的片段數必須介於 2 和片段數之間，片段數最多 %d 份", services.MaxShamirShares)
+	}
+	return true, shares, threshold, nil
+}
+
 // updatePasswordStrength 更新密碼強度指示器
 // 參數：
 //   - password: 要檢查的密碼
@@ -177,11 +241,21 @@ func (d *PasswordSetupDialog) handleConfirm() {
 		return
 	}
 
+	// 檢查復原金鑰選項
+	generateRecovery, shares, threshold, err := d.recoveryOptions()
+	if err != nil {
+		dialog.ShowError(err, d.parent)
+		return
+	}
+
 	// 調用回調函數
 	if d.callback != nil {
 		d.callback(PasswordDialogResult{
-			Password:  password,
-			Confirmed: true,
+			Password:          password,
+			Confirmed:         true,
+			GenerateRecovery:  generateRecovery,
+			RecoveryShares:    shares,
+			RecoveryThreshold: threshold,
 		})
 	}
 
@@ -210,9 +284,13 @@ type PasswordVerifyDialog struct {
 	dialog        dialog.Dialog        // Fyne 對話框實例
 	passwordEntry *widget.Entry        // 密碼輸入框
 	attemptsLabel *widget.Label        // 剩餘嘗試次數標籤
+	lockoutLabel  *widget.Label        // 鎖定倒數標籤
+	verifyButton  *widget.Button       // 驗證按鈕（鎖定期間停用）
 	callback      PasswordDialogCallback // 完成時的回調函數
 	maxAttempts   int                  // 最大嘗試次數
 	attempts      int                  // 當前嘗試次數
+	lockedUntil   time.Time            // 鎖定到期時間（未鎖定時為零值）
+	stopCountdown chan struct{}        // 停止倒數計時的通道
 }
 
 // NewPasswordVerifyDialog 創建新的密碼驗證對話框
@@ -247,8 +325,12 @@ func NewPasswordVerifyDialog(parent fyne.Window, title string, maxAttempts int,
 		d.handleVerify()
 	}
 
+	// 創建鎖定倒數標籤（鎖定時才顯示）
+	d.lockoutLabel = widget.NewLabel("")
+	d.lockoutLabel.Hide()
+
 	// 創建確認按鈕
-	verifyButton := widget.NewButton("驗證", func() {
+	d.verifyButton = widget.NewButton("驗證", func() {
 		d.handleVerify()
 	})
 
@@ -262,9 +344,10 @@ func NewPasswordVerifyDialog(parent fyne.Window, title string, maxAttempts int,
 		widget.NewLabel("請輸入密碼："),
 		d.passwordEntry,
 		d.attemptsLabel,
+		d.lockoutLabel,
 		widget.NewSeparator(),
 		container.NewHBox(
-			verifyButton,
+			d.verifyButton,
 			cancelButton,
 		),
 	)
@@ -291,6 +374,11 @@ func (d *PasswordVerifyDialog) Show() {
 // 3. 調用回調函數進行驗證
 // 4. 根據驗證結果決定後續操作
 func (d *PasswordVerifyDialog) handleVerify() {
+	// 鎖定期間不接受驗證
+	if d.IsLockedOut() {
+		return
+	}
+
 	password := d.passwordEntry.Text
 	d.attempts++
 
@@ -308,6 +396,11 @@ func (d *PasswordVerifyDialog) handleVerify() {
 		})
 	}
 
+	// 回調函數回報鎖定時保留對話框，讓用戶看到剩餘的等待時間
+	if d.IsLockedOut() {
+		return
+	}
+
 	// 更新嘗試次數顯示
 	remaining := d.maxAttempts - d.attempts
 	d.attemptsLabel.SetText(fmt.Sprintf("剩餘嘗試次數：%d", remaining))
@@ -321,9 +414,97 @@ func (d *PasswordVerifyDialog) handleVerify() {
 	}
 }
 
+// Hide 關閉密碼驗證對話框
+// 驗證成功後由呼叫者關閉，同時停止鎖定倒數
+func (d *PasswordVerifyDialog) Hide() {
+	d.stopLockoutCountdown()
+	d.dialog.Hide()
+}
+
+// SetLockout 進入鎖定狀態並顯示剩餘的等待時間
+// 參數：
+//   - remaining: 鎖定的剩餘時間（通常來自 services.LockoutError）
+//
+// 執行流程：
+// 1. 停用密碼輸入框和驗證按鈕
+// 2. 每秒更新倒數標籤
+// 3. 鎖定到期後恢復輸入並重設嘗試次數
+func (d *PasswordVerifyDialog) SetLockout(remaining time.Duration) {
+	d.stopLockoutCountdown()
+	if remaining <= 0 {
+		return
+	}
+
+	d.lockedUntil = time.Now().Add(remaining)
+	d.passwordEntry.SetText("")
+	d.passwordEntry.Disable()
+	d.verifyButton.Disable()
+	d.attemptsLabel.Hide()
+	d.updateLockoutLabel()
+	d.lockoutLabel.Show()
+
+	stop := make(chan struct{})
+	d.stopCountdown = stop
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				fyne.Do(func() {
+					if d.stopCountdown != stop {
+						return
+					}
+					if d.IsLockedOut() {
+						d.updateLockoutLabel()
+						return
+					}
+					d.stopLockoutCountdown()
+				})
+			}
+		}
+	}()
+}
+
+// IsLockedOut 檢查對話框是否處於鎖定狀態
+// 回傳：是否仍在鎖定期間
+func (d *PasswordVerifyDialog) IsLockedOut() bool {
+	return !d.lockedUntil.IsZero() && time.Now().Before(d.lockedUntil)
+}
+
+// updateLockoutLabel 更新鎖定倒數標籤
+func (d *PasswordVerifyDialog) updateLockoutLabel() {
+	remaining := time.Until(d.lockedUntil).Round(time.Second)
+	if remaining < time.Second {
+		remaining = time.Second
+	}
+	d.lockoutLabel.SetText(fmt.Sprintf("密碼錯誤次數過多，請在 %v 後重試", remaining))
+}
+
+// stopLockoutCountdown 結束鎖定狀態，恢復輸入並重設嘗試次數
+func (d *PasswordVerifyDialog) stopLockoutCountdown() {
+	if d.stopCountdown == nil {
+		return
+	}
+	close(d.stopCountdown)
+	d.stopCountdown = nil
+	d.lockedUntil = time.Time{}
+
+	d.attempts = 0
+	d.attemptsLabel.SetText(fmt.Sprintf("剩餘嘗試次數：%d", d.maxAttempts))
+	d.attemptsLabel.Show()
+	d.lockoutLabel.Hide()
+	d.passwordEntry.Enable()
+	d.verifyButton.Enable()
+}
+
 // handleCancel 處理取消按鈕點擊事件
 // 調用回調函數並關閉對話框
 func (d *PasswordVerifyDialog) handleCancel() {
+	d.stopLockoutCountdown()
+
 	// 調用回調函數
 	if d.callback != nil {
 		d.callback(PasswordDialogResult{
@@ -434,4 +615,39 @@ func ShowPasswordSetupDialog(parent fyne.Window, title string, callback Password
 func ShowPasswordVerifyDialog(parent fyne.Window, title string, maxAttempts int, callback PasswordDialogCallback) {
 	dialog := NewPasswordVerifyDialog(parent, title, maxAttempts, callback)
 	dialog.Show()
-}
\ No newline at end of file
+}
+
+// ShowReauthDialog 匯出或分享加密筆記前要求重新輸入密碼
+// 參數：
+//   - parent: 父視窗
+//   - exportService: 匯出服務，用於驗證密碼
+//   - note: 要匯出或分享的加密筆記
+//   - onSuccess: 驗證成功後的回調函數
+//
+// 執行流程：
+// 1. 顯示密碼驗證對話框
+// 2. 以匯出服務驗證密碼，錯誤次數過多時在對話框中顯示剩餘的等待時間
+// 3. 驗證成功後關閉對話框並呼叫 onSuccess
+func ShowReauthDialog(parent fyne.Window, exportService services.ExportService, note *models.Note, onSuccess func()) {
+	var verifyDialog *PasswordVerifyDialog
+	verifyDialog = NewPasswordVerifyDialog(parent, "請重新輸入密碼以匯出或分享加密筆記", services.MaxRetryAttempts, func(result PasswordDialogResult) {
+		if !result.Confirmed {
+			return
+		}
+
+		err := exportService.Reauthenticate(note, result.Password)
+		if err == nil {
+			verifyDialog.Hide()
+			onSuccess()
+			return
+		}
+
+		var lockErr *services.LockoutError
+		if errors.As(err, &lockErr) {
+			verifyDialog.SetLockout(lockErr.Remaining)
+			return
+		}
+		dialog.ShowError(err, parent)
+	})
+	verifyDialog.Show()
+}
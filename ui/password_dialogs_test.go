@@ -1,7 +1,9 @@
 package ui
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"fyne.io/fyne/v2/test"
 )
@@ -291,6 +293,43 @@ func TestPasswordVerifyDialogAttempts(t *testing.T) {
 	}
 }
 
+// TestPasswordVerifyDialogLockout 測試鎖定期間停用驗證並保留對話框，解除後重設嘗試次數
+func TestPasswordVerifyDialogLockout(t *testing.T) {
+	testWindow := test.NewWindow(nil)
+	defer testWindow.Close()
+
+	callbackCount := 0
+	var dialog *PasswordVerifyDialog
+	dialog = NewPasswordVerifyDialog(testWindow, "驗證密碼", 3, func(result PasswordDialogResult) {
+		callbackCount++
+		dialog.SetLockout(30 * time.Second)
+	})
+
+	dialog.passwordEntry.SetText("wrongpassword")
+	dialog.handleVerify()
+	if !dialog.IsLockedOut() {
+		t.Fatal("回調函數回報鎖定後對話框應該處於鎖定狀態")
+	}
+	if !dialog.verifyButton.Disabled() || !dialog.passwordEntry.Disabled() {
+		t.Error("鎖定期間應該停用驗證按鈕和密碼輸入框")
+	}
+	if !strings.Contains(dialog.lockoutLabel.Text, "30s") {
+		t.Errorf("鎖定標籤應該顯示剩餘時間，實際為 %q", dialog.lockoutLabel.Text)
+	}
+
+	// 鎖定期間不會再呼叫回調函數
+	dialog.handleVerify()
+	if callbackCount != 1 {
+		t.Errorf("鎖定期間不應呼叫回調函數，實際呼叫 %d 次", callbackCount)
+	}
+
+	// 解除鎖定後恢復輸入並重設嘗試次數
+	dialog.stopLockoutCountdown()
+	if dialog.IsLockedOut() || dialog.verifyButton.Disabled() || dialog.attempts != 0 {
+		t.Error("解除鎖定後應該恢復輸入並重設嘗試次數")
+	}
+}
+
 // TestPasswordDialogUI 測試密碼對話框的 UI 互動
 func TestPasswordDialogUI(t *testing.T) {
 	// 創建測試視窗
@@ -379,4 +418,40 @@ func TestPasswordDialogMemoryUsage(t *testing.T) {
 			dialog.dialog.Hide()
 		}
 	}
-}
\ No newline at end of file
+}
+
+// TestPasswordSetupDialogRecoveryOptions 測試復原金鑰選項的解析
+func TestPasswordSetupDialogRecoveryOptions(t *testing.T) {
+	testWindow := test.NewWindow(nil)
+	defer testWindow.Close()
+
+	var result PasswordDialogResult
+	dialog := NewPasswordSetupDialog(testWindow, "設定保險庫密碼", func(r PasswordDialogResult) {
+		result = r
+	})
+
+	// 未啟用復原金鑰選項時不產生復原金鑰
+	dialog.passwordEntry.SetText("VeryStrong123!@#")
+	dialog.confirmEntry.SetText("VeryStrong123!@#")
+	dialog.handleConfirm()
+	if !result.Confirmed || result.GenerateRecovery {
+		t.Errorf("未啟用選項時不應產生復原金鑰: %+v", result)
+	}
+
+	dialog.EnableRecoveryOptions()
+	if generate, shares, _, err := dialog.recoveryOptions(); err != nil || !generate || shares != 0 {
+		t.Errorf("預設應該產生未拆分的復原金鑰: %v, %d, %v", generate, shares, err)
+	}
+
+	dialog.sharesEntry.SetText("3")
+	dialog.thresholdEntry.SetText("4")
+	if _, _, _, err := dialog.recoveryOptions(); err == nil {
+		t.Error("門檻大於片段數時應該回傳錯誤")
+	}
+
+	dialog.thresholdEntry.SetText("2")
+	dialog.handleConfirm()
+	if !result.GenerateRecovery || result.RecoveryShares != 3 || result.RecoveryThreshold != 2 {
+		t.Errorf("復原金鑰選項解析錯誤: %+v", result)
+	}
+}
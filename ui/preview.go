@@ -52,6 +52,7 @@ type MarkdownPreview struct {
 	lastUpdateTime time.Time           // 上次更新時間
 	updateThrottle time.Duration       // 更新節流間隔
 	contentCache   map[string]string   // 內容快取
+	sensitive      bool                // 是否正在預覽加密筆記（不使用內容快取）
 	
 	// 回調函數
 	onVisibilityChanged func(visible bool) // 可見性變更回調
@@ -336,7 +337,7 @@ func (mp *MarkdownPreview) GetContainer() *fyne.Container {
 // 參數：content（要預覽的 Markdown 內容）
 //
 // 執行流程：
-// 1. 檢查內容是否有變更
+// 1. 以佔位文字取代機密區塊，檢查內容是否有變更
 // 2. 實作更新節流機制以提升效能
 // 3. 檢查內容快取以避免重複處理
 // 4. 使用編輯器服務轉換 Markdown 為 HTML
@@ -344,6 +345,9 @@ func (mp *MarkdownPreview) GetContainer() *fyne.Container {
 // 6. 更新搜尋結果（如果有搜尋查詢）
 // 7. 更新狀態標籤和觸發相關回調
 func (mp *MarkdownPreview) UpdatePreview(content string) {
+	// 機密區塊一律顯示為鎖定的佔位文字
+	content = services.RedactSecretBlocks(content)
+	
 	// 檢查內容是否有變更
 	if mp.currentContent == content {
 		return // 內容未變更，無需更新
@@ -361,9 +365,9 @@ func (mp *MarkdownPreview) UpdatePreview(content string) {
 	}
 	mp.lastUpdateTime = now
 	
-	// 檢查內容快取
+	// 檢查內容快取（加密筆記的內容不寫入快取）
 	contentHash := fmt.Sprintf("%x", content) // 簡化的內容雜湊
-	if cachedHTML, exists := mp.contentCache[contentHash]; exists {
+	if cachedHTML, exists := mp.contentCache[contentHash]; exists && !mp.sensitive {
 		// 使用快取的內容
 		mp.previewArea.ParseMarkdown(cachedHTML)
 		mp.updateStatus("已從快取載入預覽")
@@ -385,7 +389,9 @@ func (mp *MarkdownPreview) UpdatePreview(content string) {
 	mp.previewArea.ParseMarkdown(content)
 	
 	// 快取處理後的內容
-	mp.contentCache[contentHash] = content
+	if !mp.sensitive {
+		mp.contentCache[contentHash] = content
+	}
 	
 	// 更新獨立視窗（如果存在）
 	if mp.isIndependent {
@@ -488,6 +494,22 @@ func (mp *MarkdownPreview) Clear() {
 	mp.updateStatus("預覽內容已清空")
 }
 
+// SetSensitive 設定是否正在預覽加密筆記
+// 加密筆記的明文不寫入內容快取，切換到加密筆記時清空既有快取
+// 參數：sensitive（是否為加密筆記）
+func (mp *MarkdownPreview) SetSensitive(sensitive bool) {
+	mp.sensitive = sensitive
+	if sensitive {
+		mp.contentCache = make(map[string]string)
+	}
+}
+
+// IsSensitive 檢查是否正在預覽加密筆記
+// 回傳：是否為加密筆記
+func (mp *MarkdownPreview) IsSensitive() bool {
+	return mp.sensitive
+}
+
 // GetCurrentContent 取得當前預覽的內容
 // 回傳：當前預覽的 Markdown 內容
 func (mp *MarkdownPreview) GetCurrentContent() string {
@@ -4,9 +4,11 @@ package ui
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"mac-notebook-app/internal/services"
 )
 
 // TestEnhancedMarkdownPreviewCreation 測試增強版 Markdown 預覽面板的建立
@@ -658,4 +660,41 @@ func TestConcurrentOperations(t *testing.T) {
 	if preview.GetZoomLevel() < 0.5 || preview.GetZoomLevel() > 3.0 {
 		t.Errorf("並發縮放後縮放級別應該在合理範圍內，但得到 %f", preview.GetZoomLevel())
 	}
-}
\ No newline at end of file
+}
+// TestPreviewSensitiveContentNotCached 測試預覽加密筆記時不使用內容快取
+func TestPreviewSensitiveContentNotCached(t *testing.T) {
+	preview := NewMarkdownPreview(newMockEditorServiceForPreview())
+	
+	preview.UpdatePreview("# 公開筆記")
+	if len(preview.contentCache) == 0 {
+		t.Fatal("一般筆記的預覽應該寫入快取")
+	}
+	
+	// 切換到加密筆記時清空既有快取，之後的內容也不寫入快取
+	preview.SetSensitive(true)
+	if len(preview.contentCache) != 0 {
+		t.Error("切換到加密筆記時應該清空快取")
+	}
+	preview.lastUpdateTime = time.Time{}
+	preview.UpdatePreview("# 機密筆記")
+	if preview.GetCurrentContent() != "# 機密筆記" {
+		t.Error("加密筆記仍應該正常預覽")
+	}
+	if len(preview.contentCache) != 0 {
+		t.Error("加密筆記的內容不應寫入快取")
+	}
+}
+
+// TestPreviewRedactsSecretBlocks 測試預覽以鎖定的佔位文字顯示機密區塊
+func TestPreviewRedactsSecretBlocks(t *testing.T) {
+	preview := NewMarkdownPreview(newMockEditorServiceForPreview())
+	
+	preview.UpdatePreview("# 部署手冊\n\n```secret\nAPI_KEY=abc123\n```\n\n完成")
+	content := preview.GetCurrentContent()
+	if strings.Contains(content, "abc123") {
+		t.Error("預覽不應顯示機密區塊的內容")
+	}
+	if !strings.Contains(content, services.SecretBlockPlaceholder) || !strings.Contains(content, "完成") {
+		t.Errorf("機密區塊應該以佔位文字顯示，其他內容保留: %s", content)
+	}
+}
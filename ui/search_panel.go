@@ -0,0 +1,179 @@
+// Package ui 包含全文搜尋面板
+// 在筆記列表面板中輸入查詢字串，依相關程度列出符合的筆記和標示符合文字的摘要，點選後開啟筆記並移到符合的位置
+package ui
+
+import (
+	"fmt" // Go 標準庫，用於格式化狀態文字
+
+	"fyne.io/fyne/v2"                    // Fyne GUI 框架核心套件
+	"fyne.io/fyne/v2/container"          // Fyne 容器佈局套件
+	"fyne.io/fyne/v2/theme"              // Fyne 主題套件（標示顏色）
+	"fyne.io/fyne/v2/widget"             // Fyne UI 元件套件
+	"mac-notebook-app/internal/services" // 本專案的服務層套件
+)
+
+// 搜尋面板的參數
+const (
+	searchPanelResultLimit = 50  // 最多顯示的搜尋結果數
+	searchPanelMinHeight   = 400 // 搜尋結果列表的最小高度
+)
+
+// SearchPanel 代表全文搜尋面板
+type SearchPanel struct {
+	widget.BaseWidget // 繼承 Fyne 基礎元件
+
+	// 服務依賴
+	index services.SearchIndex // 全文搜尋索引
+
+	// UI 元件
+	entry       *widget.Entry   // 查詢輸入框
+	statusLabel *widget.Label   // 搜尋結果數量
+	list        *widget.List    // 搜尋結果列表
+	container   *fyne.Container // 容器元件
+
+	// 資料和狀態
+	results []*services.SearchResult // 目前顯示的搜尋結果
+
+	// 回調函數
+	onResultOpen func(result *services.SearchResult) // 開啟搜尋結果回調
+}
+
+// NewSearchPanel 建立全文搜尋面板
+// 參數：index（全文搜尋索引）
+// 回傳：全文搜尋面板實例
+func NewSearchPanel(index services.SearchIndex) *SearchPanel {
+	sp := &SearchPanel{index: index}
+	sp.ExtendBaseWidget(sp)
+	sp.createUI()
+	return sp
+}
+
+// createUI 建立搜尋面板的 UI 佈局
+func (sp *SearchPanel) createUI() {
+	sp.entry = widget.NewEntry()
+	sp.entry.SetPlaceHolder("搜尋筆記內容…")
+	sp.entry.OnChanged = sp.Search
+	sp.entry.OnSubmitted = func(string) {
+		if len(sp.results) > 0 && sp.onResultOpen != nil {
+			sp.onResultOpen(sp.results[0])
+		}
+	}
+
+	sp.statusLabel = widget.NewLabel("")
+	sp.statusLabel.Hide()
+
+	sp.list = widget.NewList(
+		func() int {
+			return len(sp.results)
+		},
+		func() fyne.CanvasObject {
+			title := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+			title.Truncation = fyne.TextTruncateEllipsis
+			snippet := widget.NewRichText()
+			snippet.Truncation = fyne.TextTruncateEllipsis
+			return container.NewVBox(title, snippet)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			sp.updateItem(id, obj.(*fyne.Container))
+		},
+	)
+	sp.list.OnSelected = func(id widget.ListItemID) {
+		sp.list.UnselectAll()
+		if id < len(sp.results) && sp.onResultOpen != nil {
+			sp.onResultOpen(sp.results[id])
+		}
+	}
+
+	sp.container = container.NewVBox(sp.entry, sp.statusLabel, withMinHeight(sp.list, searchPanelMinHeight))
+}
+
+// updateItem 更新列表項目的標題和摘要
+// 參數：id（項目索引）、row（項目的容器）
+func (sp *SearchPanel) updateItem(id widget.ListItemID, row *fyne.Container) {
+	if id >= len(sp.results) {
+		return
+	}
+	result := sp.results[id]
+	row.Objects[0].(*widget.Label).SetText(result.Title)
+	snippet := row.Objects[1].(*widget.RichText)
+	snippet.Segments = snippetSegments(result)
+	snippet.Refresh()
+}
+
+// snippetSegments 將摘要依標示範圍切成一般文字和強調文字
+// 參數：result（搜尋結果）
+// 回傳：RichText 的文字片段
+func snippetSegments(result *services.SearchResult) []widget.RichTextSegment {
+	runes := []rune(result.Snippet)
+	highlightStyle := widget.RichTextStyle{
+		ColorName: theme.ColorNamePrimary,
+		Inline:    true,
+		TextStyle: fyne.TextStyle{Bold: true},
+	}
+
+	var segments []widget.RichTextSegment
+	next := 0
+	for _, h := range result.Highlights {
+		if h.Start < next || h.End > len(runes) {
+			continue
+		}
+		if h.Start > next {
+			segments = append(segments, &widget.TextSegment{Text: string(runes[next:h.Start]), Style: widget.RichTextStyleInline})
+		}
+		segments = append(segments, &widget.TextSegment{Text: string(runes[h.Start:h.End]), Style: highlightStyle})
+		next = h.End
+	}
+	if next < len(runes) || len(segments) == 0 {
+		segments = append(segments, &widget.TextSegment{Text: string(runes[next:]), Style: widget.RichTextStyleInline})
+	}
+	return segments
+}
+
+// CreateRenderer 實作 fyne.Widget 介面
+// 回傳：元件的渲染器
+func (sp *SearchPanel) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(sp.container)
+}
+
+// Search 搜尋並顯示結果，查詢字串為空時清除結果
+// 參數：query（查詢字串）
+func (sp *SearchPanel) Search(query string) {
+	sp.results = sp.index.Search(query, searchPanelResultLimit)
+	switch {
+	case query == "":
+		sp.statusLabel.Hide()
+	case len(sp.results) == 0:
+		sp.statusLabel.SetText("找不到符合的筆記")
+		sp.statusLabel.Show()
+	default:
+		sp.statusLabel.SetText(fmt.Sprintf("找到 %d 個筆記", len(sp.results)))
+		sp.statusLabel.Show()
+	}
+	sp.list.Refresh()
+	sp.list.ScrollToTop()
+}
+
+// GetResults 取得目前顯示的搜尋結果
+// 回傳：搜尋結果
+func (sp *SearchPanel) GetResults() []*services.SearchResult {
+	return sp.results
+}
+
+// FocusSearch 讓查詢輸入框獲得焦點並選取其中的文字
+// 參數：canvas（視窗的畫布）
+func (sp *SearchPanel) FocusSearch(canvas fyne.Canvas) {
+	canvas.Focus(sp.entry)
+	sp.entry.TypedShortcut(&fyne.ShortcutSelectAll{})
+}
+
+// SetOnResultOpen 設定開啟搜尋結果回調函數
+// 參數：callback（點選搜尋結果或在輸入框按 Enter 時的回調函數）
+func (sp *SearchPanel) SetOnResultOpen(callback func(result *services.SearchResult)) {
+	sp.onResultOpen = callback
+}
+
+// GetContainer 取得搜尋面板的容器
+// 回傳：容器元件
+func (sp *SearchPanel) GetContainer() *fyne.Container {
+	return sp.container
+}
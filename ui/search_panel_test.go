@@ -0,0 +1,80 @@
+// Package ui 包含全文搜尋面板的測試
+// 測試輸入查詢後列出搜尋結果、摘要的標示文字，以及點選和按 Enter 開啟搜尋結果
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"mac-notebook-app/internal/repositories"
+	"mac-notebook-app/internal/services"
+)
+
+// TestSearchPanel 測試搜尋面板顯示搜尋結果並開啟選擇的結果
+func TestSearchPanel(t *testing.T) {
+	test.NewApp()
+	baseDir := t.TempDir()
+	os.WriteFile(filepath.Join(baseDir, "ml.md"), []byte("# 機器學習\n\n研究機器學習的基礎"), 0644)
+	os.WriteFile(filepath.Join(baseDir, "diary.md"), []byte("# 日記\n\n今天天氣很好"), 0644)
+	fileRepo, err := repositories.NewLocalFileRepository(baseDir)
+	if err != nil {
+		t.Fatalf("建立檔案儲存庫失敗: %v", err)
+	}
+	index := services.NewSearchIndex(fileRepo)
+	if err := index.Rebuild(); err != nil {
+		t.Fatalf("建立索引失敗: %v", err)
+	}
+
+	panel := NewSearchPanel(index)
+	var opened *services.SearchResult
+	panel.SetOnResultOpen(func(result *services.SearchResult) {
+		opened = result
+	})
+
+	test.Type(panel.entry, "學習")
+	results := panel.GetResults()
+	if len(results) != 1 || results[0].FilePath != "ml.md" || panel.statusLabel.Text != "找到 1 個筆記" {
+		t.Fatalf("搜尋結果不正確: %d 筆，狀態 %q", len(results), panel.statusLabel.Text)
+	}
+
+	// 摘要中的符合文字以強調樣式顯示
+	row := panel.list.CreateItem().(*fyne.Container)
+	panel.updateItem(0, row)
+	if title := row.Objects[0].(*widget.Label).Text; title != "機器學習" {
+		t.Errorf("標題應該是機器學習，但得到 %q", title)
+	}
+	highlighted := ""
+	for _, segment := range row.Objects[1].(*widget.RichText).Segments {
+		if text, ok := segment.(*widget.TextSegment); ok && text.Style.ColorName == theme.ColorNamePrimary {
+			highlighted += text.Text
+		}
+	}
+	if highlighted != "學習" {
+		t.Errorf("應該標示符合的文字，但得到 %q", highlighted)
+	}
+
+	panel.list.OnSelected(0)
+	if opened == nil || opened.FilePath != "ml.md" {
+		t.Error("點選搜尋結果應該開啟筆記")
+	}
+
+	opened = nil
+	panel.entry.OnSubmitted(panel.entry.Text)
+	if opened == nil {
+		t.Error("按 Enter 應該開啟第一個搜尋結果")
+	}
+
+	panel.entry.SetText("不存在的內容")
+	if len(panel.GetResults()) != 0 || panel.statusLabel.Text != "找不到符合的筆記" {
+		t.Error("沒有符合的筆記時應該顯示提示")
+	}
+	panel.entry.SetText("")
+	if panel.statusLabel.Visible() {
+		t.Error("清除查詢後應該隱藏狀態")
+	}
+}
@@ -27,6 +27,10 @@ type SettingsDialog struct {
 	autoSaveEntry      *widget.Entry     // 自動保存間隔輸入框
 	saveLocationEntry  *widget.Entry     // 預設保存位置輸入框
 	biometricCheck     *widget.Check     // 生物識別啟用勾選框
+	sessionTimeoutEntry *widget.Entry    // 保險庫閒置自動鎖定時間輸入框
+	lockOnBlurCheck    *widget.Check     // 失去焦點時鎖定勾選框
+	obfuscateCheck     *widget.Check     // 加密筆記隨機檔名勾選框
+	keepBackupsCheck   *widget.Check     // 保留上一版備份勾選框
 	themeSelect        *widget.Select    // 主題選擇器
 	
 	// 回調函數
@@ -116,6 +120,39 @@ func (sd *SettingsDialog) initializeComponents() {
 		sd.notifySettingsChanged()
 	})
 	sd.biometricCheck.SetChecked(sd.settings.BiometricEnabled)
+
+	// 建立保險庫閒置自動鎖定時間輸入框
+	sd.sessionTimeoutEntry = widget.NewEntry()
+	sd.sessionTimeoutEntry.SetText(strconv.Itoa(sd.settings.SessionTimeout))
+	sd.sessionTimeoutEntry.OnChanged = func(text string) {
+		// 驗證並更新閒置自動鎖定時間
+		if minutes, err := strconv.Atoi(text); err == nil {
+			if err := sd.settings.UpdateSessionTimeout(minutes); err == nil {
+				sd.notifySettingsChanged()
+			}
+		}
+	}
+
+	// 建立失去焦點時鎖定勾選框
+	sd.lockOnBlurCheck = widget.NewCheck("視窗移到背景時鎖定保險庫", func(checked bool) {
+		sd.settings.SetLockOnBlur(checked)
+		sd.notifySettingsChanged()
+	})
+	sd.lockOnBlurCheck.SetChecked(sd.settings.LockOnBlur)
+
+	// 建立加密筆記隨機檔名勾選框
+	sd.obfuscateCheck = widget.NewCheck("加密筆記使用隨機檔名（隱藏標題）", func(checked bool) {
+		sd.settings.SetObfuscateFilenames(checked)
+		sd.notifySettingsChanged()
+	})
+	sd.obfuscateCheck.SetChecked(sd.settings.ObfuscateFilenames)
+
+	// 建立保留上一版備份勾選框
+	sd.keepBackupsCheck = widget.NewCheck("保存時保留上一版為 .bak（重新啟動後生效）", func(checked bool) {
+		sd.settings.SetKeepBackups(checked)
+		sd.notifySettingsChanged()
+	})
+	sd.keepBackupsCheck.SetChecked(sd.settings.KeepBackups)
 	
 	// 建立主題選擇器
 	sd.themeSelect = widget.NewSelect(
@@ -173,7 +210,8 @@ func (sd *SettingsDialog) createContent() *fyne.Container {
 // 1. 建立區塊標題
 // 2. 建立加密演算法選擇器佈局
 // 3. 建立生物識別設定佈局
-// 4. 組合成完整的加密設定區塊
+// 4. 建立保險庫自動鎖定設定佈局
+// 5. 組合成完整的加密設定區塊
 func (sd *SettingsDialog) createEncryptionSection() *fyne.Container {
 	// 區塊標題
 	title := widget.NewRichTextFromMarkdown("## 🔐 加密設定")
@@ -190,6 +228,13 @@ func (sd *SettingsDialog) createEncryptionSection() *fyne.Container {
 	
 	// 生物識別設定
 	biometricRow := container.NewHBox(sd.biometricCheck)
+
+	// 保險庫自動鎖定設定
+	sessionTimeoutLabel := widget.NewLabel("閒置自動鎖定（分鐘）：")
+	sessionTimeoutHelp := widget.NewLabel("0 表示不自動鎖定")
+	sessionTimeoutRow := container.NewBorder(nil, nil, sessionTimeoutLabel, sessionTimeoutHelp, sd.sessionTimeoutEntry)
+	lockOnBlurRow := container.NewHBox(sd.lockOnBlurCheck)
+	obfuscateRow := container.NewHBox(sd.obfuscateCheck)
 	
 	// 組合加密設定區塊
 	section := container.NewVBox(
@@ -197,6 +242,9 @@ func (sd *SettingsDialog) createEncryptionSection() *fyne.Container {
 		encryptionRow,
 		encryptionHelp,
 		biometricRow,
+		sessionTimeoutRow,
+		lockOnBlurRow,
+		obfuscateRow,
 	)
 	
 	return section
@@ -223,12 +271,14 @@ func (sd *SettingsDialog) createFileSection() *fyne.Container {
 	saveLocationLabel := widget.NewLabel("預設保存位置：")
 	browseButton := widget.NewButton("瀏覽...", sd.onBrowseLocation)
 	saveLocationRow := container.NewBorder(nil, nil, saveLocationLabel, browseButton, sd.saveLocationEntry)
+	keepBackupsRow := container.NewHBox(sd.keepBackupsCheck)
 	
 	// 組合檔案管理設定區塊
 	section := container.NewVBox(
 		title,
 		autoSaveRow,
 		saveLocationRow,
+		keepBackupsRow,
 	)
 	
 	return section
@@ -390,6 +440,10 @@ func (sd *SettingsDialog) updateUIFromSettings() {
 	sd.autoSaveEntry.SetText(strconv.Itoa(sd.settings.AutoSaveInterval))
 	sd.saveLocationEntry.SetText(sd.settings.DefaultSaveLocation)
 	sd.biometricCheck.SetChecked(sd.settings.BiometricEnabled)
+	sd.sessionTimeoutEntry.SetText(strconv.Itoa(sd.settings.SessionTimeout))
+	sd.lockOnBlurCheck.SetChecked(sd.settings.LockOnBlur)
+	sd.obfuscateCheck.SetChecked(sd.settings.ObfuscateFilenames)
+	sd.keepBackupsCheck.SetChecked(sd.settings.KeepBackups)
 	sd.themeSelect.SetSelected(sd.settings.Theme)
 }
 
@@ -159,6 +159,53 @@ func TestSettingsDialog_AutoSaveIntervalChange(t *testing.T) {
 	}
 }
 
+// TestSettingsDialog_SessionLockChange 測試保險庫自動鎖定設定變更
+// 驗證：
+// 1. 輸入有效的閒置時間時設定正確更新
+// 2. 超出範圍的閒置時間不會更新設定
+// 3. 勾選失去焦點鎖定時設定正確更新
+func TestSettingsDialog_SessionLockChange(t *testing.T) {
+	// 建立測試環境
+	testApp := test.NewApp()
+	testWindow := testApp.NewWindow("Test")
+	defer testWindow.Close()
+
+	testSettings := models.NewDefaultSettings()
+	var changedSettings *models.Settings
+	onChanged := func(settings *models.Settings) {
+		changedSettings = settings
+	}
+
+	dialog := NewSettingsDialog(testWindow, testSettings, onChanged)
+
+	// 測試有效的閒置時間
+	dialog.sessionTimeoutEntry.SetText("30")
+	if dialog.settings.SessionTimeout != 30 {
+		t.Errorf("閒置自動鎖定時間未更新，期望 30，實際 %d", dialog.settings.SessionTimeout)
+	}
+
+	// 測試超出範圍的值
+	dialog.sessionTimeoutEntry.SetText("99999")
+	if dialog.settings.SessionTimeout != 30 {
+		t.Errorf("超出範圍輸入後設定被錯誤更新，期望 30，實際 %d", dialog.settings.SessionTimeout)
+	}
+
+	// 模擬勾選失去焦點鎖定
+	test.Tap(dialog.lockOnBlurCheck)
+	if !dialog.settings.LockOnBlur {
+		t.Error("失去焦點鎖定設定未正確啟用")
+	}
+	if changedSettings == nil || !changedSettings.LockOnBlur {
+		t.Error("回調函數接收的失去焦點鎖定設定不正確")
+	}
+
+	// 模擬勾選加密筆記隨機檔名
+	test.Tap(dialog.obfuscateCheck)
+	if !dialog.settings.ObfuscateFilenames || !changedSettings.ObfuscateFilenames {
+		t.Error("加密筆記隨機檔名設定未正確啟用")
+	}
+}
+
 // TestSettingsDialog_BiometricToggle 測試生物識別切換
 // 驗證：
 // 1. 勾選/取消勾選時設定正確更新
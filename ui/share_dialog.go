@@ -3,9 +3,11 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"mac-notebook-app/internal/models"
 	"mac-notebook-app/internal/services"
+	"strings"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -29,6 +31,9 @@ type ShareDialog struct {
 	allowDownload   *widget.Check           // 允許下載選項
 	allowEdit       *widget.Check           // 允許編輯選項
 	recipientsEntry *widget.Entry           // 收件人輸入（電子郵件分享）
+	publicKeysEntry *widget.Entry           // 收件人公鑰輸入（收件人公鑰加密）
+	outputPathEntry *widget.Entry           // 加密檔案輸出路徑（收件人公鑰加密）
+	includeSelf     *widget.Check           // 同時加密給自己的身分
 	
 	// 分享結果顯示
 	resultLabel     *widget.Label           // 結果標籤
@@ -124,12 +129,26 @@ func (d *ShareDialog) createUIComponents() {
 	d.recipientsEntry.SetPlaceHolder("輸入收件人電子郵件地址，多個地址用逗號分隔")
 	d.recipientsEntry.Hide() // 預設隱藏
 	
+	// 收件人公鑰加密用的輸入
+	d.publicKeysEntry = widget.NewMultiLineEntry()
+	d.publicKeysEntry.SetPlaceHolder("貼上收件人公鑰（" + services.RecipientKeyPrefix + "...），每行一個")
+	d.publicKeysEntry.Hide()
+	
+	d.outputPathEntry = widget.NewEntry()
+	d.outputPathEntry.SetPlaceHolder("加密檔案輸出路徑（預設為家目錄）")
+	d.outputPathEntry.Hide()
+	
+	d.includeSelf = widget.NewCheck("同時以我的身分加密", nil)
+	d.includeSelf.SetChecked(true)
+	d.includeSelf.Hide()
+	
 	// 分享類型選擇（放在最後，避免在其他組件創建前觸發回調）
 	d.shareTypeSelect = widget.NewSelect([]string{
 		"連結分享",
 		"電子郵件分享", 
 		"AirDrop 分享",
 		"複製到剪貼簿",
+		"收件人公鑰加密",
 	}, d.onShareTypeChanged)
 	d.shareTypeSelect.SetSelected("連結分享")
 	
@@ -183,6 +202,9 @@ func (d *ShareDialog) createLayout() {
 			d.allowEdit,
 		),
 		d.recipientsEntry,
+		d.publicKeysEntry,
+		d.outputPathEntry,
+		d.includeSelf,
 	)
 	
 	// 分享結果區域
@@ -219,6 +241,17 @@ func (d *ShareDialog) createLayout() {
 // onShareTypeChanged 處理分享類型變更事件
 // 參數：shareType（選擇的分享類型）
 func (d *ShareDialog) onShareTypeChanged(shareType string) {
+	// 收件人公鑰加密的選項只在該分享類型下顯示
+	if shareType == "收件人公鑰加密" {
+		d.publicKeysEntry.Show()
+		d.outputPathEntry.Show()
+		d.includeSelf.Show()
+	} else {
+		d.publicKeysEntry.Hide()
+		d.outputPathEntry.Hide()
+		d.includeSelf.Hide()
+	}
+	
 	// 根據分享類型顯示/隱藏相關選項
 	switch shareType {
 	case "電子郵件分享":
@@ -242,7 +275,7 @@ func (d *ShareDialog) onShareTypeChanged(shareType string) {
 		d.allowDownload.Hide()
 		d.allowEdit.Hide()
 		
-	case "複製到剪貼簿":
+	case "複製到剪貼簿", "收件人公鑰加密":
 		d.recipientsEntry.Hide()
 		d.passwordEntry.Hide()
 		d.expirySelect.Hide()
@@ -291,6 +324,25 @@ func (d *ShareDialog) onCopyClicked() {
 
 // 輔助方法
 
+// copyToClipboard 複製內容到剪貼簿
+// 參數：clipboard（系統剪貼簿）、content（要複製的內容）、clearAfter（自動清除的時間，0 表示不清除）
+//
+// 時間到時只有剪貼簿仍是同一份內容才會清除，不會覆蓋用戶之後複製的其他內容
+func copyToClipboard(clipboard fyne.Clipboard, content string, clearAfter time.Duration) {
+	clipboard.SetContent(content)
+	if clearAfter <= 0 {
+		return
+	}
+	
+	time.AfterFunc(clearAfter, func() {
+		fyne.Do(func() {
+			if clipboard.Content() == content {
+				clipboard.SetContent("")
+			}
+		})
+	})
+}
+
 // validateInput 驗證用戶輸入
 // 回傳：輸入是否有效
 func (d *ShareDialog) validateInput() bool {
@@ -311,6 +363,21 @@ func (d *ShareDialog) validateInput() bool {
 		}
 	}
 	
+	// 收件人公鑰加密需要有效的公鑰
+	if shareType == "收件人公鑰加密" {
+		publicKeys := d.parsePublicKeyList(d.publicKeysEntry.Text)
+		if len(publicKeys) == 0 && !d.includeSelf.Checked {
+			d.showError("請輸入收件人公鑰")
+			return false
+		}
+		for _, publicKey := range publicKeys {
+			if _, err := services.ParseRecipientKey(publicKey); err != nil {
+				d.showError(fmt.Sprintf("收件人公鑰無效: %s", publicKey))
+				return false
+			}
+		}
+	}
+	
 	return true
 }
 
@@ -334,6 +401,13 @@ func (d *ShareDialog) createShareOptions() *services.ShareOptions {
 		options.Recipients = recipients
 	}
 	
+	// 收件人公鑰加密需要公鑰列表和輸出路徑
+	if shareType == services.ShareTypeRecipients {
+		options.Recipients = d.parsePublicKeyList(d.publicKeysEntry.Text)
+		options.OutputPath = trimString(d.outputPathEntry.Text)
+		options.IncludeSelf = d.includeSelf.Checked
+	}
+	
 	return options
 }
 
@@ -349,6 +423,8 @@ func (d *ShareDialog) getShareType() services.ShareType {
 		return services.ShareTypeAirDrop
 	case "複製到剪貼簿":
 		return services.ShareTypeClipboard
+	case "收件人公鑰加密":
+		return services.ShareTypeRecipients
 	default:
 		return services.ShareTypeLink
 	}
@@ -395,6 +471,14 @@ func (d *ShareDialog) onShareComplete(result *services.ShareResult, err error) {
 	d.shareButton.SetText("分享")
 	d.shareButton.Enable()
 	
+	// 加密筆記需要先重新輸入密碼，驗證成功後重新分享
+	if errors.Is(err, services.ErrReauthRequired) {
+		fyne.Do(func() {
+			ShowReauthDialog(d.window, d.exportService, d.note, d.onShareClicked)
+		})
+		return
+	}
+	
 	if err != nil || result == nil || !result.Success {
 		// 分享失敗
 		errorMsg := "分享失敗"
@@ -425,6 +509,17 @@ func (d *ShareDialog) onShareComplete(result *services.ShareResult, err error) {
 			d.copyButton.Show()
 		}
 		
+		// 如果產生了加密檔案，顯示檔案路徑
+		if result.FilePath != "" {
+			d.shareURLEntry.SetText(result.FilePath)
+			d.shareURLEntry.Show()
+		}
+		
+		// 複製到剪貼簿，加密筆記的內容在一段時間後自動清除
+		if result.ClipboardContent != "" {
+			copyToClipboard(d.window.Clipboard(), result.ClipboardContent, result.ClipboardClearAfter)
+		}
+		
 		d.showSuccess(result.Message)
 		
 		// 呼叫回調函數
@@ -499,6 +594,24 @@ func (d *ShareDialog) parseEmailList(emailList string) []string {
 	return emails
 }
 
+// parsePublicKeyList 解析收件人公鑰列表
+// 參數：keyList（以換行或逗號分隔的公鑰字串）
+// 回傳：公鑰字串陣列
+func (d *ShareDialog) parsePublicKeyList(keyList string) []string {
+	var publicKeys []string
+	
+	for _, part := range strings.FieldsFunc(keyList, func(r rune) bool {
+		return r == '\n' || r == ','
+	}) {
+		publicKey := trimString(part)
+		if publicKey != "" {
+			publicKeys = append(publicKeys, publicKey)
+		}
+	}
+	
+	return publicKeys
+}
+
 // showError 顯示錯誤訊息
 // 參數：message（錯誤訊息）
 func (d *ShareDialog) showError(message string) {
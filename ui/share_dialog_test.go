@@ -638,4 +638,8 @@ func (m *mockShareExportService) GetExportProgress(exportID string) *services.Ex
 
 func (m *mockShareExportService) CancelExport(exportID string) bool {
 	return true
+}
+
+func (m *mockShareExportService) Reauthenticate(note *models.Note, password string) error {
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,226 @@
+// Package ui 包含標籤瀏覽器元件
+// 在側邊欄以樹狀結構顯示所有標籤和筆記數量，選擇標籤後列出含有該標籤的筆記
+package ui
+
+import (
+	"fmt"           // Go 標準庫，用於格式化字串
+	"path/filepath" // Go 標準庫，用於檔案路徑處理
+
+	"fyne.io/fyne/v2"                    // Fyne GUI 框架核心套件
+	"fyne.io/fyne/v2/canvas"             // Fyne 繪圖元件（保留最小高度）
+	"fyne.io/fyne/v2/container"          // Fyne 容器佈局套件
+	"fyne.io/fyne/v2/widget"             // Fyne UI 元件套件
+	"mac-notebook-app/internal/services" // 本專案的服務層套件
+)
+
+// tagBrowserMinHeight 標籤樹和筆記列表的最小高度
+const tagBrowserMinHeight = 140
+
+// TagBrowserWidget 代表側邊欄的標籤瀏覽器
+// 巢狀標籤（例如 project/alpha）依階層顯示，數量包含子標籤的筆記
+type TagBrowserWidget struct {
+	widget.BaseWidget // 繼承 Fyne 基礎元件
+
+	// 服務依賴
+	tagService services.TagService // 標籤服務
+
+	// UI 元件
+	tree         *widget.Tree    // 標籤樹
+	noteList     *widget.List    // 含有選擇的標籤的筆記
+	renameButton *widget.Button  // 重新命名或合併標籤按鈕
+	emptyLabel   *widget.Label   // 沒有標籤時的提示
+	container    *fyne.Container // 容器元件
+
+	// 資料和狀態
+	tags        map[string]*services.TagInfo // 以完整名稱為鍵的標籤
+	roots       []string                     // 最上層標籤的名稱
+	selectedTag string                       // 目前選擇的標籤
+	notes       []string                     // 含有選擇的標籤的筆記路徑
+
+	// 回調函數
+	onNoteOpen  func(filePath string) // 開啟筆記回調
+	onRenameTag func(tag string)      // 重新命名標籤回調
+}
+
+// NewTagBrowserWidget 建立標籤瀏覽器
+// 參數：tagService（標籤服務）
+// 回傳：標籤瀏覽器實例
+//
+// 執行流程：
+// 1. 建立標籤樹、筆記列表和操作按鈕
+// 2. 從標籤服務載入目前的標籤
+func NewTagBrowserWidget(tagService services.TagService) *TagBrowserWidget {
+	tb := &TagBrowserWidget{
+		tagService: tagService,
+		tags:       make(map[string]*services.TagInfo),
+	}
+	tb.ExtendBaseWidget(tb)
+	tb.createUI()
+	tb.ReloadTags()
+	return tb
+}
+
+// createUI 建立標籤瀏覽器的 UI 佈局
+func (tb *TagBrowserWidget) createUI() {
+	tb.tree = widget.NewTree(
+		func(uid widget.TreeNodeID) []widget.TreeNodeID {
+			return tb.childUIDs(uid)
+		},
+		func(uid widget.TreeNodeID) bool {
+			return uid == "" || len(tb.childUIDs(uid)) > 0
+		},
+		func(branch bool) fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(uid widget.TreeNodeID, branch bool, obj fyne.CanvasObject) {
+			if info, ok := tb.tags[uid]; ok {
+				obj.(*widget.Label).SetText(fmt.Sprintf("#%s (%d)", info.Label, info.Count))
+			}
+		},
+	)
+	tb.tree.OnSelected = func(uid widget.TreeNodeID) {
+		tb.SelectTag(uid)
+	}
+
+	tb.noteList = widget.NewList(
+		func() int {
+			return len(tb.notes)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			obj.(*widget.Label).SetText(filepath.Base(tb.notes[id]))
+		},
+	)
+	tb.noteList.OnSelected = func(id widget.ListItemID) {
+		tb.noteList.UnselectAll()
+		if id < len(tb.notes) && tb.onNoteOpen != nil {
+			tb.onNoteOpen(tb.notes[id])
+		}
+	}
+
+	tb.renameButton = widget.NewButton("重新命名或合併…", func() {
+		if tb.selectedTag != "" && tb.onRenameTag != nil {
+			tb.onRenameTag(tb.selectedTag)
+		}
+	})
+	tb.renameButton.Disable()
+	tb.emptyLabel = widget.NewLabel("尚無標籤，在筆記中輸入 #標籤 或在 front matter 加入 tags")
+	tb.emptyLabel.Wrapping = fyne.TextWrapWord
+
+	tb.container = container.NewVBox(
+		tb.emptyLabel,
+		withMinHeight(tb.tree, tagBrowserMinHeight),
+		tb.renameButton,
+		withMinHeight(tb.noteList, tagBrowserMinHeight),
+	)
+}
+
+// CreateRenderer 實作 fyne.Widget 介面
+// 回傳：元件的渲染器
+func (tb *TagBrowserWidget) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(tb.container)
+}
+
+// ReloadTags 從標籤服務重新載入標籤，選擇的標籤仍存在時保留選擇
+//
+// 執行流程：
+// 1. 取得標籤樹並建立名稱索引
+// 2. 選擇的標籤已不存在時清除選擇
+// 3. 重新整理標籤樹和筆記列表
+func (tb *TagBrowserWidget) ReloadTags() {
+	tb.tags = make(map[string]*services.TagInfo)
+	tb.roots = tb.roots[:0]
+	var index func(infos []*services.TagInfo)
+	index = func(infos []*services.TagInfo) {
+		for _, info := range infos {
+			tb.tags[info.Name] = info
+			index(info.Children)
+		}
+	}
+	roots := tb.tagService.Tags()
+	for _, info := range roots {
+		tb.roots = append(tb.roots, info.Name)
+	}
+	index(roots)
+
+	if len(roots) == 0 {
+		tb.emptyLabel.Show()
+	} else {
+		tb.emptyLabel.Hide()
+	}
+	tb.tree.Refresh()
+
+	if _, ok := tb.tags[tb.selectedTag]; ok {
+		tb.SelectTag(tb.selectedTag)
+	} else {
+		tb.SelectTag("")
+	}
+}
+
+// SelectTag 選擇標籤並列出含有該標籤或其子標籤的筆記
+// 參數：tag（標籤名稱，空字串代表清除選擇）
+func (tb *TagBrowserWidget) SelectTag(tag string) {
+	tb.selectedTag = tag
+	tb.notes = nil
+	if tag != "" {
+		tb.notes = tb.tagService.NotesWithTag(tag)
+		tb.renameButton.Enable()
+	} else {
+		tb.tree.UnselectAll()
+		tb.renameButton.Disable()
+	}
+	tb.noteList.Refresh()
+}
+
+// GetSelectedTag 取得目前選擇的標籤
+// 回傳：標籤名稱，沒有選擇時為空字串
+func (tb *TagBrowserWidget) GetSelectedTag() string {
+	return tb.selectedTag
+}
+
+// SetOnNoteOpen 設定開啟筆記回調函數
+// 參數：callback（點選筆記列表時的回調函數）
+func (tb *TagBrowserWidget) SetOnNoteOpen(callback func(filePath string)) {
+	tb.onNoteOpen = callback
+}
+
+// SetOnRenameTag 設定重新命名標籤回調函數
+// 參數：callback（點選重新命名按鈕時的回調函數）
+func (tb *TagBrowserWidget) SetOnRenameTag(callback func(tag string)) {
+	tb.onRenameTag = callback
+}
+
+// GetContainer 取得標籤瀏覽器的容器
+// 回傳：容器元件
+func (tb *TagBrowserWidget) GetContainer() *fyne.Container {
+	return tb.container
+}
+
+// childUIDs 取得標籤樹節點的子節點
+// 參數：uid（節點 ID，空字串為根節點）
+// 回傳：子標籤的完整名稱
+func (tb *TagBrowserWidget) childUIDs(uid widget.TreeNodeID) []widget.TreeNodeID {
+	if uid == "" {
+		return tb.roots
+	}
+	info, ok := tb.tags[uid]
+	if !ok {
+		return nil
+	}
+	children := make([]widget.TreeNodeID, 0, len(info.Children))
+	for _, child := range info.Children {
+		children = append(children, child.Name)
+	}
+	return children
+}
+
+// withMinHeight 為清單類元件保留最小高度，放在垂直容器中時才不會被壓縮
+// 參數：content（元件）、height（最小高度）
+// 回傳：包含元件的容器
+func withMinHeight(content fyne.CanvasObject, height float32) fyne.CanvasObject {
+	spacer := canvas.NewRectangle(nil)
+	spacer.SetMinSize(fyne.NewSize(0, height))
+	return container.NewStack(spacer, content)
+}
@@ -0,0 +1,83 @@
+// Package ui 包含標籤瀏覽器的測試
+// 測試標籤樹的階層、選擇標籤後列出筆記，以及重新載入時保留選擇
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+	"mac-notebook-app/internal/repositories"
+	"mac-notebook-app/internal/services"
+)
+
+// createTestTagBrowser 建立使用暫存筆記本的標籤瀏覽器
+func createTestTagBrowser(t *testing.T, files map[string]string) (string, services.TagService, *TagBrowserWidget) {
+	test.NewApp()
+	baseDir := t.TempDir()
+	for name, content := range files {
+		os.WriteFile(filepath.Join(baseDir, name), []byte(content), 0644)
+	}
+	fileRepo, err := repositories.NewLocalFileRepository(baseDir)
+	if err != nil {
+		t.Fatalf("建立檔案儲存庫失敗: %v", err)
+	}
+	tags := services.NewTagService(fileRepo, nil)
+	if err := tags.Rebuild(); err != nil {
+		t.Fatalf("建立標籤索引失敗: %v", err)
+	}
+	return baseDir, tags, NewTagBrowserWidget(tags)
+}
+
+// TestTagBrowserTree 測試標籤樹的階層和選擇標籤後列出的筆記
+func TestTagBrowserTree(t *testing.T) {
+	_, _, browser := createTestTagBrowser(t, map[string]string{
+		"a.md": "#project/alpha",
+		"b.md": "#project #會議",
+	})
+
+	roots := browser.childUIDs("")
+	if len(roots) != 2 || roots[0] != "project" || roots[1] != "會議" {
+		t.Fatalf("最上層標籤不正確: %v", roots)
+	}
+	if children := browser.childUIDs("project"); len(children) != 1 || children[0] != "project/alpha" {
+		t.Errorf("子標籤不正確: %v", children)
+	}
+	if browser.emptyLabel.Visible() {
+		t.Error("有標籤時不應顯示空白提示")
+	}
+
+	var opened string
+	browser.SetOnNoteOpen(func(filePath string) { opened = filePath })
+	browser.SelectTag("project")
+	if len(browser.notes) != 2 || browser.renameButton.Disabled() {
+		t.Errorf("選擇標籤後應列出 2 個筆記並啟用重新命名: %v", browser.notes)
+	}
+	browser.noteList.OnSelected(1)
+	if opened != "b.md" {
+		t.Errorf("點選筆記應該開啟 b.md，但得到 %q", opened)
+	}
+}
+
+// TestTagBrowserReload 測試重新載入時保留仍存在的選擇，標籤消失時清除選擇
+func TestTagBrowserReload(t *testing.T) {
+	_, tags, browser := createTestTagBrowser(t, map[string]string{
+		"a.md": "#工作",
+		"b.md": "#工作 #草稿",
+	})
+
+	browser.SelectTag("草稿")
+	tags.UpdateNote("c.md", "#草稿")
+	browser.ReloadTags()
+	if browser.GetSelectedTag() != "草稿" || len(browser.notes) != 2 {
+		t.Errorf("重新載入後應保留選擇並更新筆記: %q %v", browser.GetSelectedTag(), browser.notes)
+	}
+
+	tags.RemoveNote("b.md")
+	tags.RemoveNote("c.md")
+	browser.ReloadTags()
+	if browser.GetSelectedTag() != "" || len(browser.notes) != 0 || !browser.renameButton.Disabled() {
+		t.Error("標籤消失後應該清除選擇")
+	}
+}